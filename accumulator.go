@@ -40,7 +40,44 @@ type Accumulator interface {
 		tags map[string]string,
 		t ...time.Time)
 
+	// AddMetrics adds a batch of metrics, like the metrics parsed from a
+	// message, with a single send to the agent. The accumulator owns the
+	// slice and the metrics after the call.
+	AddMetrics(metrics []Metric)
+
 	SetPrecision(precision, interval time.Duration)
 
 	AddError(err error)
+
+	// WithTracking returns an Accumulator reporting the delivery of metric
+	// groups, at most maxTracked groups may be undelivered at a time.
+	WithTracking(maxTracked int) TrackingAccumulator
+}
+
+// TrackingID identifies a group of tracking metrics.
+type TrackingID uint64
+
+// DeliveryInfo reports the delivery of a group of tracking metrics once all
+// its metrics were accepted, rejected or dropped.
+type DeliveryInfo interface {
+	// ID returns the id of the group returned by AddTrackingMetricGroup.
+	ID() TrackingID
+
+	// Delivered returns false if a metric of the group was rejected.
+	Delivered() bool
+}
+
+// TrackingAccumulator is an Accumulator for service inputs acknowledging
+// their messages, like queue consumers, only after the outputs wrote the
+// metrics of the messages.
+type TrackingAccumulator interface {
+	Accumulator
+
+	// AddTrackingMetricGroup adds the metrics of a message and returns the id
+	// of their delivery report, a group without metrics is delivered at once.
+	AddTrackingMetricGroup(group []Metric) TrackingID
+
+	// Delivered returns the channel of the delivery reports, the input must
+	// read it while it has undelivered groups.
+	Delivered() <-chan DeliveryInfo
 }
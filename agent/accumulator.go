@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -14,6 +16,8 @@ var (
 
 type MetricMaker interface {
 	Name() string
+	// LogName is the name of the plugin instance in the logs.
+	LogName() string
 	MakeMetric(
 		measurement string,
 		fields map[string]interface{},
@@ -25,8 +29,15 @@ type MetricMaker interface {
 
 func NewAccumulator(
 	maker MetricMaker,
-	metrics chan telegraf.Metric,
+	metrics chan []telegraf.Metric,
 ) telegraf.Accumulator {
+	return newAccumulator(maker, metrics)
+}
+
+func newAccumulator(
+	maker MetricMaker,
+	metrics chan []telegraf.Metric,
+) *accumulator {
 	acc := accumulator{
 		maker:     maker,
 		metrics:   metrics,
@@ -36,11 +47,34 @@ func NewAccumulator(
 }
 
 type accumulator struct {
-	metrics chan telegraf.Metric
+	metrics chan []telegraf.Metric
 
 	maker MetricMaker
 
 	precision time.Duration
+	// truncate truncates the timestamps to the precision instead of
+	// rounding them.
+	truncate bool
+
+	// window drops or clamps the metrics with timestamps out of range, it is
+	// nil if their timestamps are not limited.
+	window *timeWindow
+}
+
+// makeMetric makes the metric of the maker if its timestamp is in the time
+// window, the timestamp is reduced to the precision.
+func (ac *accumulator) makeMetric(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	mType telegraf.ValueType,
+	t time.Time,
+) telegraf.Metric {
+	t, ok := ac.window.check(t, time.Now())
+	if !ok {
+		return nil
+	}
+	return ac.maker.MakeMetric(measurement, fields, tags, mType, ac.round(t))
 }
 
 func (ac *accumulator) AddFields(
@@ -49,8 +83,8 @@ func (ac *accumulator) AddFields(
 	tags map[string]string,
 	t ...time.Time,
 ) {
-	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Untyped, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+	if m := ac.makeMetric(measurement, fields, tags, telegraf.Untyped, ac.getTime(t)); m != nil {
+		ac.metrics <- []telegraf.Metric{m}
 	}
 }
 
@@ -60,8 +94,8 @@ func (ac *accumulator) AddGauge(
 	tags map[string]string,
 	t ...time.Time,
 ) {
-	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Gauge, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+	if m := ac.makeMetric(measurement, fields, tags, telegraf.Gauge, ac.getTime(t)); m != nil {
+		ac.metrics <- []telegraf.Metric{m}
 	}
 }
 
@@ -71,8 +105,8 @@ func (ac *accumulator) AddCounter(
 	tags map[string]string,
 	t ...time.Time,
 ) {
-	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Counter, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+	if m := ac.makeMetric(measurement, fields, tags, telegraf.Counter, ac.getTime(t)); m != nil {
+		ac.metrics <- []telegraf.Metric{m}
 	}
 }
 
@@ -82,8 +116,8 @@ func (ac *accumulator) AddSummary(
 	tags map[string]string,
 	t ...time.Time,
 ) {
-	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Summary, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+	if m := ac.makeMetric(measurement, fields, tags, telegraf.Summary, ac.getTime(t)); m != nil {
+		ac.metrics <- []telegraf.Metric{m}
 	}
 }
 
@@ -93,8 +127,22 @@ func (ac *accumulator) AddHistogram(
 	tags map[string]string,
 	t ...time.Time,
 ) {
-	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Histogram, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+	if m := ac.makeMetric(measurement, fields, tags, telegraf.Histogram, ac.getTime(t)); m != nil {
+		ac.metrics <- []telegraf.Metric{m}
+	}
+}
+
+// AddMetrics makes the metrics of the input or aggregator from the batch and
+// sends them together, the batch is reused for the new metrics.
+func (ac *accumulator) AddMetrics(metrics []telegraf.Metric) {
+	batch := metrics[:0]
+	for _, m := range metrics {
+		if m := ac.makeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(), m.Time()); m != nil {
+			batch = append(batch, m)
+		}
+	}
+	if len(batch) > 0 {
+		ac.metrics <- batch
 	}
 }
 
@@ -105,8 +153,11 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
+	if input, ok := ac.maker.(*models.RunningInput); ok {
+		input.GatherErrors.Incr(1)
+	}
 	//TODO suppress/throttle consecutive duplicate errors?
-	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
+	log.Printf("E! Error in plugin [%s]: %s", ac.maker.LogName(), err)
 }
 
 // SetPrecision takes two time.Duration objects. If the first is non-zero,
@@ -137,5 +188,110 @@ func (ac accumulator) getTime(t []time.Time) time.Time {
 	} else {
 		timestamp = time.Now()
 	}
-	return timestamp.Round(ac.precision)
+	return ac.round(timestamp)
+}
+
+// round reduces the timestamp to the precision.
+func (ac accumulator) round(t time.Time) time.Time {
+	if ac.truncate {
+		return t.Truncate(ac.precision)
+	}
+	return t.Round(ac.precision)
+}
+
+// How the timestamps are reduced to the precision.
+const (
+	PrecisionRound    = "round"
+	PrecisionTruncate = "truncate"
+)
+
+// Actions on the metrics with timestamps out of the time window.
+const (
+	TimestampDrop  = "drop"
+	TimestampClamp = "clamp"
+)
+
+// timeWindow limits how far in the past or in the future the timestamps of
+// the metrics of an input may be, the metrics out of range are dropped or get
+// the current time.
+type timeWindow struct {
+	maxPast   time.Duration
+	maxFuture time.Duration
+	clamp     bool
+
+	dropped selfstat.Stat
+	clamped selfstat.Stat
+}
+
+// newTimeWindow returns the time window of the input, or nil if both limits
+// are zero.
+func newTimeWindow(maxPast, maxFuture time.Duration, action string, tags map[string]string) *timeWindow {
+	if maxPast <= 0 && maxFuture <= 0 {
+		return nil
+	}
+	return &timeWindow{
+		maxPast:   maxPast,
+		maxFuture: maxFuture,
+		clamp:     action == TimestampClamp,
+		dropped:   selfstat.Register("gather", "metrics_timestamp_dropped", tags),
+		clamped:   selfstat.Register("gather", "metrics_timestamp_clamped", tags),
+	}
+}
+
+// check returns the timestamp of a metric made at now and whether the metric
+// is kept.
+func (w *timeWindow) check(t, now time.Time) (time.Time, bool) {
+	if w == nil {
+		return t, true
+	}
+	if (w.maxPast <= 0 || !t.Before(now.Add(-w.maxPast))) &&
+		(w.maxFuture <= 0 || !t.After(now.Add(w.maxFuture))) {
+		return t, true
+	}
+	if w.clamp {
+		w.clamped.Incr(1)
+		return now, true
+	}
+	w.dropped.Incr(1)
+	return t, false
+}
+
+func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	if maxTracked < 1 {
+		maxTracked = 1
+	}
+	return &trackingAccumulator{
+		accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// trackingAccumulator sends the delivery reports to a channel with room for
+// the report of every undelivered group, the outputs never wait on it.
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	batch := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		if m := a.makeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(), m.Time()); m != nil {
+			batch = append(batch, m)
+		}
+	}
+
+	batch, id := metric.WithGroupTracking(batch, a.onDelivery)
+	if len(batch) > 0 {
+		a.metrics <- batch
+	}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	a.delivered <- info
 }
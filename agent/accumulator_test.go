@@ -3,12 +3,14 @@ package agent
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/metric"
 
 	"github.com/stretchr/testify/assert"
@@ -16,7 +18,7 @@ import (
 )
 
 func TestAddFields(t *testing.T) {
-	metrics := make(chan telegraf.Metric, 10)
+	metrics := make(chan []telegraf.Metric, 10)
 	defer close(metrics)
 	a := NewAccumulator(&TestMetricMaker{}, metrics)
 
@@ -27,7 +29,7 @@ func TestAddFields(t *testing.T) {
 	now := time.Now()
 	a.AddCounter("acctest", fields, tags, now)
 
-	testm := <-metrics
+	testm := (<-metrics)[0]
 
 	require.Equal(t, "acctest", testm.Name())
 	actual, ok := testm.GetField("usage")
@@ -52,7 +54,7 @@ func TestAccAddError(t *testing.T) {
 	log.SetOutput(errBuf)
 	defer log.SetOutput(os.Stderr)
 
-	metrics := make(chan telegraf.Metric, 10)
+	metrics := make(chan []telegraf.Metric, 10)
 	defer close(metrics)
 	a := NewAccumulator(&TestMetricMaker{}, metrics)
 
@@ -71,6 +73,18 @@ func TestAccAddError(t *testing.T) {
 	assert.Contains(t, string(errs[2]), "baz")
 }
 
+func TestAccAddErrorInput(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	input := models.NewRunningInput(&countingInput{}, &models.InputConfig{Name: "errors", Alias: "orders"})
+	assert.Equal(t, map[string]string{"input": "errors", "alias": "orders"}, input.StatTags())
+	a := NewAccumulator(input, make(chan []telegraf.Metric, 10))
+	a.AddError(fmt.Errorf("foo"))
+	a.AddError(fmt.Errorf("bar"))
+	assert.EqualValues(t, 2, input.GatherErrors.Get())
+}
+
 func TestSetPrecision(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -107,7 +121,7 @@ func TestSetPrecision(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			metrics := make(chan telegraf.Metric, 10)
+			metrics := make(chan []telegraf.Metric, 10)
 
 			a := NewAccumulator(&TestMetricMaker{}, metrics)
 			if !tt.unset {
@@ -120,7 +134,7 @@ func TestSetPrecision(t *testing.T) {
 				tt.timestamp,
 			)
 
-			testm := <-metrics
+			testm := (<-metrics)[0]
 			require.Equal(t, tt.expected, testm.Time())
 
 			close(metrics)
@@ -128,12 +142,118 @@ func TestSetPrecision(t *testing.T) {
 	}
 }
 
+func TestAddMetrics(t *testing.T) {
+	metrics := make(chan []telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics)
+	a.SetPrecision(time.Second, 0)
+
+	now := time.Date(2006, time.February, 10, 12, 0, 0, 82912748, time.UTC)
+	m1, _ := metric.New("cpu", map[string]string{"cpu": "cpu0"}, map[string]interface{}{"usage": 42.0}, now)
+	m2, _ := metric.New("mem", map[string]string{}, map[string]interface{}{"used": 42}, now, telegraf.Gauge)
+	// dropped by the metric maker
+	m3, _ := metric.New("disk", map[string]string{}, map[string]interface{}{"free": 42}, now, telegraf.Summary)
+	a.AddMetrics([]telegraf.Metric{m1, m2, m3})
+
+	batch := <-metrics
+	require.Len(t, batch, 2)
+	assert.Equal(t, "cpu", batch[0].Name())
+	assert.Equal(t, map[string]string{"cpu": "cpu0"}, batch[0].Tags())
+	assert.Equal(t, "mem", batch[1].Name())
+	assert.Equal(t, telegraf.Gauge, batch[1].Type())
+	assert.Equal(t, time.Date(2006, time.February, 10, 12, 0, 0, 0, time.UTC), batch[0].Time())
+
+	// nothing is sent if all the metrics are dropped
+	a.AddMetrics([]telegraf.Metric{m3})
+	a.AddMetrics(nil)
+	assert.Len(t, metrics, 0)
+}
+
+func TestPrecisionTruncate(t *testing.T) {
+	metrics := make(chan []telegraf.Metric, 10)
+	defer close(metrics)
+	a := newAccumulator(&TestMetricMaker{}, metrics)
+	a.SetPrecision(time.Second, 0)
+
+	late := time.Date(2006, time.February, 10, 12, 0, 0, 900000000, time.UTC)
+	a.AddFields("cpu", map[string]interface{}{"usage": 42.0}, nil, late)
+	assert.Equal(t, late.Add(100*time.Millisecond), (<-metrics)[0].Time())
+
+	a.truncate = true
+	a.AddFields("cpu", map[string]interface{}{"usage": 42.0}, nil, late)
+	assert.Equal(t, late.Add(-900*time.Millisecond), (<-metrics)[0].Time())
+}
+
+func TestAddTrackingMetricGroup(t *testing.T) {
+	metrics := make(chan []telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(10)
+
+	now := time.Now()
+	m1, _ := metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 42.0}, now)
+	m2, _ := metric.New("disk", map[string]string{}, map[string]interface{}{"free": 42}, now, telegraf.Summary)
+	id := a.AddTrackingMetricGroup([]telegraf.Metric{m1, m2})
+
+	// the metric dropped by the metric maker is not tracked
+	batch := <-metrics
+	require.Len(t, batch, 1)
+	assert.Len(t, a.Delivered(), 0)
+
+	batch[0].Copy().Accept()
+	assert.Len(t, a.Delivered(), 0)
+	batch[0].Accept()
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+
+	// a group without metrics is delivered at once
+	id = a.AddTrackingMetricGroup([]telegraf.Metric{m2})
+	info = <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+	assert.Len(t, metrics, 0)
+}
+
+func TestTimeWindow(t *testing.T) {
+	metrics := make(chan []telegraf.Metric, 10)
+	defer close(metrics)
+	a := newAccumulator(&TestMetricMaker{}, metrics)
+	a.window = newTimeWindow(time.Hour, time.Minute, TimestampDrop, map[string]string{"input": "test_window"})
+
+	fields := map[string]interface{}{"usage": float64(99)}
+	now := time.Now()
+	a.AddFields("acctest", fields, nil, now.Add(-2*time.Hour))
+	a.AddFields("acctest", fields, nil, now.Add(time.Hour))
+	a.AddFields("acctest", fields, nil, now.Add(-30*time.Minute))
+	m := (<-metrics)[0]
+	assert.True(t, now.Add(-30*time.Minute).Equal(m.Time()))
+	assert.Len(t, metrics, 0)
+	assert.Equal(t, int64(2), a.window.dropped.Get())
+
+	a.window = newTimeWindow(0, time.Minute, TimestampClamp, map[string]string{"input": "test_window_clamp"})
+	a.AddFields("acctest", fields, nil, now.Add(-2*time.Hour))
+	a.AddFields("acctest", fields, nil, now.Add(time.Hour))
+	assert.True(t, now.Add(-2*time.Hour).Equal((<-metrics)[0].Time()))
+	clamped := (<-metrics)[0].Time()
+	assert.False(t, clamped.Before(now))
+	assert.True(t, clamped.Before(now.Add(time.Minute)))
+	assert.Equal(t, int64(1), a.window.clamped.Get())
+	assert.Equal(t, int64(0), a.window.dropped.Get())
+
+	// no window without limits
+	assert.Nil(t, newTimeWindow(0, 0, TimestampDrop, map[string]string{"input": "test_window_none"}))
+}
+
 type TestMetricMaker struct {
 }
 
 func (tm *TestMetricMaker) Name() string {
 	return "TestPlugin"
 }
+
+func (tm *TestMetricMaker) LogName() string {
+	return "TestPlugin"
+}
 func (tm *TestMetricMaker) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
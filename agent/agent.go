@@ -1,9 +1,12 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"time"
@@ -11,19 +14,46 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/discovery"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/store"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// elector elects the instance gathering the leader_only inputs, they
+	// are always gathered if it is nil.
+	elector elector
+
+	reload     chan struct{}
+	reloadOnce sync.Once
+
+	// store holds the state of the plugins keeping state, it is written
+	// when the agent closes.
+	store *store.DB
+
+	// services are the accumulators of the running service inputs, kept
+	// are those left running at shutdown and adopted those started by the
+	// previous agent.
+	servicesMu sync.Mutex
+	services   map[*models.RunningInput]*serviceAccumulator
+	kept       map[*models.RunningInput]bool
+	adopted    map[*models.RunningInput]*serviceAccumulator
+	// retries are the service inputs started again every interval
+	// after failing to start, they join services once started.
+	retries map[*models.RunningInput]*startRetry
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:   config,
+		reload:   make(chan struct{}),
+		services: make(map[*models.RunningInput]*serviceAccumulator),
+		retries:  make(map[*models.RunningInput]*startRetry),
 	}
 
 	if !a.Config.Agent.OmitHostname {
@@ -39,9 +69,199 @@ func NewAgent(config *config.Config) (*Agent, error) {
 		config.Tags["host"] = a.Config.Agent.Hostname
 	}
 
+	switch a.Config.Agent.TimestampOutOfRange {
+	case "", TimestampDrop, TimestampClamp:
+	default:
+		return nil, fmt.Errorf("invalid timestamp_out_of_range %q, must be %q or %q",
+			a.Config.Agent.TimestampOutOfRange, TimestampDrop, TimestampClamp)
+	}
+
+	switch a.Config.Agent.PrecisionRounding {
+	case "", PrecisionRound, PrecisionTruncate:
+	default:
+		return nil, fmt.Errorf("invalid precision_rounding %q, must be %q or %q",
+			a.Config.Agent.PrecisionRounding, PrecisionRound, PrecisionTruncate)
+	}
+
+	if err := models.CheckMetricOrdering(a.Config.Agent.MetricOrdering); err != nil {
+		return nil, err
+	}
+	for _, o := range a.Config.Outputs {
+		if o.Config.MetricOrdering == "" {
+			o.Config.MetricOrdering = a.Config.Agent.MetricOrdering
+		}
+	}
+
+	if rate := a.Config.Agent.TraceSampleRate; rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("invalid trace_sample_rate %v, must be between 0 and 1", rate)
+	}
+	if a.Config.Agent.TraceTag == "" {
+		a.Config.Agent.TraceTag = "trace_id"
+	}
+	models.SetTracing(a.Config.Agent.TraceSampleRate, a.Config.Agent.TraceTag)
+
+	if err := a.setStores(); err != nil {
+		return nil, err
+	}
+
+	if err := a.openSpools(); err != nil {
+		return nil, err
+	}
+
+	if err := a.setElector(); err != nil {
+		return nil, err
+	}
+
 	return a, nil
 }
 
+// setElector sets the leader elector of the agent if the leader election is
+// enabled, the service inputs cannot be leader_only.
+func (a *Agent) setElector() error {
+	for _, input := range a.Config.Inputs {
+		if _, ok := input.Input.(telegraf.ServiceInput); ok && input.Config.LeaderOnly {
+			return fmt.Errorf("input %s: leader_only is not supported by service inputs", input.Name())
+		}
+	}
+	if a.Config.Agent.LeaderElectionKey == "" {
+		return nil
+	}
+	e, err := newConsulElector(a.Config.Agent.LeaderElectionAddress,
+		a.Config.Agent.LeaderElectionKey)
+	if err != nil {
+		return err
+	}
+	a.elector = e
+	return nil
+}
+
+// leads returns whether the input is gathered by this instance: it is not
+// leader_only or the instance is the leader.
+func (a *Agent) leads(input *models.RunningInput) bool {
+	return !input.Config.LeaderOnly || a.elector == nil || a.elector.IsLeader()
+}
+
+// newAccumulator returns the accumulator of the input, it enforces the
+// timestamp limits of the agent.
+func (a *Agent) newAccumulator(
+	input *models.RunningInput,
+	metricC chan []telegraf.Metric,
+) *accumulator {
+	acc := newAccumulator(input, metricC)
+	acc.truncate = a.Config.Agent.PrecisionRounding == PrecisionTruncate
+	acc.window = newTimeWindow(a.Config.Agent.TimestampMaxPast.Duration,
+		a.Config.Agent.TimestampMaxFuture.Duration,
+		a.Config.Agent.TimestampOutOfRange, input.StatTags())
+	return acc
+}
+
+// setStores opens the store of the agent and gives the plugins keeping state
+// their namespace, named after the plugin and its alias. The instances of a
+// plugin without alias are named by their number instead, their state follows
+// their order in the config.
+func (a *Agent) setStores() error {
+	db, err := store.Open(a.Config.Agent.StateFile)
+	if err != nil {
+		return err
+	}
+	a.store = db
+
+	type instance struct {
+		plugin telegraf.StatefulPlugin
+		name   string
+		alias  string
+		// input is set for the inputs, it keeps their namespace
+		input *models.RunningInput
+	}
+	var stateful []instance
+	add := func(plugin interface{}, name, alias string, input *models.RunningInput) {
+		if p, ok := plugin.(telegraf.StatefulPlugin); ok {
+			stateful = append(stateful, instance{plugin: p, name: name, alias: alias, input: input})
+		}
+	}
+	for _, input := range a.Config.Inputs {
+		add(input.Input, input.Name(), input.Config.Alias, input)
+	}
+	for _, processor := range a.Config.Processors {
+		add(processor.Processor, "processors."+processor.Name, processor.Config.Alias, nil)
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		add(aggregator.Aggregator(), aggregator.Name(), aggregator.Config.Alias, nil)
+	}
+	for _, output := range a.Config.Outputs {
+		add(output.Output, "outputs."+output.Name, output.Config.Alias, nil)
+	}
+
+	unaliased := make(map[string]int)
+	for _, i := range stateful {
+		if i.alias == "" {
+			unaliased[i.name]++
+		}
+	}
+	instances := make(map[string]int)
+	warned := make(map[string]bool)
+	for _, i := range stateful {
+		// the namespace of the instance before it had an alias
+		instances[i.name]++
+		numbered := i.name
+		if n := instances[i.name]; n > 1 {
+			numbered = fmt.Sprintf("%s.%d", i.name, n)
+		}
+
+		name := numbered
+		if i.alias != "" {
+			name = models.LogName(i.name, i.alias)
+			if !db.HasNamespace(name) && db.HasNamespace(numbered) {
+				log.Printf("W! No state is stored for %s, the state of %s is not used now "+
+					"that it is keyed by its alias", name, numbered)
+			}
+		} else if unaliased[i.name] > 1 && !warned[i.name] {
+			warned[i.name] = true
+			log.Printf("W! The state of the instances of %s without alias is keyed by their order, "+
+				"set their alias to keep their state when they are reordered", i.name)
+		}
+		if i.input != nil && i.input.Store != nil {
+			// the service input kept running by the previous agent keeps
+			// its namespace
+			if err := db.Move(i.input.Store, name); err != nil {
+				return err
+			}
+			continue
+		}
+		ns := db.Namespace(name)
+		if i.input != nil {
+			i.input.Store = ns
+		}
+		i.plugin.SetStore(ns)
+	}
+	return nil
+}
+
+// openSpools opens the spool files of the outputs if metric_buffer_directory
+// is set, they are named after the output and the number of the instance.
+func (a *Agent) openSpools() error {
+	dir := a.Config.Agent.MetricBufferDirectory
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	instances := make(map[string]int)
+	for _, output := range a.Config.Outputs {
+		name := "outputs." + output.Name
+		instances[name]++
+		if n := instances[name]; n > 1 {
+			name = fmt.Sprintf("%s.%d", name, n)
+		}
+		if err := output.OpenSpool(filepath.Join(dir, name+".wal")); err != nil {
+			return fmt.Errorf("opening the spool file of output %s: %s", output.Name, err)
+		}
+	}
+	return nil
+}
+
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
@@ -49,23 +269,23 @@ func (a *Agent) Connect() error {
 		case telegraf.ServiceOutput:
 			if err := ot.Start(); err != nil {
 				log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
+					o.LogName(), err.Error())
 				return err
 			}
 		}
 
-		log.Printf("D! Attempting connection to output: %s\n", o.Name)
+		log.Printf("D! Attempting connection to output: %s\n", o.LogName())
 		err := o.Output.Connect()
 		if err != nil {
 			log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
+				"error was '%s' \n", o.LogName(), err)
 			time.Sleep(15 * time.Second)
 			err = o.Output.Connect()
 			if err != nil {
 				return err
 			}
 		}
-		log.Printf("D! Successfully connected to output: %s\n", o.Name)
+		log.Printf("D! Successfully connected to output: %s\n", o.LogName())
 	}
 	return nil
 }
@@ -79,6 +299,14 @@ func (a *Agent) Close() error {
 		case telegraf.ServiceOutput:
 			ot.Stop()
 		}
+		if serr := o.CloseSpool(); serr != nil {
+			log.Printf("E! Failed to close the spool file of output %s: %s", o.LogName(), serr)
+		}
+	}
+	if a.store != nil {
+		if serr := a.store.Close(); serr != nil {
+			log.Printf("E! Failed to write the state file %s: %s", a.Config.Agent.StateFile, serr)
+		}
 	}
 	return err
 }
@@ -88,7 +316,7 @@ func panicRecover(input *models.RunningInput) {
 		trace := make([]byte, 2048)
 		runtime.Stack(trace, true)
 		log.Printf("E! FATAL: Input [%s] panicked: %s, Stack:\n%s\n",
-			input.Name(), err, trace)
+			input.LogName(), err, trace)
 		log.Println("E! PLEASE REPORT THIS PANIC ON GITHUB with " +
 			"stack trace, configuration, and OS information: " +
 			"https://github.com/influxdata/telegraf/issues/new")
@@ -101,30 +329,57 @@ func (a *Agent) gatherer(
 	shutdown chan struct{},
 	input *models.RunningInput,
 	interval time.Duration,
-	metricC chan telegraf.Metric,
+	start time.Time,
+	metricC chan []telegraf.Metric,
 ) {
 	defer panicRecover(input)
 
 	GatherTime := selfstat.RegisterTiming("gather",
 		"gather_time_ns",
-		map[string]string{"input": input.Config.Name},
+		input.StatTags(),
 	)
 
-	acc := NewAccumulator(input, metricC)
-	acc.SetPrecision(a.Config.Agent.Precision.Duration,
+	acc := a.newAccumulator(input, metricC)
+	acc.SetPrecision(a.precision(input),
 		a.Config.Agent.Interval.Duration)
 
+	if input.Config.Schedule != nil {
+		a.scheduledGatherer(shutdown, input, acc, GatherTime)
+		return
+	}
+
+	// Round collection to the interval of the input by sleeping
+	if a.roundInterval(input) {
+		select {
+		case <-shutdown:
+			return
+		case <-time.After(time.Until(alignTime(start, interval))):
+		}
+	}
+
+	// the offset delays the first gather, the following are as late
+	if offset := a.collectionOffset(input); offset > 0 {
+		select {
+		case <-shutdown:
+			return
+		case <-time.After(offset):
+		}
+	}
+
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	jitter := a.collectionJitter(input)
 	for {
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
+		internal.RandomSleep(jitter, shutdown)
 
-		start := time.Now()
-		gatherWithTimeout(shutdown, input, acc, interval)
-		elapsed := time.Since(start)
+		if a.leads(input) && a.started(input) {
+			start := time.Now()
+			a.gather(shutdown, input, acc, interval)
+			elapsed := time.Since(start)
 
-		GatherTime.Incr(elapsed.Nanoseconds())
+			GatherTime.Incr(elapsed.Nanoseconds())
+		}
 
 		select {
 		case <-shutdown:
@@ -135,6 +390,167 @@ func (a *Agent) gatherer(
 	}
 }
 
+// scheduledGatherer gathers the input at the times of its schedule until
+// shutdown, with the jitter of the input. A gather taking longer than the
+// time to the next one is logged.
+func (a *Agent) scheduledGatherer(
+	shutdown chan struct{},
+	input *models.RunningInput,
+	acc telegraf.Accumulator,
+	gatherTime selfstat.Stat,
+) {
+	jitter := a.collectionJitter(input)
+	next := input.Config.Schedule.Next(time.Now())
+	for !next.IsZero() {
+		select {
+		case <-shutdown:
+			return
+		case <-time.After(time.Until(next)):
+		}
+		internal.RandomSleep(jitter, shutdown)
+
+		following := input.Config.Schedule.Next(next)
+		if a.leads(input) && a.started(input) {
+			start := time.Now()
+			timeout := time.Until(following)
+			if following.IsZero() || timeout <= 0 {
+				timeout = a.Config.Agent.Interval.Duration
+			}
+			a.gather(shutdown, input, acc, timeout)
+			gatherTime.Incr(time.Since(start).Nanoseconds())
+		}
+		// the runs missed while gathering are skipped
+		next = following
+		if now := time.Now(); !next.IsZero() && next.Before(now) {
+			next = input.Config.Schedule.Next(now)
+		}
+	}
+	log.Printf("W! Input [%s] has no time left in its schedule %q", input.LogName(), input.Config.Schedule)
+}
+
+// interval returns the interval of the input, or the interval of the agent if
+// the input has none.
+func (a *Agent) interval(input *models.RunningInput) time.Duration {
+	if input.Config.Interval != 0 {
+		return input.Config.Interval
+	}
+	return a.Config.Agent.Interval.Duration
+}
+
+// precision returns the precision of the input, or the precision of the
+// agent if the input has none.
+func (a *Agent) precision(input *models.RunningInput) time.Duration {
+	if input.Config.Precision != 0 {
+		return input.Config.Precision
+	}
+	return a.Config.Agent.Precision.Duration
+}
+
+// roundInterval returns whether the collection of the input is rounded to
+// its interval, the input setting overrides the setting of the agent.
+func (a *Agent) roundInterval(input *models.RunningInput) bool {
+	if input.Config.RoundInterval != nil {
+		return *input.Config.RoundInterval
+	}
+	return a.Config.Agent.RoundInterval
+}
+
+// collectionJitter returns the collection jitter of the input, the jitter of
+// the agent unless the input sets its own.
+func (a *Agent) collectionJitter(input *models.RunningInput) time.Duration {
+	if input.Config.CollectionJitter != nil {
+		return *input.Config.CollectionJitter
+	}
+	return a.Config.Agent.CollectionJitter.Duration
+}
+
+// collectionOffset returns the collection offset of the input, the offset of
+// the agent unless the input sets its own.
+func (a *Agent) collectionOffset(input *models.RunningInput) time.Duration {
+	if input.Config.CollectionOffset != nil {
+		return *input.Config.CollectionOffset
+	}
+	return a.Config.Agent.CollectionOffset.Duration
+}
+
+// gatherTimeout returns the gather timeout of the input, the timeout of the
+// agent unless the input sets its own.
+func (a *Agent) gatherTimeout(input *models.RunningInput) time.Duration {
+	if input.Config.GatherTimeout != nil {
+		return *input.Config.GatherTimeout
+	}
+	return a.Config.Agent.GatherTimeout.Duration
+}
+
+// alignTime returns the first time from t that is a multiple of interval.
+func alignTime(t time.Time, interval time.Duration) time.Time {
+	r := t.UnixNano() % int64(interval)
+	if r == 0 {
+		return t
+	}
+	return t.Add(interval - time.Duration(r))
+}
+
+// gather gathers from the input with its gather timeout, or with the
+// interval given if it has none.
+func (a *Agent) gather(
+	shutdown chan struct{},
+	input *models.RunningInput,
+	acc telegraf.Accumulator,
+	interval time.Duration,
+) {
+	if timeout := a.gatherTimeout(input); timeout > 0 {
+		gatherWithDeadline(shutdown, input, acc, timeout)
+		return
+	}
+	gatherWithTimeout(shutdown, input, acc, interval)
+}
+
+// gatherWithDeadline gathers from the given input, with the given timeout.
+// When the timeout is reached the context of a ContextInput is canceled, the
+// timeout is counted in the timeouts of the input and gatherWithDeadline
+// returns without waiting for the Gather. The gathers of the input are
+// skipped until a Gather left running returns, so that a hung input is not
+// called over and over.
+func gatherWithDeadline(
+	shutdown chan struct{},
+	input *models.RunningInput,
+	acc telegraf.Accumulator,
+	timeout time.Duration,
+) {
+	if !input.StartGather() {
+		acc.AddError(fmt.Errorf("skipped, the previous gather has not returned yet"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer input.EndGather()
+		if ci, ok := input.Input.(telegraf.ContextInput); ok {
+			done <- ci.GatherContext(ctx, acc)
+		} else {
+			done <- input.Input.Gather(acc)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			return
+		}
+		if ctx.Err() == nil {
+			acc.AddError(err)
+			return
+		}
+	case <-ctx.Done():
+	case <-shutdown:
+		return
+	}
+	input.GatherTimeouts.Incr(1)
+	acc.AddError(fmt.Errorf("took longer to collect than gather_timeout (%s)", timeout))
+}
+
 // gatherWithTimeout gathers from the given input, with the given timeout.
 //   when the given timeout is reached, gatherWithTimeout logs an error message
 //   but continues waiting for it to return. This is to avoid leaving behind
@@ -176,7 +592,7 @@ func gatherWithTimeout(
 func (a *Agent) Test() error {
 	shutdown := make(chan struct{})
 	defer close(shutdown)
-	metricC := make(chan telegraf.Metric)
+	metricC := make(chan []telegraf.Metric)
 
 	// dummy receiver for the point channel
 	go func() {
@@ -197,8 +613,8 @@ func (a *Agent) Test() error {
 			continue
 		}
 
-		acc := NewAccumulator(input, metricC)
-		acc.SetPrecision(a.Config.Agent.Precision.Duration,
+		acc := a.newAccumulator(input, metricC)
+		acc.SetPrecision(a.precision(input),
 			a.Config.Agent.Interval.Duration)
 		input.SetTrace(true)
 		input.SetDefaultTags(a.Config.Tags)
@@ -235,39 +651,83 @@ func (a *Agent) flush() {
 	for _, o := range a.Config.Outputs {
 		go func(output *models.RunningOutput) {
 			defer wg.Done()
-			err := output.Write()
-			if err != nil {
-				log.Printf("E! Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
-			}
+			writeOutput(output)
 		}(o)
 	}
 
 	wg.Wait()
 }
 
+func writeOutput(output *models.RunningOutput) {
+	err := output.Write()
+	if err != nil {
+		log.Printf("E! Error writing to output [%s]: %s\n",
+			output.LogName(), err.Error())
+	}
+}
+
+// flushIntervals returns the flush interval and jitter of the output, those
+// of the agent unless the output overrides them.
+func (a *Agent) flushIntervals(output *models.RunningOutput) (time.Duration, time.Duration) {
+	interval, jitter := a.Config.Agent.FlushInterval.Duration, a.Config.Agent.FlushJitter.Duration
+	if output.Config.FlushInterval > 0 {
+		interval = output.Config.FlushInterval
+	}
+	if output.Config.FlushJitter > 0 {
+		jitter = output.Config.FlushJitter
+	}
+	return interval, jitter
+}
+
+// flushOutput writes the output every flush interval until shutdown, a flush
+// is skipped while the previous one is ongoing.
+func (a *Agent) flushOutput(shutdown chan struct{}, output *models.RunningOutput) {
+	interval, jitter := a.flushIntervals(output)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	semaphore := make(chan struct{}, 1)
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+			go func() {
+				select {
+				case semaphore <- struct{}{}:
+					internal.RandomSleep(jitter, shutdown)
+					writeOutput(output)
+					<-semaphore
+				default:
+					// skipping this flush because one is already happening
+					log.Printf("W! Skipping a scheduled flush of output [%s] because"+
+						" there is already a flush ongoing.", output.LogName())
+				}
+			}()
+		}
+	}
+}
+
 // flusher monitors the metrics input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) error {
+func (a *Agent) flusher(
+	shutdown chan struct{},
+	metricC chan []telegraf.Metric,
+	aggC chan []telegraf.Metric,
+	services []*models.RunningInput,
+) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 300)
 
 	// create an output metric channel and a gorouting that continuously passes
-	// each metric onto the output plugins & aggregators.
-	outMetricC := make(chan telegraf.Metric, 100)
+	// each batch of metrics onto the output plugins & aggregators.
+	outMetricC := make(chan []telegraf.Metric, 100)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for {
-			select {
-			case <-shutdown:
-				if len(outMetricC) > 0 {
-					// keep going until outMetricC is flushed
-					continue
-				}
-				return
-			case m := <-outMetricC:
+		// outMetricC is closed once the service inputs are stopped
+		for metrics := range outMetricC {
+			for _, m := range metrics {
 				// if dropOriginal is set to true, then we will only send this
 				// metric to the aggregators, not the outputs.
 				var dropOriginal bool
@@ -276,7 +736,9 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 						dropOriginal = true
 					}
 				}
-				if !dropOriginal {
+				if dropOriginal || len(a.Config.Outputs) == 0 {
+					m.Drop()
+				} else {
 					for i, o := range a.Config.Outputs {
 						if i == len(a.Config.Outputs)-1 {
 							o.AddMetric(m)
@@ -300,8 +762,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 					continue
 				}
 				return
-			case metric := <-aggC:
-				metrics := []telegraf.Metric{metric}
+			case metrics := <-aggC:
 				for _, processor := range a.Config.Processors {
 					metrics = processor.Apply(metrics...)
 				}
@@ -318,39 +779,262 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 		}
 	}()
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
-	semaphore := make(chan struct{}, 1)
+	process := func(metrics []telegraf.Metric) {
+		// NOTE potential bottleneck here as we put each batch through the
+		// processors serially.
+		for _, processor := range a.Config.Processors {
+			metrics = processor.Apply(metrics...)
+		}
+		if len(metrics) > 0 {
+			outMetricC <- metrics
+		}
+	}
+
+	for _, o := range a.Config.Outputs {
+		wg.Add(1)
+		go func(output *models.RunningOutput) {
+			defer wg.Done()
+			a.flushOutput(shutdown, output)
+		}(o)
+	}
+
 	for {
 		select {
 		case <-shutdown:
 			log.Println("I! Hang on, flushing any cached metrics before shutdown")
+			// outputs blocking on a full buffer would never let outMetricC
+			// get flushed
+			for _, o := range a.Config.Outputs {
+				o.Unblock()
+			}
+			// the service inputs may add the metrics of the messages they
+			// read while they stop, they are flushed too
+			stopServices(a.stoppers(services), metricC, process)
+			close(outMetricC)
 			// wait for outMetricC to get flushed before flushing outputs
 			wg.Wait()
 			a.flush()
 			return nil
-		case <-ticker.C:
-			go func() {
+		case metrics := <-metricC:
+			process(metrics)
+		}
+	}
+}
+
+// stoppers returns the service inputs stopped at shutdown, the inputs kept
+// for the next agent are detached instead.
+func (a *Agent) stoppers(services []*models.RunningInput) []stopper {
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+	stoppers := make([]stopper, 0, len(services))
+	for _, input := range services {
+		if r, ok := a.retries[input]; ok {
+			stoppers = append(stoppers, a.retryStopper(input, r))
+		} else if a.kept[input] {
+			stoppers = append(stoppers, stopFunc(a.services[input].detach))
+		} else {
+			stoppers = append(stoppers, input.Input.(telegraf.ServiceInput))
+		}
+	}
+	return stoppers
+}
+
+type stopper interface {
+	Stop()
+}
+
+type stopFunc func()
+
+func (f stopFunc) Stop() {
+	f()
+}
+
+// stopServices stops the service inputs and processes the metrics they add
+// until they are stopped.
+func stopServices(
+	services []stopper,
+	metricC chan []telegraf.Metric,
+	process func([]telegraf.Metric),
+) {
+	stopped := make(chan struct{})
+	go func() {
+		for _, s := range services {
+			s.Stop()
+		}
+		close(stopped)
+	}()
+	for {
+		select {
+		case <-stopped:
+			for {
 				select {
-				case semaphore <- struct{}{}:
-					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
-					a.flush()
-					<-semaphore
+				case metrics := <-metricC:
+					process(metrics)
 				default:
-					// skipping this flush because one is already happening
-					log.Println("W! Skipping a scheduled flush because there is" +
-						" already a flush ongoing.")
+					return
 				}
-			}()
-		case metric := <-metricC:
-			// NOTE potential bottleneck here as we put each metric through the
-			// processors serially.
-			mS := []telegraf.Metric{metric}
-			for _, processor := range a.Config.Processors {
-				mS = processor.Apply(mS...)
 			}
-			for _, m := range mS {
-				outMetricC <- m
+		case metrics := <-metricC:
+			process(metrics)
+		}
+	}
+}
+
+// startServices starts the service inputs, the inputs kept running by the
+// previous agent are attached instead.
+func (a *Agent) startServices(metricC chan []telegraf.Metric) ([]*models.RunningInput, error) {
+	var services []*models.RunningInput
+	for _, input := range a.Config.Inputs {
+		input.SetDefaultTags(a.Config.Tags)
+		switch p := input.Input.(type) {
+		case telegraf.ServiceInput:
+			acc := a.newAccumulator(input, metricC)
+			// Service input plugins should set their own precision of their
+			// metrics, unless the input overrides it.
+			acc.SetPrecision(time.Nanosecond, 0)
+			if input.Config.Precision != 0 {
+				acc.SetPrecision(input.Config.Precision, 0)
+			}
+			a.servicesMu.Lock()
+			sa, ok := a.adopted[input]
+			if ok {
+				sa.attach(acc)
+			} else {
+				sa = newServiceAccumulator(acc)
+			}
+			a.services[input] = sa
+			a.servicesMu.Unlock()
+			if !ok {
+				if err := p.Start(sa); err != nil {
+					a.servicesMu.Lock()
+					delete(a.services, input)
+					a.servicesMu.Unlock()
+
+					switch input.Config.StartupErrorBehavior {
+					case models.StartupErrorIgnore:
+						log.Printf("E! Service for input %s failed to start, ignoring it\n%s\n",
+							input.LogName(), err.Error())
+						continue
+					case models.StartupErrorRetry:
+						log.Printf("E! Service for input %s failed to start, retrying every %s\n%s\n",
+							input.LogName(), a.interval(input), err.Error())
+						a.retryStart(input, sa)
+					default:
+						log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
+							input.LogName(), err.Error())
+						for _, s := range a.stoppers(services) {
+							s.Stop()
+						}
+						return nil, err
+					}
+				}
 			}
+			services = append(services, input)
+		}
+	}
+	return services, nil
+}
+
+// startRetry retries to start a service input until it starts or the retries
+// are stopped.
+type startRetry struct {
+	stop    chan struct{}
+	done    chan struct{}
+	started bool
+}
+
+// retryStart starts the service input again every interval of the input in
+// the background, it joins the running services once started.
+func (a *Agent) retryStart(input *models.RunningInput, sa *serviceAccumulator) {
+	r := &startRetry{stop: make(chan struct{}), done: make(chan struct{})}
+	a.servicesMu.Lock()
+	a.retries[input] = r
+	a.servicesMu.Unlock()
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(a.interval(input))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+			if err := input.Input.(telegraf.ServiceInput).Start(sa); err != nil {
+				log.Printf("W! Service for input %s failed to start again: %s", input.LogName(), err)
+				continue
+			}
+			log.Printf("I! Service for input %s started", input.LogName())
+			a.servicesMu.Lock()
+			a.services[input] = sa
+			r.started = true
+			a.servicesMu.Unlock()
+			return
+		}
+	}()
+}
+
+// retryStopper returns the stopper of an input started in the background,
+// the retries are stopped and the input stopped once started, or detached if
+// it is kept for the next agent.
+func (a *Agent) retryStopper(input *models.RunningInput, r *startRetry) stopper {
+	return stopFunc(func() {
+		close(r.stop)
+		<-r.done
+		a.servicesMu.Lock()
+		started, kept, sa := r.started, a.kept[input], a.services[input]
+		a.servicesMu.Unlock()
+		switch {
+		case !started:
+		case kept:
+			sa.detach()
+		default:
+			input.Input.(telegraf.ServiceInput).Stop()
+		}
+	})
+}
+
+// started reports whether the input is gathered, the service inputs are
+// not until they are started.
+func (a *Agent) started(input *models.RunningInput) bool {
+	if _, ok := input.Input.(telegraf.ServiceInput); !ok {
+		return true
+	}
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+	_, ok := a.services[input]
+	return ok
+}
+
+// startProcessors starts the service processors, those already started are
+// stopped if one fails to start.
+func (a *Agent) startProcessors() error {
+	var started []telegraf.ServiceProcessor
+	for _, processor := range a.Config.Processors {
+		p, ok := processor.Processor.(telegraf.ServiceProcessor)
+		if !ok {
+			continue
+		}
+		if err := p.Start(); err != nil {
+			log.Printf("E! Service for processor %s failed to start, exiting\n%s\n",
+				processor.LogName(), err.Error())
+			for _, s := range started {
+				s.Stop()
+			}
+			return err
+		}
+		started = append(started, p)
+	}
+	return nil
+}
+
+// stopProcessors stops the service processors once no more metrics are
+// applied.
+func (a *Agent) stopProcessors() {
+	for _, processor := range a.Config.Processors {
+		if p, ok := processor.Processor.(telegraf.ServiceProcessor); ok {
+			p.Stop()
 		}
 	}
 }
@@ -365,37 +1049,92 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
 	// channel shared between all input threads for accumulating metrics
-	metricC := make(chan telegraf.Metric, 100)
-	aggC := make(chan telegraf.Metric, 100)
+	metricC := make(chan []telegraf.Metric, 100)
+	aggC := make(chan []telegraf.Metric, 100)
 
-	// Start all ServicePlugins
-	for _, input := range a.Config.Inputs {
-		input.SetDefaultTags(a.Config.Tags)
-		switch p := input.Input.(type) {
-		case telegraf.ServiceInput:
-			acc := NewAccumulator(input, metricC)
-			// Service input plugins should set their own precision of their
-			// metrics.
-			acc.SetPrecision(time.Nanosecond, 0)
-			if err := p.Start(acc); err != nil {
-				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
-				return err
-			}
-			defer p.Stop()
+	if a.elector != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.elector.Run(shutdown)
+		}()
+	}
+
+	for _, d := range a.Config.Discoveries {
+		if d.RefreshInterval <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(d *discovery.Discovery) {
+			defer wg.Done()
+			a.watchDiscovery(shutdown, d)
+		}(d)
+	}
+
+	if interval := a.Config.SecretsRefreshInterval; interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.watchSecrets(shutdown, interval)
+		}()
+	}
+
+	// the health service is bound first so that nothing is left running if
+	// its address is in use
+	var health *http.Server
+	if a.Config.Agent.HealthServiceAddress != "" {
+		srv, err := a.startHealth()
+		if err != nil {
+			return err
 		}
+		health = srv
+	}
+
+	if err := a.startProcessors(); err != nil {
+		if health != nil {
+			health.Close()
+		}
+		return err
+	}
+
+	// Start all ServicePlugins, the flusher stops them at shutdown
+	services, err := a.startServices(metricC)
+	if err != nil {
+		a.stopProcessors()
+		if health != nil {
+			health.Close()
+		}
+		return err
+	}
+
+	if health != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-shutdown
+			health.Close()
+		}()
+	}
+
+	if a.Config.Agent.BufferHighWatermark > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.watchBuffers(shutdown)
+		}()
 	}
 
 	// Round collection to nearest interval by sleeping
+	start := time.Now()
 	if a.Config.Agent.RoundInterval {
-		i := int64(a.Config.Agent.Interval.Duration)
-		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
+		start = alignTime(start, a.Config.Agent.Interval.Duration)
+		time.Sleep(time.Until(start))
 	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := a.flusher(shutdown, metricC, aggC); err != nil {
+		if err := a.flusher(shutdown, metricC, aggC, services); err != nil {
 			log.Printf("E! Flusher routine failed, exiting: %s\n", err.Error())
 			close(shutdown)
 		}
@@ -405,7 +1144,8 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	for _, aggregator := range a.Config.Aggregators {
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
-			acc := NewAccumulator(agg, aggC)
+			acc := newAccumulator(agg, aggC)
+			acc.truncate = a.Config.Agent.PrecisionRounding == PrecisionTruncate
 			acc.SetPrecision(a.Config.Agent.Precision.Duration,
 				a.Config.Agent.Interval.Duration)
 			agg.Run(acc, shutdown)
@@ -414,18 +1154,14 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 
 	wg.Add(len(a.Config.Inputs))
 	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		// overwrite global interval if this plugin has it's own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
 		go func(in *models.RunningInput, interv time.Duration) {
 			defer wg.Done()
-			a.gatherer(shutdown, in, interv, metricC)
-		}(input, interval)
+			a.gatherer(shutdown, in, interv, start, metricC)
+		}(input, a.interval(input))
 	}
 
 	wg.Wait()
+	a.stopProcessors()
 	a.Close()
 	return nil
 }
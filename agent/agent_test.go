@@ -1,9 +1,25 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/discovery"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/store"
+	"github.com/influxdata/telegraf/testutil"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -11,6 +27,7 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAgent_OmitHostname(t *testing.T) {
@@ -21,6 +38,29 @@ func TestAgent_OmitHostname(t *testing.T) {
 	assert.NotContains(t, c.Tags, "host")
 }
 
+func TestAgent_MetricOrdering(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.MetricOrdering = models.MetricOrderingTime
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{}, 0, 0),
+		models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{
+			MetricOrdering: models.MetricOrderingNone,
+		}, 0, 0))
+	_, err := NewAgent(c)
+	require.NoError(t, err)
+	assert.Equal(t, models.MetricOrderingTime, c.Outputs[0].Config.MetricOrdering)
+	assert.Equal(t, models.MetricOrderingNone, c.Outputs[1].Config.MetricOrdering)
+
+	c.Agent.MetricOrdering = "name"
+	_, err = NewAgent(c)
+	assert.EqualError(t, err, `unsupported metric_ordering "name", expected none or time`)
+
+	c.Agent.MetricOrdering = ""
+	c.Agent.PrecisionRounding = "floor"
+	_, err = NewAgent(c)
+	assert.EqualError(t, err, `invalid precision_rounding "floor", must be "round" or "truncate"`)
+}
+
 func TestAgent_LoadPlugin(t *testing.T) {
 	c := config.NewConfig()
 	c.InputFilters = []string{"mysql"}
@@ -109,3 +149,777 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+func TestAgent_InputPrecisionAndRoundInterval(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Precision.Duration = time.Second
+	c.Agent.RoundInterval = true
+	a, _ := NewAgent(c)
+
+	input := &models.RunningInput{Config: &models.InputConfig{}}
+	assert.Equal(t, time.Second, a.precision(input))
+	assert.True(t, a.roundInterval(input))
+
+	round := false
+	input.Config.Precision = time.Millisecond
+	input.Config.RoundInterval = &round
+	assert.Equal(t, time.Millisecond, a.precision(input))
+	assert.False(t, a.roundInterval(input))
+}
+
+func TestAgent_InputCollectionJitterAndOffset(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.CollectionJitter.Duration = 5 * time.Second
+	c.Agent.CollectionOffset.Duration = time.Second
+	a, _ := NewAgent(c)
+
+	input := &models.RunningInput{Config: &models.InputConfig{}}
+	assert.Equal(t, 5*time.Second, a.collectionJitter(input))
+	assert.Equal(t, time.Second, a.collectionOffset(input))
+
+	jitter, offset := time.Duration(0), 30*time.Second
+	input.Config.CollectionJitter = &jitter
+	input.Config.CollectionOffset = &offset
+	assert.Equal(t, time.Duration(0), a.collectionJitter(input))
+	assert.Equal(t, 30*time.Second, a.collectionOffset(input))
+}
+
+func TestAgent_InputGatherTimeout(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.GatherTimeout.Duration = 10 * time.Second
+	a, _ := NewAgent(c)
+
+	input := &models.RunningInput{Config: &models.InputConfig{}}
+	assert.Equal(t, 10*time.Second, a.gatherTimeout(input))
+
+	timeout := time.Duration(0)
+	input.Config.GatherTimeout = &timeout
+	assert.Equal(t, time.Duration(0), a.gatherTimeout(input))
+}
+
+// hungInput blocks in Gather until release is closed, and in GatherContext
+// until its context is done.
+type hungInput struct {
+	release  chan struct{}
+	returned chan struct{}
+}
+
+func (i *hungInput) SampleConfig() string { return "" }
+func (i *hungInput) Description() string  { return "" }
+func (i *hungInput) Gather(acc telegraf.Accumulator) error {
+	defer func() { i.returned <- struct{}{} }()
+	<-i.release
+	return nil
+}
+
+type hungContextInput struct {
+	hungInput
+}
+
+func (i *hungContextInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestGatherWithDeadline(t *testing.T) {
+	shutdown := make(chan struct{})
+	hung := &hungInput{release: make(chan struct{}), returned: make(chan struct{}, 2)}
+	input := models.NewRunningInput(hung, &models.InputConfig{Name: "hung"})
+	acc := &testutil.Accumulator{}
+	timeouts := input.GatherTimeouts.Get()
+
+	gatherWithDeadline(shutdown, input, acc, 10*time.Millisecond)
+	assert.Equal(t, timeouts+1, input.GatherTimeouts.Get())
+	require.Len(t, acc.Errors, 1)
+	assert.EqualError(t, acc.Errors[0], "took longer to collect than gather_timeout (10ms)")
+
+	// the input is skipped while its Gather is still running
+	gatherWithDeadline(shutdown, input, acc, 10*time.Millisecond)
+	assert.Equal(t, timeouts+1, input.GatherTimeouts.Get())
+	require.Len(t, acc.Errors, 2)
+	assert.EqualError(t, acc.Errors[1], "skipped, the previous gather has not returned yet")
+
+	close(hung.release)
+	<-hung.returned
+	require.True(t, input.StartGather())
+	input.EndGather()
+	gatherWithDeadline(shutdown, input, acc, time.Second)
+	assert.Equal(t, timeouts+1, input.GatherTimeouts.Get())
+	assert.Len(t, acc.Errors, 2)
+
+	input = models.NewRunningInput(&hungContextInput{}, &models.InputConfig{Name: "hung_context"})
+	acc = &testutil.Accumulator{}
+	timeouts = input.GatherTimeouts.Get()
+	gatherWithDeadline(shutdown, input, acc, 10*time.Millisecond)
+	assert.Equal(t, timeouts+1, input.GatherTimeouts.Get())
+	require.Len(t, acc.Errors, 1)
+	assert.EqualError(t, acc.Errors[0], "took longer to collect than gather_timeout (10ms)")
+}
+
+func TestAlignTime(t *testing.T) {
+	start := time.Unix(600, 0)
+	assert.Equal(t, start, alignTime(start, 10*time.Second))
+	assert.Equal(t, time.Unix(900, 0), alignTime(start, 7*time.Minute+30*time.Second))
+	assert.Equal(t, time.Unix(610, 0), alignTime(start.Add(time.Millisecond), 10*time.Second))
+}
+
+type statefulInput struct {
+	store telegraf.Store
+}
+
+func (i *statefulInput) SampleConfig() string                  { return "" }
+func (i *statefulInput) Description() string                   { return "" }
+func (i *statefulInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *statefulInput) SetStore(store telegraf.Store)         { i.store = store }
+
+func TestAgent_SetStores(t *testing.T) {
+	c := config.NewConfig()
+	first, second := &statefulInput{}, &statefulInput{}
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(first, &models.InputConfig{Name: "stateful"}),
+		models.NewRunningInput(second, &models.InputConfig{Name: "stateful"}),
+	}
+	_, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	// each instance has its own namespace
+	assert.NoError(t, first.store.Set("key", []byte("first")))
+	value, err := second.store.Get("key")
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	// the aliased instances keep their state when they are reordered
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c.Agent.StateFile = filepath.Join(dir, "state.json")
+	newAgent := func(aliases ...string) ([]*statefulInput, *Agent) {
+		c.Inputs = nil
+		var inputs []*statefulInput
+		for _, alias := range aliases {
+			input := &statefulInput{}
+			inputs = append(inputs, input)
+			c.Inputs = append(c.Inputs, models.NewRunningInput(input,
+				&models.InputConfig{Name: "stateful", Alias: alias}))
+		}
+		a, err := NewAgent(c)
+		require.NoError(t, err)
+		return inputs, a
+	}
+	inputs, a := newAgent("east", "west")
+	require.NoError(t, inputs[0].store.Set("key", []byte("east")))
+	require.NoError(t, inputs[1].store.Set("key", []byte("west")))
+	require.NoError(t, a.Close())
+
+	inputs, _ = newAgent("west", "east")
+	value, err = inputs[0].store.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, "west", string(value))
+	value, err = inputs[1].store.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, "east", string(value))
+}
+
+func TestAgent_OpenSpools(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spools")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := config.NewConfig()
+	c.Agent.MetricBufferDirectory = filepath.Join(dir, "buffer")
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{}, 0, 0),
+		models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{}, 0, 0),
+	}
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+	assert.NoError(t, a.Close())
+
+	// each instance has its own file
+	files, err := filepath.Glob(filepath.Join(dir, "buffer", "*.wal"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "buffer", "outputs.discard.2.wal"),
+		filepath.Join(dir, "buffer", "outputs.discard.wal"),
+	}, files)
+}
+
+type discardOutput struct{}
+
+func (o *discardOutput) Connect() error                        { return nil }
+func (o *discardOutput) Close() error                          { return nil }
+func (o *discardOutput) Description() string                   { return "" }
+func (o *discardOutput) SampleConfig() string                  { return "" }
+func (o *discardOutput) Write(metrics []telegraf.Metric) error { return nil }
+
+type fakeElector struct {
+	leader bool
+}
+
+func (e *fakeElector) Run(shutdown chan struct{}) {}
+func (e *fakeElector) IsLeader() bool             { return e.leader }
+
+func TestAgent_Leads(t *testing.T) {
+	c := config.NewConfig()
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+	assert.Nil(t, a.elector)
+
+	input := models.NewRunningInput(&statefulInput{}, &models.InputConfig{Name: "stateful"})
+	leaderOnly := models.NewRunningInput(&statefulInput{},
+		&models.InputConfig{Name: "stateful", LeaderOnly: true})
+
+	// without leader election the leader_only inputs are gathered
+	assert.True(t, a.leads(leaderOnly))
+
+	e := &fakeElector{}
+	a.elector = e
+	assert.True(t, a.leads(input))
+	assert.False(t, a.leads(leaderOnly))
+	e.leader = true
+	assert.True(t, a.leads(leaderOnly))
+
+	c.Agent.LeaderElectionKey = "telegraf/leader"
+	a, err = NewAgent(c)
+	assert.NoError(t, err)
+	assert.NotNil(t, a.elector)
+	assert.False(t, a.elector.IsLeader())
+}
+
+type fakeProvider struct {
+	sync.Mutex
+	targets []discovery.Target
+}
+
+func (p *fakeProvider) Discover() ([]discovery.Target, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.targets, nil
+}
+
+func TestAgent_WatchDiscovery(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	assert.NoError(t, err)
+
+	p := &fakeProvider{targets: []discovery.Target{{"address": "10.0.0.1"}}}
+	d, err := discovery.New("fake", p, "{{.address}}", time.Millisecond)
+	assert.NoError(t, err)
+	d.Rendered, err = d.Render()
+	assert.NoError(t, err)
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	go a.watchDiscovery(shutdown, d)
+
+	select {
+	case <-a.Reload():
+		t.Fatal("reloaded without new targets")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	p.Lock()
+	p.targets = append(p.targets, discovery.Target{"address": "10.0.0.2"})
+	p.Unlock()
+	select {
+	case <-a.Reload():
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded with new targets")
+	}
+}
+
+func TestAgent_WatchSecrets(t *testing.T) {
+	os.Setenv("TELEGRAF_SECRETS_DIR", "../internal/config/testdata/secrets")
+	os.Setenv("TELEGRAF_MEMCACHED_SERVER", "remote")
+	defer os.Unsetenv("TELEGRAF_SECRETS_DIR")
+	defer os.Unsetenv("TELEGRAF_MEMCACHED_SERVER")
+
+	c := config.NewConfig()
+	assert.NoError(t, c.LoadConfig("../internal/config/testdata/secrets.toml"))
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	go a.watchSecrets(shutdown, time.Millisecond)
+
+	select {
+	case <-a.Reload():
+		t.Fatal("reloaded without a changed secret")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	os.Setenv("TELEGRAF_MEMCACHED_SERVER", "rotated")
+	select {
+	case <-a.Reload():
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded with a changed secret")
+	}
+}
+
+// drainingInput adds a metric when it is stopped, like the messages a
+// consumer read before stopping.
+type drainingInput struct {
+	acc     telegraf.Accumulator
+	stopped bool
+}
+
+func (d *drainingInput) SampleConfig() string                  { return "" }
+func (d *drainingInput) Description() string                   { return "" }
+func (d *drainingInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (d *drainingInput) Start(acc telegraf.Accumulator) error {
+	d.acc = acc
+	return nil
+}
+func (d *drainingInput) Stop() {
+	// more metrics than metricC holds
+	for i := 0; i < 3; i++ {
+		d.acc.AddFields("drained", map[string]interface{}{"value": i}, nil)
+	}
+	d.stopped = true
+}
+
+func TestStopServices(t *testing.T) {
+	metricC := make(chan []telegraf.Metric, 1)
+	input := &drainingInput{}
+	input.Start(NewAccumulator(models.NewRunningInput(input, &models.InputConfig{Name: "draining"}), metricC))
+
+	var processed int
+	stopServices([]stopper{input}, metricC, func(metrics []telegraf.Metric) {
+		processed += len(metrics)
+	})
+	assert.True(t, input.stopped)
+	assert.Equal(t, 3, processed)
+}
+
+// countingInput counts the times it is started and stopped, the first fails
+// starts fail.
+type countingInput struct {
+	acc    telegraf.Accumulator
+	starts int
+	stops  int
+	fails  int
+}
+
+func (c *countingInput) SampleConfig() string                  { return "" }
+func (c *countingInput) Description() string                   { return "" }
+func (c *countingInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (c *countingInput) Start(acc telegraf.Accumulator) error {
+	c.starts++
+	if c.fails > 0 {
+		c.fails--
+		return errors.New("connection refused")
+	}
+	c.acc = acc
+	return nil
+}
+func (c *countingInput) Stop() {
+	c.stops++
+}
+
+func newCountingInput(fingerprint string) *models.RunningInput {
+	input := models.NewRunningInput(&countingInput{}, &models.InputConfig{Name: "counting"})
+	input.Fingerprint = fingerprint
+	return input
+}
+
+func TestAgent_KeepServices(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = []*models.RunningInput{newCountingInput("kept"), newCountingInput("removed")}
+	prev, err := NewAgent(c)
+	require.NoError(t, err)
+	metricC := make(chan []telegraf.Metric, 10)
+	services, err := prev.startServices(metricC)
+	require.NoError(t, err)
+	kept := c.Inputs[0].Input.(*countingInput)
+	removed := c.Inputs[1].Input.(*countingInput)
+
+	next := config.NewConfig()
+	next.Inputs = []*models.RunningInput{newCountingInput("kept"), newCountingInput("added")}
+	keptServices := prev.KeepServices(next)
+	assert.Equal(t, []string{"inputs.counting"}, keptServices.Names())
+	assert.True(t, next.Inputs[0].Input == kept)
+
+	stopServices(prev.stoppers(services), metricC, func([]telegraf.Metric) {})
+	assert.Equal(t, 0, kept.stops)
+	assert.Equal(t, 1, removed.stops)
+
+	// the kept input waits for the next agent
+	added := make(chan struct{})
+	go func() {
+		kept.acc.AddFields("kept", map[string]interface{}{"value": 1}, nil)
+		close(added)
+	}()
+	select {
+	case <-added:
+		t.Fatal("metric added while the input is detached")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ag, err := NewAgent(next)
+	require.NoError(t, err)
+	ag.AdoptServices(keptServices)
+	nextC := make(chan []telegraf.Metric, 10)
+	_, err = ag.startServices(nextC)
+	require.NoError(t, err)
+	<-added
+	assert.Equal(t, 1, kept.starts)
+	assert.Equal(t, 1, next.Inputs[1].Input.(*countingInput).starts)
+	metrics := <-nextC
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "kept", metrics[0].Name())
+	assert.Len(t, metricC, 0)
+}
+
+// statefulService is a service input keeping state, it counts the times
+// its store is set.
+type statefulService struct {
+	countingInput
+	store  telegraf.Store
+	stores int
+}
+
+func (s *statefulService) SetStore(store telegraf.Store) {
+	s.store = store
+	s.stores++
+}
+
+func TestAgent_KeepStatefulServices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	newConfig := func() *config.Config {
+		c := config.NewConfig()
+		c.Agent.StateFile = filepath.Join(dir, "state.json")
+		input := models.NewRunningInput(&statefulService{}, &models.InputConfig{Name: "stateful"})
+		input.Fingerprint = "kept"
+		c.Inputs = []*models.RunningInput{input}
+		return c
+	}
+
+	c := newConfig()
+	prev, err := NewAgent(c)
+	require.NoError(t, err)
+	metricC := make(chan []telegraf.Metric, 10)
+	services, err := prev.startServices(metricC)
+	require.NoError(t, err)
+	kept := c.Inputs[0].Input.(*statefulService)
+	require.NoError(t, kept.store.Set("offset", []byte("1")))
+
+	next := newConfig()
+	assert.Equal(t, []string{"inputs.stateful"}, prev.KeepServices(next).Names())
+	stopServices(prev.stoppers(services), metricC, func([]telegraf.Metric) {})
+	require.NoError(t, prev.Close())
+	// the input keeps running while the config is reloaded
+	require.NoError(t, kept.store.Set("offset", []byte("2")))
+
+	ag, err := NewAgent(next)
+	require.NoError(t, err)
+	assert.True(t, next.Inputs[0].Input == kept)
+	assert.Equal(t, 0, kept.stops)
+	assert.Equal(t, 1, kept.stores)
+	require.NoError(t, kept.store.Set("reloaded", []byte("true")))
+	require.NoError(t, ag.Close())
+
+	db, err := store.Open(filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	ns := db.Namespace("inputs.stateful")
+	value, err := ns.Get("offset")
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(value))
+	value, err = ns.Get("reloaded")
+	require.NoError(t, err)
+	assert.Equal(t, "true", string(value))
+}
+
+func TestAgent_StartupErrorBehavior(t *testing.T) {
+	newInput := func(behavior string, fails int) *models.RunningInput {
+		return models.NewRunningInput(&countingInput{fails: fails}, &models.InputConfig{
+			Name:                 "counting",
+			Interval:             10 * time.Millisecond,
+			StartupErrorBehavior: behavior,
+		})
+	}
+	metricC := make(chan []telegraf.Metric, 10)
+
+	// the started inputs are stopped when one fails to start
+	c := config.NewConfig()
+	c.Inputs = []*models.RunningInput{newInput("", 0), newInput(models.StartupErrorError, 1)}
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	_, err = a.startServices(metricC)
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 1, c.Inputs[0].Input.(*countingInput).stops)
+
+	c = config.NewConfig()
+	c.Inputs = []*models.RunningInput{newInput(models.StartupErrorIgnore, 1), newInput(models.StartupErrorRetry, 2)}
+	a, err = NewAgent(c)
+	require.NoError(t, err)
+	services, err := a.startServices(metricC)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.RunningInput{c.Inputs[1]}, services)
+	assert.False(t, a.started(c.Inputs[0]))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !a.started(c.Inputs[1]) {
+		require.True(t, time.Now().Before(deadline), "input not started again")
+		time.Sleep(5 * time.Millisecond)
+	}
+	stopServices(a.stoppers(services), metricC, func([]telegraf.Metric) {})
+	retried := c.Inputs[1].Input.(*countingInput)
+	assert.Equal(t, 3, retried.starts)
+	assert.Equal(t, 1, retried.stops)
+	assert.Equal(t, 0, c.Inputs[0].Input.(*countingInput).stops)
+}
+
+// pausableInput records whether it is paused.
+type pausableInput struct {
+	countingInput
+	paused bool
+}
+
+func (p *pausableInput) Pause()  { p.paused = true }
+func (p *pausableInput) Resume() { p.paused = false }
+
+func TestAgent_Backpressure(t *testing.T) {
+	full := models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{}, 0, 10)
+	empty := models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{}, 0, 10)
+	c := config.NewConfig()
+	c.Outputs = append(c.Outputs, empty, full)
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&pausableInput{}, &models.InputConfig{Name: "pausable"}),
+		newCountingInput(""),
+	}
+	c.Agent.BufferHighWatermark = 0.8
+	c.Agent.BufferLowWatermark = 0.5
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	_, err = a.startServices(make(chan []telegraf.Metric, 10))
+	require.NoError(t, err)
+	input := c.Inputs[0].Input.(*pausableInput)
+
+	b := a.newBackpressure()
+	add := func(n int) {
+		for i := 0; i < n; i++ {
+			full.AddMetric(testutil.TestMetric(i))
+		}
+	}
+	update := func() bool {
+		output, changed := b.update()
+		if changed && b.paused {
+			assert.True(t, output == full)
+		}
+		if changed {
+			a.pauseServices(b.paused, false)
+		}
+		return changed
+	}
+
+	add(8)
+	assert.False(t, update())
+	add(1)
+	assert.True(t, update())
+	assert.True(t, input.paused)
+
+	// the inputs stay paused down to the low watermark
+	require.NoError(t, full.Write())
+	add(6)
+	assert.False(t, update())
+	assert.True(t, input.paused)
+	require.NoError(t, full.Write())
+	add(5)
+	assert.True(t, update())
+	assert.False(t, input.paused)
+}
+
+func TestAgent_FlushIntervals(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.FlushInterval.Duration = 10 * time.Second
+	c.Agent.FlushJitter.Duration = time.Second
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	output := models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{}, 0, 0)
+	interval, jitter := a.flushIntervals(output)
+	assert.Equal(t, 10*time.Second, interval)
+	assert.Equal(t, time.Second, jitter)
+
+	output = models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{
+		FlushInterval: 30 * time.Second,
+	}, 0, 0)
+	interval, jitter = a.flushIntervals(output)
+	assert.Equal(t, 30*time.Second, interval)
+	assert.Equal(t, time.Second, jitter)
+}
+
+func TestAgent_Health(t *testing.T) {
+	output := models.NewRunningOutput("discard", &discardOutput{}, &models.OutputConfig{}, 0, 10)
+	for i := 0; i < 9; i++ {
+		output.AddMetric(testutil.TestMetric(i))
+	}
+	c := config.NewConfig()
+	c.Outputs = append(c.Outputs, output)
+	c.Agent.HealthMaxBufferFullness = 0.8
+	c.Agent.HealthMaxWriteAge.Duration = 5 * time.Minute
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	h := a.newHealth()
+	now := h.started.Add(10 * time.Minute)
+	h.now = func() time.Time { return now }
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	code, body := get("/live")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"status":"ok"}`, body)
+
+	code, body = get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, `{"status":"failing","checks":[`+
+		`{"output":"discard","check":"buffer_fullness","ok":false,"message":"buffer 90% full, above 80%"},`+
+		`{"output":"discard","check":"write_age","ok":false,"message":"no successful write for 10m0s with 9 metrics buffered"}]}`, body)
+
+	require.NoError(t, output.Write())
+	now = time.Now()
+	code, _ = get("/ready")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = get("/metrics")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// Test that no plugin is started if the health service address is in use
+func TestAgent_HealthAddressInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	var calls []string
+	input := &countingInput{}
+	c := config.NewConfig()
+	c.Agent.HealthServiceAddress = listener.Addr().String()
+	c.Inputs = []*models.RunningInput{models.NewRunningInput(input, &models.InputConfig{Name: "counting"})}
+	c.Processors = models.RunningProcessors{{
+		Name:      "a",
+		Processor: &serviceProcessor{name: "a", started: &calls},
+		Config:    &models.ProcessorConfig{Name: "a"},
+	}}
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	err = a.Run(make(chan struct{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "health service:")
+	assert.Empty(t, calls)
+	assert.Equal(t, 0, input.starts)
+}
+
+type serviceProcessor struct {
+	name    string
+	err     error
+	started *[]string
+}
+
+func (p *serviceProcessor) SampleConfig() string { return "" }
+func (p *serviceProcessor) Description() string  { return "" }
+func (p *serviceProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	return in
+}
+func (p *serviceProcessor) Start() error {
+	if p.err != nil {
+		return p.err
+	}
+	*p.started = append(*p.started, "start "+p.name)
+	return nil
+}
+func (p *serviceProcessor) Stop() {
+	*p.started = append(*p.started, "stop "+p.name)
+}
+
+func TestAgent_ServiceProcessors(t *testing.T) {
+	var calls []string
+	processor := func(name string, err error) *models.RunningProcessor {
+		return &models.RunningProcessor{
+			Name:      name,
+			Processor: &serviceProcessor{name: name, err: err, started: &calls},
+			Config:    &models.ProcessorConfig{Name: name},
+		}
+	}
+
+	c := config.NewConfig()
+	c.Processors = models.RunningProcessors{processor("a", nil), processor("b", nil)}
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.startProcessors())
+	a.stopProcessors()
+	assert.Equal(t, []string{"start a", "start b", "stop a", "stop b"}, calls)
+
+	// the processors already started are stopped when one fails to start
+	calls = nil
+	c.Processors = models.RunningProcessors{processor("a", nil), processor("b", assert.AnError)}
+	assert.Equal(t, assert.AnError, a.startProcessors())
+	assert.Equal(t, []string{"start a", "stop a"}, calls)
+}
+
+// onceInput adds a metric and fails if err is set.
+type onceInput struct {
+	err error
+}
+
+func (i *onceInput) SampleConfig() string { return "" }
+func (i *onceInput) Description() string  { return "" }
+func (i *onceInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("once", map[string]interface{}{"value": 1}, nil)
+	return i.err
+}
+
+// recordingOutput keeps the metrics written, it fails if err is set.
+type recordingOutput struct {
+	discardOutput
+	err     error
+	written int
+}
+
+func (o *recordingOutput) Write(metrics []telegraf.Metric) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.written += len(metrics)
+	return nil
+}
+
+func TestAgent_Once(t *testing.T) {
+	run := func(inputErr, outputErr error) (*recordingOutput, error) {
+		output := &recordingOutput{err: outputErr}
+		c := config.NewConfig()
+		c.Agent.OmitHostname = true
+		c.Inputs = []*models.RunningInput{
+			models.NewRunningInput(&onceInput{}, &models.InputConfig{Name: "a"}),
+			models.NewRunningInput(&onceInput{err: inputErr}, &models.InputConfig{Name: "b"}),
+			newCountingInput(""),
+		}
+		c.Outputs = append(c.Outputs,
+			models.NewRunningOutput("recording", output, &models.OutputConfig{}, 0, 0))
+		a, err := NewAgent(c)
+		require.NoError(t, err)
+		err = a.Once()
+		service := c.Inputs[2].Input.(*countingInput)
+		assert.Equal(t, 1, service.starts)
+		assert.Equal(t, 1, service.stops)
+		return output, err
+	}
+
+	output, err := run(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, output.written)
+
+	output, err = run(errors.New("gather failed"), nil)
+	assert.EqualError(t, err, "1 of 3 inputs failed to gather and 0 of 1 outputs failed to write")
+	assert.Equal(t, 2, output.written)
+
+	_, err = run(nil, errors.New("write failed"))
+	assert.EqualError(t, err, "0 of 3 inputs failed to gather and 1 of 1 outputs failed to write")
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// backpressureInterval is how often the buffers of the outputs are compared
+// to the watermarks.
+const backpressureInterval = 100 * time.Millisecond
+
+// backpressure tells when to pause the service inputs: once the buffer of an
+// output is fuller than the high watermark, until the buffers of all the
+// outputs are at most at the low watermark.
+type backpressure struct {
+	outputs []*models.RunningOutput
+	high    float64
+	low     float64
+	paused  bool
+}
+
+func (a *Agent) newBackpressure() *backpressure {
+	return &backpressure{
+		outputs: a.Config.Outputs,
+		high:    a.Config.Agent.BufferHighWatermark,
+		low:     a.Config.Agent.BufferLowWatermark,
+	}
+}
+
+// update compares the buffers to the watermarks, it returns the output
+// pausing the inputs when they are paused and whether they are paused or
+// resumed.
+func (b *backpressure) update() (*models.RunningOutput, bool) {
+	var fullest *models.RunningOutput
+	max := 0.0
+	for _, output := range b.outputs {
+		fullness := float64(output.BufferLen()) / float64(output.MetricBufferLimit)
+		if fullest == nil || fullness > max {
+			fullest, max = output, fullness
+		}
+	}
+	switch {
+	case !b.paused && max > b.high:
+		b.paused = true
+		return fullest, true
+	case b.paused && max <= b.low:
+		b.paused = false
+		return nil, true
+	}
+	return nil, false
+}
+
+// watchBuffers pauses and resumes the service inputs with the fullness of
+// the buffers of the outputs until shutdown. The inputs kept for the next
+// agent are resumed at shutdown, the next agent pauses them again.
+func (a *Agent) watchBuffers(shutdown chan struct{}) {
+	b := a.newBackpressure()
+	ticker := time.NewTicker(backpressureInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-shutdown:
+			if b.paused {
+				a.pauseServices(false, true)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		fullest, changed := b.update()
+		if changed && b.paused {
+			log.Printf("W! Output [%s] buffer is more than %.0f%% full, pausing the service inputs",
+				fullest.LogName(), b.high*100)
+		} else if changed {
+			log.Printf("I! Output buffers are at most %.0f%% full, resuming the service inputs", b.low*100)
+		}
+		// the inputs started again after failing to start are paused too
+		if changed || b.paused {
+			a.pauseServices(b.paused, false)
+		}
+	}
+}
+
+// pauseServices pauses or resumes the running service inputs that can be
+// paused, only those kept for the next agent if kept is set.
+func (a *Agent) pauseServices(paused, kept bool) {
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+	for input := range a.services {
+		p, ok := input.Input.(telegraf.PausableInput)
+		if !ok || (kept && !a.kept[input]) {
+			continue
+		}
+		if paused {
+			p.Pause()
+		} else {
+			p.Resume()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"bytes"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/discovery"
+)
+
+// Reload is closed when the configuration must be loaded again, like when
+// the discovered inputs changed.
+func (a *Agent) Reload() <-chan struct{} {
+	return a.reload
+}
+
+// watchDiscovery renders the discovery every refresh interval until it
+// differs from the rendered config, then the config is reloaded.
+func (a *Agent) watchDiscovery(shutdown chan struct{}, d *discovery.Discovery) {
+	ticker := time.NewTicker(d.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		rendered, err := d.Render()
+		if err != nil {
+			log.Printf("E! Discovery [%s] failed: %s", d.Name, err)
+			continue
+		}
+		if !bytes.Equal(rendered, d.Rendered) {
+			log.Printf("I! Discovery [%s] found new inputs", d.Name)
+			a.reloadOnce.Do(func() { close(a.reload) })
+			return
+		}
+	}
+}
+
+// watchSecrets reads the secrets referenced by the config again every
+// interval until one of them changed, then the config is reloaded.
+func (a *Agent) watchSecrets(shutdown chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		ref, err := a.Config.SecretsChanged()
+		if err != nil {
+			log.Printf("E! Reading the secrets failed: %s", err)
+			continue
+		}
+		if ref != "" {
+			log.Printf("I! Secret %s changed, reloading the config", ref)
+			a.reloadOnce.Do(func() { close(a.reload) })
+			return
+		}
+	}
+}
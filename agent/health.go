@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// healthCheck is the result of a check of an output.
+type healthCheck struct {
+	Output  string `json:"output"`
+	Check   string `json:"check"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// health serves the liveness and readiness of the agent: it is live while it
+// runs and ready while the checks of the outputs pass.
+//
+// The buffer check fails once the buffer of an output is fuller than
+// maxBufferFullness, the write check once an output with buffered metrics
+// did not write for maxWriteAge. They are disabled if they are 0.
+type health struct {
+	outputs           []*models.RunningOutput
+	maxBufferFullness float64
+	maxWriteAge       time.Duration
+	// started is the time the agent started, the write age of the outputs
+	// that did not write yet
+	started time.Time
+	now     func() time.Time
+}
+
+func (a *Agent) newHealth() *health {
+	return &health{
+		outputs:           a.Config.Outputs,
+		maxBufferFullness: a.Config.Agent.HealthMaxBufferFullness,
+		maxWriteAge:       a.Config.Agent.HealthMaxWriteAge.Duration,
+		started:           time.Now(),
+		now:               time.Now,
+	}
+}
+
+func (h *health) checks() []healthCheck {
+	var checks []healthCheck
+	for _, output := range h.outputs {
+		buffered := output.BufferLen()
+		if h.maxBufferFullness > 0 {
+			fullness := float64(buffered) / float64(output.MetricBufferLimit)
+			check := healthCheck{
+				Output: output.LogName(),
+				Check:  "buffer_fullness",
+				OK:     fullness <= h.maxBufferFullness,
+			}
+			if !check.OK {
+				check.Message = fmt.Sprintf("buffer %.0f%% full, above %.0f%%",
+					fullness*100, h.maxBufferFullness*100)
+			}
+			checks = append(checks, check)
+		}
+		if h.maxWriteAge > 0 {
+			last := output.LastWrite()
+			if last.Before(h.started) {
+				last = h.started
+			}
+			age := h.now().Sub(last)
+			check := healthCheck{
+				Output: output.LogName(),
+				Check:  "write_age",
+				OK:     buffered == 0 || age <= h.maxWriteAge,
+			}
+			if !check.OK {
+				check.Message = fmt.Sprintf("no successful write for %s with %d metrics buffered",
+					age-age%time.Second, buffered)
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+func (h *health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Status string        `json:"status"`
+		Checks []healthCheck `json:"checks,omitempty"`
+	}
+	body.Status = "ok"
+	switch r.URL.Path {
+	case "/live":
+	case "/ready":
+		body.Checks = h.checks()
+		for _, check := range body.Checks {
+			if !check.OK {
+				body.Status = "failing"
+			}
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if body.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// startHealth serves the health of the agent on the health_service_address
+// until the server returned is closed.
+func (a *Agent) startHealth() (*http.Server, error) {
+	listener, err := net.Listen("tcp", a.Config.Agent.HealthServiceAddress)
+	if err != nil {
+		return nil, fmt.Errorf("health service: %s", err)
+	}
+	srv := &http.Server{Handler: a.newHealth()}
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! Health service failed: %s", err)
+		}
+	}()
+	log.Printf("I! Serving the health of the agent on %s", listener.Addr())
+	return srv, nil
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// elector elects the leader of the telegraf instances sharing a key, the
+// inputs with leader_only are only gathered by the leader.
+type elector interface {
+	// Run campaigns for the leadership until shutdown.
+	Run(shutdown chan struct{})
+	IsLeader() bool
+}
+
+// consulElector is the leader while it holds the lock of its key in Consul,
+// the lock is released when its session expires, like when the instance dies.
+type consulElector struct {
+	client *api.Client
+	key    string
+	retry  time.Duration
+	leader int32
+}
+
+func newConsulElector(address, key string) (*consulElector, error) {
+	config := api.DefaultConfig()
+	if address != "" {
+		config.Address = address
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &consulElector{
+		client: client,
+		key:    key,
+		retry:  5 * time.Second,
+	}, nil
+}
+
+func (e *consulElector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+func (e *consulElector) Run(shutdown chan struct{}) {
+	hostname, _ := os.Hostname()
+	for {
+		lock, err := e.client.LockOpts(&api.LockOptions{
+			Key:         e.key,
+			Value:       []byte(hostname),
+			SessionName: "telegraf " + hostname,
+		})
+		if err == nil {
+			err = e.lead(lock, shutdown)
+		}
+		if err != nil {
+			log.Printf("E! Leader election on %s failed: %s", e.key, err)
+		}
+
+		select {
+		case <-shutdown:
+			return
+		case <-time.After(e.retry):
+		}
+	}
+}
+
+// lead waits for the lock and leads until it is lost or shutdown.
+func (e *consulElector) lead(lock *api.Lock, shutdown chan struct{}) error {
+	lost, err := lock.Lock(shutdown)
+	if err != nil || lost == nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	atomic.StoreInt32(&e.leader, 1)
+	log.Printf("I! Elected leader of %s", e.key)
+	select {
+	case <-lost:
+		log.Printf("W! Lost the leadership of %s", e.key)
+	case <-shutdown:
+	}
+	atomic.StoreInt32(&e.leader, 0)
+	return nil
+}
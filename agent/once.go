@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// Once gathers the inputs once, writes their metrics to the outputs and
+// stops. The service inputs are started and stopped after the gather, their
+// metrics are written too. It returns an error if an input failed to gather
+// or an output failed to write, the errors themselves are logged.
+func (a *Agent) Once() error {
+	if len(a.Config.Aggregators) > 0 {
+		log.Printf("W! The aggregators are not run in --once mode")
+	}
+	if err := a.startProcessors(); err != nil {
+		return err
+	}
+
+	var metrics []telegraf.Metric
+	process := func(in []telegraf.Metric) {
+		for _, processor := range a.Config.Processors {
+			in = processor.Apply(in...)
+		}
+		metrics = append(metrics, in...)
+	}
+
+	metricC := make(chan []telegraf.Metric, 100)
+	services, err := a.startServices(metricC)
+	if err != nil {
+		a.stopProcessors()
+		return err
+	}
+
+	gatherErrors := make(map[*models.RunningInput]int64, len(a.Config.Inputs))
+	for _, input := range a.Config.Inputs {
+		gatherErrors[input] = input.GatherErrors.Get()
+	}
+
+	collect := make(chan struct{})
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for {
+			select {
+			case in := <-metricC:
+				process(in)
+			case <-collect:
+				return
+			}
+		}
+	}()
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	var wg sync.WaitGroup
+	for _, input := range a.Config.Inputs {
+		if _, ok := input.Input.(telegraf.ServiceInput); ok || !a.leads(input) {
+			continue
+		}
+		wg.Add(1)
+		go func(input *models.RunningInput) {
+			defer wg.Done()
+			defer panicRecover(input)
+			acc := a.newAccumulator(input, metricC)
+			acc.SetPrecision(a.precision(input),
+				a.Config.Agent.Interval.Duration)
+			input.SetDefaultTags(a.Config.Tags)
+			a.gather(shutdown, input, acc, a.interval(input))
+		}(input)
+	}
+	wg.Wait()
+	close(collect)
+	<-collected
+	stopServices(a.stoppers(services), metricC, process)
+	a.stopProcessors()
+
+	var failedInputs int
+	for _, input := range a.Config.Inputs {
+		if input.GatherErrors.Get() > gatherErrors[input] {
+			failedInputs++
+		}
+	}
+
+	for _, m := range metrics {
+		if len(a.Config.Outputs) == 0 {
+			m.Drop()
+		}
+		for i, o := range a.Config.Outputs {
+			if i == len(a.Config.Outputs)-1 {
+				o.AddMetric(m)
+			} else {
+				o.AddMetric(m.Copy())
+			}
+		}
+	}
+	var failedOutputs int
+	for _, o := range a.Config.Outputs {
+		if err := o.Write(); err != nil {
+			log.Printf("E! Error writing to output [%s]: %s\n", o.LogName(), err)
+			failedOutputs++
+		}
+	}
+	a.Close()
+
+	if failedInputs > 0 || failedOutputs > 0 {
+		return fmt.Errorf("%d of %d inputs failed to gather and %d of %d outputs failed to write",
+			failedInputs, len(a.Config.Inputs), failedOutputs, len(a.Config.Outputs))
+	}
+	return nil
+}
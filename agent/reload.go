@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// serviceAccumulator is the accumulator of a running service input. The
+// input keeps it while the config is reloaded: it is detached from the agent
+// shutting down and attached to the agent of the new config, the input waits
+// in between.
+type serviceAccumulator struct {
+	mu       sync.Mutex
+	attached *sync.Cond
+	acc      *accumulator
+
+	// precision set by the input, it is set again on the accumulator of the
+	// next agent
+	precision, interval time.Duration
+	setPrecision        bool
+}
+
+func newServiceAccumulator(acc *accumulator) *serviceAccumulator {
+	sa := &serviceAccumulator{acc: acc}
+	sa.attached = sync.NewCond(&sa.mu)
+	return sa
+}
+
+// do calls f with the accumulator of the agent, it waits until the input is
+// attached to an agent. The agent may only detach the input once f returns.
+func (sa *serviceAccumulator) do(f func(acc *accumulator)) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	for sa.acc == nil {
+		sa.attached.Wait()
+	}
+	f(sa.acc)
+}
+
+// detach detaches the input from its agent, the metrics it adds wait for
+// the next agent.
+func (sa *serviceAccumulator) detach() {
+	sa.mu.Lock()
+	sa.acc = nil
+	sa.mu.Unlock()
+}
+
+// attach attaches the input to the accumulator of an agent.
+func (sa *serviceAccumulator) attach(acc *accumulator) {
+	sa.mu.Lock()
+	if sa.setPrecision {
+		acc.SetPrecision(sa.precision, sa.interval)
+	}
+	sa.acc = acc
+	sa.mu.Unlock()
+	sa.attached.Broadcast()
+}
+
+func (sa *serviceAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	sa.do(func(acc *accumulator) { acc.AddFields(measurement, fields, tags, t...) })
+}
+
+func (sa *serviceAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	sa.do(func(acc *accumulator) { acc.AddGauge(measurement, fields, tags, t...) })
+}
+
+func (sa *serviceAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	sa.do(func(acc *accumulator) { acc.AddCounter(measurement, fields, tags, t...) })
+}
+
+func (sa *serviceAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	sa.do(func(acc *accumulator) { acc.AddSummary(measurement, fields, tags, t...) })
+}
+
+func (sa *serviceAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	sa.do(func(acc *accumulator) { acc.AddHistogram(measurement, fields, tags, t...) })
+}
+
+func (sa *serviceAccumulator) AddMetrics(metrics []telegraf.Metric) {
+	sa.do(func(acc *accumulator) { acc.AddMetrics(metrics) })
+}
+
+func (sa *serviceAccumulator) AddError(err error) {
+	sa.do(func(acc *accumulator) { acc.AddError(err) })
+}
+
+func (sa *serviceAccumulator) SetPrecision(precision, interval time.Duration) {
+	sa.do(func(acc *accumulator) {
+		sa.precision, sa.interval, sa.setPrecision = precision, interval, true
+		acc.SetPrecision(precision, interval)
+	})
+}
+
+func (sa *serviceAccumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	if maxTracked < 1 {
+		maxTracked = 1
+	}
+	return &serviceTrackingAccumulator{
+		serviceAccumulator: sa,
+		delivered:          make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// serviceTrackingAccumulator keeps the delivery reports of the input across
+// reloads, the outputs of the previous agent report to the same channel.
+type serviceTrackingAccumulator struct {
+	*serviceAccumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *serviceTrackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	var id telegraf.TrackingID
+	a.do(func(acc *accumulator) {
+		t := &trackingAccumulator{accumulator: acc, delivered: a.delivered}
+		id = t.AddTrackingMetricGroup(group)
+	})
+	return id
+}
+
+func (a *serviceTrackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+// KeptServices are the service inputs kept running by an agent shutting down
+// for the agent of the reloaded config.
+type KeptServices struct {
+	accs map[*models.RunningInput]*serviceAccumulator
+}
+
+// Names returns the names of the inputs kept.
+func (k *KeptServices) Names() []string {
+	var names []string
+	for input := range k.accs {
+		names = append(names, input.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeepServices keeps the service inputs running when the agent shuts down if
+// the next config has an input configured the same, the input of the next
+// config is replaced by the running one. The agent of the next config adopts
+// them with AdoptServices. The inputs keeping state keep their namespace,
+// it is moved to the store of the next agent.
+func (a *Agent) KeepServices(next *config.Config) *KeptServices {
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+
+	kept := &KeptServices{accs: make(map[*models.RunningInput]*serviceAccumulator)}
+	used := make(map[*models.RunningInput]bool)
+	for _, input := range next.Inputs {
+		if input.Fingerprint == "" {
+			continue
+		}
+		for running, sa := range a.services {
+			if used[running] || running.Fingerprint != input.Fingerprint {
+				continue
+			}
+			used[running] = true
+			input.Input = running.Input
+			input.Store = running.Store
+			kept.accs[input] = sa
+			break
+		}
+	}
+	a.kept = used
+	return kept
+}
+
+// AdoptServices adopts the service inputs kept by the previous agent, they
+// are attached to the agent when it runs instead of being started.
+func (a *Agent) AdoptServices(kept *KeptServices) {
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+	a.adopted = kept.accs
+}
@@ -11,9 +11,11 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/logger"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/all"
 	"github.com/influxdata/telegraf/plugins/inputs"
@@ -31,7 +33,21 @@ var pprofAddr = flag.String("pprof-addr", "",
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fOnce = flag.Bool("once", false,
+	"gather metrics once, write them to the outputs, and exit with an error if any failed")
 var fConfig = flag.String("config", "", "configuration file to load")
+var fConfigPollInterval = flag.Duration("config-poll-interval", 0,
+	"interval of the checks of a remote config for changes, 0 disables them")
+var fConfigToken = flag.String("config-token", "",
+	"bearer token of a remote config, by default $TELEGRAF_CONFIG_TOKEN")
+var fConfigTLSCA = flag.String("config-tls-ca", "",
+	"CA file verifying the server of a remote config")
+var fConfigTLSCert = flag.String("config-tls-cert", "",
+	"client certificate file of a remote config")
+var fConfigTLSKey = flag.String("config-tls-key", "",
+	"client key file of a remote config")
+var fConfigInsecureSkipVerify = flag.Bool("config-insecure-skip-verify", false,
+	"do not verify the server certificate of a remote config")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
 var fVersion = flag.Bool("version", false, "display the version")
@@ -55,6 +71,8 @@ var fUsage = flag.String("usage", "",
 var fService = flag.String("service", "",
 	"operate on the service")
 var fRunAsConsole = flag.Bool("console", false, "run as console application (windows only)")
+var fWatchConfig = flag.Bool("watch-config", false,
+	"reload the config when its files change")
 
 var (
 	nextVersion = "1.6.0"
@@ -82,11 +100,23 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config check        load the configuration and configure its plugins
+                      without starting them, exit with an error if it is invalid
   version             print the version to stdout
 
-  --config <file>     configuration file to load
+  --config <file>     configuration file to load, or URL of a remote config
+  --config-poll-interval
+                      interval of the checks of a remote config for changes
+  --config-token      bearer token of a remote config, by default $TELEGRAF_CONFIG_TOKEN
+  --config-tls-ca, --config-tls-cert, --config-tls-key
+                      TLS files of a remote config
+  --config-insecure-skip-verify
+                      do not verify the server certificate of a remote config
   --test              gather metrics once, print them to stdout, and exit
+  --once              gather metrics once, write them to the outputs, and exit,
+                      with status 1 if an input or an output failed
   --config-directory  directory containing additional *.conf files
+  --watch-config      reload the config when its files change
   --input-filter      filter the input plugins to enable, separator is :
   --output-filter     filter the output plugins to enable, separator is :
   --usage             print usage for a plugin, ie, 'telegraf --usage mysql'
@@ -102,12 +132,21 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
+  # check a config file before deploying it
+  telegraf --config telegraf.conf config check
+
   # run a single telegraf collection, outputing metrics to stdout
   telegraf --config telegraf.conf --test
 
+  # run a single collection written to the outputs, from cron
+  telegraf --config telegraf.conf --once
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
+  # run telegraf with a remote config, reloaded when it changes
+  telegraf --config https://config.example.com/telegraf.conf --config-poll-interval 1m
+
   # run telegraf, enabling the cpu & memory input, and influxdb output plugins
   telegraf --config telegraf.conf --input-filter cpu:mem --output-filter influxdb
 
@@ -124,55 +163,37 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
-	reload := make(chan bool, 1)
-	reload <- true
-	for <-reload {
-		reload <- false
-
-		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
+	remote := newRemote()
+	c, err := loadConfig(remote, inputFilters, outputFilters)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+	var kept *agent.KeptServices
+	for c != nil {
+		ag, err := agent.NewAgent(c)
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
-
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-		}
-		if !*fTest && len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
-
-		if int64(c.Agent.Interval.Duration) <= 0 {
-			log.Fatalf("E! Agent interval must be positive, found %s",
-				c.Agent.Interval.Duration)
-		}
-
-		if int64(c.Agent.FlushInterval.Duration) <= 0 {
-			log.Fatalf("E! Agent flush_interval must be positive; found %s",
-				c.Agent.Interval.Duration)
+		if kept != nil {
+			ag.AdoptServices(kept)
 		}
 
-		ag, err := agent.NewAgent(c)
+		// Setup logging
+		err = logger.Setup(logger.Config{
+			Debug:               ag.Config.Agent.Debug || *fDebug,
+			Quiet:               ag.Config.Agent.Quiet || *fQuiet,
+			Level:               ag.Config.Agent.LogLevel,
+			Format:              ag.Config.Agent.LogFormat,
+			Target:              ag.Config.Agent.LogTarget,
+			Logfile:             ag.Config.Agent.Logfile,
+			RotationInterval:    ag.Config.Agent.LogfileRotationInterval.Duration,
+			RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize.Size,
+			RotationMaxArchives: ag.Config.Agent.LogfileRotationMaxArchives,
+		})
 		if err != nil {
-			log.Fatal("E! " + err.Error())
+			log.Printf("E! Invalid logging config: %s", err)
 		}
 
-		// Setup logging
-		logger.SetupLogging(
-			ag.Config.Agent.Debug || *fDebug,
-			ag.Config.Agent.Quiet || *fQuiet,
-			ag.Config.Agent.Logfile,
-		)
-
 		if *fTest {
 			err = ag.Test()
 			if err != nil {
@@ -186,23 +207,54 @@ func reloadLoop(
 			log.Fatal("E! " + err.Error())
 		}
 
+		if *fOnce {
+			if err := ag.Once(); err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			os.Exit(0)
+		}
+
+		// the next config is loaded before the agent shuts down, the service
+		// inputs configured the same keep running. A config failing to load
+		// keeps the running one.
+		var next *config.Config
 		shutdown := make(chan struct{})
-		signals := make(chan os.Signal)
+		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
+		changed := make(chan struct{})
+		if *fWatchConfig {
+			go watchConfig(shutdown, changed, c.Includes, c.IncludePatterns)
+		}
+		if *fConfigPollInterval > 0 && config.IsURL(*fConfig) {
+			go pollConfig(remote, shutdown, changed)
+		}
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == os.Interrupt {
+			for {
+				select {
+				case sig := <-signals:
+					if sig == os.Interrupt {
+						close(shutdown)
+						return
+					}
+				case <-ag.Reload():
+				case <-changed:
+				case <-stop:
 					close(shutdown)
+					return
 				}
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config\n")
-					<-reload
-					reload <- true
-					close(shutdown)
+
+				log.Printf("I! Reloading Telegraf config\n")
+				nc, err := loadConfig(remote, inputFilters, outputFilters)
+				if err != nil {
+					log.Printf("E! Error reloading the config, keeping the running one: %s", err)
+					continue
+				}
+				next, kept = nc, ag.KeepServices(nc)
+				if names := kept.Names(); len(names) > 0 {
+					log.Printf("I! Keeping inputs running: %s", strings.Join(names, " "))
 				}
-			case <-stop:
 				close(shutdown)
+				return
 			}
 		}()
 
@@ -230,6 +282,101 @@ func reloadLoop(
 		}
 
 		ag.Run(shutdown)
+		signal.Stop(signals)
+		c = next
+	}
+}
+
+// newRemote returns the client of a remote config.
+func newRemote() *config.Remote {
+	remote := &config.Remote{Token: *fConfigToken}
+	if remote.Token == "" {
+		remote.Token = os.Getenv("TELEGRAF_CONFIG_TOKEN")
+	}
+	remote.TlsCa = *fConfigTLSCA
+	remote.TlsCert = *fConfigTLSCert
+	remote.TlsKey = *fConfigTLSKey
+	remote.InsecureSkipVerify = *fConfigInsecureSkipVerify
+	return remote
+}
+
+// loadConfig loads the config file and the config directory.
+func loadConfig(remote *config.Remote, inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	c.Remote = remote
+	if err := c.LoadConfig(*fConfig); err != nil {
+		return nil, err
+	}
+
+	if *fConfigDirectory != "" {
+		if err := c.LoadDirectory(*fConfigDirectory); err != nil {
+			return nil, err
+		}
+	}
+	if !*fTest && len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+
+	if int64(c.Agent.Interval.Duration) <= 0 {
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
+			c.Agent.Interval.Duration)
+	}
+
+	if int64(c.Agent.FlushInterval.Duration) <= 0 {
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
+			c.Agent.FlushInterval.Duration)
+	}
+
+	if c.Agent.FIPSMode {
+		if err := c.CheckFIPS(); err != nil {
+			return nil, fmt.Errorf("FIPS mode: %s", err)
+		}
+	}
+	// a reloaded config may turn the FIPS mode off
+	tls.FIPSMode = c.Agent.FIPSMode
+	return c, nil
+}
+
+// checkConfig loads the config and configures its plugins without starting
+// them, it exits with an error if the config is invalid.
+func checkConfig(inputFilters, outputFilters []string) {
+	c, err := loadConfig(newRemote(), inputFilters, outputFilters)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "E! %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Configuration is valid: %d inputs, %d processors, %d aggregators, %d outputs\n",
+		len(c.Inputs), len(c.Processors), len(c.Aggregators), len(c.Outputs))
+}
+
+// pollConfig signals changed when the contents of the remote config change,
+// until done is closed.
+func pollConfig(remote *config.Remote, done chan struct{}, changed chan struct{}) {
+	ticker := time.NewTicker(*fConfigPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ok, err := remote.Changed(*fConfig)
+			if err != nil {
+				log.Printf("W! Error polling the config %s: %s", *fConfig, err)
+				continue
+			}
+			if ok {
+				select {
+				case changed <- struct{}{}:
+				case <-done:
+					return
+				}
+			}
+		}
 	}
 }
 
@@ -315,6 +462,10 @@ func main() {
 			fmt.Printf("Telegraf %s (git: %s %s)\n", displayVersion(), branch, commit)
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				checkConfig(inputFilters, outputFilters)
+				return
+			}
 			config.PrintSampleConfig(
 				inputFilters,
 				outputFilters,
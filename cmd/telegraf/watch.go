@@ -0,0 +1,199 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/influxdata/telegraf/internal/config"
+)
+
+const (
+	// watchConfigInterval is the interval of the checks of --watch-config
+	// when the file notifications are not supported.
+	watchConfigInterval = 5 * time.Second
+	// watchConfigDelay is how long the notifications of the config files are
+	// gathered before reloading, editors write a file in several steps and
+	// several files may be dropped at once.
+	watchConfigDelay = time.Second
+)
+
+// watchConfig signals changed when the config file, a .conf file of the
+// config directory or of its sub-directories, or a file matching the include
+// patterns changes, until done is closed. It checks the modification times
+// every watchConfigInterval if the notifications of the system cannot be
+// used.
+func watchConfig(done chan struct{}, changed chan struct{}, includes, patterns []string) {
+	w, err := newConfigWatcher(includes, patterns)
+	if err != nil {
+		log.Printf("W! Cannot watch the config files for changes, checking them every %s: %s",
+			watchConfigInterval, err)
+		pollConfigFiles(done, changed, includes)
+		return
+	}
+	defer w.watcher.Close()
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case event := <-w.watcher.Events:
+			if !w.relevant(event) {
+				continue
+			}
+			log.Printf("D! Config file %s changed: %s", event.Name, event.Op)
+			if reload == nil {
+				reload = time.After(watchConfigDelay)
+			}
+		case err := <-w.watcher.Errors:
+			log.Printf("W! Error watching the config files: %s", err)
+		case <-reload:
+			reload = nil
+			select {
+			case changed <- struct{}{}:
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// configWatcher watches the directories of the config files, the files
+// themselves may be replaced by editors.
+type configWatcher struct {
+	watcher *fsnotify.Watcher
+	// file is the config file, directory the config directory
+	file      string
+	directory string
+	// includes are the included files, patterns the include patterns
+	includes map[string]bool
+	patterns []string
+}
+
+// newConfigWatcher watches the directories of the included files, and those
+// of the include patterns without wildcards in their directory for the files
+// added to them.
+func newConfigWatcher(includes, patterns []string) (*configWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &configWatcher{watcher: watcher, includes: make(map[string]bool), patterns: patterns}
+	dirs := make(map[string]bool)
+	if *fConfig != "" && !config.IsURL(*fConfig) {
+		w.file = filepath.Clean(*fConfig)
+		dirs[filepath.Dir(w.file)] = true
+	}
+	for _, file := range includes {
+		w.includes[filepath.Clean(file)] = true
+		dirs[filepath.Dir(file)] = true
+	}
+	for _, pattern := range patterns {
+		dir := filepath.Dir(pattern)
+		if strings.ContainsAny(dir, "*?[") {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs[dir] = true
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	if *fConfigDirectory != "" {
+		w.directory = filepath.Clean(*fConfigDirectory)
+		err := filepath.Walk(w.directory, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.IsDir() {
+				return nil
+			}
+			return watcher.Add(path)
+		})
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// relevant returns true if the event changes the config, the sub-directories
+// created in the config directory are watched too.
+func (w *configWatcher) relevant(event fsnotify.Event) bool {
+	name := filepath.Clean(event.Name)
+	if name == w.file || w.includes[name] {
+		return true
+	}
+	for _, pattern := range w.patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	if w.directory == "" || !strings.HasPrefix(name, w.directory+string(filepath.Separator)) {
+		return false
+	}
+	if event.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			if err := w.watcher.Add(name); err != nil {
+				log.Printf("W! Cannot watch the config directory %s: %s", name, err)
+			}
+			return false
+		}
+	}
+	return strings.HasSuffix(name, ".conf")
+}
+
+// pollConfigFiles signals changed when the modification time of the config
+// files changes, until done is closed.
+func pollConfigFiles(done chan struct{}, changed chan struct{}, includes []string) {
+	last := configModTime(includes)
+	ticker := time.NewTicker(watchConfigInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if t := configModTime(includes); !t.Equal(last) {
+				last = t
+				select {
+				case changed <- struct{}{}:
+				case <-done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// configModTime returns the latest modification time of the config files,
+// a file removed from the config directory changes the time of the directory.
+// The directories of the included files are checked for the files added to
+// or removed from them.
+func configModTime(includes []string) time.Time {
+	var latest time.Time
+	check := func(path string) {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	check(*fConfig)
+	if *fConfigDirectory != "" {
+		check(*fConfigDirectory)
+		files, _ := filepath.Glob(filepath.Join(*fConfigDirectory, "*.conf"))
+		for _, file := range files {
+			check(file)
+		}
+	}
+	for _, file := range includes {
+		check(file)
+		check(filepath.Dir(file))
+	}
+	return latest
+}
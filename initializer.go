@@ -0,0 +1,8 @@
+package telegraf
+
+// Initializer is a plugin checking its configuration, Init is called once the
+// plugin is configured, before it is started. An error fails loading the
+// configuration.
+type Initializer interface {
+	Init() error
+}
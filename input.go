@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Input interface {
 	// SampleConfig returns the default configuration of the Input
 	SampleConfig() string
@@ -12,6 +14,17 @@ type Input interface {
 	Gather(Accumulator) error
 }
 
+// ContextInput is an Input that can stop gathering, the agent cancels the
+// context once the gather_timeout of the input is over and calls
+// GatherContext instead of Gather.
+type ContextInput interface {
+	Input
+
+	// GatherContext gathers like Gather, it returns as soon as it can once
+	// ctx is done.
+	GatherContext(ctx context.Context, acc Accumulator) error
+}
+
 type ServiceInput interface {
 	// SampleConfig returns the default configuration of the Input
 	SampleConfig() string
@@ -29,3 +42,18 @@ type ServiceInput interface {
 	// Stop stops the services and closes any necessary channels and connections
 	Stop()
 }
+
+// PausableInput is a ServiceInput that can stop consuming its messages while
+// the outputs fall behind, the agent pauses it once the buffer of an output
+// is above the buffer_high_watermark and resumes it below the
+// buffer_low_watermark.
+type PausableInput interface {
+	ServiceInput
+
+	// Pause stops reading new messages, the messages already read are still
+	// added. It must not block.
+	Pause()
+
+	// Resume reads the messages again.
+	Resume()
+}
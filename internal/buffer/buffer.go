@@ -15,19 +15,44 @@ var (
 // Buffer is an object for storing metrics in a circular buffer.
 type Buffer struct {
 	buf chan telegraf.Metric
+	// dropNewest drops the metrics added to a full buffer instead of the
+	// oldest metrics of the buffer.
+	dropNewest bool
+	// dropFunc is called with the metrics dropped, if it is set.
+	dropFunc func(telegraf.Metric)
 
 	mu sync.Mutex
 }
 
 // NewBuffer returns a Buffer
 //   size is the maximum number of metrics that Buffer will cache. If Add is
-//   called when the buffer is full, then the oldest metric(s) will be dropped.
+//   called when the buffer is full, then the oldest metric(s) will be dropped
+//   and rejected.
 func NewBuffer(size int) *Buffer {
 	return &Buffer{
 		buf: make(chan telegraf.Metric, size),
 	}
 }
 
+// SetDropNewest sets whether the metrics added to a full buffer are dropped
+// and rejected, instead of the oldest metrics of the buffer.
+func (b *Buffer) SetDropNewest(dropNewest bool) {
+	b.dropNewest = dropNewest
+}
+
+// SetDropFunc sets the function called with each metric dropped because the
+// buffer is full.
+func (b *Buffer) SetDropFunc(fn func(telegraf.Metric)) {
+	b.dropFunc = fn
+}
+
+func (b *Buffer) drop(m telegraf.Metric) {
+	if b.dropFunc != nil {
+		b.dropFunc(m)
+	}
+	m.Reject()
+}
+
 // IsEmpty returns true if Buffer is empty.
 func (b *Buffer) IsEmpty() bool {
 	return len(b.buf) == 0
@@ -38,20 +63,29 @@ func (b *Buffer) Len() int {
 	return len(b.buf)
 }
 
-// Add adds metrics to the buffer.
-func (b *Buffer) Add(metrics ...telegraf.Metric) {
+// Add adds metrics to the buffer, it returns the number of metrics dropped
+// because the buffer is full.
+func (b *Buffer) Add(metrics ...telegraf.Metric) int {
+	var dropped int
 	for i, _ := range metrics {
 		MetricsWritten.Incr(1)
 		select {
 		case b.buf <- metrics[i]:
 		default:
-			b.mu.Lock()
 			MetricsDropped.Incr(1)
-			<-b.buf
+			dropped++
+			if b.dropNewest {
+				b.drop(metrics[i])
+				continue
+			}
+			b.mu.Lock()
+			oldest := <-b.buf
+			b.drop(oldest)
 			b.buf <- metrics[i]
 			b.mu.Unlock()
 		}
 	}
+	return dropped
 }
 
 // Batch returns a batch of metrics of size batchSize.
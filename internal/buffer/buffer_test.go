@@ -70,6 +70,20 @@ func TestDroppingMetrics(t *testing.T) {
 	assert.Equal(t, int64(15), MetricsWritten.Get())
 }
 
+func TestDroppingNewestMetrics(t *testing.T) {
+	b := NewBuffer(5)
+	b.SetDropNewest(true)
+	MetricsDropped.Set(0)
+
+	assert.Equal(t, 0, b.Add(metricList...))
+	newer := testutil.TestMetric(3, "mymetric6")
+	assert.Equal(t, 1, b.Add(newer))
+	assert.Equal(t, int64(1), MetricsDropped.Get())
+
+	// the oldest metrics are kept
+	assert.Equal(t, metricList, b.Batch(10))
+}
+
 func TestGettingBatches(t *testing.T) {
 	b := NewBuffer(20)
 	MetricsDropped.Set(0)
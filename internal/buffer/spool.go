@@ -0,0 +1,262 @@
+package buffer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	parser "github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// compactRecords is the minimum number of records of a spool file before it
+// is compacted.
+const compactRecords = 10000
+
+// Spool is the write-ahead log of the metrics of an output buffer. Each
+// metric added to the buffer is appended to the file and marked once it is
+// written or dropped, the metrics still in the file when the output is
+// started again are added back to its buffer.
+//
+// The records of the metrics added are a "+<id> <length>" line followed by
+// the line protocol of the metric, those of the metrics removed a "-<id>"
+// line.
+type Spool struct {
+	path       string
+	file       *os.File
+	serializer *influx.Serializer
+
+	mu     sync.Mutex
+	ids    map[telegraf.Metric]uint64
+	lastID uint64
+	// records written to the file since it was compacted
+	records int
+}
+
+// OpenSpool opens the spool file at path, it is created if it does not
+// exist. It returns the metrics of the file that were not removed, in the
+// order they were added, they are tracked by the spool.
+func OpenSpool(path string) (*Spool, []telegraf.Metric, error) {
+	s := &Spool{
+		path:       path,
+		serializer: influx.NewSerializer(),
+		ids:        make(map[telegraf.Metric]uint64),
+	}
+	s.serializer.SetFieldTypeSupport(influx.UintSupport)
+
+	metrics, err := s.read()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range metrics {
+		s.lastID++
+		s.ids[m] = s.lastID
+	}
+	if err := s.compact(); err != nil {
+		return nil, nil, err
+	}
+	return s, metrics, nil
+}
+
+// read returns the metrics of the file that were not removed.
+func (s *Spool) read() ([]telegraf.Metric, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make(map[uint64][]byte)
+	r := bufio.NewReader(f)
+records:
+	for {
+		// the last record is incomplete if telegraf stopped while writing it
+		header, err := r.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(header[1:])
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid record in %s: %q", s.path, header)
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record in %s: %q", s.path, header)
+		}
+
+		switch {
+		case header[0] == '+' && len(fields) == 2:
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid record in %s: %q", s.path, header)
+			}
+			line := make([]byte, n)
+			if _, err := io.ReadFull(r, line); err != nil {
+				if err == io.ErrUnexpectedEOF {
+					break records
+				}
+				return nil, err
+			}
+			lines[id] = line
+		case header[0] == '-':
+			delete(lines, id)
+		default:
+			return nil, fmt.Errorf("invalid record in %s: %q", s.path, header)
+		}
+	}
+
+	ids := make([]uint64, 0, len(lines))
+	for id := range lines {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	p := parser.NewParser(parser.NewMetricHandler())
+	metrics := make([]telegraf.Metric, 0, len(ids))
+	for _, id := range ids {
+		ms, err := p.Parse(lines[id])
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric in %s: %s", s.path, err)
+		}
+		metrics = append(metrics, ms...)
+	}
+	return metrics, nil
+}
+
+// compact rewrites the file with the records of the metrics not removed,
+// the new file replaces the previous one once it is complete.
+func (s *Spool) compact() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(s.ids))
+	for m := range s.ids {
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return s.ids[metrics[i]] < s.ids[metrics[j]] })
+
+	w := bufio.NewWriter(f)
+	for _, m := range metrics {
+		record, err := s.addRecord(s.ids[m], m)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(record)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		f.Close()
+		return err
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = f
+	s.records = len(metrics)
+	return nil
+}
+
+func (s *Spool) addRecord(id uint64, m telegraf.Metric) ([]byte, error) {
+	line, err := s.serializer.Serialize(m)
+	if err != nil {
+		return nil, err
+	}
+	header := "+" + strconv.FormatUint(id, 10) + " " + strconv.Itoa(len(line)) + "\n"
+	return append([]byte(header), line...), nil
+}
+
+// Add appends the metrics to the file.
+func (s *Spool) Add(metrics ...telegraf.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var buf []byte
+	var err error
+	for _, m := range metrics {
+		record, serr := s.addRecord(s.lastID+1, m)
+		if serr != nil {
+			err = serr
+			continue
+		}
+		s.lastID++
+		s.ids[m] = s.lastID
+		buf = append(buf, record...)
+		s.records++
+	}
+	if _, werr := s.file.Write(buf); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// Remove marks the metrics as written or dropped, the file is compacted
+// once most of its records are removed metrics.
+func (s *Spool) Remove(metrics ...telegraf.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var buf []byte
+	for _, m := range metrics {
+		id, ok := s.ids[m]
+		if !ok {
+			continue
+		}
+		delete(s.ids, m)
+		buf = append(buf, "-"+strconv.FormatUint(id, 10)+"\n"...)
+		s.records++
+	}
+	if _, err := s.file.Write(buf); err != nil {
+		return err
+	}
+	if s.records >= compactRecords && s.records > 2*len(s.ids) {
+		return s.compact()
+	}
+	return nil
+}
+
+// Len returns the number of metrics of the file that are not removed.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ids)
+}
+
+// Sync commits the file to the disk.
+func (s *Spool) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Sync()
+}
+
+// Close syncs and closes the file.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
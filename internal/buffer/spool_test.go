@@ -0,0 +1,113 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spoolMetric(t *testing.T, name string, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New(name, map[string]string{"host": "a"}, fields, time.Unix(0, 1500000000000000000))
+	require.NoError(t, err)
+	return m
+}
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "outputs.test.wal")
+
+	s, metrics, err := OpenSpool(path)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	m1 := spoolMetric(t, "m1", map[string]interface{}{"value": int64(1)})
+	m2 := spoolMetric(t, "m2", map[string]interface{}{"text": "line\nbreak", "u": uint64(2)})
+	m3 := spoolMetric(t, "m3", map[string]interface{}{"ok": true})
+	require.NoError(t, s.Add(m1, m2))
+	require.NoError(t, s.Add(m3))
+	require.NoError(t, s.Remove(m1))
+	assert.Equal(t, 2, s.Len())
+	require.NoError(t, s.Close())
+
+	s, metrics, err = OpenSpool(path)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, m2.Name(), metrics[0].Name())
+	assert.Equal(t, m2.Fields(), metrics[0].Fields())
+	assert.Equal(t, m2.Tags(), metrics[0].Tags())
+	assert.Equal(t, m2.Time(), metrics[0].Time())
+	assert.Equal(t, m3.Fields(), metrics[1].Fields())
+
+	// the restored metrics are removed like the others
+	require.NoError(t, s.Remove(metrics[0]))
+	require.NoError(t, s.Close())
+	_, metrics, err = OpenSpool(path)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "m3", metrics[0].Name())
+}
+
+// Test that an incomplete last record is ignored
+func TestSpoolIncompleteRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "outputs.test.wal")
+
+	data := "+1 30\nm1,host=a value=1i 1500000000\n+2 30\nm2,host=a va"
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+	s, metrics, err := OpenSpool(path)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "m1", metrics[0].Name())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("garbage\n"), 0600))
+	_, _, err = OpenSpool(path)
+	assert.Error(t, err)
+}
+
+// Test that the removed metrics are dropped from the file once it is
+// compacted
+func TestSpoolCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "outputs.test.wal")
+
+	s, _, err := OpenSpool(path)
+	require.NoError(t, err)
+	defer s.Close()
+	kept := spoolMetric(t, "kept", map[string]interface{}{"value": 1.0})
+	require.NoError(t, s.Add(kept))
+	for i := 0; i < compactRecords/2; i++ {
+		m := spoolMetric(t, "m", map[string]interface{}{"value": 1.0})
+		require.NoError(t, s.Add(m))
+		require.NoError(t, s.Remove(m))
+	}
+	assert.Equal(t, 1, s.records)
+
+	_, metrics, err := OpenSpool(path)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "kept", metrics[0].Name())
+}
+
+func TestBufferDropFunc(t *testing.T) {
+	b := NewBuffer(2)
+	var dropped []telegraf.Metric
+	b.SetDropFunc(func(m telegraf.Metric) {
+		dropped = append(dropped, m)
+	})
+	b.Add(metricList[:3]...)
+	assert.Equal(t, []telegraf.Metric{metricList[0]}, dropped)
+}
@@ -19,7 +19,11 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/cron"
+	"github.com/influxdata/telegraf/internal/discovery"
+	"github.com/influxdata/telegraf/internal/globaltags"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/secret"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -39,8 +43,9 @@ var (
 	// Default output plugins
 	outputDefaults = []string{"influxdb"}
 
-	// envVarRe is a regex to find environment variables in the config file
-	envVarRe = regexp.MustCompile(`\$\w+`)
+	// envVarRe is a regex to find environment variables in the config file:
+	// $$, $VAR, ${VAR}, ${VAR:-default} and ${VAR:?message}
+	envVarRe = regexp.MustCompile(`\$(?:\$|(\w+)|\{(\w+)(?:(:-|:\?)([^}]*))?\})`)
 
 	envVarEscaper = strings.NewReplacer(
 		`"`, `\"`,
@@ -62,6 +67,32 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+	// Discoveries generate the configuration of some of the inputs
+	Discoveries []*discovery.Discovery
+
+	// Remote fetches the config if its path is a URL, a default client is
+	// used if it is nil.
+	Remote *Remote
+	// Includes are the files included by the include directives of the
+	// config files, in the order they were loaded.
+	Includes []string
+	// IncludePatterns are the glob patterns of the include directives,
+	// relative to the directory of their config file.
+	IncludePatterns []string
+
+	// loading are the files being loaded, an include of one of them is a
+	// cycle.
+	loading []string
+
+	// SecretsRefreshInterval is how often the secrets referenced by the
+	// config are read again, the config is reloaded once one of them
+	// changed. They are only read when the config is loaded if it is 0.
+	SecretsRefreshInterval time.Duration
+
+	// secretStores resolve the references to secrets of the config strings,
+	// secrets keeps the secrets resolved.
+	secretStores map[string]secret.Store
+	secrets      *secret.Resolver
 }
 
 func NewConfig() *Config {
@@ -79,7 +110,13 @@ func NewConfig() *Config {
 		Processors:    make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+
+		secretStores: map[string]secret.Store{
+			"env":  secret.Env{},
+			"file": &secret.File{},
+		},
 	}
+	c.secrets = &secret.Resolver{Stores: c.secretStores}
 	return c
 }
 
@@ -98,6 +135,28 @@ type AgentConfig struct {
 	// Precision will NOT be used for service inputs. It is up to each individual
 	// service input to set the timestamp at the appropriate precision.
 	Precision internal.Duration
+	// PrecisionRounding is how the timestamps are reduced to the precision,
+	// "round" to the nearest multiple or "truncate" to the previous one so
+	// that a timestamp is never moved forward.
+	PrecisionRounding string
+
+	// MetricOrdering is "time" to sort the batches written by the outputs
+	// by timestamp, the outputs may override it.
+	MetricOrdering string
+
+	// TimestampMaxPast and TimestampMaxFuture limit how far in the past or in
+	// the future the timestamps of the metrics of the inputs may be, a zero
+	// duration disables the limit. TimestampOutOfRange is "drop" or "clamp",
+	// clamped metrics get the current time.
+	TimestampMaxPast    internal.Duration
+	TimestampMaxFuture  internal.Duration
+	TimestampOutOfRange string
+
+	// TraceSampleRate is the fraction of the metrics of the inputs and
+	// aggregators whose path through the pipeline is logged, they are tagged
+	// with TraceTag set to their trace id. Zero disables the tracing.
+	TraceSampleRate float64
+	TraceTag        string
 
 	// CollectionJitter is used to jitter the collection by a random amount.
 	// Each plugin will sleep for a random time within jitter before collecting.
@@ -105,6 +164,16 @@ type AgentConfig struct {
 	// same time, which can have a measurable effect on the system.
 	CollectionJitter internal.Duration
 
+	// CollectionOffset shifts the collection of the inputs by a fixed amount,
+	// after the collection is rounded to the interval.
+	CollectionOffset internal.Duration
+
+	// GatherTimeout is how long a gather of the inputs may take, the context
+	// of the inputs supporting it is canceled and the gather is counted as a
+	// timeout after it. Zero waits for the gathers, with an error logged
+	// every interval.
+	GatherTimeout internal.Duration
+
 	// FlushInterval is the Interval at which to flush data
 	FlushInterval internal.Duration
 
@@ -125,6 +194,11 @@ type AgentConfig struct {
 	// not be less than 2 times MetricBatchSize.
 	MetricBufferLimit int
 
+	// MetricBufferDirectory is the directory of the spool files keeping the
+	// buffered metrics of each output on disk until they are written, the
+	// buffers are only kept in memory if it is empty.
+	MetricBufferDirectory string
+
 	// FlushBufferWhenFull tells Telegraf to flush the metric buffer whenever
 	// it fills up, regardless of FlushInterval. Setting this option to true
 	// does _not_ deactivate FlushInterval.
@@ -141,10 +215,70 @@ type AgentConfig struct {
 	// Logfile specifies the file to send logs to
 	Logfile string
 
+	// LogLevel overrides the log level of Debug and Quiet, LogFormat is the
+	// format of the log lines and LogTarget where the logs go, see the
+	// logger package.
+	LogLevel  string
+	LogFormat string
+	LogTarget string
+
+	// The Logfile is rotated once it is older than the
+	// LogfileRotationInterval or larger than LogfileRotationMaxSize, the
+	// oldest rotated files beyond LogfileRotationMaxArchives are removed.
+	LogfileRotationInterval    internal.Duration
+	LogfileRotationMaxSize     internal.Size
+	LogfileRotationMaxArchives int
+
 	// Quiet is the option for running in quiet mode
 	Quiet        bool
 	Hostname     string
 	OmitHostname bool
+
+	// StateFile is the file of the persistent store of the plugins keeping
+	// state, their state is only kept in memory if it is empty.
+	StateFile string
+
+	// FIPSMode restricts the TLS configs of the plugins to the FIPS approved
+	// TLS versions and cipher suites, the plugins with other TLS settings are
+	// rejected.
+	FIPSMode bool `toml:"fips_mode"`
+
+	// LeaderElectionKey enables the leader election of the instances sharing
+	// the key in Consul, only the leader gathers the leader_only inputs.
+	// LeaderElectionAddress is the address of the Consul agent.
+	LeaderElectionKey     string
+	LeaderElectionAddress string
+
+	// HealthServiceAddress is the address serving the liveness and the
+	// readiness of the agent, the readiness fails once the buffer of an
+	// output is fuller than HealthMaxBufferFullness or an output with
+	// buffered metrics did not write for HealthMaxWriteAge.
+	HealthServiceAddress    string
+	HealthMaxBufferFullness float64
+	HealthMaxWriteAge       internal.Duration
+
+	// BufferHighWatermark pauses the service inputs supporting it once the
+	// buffer of an output is fuller than this fraction of its limit, they
+	// are resumed once all the buffers are at most BufferLowWatermark full.
+	// They are not paused if it is 0.
+	BufferHighWatermark float64
+	BufferLowWatermark  float64
+}
+
+// checkWatermarks returns an error if the watermarks are not fractions with
+// the low watermark below the high one.
+func (a *AgentConfig) checkWatermarks() error {
+	if a.BufferHighWatermark == 0 {
+		return nil
+	}
+	if a.BufferHighWatermark < 0 || a.BufferHighWatermark > 1 {
+		return fmt.Errorf("buffer_high_watermark must be between 0 and 1, not %g", a.BufferHighWatermark)
+	}
+	if a.BufferLowWatermark < 0 || a.BufferLowWatermark >= a.BufferHighWatermark {
+		return fmt.Errorf("buffer_low_watermark must be between 0 and buffer_high_watermark %g, not %g",
+			a.BufferHighWatermark, a.BufferLowWatermark)
+	}
+	return nil
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -165,6 +299,32 @@ func (c *Config) OutputNames() []string {
 	return name
 }
 
+// fipsChecker is a plugin with TLS options, usually embedding the
+// ClientConfig or ServerConfig of internal/tls.
+type fipsChecker interface {
+	CheckFIPS() error
+}
+
+// CheckFIPS returns an error if the TLS options of a plugin are not allowed
+// in FIPS mode.
+func (c *Config) CheckFIPS() error {
+	for _, input := range c.Inputs {
+		if p, ok := input.Input.(fipsChecker); ok {
+			if err := p.CheckFIPS(); err != nil {
+				return fmt.Errorf("%s: %s", input.Name(), err)
+			}
+		}
+	}
+	for _, output := range c.Outputs {
+		if p, ok := output.Output.(fipsChecker); ok {
+			if err := p.CheckFIPS(); err != nil {
+				return fmt.Errorf("%s: %s", output.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
 // ListTags returns a string of tags specified in the config,
 // line-protocol style
 func (c *Config) ListTags() string {
@@ -193,6 +353,10 @@ var header = `# Telegraf Configuration
 # Environment variables can be used anywhere in this config file, simply prepend
 # them with $. For strings the variable must be within quotes (ie, "$STR_VAR"),
 # for numbers and booleans they should be plain (ie, $INT_VAR, $BOOL_VAR)
+#
+# Strings can reference secrets instead of holding them, for example
+# password = "@{vault:kv/telegraf:password}", the stores are configured in
+# the [secretstores] table.
 
 
 # Global tags can be specified here in key="value" format.
@@ -202,6 +366,17 @@ var header = `# Telegraf Configuration
   ## Environment variables can be used as tags, and throughout the config file
   # user = "$USER"
 
+## Global tags computed at startup, from the instance metadata of ec2 or gcp,
+## a kubernetes downward API volume or the key=value lines of a command. The
+## tags of [global_tags] are kept. hostname sets the hostname of the agent to
+## the value of a tag if it is not set.
+# [[global_tag_providers.ec2]]
+#   ## Tags to add, all of instance_id, instance_type, ami_id,
+#   ## availability_zone and region by default
+#   # tags = ["instance_id", "region"]
+#   # hostname = "instance_id"
+#   # timeout = "5s"
+
 
 # Configuration for telegraf agent
 [agent]
@@ -222,11 +397,25 @@ var header = `# Telegraf Configuration
   ## This buffer only fills when writes fail to output plugin(s).
   metric_buffer_limit = 10000
 
+  ## Directory of the spool files keeping the buffered metrics of each output
+  ## on disk until they are written, they are written after a restart. By
+  ## default the buffers are only kept in memory.
+  # metric_buffer_directory = "/var/lib/telegraf/buffer"
+
   ## Collection jitter is used to jitter the collection by a random amount.
   ## Each plugin will sleep for a random time within jitter before collecting.
   ## This can be used to avoid many plugins querying things like sysfs at the
   ## same time, which can have a measurable effect on the system.
   collection_jitter = "0s"
+  ## Collection offset shifts the collection of every input by a fixed amount,
+  ## after it is rounded to the interval, ie an offset of 5s and interval 1m
+  ## collects on :05 of every minute.
+  # collection_offset = "0s"
+  ## Gather timeout stops waiting for the gathers of the inputs taking longer,
+  ## like an SNMP device that does not answer, and counts them in the timeouts
+  ## of the inputs. An input is skipped until its previous gather returns. By
+  ## default the agent waits for the gathers.
+  # gather_timeout = "0s"
 
   ## Default flushing interval for all outputs. You shouldn't set this below
   ## interval. Maximum flush_interval will be flush_interval + flush_jitter
@@ -235,6 +424,8 @@ var header = `# Telegraf Configuration
   ## large write spikes for users running a large number of telegraf instances.
   ## ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
   flush_jitter = "0s"
+  ## metric_batch_size, metric_buffer_limit, flush_interval and flush_jitter
+  ## can be overridden in the config of each output.
 
   ## By default or when set to "0s", precision will be set to the same
   ## timestamp order as the collection interval, with the maximum being 1s.
@@ -244,6 +435,27 @@ var header = `# Telegraf Configuration
   ## service input to set the timestamp at the appropriate precision.
   ## Valid time units are "ns", "us" (or "µs"), "ms", "s".
   precision = ""
+  ## How the timestamps are reduced to the precision, "round" to the nearest
+  ## multiple or "truncate" so they are never moved forward.
+  # precision_rounding = "round"
+
+  ## Sort the batches written by the outputs by timestamp, then by name, with
+  ## "time". The metrics are written in the order they were gathered by
+  ## default.
+  # metric_ordering = ""
+
+  ## Metrics of the inputs with timestamps further in the past or in the
+  ## future are dropped, or get the current time if timestamp_out_of_range is
+  ## "clamp". This protects the outputs from devices with broken clocks.
+  # timestamp_max_past = "0s"
+  # timestamp_max_future = "0s"
+  # timestamp_out_of_range = "drop"
+
+  ## Fraction of the metrics, between 0 and 1, whose path through the
+  ## processors, aggregators and outputs is logged, for debugging. The traced
+  ## metrics are tagged with trace_tag set to their trace id.
+  # trace_sample_rate = 0.0
+  # trace_tag = "trace_id"
 
   ## Logging configuration:
   ## Run telegraf with debug log messages.
@@ -252,12 +464,58 @@ var header = `# Telegraf Configuration
   quiet = false
   ## Specify the log file name. The empty string means to log to stderr.
   logfile = ""
+  ## Override the log level of debug and quiet: "debug", "info", "warn" or
+  ## "error".
+  # log_level = ""
+  ## Format of the log lines: "text", "logfmt" or "json".
+  # log_format = "text"
+  ## Target of the logs: "stderr", "file" (the logfile) or "syslog". The
+  ## default is the logfile if it is set and stderr otherwise.
+  # log_target = ""
+
+  ## Rotate the logfile once it is older than the interval or larger than the
+  ## size, 0 never rotates it. The oldest rotated files beyond the maximum
+  ## number of archives are removed, -1 keeps them all.
+  # logfile_rotation_interval = "0s"
+  # logfile_rotation_max_size = "0MB"
+  # logfile_rotation_max_archives = 5
 
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## File persisting the state of the plugins keeping state, like caches or
+  ## inventories; by default their state is lost on restart.
+  # state_file = "/var/lib/telegraf/state.json"
+
+  ## Restrict the TLS connections of the plugins to the FIPS approved TLS
+  ## versions and cipher suites, plugins with other tls_ settings are rejected.
+  # fips_mode = false
+
+  ## Elect a leader among the instances sharing this Consul key, only the
+  ## leader gathers the inputs with leader_only = true. The address is the
+  ## address of the Consul agent, its token is the CONSUL_HTTP_TOKEN.
+  # leader_election_key = "telegraf/leader/snmp"
+  # leader_election_address = "localhost:8500"
+
+  ## Address serving the health of the agent for probes like those of
+  ## Kubernetes: /live answers while the agent runs, /ready fails once the
+  ## buffer of an output is fuller than health_max_buffer_fullness (0 to 1) or
+  ## an output with buffered metrics did not write for health_max_write_age.
+  ## The checks are disabled if they are 0.
+  # health_service_address = ":8088"
+  # health_max_buffer_fullness = 0.9
+  # health_max_write_age = "5m"
+
+  ## Pause the service inputs consuming from brokers, like kafka_consumer,
+  ## nats_consumer and mqtt_consumer, once the buffer of an output is fuller
+  ## than buffer_high_watermark (0 to 1), instead of dropping the oldest
+  ## metrics when it is full. They are resumed once the buffers of all the
+  ## outputs are at most buffer_low_watermark full. 0 disables the pausing.
+  # buffer_high_watermark = 0.8
+  # buffer_low_watermark = 0.5
+
 
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
@@ -547,6 +805,10 @@ func getDefaultConfigPath() (string, error) {
 	if runtime.GOOS == "windows" {
 		etcfile = `C:\Program Files\Telegraf\telegraf.conf`
 	}
+	if IsURL(envfile) {
+		log.Printf("I! Using config file: %s", envfile)
+		return envfile, nil
+	}
 	for _, path := range []string{envfile, homefile, etcfile} {
 		if _, err := os.Stat(path); err == nil {
 			log.Printf("I! Using config file: %s", path)
@@ -567,10 +829,31 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	tbl, err := parseFile(path)
+	var tbl *ast.Table
+	if IsURL(path) {
+		if c.Remote == nil {
+			c.Remote = &Remote{}
+		}
+		tbl, err = parseURL(c.Remote, path)
+	} else {
+		tbl, err = parseFile(path)
+	}
 	if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
+	include, exclude, err := includeDirectives(tbl)
+	if err != nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
+
+	// The secret stores are configured before the secrets are resolved, the
+	// options of the stores may only use the env and file stores.
+	if err := c.addSecretStores(tbl); err != nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
+	if err := resolveSecrets(tbl, c.secrets.Resolve); err != nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
 
 	// Parse tags tables first:
 	for _, tableName := range []string{"tags", "global_tags"} {
@@ -596,6 +879,9 @@ func (c *Config) LoadConfig(path string) error {
 			log.Printf("E! Could not parse [agent] config\n")
 			return fmt.Errorf("Error parsing %s, %s", path, err)
 		}
+		if err = c.Agent.checkWatermarks(); err != nil {
+			return fmt.Errorf("Error parsing %s, %s", path, err)
+		}
 	}
 
 	// Parse all the rest of the plugins:
@@ -606,19 +892,33 @@ func (c *Config) LoadConfig(path string) error {
 		}
 
 		switch name {
-		case "agent", "global_tags", "tags":
+		case "agent", "global_tags", "tags", "secretstores":
+		case "global_tag_providers":
+			for kind, val := range subTable.Fields {
+				switch providerTables := val.(type) {
+				case []*ast.Table:
+					for _, t := range providerTables {
+						if err = c.addTagProvider(kind, t); err != nil {
+							return tableError(path, t, err)
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s, file %s",
+						kind, path)
+				}
+			}
 		case "outputs":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
 				// legacy [outputs.influxdb] support
 				case *ast.Table:
 					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
+						return tableError(path, pluginSubTable, err)
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addOutput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							return tableError(path, t, err)
 						}
 					}
 				default:
@@ -627,22 +927,21 @@ func (c *Config) LoadConfig(path string) error {
 				}
 			}
 		case "inputs", "plugins":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				// legacy [inputs.cpu] support
-				case *ast.Table:
-					if err = c.addInput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
-					}
+			if err = c.addInputs(path, subTable); err != nil {
+				return err
+			}
+		case "discovery":
+			for kind, val := range subTable.Fields {
+				switch discoveryTables := val.(type) {
 				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addInput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+					for _, t := range discoveryTables {
+						if err = c.addDiscovery(kind, t); err != nil {
+							return tableError(path, t, err)
 						}
 					}
 				default:
 					return fmt.Errorf("Unsupported config format: %s, file %s",
-						pluginName, path)
+						kind, path)
 				}
 			}
 		case "processors":
@@ -651,7 +950,7 @@ func (c *Config) LoadConfig(path string) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addProcessor(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							return tableError(path, t, err)
 						}
 					}
 				default:
@@ -665,7 +964,7 @@ func (c *Config) LoadConfig(path string) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addAggregator(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							return tableError(path, t, err)
 						}
 					}
 				default:
@@ -677,7 +976,7 @@ func (c *Config) LoadConfig(path string) error {
 		// identifiers are present
 		default:
 			if err = c.addInput(name, subTable); err != nil {
-				return fmt.Errorf("Error parsing %s, %s", path, err)
+				return tableError(path, subTable, err)
 			}
 		}
 	}
@@ -685,6 +984,363 @@ func (c *Config) LoadConfig(path string) error {
 	if len(c.Processors) > 1 {
 		sort.Sort(c.Processors)
 	}
+	return c.loadIncludes(path, include, exclude)
+}
+
+// includeDirectives returns the glob patterns of the include and exclude
+// arrays at the top of the config file, they are removed from the table.
+func includeDirectives(tbl *ast.Table) ([]string, []string, error) {
+	var patterns [2][]string
+	for i, key := range []string{"include", "exclude"} {
+		node, ok := tbl.Fields[key]
+		if !ok {
+			continue
+		}
+		delete(tbl.Fields, key)
+		kv, ok := node.(*ast.KeyValue)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s must be an array of file patterns", key)
+		}
+		ary, ok := kv.Value.(*ast.Array)
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: %s must be an array of file patterns", kv.Line, key)
+		}
+		for _, elem := range ary.Value {
+			str, ok := elem.(*ast.String)
+			if !ok {
+				return nil, nil, fmt.Errorf("line %d: %s must be an array of file patterns", kv.Line, key)
+			}
+			if _, err := filepath.Match(str.Value, ""); err != nil {
+				return nil, nil, fmt.Errorf("line %d: invalid %s pattern %q: %s", kv.Line, key, str.Value, err)
+			}
+			patterns[i] = append(patterns[i], str.Value)
+		}
+	}
+	return patterns[0], patterns[1], nil
+}
+
+// loadIncludes loads the files matching the include patterns of the config
+// file at path but none of its exclude patterns, in the order of the
+// patterns and of the names of the files. Relative patterns are relative to
+// the directory of the file, a file is loaded once even if it matches
+// several patterns. A pattern without wildcards must match a file.
+func (c *Config) loadIncludes(path string, include, exclude []string) error {
+	if len(include) == 0 {
+		return nil
+	}
+	dir := "."
+	if !IsURL(path) {
+		dir = filepath.Dir(path)
+	}
+	resolve := func(pattern string) string {
+		if filepath.IsAbs(pattern) {
+			return filepath.Clean(pattern)
+		}
+		return filepath.Join(dir, pattern)
+	}
+
+	c.loading = append(c.loading, absPath(path))
+	defer func() {
+		c.loading = c.loading[:len(c.loading)-1]
+	}()
+	excluded := func(file string) bool {
+		for _, pattern := range exclude {
+			if ok, _ := filepath.Match(resolve(pattern), file); ok {
+				return true
+			}
+		}
+		return false
+	}
+	for _, pattern := range include {
+		c.IncludePatterns = append(c.IncludePatterns, resolve(pattern))
+		files, err := filepath.Glob(resolve(pattern))
+		if err != nil {
+			return fmt.Errorf("Error parsing %s, invalid include pattern %q: %s", path, pattern, err)
+		}
+		if len(files) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return fmt.Errorf("Error parsing %s, included file %s does not exist", path, resolve(pattern))
+		}
+		for _, file := range files {
+			if info, err := os.Stat(file); err != nil || info.IsDir() || excluded(file) {
+				continue
+			}
+			for _, loading := range c.loading {
+				if loading == absPath(file) {
+					return fmt.Errorf("Error parsing %s, %s is included in a cycle", path, file)
+				}
+			}
+			if c.included(file) {
+				continue
+			}
+			c.Includes = append(c.Includes, file)
+			if err := c.LoadConfig(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// absPath returns the absolute path of the file, or path if it has none.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// included returns true if the file was already included.
+func (c *Config) included(file string) bool {
+	for _, f := range c.Includes {
+		if f == file {
+			return true
+		}
+	}
+	return false
+}
+
+// addInputs adds the inputs of the inputs table of the config file.
+func (c *Config) addInputs(path string, table *ast.Table) error {
+	for pluginName, pluginVal := range table.Fields {
+		switch pluginSubTable := pluginVal.(type) {
+		// legacy [inputs.cpu] support
+		case *ast.Table:
+			if err := c.addInput(pluginName, pluginSubTable); err != nil {
+				return tableError(path, pluginSubTable, err)
+			}
+		case []*ast.Table:
+			for _, t := range pluginSubTable {
+				if err := c.addInput(pluginName, t); err != nil {
+					return tableError(path, t, err)
+				}
+			}
+		default:
+			return fmt.Errorf("Unsupported config format: %s, file %s",
+				pluginName, path)
+		}
+	}
+	return nil
+}
+
+// addSecretStores configures the secret stores of the [secretstores] table.
+func (c *Config) addSecretStores(tbl *ast.Table) error {
+	val, ok := tbl.Fields["secretstores"]
+	if !ok {
+		return nil
+	}
+	subTable, ok := val.(*ast.Table)
+	if !ok {
+		return fmt.Errorf("invalid secretstores configuration")
+	}
+	err := resolveSecrets(subTable, func(s string) (string, error) {
+		return secret.Resolve(s, c.secretStores)
+	})
+	if err != nil {
+		return err
+	}
+
+	if node, ok := subTable.Fields["refresh_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return err
+				}
+
+				c.SecretsRefreshInterval = dur
+			}
+		}
+	}
+	for kind, val := range subTable.Fields {
+		if kind == "refresh_interval" {
+			continue
+		}
+		storeTable, ok := val.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("Unsupported config format: secretstores.%s", kind)
+		}
+		var store secret.Store
+		switch kind {
+		case "file":
+			store = &secret.File{}
+		case "vault":
+			store = &secret.Vault{}
+		default:
+			return fmt.Errorf("Undefined but requested secret store: %s", kind)
+		}
+		if err := toml.UnmarshalTable(storeTable, store); err != nil {
+			return err
+		}
+		c.secretStores[kind] = store
+	}
+	return nil
+}
+
+// resolveSecrets replaces the references to secrets of the strings of the
+// table and of its sub-tables.
+func resolveSecrets(table *ast.Table, resolve func(string) (string, error)) error {
+	for _, node := range table.Fields {
+		if err := resolveNode(node, resolve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveNode(node interface{}, resolve func(string) (string, error)) error {
+	switch n := node.(type) {
+	case *ast.Table:
+		return resolveSecrets(n, resolve)
+	case []*ast.Table:
+		for _, t := range n {
+			if err := resolveSecrets(t, resolve); err != nil {
+				return err
+			}
+		}
+	case *ast.KeyValue:
+		return resolveNode(n.Value, resolve)
+	case *ast.Array:
+		for _, v := range n.Value {
+			if err := resolveNode(v, resolve); err != nil {
+				return err
+			}
+		}
+	case *ast.String:
+		value, err := resolve(n.Value)
+		if err != nil {
+			return err
+		}
+		n.Value = value
+	}
+	return nil
+}
+
+// SecretsChanged reads the secrets referenced by the config again, it
+// returns the reference of the first one that changed, or an empty string.
+func (c *Config) SecretsChanged() (string, error) {
+	return c.secrets.Changed()
+}
+
+// addTagProvider adds the global tags of the provider, the tags already set
+// by the global_tags table are kept. The hostname of the agent is set to the
+// value of the tag named by the hostname option if it is not set.
+func (c *Config) addTagProvider(kind string, table *ast.Table) error {
+	var provider globaltags.Provider
+	switch kind {
+	case "ec2":
+		provider = &globaltags.EC2{}
+	case "gcp":
+		provider = &globaltags.GCP{}
+	case "kubernetes":
+		provider = &globaltags.Kubernetes{}
+	case "exec":
+		provider = &globaltags.Exec{}
+	default:
+		return fmt.Errorf("Undefined but requested global tag provider: %s", kind)
+	}
+
+	var hostname string
+	if node, ok := table.Fields["hostname"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				hostname = str.Value
+			}
+		}
+	}
+	delete(table.Fields, "hostname")
+	if err := toml.UnmarshalTable(table, provider); err != nil {
+		return err
+	}
+
+	tags, err := provider.Tags()
+	if err != nil {
+		return fmt.Errorf("global tag provider %s: %s", kind, err)
+	}
+	for key, value := range tags {
+		if _, ok := c.Tags[key]; !ok {
+			c.Tags[key] = value
+		}
+	}
+	if hostname != "" {
+		value, ok := tags[hostname]
+		if !ok {
+			return fmt.Errorf("global tag provider %s: no %s tag for the hostname", kind, hostname)
+		}
+		if c.Agent.Hostname == "" {
+			c.Agent.Hostname = value
+		}
+	}
+	return nil
+}
+
+// addDiscovery adds the discovery and the inputs of its targets. The inputs
+// are not added if the discovery fails, the agent reloads the config once
+// it succeeds.
+func (c *Config) addDiscovery(kind string, table *ast.Table) error {
+	var provider discovery.Provider
+	switch kind {
+	case "file":
+		provider = &discovery.File{}
+	case "consul":
+		provider = &discovery.Consul{}
+	default:
+		return fmt.Errorf("Undefined but requested discovery: %s", kind)
+	}
+
+	var tmpl string
+	if node, ok := table.Fields["template"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				tmpl = str.Value
+			}
+		}
+	}
+	var refresh time.Duration
+	if node, ok := table.Fields["refresh_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return err
+				}
+
+				refresh = dur
+			}
+		}
+	}
+	delete(table.Fields, "template")
+	delete(table.Fields, "refresh_interval")
+	if err := toml.UnmarshalTable(table, provider); err != nil {
+		return err
+	}
+
+	name := "discovery." + kind
+	d, err := discovery.New(name, provider, tmpl, refresh)
+	if err != nil {
+		return err
+	}
+	c.Discoveries = append(c.Discoveries, d)
+
+	rendered, err := d.Render()
+	if err != nil {
+		log.Printf("E! Discovery [%s] failed, its inputs are not loaded: %s", name, err)
+		return nil
+	}
+	d.Rendered = rendered
+
+	tbl, err := toml.Parse(rendered)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	for key, val := range tbl.Fields {
+		subTable, ok := val.(*ast.Table)
+		if key != "inputs" || !ok {
+			return fmt.Errorf("%s: the template may only configure inputs", name)
+		}
+		if err := c.addInputs(name, subTable); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -708,21 +1364,82 @@ func parseFile(fpath string) (*ast.Table, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseContents(contents)
+}
+
+// parseURL parses the remote config at url.
+func parseURL(remote *Remote, url string) (*ast.Table, error) {
+	contents, err := remote.Fetch(url)
+	if err != nil {
+		return nil, err
+	}
+	return parseContents(contents)
+}
+
+func parseContents(contents []byte) (*ast.Table, error) {
 	// ugh windows why
 	contents = trimBOM(contents)
 
-	env_vars := envVarRe.FindAll(contents, -1)
-	for _, env_var := range env_vars {
-		env_val, ok := os.LookupEnv(strings.TrimPrefix(string(env_var), "$"))
-		if ok {
-			env_val = escapeEnv(env_val)
-			contents = bytes.Replace(contents, env_var, []byte(env_val), 1)
-		}
+	contents, err := substituteEnvVars(contents)
+	if err != nil {
+		return nil, err
 	}
 
 	return toml.Parse(contents)
 }
 
+// substituteEnvVars replaces the environment variables of the contents by
+// their values:
+//   - $VAR and ${VAR} are kept as is if VAR is not set
+//   - ${VAR:-default} is replaced by default if VAR is not set or empty
+//   - ${VAR:?message} returns an error with message if VAR is not set or empty
+//   - $$ is replaced by a literal $
+func substituteEnvVars(contents []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	last := 0
+	for _, loc := range envVarRe.FindAllSubmatchIndex(contents, -1) {
+		buf.Write(contents[last:loc[0]])
+		last = loc[1]
+
+		submatch := func(i int) string {
+			if loc[2*i] < 0 {
+				return ""
+			}
+			return string(contents[loc[2*i]:loc[2*i+1]])
+		}
+		name := submatch(1) + submatch(2)
+		if name == "" {
+			buf.WriteByte('$')
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		switch submatch(3) {
+		case ":-":
+			if value == "" {
+				value, ok = submatch(4), true
+			}
+		case ":?":
+			if value == "" {
+				line := bytes.Count(contents[:loc[0]], []byte("\n")) + 1
+				message := submatch(4)
+				if message == "" {
+					message = "not set"
+				}
+				return nil, fmt.Errorf("line %d: environment variable %s is required: %s",
+					line, name, message)
+			}
+		}
+		if !ok {
+			buf.Write(contents[loc[0]:loc[1]])
+			continue
+		}
+		buf.WriteString(escapeEnv(value))
+	}
+	buf.Write(contents[last:])
+	return buf.Bytes(), nil
+}
+
 func (c *Config) addAggregator(name string, table *ast.Table) error {
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
@@ -738,6 +1455,11 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	if err := toml.UnmarshalTable(table, aggregator); err != nil {
 		return err
 	}
+	models.SetLogger(aggregator, "aggregators."+name, conf.Alias)
+	models.SetAlias(aggregator, conf.Alias)
+	if err := initPlugin(aggregator, "aggregators."+name); err != nil {
+		return err
+	}
 
 	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
 	return nil
@@ -758,6 +1480,11 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	if err := toml.UnmarshalTable(table, processor); err != nil {
 		return err
 	}
+	models.SetLogger(processor, "processors."+name, processorConfig.Alias)
+	models.SetAlias(processor, processorConfig.Alias)
+	if err := initPlugin(processor, "processors."+name); err != nil {
+		return err
+	}
 
 	rf := &models.RunningProcessor{
 		Name:      name,
@@ -798,9 +1525,20 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err := toml.UnmarshalTable(table, output); err != nil {
 		return err
 	}
+	models.SetLogger(output, "outputs."+name, outputConfig.Alias)
+	models.SetAlias(output, outputConfig.Alias)
+	if err := initPlugin(output, "outputs."+name); err != nil {
+		return err
+	}
 
-	ro := models.NewRunningOutput(name, output, outputConfig,
-		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	batchSize, bufferLimit := c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit
+	if outputConfig.MetricBatchSize > 0 {
+		batchSize = outputConfig.MetricBatchSize
+	}
+	if outputConfig.MetricBufferLimit > 0 {
+		bufferLimit = outputConfig.MetricBufferLimit
+	}
+	ro := models.NewRunningOutput(name, output, outputConfig, batchSize, bufferLimit)
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -819,6 +1557,8 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	// the parsers and options below delete the fields they use
+	fingerprint := name + tableFingerprint(table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -829,6 +1569,17 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 			return err
 		}
 		t.SetParser(parser)
+	case parsers.ParserFuncInput:
+		parserFunc, err := buildParserFunc(name, table)
+		if err != nil {
+			return err
+		}
+		t.SetParserFunc(parserFunc)
+	}
+	if t, ok := input.(parsers.SubjectParserInput); ok {
+		if err := buildSubjectParsers(name, table, t); err != nil {
+			return err
+		}
 	}
 
 	pluginConfig, err := buildInput(name, table)
@@ -839,12 +1590,96 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err := toml.UnmarshalTable(table, input); err != nil {
 		return err
 	}
+	models.SetLogger(input, "inputs."+name, pluginConfig.Alias)
+	models.SetAlias(input, pluginConfig.Alias)
+	if err := initPlugin(input, "inputs."+name); err != nil {
+		return err
+	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	rp.Fingerprint = fingerprint
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
 
+// tableError returns the error of the plugin of the table, with the line of
+// the table in the file.
+func tableError(path string, table *ast.Table, err error) error {
+	return fmt.Errorf("Error parsing %s, line %d: %s", path, table.Line, err)
+}
+
+// initPlugin calls the Init method of the plugin once it is configured.
+func initPlugin(plugin interface{}, name string) error {
+	if p, ok := plugin.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// tableFingerprint returns the fields of the table and of its sub-tables,
+// sorted by key, the tables with the same fields have the same fingerprint.
+func tableFingerprint(table *ast.Table) string {
+	keys := make([]string, 0, len(table.Fields))
+	for key := range table.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for _, key := range keys {
+		buf.WriteString(strconv.Quote(key))
+		buf.WriteString("=")
+		buf.WriteString(nodeFingerprint(table.Fields[key]))
+		buf.WriteString(",")
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+func nodeFingerprint(node interface{}) string {
+	switch n := node.(type) {
+	case *ast.Table:
+		return tableFingerprint(n)
+	case []*ast.Table:
+		parts := make([]string, len(n))
+		for i, t := range n {
+			parts[i] = tableFingerprint(t)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case *ast.KeyValue:
+		return nodeFingerprint(n.Value)
+	case *ast.Array:
+		parts := make([]string, len(n.Value))
+		for i, v := range n.Value {
+			parts[i] = nodeFingerprint(v)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case *ast.String:
+		return strconv.Quote(n.Value)
+	case ast.Value:
+		return n.Source()
+	}
+	return fmt.Sprintf("%v", node)
+}
+
+// buildAlias returns the alias of the plugin instance, it names the instance
+// in the logs.
+func buildAlias(tbl *ast.Table) string {
+	var alias string
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				alias = str.Value
+			}
+		}
+	}
+	delete(tbl.Fields, "alias")
+	return alias
+}
+
 // buildAggregator parses Aggregator specific items from the ast.Table,
 // builds the filter and returns a
 // models.AggregatorConfig to be inserted into models.RunningAggregator
@@ -859,6 +1694,7 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 
 	conf := &models.AggregatorConfig{
 		Name:   name,
+		Alias:  buildAlias(tbl),
 		Delay:  time.Millisecond * 100,
 		Period: time.Second * 30,
 	}
@@ -953,7 +1789,7 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 // builds the filter and returns a
 // models.ProcessorConfig to be inserted into models.RunningProcessor
 func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error) {
-	conf := &models.ProcessorConfig{Name: name}
+	conf := &models.ProcessorConfig{Name: name, Alias: buildAlias(tbl)}
 	unsupportedFields := []string{"tagexclude", "taginclude", "fielddrop", "fieldpass"}
 	for _, field := range unsupportedFields {
 		if _, ok := tbl.Fields[field]; ok {
@@ -1103,6 +1939,13 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 			}
 		}
 	}
+	if node, ok := tbl.Fields["metricpass"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				f.MetricPass = str.Value
+			}
+		}
+	}
 	if err := f.Compile(); err != nil {
 		return f, err
 	}
@@ -1117,6 +1960,7 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	delete(tbl.Fields, "tagpass")
 	delete(tbl.Fields, "tagexclude")
 	delete(tbl.Fields, "taginclude")
+	delete(tbl.Fields, "metricpass")
 	return f, nil
 }
 
@@ -1124,7 +1968,7 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 // builds the filter and returns a
 // models.InputConfig to be inserted into models.RunningInput
 func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
-	cp := &models.InputConfig{Name: name}
+	cp := &models.InputConfig{Name: name, Alias: buildAlias(tbl)}
 	if node, ok := tbl.Fields["interval"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1138,6 +1982,95 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["precision"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.Precision = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["round_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				round, err := b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+
+				cp.RoundInterval = &round
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				schedule, err := cron.Parse(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.Schedule = schedule
+			}
+		}
+	}
+	if cp.Schedule != nil && cp.Interval != 0 {
+		return nil, fmt.Errorf("input %s: schedule and interval cannot both be set", name)
+	}
+
+	for key, d := range map[string]**time.Duration{
+		"collection_jitter": &cp.CollectionJitter,
+		"collection_offset": &cp.CollectionOffset,
+		"gather_timeout":    &cp.GatherTimeout,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					dur, err := time.ParseDuration(str.Value)
+					if err != nil {
+						return nil, err
+					}
+
+					*d = &dur
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	if node, ok := tbl.Fields["leader_only"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				leader, err := b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+
+				cp.LeaderOnly = leader
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["startup_error_behavior"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				switch str.Value {
+				case models.StartupErrorError, models.StartupErrorIgnore, models.StartupErrorRetry:
+					cp.StartupErrorBehavior = str.Value
+				default:
+					return nil, fmt.Errorf("input %s: invalid startup_error_behavior %q, expected error, ignore or retry",
+						name, str.Value)
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1175,6 +2108,11 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "precision")
+	delete(tbl.Fields, "round_interval")
+	delete(tbl.Fields, "schedule")
+	delete(tbl.Fields, "leader_only")
+	delete(tbl.Fields, "startup_error_behavior")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -1184,10 +2122,72 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	return cp, nil
 }
 
+// buildSubjectParsers builds the parsers of the subject tables of the input.
+func buildSubjectParsers(name string, tbl *ast.Table, input parsers.SubjectParserInput) error {
+	node, ok := tbl.Fields["subject"]
+	if !ok {
+		return nil
+	}
+	subTables, ok := node.([]*ast.Table)
+	if !ok {
+		return fmt.Errorf("%s: subject must be a table array", name)
+	}
+	for _, subTbl := range subTables {
+		var subject string
+		if node, ok := subTbl.Fields["subject"]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					subject = str.Value
+				}
+			}
+		}
+		if subject == "" {
+			return fmt.Errorf("%s: subject tables must set subject", name)
+		}
+		delete(subTbl.Fields, "subject")
+
+		parserFunc, err := buildParserFunc(name, subTbl)
+		if err != nil {
+			return err
+		}
+		for key := range subTbl.Fields {
+			return fmt.Errorf("%s: unknown option %s in subject %s", name, key, subject)
+		}
+		input.SetSubjectParserFunc(subject, parserFunc)
+	}
+	delete(tbl.Fields, "subject")
+	return nil
+}
+
 // buildParser grabs the necessary entries from the ast.Table for creating
 // a parsers.Parser object, and creates it, which can then be added onto
 // an Input object.
 func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
+	c, err := getParserConfig(name, tbl)
+	if err != nil {
+		return nil, err
+	}
+	return parsers.NewParser(c)
+}
+
+// buildParserFunc returns a function creating parsers of the options of the
+// table, for inputs parsing in parallel.
+func buildParserFunc(name string, tbl *ast.Table) (parsers.ParserFunc, error) {
+	c, err := getParserConfig(name, tbl)
+	if err != nil {
+		return nil, err
+	}
+	// report the errors of the options at once
+	if _, err := parsers.NewParser(c); err != nil {
+		return nil, err
+	}
+	return func() (parsers.Parser, error) {
+		return parsers.NewParser(c)
+	}, nil
+}
+
+// getParserConfig reads the parser options of the table and removes them.
+func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	c := &parsers.Config{}
 
 	if node, ok := tbl.Fields["data_format"]; ok {
@@ -1314,6 +2314,103 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 		}
 	}
 
+	for key, s := range map[string]*string{
+		"csv_comment":            &c.CSVComment,
+		"csv_delimiter":          &c.CSVDelimiter,
+		"csv_measurement_column": &c.CSVMeasurementColumn,
+		"csv_timestamp_column":   &c.CSVTimestampColumn,
+		"csv_timestamp_format":   &c.CSVTimestampFormat,
+		"grok_custom_patterns":   &c.GrokCustomPatterns,
+		"grok_timezone":          &c.GrokTimezone,
+		"xml_metric_selection":   &c.XMLMetricSelection,
+		"xml_metric_name":        &c.XMLMetricName,
+		"xml_timestamp":          &c.XMLTimestamp,
+		"xml_timestamp_format":   &c.XMLTimestampFormat,
+		"wasm_module":            &c.WASMModule,
+		"wasm_timeout":           &c.WASMTimeout,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					*s = str.Value
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	for key, a := range map[string]*[]string{
+		"csv_column_names":          &c.CSVColumnNames,
+		"csv_column_types":          &c.CSVColumnTypes,
+		"csv_tag_columns":           &c.CSVTagColumns,
+		"grok_patterns":             &c.GrokPatterns,
+		"grok_custom_pattern_files": &c.GrokCustomPatternFiles,
+		"wasm_args":                 &c.WASMArgs,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if ary, ok := kv.Value.(*ast.Array); ok {
+					for _, elem := range ary.Value {
+						if str, ok := elem.(*ast.String); ok {
+							*a = append(*a, str.Value)
+						}
+					}
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	for key, i := range map[string]*int{
+		"csv_header_row_count": &c.CSVHeaderRowCount,
+		"csv_skip_columns":     &c.CSVSkipColumns,
+		"csv_skip_rows":        &c.CSVSkipRows,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if integer, ok := kv.Value.(*ast.Integer); ok {
+					v, err := integer.Int()
+					if err != nil {
+						return nil, err
+					}
+					*i = int(v)
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	for key, m := range map[string]*map[string]string{
+		"xml_tags":   &c.XMLTags,
+		"xml_fields": &c.XMLFields,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if subtbl, ok := node.(*ast.Table); ok {
+				*m = make(map[string]string)
+				for name, val := range subtbl.Fields {
+					if kv, ok := val.(*ast.KeyValue); ok {
+						if str, ok := kv.Value.(*ast.String); ok {
+							(*m)[name] = str.Value
+						}
+					}
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	if node, ok := tbl.Fields["csv_trim_space"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				trim, err := b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+				c.CSVTrimSpace = trim
+			}
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
@@ -1329,8 +2426,9 @@ func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 	delete(tbl.Fields, "dropwizard_time_format")
 	delete(tbl.Fields, "dropwizard_tags_path")
 	delete(tbl.Fields, "dropwizard_tag_paths")
+	delete(tbl.Fields, "csv_trim_space")
 
-	return parsers.NewParser(c)
+	return c, nil
 }
 
 // buildSerializer grabs the necessary entries from the ast.Table for creating
@@ -1440,8 +2538,93 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	}
 	oc := &models.OutputConfig{
 		Name:   name,
+		Alias:  buildAlias(tbl),
 		Filter: filter,
 	}
+
+	if node, ok := tbl.Fields["metric_buffer_overflow"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferOverflow = str.Value
+			}
+		}
+	}
+	if err := models.CheckBufferOverflow(oc.BufferOverflow); err != nil {
+		return nil, err
+	}
+	delete(tbl.Fields, "metric_buffer_overflow")
+
+	if node, ok := tbl.Fields["metric_ordering"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.MetricOrdering = str.Value
+			}
+		}
+	}
+	if err := models.CheckMetricOrdering(oc.MetricOrdering); err != nil {
+		return nil, err
+	}
+	delete(tbl.Fields, "metric_ordering")
+
+	for key, d := range map[string]*time.Duration{
+		"flush_interval": &oc.FlushInterval,
+		"flush_jitter":   &oc.FlushJitter,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					dur, err := time.ParseDuration(str.Value)
+					if err != nil {
+						return nil, fmt.Errorf("invalid %s: %s", key, err)
+					}
+					*d = dur
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	for key, n := range map[string]*int{
+		"metric_batch_size":   &oc.MetricBatchSize,
+		"metric_buffer_limit": &oc.MetricBufferLimit,
+	} {
+		if node, ok := tbl.Fields[key]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if integer, ok := kv.Value.(*ast.Integer); ok {
+					v, err := integer.Int()
+					if err != nil {
+						return nil, err
+					}
+					*n = int(v)
+				}
+			}
+		}
+		delete(tbl.Fields, key)
+	}
+
+	if node, ok := tbl.Fields["metric_rate_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Integer:
+				n, err := v.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.MetricRateLimit = float64(n)
+			case *ast.Float:
+				f, err := v.Float()
+				if err != nil {
+					return nil, err
+				}
+				oc.MetricRateLimit = f
+			}
+		}
+	}
+	if oc.MetricRateLimit < 0 {
+		return nil, fmt.Errorf("invalid metric_rate_limit %g, it cannot be negative", oc.MetricRateLimit)
+	}
+	delete(tbl.Fields, "metric_rate_limit")
+
 	// Outputs don't support FieldDrop/FieldPass, so set to NameDrop/NamePass
 	if len(oc.Filter.FieldDrop) > 0 {
 		oc.Filter.NameDrop = oc.Filter.FieldDrop
@@ -1,18 +1,24 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
+	_ "github.com/influxdata/telegraf/plugins/inputs/kafka_lag"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	_ "github.com/influxdata/telegraf/plugins/outputs/discard"
 	"github.com/influxdata/telegraf/plugins/parsers"
 
+	"github.com/influxdata/toml"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
@@ -58,6 +64,46 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 		"Testdata did not produce correct memcached metadata.")
 }
 
+func TestSubstituteEnvVars(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_SET", "value"))
+	require.NoError(t, os.Setenv("TEST_EMPTY", ""))
+	require.NoError(t, os.Setenv("TEST_QUOTED", `a "b"`))
+	defer os.Unsetenv("TEST_SET")
+	defer os.Unsetenv("TEST_EMPTY")
+	defer os.Unsetenv("TEST_QUOTED")
+	os.Unsetenv("TEST_UNSET")
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{`a = "$TEST_SET"`, `a = "value"`},
+		{`a = "${TEST_SET}x"`, `a = "valuex"`},
+		{`a = "$TEST_UNSET ${TEST_UNSET}"`, `a = "$TEST_UNSET ${TEST_UNSET}"`},
+		{`a = "${TEST_SET:-default}"`, `a = "value"`},
+		{`a = "${TEST_UNSET:-default}"`, `a = "default"`},
+		{`a = "${TEST_EMPTY:-default}"`, `a = "default"`},
+		{`a = "${TEST_UNSET:-}"`, `a = ""`},
+		{`a = "${TEST_UNSET:-"x"}"`, `a = "\"x\""`},
+		{`a = "${TEST_SET:?required}"`, `a = "value"`},
+		{`a = "$$TEST_SET $${TEST_SET} $$"`, `a = "$TEST_SET ${TEST_SET} $"`},
+		{`a = "$TEST_QUOTED"`, `a = "a \"b\""`},
+	}
+	for _, tt := range tests {
+		output, err := substituteEnvVars([]byte(tt.input))
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.output, string(output), tt.input)
+	}
+
+	_, err := substituteEnvVars([]byte("a = 1\nb = \"${TEST_UNSET:?set the server}\""))
+	require.Error(t, err)
+	assert.Equal(t, "line 2: environment variable TEST_UNSET is required: set the server", err.Error())
+
+	_, err = substituteEnvVars([]byte(`a = "${TEST_EMPTY:?}"`))
+	require.Error(t, err)
+	assert.Equal(t, "line 1: environment variable TEST_EMPTY is required: not set", err.Error())
+}
+
 func TestConfig_LoadSingleInput(t *testing.T) {
 	c := NewConfig()
 	c.LoadConfig("./testdata/single_plugin.toml")
@@ -97,6 +143,264 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 		"Testdata did not produce correct memcached metadata.")
 }
 
+func TestConfig_LoadInputPrecision(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/input_precision.toml")
+	assert.NoError(t, err)
+
+	memcached := inputs.Inputs["memcached"]().(*memcached.Memcached)
+	memcached.Servers = []string{"localhost"}
+
+	round := false
+	jitter, offset, timeout := time.Duration(0), 30*time.Second, 10*time.Second
+	mConfig := &models.InputConfig{
+		Name:             "memcached",
+		Interval:         5 * time.Minute,
+		Precision:        time.Minute,
+		RoundInterval:    &round,
+		LeaderOnly:       true,
+		CollectionJitter: &jitter,
+		CollectionOffset: &offset,
+		GatherTimeout:    &timeout,
+	}
+	mConfig.Tags = make(map[string]string)
+
+	assert.Equal(t, memcached, c.Inputs[0].Input,
+		"Testdata did not produce a correct memcached struct.")
+	assert.Equal(t, mConfig, c.Inputs[0].Config,
+		"Testdata did not produce correct memcached metadata.")
+}
+
+func TestConfig_LoadSchedule(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/schedule.toml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "input memcached: schedule and interval cannot both be set")
+
+	require.Len(t, c.Inputs, 1)
+	schedule := c.Inputs[0].Config.Schedule
+	require.NotNil(t, schedule)
+	assert.Equal(t, "0 */5 * * *", schedule.String())
+	assert.Equal(t, time.Date(2018, 6, 13, 15, 0, 0, 0, time.UTC),
+		schedule.Next(time.Date(2018, 6, 13, 10, 17, 0, 0, time.UTC)))
+}
+
+func TestConfig_LoadStartupErrorBehavior(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/startup_error_behavior.toml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(),
+		`input memcached: invalid startup_error_behavior "restart", expected error, ignore or retry`)
+
+	require.Len(t, c.Inputs, 2)
+	assert.Equal(t, models.StartupErrorRetry, c.Inputs[0].Config.StartupErrorBehavior)
+	assert.Equal(t, "", c.Inputs[1].Config.StartupErrorBehavior)
+}
+
+func TestAgentConfig_CheckWatermarks(t *testing.T) {
+	for _, tt := range []struct {
+		high, low float64
+		err       string
+	}{
+		{0, 0, ""},
+		{0.8, 0, ""},
+		{0.8, 0.5, ""},
+		{1.5, 0.5, "buffer_high_watermark must be between 0 and 1, not 1.5"},
+		{0.8, 0.8, "buffer_low_watermark must be between 0 and buffer_high_watermark 0.8, not 0.8"},
+	} {
+		a := &AgentConfig{BufferHighWatermark: tt.high, BufferLowWatermark: tt.low}
+		err := a.checkWatermarks()
+		if tt.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.err)
+		}
+	}
+}
+
+func TestConfig_LoadDiscovery(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/discovery.toml")
+	require.NoError(t, err)
+
+	require.Len(t, c.Discoveries, 1)
+	assert.Equal(t, time.Minute, c.Discoveries[0].RefreshInterval)
+	require.Len(t, c.Inputs, 2)
+	var servers []string
+	for _, input := range c.Inputs {
+		servers = append(servers, input.Input.(*memcached.Memcached).Servers...)
+	}
+	assert.ElementsMatch(t, []string{"10.0.0.1:11211", "10.0.0.2:11211"}, servers)
+
+	rendered, err := c.Discoveries[0].Render()
+	require.NoError(t, err)
+	assert.Equal(t, c.Discoveries[0].Rendered, rendered)
+}
+
+// subjectsInput records the parsers of its subject tables.
+type subjectsInput struct {
+	parserFunc parsers.ParserFunc
+	subjects   []string
+	parsers    []parsers.ParserFunc
+}
+
+func (s *subjectsInput) SampleConfig() string                  { return "" }
+func (s *subjectsInput) Description() string                   { return "" }
+func (s *subjectsInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (s *subjectsInput) SetParserFunc(fn parsers.ParserFunc)   { s.parserFunc = fn }
+func (s *subjectsInput) SetSubjectParserFunc(subject string, fn parsers.ParserFunc) {
+	s.subjects = append(s.subjects, subject)
+	s.parsers = append(s.parsers, fn)
+}
+
+func TestConfig_LoadSubjectParsers(t *testing.T) {
+	inputs.Add("subjects", func() telegraf.Input { return &subjectsInput{} })
+	defer delete(inputs.Inputs, "subjects")
+
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/subject_parsers.toml")
+	require.NoError(t, err)
+
+	require.Len(t, c.Inputs, 1)
+	input := c.Inputs[0].Input.(*subjectsInput)
+	assert.Equal(t, []string{"sensors.>", "graphite.*", "plc.*", "syslog.*", "scada.*"}, input.subjects)
+	require.Len(t, input.parsers, 5)
+
+	parser, err := input.parsers[0]()
+	require.NoError(t, err)
+	metrics, err := parser.Parse([]byte(`{"device": "d1", "value": 1}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "subjects", metrics[0].Name())
+	assert.Equal(t, map[string]string{"device": "d1"}, metrics[0].Tags())
+
+	parser, err = input.parsers[1]()
+	require.NoError(t, err)
+	metrics, err = parser.Parse([]byte("cpu.load 1 1454780029"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "cpu", metrics[0].Name())
+
+	parser, err = input.parsers[2]()
+	require.NoError(t, err)
+	metrics, err = parser.Parse([]byte("line;temperature\nl1;21\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]string{"line": "l1"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{"temperature": 21.0}, metrics[0].Fields())
+
+	parser, err = input.parsers[3]()
+	require.NoError(t, err)
+	metrics, err = parser.Parse([]byte("pump: 3\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "subjects", metrics[0].Name())
+	assert.Equal(t, map[string]string{"program": "pump"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": int64(3)}, metrics[0].Fields())
+
+	parser, err = input.parsers[4]()
+	require.NoError(t, err)
+	metrics, err = parser.Parse([]byte(`<Bus><Sensor name="pump"><Temperature>21</Temperature></Sensor></Bus>`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]string{"name": "pump"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{"temperature": int64(21)}, metrics[0].Fields())
+
+	parser, err = input.parserFunc()
+	require.NoError(t, err)
+	metrics, err = parser.Parse([]byte("cpu value=1 1454780029000000000"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+}
+
+func TestConfig_CheckFIPS(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/fips.toml")
+	require.NoError(t, err)
+	assert.True(t, c.Agent.FIPSMode)
+
+	err = c.CheckFIPS()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "inputs.kafka_lag")
+}
+
+func TestConfig_LoadLogging(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/logging.toml")
+	require.NoError(t, err)
+
+	assert.Equal(t, "warn", c.Agent.LogLevel)
+	assert.Equal(t, "json", c.Agent.LogFormat)
+	assert.Equal(t, "file", c.Agent.LogTarget)
+	assert.Equal(t, 24*time.Hour, c.Agent.LogfileRotationInterval.Duration)
+	assert.Equal(t, int64(10000000), c.Agent.LogfileRotationMaxSize.Size)
+	assert.Equal(t, -1, c.Agent.LogfileRotationMaxArchives)
+}
+
+func TestConfig_LoadMetricPass(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/metricpass.toml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Error compiling 'metricpass', unexpected end of the expression")
+
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, `tags.tenant == "acme"`, c.Inputs[0].Config.Filter.MetricPass)
+	assert.False(t, c.Inputs[0].Config.Filter.Apply("cpu",
+		map[string]interface{}{"value": 1.0}, map[string]string{"tenant": "globex"}))
+}
+
+func TestConfig_LoadOutputFlush(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_flush.toml")
+	require.NoError(t, err)
+	require.Len(t, c.Outputs, 2)
+
+	o := c.Outputs[0]
+	assert.Equal(t, time.Second, o.Config.FlushInterval)
+	assert.Equal(t, 100*time.Millisecond, o.Config.FlushJitter)
+	assert.Equal(t, 10000, o.MetricBatchSize)
+	assert.Equal(t, 100000, o.MetricBufferLimit)
+
+	o = c.Outputs[1]
+	assert.Equal(t, time.Duration(0), o.Config.FlushInterval)
+	assert.Equal(t, 1000, o.MetricBatchSize)
+	assert.Equal(t, 10000, o.MetricBufferLimit)
+}
+
+func TestBuildOutputBufferOverflow(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`metric_buffer_overflow = "block"`))
+	require.NoError(t, err)
+	oc, err := buildOutput("file", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, models.BufferOverflowBlock, oc.BufferOverflow)
+	assert.NotContains(t, tbl.Fields, "metric_buffer_overflow")
+
+	tbl, err = toml.Parse([]byte(`metric_buffer_overflow = "drop_all"`))
+	require.NoError(t, err)
+	_, err = buildOutput("file", tbl)
+	assert.Error(t, err)
+}
+
+func TestBuildOutputRateLimit(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`metric_rate_limit = 500`))
+	require.NoError(t, err)
+	oc, err := buildOutput("file", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, oc.MetricRateLimit)
+	assert.NotContains(t, tbl.Fields, "metric_rate_limit")
+
+	tbl, err = toml.Parse([]byte(`metric_rate_limit = 0.5`))
+	require.NoError(t, err)
+	oc, err = buildOutput("file", tbl)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, oc.MetricRateLimit)
+
+	tbl, err = toml.Parse([]byte(`metric_rate_limit = -1`))
+	require.NoError(t, err)
+	_, err = buildOutput("file", tbl)
+	assert.EqualError(t, err, "invalid metric_rate_limit -1, it cannot be negative")
+}
+
 func TestConfig_LoadDirectory(t *testing.T) {
 	c := NewConfig()
 	err := c.LoadConfig("./testdata/single_plugin.toml")
@@ -174,3 +478,145 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_InputFingerprint(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/fingerprint.toml"))
+	require.Len(t, c.Inputs, 4)
+
+	// the order of the fields and the spacing do not matter
+	assert.NotEmpty(t, c.Inputs[0].Fingerprint)
+	assert.Equal(t, c.Inputs[0].Fingerprint, c.Inputs[1].Fingerprint)
+	assert.NotEqual(t, c.Inputs[0].Fingerprint, c.Inputs[2].Fingerprint)
+	assert.NotEqual(t, c.Inputs[0].Fingerprint, c.Inputs[3].Fingerprint)
+
+	// the same file has the same fingerprints
+	again := NewConfig()
+	require.NoError(t, again.LoadConfig("./testdata/fingerprint.toml"))
+	for i, input := range again.Inputs {
+		assert.Equal(t, c.Inputs[i].Fingerprint, input.Fingerprint)
+	}
+}
+
+func TestConfig_LoadSecrets(t *testing.T) {
+	os.Setenv("TELEGRAF_SECRETS_DIR", "./testdata/secrets")
+	os.Setenv("TELEGRAF_MEMCACHED_SERVER", "remote")
+	defer os.Unsetenv("TELEGRAF_SECRETS_DIR")
+	defer os.Unsetenv("TELEGRAF_MEMCACHED_SERVER")
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secrets.toml"))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, []string{"localhost", "remote"}, c.Inputs[0].Input.(*memcached.Memcached).Servers)
+
+	assert.Equal(t, 30*time.Second, c.SecretsRefreshInterval)
+
+	ref, err := c.SecretsChanged()
+	require.NoError(t, err)
+	assert.Equal(t, "", ref)
+	os.Setenv("TELEGRAF_MEMCACHED_SERVER", "rotated")
+	ref, err = c.SecretsChanged()
+	require.NoError(t, err)
+	assert.Equal(t, "@{env:TELEGRAF_MEMCACHED_SERVER}", ref)
+
+	os.Unsetenv("TELEGRAF_MEMCACHED_SERVER")
+	assert.Error(t, NewConfig().LoadConfig("./testdata/secrets.toml"))
+}
+
+func TestConfig_LoadAlias(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/alias.toml"))
+	require.Len(t, c.Inputs, 2)
+	assert.Equal(t, "cache", c.Inputs[0].Config.Alias)
+	assert.Equal(t, "inputs.memcached::cache", c.Inputs[0].LogName())
+	assert.Equal(t, "", c.Inputs[1].Config.Alias)
+	assert.Equal(t, "inputs.memcached", c.Inputs[1].LogName())
+}
+
+func TestConfig_LoadInclude(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/include.toml"))
+	var servers []string
+	for _, input := range c.Inputs {
+		servers = append(servers, input.Input.(*memcached.Memcached).Servers...)
+	}
+	// the memcached.conf file matches both patterns, it is loaded once
+	assert.Equal(t, []string{"main", "included", "nested", "deeper"}, servers)
+	assert.Equal(t, []string{
+		"testdata/include/memcached.conf",
+		"testdata/include/nested.conf",
+		"testdata/include/nested/memcached.conf",
+	}, c.Includes)
+	// the patterns are relative to the file including them
+	assert.Equal(t, []string{
+		"testdata/include/*.conf",
+		"testdata/include/nested/*.conf",
+		"testdata/include/memcached.conf",
+	}, c.IncludePatterns)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/include_cycle.toml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "testdata/include_cycle.toml is included in a cycle")
+
+	tbl, err := toml.Parse([]byte(`include = "conf.d/*.conf"`))
+	require.NoError(t, err)
+	_, _, err = includeDirectives(tbl)
+	assert.EqualError(t, err, "line 1: include must be an array of file patterns")
+
+	tbl, err = toml.Parse([]byte(`include = ["missing.conf"]`))
+	require.NoError(t, err)
+	include, _, err := includeDirectives(tbl)
+	require.NoError(t, err)
+	assert.NotContains(t, tbl.Fields, "include")
+	assert.EqualError(t, NewConfig().loadIncludes("testdata/main.conf", include, nil),
+		"Error parsing testdata/main.conf, included file testdata/missing.conf does not exist")
+}
+
+func TestConfig_LoadGlobalTagProviders(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/global_tag_providers.toml"))
+	assert.Equal(t, map[string]string{"dc": "static", "node": "node-1"}, c.Tags)
+	assert.Equal(t, "node-1", c.Agent.Hostname)
+
+	tbl, err := toml.Parse([]byte(`command = ["printf", "dc=provided"]
+hostname = "node"`))
+	require.NoError(t, err)
+	assert.EqualError(t, NewConfig().addTagProvider("exec", tbl),
+		"global tag provider exec: no node tag for the hostname")
+	assert.EqualError(t, NewConfig().addTagProvider("azure", tbl),
+		"Undefined but requested global tag provider: azure")
+}
+
+// initInput fails its Init if configured to.
+type initInput struct {
+	Fail bool
+
+	initialized bool
+}
+
+func (i *initInput) SampleConfig() string                  { return "" }
+func (i *initInput) Description() string                   { return "" }
+func (i *initInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func (i *initInput) Init() error {
+	if i.Fail {
+		return fmt.Errorf("failing as configured")
+	}
+	i.initialized = true
+	return nil
+}
+
+func init() {
+	inputs.Add("init_test", func() telegraf.Input { return &initInput{} })
+}
+
+func TestConfig_LoadInit(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/init.toml")
+	require.Error(t, err)
+	assert.Equal(t, "Error parsing ./testdata/init.toml, line 5: inputs.init_test: failing as configured", err.Error())
+
+	require.Len(t, c.Inputs, 1)
+	assert.True(t, c.Inputs[0].Input.(*initInput).initialized)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/tls"
+)
+
+// IsURL reports whether the config path is the URL of a remote config.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// Remote fetches the config files served over HTTP. The credentials of the
+// URL are sent with basic authentication, the Token as a bearer token.
+type Remote struct {
+	tls.ClientConfig
+	Token   string
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	client  *http.Client
+	digests map[string][sha256.Size]byte
+}
+
+func (r *Remote) httpClient() (*http.Client, error) {
+	if r.client != nil {
+		return r.client, nil
+	}
+	tlsConfig, err := r.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	r.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+		Timeout: timeout,
+	}
+	return r.client, nil
+}
+
+func (r *Remote) get(url string) ([]byte, error) {
+	r.mu.Lock()
+	client, err := r.httpClient()
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Fetch returns the contents of the config at url, Changed compares the
+// contents fetched later with them.
+func (r *Remote) Fetch(url string) ([]byte, error) {
+	contents, err := r.get(url)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.digests == nil {
+		r.digests = make(map[string][sha256.Size]byte)
+	}
+	r.digests[url] = sha256.Sum256(contents)
+	return contents, nil
+}
+
+// Changed reports whether the contents of the config at url changed since it
+// was fetched by Fetch.
+func (r *Remote) Changed(url string) (bool, error) {
+	contents, err := r.get(url)
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.digests[url] != sha256.Sum256(contents), nil
+}
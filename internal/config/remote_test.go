@@ -0,0 +1,57 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadRemote(t *testing.T) {
+	var mu sync.Mutex
+	contents := "[[inputs.memcached]]\n  servers = [\"localhost\"]\n"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(contents))
+	}))
+	defer ts.Close()
+
+	c := NewConfig()
+	err := c.LoadConfig(ts.URL + "/telegraf.conf")
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "401"), err.Error())
+
+	remote := &Remote{Token: "secret"}
+	c = NewConfig()
+	c.Remote = remote
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, "memcached", c.Inputs[0].Config.Name)
+
+	changed, err := remote.Changed(ts.URL + "/telegraf.conf")
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	mu.Lock()
+	contents += "[[inputs.memcached]]\n  servers = [\"remote\"]\n"
+	mu.Unlock()
+	changed, err = remote.Changed(ts.URL + "/telegraf.conf")
+	require.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, IsURL("https://config.example.com/telegraf.conf"))
+	assert.True(t, IsURL("http://config.example.com/telegraf.conf"))
+	assert.False(t, IsURL("/etc/telegraf/telegraf.conf"))
+	assert.False(t, IsURL("telegraf.conf"))
+}
@@ -0,0 +1,55 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2018, 6, 13, 10, 17, 42, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/5 * * * *", time.Date(2018, 6, 13, 10, 20, 0, 0, time.UTC)},
+		{"0 */5 * * *", time.Date(2018, 6, 13, 15, 0, 0, 0, time.UTC)},
+		{"17 10 * * *", time.Date(2018, 6, 14, 10, 17, 0, 0, time.UTC)},
+		{"30 2 * * mon-fri", time.Date(2018, 6, 14, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2018, 6, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2018, 6, 15, 0, 0, 0, 0, time.UTC)},
+		// either the day of the month or the day of the week
+		{"0 0 1 * sat", time.Date(2018, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 feb *", time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2018, 6, 13, 10, 25, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2018, 6, 13, 11, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, s.Next(now), tt.spec)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{"* * * *", `invalid schedule "* * * *": expected 5 fields, got 4`},
+		{"60 * * * *", `invalid schedule "60 * * * *": invalid minute "60"`},
+		{"* * 0 * *", `invalid schedule "* * 0 * *": invalid day of month "0"`},
+		{"* * * foo *", `invalid schedule "* * * foo *": invalid month "foo"`},
+		{"*/0 * * * *", `invalid schedule "*/0 * * * *": invalid step of minute "*/0"`},
+		{"* 5-2 * * *", `invalid schedule "* 5-2 * * *": invalid range of hour "5-2"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.spec)
+		assert.EqualError(t, err, tt.err, tt.spec)
+	}
+}
@@ -0,0 +1,140 @@
+// Package discovery generates the configuration of inputs from the targets
+// of a service discovery, rendering a template for each target.
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Target is a discovered target, its values are the fields of the templates.
+type Target map[string]string
+
+// Provider discovers targets.
+type Provider interface {
+	Discover() ([]Target, error)
+}
+
+// Discovery renders the configuration of the inputs of the targets of its
+// provider, it is rendered again every refresh interval.
+type Discovery struct {
+	Name            string
+	Provider        Provider
+	Template        *template.Template
+	RefreshInterval time.Duration
+
+	// Rendered is the configuration rendered when the config was loaded.
+	Rendered []byte
+}
+
+// New returns the discovery of the provider, the template is parsed with
+// text/template.
+func New(name string, provider Provider, tmpl string, refresh time.Duration) (*Discovery, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return &Discovery{
+		Name:            name,
+		Provider:        provider,
+		Template:        t,
+		RefreshInterval: refresh,
+	}, nil
+}
+
+// Render discovers the targets and renders the template for each of them, in
+// the order of their values so an unchanged set of targets renders the same
+// configuration.
+func (d *Discovery) Render() ([]byte, error) {
+	targets, err := d.Provider.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]string, 0, len(targets))
+	for _, target := range targets {
+		var buf bytes.Buffer
+		if err := d.Template.Execute(&buf, target); err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, buf.String())
+	}
+	sort.Strings(rendered)
+	return []byte(strings.Join(rendered, "\n")), nil
+}
+
+// File discovers the targets listed in a JSON file, an array of objects.
+type File struct {
+	Path string
+}
+
+func (f *File) Discover() ([]Target, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("invalid targets in %s: %s", f.Path, err)
+	}
+
+	targets := make([]Target, 0, len(objects))
+	for _, object := range objects {
+		target := make(Target, len(object))
+		for key, value := range object {
+			target[key] = fmt.Sprint(value)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// Consul discovers the healthy instances of a Consul service, the targets
+// have the values "service", "id", "node", "address", "port" and "tags", a
+// comma separated list.
+type Consul struct {
+	Address string
+	Service string
+	Tag     string
+}
+
+func (c *Consul) Discover() ([]Target, error) {
+	config := api.DefaultConfig()
+	if c.Address != "" {
+		config.Address = c.Address
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := client.Health().Service(c.Service, c.Tag, true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]Target, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		targets = append(targets, Target{
+			"service": entry.Service.Service,
+			"id":      entry.Service.ID,
+			"node":    entry.Node.Node,
+			"address": address,
+			"port":    strconv.Itoa(entry.Service.Port),
+			"tags":    strings.Join(entry.Service.Tags, ","),
+		})
+	}
+	return targets, nil
+}
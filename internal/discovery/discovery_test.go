@@ -0,0 +1,50 @@
+package discovery
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRender(t *testing.T) {
+	f, err := ioutil.TempFile("", "targets")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`[{"address": "10.0.0.2", "port": 9100}, {"address": "10.0.0.1", "port": 9100}]`)
+	f.Close()
+
+	d, err := New("file", &File{Path: f.Name()}, `[[inputs.prometheus]]
+  urls = ["http://{{.address}}:{{.port}}/metrics"]
+`, 0)
+	require.NoError(t, err)
+	out, err := d.Render()
+	require.NoError(t, err)
+	assert.Equal(t, `[[inputs.prometheus]]
+  urls = ["http://10.0.0.1:9100/metrics"]
+
+[[inputs.prometheus]]
+  urls = ["http://10.0.0.2:9100/metrics"]
+`, string(out))
+
+	// the fields of the template are required
+	d, err = New("file", &File{Path: f.Name()}, `{{.host}}`, 0)
+	require.NoError(t, err)
+	_, err = d.Render()
+	assert.Error(t, err)
+}
+
+func TestFileErrors(t *testing.T) {
+	_, err := (&File{Path: "/nonexistent/targets.json"}).Discover()
+	assert.Error(t, err)
+
+	f, err := ioutil.TempFile("", "targets")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`{"address": "10.0.0.1"}`)
+	f.Close()
+	_, err = (&File{Path: f.Name()}).Discover()
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+// Package encoding decodes and encodes HTTP bodies with their
+// Content-Encoding.
+package encoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The supported Content-Encodings, snappy is the block format used by the
+// Prometheus remote storage protocol. zstd is only decoded.
+const (
+	Identity = "identity"
+	Gzip     = "gzip"
+	Snappy   = "snappy"
+	Zstd     = "zstd"
+)
+
+// ErrTooLarge is returned when the decoded body is larger than the limit.
+var ErrTooLarge = fmt.Errorf("decoded body too large")
+
+// UnsupportedError is the error of an unsupported Content-Encoding.
+type UnsupportedError struct {
+	Encoding string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported content encoding %q", e.Encoding)
+}
+
+// Check returns an error if the Content-Encoding cannot be decoded.
+func Check(encoding string) error {
+	switch encoding {
+	case "", Identity, Gzip, Snappy, Zstd:
+		return nil
+	}
+	return &UnsupportedError{Encoding: encoding}
+}
+
+// CheckEncode returns an error if the Content-Encoding cannot be encoded.
+func CheckEncode(encoding string) error {
+	switch encoding {
+	case "", Identity, Gzip, Snappy:
+		return nil
+	}
+	return &UnsupportedError{Encoding: encoding}
+}
+
+// NewDecoder returns a reader of the body decoded from the Content-Encoding.
+// The snappy block format is decoded at once, the body and its decoded size
+// are limited to maxSize, like the memory of the zstd decoder.
+func NewDecoder(encoding string, body io.Reader, maxSize int64) (io.ReadCloser, error) {
+	switch encoding {
+	case "", Identity:
+		return ioutil.NopCloser(body), nil
+	case Gzip:
+		return gzip.NewReader(body)
+	case Snappy:
+		data, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > maxSize {
+			return nil, ErrTooLarge
+		}
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if int64(n) > maxSize {
+			return nil, ErrTooLarge
+		}
+		decoded, err := snappy.Decode(nil, data)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(decoded)), nil
+	case Zstd:
+		opts := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+		if maxSize > 0 {
+			opts = append(opts, zstd.WithDecoderMaxMemory(uint64(maxSize)))
+		}
+		d, err := zstd.NewReader(body, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReader{d}, nil
+	}
+	return nil, &UnsupportedError{Encoding: encoding}
+}
+
+// zstdReader releases the goroutines of the zstd decoder when it is closed.
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (r *zstdReader) Close() error {
+	r.Decoder.Close()
+	return nil
+}
+
+// Encode returns a reader of the body encoded with the Content-Encoding,
+// gzip is encoded while the body is read.
+func Encode(encoding string, body io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "", Identity:
+		return body, nil
+	case Gzip:
+		pr, pw := io.Pipe()
+		go func() {
+			gw := gzip.NewWriter(pw)
+			_, err := io.Copy(gw, body)
+			if err == nil {
+				err = gw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	case Snappy:
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(snappy.Encode(nil, data)), nil
+	}
+	return nil, &UnsupportedError{Encoding: encoding}
+}
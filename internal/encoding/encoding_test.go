@@ -0,0 +1,70 @@
+package encoding
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	body := strings.Repeat("cpu value=42i 1000000000\n", 100)
+	for _, encoding := range []string{"", Identity, Gzip, Snappy} {
+		r, err := Encode(encoding, strings.NewReader(body))
+		require.NoError(t, err, encoding)
+		encoded, err := ioutil.ReadAll(r)
+		require.NoError(t, err, encoding)
+		if encoding == Gzip || encoding == Snappy {
+			assert.True(t, len(encoded) < len(body), encoding)
+		}
+
+		d, err := NewDecoder(encoding, bytes.NewReader(encoded), int64(len(body)))
+		require.NoError(t, err, encoding)
+		decoded, err := ioutil.ReadAll(d)
+		require.NoError(t, err, encoding)
+		assert.Equal(t, body, string(decoded), encoding)
+	}
+}
+
+func TestDecodeZstd(t *testing.T) {
+	body := strings.Repeat("cpu value=42i 1000000000\n", 100)
+	e, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	encoded := e.EncodeAll([]byte(body), nil)
+	assert.True(t, len(encoded) < len(body))
+
+	d, err := NewDecoder(Zstd, bytes.NewReader(encoded), int64(len(body)))
+	require.NoError(t, err)
+	decoded, err := ioutil.ReadAll(d)
+	require.NoError(t, err)
+	assert.NoError(t, d.Close())
+	assert.Equal(t, body, string(decoded))
+
+	d, err = NewDecoder(Zstd, strings.NewReader("not zstd"), 100)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(d)
+	assert.Error(t, err)
+}
+
+func TestDecodeSnappyTooLarge(t *testing.T) {
+	encoded := snappy.Encode(nil, bytes.Repeat([]byte("a"), 1000))
+	_, err := NewDecoder(Snappy, bytes.NewReader(encoded), 999)
+	assert.Equal(t, ErrTooLarge, err)
+}
+
+func TestUnsupported(t *testing.T) {
+	assert.NoError(t, Check(Snappy))
+	assert.NoError(t, Check(Zstd))
+	assert.Error(t, Check("br"))
+	assert.NoError(t, CheckEncode(Gzip))
+	assert.Error(t, CheckEncode(Zstd))
+	_, err := NewDecoder("br", strings.NewReader(""), 10)
+	assert.IsType(t, &UnsupportedError{}, err)
+	_, err = Encode(Zstd, strings.NewReader(""))
+	assert.IsType(t, &UnsupportedError{}, err)
+}
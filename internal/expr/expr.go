@@ -0,0 +1,521 @@
+// Package expr evaluates the boolean expressions of the metricpass filters on
+// the name, tags and fields of metrics, like
+//
+//	tags.tenant == "acme" && (name == "cpu" || fields.usage_idle < 10)
+//
+// The values are the name, tags.<key> and fields.<key>, or tags["<key>"]
+// and fields["<key>"] for the keys that are not identifiers. The missing tags
+// and fields are null. The literals are strings in double or single quotes,
+// numbers, true, false and null.
+//
+// The comparisons are ==, !=, <, <=, >, >=, =~ and !~ with a regular
+// expression and "in" with a list of literals, like
+// tags.region in ["us-east", "us-west"]. Numbers compare to numbers and
+// strings to strings, a comparison of values of different types is false
+// except for !=. The comparisons are combined with &&, || and !, a value by
+// itself is false if it is null, false, 0 or the empty string.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled expression.
+type Expr struct {
+	source string
+	root   node
+}
+
+// Compile parses the expression.
+func Compile(source string) (*Expr, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Eval reports whether the metric with the name, tags and fields matches the
+// expression.
+func (e *Expr) Eval(name string, tags map[string]string, fields map[string]interface{}) bool {
+	return truthy(e.root.eval(&metric{name: name, tags: tags, fields: fields}))
+}
+
+type metric struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// The values of the expressions are nil, string, float64 and bool.
+type node interface {
+	eval(m *metric) interface{}
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l *literal) eval(*metric) interface{} {
+	return l.value
+}
+
+type nameNode struct{}
+
+func (nameNode) eval(m *metric) interface{} {
+	return m.name
+}
+
+type tagNode struct {
+	key string
+}
+
+func (t *tagNode) eval(m *metric) interface{} {
+	if v, ok := m.tags[t.key]; ok {
+		return v
+	}
+	return nil
+}
+
+type fieldNode struct {
+	key string
+}
+
+func (f *fieldNode) eval(m *metric) interface{} {
+	v, ok := m.fields[f.key]
+	if !ok {
+		return nil
+	}
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case float64, string, bool:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(m *metric) interface{} {
+	return !truthy(n.operand.eval(m))
+}
+
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (l *logicalNode) eval(m *metric) interface{} {
+	left := truthy(l.left.eval(m))
+	if l.and != left {
+		return left
+	}
+	return truthy(l.right.eval(m))
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (c *compareNode) eval(m *metric) interface{} {
+	left, right := c.left.eval(m), c.right.eval(m)
+	switch c.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+type matchNode struct {
+	negate  bool
+	operand node
+	re      *regexp.Regexp
+}
+
+func (n *matchNode) eval(m *metric) interface{} {
+	s, ok := n.operand.eval(m).(string)
+	return ok && n.re.MatchString(s) != n.negate
+}
+
+type inNode struct {
+	operand node
+	values  []interface{}
+}
+
+func (n *inNode) eval(m *metric) interface{} {
+	v := n.operand.eval(m)
+	for _, value := range n.values {
+		if equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	return a == b
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// operators, the longest first
+var operators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func isIdentRune(r rune, first bool) bool {
+	if r == '_' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && (unicode.IsDigit(r) || r == '.' || r == '-')
+}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	i := 0
+next:
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(source) && source[j] != c {
+				if source[j] == '\\' && c == '"' {
+					j++
+				}
+				j++
+			}
+			if j >= len(source) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			text := source[i : j+1]
+			value := source[i+1 : j]
+			if c == '"' {
+				var err error
+				if value, err = strconv.Unquote(text); err != nil {
+					return nil, fmt.Errorf("invalid string %s at %d", text, i)
+				}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, value: value, pos: i})
+			i = j + 1
+			continue
+		case c >= '0' && c <= '9' || c == '-' || c == '.':
+			j := i + 1
+			for j < len(source) && strings.IndexByte("0123456789.eE+-", source[j]) >= 0 {
+				if (source[j] == '+' || source[j] == '-') && source[j-1] != 'e' && source[j-1] != 'E' {
+					break
+				}
+				j++
+			}
+			v, err := strconv.ParseFloat(source[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s at %d", source[i:j], i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:j], value: v, pos: i})
+			i = j
+			continue
+		case isIdentRune(rune(c), true):
+			j := i + 1
+			for j < len(source) && isIdentRune(rune(source[j]), false) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:j], pos: i})
+			i = j
+			continue
+		}
+		for _, op := range operators {
+			if strings.HasPrefix(source[i:], op) {
+				tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+				i += len(op)
+				continue next
+			}
+		}
+		return nil, fmt.Errorf("unexpected %q at %d", c, i)
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOp && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of the expression")
+	}
+	return fmt.Errorf("unexpected %s at %d", t.text, t.pos)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokenOp && (t.text == "=~" || t.text == "!~"):
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, fmt.Errorf("%s at %d expects a string", t.text, t.pos)
+		}
+		re, err := regexp.Compile(pattern.value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at %d: %s", pattern.pos, err)
+		}
+		return &matchNode{negate: t.text == "!~", operand: left, re: re}, nil
+	case t.kind == tokenOp && strings.IndexByte("=!<>", t.text[0]) >= 0 && t.text != "!":
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: t.text, left: left, right: right}, nil
+	case t.kind == tokenIdent && t.text == "in":
+		p.next()
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{operand: left, values: values}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseList() ([]interface{}, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for !p.accept("]") {
+		if len(values) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.peek()
+		n, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		l, ok := n.(*literal)
+		if !ok {
+			return nil, fmt.Errorf("the values of the list at %d must be literals", t.pos)
+		}
+		values = append(values, l.value)
+	}
+	return values, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString, tokenNumber:
+		return &literal{value: t.value}, nil
+	case tokenOp:
+		if t.text != "(" {
+			break
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{}, nil
+		case "name":
+			return nameNode{}, nil
+		case "tags", "fields":
+			if !p.accept("[") {
+				break
+			}
+			key := p.next()
+			if key.kind != tokenString {
+				return nil, p.unexpected(key)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return keyNode(t.text, key.value.(string)), nil
+		}
+		if i := strings.IndexByte(t.text, '.'); i > 0 && i < len(t.text)-1 {
+			switch t.text[:i] {
+			case "tags", "fields":
+				return keyNode(t.text[:i], t.text[i+1:]), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown value %s at %d, expected name, tags.<key> or fields.<key>", t.text, t.pos)
+	}
+	return nil, p.unexpected(t)
+}
+
+func keyNode(kind, key string) node {
+	if kind == "tags" {
+		return &tagNode{key: key}
+	}
+	return &fieldNode{key: key}
+}
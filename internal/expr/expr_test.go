@@ -0,0 +1,75 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	tags := map[string]string{"tenant": "acme", "host": "web-1", "dc.name": "ams"}
+	fields := map[string]interface{}{
+		"usage_idle": 5.5,
+		"count":      int64(42),
+		"up":         true,
+		"status":     "ok",
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`tags.tenant == "acme"`, true},
+		{`tags.tenant == 'globex'`, false},
+		{`tags.tenant != "globex"`, true},
+		{`tags.missing != "acme"`, true},
+		{`tags.missing == null`, true},
+		{`tags.missing`, false},
+		{`tags.tenant`, true},
+		{`!tags.missing`, true},
+		{`tags["dc.name"] == "ams"`, true},
+		{`name == "cpu" && fields.usage_idle < 10`, true},
+		{`name == "mem" || fields.count >= 42`, true},
+		{`fields.count > 42`, false},
+		{`fields.count == 42`, true},
+		{`fields.count == "42"`, false},
+		{`fields.up`, true},
+		{`fields.up == false`, false},
+		{`fields.status <= "ok"`, true},
+		{`fields.status < 1`, false},
+		{`tags.host =~ "^web-\\d+$"`, true},
+		{`tags.host !~ '^db-'`, true},
+		{`tags.missing =~ ".*"`, false},
+		{`tags.tenant in ["acme", "globex"]`, true},
+		{`fields.count in [1, 2]`, false},
+		{`!(tags.tenant == "acme" && name == "cpu") || fields.usage_idle == 5.5`, true},
+		{`!(tags.tenant == "acme" && name == "cpu")`, false},
+		{`fields.usage_idle > -1e3`, true},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, e.Eval("cpu", tags, fields), tt.expr)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{`tags.tenant ==`, "unexpected end of the expression"},
+		{`tenant == "acme"`, "unknown value tenant at 0, expected name, tags.<key> or fields.<key>"},
+		{`tags.tenant == "acme`, "unterminated string at 15"},
+		{`(name == "cpu"`, "unexpected end of the expression"},
+		{`name == "cpu" name`, "unexpected name at 14"},
+		{`tags.host =~ tags.pattern`, "=~ at 10 expects a string"},
+		{`tags.host =~ "("`, "invalid regular expression at 13: error parsing regexp: missing closing ): `(`"},
+		{`tags.host in [tags.x]`, "the values of the list at 14 must be literals"},
+		{`name = "cpu"`, "unexpected '=' at 5"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		assert.EqualError(t, err, tt.err, tt.expr)
+	}
+}
@@ -0,0 +1,305 @@
+// Package globaltags computes global tags when the config is loaded, from
+// the metadata of the instance or of the pod telegraf runs in, or from the
+// output of a command.
+package globaltags
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// defaultTimeout is the timeout of the requests and commands of the
+// providers.
+const defaultTimeout = 5 * time.Second
+
+// Provider provides global tags.
+type Provider interface {
+	Tags() (map[string]string, error)
+}
+
+// metadata is a metadata path and the tag of its value.
+type metadata struct {
+	tag  string
+	path string
+}
+
+// EC2 provides the tags of the EC2 instance from its instance metadata
+// service, with a session token like IMDSv2 requires if the service gives
+// one. Names selects the tags, they are all provided if it is empty.
+type EC2 struct {
+	Endpoint string            `toml:"endpoint"`
+	Names    []string          `toml:"tags"`
+	Timeout  internal.Duration `toml:"timeout"`
+}
+
+var ec2Metadata = []metadata{
+	{"instance_id", "instance-id"},
+	{"instance_type", "instance-type"},
+	{"ami_id", "ami-id"},
+	{"availability_zone", "placement/availability-zone"},
+	{"region", "placement/region"},
+}
+
+func (e *EC2) Tags() (map[string]string, error) {
+	selection, err := selected(ec2Metadata, e.Names)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	client := newClient(e.Timeout)
+
+	header := make(http.Header)
+	req, err := http.NewRequest("PUT", endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if token, err := do(client, req); err == nil {
+		header.Set("X-aws-ec2-metadata-token", token)
+	}
+
+	tags := make(map[string]string)
+	for _, m := range selection {
+		value, err := get(client, endpoint+"/latest/meta-data/"+m.path, header)
+		if err != nil {
+			return nil, err
+		}
+		tags[m.tag] = value
+	}
+	return tags, nil
+}
+
+// GCP provides the tags of the Compute Engine instance from its metadata
+// server. Names selects the tags, they are all provided if it is empty.
+type GCP struct {
+	Endpoint string            `toml:"endpoint"`
+	Names    []string          `toml:"tags"`
+	Timeout  internal.Duration `toml:"timeout"`
+}
+
+var gcpMetadata = []metadata{
+	{"project_id", "project/project-id"},
+	{"instance_id", "instance/id"},
+	{"instance_name", "instance/name"},
+	{"machine_type", "instance/machine-type"},
+	{"zone", "instance/zone"},
+	// the region is the zone without its last part
+	{"region", "instance/zone"},
+}
+
+func (g *GCP) Tags() (map[string]string, error) {
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "http://metadata.google.internal"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	selection, err := selected(gcpMetadata, g.Names)
+	if err != nil {
+		return nil, err
+	}
+	client := newClient(g.Timeout)
+	header := http.Header{"Metadata-Flavor": []string{"Google"}}
+
+	tags := make(map[string]string)
+	values := make(map[string]string)
+	for _, m := range selection {
+		value, ok := values[m.path]
+		if !ok {
+			value, err = get(client, endpoint+"/computeMetadata/v1/"+m.path, header)
+			if err != nil {
+				return nil, err
+			}
+			values[m.path] = value
+		}
+		switch m.tag {
+		case "machine_type", "zone":
+			// projects/<number>/zones/<zone> and
+			// projects/<number>/machineTypes/<type>
+			value = value[strings.LastIndex(value, "/")+1:]
+		case "region":
+			value = value[strings.LastIndex(value, "/")+1:]
+			if i := strings.LastIndex(value, "-"); i > 0 {
+				value = value[:i]
+			}
+		}
+		tags[m.tag] = value
+	}
+	return tags, nil
+}
+
+// Kubernetes provides the tags of the files of a downward API volume: each
+// file is a tag named after it, like a node_name file holding
+// spec.nodeName, and the labels of a labels file are tags too. The
+// annotations file is skipped.
+type Kubernetes struct {
+	Directory string `toml:"directory"`
+}
+
+func (k *Kubernetes) Tags() (map[string]string, error) {
+	dir := k.Directory
+	if dir == "" {
+		dir = "/etc/podinfo"
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for _, file := range files {
+		name := file.Name()
+		// the volume keeps the files in hidden directories and links them
+		if strings.HasPrefix(name, ".") || name == "annotations" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if name != "labels" {
+			tags[name] = strings.TrimSpace(string(contents))
+			continue
+		}
+		labels, err := parseLines(contents, true)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		for key, value := range labels {
+			tags[key] = value
+		}
+	}
+	return tags, nil
+}
+
+// Exec provides the tags written by a command to its stdout, a key=value
+// pair per line.
+type Exec struct {
+	Command []string          `toml:"command"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+func (e *Exec) Tags() (map[string]string, error) {
+	if len(e.Command) == 0 {
+		return nil, fmt.Errorf("no command")
+	}
+	timeout := e.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	cmd := exec.Command(e.Command[0], e.Command[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := internal.RunTimeout(cmd, timeout); err != nil {
+		return nil, fmt.Errorf("%s: %s %s", e.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	tags, err := parseLines(stdout.Bytes(), false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", e.Command[0], err)
+	}
+	return tags, nil
+}
+
+// parseLines parses the key=value lines of contents, the blank lines are
+// skipped. The values are unquoted if quoted is set.
+func parseLines(contents []byte, quoted bool) (map[string]string, error) {
+	tags := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected key=value, not %q", n, line)
+		}
+		key, value := line[:i], line[i+1:]
+		if quoted {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value %s", n, value)
+			}
+			value = unquoted
+		}
+		tags[key] = value
+	}
+	return tags, scanner.Err()
+}
+
+// selected returns the metadata of the tags, all of them if tags is empty.
+func selected(all []metadata, tags []string) ([]metadata, error) {
+	if len(tags) == 0 {
+		return all, nil
+	}
+	var out []metadata
+	for _, tag := range tags {
+		found := false
+		for _, m := range all {
+			if m.tag == tag {
+				out = append(out, m)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown tag %s", tag)
+		}
+	}
+	return out, nil
+}
+
+func newClient(timeout internal.Duration) *http.Client {
+	if timeout.Duration == 0 {
+		timeout.Duration = defaultTimeout
+	}
+	// the metadata services are local, they are never reached through a
+	// proxy
+	return &http.Client{Timeout: timeout.Duration}
+}
+
+func get(client *http.Client, url string, header http.Header) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return do(client, req)
+}
+
+// do returns the trimmed body of the response to the request, an error if
+// its status is not 200.
+func do(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,130 @@
+package globaltags
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEC2(t *testing.T) {
+	values := map[string]string{
+		"/latest/meta-data/instance-id":                 "i-0123456789abcdef0",
+		"/latest/meta-data/instance-type":               "m5.large",
+		"/latest/meta-data/ami-id":                      "ami-0abcdef",
+		"/latest/meta-data/placement/availability-zone": "eu-west-1a",
+		"/latest/meta-data/placement/region":            "eu-west-1",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/latest/api/token" {
+			w.Write([]byte("token"))
+			return
+		}
+		value, ok := values[r.URL.Path]
+		if !ok || r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	defer ts.Close()
+
+	tags, err := (&EC2{Endpoint: ts.URL}).Tags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"instance_id":       "i-0123456789abcdef0",
+		"instance_type":     "m5.large",
+		"ami_id":            "ami-0abcdef",
+		"availability_zone": "eu-west-1a",
+		"region":            "eu-west-1",
+	}, tags)
+
+	tags, err = (&EC2{Endpoint: ts.URL, Names: []string{"region"}}).Tags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu-west-1"}, tags)
+
+	_, err = (&EC2{Endpoint: ts.URL, Names: []string{"rack"}}).Tags()
+	assert.EqualError(t, err, "unknown tag rack")
+}
+
+func TestGCP(t *testing.T) {
+	values := map[string]string{
+		"/computeMetadata/v1/project/project-id":    "acme",
+		"/computeMetadata/v1/instance/id":           "4520031799277581759",
+		"/computeMetadata/v1/instance/name":         "telegraf-1",
+		"/computeMetadata/v1/instance/machine-type": "projects/123/machineTypes/n1-standard-1",
+		"/computeMetadata/v1/instance/zone":         "projects/123/zones/us-central1-a",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, ok := values[r.URL.Path]
+		if !ok || r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	defer ts.Close()
+
+	tags, err := (&GCP{Endpoint: ts.URL}).Tags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"project_id":    "acme",
+		"instance_id":   "4520031799277581759",
+		"instance_name": "telegraf-1",
+		"machine_type":  "n1-standard-1",
+		"zone":          "us-central1-a",
+		"region":        "us-central1",
+	}, tags)
+
+	delete(values, "/computeMetadata/v1/instance/name")
+	_, err = (&GCP{Endpoint: ts.URL}).Tags()
+	assert.Error(t, err)
+}
+
+func TestKubernetes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "podinfo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// the files are links to a hidden directory, like in a volume
+	data := filepath.Join(dir, "..data")
+	require.NoError(t, os.Mkdir(data, 0755))
+	for name, contents := range map[string]string{
+		"node_name":   "node-1\n",
+		"namespace":   "monitoring",
+		"labels":      "app=\"telegraf\"\npod-template-hash=\"7d9c\"\n",
+		"annotations": "kubernetes.io/config.seen=\"2026-10-14\"\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(data, name), []byte(contents), 0644))
+		require.NoError(t, os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)))
+	}
+
+	tags, err := (&Kubernetes{Directory: dir}).Tags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"node_name":         "node-1",
+		"namespace":         "monitoring",
+		"app":               "telegraf",
+		"pod-template-hash": "7d9c",
+	}, tags)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(data, "labels"), []byte("app=telegraf\n"), 0644))
+	_, err = (&Kubernetes{Directory: dir}).Tags()
+	assert.Contains(t, err.Error(), "line 1: invalid value telegraf")
+}
+
+func TestExec(t *testing.T) {
+	tags, err := (&Exec{Command: []string{"printf", `rack=r1\n\ndc=eu\n`}}).Tags()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"rack": "r1", "dc": "eu"}, tags)
+
+	_, err = (&Exec{Command: []string{"printf", `rack\n`}}).Tags()
+	assert.EqualError(t, err, `printf: line 1: expected key=value, not "rack"`)
+
+	_, err = (&Exec{Command: []string{"false"}}).Tags()
+	assert.Error(t, err)
+}
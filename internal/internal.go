@@ -17,6 +17,8 @@ import (
 	"strings"
 	"time"
 	"unicode"
+
+	internaltls "github.com/influxdata/telegraf/internal/tls"
 )
 
 const alphanum string = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
@@ -67,6 +69,45 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 	return nil
 }
 
+// Size is a number of bytes, like "10MB", "512KiB" or 1024.
+type Size struct {
+	Size int64
+}
+
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+}
+
+// UnmarshalTOML parses the size from a TOML integer or string.
+func (s *Size) UnmarshalTOML(b []byte) error {
+	str := string(b)
+	if uq, err := strconv.Unquote(str); err == nil {
+		str = uq
+	}
+	str = strings.TrimSpace(str)
+	i := strings.IndexFunc(str, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(str)
+	}
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(str[i:]))]
+	if !ok {
+		return fmt.Errorf("invalid size %q", str)
+	}
+	n, err := strconv.ParseFloat(str[:i], 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q", str)
+	}
+	s.Size = int64(n * float64(unit))
+	return nil
+}
+
 // ReadLines reads contents from a file and splits them by new lines.
 // A convenience wrapper to ReadLinesOffsetN(filename, 0, -1).
 func ReadLines(filename string) ([]string, error) {
@@ -113,8 +154,10 @@ func RandomString(n int) string {
 }
 
 // GetTLSConfig gets a tls.Config object from the given certs, key, and CA files.
+// Deprecated: embed tls.ClientConfig from internal/tls in the plugin instead.
 // you must give the full path to the files.
 // If all files are blank and InsecureSkipVerify=false, returns a nil pointer.
+// The config is restricted to the FIPS settings in FIPS mode.
 func GetTLSConfig(
 	SSLCert, SSLKey, SSLCA string,
 	InsecureSkipVerify bool,
@@ -151,6 +194,10 @@ func GetTLSConfig(
 		t.BuildNameToCertificate()
 	}
 
+	if internaltls.FIPSMode {
+		internaltls.ApplyFIPS(t)
+	}
+
 	// will be nil by default if nothing is provided
 	return t, nil
 }
@@ -1,10 +1,12 @@
 package internal
 
 import (
+	"crypto/tls"
 	"os/exec"
 	"testing"
 	"time"
 
+	internaltls "github.com/influxdata/telegraf/internal/tls"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -162,3 +164,39 @@ func TestDuration(t *testing.T) {
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
 }
+
+func TestSize(t *testing.T) {
+	var s Size
+
+	assert.NoError(t, s.UnmarshalTOML([]byte(`1024`)))
+	assert.Equal(t, int64(1024), s.Size)
+
+	assert.NoError(t, s.UnmarshalTOML([]byte(`"10MB"`)))
+	assert.Equal(t, int64(10000000), s.Size)
+
+	assert.NoError(t, s.UnmarshalTOML([]byte(`"1.5 KiB"`)))
+	assert.Equal(t, int64(1536), s.Size)
+
+	assert.Error(t, s.UnmarshalTOML([]byte(`"10 parsecs"`)))
+}
+
+func TestGetTLSConfig(t *testing.T) {
+	config, err := GetTLSConfig("", "", "", false)
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	// a certificate without its key is ignored
+	config, err = GetTLSConfig("/nonexistent/cert.pem", "", "", false)
+	assert.NoError(t, err)
+	assert.Empty(t, config.Certificates)
+
+	_, err = GetTLSConfig("", "", "/nonexistent/ca.pem", false)
+	assert.Error(t, err)
+
+	internaltls.FIPSMode = true
+	defer func() { internaltls.FIPSMode = false }()
+	config, err = GetTLSConfig("", "", "", true)
+	assert.NoError(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Listen listens on the address of a service: "unix:///path" listens on a
+// unix socket, other addresses are TCP addresses like ":8186". The unix socket
+// left by a previous run is removed, the socket is given the permissions of
+// mode, an octal number like "0660", when it is not empty and is removed when
+// the listener is closed.
+func Listen(address, mode string) (net.Listener, error) {
+	if !strings.HasPrefix(address, "unix://") {
+		if mode != "" {
+			return nil, fmt.Errorf("socket_mode requires a unix:// address")
+		}
+		return net.Listen("tcp", address)
+	}
+
+	var perm os.FileMode
+	if mode != "" {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || m > 0777 {
+			return nil, fmt.Errorf("invalid socket_mode %q", mode)
+		}
+		perm = os.FileMode(m)
+	}
+
+	path := strings.TrimPrefix(address, "unix://")
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" {
+		if err := os.Chmod(path, perm); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
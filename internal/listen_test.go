@@ -0,0 +1,52 @@
+// +build !windows
+
+package internal
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegraf.sock")
+
+	// the socket of a previous run is removed
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := Listen("unix://"+path, "0600")
+	require.NoError(t, err)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+
+	l.Close()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListenErrors(t *testing.T) {
+	_, err := Listen("localhost:0", "0600")
+	assert.Error(t, err)
+	_, err = Listen("unix:///tmp/telegraf.sock", "0999")
+	assert.Error(t, err)
+
+	l, err := Listen("localhost:0", "")
+	require.NoError(t, err)
+	l.Close()
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal/expr"
 )
 
 // TagFilter is the name of a tag, and the values on which to filter
@@ -33,6 +34,11 @@ type Filter struct {
 	TagInclude []string
 	tagInclude filter.Filter
 
+	// MetricPass is an expression on the name, tags and fields, the metrics
+	// not matching it are dropped.
+	MetricPass string
+	metricPass *expr.Expr
+
 	isActive bool
 }
 
@@ -45,7 +51,8 @@ func (f *Filter) Compile() error {
 		len(f.TagInclude) == 0 &&
 		len(f.TagExclude) == 0 &&
 		len(f.TagPass) == 0 &&
-		len(f.TagDrop) == 0 {
+		len(f.TagDrop) == 0 &&
+		f.MetricPass == "" {
 		return nil
 	}
 
@@ -90,6 +97,13 @@ func (f *Filter) Compile() error {
 			return fmt.Errorf("Error compiling 'tagpass', %s", err)
 		}
 	}
+
+	if f.MetricPass != "" {
+		f.metricPass, err = expr.Compile(f.MetricPass)
+		if err != nil {
+			return fmt.Errorf("Error compiling 'metricpass', %s", err)
+		}
+	}
 	return nil
 }
 
@@ -116,6 +130,12 @@ func (f *Filter) Apply(
 		return false
 	}
 
+	// check if the metric matches the metricpass expression, before its
+	// fields and tags are filtered
+	if f.metricPass != nil && !f.metricPass.Eval(measurement, tags, fields) {
+		return false
+	}
+
 	// filter fields
 	for fieldkey, _ := range fields {
 		if !f.shouldFieldPass(fieldkey) {
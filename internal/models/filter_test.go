@@ -215,6 +215,27 @@ func TestFilter_FieldDrop(t *testing.T) {
 	}
 }
 
+func TestFilter_MetricPass(t *testing.T) {
+	f := Filter{
+		MetricPass: `tags.tenant == "acme" && fields.value > 1`,
+		TagExclude: []string{"tenant"},
+	}
+	require.NoError(t, f.Compile())
+
+	tags := map[string]string{"tenant": "acme", "host": "web"}
+	assert.True(t, f.Apply("m", map[string]interface{}{"value": int64(2)}, tags))
+	// the tags are excluded once the expression is evaluated
+	assert.Equal(t, map[string]string{"host": "web"}, tags)
+
+	assert.False(t, f.Apply("m", map[string]interface{}{"value": int64(1)},
+		map[string]string{"tenant": "acme"}))
+	assert.False(t, f.Apply("m", map[string]interface{}{"value": int64(2)},
+		map[string]string{"tenant": "globex"}))
+
+	f = Filter{MetricPass: `tags.tenant ==`}
+	assert.EqualError(t, f.Compile(), "Error compiling 'metricpass', unexpected end of the expression")
+}
+
 func TestFilter_TagPass(t *testing.T) {
 	filters := []TagFilter{
 		TagFilter{
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// LogName returns the name of a plugin instance in the logs, the name of the
+// plugin followed by its alias if it has one.
+func LogName(name, alias string) string {
+	if alias == "" {
+		return name
+	}
+	return name + "::" + alias
+}
+
+// statTags returns the tags of the internal stats of a plugin instance.
+func statTags(kind, name, alias string) map[string]string {
+	tags := map[string]string{kind: name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	return tags
+}
+
+// pluginLogger prefixes the messages with the log name of the plugin.
+type pluginLogger struct {
+	prefix string
+}
+
+// NewLogger returns the logger of a plugin instance, like inputs.nats_consumer
+// with the alias east: its messages are prefixed with
+// [inputs.nats_consumer::east].
+func NewLogger(name, alias string) telegraf.Logger {
+	return &pluginLogger{prefix: "[" + LogName(name, alias) + "] "}
+}
+
+func (l *pluginLogger) Errorf(format string, args ...interface{}) {
+	log.Printf("E! "+l.prefix+format, args...)
+}
+
+func (l *pluginLogger) Warnf(format string, args ...interface{}) {
+	log.Printf("W! "+l.prefix+format, args...)
+}
+
+func (l *pluginLogger) Infof(format string, args ...interface{}) {
+	log.Printf("I! "+l.prefix+format, args...)
+}
+
+func (l *pluginLogger) Debugf(format string, args ...interface{}) {
+	log.Printf("D! "+l.prefix+format, args...)
+}
+
+// SetLogger sets the logger of the plugin if it is a telegraf.LoggingPlugin.
+func SetLogger(plugin interface{}, name, alias string) {
+	if p, ok := plugin.(telegraf.LoggingPlugin); ok {
+		p.SetLogger(NewLogger(name, alias))
+	}
+}
+
+// SetAlias sets the alias of the plugin if it is a telegraf.AliasedPlugin.
+func SetAlias(plugin interface{}, alias string) {
+	if p, ok := plugin.(telegraf.AliasedPlugin); ok {
+		p.SetAlias(alias)
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+)
+
+type loggingPlugin struct {
+	log telegraf.Logger
+}
+
+func (p *loggingPlugin) SetLogger(logger telegraf.Logger) {
+	p.log = logger
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	p := &loggingPlugin{}
+	SetLogger(p, "inputs.nats_consumer", "orders")
+	p.log.Errorf("error %d", 1)
+	p.log.Warnf("warning")
+	SetLogger(p, "inputs.nats_consumer", "")
+	p.log.Infof("info")
+	p.log.Debugf("debug")
+	assert.Equal(t, "E! [inputs.nats_consumer::orders] error 1\n"+
+		"W! [inputs.nats_consumer::orders] warning\n"+
+		"I! [inputs.nats_consumer] info\n"+
+		"D! [inputs.nats_consumer] debug\n", buf.String())
+
+	// plugins without a logger are left as is
+	SetLogger(struct{}{}, "inputs.cpu", "")
+}
+
+func TestLogName(t *testing.T) {
+	input := NewRunningInput(nil, &InputConfig{Name: "nats_consumer", Alias: "orders"})
+	assert.Equal(t, "inputs.nats_consumer::orders", input.LogName())
+	input = NewRunningInput(nil, &InputConfig{Name: "nats_consumer"})
+	assert.Equal(t, "inputs.nats_consumer", input.LogName())
+	output := NewRunningOutput("nats", nil, &OutputConfig{Name: "nats", Alias: "east"}, 0, 0)
+	assert.Equal(t, "nats::east", output.LogName())
+}
@@ -31,7 +31,8 @@ func NewRunningAggregator(
 // AggregatorConfig containing configuration parameters for the running
 // aggregator plugin.
 type AggregatorConfig struct {
-	Name string
+	Name  string
+	Alias string
 
 	DropOriginal      bool
 	NameOverride      string
@@ -44,10 +45,20 @@ type AggregatorConfig struct {
 	Delay  time.Duration
 }
 
+// Aggregator returns the aggregator plugin.
+func (r *RunningAggregator) Aggregator() telegraf.Aggregator {
+	return r.a
+}
+
 func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// LogName returns the name of the aggregator in the logs.
+func (r *RunningAggregator) LogName() string {
+	return LogName(r.Name(), r.Config.Alias)
+}
+
 func (r *RunningAggregator) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
@@ -72,6 +83,7 @@ func (r *RunningAggregator) MakeMetric(
 
 	if m != nil {
 		m.SetAggregate(true)
+		traceNew(m, r.Name())
 	}
 
 	return m
@@ -89,12 +101,19 @@ func (r *RunningAggregator) Add(in telegraf.Metric) bool {
 		t := in.Time()
 		if ok := r.Config.Filter.Apply(name, fields, tags); !ok {
 			// aggregator should not apply this metric
+			in.Drop()
 			return false
 		}
 
+		in.Drop()
 		in, _ = metric.New(name, tags, fields, t)
 	}
 
+	if r.Config.DropOriginal {
+		traceLog(in, "added to %s, dropping the original", r.Name())
+	} else {
+		traceLog(in, "added to %s", r.Name())
+	}
 	r.metrics <- in
 	return r.Config.DropOriginal
 }
@@ -153,9 +172,11 @@ func (r *RunningAggregator) Run(
 				m.Time().After(r.periodEnd.Add(truncation).Add(r.Config.Delay)) {
 				// the metric is outside the current aggregation period, so
 				// skip it.
+				m.Drop()
 				continue
 			}
 			r.add(m)
+			m.Drop()
 		case <-periodT.C:
 			r.periodStart = r.periodEnd
 			r.periodEnd = r.periodStart.Add(r.Config.Period)
@@ -2,9 +2,11 @@ package models
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/cron"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/selfstat"
 )
@@ -15,42 +17,95 @@ type RunningInput struct {
 	Input  telegraf.Input
 	Config *InputConfig
 
+	// Fingerprint identifies the configuration of the input, the inputs of
+	// two configs with the same fingerprint are configured the same.
+	Fingerprint string
+	// Store is the namespace of the input in the state file if it keeps
+	// state, it is kept with the input across config reloads.
+	Store telegraf.Store
+
 	trace       bool
 	defaultTags map[string]string
 
+	// gathering is set while a Gather of the input runs, a Gather left
+	// running after its gather_timeout keeps it set until it returns.
+	gathering int32
+
 	MetricsGathered selfstat.Stat
+	GatherErrors    selfstat.Stat
+	GatherTimeouts  selfstat.Stat
 }
 
 func NewRunningInput(
 	input telegraf.Input,
 	config *InputConfig,
 ) *RunningInput {
-	return &RunningInput{
+	r := &RunningInput{
 		Input:  input,
 		Config: config,
-		MetricsGathered: selfstat.Register(
-			"gather",
-			"metrics_gathered",
-			map[string]string{"input": config.Name},
-		),
 	}
+	r.MetricsGathered = selfstat.Register("gather", "metrics_gathered", r.StatTags())
+	r.GatherErrors = selfstat.Register("gather", "errors", r.StatTags())
+	r.GatherTimeouts = selfstat.Register("gather", "timeouts", r.StatTags())
+	return r
+}
+
+// StatTags returns the tags of the internal stats of the input, the alias
+// tells the instances of the input apart.
+func (r *RunningInput) StatTags() map[string]string {
+	return statTags("input", r.Config.Name, r.Config.Alias)
 }
 
 // InputConfig containing a name, interval, and filter
 type InputConfig struct {
 	Name              string
+	Alias             string
 	NameOverride      string
 	MeasurementPrefix string
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+	Precision         time.Duration
+	RoundInterval     *bool
+	LeaderOnly        bool
+
+	// CollectionJitter and CollectionOffset override those of the agent if
+	// they are set.
+	CollectionJitter *time.Duration
+	CollectionOffset *time.Duration
+
+	// GatherTimeout overrides the gather_timeout of the agent if it is set.
+	GatherTimeout *time.Duration
+
+	// Schedule gathers the input at the times of the cron schedule instead
+	// of every interval.
+	Schedule *cron.Schedule
+
+	// StartupErrorBehavior is what the agent does when the service input
+	// fails to start, one of the StartupError behaviors. It stops with an
+	// error if it is empty.
+	StartupErrorBehavior string
 }
 
+// The behaviors of the agent when a service input fails to start: it stops
+// with the error, runs without the input or starts it again every interval
+// until it starts.
+const (
+	StartupErrorError  = "error"
+	StartupErrorIgnore = "ignore"
+	StartupErrorRetry  = "retry"
+)
+
 func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// LogName returns the name of the input in the logs.
+func (r *RunningInput) LogName() string {
+	return LogName(r.Name(), r.Config.Alias)
+}
+
 // MakeMetric either returns a metric, or returns nil if the metric doesn't
 // need to be created (because of filtering, an error, etc.)
 func (r *RunningInput) MakeMetric(
@@ -75,6 +130,10 @@ func (r *RunningInput) MakeMetric(
 		t,
 	)
 
+	if m != nil {
+		traceNew(m, r.Name())
+	}
+
 	if r.trace && m != nil {
 		s := influx.NewSerializer()
 		octets, err := s.Serialize(m)
@@ -88,6 +147,17 @@ func (r *RunningInput) MakeMetric(
 	return m
 }
 
+// StartGather marks the input as gathering, it returns false if a previous
+// Gather is still running.
+func (r *RunningInput) StartGather() bool {
+	return atomic.CompareAndSwapInt32(&r.gathering, 0, 1)
+}
+
+// EndGather marks the end of the Gather started with StartGather.
+func (r *RunningInput) EndGather() {
+	atomic.StoreInt32(&r.gathering, 0)
+}
+
 func (r *RunningInput) Trace() bool {
 	return r.trace
 }
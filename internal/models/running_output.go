@@ -1,13 +1,16 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/buffer"
-	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -19,6 +22,49 @@ const (
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
 )
 
+// What happens when metrics are added to the full buffer of an output.
+const (
+	// The oldest metrics of the buffer are dropped.
+	BufferOverflowDropOldest = "drop_oldest"
+	// The metrics added are dropped.
+	BufferOverflowDropNewest = "drop_newest"
+	// Adding metrics blocks until the output writes, the inputs are blocked
+	// in turn once the channels of the agent are full.
+	BufferOverflowBlock = "block"
+)
+
+// CheckBufferOverflow returns an error if overflow is not a known strategy.
+func CheckBufferOverflow(overflow string) error {
+	switch overflow {
+	case "", BufferOverflowDropOldest, BufferOverflowDropNewest, BufferOverflowBlock:
+		return nil
+	}
+	return fmt.Errorf("unsupported metric_buffer_overflow %q, expected %s, %s or %s",
+		overflow, BufferOverflowDropOldest, BufferOverflowDropNewest, BufferOverflowBlock)
+}
+
+// errRateLimited is returned by Write for the batches held back by the rate
+// limit on shutdown, they stay in the buffer.
+var errRateLimited = errors.New("metrics held back by metric_rate_limit on shutdown")
+
+// The orders of the metrics of the batches written by an output, they are
+// written in the order they were added by default.
+const (
+	MetricOrderingNone = "none"
+	// The metrics are sorted by timestamp then by name.
+	MetricOrderingTime = "time"
+)
+
+// CheckMetricOrdering returns an error if ordering is not a known order.
+func CheckMetricOrdering(ordering string) error {
+	switch ordering {
+	case "", MetricOrderingNone, MetricOrderingTime:
+		return nil
+	}
+	return fmt.Errorf("unsupported metric_ordering %q, expected %s or %s",
+		ordering, MetricOrderingNone, MetricOrderingTime)
+}
+
 // RunningOutput contains the output configuration
 type RunningOutput struct {
 	Name              string
@@ -32,10 +78,31 @@ type RunningOutput struct {
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
+	BatchesWritten  selfstat.Stat
+	BatchesFailed   selfstat.Stat
+	MetricsDropped  selfstat.Stat
+	BlockTime       selfstat.Stat
+	RateLimitTime   selfstat.Stat
 
 	metrics     *buffer.Buffer
 	failMetrics *buffer.Buffer
 
+	// pending counts the metrics taken from the buffers that are written,
+	// they are still buffered for the block strategy.
+	pending int64
+	// space is signaled when metrics are written, with the block strategy.
+	space     *sync.Cond
+	unblocked bool
+	// batchID numbers the writes, it is logged with the traced metrics.
+	batchID uint64
+	// lastWrite is the unix time in nanoseconds of the last successful write.
+	lastWrite int64
+	// spool keeps the buffered metrics on disk until they are written, if
+	// it is set.
+	spool *buffer.Spool
+	// limiter spaces the writes with metric_rate_limit, if it is set.
+	limiter *rateLimiter
+
 	// Guards against concurrent calls to the Output as described in #3009
 	sync.Mutex
 }
@@ -53,6 +120,16 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DEFAULT_METRIC_BATCH_SIZE
 	}
+	if conf.MetricRateLimit > 0 && float64(batchSize) > conf.MetricRateLimit {
+		// a batch never has more metrics than the output may write in a
+		// second
+		batchSize = int(conf.MetricRateLimit)
+		if batchSize < 1 {
+			batchSize = 1
+		}
+		log.Printf("I! Output [%s] writes batches of %d metrics to keep under its metric_rate_limit",
+			LogName(name, conf.Alias), batchSize)
+	}
 	ro := &RunningOutput{
 		Name:              name,
 		metrics:           buffer.NewBuffer(batchSize),
@@ -64,33 +141,108 @@ func NewRunningOutput(
 		MetricsWritten: selfstat.Register(
 			"write",
 			"metrics_written",
-			map[string]string{"output": name},
+			statTags("output", name, conf.Alias),
 		),
 		MetricsFiltered: selfstat.Register(
 			"write",
 			"metrics_filtered",
-			map[string]string{"output": name},
+			statTags("output", name, conf.Alias),
 		),
 		BufferSize: selfstat.Register(
 			"write",
 			"buffer_size",
-			map[string]string{"output": name},
+			statTags("output", name, conf.Alias),
 		),
 		BufferLimit: selfstat.Register(
 			"write",
 			"buffer_limit",
-			map[string]string{"output": name},
+			statTags("output", name, conf.Alias),
 		),
-		WriteTime: selfstat.RegisterTiming(
+		WriteTime: selfstat.RegisterHistogram(
 			"write",
 			"write_time_ns",
-			map[string]string{"output": name},
+			statTags("output", name, conf.Alias),
+		),
+		BatchesWritten: selfstat.Register(
+			"write",
+			"batches_written",
+			statTags("output", name, conf.Alias),
+		),
+		BatchesFailed: selfstat.Register(
+			"write",
+			"batches_failed",
+			statTags("output", name, conf.Alias),
+		),
+		MetricsDropped: selfstat.Register(
+			"write",
+			"metrics_dropped",
+			statTags("output", name, conf.Alias),
+		),
+		BlockTime: selfstat.Register(
+			"write",
+			"block_time_ns",
+			statTags("output", name, conf.Alias),
+		),
+		RateLimitTime: selfstat.Register(
+			"write",
+			"rate_limit_time_ns",
+			statTags("output", name, conf.Alias),
 		),
 	}
+	if conf.MetricRateLimit > 0 {
+		ro.limiter = newRateLimiter(conf.MetricRateLimit)
+	}
+	switch conf.BufferOverflow {
+	case BufferOverflowDropNewest:
+		ro.failMetrics.SetDropNewest(true)
+	case BufferOverflowBlock:
+		ro.space = sync.NewCond(&sync.Mutex{})
+	}
 	ro.BufferLimit.Set(int64(ro.MetricBufferLimit))
 	return ro
 }
 
+// OpenSpool keeps the buffered metrics of the output in the spool file at
+// path, the metrics of the file that were not written are added to the
+// buffer first.
+func (ro *RunningOutput) OpenSpool(path string) error {
+	spool, metrics, err := buffer.OpenSpool(path)
+	if err != nil {
+		return err
+	}
+	ro.spool = spool
+	drop := func(m telegraf.Metric) {
+		ro.unspool(m)
+	}
+	ro.metrics.SetDropFunc(drop)
+	ro.failMetrics.SetDropFunc(drop)
+	if len(metrics) > 0 {
+		log.Printf("I! Output [%s] restored %d buffered metrics from %s",
+			ro.LogName(), len(metrics), path)
+		dropped := ro.failMetrics.Add(metrics...)
+		ro.MetricsDropped.Incr(int64(dropped))
+	}
+	return nil
+}
+
+// CloseSpool closes the spool file of the output, if there is one.
+func (ro *RunningOutput) CloseSpool() error {
+	if ro.spool == nil {
+		return nil
+	}
+	return ro.spool.Close()
+}
+
+// unspool removes the metrics written or dropped from the spool file.
+func (ro *RunningOutput) unspool(metrics ...telegraf.Metric) {
+	if ro.spool == nil {
+		return
+	}
+	if err := ro.spool.Remove(metrics...); err != nil {
+		log.Printf("E! Output [%s] failed to update its spool file: %s", ro.LogName(), err)
+	}
+}
+
 // AddMetric adds a metric to the output. This function can also write cached
 // points if FlushBufferWhenFull is true.
 func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
@@ -99,27 +251,110 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	}
 	// Filter any tagexclude/taginclude parameters before adding metric
 	if ro.Config.Filter.IsActive() {
-		// In order to filter out tags, we need to create a new metric, since
-		// metrics are immutable once created.
 		name := m.Name()
 		tags := m.Tags()
 		fields := m.Fields()
-		t := m.Time()
 		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
+			traceLog(m, "filtered out by outputs.%s", ro.Name)
 			ro.MetricsFiltered.Incr(1)
+			m.Drop()
 			return
 		}
-		// error is not possible if creating from another metric, so ignore.
-		m, _ = metric.New(name, tags, fields, t)
+		// The metric is owned by the output, the filtered out tags and
+		// fields are removed from it to keep its delivery tracking.
+		for k := range m.Tags() {
+			if _, ok := tags[k]; !ok {
+				m.RemoveTag(k)
+			}
+		}
+		for k := range m.Fields() {
+			if _, ok := fields[k]; !ok {
+				m.RemoveField(k)
+			}
+		}
+	}
+
+	if ro.space != nil {
+		ro.waitForSpace()
 	}
 
+	traceLog(m, "buffered by outputs.%s", ro.Name)
+	if ro.spool != nil {
+		if err := ro.spool.Add(m); err != nil {
+			log.Printf("E! Output [%s] failed to spool metric: %s", ro.LogName(), err)
+		}
+	}
 	ro.metrics.Add(m)
 	if ro.metrics.Len() == ro.MetricBatchSize {
-		batch := ro.metrics.Batch(ro.MetricBatchSize)
-		err := ro.write(batch)
-		if err != nil {
-			ro.failMetrics.Add(batch...)
+		if ro.limiter != nil && ro.limiter.delay() > 0 {
+			// the inputs are not held up by the rate limit, the batch is
+			// moved to the failed metrics, bounded by the buffer limit, and
+			// written with the next flush
+			dropped := ro.failMetrics.Add(ro.metrics.Batch(ro.MetricBatchSize)...)
+			ro.MetricsDropped.Incr(int64(dropped))
+			return
 		}
+		batch := ro.takeBatch(ro.metrics, ro.MetricBatchSize)
+		err := ro.limitedWrite(batch)
+		ro.finishBatch(batch, err)
+	}
+}
+
+// waitForSpace waits until the buffered metrics are below the buffer limit,
+// or until the output is unblocked.
+func (ro *RunningOutput) waitForSpace() {
+	ro.space.L.Lock()
+	defer ro.space.L.Unlock()
+	if !ro.full() || ro.unblocked {
+		return
+	}
+	start := time.Now()
+	log.Printf("W! Output [%s] buffer is full, waiting for a write", ro.LogName())
+	for ro.full() && !ro.unblocked {
+		ro.space.Wait()
+	}
+	ro.BlockTime.Incr(time.Since(start).Nanoseconds())
+}
+
+func (ro *RunningOutput) full() bool {
+	return ro.BufferLen() >= ro.MetricBufferLimit
+}
+
+// Unblock stops blocking AddMetric with the block strategy, the oldest
+// metrics are dropped from then on, and stops waiting for the rate limit,
+// the batches over it are kept in the buffer. It is called on shutdown so
+// the metrics of the agent can be flushed.
+func (ro *RunningOutput) Unblock() {
+	if ro.limiter != nil {
+		ro.limiter.stop()
+	}
+	if ro.space == nil {
+		return
+	}
+	ro.space.L.Lock()
+	ro.unblocked = true
+	ro.space.Broadcast()
+	ro.space.L.Unlock()
+}
+
+// takeBatch takes a batch of metrics from the buffer to write it.
+func (ro *RunningOutput) takeBatch(b *buffer.Buffer, batchSize int) []telegraf.Metric {
+	batch := b.Batch(batchSize)
+	atomic.AddInt64(&ro.pending, int64(len(batch)))
+	return batch
+}
+
+// finishBatch adds the batch to the failed metrics if it was not written.
+func (ro *RunningOutput) finishBatch(batch []telegraf.Metric, err error) {
+	if err != nil {
+		dropped := ro.failMetrics.Add(batch...)
+		ro.MetricsDropped.Incr(int64(dropped))
+	}
+	atomic.AddInt64(&ro.pending, -int64(len(batch)))
+	if err == nil && ro.space != nil {
+		ro.space.L.Lock()
+		ro.space.Broadcast()
+		ro.space.L.Unlock()
 	}
 }
 
@@ -128,7 +363,7 @@ func (ro *RunningOutput) Write() error {
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
 	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
-		ro.Name, nFails+nMetrics, ro.MetricBufferLimit)
+		ro.LogName(), nFails+nMetrics, ro.MetricBufferLimit)
 	var err error
 	if !ro.failMetrics.IsEmpty() {
 		// how many batches of failed writes we need to write.
@@ -141,31 +376,47 @@ func (ro *RunningOutput) Write() error {
 			if i == nBatches-1 {
 				batchSize = nFails % ro.MetricBatchSize
 			}
-			batch := ro.failMetrics.Batch(batchSize)
+			batch := ro.takeBatch(ro.failMetrics, batchSize)
 			// If we've already failed previous writes, don't bother trying to
 			// write to this output again. We are not exiting the loop just so
 			// that we can rotate the metrics to preserve order.
 			if err == nil {
-				err = ro.write(batch)
-			}
-			if err != nil {
-				ro.failMetrics.Add(batch...)
+				err = ro.limitedWrite(batch)
 			}
+			ro.finishBatch(batch, err)
 		}
 	}
 
-	batch := ro.metrics.Batch(ro.MetricBatchSize)
+	batch := ro.takeBatch(ro.metrics, ro.MetricBatchSize)
 	// see comment above about not trying to write to an already failed output.
 	// if ro.failMetrics is empty then err will always be nil at this point.
 	if err == nil {
-		err = ro.write(batch)
+		err = ro.limitedWrite(batch)
 	}
+	ro.finishBatch(batch, err)
 
-	if err != nil {
-		ro.failMetrics.Add(batch...)
-		return err
+	if ro.spool != nil {
+		if serr := ro.spool.Sync(); serr != nil {
+			log.Printf("E! Output [%s] failed to sync its spool file: %s", ro.LogName(), serr)
+		}
 	}
-	return nil
+	return err
+}
+
+// limitedWrite waits until the rate limit allows the batch to be written and
+// writes it.
+func (ro *RunningOutput) limitedWrite(metrics []telegraf.Metric) error {
+	if ro.limiter == nil || len(metrics) == 0 {
+		return ro.write(metrics)
+	}
+	start := time.Now()
+	ok := ro.limiter.wait()
+	ro.RateLimitTime.Incr(time.Since(start).Nanoseconds())
+	if !ok {
+		return errRateLimited
+	}
+	defer ro.limiter.take(len(metrics))
+	return ro.write(metrics)
 }
 
 func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
@@ -173,22 +424,160 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	if nMetrics == 0 {
 		return nil
 	}
+	if ro.Config.MetricOrdering == MetricOrderingTime {
+		sortByTime(metrics)
+	}
 	ro.Lock()
 	defer ro.Unlock()
+	ro.batchID++
 	start := time.Now()
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
+	for _, m := range metrics {
+		if err == nil {
+			traceLog(m, "written by outputs.%s in batch %d", ro.Name, ro.batchID)
+		} else {
+			traceLog(m, "write by outputs.%s in batch %d failed: %s", ro.Name, ro.batchID, err)
+		}
+	}
 	if err == nil {
 		log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
-			ro.Name, nMetrics, elapsed)
+			ro.LogName(), nMetrics, elapsed)
 		ro.MetricsWritten.Incr(int64(nMetrics))
 		ro.WriteTime.Incr(elapsed.Nanoseconds())
+		ro.BatchesWritten.Incr(1)
+		atomic.StoreInt64(&ro.lastWrite, time.Now().UnixNano())
+		ro.unspool(metrics...)
+		for _, m := range metrics {
+			m.Accept()
+		}
+	} else {
+		ro.BatchesFailed.Incr(1)
 	}
 	return err
 }
 
+// BufferLen returns the number of metrics buffered, including the batches
+// being written.
+func (ro *RunningOutput) BufferLen() int {
+	return ro.failMetrics.Len() + ro.metrics.Len() + int(atomic.LoadInt64(&ro.pending))
+}
+
+// LastWrite returns the time of the last successful write, the zero time if
+// the output did not write yet.
+func (ro *RunningOutput) LastWrite() time.Time {
+	if t := atomic.LoadInt64(&ro.lastWrite); t != 0 {
+		return time.Unix(0, t)
+	}
+	return time.Time{}
+}
+
+// LogName returns the name of the output in the logs.
+func (ro *RunningOutput) LogName() string {
+	return LogName(ro.Name, ro.Config.Alias)
+}
+
 // OutputConfig containing name and filter
 type OutputConfig struct {
 	Name   string
+	Alias  string
 	Filter Filter
+
+	// BufferOverflow is what happens when the buffer is full, one of the
+	// BufferOverflow constants, the oldest metrics are dropped by default.
+	BufferOverflow string
+
+	// MetricOrdering is the order of the metrics of the batches, one of the
+	// MetricOrdering constants, that of the agent if it is not set.
+	MetricOrdering string
+
+	// MetricRateLimit is the maximum number of metrics written per second,
+	// there is no limit if it is 0.
+	MetricRateLimit float64
+
+	// FlushInterval, FlushJitter, MetricBatchSize and MetricBufferLimit
+	// override the options of the agent for the output if they are set.
+	FlushInterval     time.Duration
+	FlushJitter       time.Duration
+	MetricBatchSize   int
+	MetricBufferLimit int
+}
+
+// sortByTime sorts the metrics by timestamp then by name, the metrics with
+// the same timestamp and name keep their order.
+func sortByTime(metrics []telegraf.Metric) {
+	sort.SliceStable(metrics, func(i, j int) bool {
+		ti, tj := metrics[i].Time(), metrics[j].Time()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return metrics[i].Name() < metrics[j].Name()
+	})
+}
+
+// rateLimiter spaces the writes of an output so that it writes rate metrics
+// per second on average: after a batch is written the next one waits for as
+// long as the batch takes at that rate.
+type rateLimiter struct {
+	rate float64
+
+	mu   sync.Mutex
+	next time.Time
+	// stopped is closed when the waits are canceled.
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{rate: rate, stopped: make(chan struct{})}
+}
+
+// delay returns how long the next write has to wait.
+func (l *rateLimiter) delay() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if d := time.Until(l.next); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// wait waits until the next write is allowed, it returns false if the wait
+// was canceled by stop.
+func (l *rateLimiter) wait() bool {
+	select {
+	case <-l.stopped:
+		return l.delay() == 0
+	default:
+	}
+	d := l.delay()
+	if d == 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-l.stopped:
+		return false
+	}
+}
+
+// take accounts for the n metrics written.
+func (l *rateLimiter) take(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+}
+
+// stop cancels the waits.
+func (l *rateLimiter) stop() {
+	l.once.Do(func() {
+		close(l.stopped)
+	})
 }
@@ -2,10 +2,15 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -330,6 +335,214 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+// Test that the metrics not written are restored from the spool file
+func TestRunningOutputSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "outputs.test.wal")
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 4, 12)
+	require.NoError(t, ro.OpenSpool(path))
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	// the first batch is written
+	assert.Len(t, m.Metrics(), 4)
+
+	m.failWrite = true
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.NoError(t, ro.CloseSpool())
+
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, &OutputConfig{}, 4, 12)
+	require.NoError(t, ro.OpenSpool(path))
+	require.NoError(t, ro.Write())
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 6)
+	assert.Equal(t, "metric5", m.Metrics()[0].Name())
+	assert.Equal(t, "metric10", m.Metrics()[5].Name())
+	require.NoError(t, ro.CloseSpool())
+
+	ro = NewRunningOutput("test", m, &OutputConfig{}, 4, 12)
+	require.NoError(t, ro.OpenSpool(path))
+	assert.Equal(t, 0, ro.spool.Len())
+	require.NoError(t, ro.CloseSpool())
+}
+
+func TestRunningOutputBufferOverflowDropNewest(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		BufferOverflow: BufferOverflowDropNewest,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("drop_newest", m, conf, 4, 8)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(2), ro.MetricsDropped.Get())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.NoError(t, ro.Write())
+	assert.Equal(t, append(first5, next5[:3]...), m.Metrics())
+}
+
+func TestRunningOutputBufferOverflowBlock(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		BufferOverflow: BufferOverflowBlock,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("block", m, conf, 2, 4)
+
+	for _, metric := range first5[:4] {
+		ro.AddMetric(metric)
+	}
+	added := make(chan struct{})
+	go func() {
+		ro.AddMetric(first5[4])
+		close(added)
+	}()
+	select {
+	case <-added:
+		t.Fatal("AddMetric did not block on a full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	m.Lock()
+	m.failWrite = false
+	m.Unlock()
+	require.NoError(t, ro.Write())
+	<-added
+	require.NoError(t, ro.Write())
+	assert.Equal(t, first5, m.Metrics())
+	assert.Equal(t, int64(0), ro.MetricsDropped.Get())
+	assert.True(t, ro.BlockTime.Get() > 0)
+
+	// no longer blocking once unblocked
+	m.Lock()
+	m.failWrite = true
+	m.Unlock()
+	for _, metric := range next5[:4] {
+		ro.AddMetric(metric)
+	}
+	unblocked := make(chan struct{})
+	go func() {
+		ro.AddMetric(next5[4])
+		close(unblocked)
+	}()
+	ro.Unblock()
+	<-unblocked
+}
+
+func TestRunningOutputRateLimit(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		MetricRateLimit: 40,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("rate_limit", m, conf, 5, 100)
+
+	// the first batch is written at once, the next ones have to wait for
+	// 5/40s each and are kept for the next flush
+	var last5 []telegraf.Metric
+	for i := 11; i <= 15; i++ {
+		last5 = append(last5, testutil.TestMetric(101, fmt.Sprintf("metric%d", i)))
+	}
+	all := append(append(append([]telegraf.Metric{}, first5...), next5...), last5...)
+	for _, metric := range all {
+		ro.AddMetric(metric)
+	}
+	assert.Equal(t, first5, m.Metrics())
+	assert.Equal(t, 10, ro.BufferLen())
+	assert.Equal(t, int64(0), ro.MetricsDropped.Get())
+
+	start := time.Now()
+	for ro.BufferLen() > 0 && time.Since(start) < 5*time.Second {
+		require.NoError(t, ro.Write())
+	}
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+	assert.Equal(t, all, m.Metrics())
+	assert.Equal(t, int64(0), ro.MetricsDropped.Get())
+	assert.True(t, ro.RateLimitTime.Get() > 0)
+
+	// on shutdown the batches over the limit stay in the buffer
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	ro.Unblock()
+	assert.Equal(t, errRateLimited, ro.Write())
+	assert.Equal(t, 5, ro.BufferLen())
+	assert.Len(t, m.Metrics(), 15)
+
+	// a batch is not larger than a second of metrics
+	conf.MetricRateLimit = 2.5
+	ro = NewRunningOutput("rate_limit", m, conf, 5, 20)
+	assert.Equal(t, 2, ro.MetricBatchSize)
+}
+
+func TestRunningOutputMetricOrdering(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		MetricOrdering: MetricOrderingTime,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("ordering", m, conf, 10, 20)
+	at := func(name string, sec int64) telegraf.Metric {
+		mt, err := metric.New(name, nil, map[string]interface{}{"value": 1}, time.Unix(sec, 0))
+		require.NoError(t, err)
+		return mt
+	}
+	for _, mt := range []telegraf.Metric{at("b", 2), at("mem", 1), at("cpu", 1), at("a", 2)} {
+		ro.AddMetric(mt)
+	}
+	require.NoError(t, ro.Write())
+	var order []string
+	for _, mt := range m.Metrics() {
+		order = append(order, mt.Name())
+	}
+	assert.Equal(t, []string{"cpu", "mem", "a", "b"}, order)
+}
+
+func TestRunningOutputWriteStats(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("write_stats", m, conf, 10, 12)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(1), ro.BatchesFailed.Get())
+	assert.Equal(t, int64(0), ro.BatchesWritten.Get())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(1), ro.BatchesFailed.Get())
+	assert.Equal(t, int64(1), ro.BatchesWritten.Get())
+	assert.True(t, ro.WriteTime.Get() > 0)
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -479,6 +692,44 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+// Test that the tracking metrics are accepted once written and dropped when
+// filtered out.
+func TestRunningOutputTracking(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			NameDrop:   []string{"metric2"},
+			TagExclude: []string{"tag*"},
+		},
+	}
+	assert.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	var infos []telegraf.DeliveryInfo
+	notify := func(info telegraf.DeliveryInfo) {
+		infos = append(infos, info)
+	}
+	tracked1, _ := metric.WithGroupTracking([]telegraf.Metric{testutil.TestMetric(101, "metric1")}, notify)
+	tracked2, id2 := metric.WithGroupTracking([]telegraf.Metric{testutil.TestMetric(101, "metric2")}, notify)
+
+	ro.AddMetric(tracked1[0])
+	ro.AddMetric(tracked2[0])
+	require.Len(t, infos, 1)
+	assert.Equal(t, id2, infos[0].ID())
+
+	m.failWrite = true
+	require.Error(t, ro.Write())
+	assert.Len(t, infos, 1)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, infos, 2)
+	assert.True(t, infos[1].Delivered())
+	require.Len(t, m.Metrics(), 1)
+	assert.Len(t, m.Metrics()[0].Tags(), 0)
+}
+
 type mockOutput struct {
 	sync.Mutex
 
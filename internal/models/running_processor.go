@@ -23,10 +23,16 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 // FilterConfig containing a name and filter
 type ProcessorConfig struct {
 	Name   string
+	Alias  string
 	Order  int64
 	Filter Filter
 }
 
+// LogName returns the name of the processor in the logs.
+func (rp *RunningProcessor) LogName() string {
+	return LogName("processors."+rp.Name, rp.Config.Alias)
+}
+
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 	rp.Lock()
 	defer rp.Unlock()
@@ -44,8 +50,24 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 		}
 		// This metric should pass through the filter, so call the filter Apply
 		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		out := rp.Processor.Apply(metric)
+		if !contains(out, metric) {
+			traceLog(metric, "dropped by processors.%s", rp.Name)
+			metric.Drop()
+		} else {
+			traceMetric(metric, "processed by processors.%s", rp.Name)
+		}
+		ret = append(ret, out...)
 	}
 
 	return ret
 }
+
+func contains(metrics []telegraf.Metric, m telegraf.Metric) bool {
+	for _, metric := range metrics {
+		if metric == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// The tracing logs the path through the pipeline of a sample of the metrics
+// made by the inputs and aggregators, the traced metrics have the trace tag
+// set to their trace id.
+var (
+	traceRate   float64
+	traceTag    string
+	lastTraceID uint64
+)
+
+// SetTracing traces the rate fraction of the new metrics with the tag, a zero
+// rate disables the tracing.
+func SetTracing(rate float64, tag string) {
+	traceRate = rate
+	traceTag = tag
+}
+
+// traceNew samples a new metric of the plugin, it is tagged and logged if it
+// is traced.
+func traceNew(m telegraf.Metric, plugin string) {
+	if traceRate <= 0 || rand.Float64() >= traceRate {
+		return
+	}
+	id := atomic.AddUint64(&lastTraceID, 1)
+	m.AddTag(traceTag, strconv.FormatUint(id, 10))
+	traceMetric(m, "made by %s", plugin)
+}
+
+// traced returns whether the metric is traced.
+func traced(m telegraf.Metric) bool {
+	return traceRate > 0 && m.HasTag(traceTag)
+}
+
+// traceMetric logs the stage of the metric and its line protocol if it is
+// traced.
+func traceMetric(m telegraf.Metric, format string, args ...interface{}) {
+	if !traced(m) {
+		return
+	}
+	line, err := influx.NewSerializer().Serialize(m)
+	if err != nil {
+		line = []byte(err.Error())
+	}
+	traceLog(m, format+": %s", append(args, strings.TrimSpace(string(line)))...)
+}
+
+// traceLog logs the stage of the metric if it is traced.
+func traceLog(m telegraf.Metric, format string, args ...interface{}) {
+	if !traced(m) {
+		return
+	}
+	id, _ := m.GetTag(traceTag)
+	log.Printf("I! [trace "+id+"] "+format, args...)
+}
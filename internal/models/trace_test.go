@@ -0,0 +1,73 @@
+package models
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracing(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+	SetTracing(1, "trace_id")
+	defer SetTracing(0, "")
+
+	ri := NewRunningInput(&testInput{}, &InputConfig{Name: "test"})
+	m := ri.MakeMetric("foo", map[string]interface{}{"value": int64(1)},
+		map[string]string{}, telegraf.Untyped, time.Unix(0, 0))
+	require.NotNil(t, m)
+	id, ok := m.GetTag("trace_id")
+	require.True(t, ok)
+
+	rp := NewTestRunningProcessor()
+	out := rp.Apply(m)
+	require.Len(t, out, 1)
+
+	prefix := "I! [trace " + id + "] "
+	assert.Equal(t, []string{
+		prefix + "made by inputs.test: foo,trace_id=" + id + " value=1i 0",
+		prefix + "dropped by processors.test",
+	}, traceLines(&buf))
+
+	buf.Reset()
+	m = ri.MakeMetric("bar", map[string]interface{}{"value": int64(1)},
+		map[string]string{}, telegraf.Untyped, time.Unix(0, 0))
+	id, _ = m.GetTag("trace_id")
+	ro := NewRunningOutput("test", &mockOutput{}, &OutputConfig{}, 1000, 10000)
+	ro.AddMetric(m)
+	require.NoError(t, ro.Write())
+	prefix = "I! [trace " + id + "] "
+	assert.Equal(t, []string{
+		prefix + "made by inputs.test: bar,trace_id=" + id + " value=1i 0",
+		prefix + "buffered by outputs.test",
+		prefix + "written by outputs.test in batch 1",
+	}, traceLines(&buf))
+}
+
+func TestTracingDisabled(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{Name: "test"})
+	m := ri.MakeMetric("foo", map[string]interface{}{"value": int64(1)},
+		map[string]string{}, telegraf.Untyped, time.Unix(0, 0))
+	assert.False(t, m.HasTag("trace_id"))
+}
+
+// traceLines returns the lines of the tracing in the log.
+func traceLines(buf *bytes.Buffer) []string {
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "I! [trace ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,38 @@
+package internal
+
+import "sync"
+
+// Pause is the paused state of a service input consuming messages, the
+// zero value is not paused. The receiver of the input stops reading its
+// messages while it is paused and reads the state again once the channel
+// returned with it is closed, so that pausing never waits for the receiver.
+type Pause struct {
+	mu      sync.Mutex
+	paused  bool
+	changed chan struct{}
+}
+
+// Set pauses or resumes the consumption.
+func (p *Pause) Set(paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused == paused {
+		return
+	}
+	p.paused = paused
+	if p.changed != nil {
+		close(p.changed)
+		p.changed = nil
+	}
+}
+
+// State returns whether the consumption is paused and a channel closed when
+// it changes.
+func (p *Pause) State() (bool, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.changed == nil {
+		p.changed = make(chan struct{})
+	}
+	return p.paused, p.changed
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPause(t *testing.T) {
+	var p Pause
+	paused, changed := p.State()
+	assert.False(t, paused)
+
+	p.Set(false)
+	select {
+	case <-changed:
+		t.Fatal("changed without a change")
+	default:
+	}
+
+	p.Set(true)
+	<-changed
+	paused, changed = p.State()
+	assert.True(t, paused)
+
+	p.Set(false)
+	<-changed
+	paused, _ = p.State()
+	assert.False(t, paused)
+}
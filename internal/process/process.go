@@ -0,0 +1,195 @@
+// Package process runs the long lived external processes of the execd
+// plugins.
+package process
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// ErrNotRunning is returned by Write while the process is not running.
+var ErrNotRunning = errors.New("the process is not running")
+
+// stopTimeout is how long Stop waits for the process to exit once its stdin
+// is closed before killing it.
+const stopTimeout = 5 * time.Second
+
+// Process runs a command until it is stopped, the command is started again
+// after the RestartDelay when it exits.
+type Process struct {
+	Command      []string
+	Environment  []string
+	RestartDelay time.Duration
+
+	// ReadStdout reads the stdout of each run of the command until it is
+	// closed, ReadStderr its stderr. The lines of stderr are logged as
+	// errors if it is nil.
+	ReadStdout func(r io.Reader)
+	ReadStderr func(r io.Reader)
+	Log        telegraf.Logger
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stop   chan struct{}
+	done   chan struct{}
+	reader sync.WaitGroup
+}
+
+// Start starts the command, it is started again when it exits until Stop.
+func (p *Process) Start() error {
+	if len(p.Command) == 0 {
+		return fmt.Errorf("no command")
+	}
+	if p.ReadStderr == nil {
+		p.ReadStderr = p.logStderr
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	if err := p.start(); err != nil {
+		return err
+	}
+	go p.run()
+	return nil
+}
+
+func (p *Process) start() error {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	if len(p.Environment) > 0 {
+		cmd.Env = append(os.Environ(), p.Environment...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %s", p.Command[0], err)
+	}
+
+	// the pipes are read until they are closed before the command is waited
+	p.reader.Add(2)
+	go func() {
+		defer p.reader.Done()
+		if p.ReadStdout != nil {
+			p.ReadStdout(stdout)
+		}
+		io.Copy(ioutil.Discard, stdout)
+	}()
+	go func() {
+		defer p.reader.Done()
+		p.ReadStderr(stderr)
+		io.Copy(ioutil.Discard, stderr)
+	}()
+
+	p.mu.Lock()
+	p.cmd, p.stdin = cmd, stdin
+	p.mu.Unlock()
+	return nil
+}
+
+// run waits for the command and starts it again until Stop.
+func (p *Process) run() {
+	defer close(p.done)
+	for {
+		p.mu.Lock()
+		cmd := p.cmd
+		p.mu.Unlock()
+		if cmd != nil {
+			p.reader.Wait()
+			err := cmd.Wait()
+			p.mu.Lock()
+			p.cmd, p.stdin = nil, nil
+			p.mu.Unlock()
+
+			select {
+			case <-p.stop:
+				return
+			default:
+			}
+			if err != nil {
+				p.Log.Errorf("Process %s exited: %s", p.Command[0], err)
+			} else {
+				p.Log.Errorf("Process %s exited", p.Command[0])
+			}
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(p.RestartDelay):
+		}
+		if err := p.start(); err != nil {
+			p.Log.Errorf("Restarting the process failed: %s", err)
+			continue
+		}
+		p.Log.Infof("Restarted process %s", p.Command[0])
+	}
+}
+
+// Write writes to the stdin of the process.
+func (p *Process) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stdin == nil {
+		return 0, ErrNotRunning
+	}
+	return p.stdin.Write(b)
+}
+
+// Signal sends the signal to the process.
+func (p *Process) Signal(sig os.Signal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == nil {
+		return ErrNotRunning
+	}
+	return p.cmd.Process.Signal(sig)
+}
+
+// Stop closes the stdin of the process and waits for it to exit, it is killed
+// if it is still running after 5 seconds.
+func (p *Process) Stop() {
+	close(p.stop)
+	p.mu.Lock()
+	if p.stdin != nil {
+		p.stdin.Close()
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return
+	case <-time.After(stopTimeout):
+	}
+	p.mu.Lock()
+	if p.cmd != nil {
+		p.Log.Warnf("Process %s did not exit, killing it", p.Command[0])
+		p.cmd.Process.Kill()
+	}
+	p.mu.Unlock()
+	<-p.done
+}
+
+func (p *Process) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.Log.Errorf("stderr: %s", scanner.Text())
+	}
+}
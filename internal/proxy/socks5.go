@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	netproxy "golang.org/x/net/proxy"
+)
+
+// Socks5 is the SOCKS5 proxy option of the plugins opening TCP connections,
+// its fields are named after their TOML keys like those of HTTPProxy.
+type Socks5 struct {
+	Socks5Address  string
+	Socks5Username string
+	Socks5Password string
+}
+
+// Dialer opens the connections of a plugin, like a net.Dialer.
+type Dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// Dialer returns a Dialer connecting through the SOCKS5 proxy, or a
+// net.Dialer if no proxy is configured. The timeout applies to the
+// connection to the proxy or the server.
+func (s *Socks5) Dialer(timeout time.Duration) (Dialer, error) {
+	direct := &net.Dialer{Timeout: timeout}
+	if s.Socks5Address == "" {
+		if s.Socks5Username != "" || s.Socks5Password != "" {
+			return nil, fmt.Errorf("socks5_username and socks5_password require socks5_address")
+		}
+		return direct, nil
+	}
+
+	if _, _, err := net.SplitHostPort(s.Socks5Address); err != nil {
+		return nil, fmt.Errorf("invalid socks5_address %q: %s", s.Socks5Address, err)
+	}
+	var auth *netproxy.Auth
+	if s.Socks5Username != "" || s.Socks5Password != "" {
+		auth = &netproxy.Auth{
+			User:     s.Socks5Username,
+			Password: s.Socks5Password,
+		}
+	}
+	return netproxy.SOCKS5("tcp", s.Socks5Address, auth, direct)
+}
+
+// Enabled reports whether the connections use the SOCKS5 proxy.
+func (s *Socks5) Enabled() bool {
+	return s.Socks5Address != ""
+}
+
+// DialTLS connects to address with the dialer and secures the connection
+// with config, the server name defaults to the host of address. A non zero
+// timeout limits the TLS handshake like tls.DialWithDialer.
+func DialTLS(d Dialer, network, address string, config *tls.Config, timeout time.Duration) (net.Conn, error) {
+	conn, err := d.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
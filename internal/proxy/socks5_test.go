@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socks5Server is a SOCKS5 proxy supporting CONNECT, it requires the
+// username and password if they are set.
+type socks5Server struct {
+	listener net.Listener
+	username string
+	password string
+	targets  chan string
+}
+
+func newSocks5Server(t *testing.T, username, password string) *socks5Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &socks5Server{
+		listener: l,
+		username: username,
+		password: password,
+		targets:  make(chan string, 10),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socks5Server) serve(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 256)
+
+	// greeting: version, methods
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	if s.username == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		// version, username, password
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		user := make([]byte, buf[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, buf[:1])
+		password := make([]byte, buf[0])
+		io.ReadFull(conn, password)
+		if string(user) != s.username || string(password) != s.password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	// request: version, command, reserved, address type, address, port
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1:
+		io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 3:
+		io.ReadFull(conn, buf[:1])
+		name := make([]byte, buf[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+	io.ReadFull(conn, buf[:2])
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2]))))
+	s.targets <- target
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func TestSocks5Dialer(t *testing.T) {
+	d, err := (&Socks5{}).Dialer(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, &net.Dialer{Timeout: time.Second}, d)
+
+	for _, s := range []Socks5{
+		{Socks5Username: "telegraf"},
+		{Socks5Address: "socks.example.com"},
+	} {
+		_, err := s.Dialer(0)
+		assert.Error(t, err, "%+v", s)
+	}
+}
+
+func TestSocks5Dial(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("hello"))
+			conn.Close()
+		}
+	}()
+
+	server := newSocks5Server(t, "telegraf", "secret")
+	defer server.listener.Close()
+
+	s := &Socks5{
+		Socks5Address:  server.listener.Addr().String(),
+		Socks5Username: "telegraf",
+		Socks5Password: "secret",
+	}
+	d, err := s.Dialer(time.Second)
+	require.NoError(t, err)
+	conn, err := d.Dial("tcp", target.Addr().String())
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(conn)
+	conn.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, target.Addr().String(), <-server.targets)
+
+	_, err = d.Dial("udp", target.Addr().String())
+	assert.Error(t, err)
+
+	s.Socks5Password = "wrong"
+	d, err = s.Dialer(time.Second)
+	require.NoError(t, err)
+	_, err = d.Dial("tcp", target.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestDialTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	server := newSocks5Server(t, "", "")
+	defer server.listener.Close()
+	d, err := (&Socks5{Socks5Address: server.listener.Addr().String()}).Dialer(time.Second)
+	require.NoError(t, err)
+
+	addr := ts.Listener.Addr().String()
+	conn, err := DialTLS(d, "tcp", addr, &tls.Config{RootCAs: pool}, time.Second)
+	require.NoError(t, err)
+	conn.Close()
+
+	// the certificate is verified for the host of the address
+	_, err = DialTLS(d, "tcp", addr, &tls.Config{RootCAs: pool, ServerName: "telegraf.example.org"}, time.Second)
+	assert.Error(t, err)
+}
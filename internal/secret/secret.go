@@ -0,0 +1,287 @@
+// Package secret resolves the references to secrets of the config strings,
+// @{store:reference}, with the value of the secret in the store.
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+)
+
+// Store returns the secrets of a backend.
+type Store interface {
+	Get(reference string) (string, error)
+}
+
+// referenceRe matches @{store:reference}, a reference preceded by another @
+// is a literal @{...}.
+var referenceRe = regexp.MustCompile(`(@?)@\{([a-z_]+):([^{}]+)\}`)
+
+// Resolve replaces the references of s by the secrets of the stores.
+func Resolve(s string, stores map[string]Store) (string, error) {
+	return resolve(s, func(store, reference string) (string, error) {
+		return get(stores, store, reference)
+	})
+}
+
+func get(stores map[string]Store, store, reference string) (string, error) {
+	st, ok := stores[store]
+	if !ok {
+		return "", fmt.Errorf("unknown secret store %q", store)
+	}
+	value, err := st.Get(reference)
+	if err != nil {
+		return "", fmt.Errorf("secret @{%s:%s}: %s", store, reference, err)
+	}
+	return value, nil
+}
+
+func resolve(s string, get func(store, reference string) (string, error)) (string, error) {
+	if !strings.Contains(s, "@{") {
+		return s, nil
+	}
+	var err error
+	resolved := referenceRe.ReplaceAllStringFunc(s, func(match string) string {
+		m := referenceRe.FindStringSubmatch(match)
+		if m[1] == "@" || err != nil {
+			return match[len(m[1]):]
+		}
+		value, gerr := get(m[2], m[3])
+		if gerr != nil {
+			err = gerr
+			return match
+		}
+		return value
+	})
+	return resolved, err
+}
+
+// Resolver resolves the references with the Stores and keeps the secrets it
+// resolved, Changed tells once they rotate.
+type Resolver struct {
+	Stores map[string]Store
+
+	// store -> reference -> value
+	secrets map[string]map[string]string
+}
+
+// Resolve replaces the references of s by the secrets of the stores.
+func (r *Resolver) Resolve(s string) (string, error) {
+	return resolve(s, func(store, reference string) (string, error) {
+		value, err := get(r.Stores, store, reference)
+		if err != nil {
+			return "", err
+		}
+		if r.secrets == nil {
+			r.secrets = make(map[string]map[string]string)
+		}
+		if r.secrets[store] == nil {
+			r.secrets[store] = make(map[string]string)
+		}
+		r.secrets[store][reference] = value
+		return value, nil
+	})
+}
+
+// Changed gets the secrets resolved again, the stores caching them read
+// them again. It reports the first secret whose value changed, or an empty
+// string if none did.
+func (r *Resolver) Changed() (string, error) {
+	for name, store := range r.Stores {
+		if c, ok := store.(cache); ok && len(r.secrets[name]) > 0 {
+			c.clear()
+		}
+	}
+	for store, secrets := range r.secrets {
+		for reference, value := range secrets {
+			current, err := get(r.Stores, store, reference)
+			if err != nil {
+				return "", err
+			}
+			if current != value {
+				return "@{" + store + ":" + reference + "}", nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// cache is a store caching the secrets it read.
+type cache interface {
+	clear()
+}
+
+// Env returns the environment variables.
+type Env struct{}
+
+func (Env) Get(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// File returns the contents of files, without the trailing newline, like the
+// secrets mounted by container orchestrators. Relative paths are relative to
+// the Directory.
+type File struct {
+	Directory string
+}
+
+func (f *File) Get(path string) (string, error) {
+	if !filepath.IsAbs(path) && f.Directory != "" {
+		path = filepath.Join(f.Directory, path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Vault returns the keys of the secrets of a HashiCorp Vault KV engine, the
+// references are <path>:<key>. The Address and Token default to VAULT_ADDR
+// and VAULT_TOKEN.
+type Vault struct {
+	Address   string            `toml:"address"`
+	Token     string            `toml:"token"`
+	TokenFile string            `toml:"token_file"`
+	Namespace string            `toml:"namespace"`
+	KVVersion int               `toml:"kv_version"`
+	Timeout   internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+	// secrets read, a config often references several keys of a secret
+	secrets map[string]map[string]interface{}
+}
+
+func (v *Vault) Get(reference string) (string, error) {
+	i := strings.LastIndex(reference, ":")
+	if i < 0 {
+		return "", fmt.Errorf("vault references must be <path>:<key>")
+	}
+	path, key := reference[:i], reference[i+1:]
+
+	data, err := v.read(path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("no key %s in secret %s", key, path)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func (v *Vault) init() error {
+	if v.Address == "" {
+		v.Address = os.Getenv("VAULT_ADDR")
+	}
+	if v.Address == "" {
+		return fmt.Errorf("no vault address, set address or VAULT_ADDR")
+	}
+	if v.Token == "" && v.TokenFile != "" {
+		token, err := ioutil.ReadFile(v.TokenFile)
+		if err != nil {
+			return err
+		}
+		v.Token = strings.TrimSpace(string(token))
+	}
+	if v.Token == "" {
+		v.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if v.KVVersion == 0 {
+		v.KVVersion = 2
+	}
+	if v.Timeout.Duration == 0 {
+		v.Timeout.Duration = 10 * time.Second
+	}
+
+	tlsConfig, err := v.TLSConfig()
+	if err != nil {
+		return err
+	}
+	v.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+		Timeout: v.Timeout.Duration,
+	}
+	v.secrets = make(map[string]map[string]interface{})
+	return nil
+}
+
+// read returns the keys of the secret at path, the data of the version 2
+// engine is read under the data path of its mount.
+func (v *Vault) read(path string) (map[string]interface{}, error) {
+	if v.client == nil {
+		if err := v.init(); err != nil {
+			return nil, err
+		}
+	}
+	if data, ok := v.secrets[path]; ok {
+		return data, nil
+	}
+
+	apiPath := strings.Trim(path, "/")
+	if v.KVVersion == 2 {
+		parts := strings.SplitN(apiPath, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("secret path %s has no mount", path)
+		}
+		apiPath = parts[0] + "/data/" + parts[1]
+	}
+	req, err := http.NewRequest("GET", strings.TrimRight(v.Address, "/")+"/v1/"+apiPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.Token != "" {
+		req.Header.Set("X-Vault-Token", v.Token)
+	}
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading secret %s: %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid secret %s: %s", path, err)
+	}
+	data := body.Data
+	if v.KVVersion == 2 {
+		data, _ = body.Data["data"].(map[string]interface{})
+	}
+	if data == nil {
+		return nil, fmt.Errorf("secret %s has no data", path)
+	}
+	v.secrets[path] = data
+	return data, nil
+}
+
+func (v *Vault) clear() {
+	v.secrets = make(map[string]map[string]interface{})
+}
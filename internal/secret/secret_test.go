@@ -0,0 +1,168 @@
+package secret
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapStore map[string]string
+
+func (m mapStore) Get(reference string) (string, error) {
+	value, ok := m[reference]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return value, nil
+}
+
+func TestResolve(t *testing.T) {
+	stores := map[string]Store{"test": mapStore{"password": "secret", "a:b": "c"}}
+
+	for s, expected := range map[string]string{
+		"no secrets":                   "no secrets",
+		"@{test:password}":             "secret",
+		"user:@{test:password}@host":   "user:secret@host",
+		"@{test:a:b}-@{test:a:b}":      "c-c",
+		"@@{test:password}":            "@{test:password}",
+		"@{not a reference}":           "@{not a reference}",
+		"@@{test:missing} @{test:a:b}": "@{test:missing} c",
+	} {
+		resolved, err := Resolve(s, stores)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, resolved, s)
+	}
+
+	_, err := Resolve("@{test:missing}", stores)
+	assert.Error(t, err)
+	_, err = Resolve("@{vault:kv/telegraf:password}", stores)
+	assert.Error(t, err)
+}
+
+// cachedStore returns the secrets of its store read before it is cleared.
+type cachedStore struct {
+	store  mapStore
+	cached map[string]string
+}
+
+func (c *cachedStore) Get(reference string) (string, error) {
+	if value, ok := c.cached[reference]; ok {
+		return value, nil
+	}
+	value, err := c.store.Get(reference)
+	if err == nil {
+		c.cached[reference] = value
+	}
+	return value, err
+}
+
+func (c *cachedStore) clear() {
+	c.cached = make(map[string]string)
+}
+
+func TestResolverChanged(t *testing.T) {
+	secrets := mapStore{"password": "secret", "token": "token"}
+	cached := &cachedStore{store: mapStore{"key": "v1"}, cached: make(map[string]string)}
+	r := &Resolver{Stores: map[string]Store{"test": secrets, "cached": cached}}
+
+	resolved, err := r.Resolve("@{test:password} @{cached:key}")
+	require.NoError(t, err)
+	assert.Equal(t, "secret v1", resolved)
+	changed, err := r.Changed()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// the secrets not referenced are not checked
+	secrets["token"] = "rotated"
+	changed, err = r.Changed()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	cached.store["key"] = "v2"
+	changed, err = r.Changed()
+	require.NoError(t, err)
+	assert.Equal(t, "@{cached:key}", changed)
+
+	delete(secrets, "password")
+	cached.store["key"] = "v1"
+	_, err = r.Changed()
+	assert.EqualError(t, err, "secret @{test:password}: file does not exist")
+}
+
+func TestEnv(t *testing.T) {
+	os.Setenv("TELEGRAF_SECRET_TEST", "secret")
+	defer os.Unsetenv("TELEGRAF_SECRET_TEST")
+
+	value, err := Env{}.Get("TELEGRAF_SECRET_TEST")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+	_, err = Env{}.Get("TELEGRAF_SECRET_TEST_UNSET")
+	assert.Error(t, err)
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600))
+
+	f := &File{Directory: dir}
+	value, err := f.Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+	value, err = (&File{}).Get(filepath.Join(dir, "password"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+	_, err = f.Get("missing")
+	assert.Error(t, err)
+}
+
+func TestVault(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/kv/data/telegraf":
+			w.Write([]byte(`{"data":{"data":{"nats_password":"secret","port":4222},"metadata":{"version":1}}}`))
+		case "/v1/secret/telegraf":
+			w.Write([]byte(`{"data":{"nats_password":"v1secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	v := &Vault{Address: ts.URL, Token: "token"}
+	value, err := v.Get("kv/telegraf:nats_password")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+	value, err = v.Get("kv/telegraf:port")
+	require.NoError(t, err)
+	assert.Equal(t, "4222", value)
+	_, err = v.Get("kv/telegraf:missing")
+	assert.Error(t, err)
+	_, err = v.Get("kv/missing:nats_password")
+	assert.Error(t, err)
+	_, err = v.Get("kv/telegraf")
+	assert.Error(t, err)
+	// the secret is read once
+	assert.Equal(t, []string{"/v1/kv/data/telegraf", "/v1/kv/data/missing"}, requests)
+
+	v1 := &Vault{Address: ts.URL, Token: "token", KVVersion: 1}
+	value, err = v1.Get("secret/telegraf:nats_password")
+	require.NoError(t, err)
+	assert.Equal(t, "v1secret", value)
+
+	_, err = (&Vault{Address: ts.URL, Token: "wrong"}).Get("kv/telegraf:nats_password")
+	assert.Error(t, err)
+}
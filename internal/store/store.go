@@ -0,0 +1,288 @@
+// Package store implements the persistent key-value store of the plugins,
+// the state of all the plugins is kept in one JSON file.
+//
+// The file is rewritten whole, at most once per flush interval and when the
+// store is closed, so the store suits small states: the total size of the
+// keys and values is limited to MaxSize.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// MaxSize is the maximum total size of the keys and values, a Set
+	// exceeding it fails.
+	MaxSize = 16 * 1024 * 1024
+
+	// flushInterval is the delay between a change and the write of the
+	// file, the changes in between are written together.
+	flushInterval = time.Second
+)
+
+// DB holds the namespaces of the plugins, the changes are written to its
+// file by a timer at most once per flush interval, and when it is closed.
+type DB struct {
+	path string
+	// namespace -> key -> value, the values are base64 encoded in JSON
+	data map[string]map[string][]byte
+	// size is the total size of the keys and values
+	size int
+
+	mu sync.Mutex
+	// dirty is set when the data changed since the file was written, timer
+	// then writes it
+	dirty  bool
+	timer  *time.Timer
+	closed bool
+	// flushInterval is the delay of the timer
+	flushInterval time.Duration
+}
+
+// Open loads the store from the file at path, the file is created when a
+// value is first set. If path is empty, the store is only kept in memory.
+func Open(path string) (*DB, error) {
+	db := &DB{
+		path:          path,
+		data:          make(map[string]map[string][]byte),
+		flushInterval: flushInterval,
+	}
+	if path == "" {
+		return db, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &db.data); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", path, err)
+	}
+	for _, keys := range db.data {
+		for key, value := range keys {
+			db.size += len(key) + len(value)
+		}
+	}
+	return db, nil
+}
+
+// Namespace returns the store of a plugin instance.
+func (db *DB) Namespace(name string) telegraf.Store {
+	return &namespace{db: db, name: name}
+}
+
+// Move moves a namespace of another store to this one under name, its keys
+// replace the keys of name. The plugin holding the namespace keeps using it,
+// like a service input kept running while the config is reloaded.
+func (db *DB) Move(s telegraf.Store, name string) error {
+	n, ok := s.(*namespace)
+	if !ok {
+		return fmt.Errorf("the store of %s is not a namespace of a state file", name)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.db.mu.Lock()
+	keys := make(map[string][]byte, len(n.db.data[n.name]))
+	for key, value := range n.db.data[n.name] {
+		keys[key] = value
+	}
+	n.db.mu.Unlock()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	size := db.size
+	for key, value := range db.data[name] {
+		size -= len(key) + len(value)
+	}
+	for key, value := range keys {
+		size += len(key) + len(value)
+	}
+	if size > MaxSize {
+		return fmt.Errorf("state of %d bytes above the limit of %d bytes", size, MaxSize)
+	}
+	if len(keys) > 0 {
+		db.data[name] = keys
+	} else {
+		delete(db.data, name)
+	}
+	db.size = size
+	n.db, n.name = db, name
+	return db.changed()
+}
+
+// HasNamespace reports whether keys are set in the namespace.
+func (db *DB) HasNamespace(name string) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.data[name]) > 0
+}
+
+// Flush writes the changes not written yet to the file.
+func (db *DB) Flush() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.flush()
+}
+
+// Close writes the changes not written yet, the changes made after it are
+// written right away.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.closed = true
+	if db.timer != nil {
+		db.timer.Stop()
+		db.timer = nil
+	}
+	return db.flush()
+}
+
+func (db *DB) flush() error {
+	if !db.dirty {
+		return nil
+	}
+	if err := db.save(); err != nil {
+		return err
+	}
+	db.dirty = false
+	return nil
+}
+
+// changed schedules the write of the file, or writes it if the store is
+// closed.
+func (db *DB) changed() error {
+	if db.path == "" {
+		return nil
+	}
+	db.dirty = true
+	if db.closed {
+		return db.flush()
+	}
+	if db.timer == nil {
+		db.timer = time.AfterFunc(db.flushInterval, func() {
+			db.mu.Lock()
+			defer db.mu.Unlock()
+			db.timer = nil
+			if err := db.flush(); err != nil {
+				log.Printf("E! Failed to write the state file %s: %s", db.path, err)
+			}
+		})
+	}
+	return nil
+}
+
+// save writes the file atomically, a crash leaves either the previous or
+// the new state.
+func (db *DB) save() error {
+	b, err := json.Marshal(db.data)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(db.path), filepath.Base(db.path))
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), db.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+type namespace struct {
+	// mu guards db and name, they change when the namespace is moved
+	mu   sync.RWMutex
+	db   *DB
+	name string
+}
+
+func (n *namespace) Get(key string) ([]byte, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	n.db.mu.Lock()
+	defer n.db.mu.Unlock()
+	value, ok := n.db.data[n.name][key]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (n *namespace) Set(key string, value []byte) error {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	n.db.mu.Lock()
+	defer n.db.mu.Unlock()
+	keys, ok := n.db.data[n.name]
+	size := n.db.size + len(key) + len(value)
+	if old, ok := keys[key]; ok {
+		size -= len(key) + len(old)
+	}
+	if size > MaxSize {
+		return fmt.Errorf("state of %d bytes above the limit of %d bytes", size, MaxSize)
+	}
+	if !ok {
+		keys = make(map[string][]byte)
+		n.db.data[n.name] = keys
+	}
+	keys[key] = append([]byte{}, value...)
+	n.db.size = size
+	return n.db.changed()
+}
+
+func (n *namespace) Delete(key string) error {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	n.db.mu.Lock()
+	defer n.db.mu.Unlock()
+	keys, ok := n.db.data[n.name]
+	if !ok {
+		return nil
+	}
+	value, ok := keys[key]
+	if !ok {
+		return nil
+	}
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(n.db.data, n.name)
+	}
+	n.db.size -= len(key) + len(value)
+	return n.db.changed()
+}
+
+func (n *namespace) Keys() ([]string, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	n.db.mu.Lock()
+	defer n.db.mu.Unlock()
+	keys := make([]string, 0, len(n.db.data[n.name]))
+	for key := range n.db.data[n.name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	a, b := db.Namespace("inputs.a"), db.Namespace("inputs.b")
+
+	value, err := a.Get("key")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	require.NoError(t, a.Set("key", []byte("a")))
+	require.NoError(t, a.Set("other", []byte{0, 1}))
+	require.NoError(t, b.Set("key", []byte("b")))
+	require.NoError(t, b.Delete("key"))
+	require.NoError(t, b.Delete("missing"))
+	require.NoError(t, db.Close())
+
+	// the state is loaded again
+	db, err = Open(path)
+	require.NoError(t, err)
+	a, b = db.Namespace("inputs.a"), db.Namespace("inputs.b")
+	value, err = a.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), value)
+	keys, err := a.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key", "other"}, keys)
+	keys, err = b.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// the returned values are copies
+	value[0] = 'x'
+	value, err = a.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), value)
+}
+
+func TestStoreFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	db.flushInterval = 10 * time.Millisecond
+	s := db.Namespace("inputs.a")
+
+	// the changes are written together by the timer
+	require.NoError(t, s.Set("key", []byte("a")))
+	require.NoError(t, s.Set("key", []byte("b")))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	var value []byte
+	for i := 0; i < 100 && string(value) != "b"; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if loaded, err := Open(path); err == nil {
+			value, _ = loaded.Namespace("inputs.a").Get("key")
+		}
+	}
+	assert.Equal(t, "b", string(value))
+
+	// once closed, the changes are written right away
+	require.NoError(t, db.Close())
+	require.NoError(t, s.Delete("key"))
+	loaded, err := Open(path)
+	require.NoError(t, err)
+	assert.False(t, loaded.HasNamespace("inputs.a"))
+}
+
+func TestStoreMaxSize(t *testing.T) {
+	db, err := Open("")
+	require.NoError(t, err)
+	s := db.Namespace("inputs.a")
+	require.NoError(t, s.Set("key", make([]byte, MaxSize-3)))
+	// the value replaced does not count
+	require.NoError(t, s.Set("key", make([]byte, MaxSize-3)))
+	assert.EqualError(t, s.Set("other", []byte("a")),
+		fmt.Sprintf("state of %d bytes above the limit of %d bytes", MaxSize+6, MaxSize))
+	require.NoError(t, s.Delete("key"))
+	require.NoError(t, s.Set("other", []byte("a")))
+}
+
+func TestStoreMove(t *testing.T) {
+	prev, err := Open("")
+	require.NoError(t, err)
+	s := prev.Namespace("inputs.a")
+	require.NoError(t, s.Set("key", []byte("a")))
+
+	db, err := Open("")
+	require.NoError(t, err)
+	require.NoError(t, db.Namespace("inputs.b").Set("stale", []byte("b")))
+	require.NoError(t, db.Move(s, "inputs.b"))
+
+	// the keys of the namespace replace the ones of its new name, and its
+	// changes go to the new store
+	keys, err := db.Namespace("inputs.b").Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key"}, keys)
+	require.NoError(t, s.Set("other", []byte("b")))
+	value, err := db.Namespace("inputs.b").Get("other")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), value)
+	assert.False(t, prev.HasNamespace("inputs.b"))
+
+	assert.Error(t, db.Move(nil, "inputs.c"))
+}
+
+func TestStoreInMemory(t *testing.T) {
+	db, err := Open("")
+	require.NoError(t, err)
+	s := db.Namespace("inputs.a")
+	require.NoError(t, s.Set("key", []byte("a")))
+	value, err := s.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), value)
+}
+
+func TestStoreInvalidFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "state")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("{")
+	f.Close()
+
+	_, err = Open(f.Name())
+	assert.Error(t, err)
+}
@@ -0,0 +1,365 @@
+// Package tls provides the TLS options shared by the plugins, plugins
+// embed ClientConfig or ServerConfig in their configuration struct.
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// The fields are named after their TOML keys, the TOML decoder finds the
+// fields of an embedded struct by name and ignores their tags.
+
+// ClientConfig represents the standard client TLS config.
+type ClientConfig struct {
+	TlsCa              string
+	TlsCert            string
+	TlsKey             string
+	InsecureSkipVerify bool
+	TlsMinVersion      string
+	TlsMaxVersion      string
+	TlsCipherSuites    []string
+
+	// Deprecated, use the tls_ options
+	SslCa   string
+	SslCert string
+	SslKey  string
+}
+
+// ServerConfig represents the standard server TLS config.
+type ServerConfig struct {
+	TlsCert           string
+	TlsKey            string
+	TlsAllowedCacerts []string
+	TlsMinVersion     string
+	TlsMaxVersion     string
+	TlsCipherSuites   []string
+}
+
+// Enabled returns true if one of the TLS options is set, the plugins
+// connecting with or without TLS only enable it then.
+func (c *ClientConfig) Enabled() bool {
+	ca, cert, key := c.files()
+	return ca != "" || cert != "" || key != "" || c.InsecureSkipVerify ||
+		c.TlsMinVersion != "" || c.TlsMaxVersion != "" || len(c.TlsCipherSuites) > 0
+}
+
+// files returns the CA, certificate and key files, of the deprecated ssl_
+// options if the tls_ ones are not set.
+func (c *ClientConfig) files() (ca, cert, key string) {
+	ca, cert, key = c.TlsCa, c.TlsCert, c.TlsKey
+	if ca == "" {
+		ca = c.SslCa
+	}
+	if cert == "" {
+		cert = c.SslCert
+	}
+	if key == "" {
+		key = c.SslKey
+	}
+	return ca, cert, key
+}
+
+// TLSConfig returns a tls.Config, may be nil without error if TLS is not
+// configured. In FIPS mode it is the default config restricted to the FIPS
+// settings then. The client certificate is loaded again when its files
+// change.
+func (c *ClientConfig) TLSConfig() (*tls.Config, error) {
+	if !c.Enabled() {
+		if !FIPSMode {
+			return nil, nil
+		}
+		config := &tls.Config{}
+		ApplyFIPS(config)
+		return config, nil
+	}
+	ca, cert, key := c.files()
+
+	config := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if err := setVersions(config, c.TlsMinVersion, c.TlsMaxVersion, c.TlsCipherSuites); err != nil {
+		return nil, err
+	}
+	if FIPSMode {
+		if err := checkFIPS(c.TlsMinVersion, c.TlsMaxVersion, c.TlsCipherSuites); err != nil {
+			return nil, err
+		}
+		ApplyFIPS(config)
+	}
+
+	if ca != "" {
+		pool, err := makeCertPool([]string{ca})
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, fmt.Errorf("tls_cert and tls_key must be set together")
+		}
+		kp, err := newKeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return kp.get(), nil
+		}
+	}
+
+	return config, nil
+}
+
+// TLSConfig returns a tls.Config, may be nil without error if TLS is not
+// configured. The certificate and the allowed CA certificates are loaded
+// again when their files change.
+func (c *ServerConfig) TLSConfig() (*tls.Config, error) {
+	if c.TlsCert == "" && c.TlsKey == "" && len(c.TlsAllowedCacerts) == 0 {
+		return nil, nil
+	}
+	if c.TlsCert == "" || c.TlsKey == "" {
+		return nil, fmt.Errorf("tls_cert and tls_key must be set to enable TLS")
+	}
+
+	config := &tls.Config{}
+	if err := setVersions(config, c.TlsMinVersion, c.TlsMaxVersion, c.TlsCipherSuites); err != nil {
+		return nil, err
+	}
+	if FIPSMode {
+		if err := checkFIPS(c.TlsMinVersion, c.TlsMaxVersion, c.TlsCipherSuites); err != nil {
+			return nil, err
+		}
+		ApplyFIPS(config)
+	}
+
+	kp, err := newKeyPair(c.TlsCert, c.TlsKey)
+	if err != nil {
+		return nil, err
+	}
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return kp.get(), nil
+	}
+
+	if len(c.TlsAllowedCacerts) > 0 {
+		cas, err := newCertPool(c.TlsAllowedCacerts)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = cas.get()
+		// the pool of a handshake is only known from the config
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			pool := cas.get()
+			if pool == config.ClientCAs {
+				return nil, nil
+			}
+			updated := config.Clone()
+			updated.ClientCAs = pool
+			updated.GetConfigForClient = nil
+			return updated, nil
+		}
+	}
+
+	return config, nil
+}
+
+var versions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+func setVersions(config *tls.Config, min, max string, ciphers []string) error {
+	if min != "" {
+		v, ok := versions[min]
+		if !ok {
+			return fmt.Errorf("unsupported tls_min_version %q, expected TLS10, TLS11, TLS12 or TLS13", min)
+		}
+		config.MinVersion = v
+	}
+	if max != "" {
+		v, ok := versions[max]
+		if !ok {
+			return fmt.Errorf("unsupported tls_max_version %q, expected TLS10, TLS11, TLS12 or TLS13", max)
+		}
+		config.MaxVersion = v
+	}
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return fmt.Errorf("tls_min_version %s is greater than tls_max_version %s", min, max)
+	}
+	for _, name := range ciphers {
+		id, ok := cipherSuites[name]
+		if !ok {
+			return fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+	return nil
+}
+
+func makeCertPool(files []string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		pem, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load TLS CA: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Could not parse any PEM certificates from %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// checkInterval limits how often the files are checked for changes.
+var checkInterval = 10 * time.Second
+
+// watched are files loaded again when their modification time changes.
+type watched struct {
+	files   []string
+	load    func() error
+	modTime time.Time
+	checked time.Time
+	sync.Mutex
+}
+
+func (w *watched) latest() (time.Time, error) {
+	var latest time.Time
+	for _, file := range w.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (w *watched) init() error {
+	modTime, err := w.latest()
+	if err != nil {
+		return err
+	}
+	if err := w.load(); err != nil {
+		return err
+	}
+	w.modTime = modTime
+	w.checked = time.Now()
+	return nil
+}
+
+// check loads the files again if they changed, the previous content is
+// kept if they can't be loaded, for example while they are being replaced.
+func (w *watched) check() {
+	now := time.Now()
+	if now.Sub(w.checked) < checkInterval {
+		return
+	}
+	w.checked = now
+
+	modTime, err := w.latest()
+	if err != nil || modTime.Equal(w.modTime) {
+		return
+	}
+	if err := w.load(); err != nil {
+		log.Printf("E! [tls] Error reloading %v: %s", w.files, err)
+		return
+	}
+	w.modTime = modTime
+	log.Printf("I! [tls] Reloaded %v", w.files)
+}
+
+type keyPair struct {
+	cert *tls.Certificate
+	watched
+}
+
+func newKeyPair(certFile, keyFile string) (*keyPair, error) {
+	kp := &keyPair{}
+	kp.files = []string{certFile, keyFile}
+	kp.load = func() error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("Could not load TLS key/certificate from %s:%s: %s",
+				keyFile, certFile, err)
+		}
+		kp.cert = &cert
+		return nil
+	}
+	if err := kp.init(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+func (kp *keyPair) get() *tls.Certificate {
+	kp.Lock()
+	defer kp.Unlock()
+	kp.check()
+	return kp.cert
+}
+
+type certPool struct {
+	pool *x509.CertPool
+	watched
+}
+
+func newCertPool(files []string) (*certPool, error) {
+	p := &certPool{}
+	p.files = files
+	p.load = func() error {
+		pool, err := makeCertPool(files)
+		if err != nil {
+			return err
+		}
+		p.pool = pool
+		return nil
+	}
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *certPool) get() *x509.CertPool {
+	p.Lock()
+	defer p.Unlock()
+	p.check()
+	return p.pool
+}
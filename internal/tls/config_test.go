@@ -0,0 +1,244 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a certificate for localhost and its key signed by ca,
+// or self signed without ca.
+func writeCert(t *testing.T, dir, name string, ca *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  ca == nil,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	parent, signer := template, interface{}(key)
+	if ca != nil {
+		parent = ca.Leaf
+		signer = ca.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600))
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	cert.Leaf, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestDecode(t *testing.T) {
+	var plugin struct {
+		Servers []string
+		ClientConfig
+	}
+	err := toml.Unmarshal([]byte(`
+servers = ["localhost"]
+tls_ca = "/etc/telegraf/ca.pem"
+ssl_cert = "/etc/telegraf/cert.pem"
+insecure_skip_verify = true
+tls_min_version = "TLS11"
+tls_cipher_suites = ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
+`), &plugin)
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/telegraf/ca.pem", plugin.TlsCa)
+	assert.Equal(t, "/etc/telegraf/cert.pem", plugin.SslCert)
+	assert.True(t, plugin.InsecureSkipVerify)
+	assert.Equal(t, "TLS11", plugin.TlsMinVersion)
+	assert.Equal(t, []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}, plugin.TlsCipherSuites)
+
+	var listener struct {
+		ServerConfig
+	}
+	err = toml.Unmarshal([]byte(`tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]`), &listener)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/etc/telegraf/clientca.pem"}, listener.TlsAllowedCacerts)
+}
+
+func TestClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ca := writeCert(t, dir, "ca", nil)
+	writeCert(t, dir, "client", &ca)
+
+	config, err := (&ClientConfig{}).TLSConfig()
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	// the deprecated options
+	config, err = (&ClientConfig{
+		SslCa:         filepath.Join(dir, "ca.pem"),
+		SslCert:       filepath.Join(dir, "client.pem"),
+		SslKey:        filepath.Join(dir, "client.key"),
+		TlsMinVersion: "TLS11",
+		TlsMaxVersion: "TLS12",
+	}).TLSConfig()
+	require.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Equal(t, uint16(tls.VersionTLS11), config.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MaxVersion)
+	cert, err := config.GetClientCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, cert.Certificate)
+
+	config, err = (&ClientConfig{TlsMinVersion: "TLS13"}).TLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+
+	for _, c := range []ClientConfig{
+		{TlsMinVersion: "SSL30"},
+		{TlsMinVersion: "TLS12", TlsMaxVersion: "TLS10"},
+		{TlsCipherSuites: []string{"TLS_NULL"}},
+		{TlsCert: filepath.Join(dir, "client.pem")},
+		{TlsCa: filepath.Join(dir, "client.key")},
+		{TlsCa: filepath.Join(dir, "missing.pem")},
+	} {
+		_, err := c.TLSConfig()
+		assert.Error(t, err, "%+v", c)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ca := writeCert(t, dir, "ca", nil)
+	writeCert(t, dir, "server", &ca)
+	writeCert(t, dir, "client", &ca)
+
+	serverConfig, err := (&ServerConfig{
+		TlsCert:           filepath.Join(dir, "server.pem"),
+		TlsKey:            filepath.Join(dir, "server.key"),
+		TlsAllowedCacerts: []string{filepath.Join(dir, "ca.pem")},
+		TlsMaxVersion:     "TLS12",
+		TlsCipherSuites:   []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+	}).TLSConfig()
+	require.NoError(t, err)
+	clientConfig, err := (&ClientConfig{
+		TlsCa:   filepath.Join(dir, "ca.pem"),
+		TlsCert: filepath.Join(dir, "client.pem"),
+		TlsKey:  filepath.Join(dir, "client.key"),
+	}).TLSConfig()
+	require.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer l.Close()
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			err = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+		accepted <- err
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	state := conn.ConnectionState()
+	conn.Close()
+	require.NoError(t, <-accepted)
+	assert.Equal(t, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, state.CipherSuite)
+
+	// clients without certificate are rejected
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			err = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+		accepted <- err
+	}()
+	conn, err = tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: clientConfig.RootCAs})
+	if err == nil {
+		// the client learns the rejection on its next read
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	assert.Error(t, err)
+	assert.Error(t, <-accepted)
+}
+
+func TestReload(t *testing.T) {
+	checkInterval = 0
+	defer func() { checkInterval = 10 * time.Second }()
+
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ca := writeCert(t, dir, "ca", nil)
+	first := writeCert(t, dir, "server", &ca)
+
+	config, err := (&ServerConfig{
+		TlsCert:           filepath.Join(dir, "server.pem"),
+		TlsKey:            filepath.Join(dir, "server.key"),
+		TlsAllowedCacerts: []string{filepath.Join(dir, "ca.pem")},
+	}).TLSConfig()
+	require.NoError(t, err)
+	cert, err := config.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.Certificate, cert.Certificate)
+	updated, err := config.GetConfigForClient(nil)
+	require.NoError(t, err)
+	assert.Nil(t, updated)
+
+	// the files are replaced with a later modification time
+	later := time.Now().Add(time.Minute)
+	second := writeCert(t, dir, "server", &ca)
+	writeCert(t, dir, "ca", nil)
+	for _, name := range []string{"server.pem", "server.key", "ca.pem"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), later, later))
+	}
+	cert, err = config.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate, cert.Certificate)
+	updated, err = config.GetConfigForClient(nil)
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.True(t, updated.ClientCAs != config.ClientCAs)
+
+	// invalid files keep the previous certificate
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "server.pem"), []byte("invalid"), 0600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "server.pem"), later, later))
+	cert, err = config.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate, cert.Certificate)
+}
@@ -0,0 +1,74 @@
+package tls
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// FIPSMode restricts the TLS configs to the FIPS approved TLS versions,
+// cipher suites and curves, it is set from the fips_mode option of the agent
+// before the plugins are started.
+var FIPSMode bool
+
+// fipsCipherSuites are the FIPS approved cipher suites in order of
+// preference, the same as those of crypto/tls/fipsonly.
+var fipsCipherSuites = []string{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS_RSA_WITH_AES_256_GCM_SHA384",
+}
+
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// CheckFIPS returns an error if the options are not allowed in FIPS mode.
+func (c *ClientConfig) CheckFIPS() error {
+	return checkFIPS(c.TlsMinVersion, c.TlsMaxVersion, c.TlsCipherSuites)
+}
+
+// CheckFIPS returns an error if the options are not allowed in FIPS mode.
+func (c *ServerConfig) CheckFIPS() error {
+	return checkFIPS(c.TlsMinVersion, c.TlsMaxVersion, c.TlsCipherSuites)
+}
+
+func checkFIPS(min, max string, ciphers []string) error {
+	for _, v := range []struct {
+		option string
+		value  string
+	}{{"tls_min_version", min}, {"tls_max_version", max}} {
+		if v.value != "" && versions[v.value] < tls.VersionTLS12 {
+			return fmt.Errorf("%s %s is not allowed in FIPS mode, expected TLS12 or TLS13", v.option, v.value)
+		}
+	}
+	for _, name := range ciphers {
+		if !isFIPSCipherSuite(name) {
+			return fmt.Errorf("cipher suite %q is not allowed in FIPS mode", name)
+		}
+	}
+	return nil
+}
+
+func isFIPSCipherSuite(name string) bool {
+	for _, fips := range fipsCipherSuites {
+		if name == fips {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyFIPS restricts the config to the FIPS approved TLS versions, cipher
+// suites and curves, the options must have been checked with CheckFIPS.
+func ApplyFIPS(config *tls.Config) {
+	if config.MinVersion < tls.VersionTLS12 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if len(config.CipherSuites) == 0 {
+		for _, name := range fipsCipherSuites {
+			config.CipherSuites = append(config.CipherSuites, cipherSuites[name])
+		}
+	}
+	config.CurvePreferences = fipsCurves
+}
@@ -0,0 +1,55 @@
+package tls
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIPSMode(t *testing.T) {
+	config, err := (&ClientConfig{}).TLSConfig()
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	FIPSMode = true
+	defer func() { FIPSMode = false }()
+
+	config, err = (&ClientConfig{InsecureSkipVerify: true}).TLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Len(t, config.CipherSuites, len(fipsCipherSuites))
+	assert.Equal(t, fipsCurves, config.CurvePreferences)
+
+	// the plugins without TLS options use the FIPS settings too
+	c := &ClientConfig{}
+	assert.False(t, c.Enabled())
+	config, err = c.TLSConfig()
+	require.NoError(t, err)
+	require.NotNil(t, config)
+	assert.False(t, config.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, fipsCurves, config.CurvePreferences)
+
+	config, err = (&ClientConfig{
+		TlsMinVersion:   "TLS12",
+		TlsCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+	}).TLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, config.CipherSuites)
+
+	for _, c := range []ClientConfig{
+		{TlsMinVersion: "TLS10"},
+		{TlsMaxVersion: "TLS11"},
+		{TlsCipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		{TlsCipherSuites: []string{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305"}},
+	} {
+		assert.Error(t, c.CheckFIPS(), "%+v", c)
+		_, err := c.TLSConfig()
+		assert.Error(t, err, "%+v", c)
+	}
+
+	assert.NoError(t, (&ServerConfig{TlsMinVersion: "TLS12"}).CheckFIPS())
+	assert.Error(t, (&ServerConfig{TlsMinVersion: "TLS11"}).CheckFIPS())
+}
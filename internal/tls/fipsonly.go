@@ -0,0 +1,7 @@
+// +build boringcrypto
+
+package tls
+
+// Built with the BoringCrypto toolchain, all the TLS connections, including
+// those of plugins without TLS options, only use the FIPS approved settings.
+import _ "crypto/tls/fipsonly"
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"strconv"
+	"time"
+)
+
+// ParseTimestamp parses a timestamp of the given format: "unix", a time in
+// seconds which may have decimals, "unix_ms", "unix_us", "unix_ns" or a time
+// layout.
+func ParseTimestamp(value, format string) (time.Time, error) {
+	var unit time.Duration
+	switch format {
+	case "unix":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC(), nil
+	case "unix_ms":
+		unit = time.Millisecond
+	case "unix_us":
+		unit = time.Microsecond
+	case "unix_ns":
+		unit = time.Nanosecond
+	default:
+		return time.Parse(format, value)
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, i*int64(unit)).UTC(), nil
+}
+
+// ParseValue returns the value as an integer, a float or a boolean if it is
+// one, as a string otherwise.
+func ParseValue(value string) interface{} {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	return value
+}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Unix(1536843808, 500000000).UTC()
+	for format, value := range map[string]string{
+		"unix":                    "1536843808.5",
+		"unix_ms":                 "1536843808500",
+		"unix_us":                 "1536843808500000",
+		"unix_ns":                 "1536843808500000000",
+		"2006-01-02T15:04:05.0Z":  "2018-09-13T13:03:28.5Z",
+		"2006-01-02 15:04:05.000": "2018-09-13 13:03:28.500",
+	} {
+		tm, err := ParseTimestamp(value, format)
+		require.NoError(t, err, format)
+		assert.Equal(t, expected, tm, format)
+	}
+
+	_, err := ParseTimestamp("yesterday", "unix")
+	assert.Error(t, err)
+}
+
+func TestParseValue(t *testing.T) {
+	assert.Equal(t, int64(-3), ParseValue("-3"))
+	assert.Equal(t, 2.5, ParseValue("2.5"))
+	assert.Equal(t, true, ParseValue("true"))
+	assert.Equal(t, "ok", ParseValue("ok"))
+}
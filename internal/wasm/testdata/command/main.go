@@ -0,0 +1,29 @@
+// command is the module of the tests, it copies its stdin to its stdout or
+// does what its first argument says.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stderr":
+			fmt.Fprintln(os.Stderr, "something went wrong")
+		case "exit":
+			os.Exit(3)
+		case "loop":
+			for {
+			}
+		case "file":
+			if _, err := os.Open("/etc/passwd"); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+	}
+	io.Copy(os.Stdout, os.Stdin)
+}
@@ -0,0 +1,107 @@
+// Package wasm runs the WebAssembly modules of the wasm plugins, in a
+// sandbox without access to the file system or the network.
+package wasm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Module is a WASI command module, compiled once and run for each call with
+// the data on its stdin. A Module is safe for concurrent use.
+type Module struct {
+	path     string
+	args     []string
+	timeout  time.Duration
+	log      telegraf.Logger
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// Load compiles the module of the file at path, it is run with the args and
+// is stopped when it does not exit within the timeout.
+func Load(path string, args []string, timeout time.Duration, logger telegraf.Logger) (*Module, error) {
+	binary, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx,
+		wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("compiling %s: %s", path, err)
+	}
+	return &Module{
+		path:     path,
+		args:     args,
+		timeout:  timeout,
+		log:      logger,
+		runtime:  runtime,
+		compiled: compiled,
+	}, nil
+}
+
+// Run runs the module with the input on its stdin and returns what it wrote
+// to its stdout, the lines it writes to its stderr are logged as errors, with
+// the standard logger when the Module has no logger.
+func (m *Module) Run(input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{m.path}, m.args...)...).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+	mod, err := m.runtime.InstantiateModule(ctx, m.compiled, config)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	m.logStderr(&stderr)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("module %s did not exit within %s", m.path, m.timeout)
+	}
+	if exit, ok := err.(*sys.ExitError); ok {
+		return nil, fmt.Errorf("module %s exited with code %d", m.path, exit.ExitCode())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %s", m.path, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Close releases the compiled module.
+func (m *Module) Close() error {
+	return m.runtime.Close(context.Background())
+}
+
+func (m *Module) logStderr(stderr *bytes.Buffer) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		if m.log == nil {
+			log.Printf("E! %s: %s", m.path, scanner.Text())
+			continue
+		}
+		m.log.Errorf("%s: %s", m.path, scanner.Text())
+	}
+}
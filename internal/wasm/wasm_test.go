@@ -0,0 +1,96 @@
+package wasm
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// buildModule compiles the program of the testdata directory to a WASI
+// module.
+func buildModule(t *testing.T, program string) string {
+	dir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	path := filepath.Join(dir, program+".wasm")
+	cmd := exec.Command("go", "build", "-o", path, "./testdata/"+program)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		t.Skipf("cannot build the test module: %s: %s", err, out)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	path := buildModule(t, "command")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	m, err := Load(path, nil, 10*time.Second, testutil.Logger{})
+	require.NoError(t, err)
+	defer m.Close()
+
+	out, err := m.Run([]byte("cpu value=42\n"))
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42\n", string(out))
+
+	// the module is run again for each call
+	out, err = m.Run([]byte("mem value=1\n"))
+	require.NoError(t, err)
+	require.Equal(t, "mem value=1\n", string(out))
+}
+
+func TestRunErrors(t *testing.T) {
+	path := buildModule(t, "command")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	tests := []struct {
+		arg string
+		err string
+	}{
+		{arg: "exit", err: "exited with code 3"},
+		{arg: "loop", err: "did not exit within"},
+		// the module has no access to the file system
+		{arg: "file", err: "exited with code 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			m, err := Load(path, []string{tt.arg}, 2*time.Second, testutil.Logger{})
+			require.NoError(t, err)
+			defer m.Close()
+
+			_, err = m.Run([]byte("cpu value=42\n"))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestRunStderr(t *testing.T) {
+	path := buildModule(t, "command")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	m, err := Load(path, []string{"stderr"}, 10*time.Second, testutil.Logger{})
+	require.NoError(t, err)
+	defer m.Close()
+
+	out, err := m.Run([]byte("cpu value=42\n"))
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42\n", string(out))
+}
+
+func TestLoadInvalid(t *testing.T) {
+	f, err := ioutil.TempFile("", "invalid.wasm")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("not a module")
+	f.Close()
+
+	_, err = Load(f.Name(), nil, time.Second, testutil.Logger{})
+	require.Error(t, err)
+}
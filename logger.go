@@ -0,0 +1,23 @@
+package telegraf
+
+// Logger logs the messages of a plugin instance, prefixed with the name of
+// the plugin and its alias.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+}
+
+// LoggingPlugin is a plugin logging with its own Logger, the logger is set
+// when the plugin is configured.
+type LoggingPlugin interface {
+	SetLogger(logger Logger)
+}
+
+// AliasedPlugin is a plugin told the alias of its instance, for example to
+// tag its internal stats with it. The alias is set when the plugin is
+// configured, it is empty if the instance has none.
+type AliasedPlugin interface {
+	SetAlias(alias string)
+}
@@ -1,36 +1,290 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/influxdata/wlog"
 )
 
+// The formats of the log lines.
+const (
+	// FormatText lines are "<time> L! [plugin] message"
+	FormatText = "text"
+	// FormatLogfmt lines are "time=<time> level=<level> plugin=<plugin> msg=<message>"
+	FormatLogfmt = "logfmt"
+	// FormatJSON lines are objects with the time, level, plugin and msg keys
+	FormatJSON = "json"
+)
+
+// The targets of the logs.
+const (
+	TargetStderr = "stderr"
+	TargetFile   = "file"
+	TargetSyslog = "syslog"
+)
+
+// DefaultRotationMaxArchives is the number of rotated log files kept if the
+// RotationMaxArchives of the Config is 0.
+const DefaultRotationMaxArchives = 5
+
+// Config configures the logging.
+type Config struct {
+	// Debug sets the log level to DEBUG and Quiet to ERROR, Level overrides
+	// them with the name of the level.
+	Debug bool
+	Quiet bool
+	Level string
+
+	// Format of the log lines, FormatText by default.
+	Format string
+
+	// Target of the logs, the Logfile if there is one and stderr otherwise
+	// by default.
+	Target  string
+	Logfile string
+
+	// The log file is rotated once it is older than the RotationInterval or
+	// larger than the RotationMaxSize, 0 never rotates it. The oldest rotated
+	// files beyond the RotationMaxArchives are removed, -1 keeps them all.
+	RotationInterval    time.Duration
+	RotationMaxSize     int64
+	RotationMaxArchives int
+}
+
 var prefixRegex = regexp.MustCompile("^[DIWE]!")
 
+// pluginRegex matches the name of the plugin logging the message, the
+// plugin loggers prefix the messages with it.
+var pluginRegex = regexp.MustCompile(`^\[([^\[\]\s]+)\] `)
+
+var levelNames = map[wlog.Level]string{
+	wlog.DEBUG: "debug",
+	wlog.INFO:  "info",
+	wlog.WARN:  "warn",
+	wlog.ERROR: "error",
+}
+
+// output writes the formatted log lines.
+type output interface {
+	writeLevel(level wlog.Level, line []byte) error
+	Close() error
+}
+
+type writerOutput struct {
+	io.Writer
+}
+
+func (w writerOutput) writeLevel(_ wlog.Level, line []byte) error {
+	_, err := w.Write(line)
+	return err
+}
+
+func (w writerOutput) Close() error {
+	return nil
+}
+
 // newTelegrafWriter returns a logging-wrapped writer.
 func newTelegrafWriter(w io.Writer) io.Writer {
 	return &telegrafLog{
-		writer: wlog.NewWriter(w),
+		format: FormatText,
+		output: writerOutput{w},
 	}
 }
 
+// telegrafLog parses the "L! [plugin] message" lines of the log package,
+// the lines below the log level are dropped.
 type telegrafLog struct {
-	writer io.Writer
+	format string
+	// omitTime leaves the time out of the text lines, syslog records it
+	omitTime bool
+	output   output
+}
+
+// entry is a parsed log line.
+type entry struct {
+	time   time.Time
+	level  wlog.Level
+	plugin string
+	msg    string
+}
+
+func parse(b []byte) entry {
+	line := strings.TrimRight(string(b), "\n")
+	e := entry{time: time.Now().UTC(), level: wlog.INFO}
+	if prefixRegex.MatchString(line) {
+		e.level = wlog.Levels[line[0]]
+		line = strings.TrimPrefix(line[2:], " ")
+	}
+	if m := pluginRegex.FindStringSubmatch(line); m != nil {
+		e.plugin = m[1]
+		line = line[len(m[0]):]
+	}
+	e.msg = line
+	return e
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
+	e := parse(b)
+	if e.level < wlog.LogLevel() {
+		return len(b), nil
+	}
+
 	var line []byte
-	if !prefixRegex.Match(b) {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
-	} else {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
+	switch t.format {
+	case FormatLogfmt:
+		line = e.logfmt()
+	case FormatJSON:
+		line = e.json()
+	default:
+		line = e.text(t.omitTime)
+	}
+	if err := t.output.writeLevel(e.level, line); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (e *entry) text(omitTime bool) []byte {
+	var buf bytes.Buffer
+	if !omitTime {
+		buf.WriteString(e.time.Format(time.RFC3339))
+		buf.WriteByte(' ')
+	}
+	buf.WriteByte(wlog.ReverseLevels[e.level])
+	buf.WriteString("! ")
+	if e.plugin != "" {
+		buf.WriteString("[" + e.plugin + "] ")
+	}
+	buf.WriteString(e.msg)
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func (e *entry) logfmt() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("time=" + e.time.Format(time.RFC3339))
+	buf.WriteString(" level=" + levelNames[e.level])
+	if e.plugin != "" {
+		buf.WriteString(" plugin=" + logfmtValue(e.plugin))
+	}
+	buf.WriteString(" msg=" + logfmtValue(e.msg))
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// logfmtValue quotes the values that are empty or have spaces, quotes,
+// equal signs or control characters.
+func logfmtValue(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r == ' ' || r == '=' || r == '"' || unicode.IsControl(r)
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func (e *entry) json() []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(struct {
+		Time   string `json:"time"`
+		Level  string `json:"level"`
+		Plugin string `json:"plugin,omitempty"`
+		Msg    string `json:"msg"`
+	}{e.time.Format(time.RFC3339), levelNames[e.level], e.plugin, e.msg})
+	return buf.Bytes()
+}
+
+// current is the output of the logs, it is closed when the logging is set up
+// again.
+var current output
+
+// Setup configures the logging. The invalid options are replaced by their
+// default and returned in the error, the logs go to stderr if the target
+// cannot be opened.
+func Setup(config Config) error {
+	log.SetFlags(0)
+	var errs []string
+
+	level := wlog.INFO
+	if config.Debug {
+		level = wlog.DEBUG
+	}
+	if config.Quiet {
+		level = wlog.ERROR
+	}
+	if config.Level != "" {
+		if l, ok := wlog.StringToLevel[strings.ToUpper(config.Level)]; ok {
+			level = l
+		} else {
+			errs = append(errs, fmt.Sprintf("invalid log level %q", config.Level))
+		}
+	}
+	wlog.SetLevel(level)
+
+	t := &telegrafLog{format: config.Format}
+	switch config.Format {
+	case FormatText, FormatLogfmt, FormatJSON:
+	case "":
+		t.format = FormatText
+	default:
+		errs = append(errs, fmt.Sprintf("invalid log format %q", config.Format))
+		t.format = FormatText
 	}
-	return t.writer.Write(line)
+
+	target := config.Target
+	if target == "" {
+		target = TargetStderr
+		if config.Logfile != "" {
+			target = TargetFile
+		}
+	}
+	var err error
+	switch target {
+	case TargetStderr:
+		t.output = writerOutput{os.Stderr}
+	case TargetFile:
+		if config.Logfile == "" {
+			err = fmt.Errorf("no logfile for the file log target")
+			break
+		}
+		maxArchives := config.RotationMaxArchives
+		if maxArchives == 0 {
+			maxArchives = DefaultRotationMaxArchives
+		}
+		t.output, err = openRotatingFile(config.Logfile, config.RotationInterval, config.RotationMaxSize, maxArchives)
+	case TargetSyslog:
+		t.omitTime = true
+		t.output, err = newSyslogOutput()
+	default:
+		err = fmt.Errorf("invalid log target %q", target)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("%s, using stderr", err))
+		t.output = writerOutput{os.Stderr}
+	}
+
+	log.SetOutput(t)
+	if current != nil {
+		current.Close()
+	}
+	current = t.output
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
 }
 
 // SetupLogging configures the logging output.
@@ -40,30 +294,8 @@ func (t *telegrafLog) Write(b []byte) (n int, err error) {
 //           interpreted as stderr. If there is an error opening the file the
 //           logger will fallback to stderr.
 func SetupLogging(debug, quiet bool, logfile string) {
-	log.SetFlags(0)
-	if debug {
-		wlog.SetLevel(wlog.DEBUG)
+	err := Setup(Config{Debug: debug, Quiet: quiet, Logfile: logfile})
+	if err != nil {
+		log.Printf("E! Unable to set up the logging (%s)", err)
 	}
-	if quiet {
-		wlog.SetLevel(wlog.ERROR)
-	}
-
-	var oFile *os.File
-	if logfile != "" {
-		if _, err := os.Stat(logfile); os.IsNotExist(err) {
-			if oFile, err = os.Create(logfile); err != nil {
-				log.Printf("E! Unable to create %s (%s), using stderr", logfile, err)
-				oFile = os.Stderr
-			}
-		} else {
-			if oFile, err = os.OpenFile(logfile, os.O_APPEND|os.O_WRONLY, os.ModeAppend); err != nil {
-				log.Printf("E! Unable to append to %s (%s), using stderr", logfile, err)
-				oFile = os.Stderr
-			}
-		}
-	} else {
-		oFile = os.Stderr
-	}
-
-	log.SetOutput(newTelegrafWriter(oFile))
 }
@@ -2,11 +2,17 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,3 +79,102 @@ func BenchmarkTelegrafLogWrite(b *testing.B) {
 		w.Write(msg)
 	}
 }
+
+func readLog(t *testing.T, config Config, lines ...string) string {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	config.Logfile = tmpfile.Name()
+	assert.NoError(t, Setup(config))
+	for _, line := range lines {
+		log.Print(line)
+	}
+	assert.NoError(t, Setup(Config{}))
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	return string(f)
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	f := readLog(t, Config{Format: FormatLogfmt},
+		"E! [inputs.cpu::total] gathering failed",
+		"I! Starting",
+		"D! ignored",
+	)
+	lines := strings.Split(strings.TrimSpace(f), "\n")
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, `^time=\S+Z level=error plugin=inputs.cpu::total msg="gathering failed"$`, lines[0])
+	assert.Regexp(t, `^time=\S+Z level=info msg=Starting$`, lines[1])
+}
+
+func TestJSONFormat(t *testing.T) {
+	f := readLog(t, Config{Format: FormatJSON, Level: "warn"},
+		"W! [outputs.file] a <quoted> \"message\"",
+		"I! ignored",
+	)
+	var entry map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(f), &entry))
+	assert.NotEmpty(t, entry["time"])
+	delete(entry, "time")
+	assert.Equal(t, map[string]string{
+		"level":  "warn",
+		"plugin": "outputs.file",
+		"msg":    "a <quoted> \"message\"",
+	}, entry)
+}
+
+func TestTextFormatPlugin(t *testing.T) {
+	f := readLog(t, Config{}, "[inputs.cpu] TEST")
+	assert.Equal(t, "Z I! [inputs.cpu] TEST\n", f[19:])
+}
+
+func TestInvalidConfig(t *testing.T) {
+	defer Setup(Config{})
+	err := Setup(Config{Format: "xml", Level: "loud", Target: "pigeon"})
+	assert.EqualError(t, err, `invalid log level "loud", invalid log format "xml", invalid log target "pigeon", using stderr`)
+	assert.Equal(t, wlog.INFO, wlog.LogLevel())
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logfile := filepath.Join(dir, "telegraf.log")
+
+	defer Setup(Config{})
+	assert.NoError(t, Setup(Config{Logfile: logfile, RotationMaxSize: 30, RotationMaxArchives: 2}))
+	for i := 0; i < 4; i++ {
+		log.Printf("I! message %d", i)
+	}
+
+	f, err := ioutil.ReadFile(logfile)
+	assert.NoError(t, err)
+	assert.Equal(t, "Z I! message 3\n", string(f[19:]))
+
+	archives, err := filepath.Glob(logfile + ".*")
+	assert.NoError(t, err)
+	sort.Strings(archives)
+	assert.Len(t, archives, 2)
+	f, err = ioutil.ReadFile(archives[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "Z I! message 1\n", string(f[19:]))
+}
+
+func TestRotateByInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logfile := filepath.Join(dir, "telegraf.log")
+
+	defer Setup(Config{})
+	assert.NoError(t, Setup(Config{Logfile: logfile, RotationInterval: time.Millisecond, RotationMaxArchives: -1}))
+	log.Printf("I! first")
+	time.Sleep(5 * time.Millisecond)
+	log.Printf("I! second")
+
+	archives, err := filepath.Glob(logfile + ".*")
+	assert.NoError(t, err)
+	assert.Len(t, archives, 1)
+}
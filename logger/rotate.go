@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/wlog"
+)
+
+// archiveLayout is the layout of the time of the rotation in the names of
+// the rotated log files, the names sort in the order of the rotations.
+const archiveLayout = "2006-01-02T15-04-05.000000000Z"
+
+// rotatingFile is a log file rotated once it is older than the interval or
+// larger than the maximum size. The file is renamed with the time of the
+// rotation appended, the oldest of these archives beyond the maximum number
+// are removed.
+type rotatingFile struct {
+	path        string
+	interval    time.Duration
+	maxSize     int64
+	maxArchives int
+
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func openRotatingFile(path string, interval time.Duration, maxSize int64, maxArchives int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:        path,
+		interval:    interval,
+		maxSize:     maxSize,
+		maxArchives: maxArchives,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+func (f *rotatingFile) writeLevel(_ wlog.Level, line []byte) error {
+	if f.needsRotation(len(line)) {
+		// the lines keep going to the current file if it cannot be rotated
+		f.rotate()
+	}
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+func (f *rotatingFile) needsRotation(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.interval > 0 && time.Since(f.opened) >= f.interval {
+		return true
+	}
+	return f.maxSize > 0 && f.size+int64(n) > f.maxSize
+}
+
+func (f *rotatingFile) rotate() error {
+	archive := f.path + "." + time.Now().UTC().Format(archiveLayout)
+	if err := os.Rename(f.path, archive); err != nil {
+		return err
+	}
+	f.file.Close()
+	if err := f.open(); err != nil {
+		// keep logging to the renamed file
+		f.file, _ = os.OpenFile(archive, os.O_APPEND|os.O_WRONLY, 0666)
+		return err
+	}
+	return f.removeArchives()
+}
+
+// removeArchives removes the oldest archives beyond the maximum number.
+func (f *rotatingFile) removeArchives() error {
+	if f.maxArchives < 0 {
+		return nil
+	}
+	archives, err := f.archives()
+	if err != nil {
+		return err
+	}
+	for len(archives) > f.maxArchives {
+		if err := os.Remove(archives[0]); err != nil {
+			return err
+		}
+		archives = archives[1:]
+	}
+	return nil
+}
+
+// archives returns the rotated files of the log file, the oldest first.
+func (f *rotatingFile) archives() ([]string, error) {
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var archives []string
+	for _, match := range matches {
+		suffix := strings.TrimPrefix(match, f.path+".")
+		if _, err := time.Parse(archiveLayout, suffix); err == nil {
+			archives = append(archives, match)
+		}
+	}
+	sort.Strings(archives)
+	return archives, nil
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,40 @@
+// +build !windows
+
+package logger
+
+import (
+	"log/syslog"
+
+	"github.com/influxdata/wlog"
+)
+
+// syslogOutput writes the log lines to the local syslog at the severity of
+// their level.
+type syslogOutput struct {
+	writer *syslog.Writer
+}
+
+func newSyslogOutput() (output, error) {
+	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, "telegraf")
+	if err != nil {
+		return nil, err
+	}
+	return &syslogOutput{writer: w}, nil
+}
+
+func (s *syslogOutput) writeLevel(level wlog.Level, line []byte) error {
+	msg := string(line)
+	switch level {
+	case wlog.DEBUG:
+		return s.writer.Debug(msg)
+	case wlog.WARN:
+		return s.writer.Warning(msg)
+	case wlog.ERROR:
+		return s.writer.Err(msg)
+	}
+	return s.writer.Info(msg)
+}
+
+func (s *syslogOutput) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,7 @@
+package logger
+
+import "errors"
+
+func newSyslogOutput() (output, error) {
+	return nil, errors.New("the syslog log target is not supported on windows")
+}
@@ -31,6 +31,8 @@ type Metric interface {
 	// Getting data structure functions
 	Name() string
 	Tags() map[string]string
+	// TagList returns the tags sorted by key, the tags are shared between
+	// metrics and must not be modified, use AddTag instead.
 	TagList() []*Tag
 	Fields() map[string]interface{}
 	FieldList() []*Field
@@ -63,4 +65,11 @@ type Metric interface {
 	// Mark Metric as an aggregate
 	SetAggregate(bool)
 	IsAggregate() bool
+
+	// Accept marks the metric as written by an output, Reject as not
+	// written and Drop as discarded on purpose, like by a filter. They report
+	// the delivery of tracking metrics and do nothing for the others.
+	Accept()
+	Reject()
+	Drop()
 }
@@ -23,7 +23,7 @@ func NewBuilder() *Builder {
 }
 
 func (b *Builder) SetName(name string) {
-	b.name = name
+	b.metric.SetName(name)
 }
 
 func (b *Builder) AddTag(key string, value string) {
@@ -0,0 +1,88 @@
+package metric
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// maxInterned limits the number of entries of each table, the table is
+// cleared when it is full so that series with unique tags, like request ids,
+// don't grow it forever. Interned values remain valid after the reset.
+var maxInterned = 1 << 17
+
+// internTable shares the tags, metric names and field keys of the metrics,
+// most of the metrics of an agent repeat the same few thousand tags. The
+// shared tags must not be modified, the metrics replace them instead.
+type internTable struct {
+	sync.RWMutex
+	strings map[string]string
+	tags    map[telegraf.Tag]*telegraf.Tag
+}
+
+var interned = &internTable{
+	strings: make(map[string]string),
+	tags:    make(map[telegraf.Tag]*telegraf.Tag),
+}
+
+// Intern returns a string equal to s that is shared with the other metrics.
+func Intern(s string) string {
+	return interned.string(s)
+}
+
+// InternBytes returns a shared string equal to b, the string is only
+// allocated the first time b is seen, parsers use it for the bytes of their
+// input buffer.
+func InternBytes(b []byte) string {
+	interned.RLock()
+	v, ok := interned.strings[string(b)]
+	interned.RUnlock()
+	if ok {
+		return v
+	}
+	return interned.string(string(b))
+}
+
+func (t *internTable) string(s string) string {
+	t.RLock()
+	v, ok := t.strings[s]
+	t.RUnlock()
+	if ok {
+		return v
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if v, ok := t.strings[s]; ok {
+		return v
+	}
+	if len(t.strings) >= maxInterned {
+		t.strings = make(map[string]string)
+	}
+	t.strings[s] = s
+	return s
+}
+
+// tag returns the shared tag of the key and the value.
+func (t *internTable) tag(key, value string) *telegraf.Tag {
+	k := telegraf.Tag{Key: key, Value: value}
+	t.RLock()
+	tag, ok := t.tags[k]
+	t.RUnlock()
+	if ok {
+		return tag
+	}
+
+	// keys are interned separately, they repeat between the series
+	tag = &telegraf.Tag{Key: t.string(key), Value: value}
+	t.Lock()
+	defer t.Unlock()
+	if shared, ok := t.tags[k]; ok {
+		return shared
+	}
+	if len(t.tags) >= maxInterned {
+		t.tags = make(map[telegraf.Tag]*telegraf.Tag)
+	}
+	t.tags[k] = tag
+	return tag
+}
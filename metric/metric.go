@@ -34,7 +34,7 @@ func New(
 	}
 
 	m := &metric{
-		name:   name,
+		name:   Intern(name),
 		tags:   nil,
 		fields: nil,
 		tm:     tm,
@@ -44,19 +44,21 @@ func New(
 	if len(tags) > 0 {
 		m.tags = make([]*telegraf.Tag, 0, len(tags))
 		for k, v := range tags {
-			m.tags = append(m.tags,
-				&telegraf.Tag{Key: k, Value: v})
+			m.tags = append(m.tags, interned.tag(k, v))
 		}
 		sort.Slice(m.tags, func(i, j int) bool { return m.tags[i].Key < m.tags[j].Key })
 	}
 
+	// the fields are allocated together, the map has unique keys
 	m.fields = make([]*telegraf.Field, 0, len(fields))
+	buf := make([]telegraf.Field, 0, len(fields))
 	for k, v := range fields {
 		v := convertField(v)
 		if v == nil {
 			continue
 		}
-		m.AddField(k, v)
+		buf = append(buf, telegraf.Field{Key: Intern(k), Value: v})
+		m.fields = append(m.fields, &buf[len(buf)-1])
 	}
 
 	return m, nil
@@ -104,15 +106,15 @@ func (m *metric) Type() telegraf.ValueType {
 }
 
 func (m *metric) SetName(name string) {
-	m.name = name
+	m.name = Intern(name)
 }
 
 func (m *metric) AddPrefix(prefix string) {
-	m.name = prefix + m.name
+	m.name = Intern(prefix + m.name)
 }
 
 func (m *metric) AddSuffix(suffix string) {
-	m.name = m.name + suffix
+	m.name = Intern(m.name + suffix)
 }
 
 func (m *metric) AddTag(key, value string) {
@@ -121,17 +123,19 @@ func (m *metric) AddTag(key, value string) {
 			continue
 		}
 
+		// the tags are shared, they are replaced instead of modified
 		if key == tag.Key {
-			tag.Value = value
+			m.tags[i] = interned.tag(key, value)
+			return
 		}
 
 		m.tags = append(m.tags, nil)
 		copy(m.tags[i+1:], m.tags[i:])
-		m.tags[i] = &telegraf.Tag{Key: key, Value: value}
+		m.tags[i] = interned.tag(key, value)
 		return
 	}
 
-	m.tags = append(m.tags, &telegraf.Tag{Key: key, Value: value})
+	m.tags = append(m.tags, interned.tag(key, value))
 }
 
 func (m *metric) HasTag(key string) bool {
@@ -166,10 +170,11 @@ func (m *metric) RemoveTag(key string) {
 func (m *metric) AddField(key string, value interface{}) {
 	for i, field := range m.fields {
 		if key == field.Key {
-			m.fields[i] = &telegraf.Field{Key: key, Value: convertField(value)}
+			m.fields[i] = &telegraf.Field{Key: field.Key, Value: convertField(value)}
+			return
 		}
 	}
-	m.fields = append(m.fields, &telegraf.Field{Key: key, Value: convertField(value)})
+	m.fields = append(m.fields, &telegraf.Field{Key: Intern(key), Value: convertField(value)})
 }
 
 func (m *metric) HasField(key string) bool {
@@ -229,6 +234,15 @@ func (m *metric) IsAggregate() bool {
 	return m.aggregate
 }
 
+func (m *metric) Accept() {
+}
+
+func (m *metric) Reject() {
+}
+
+func (m *metric) Drop() {
+}
+
 func (m *metric) HashID() uint64 {
 	h := fnv.New64a()
 	h.Write([]byte(m.name))
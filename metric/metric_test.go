@@ -1,6 +1,8 @@
 package metric
 
 import (
+	"runtime"
+	"strconv"
 	"testing"
 	"time"
 
@@ -63,6 +65,7 @@ func TestAddTagOverwrites(t *testing.T) {
 	value, ok := m.GetTag("host")
 	require.True(t, ok)
 	require.Equal(t, "example.org", value)
+	require.Len(t, m.TagList(), 1)
 }
 
 func TestRemoveTagNoEffectOnMissingTags(t *testing.T) {
@@ -113,6 +116,7 @@ func TestAddFieldOverwrites(t *testing.T) {
 	value, ok := m.GetField("value")
 	require.True(t, ok)
 	require.Equal(t, 42.0, value)
+	require.Len(t, m.FieldList(), 1)
 }
 
 func TestAddFieldChangesType(t *testing.T) {
@@ -168,6 +172,39 @@ func TestTagList_Sorted(t *testing.T) {
 	require.Equal(t, "c", taglist[2].Key)
 }
 
+func TestTagsShared(t *testing.T) {
+	m1, err := New("cpu", map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42.0}, time.Now())
+	require.NoError(t, err)
+	m2, err := New("cpu", map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 41.0}, time.Now())
+	require.NoError(t, err)
+	require.True(t, m1.TagList()[0] == m2.TagList()[0])
+
+	// the shared tag is not modified
+	m2.AddTag("host", "example.org")
+	value, _ := m1.GetTag("host")
+	assert.Equal(t, "localhost", value)
+	value, _ = m2.GetTag("host")
+	assert.Equal(t, "example.org", value)
+	assert.True(t, m2.TagList()[0] != m1.TagList()[0])
+}
+
+func TestInternReset(t *testing.T) {
+	defer func(n int) { maxInterned = n }(maxInterned)
+	maxInterned = 2
+
+	first := interned.tag("host", "a")
+	interned.tag("host", "b")
+	interned.tag("host", "c")
+	assert.Len(t, interned.tags, 1)
+
+	// interned again after the reset
+	tag := interned.tag("host", "a")
+	assert.Equal(t, first, tag)
+	assert.True(t, first != tag)
+}
+
 func TestEquals(t *testing.T) {
 	now := time.Now()
 	m1, err := New("cpu",
@@ -308,3 +345,49 @@ func TestCopyAggreate(t *testing.T) {
 	m2 := m1.Copy()
 	assert.True(t, m2.IsAggregate())
 }
+
+// newSeries creates count metrics of series series with the tags of a
+// typical host.
+func newSeries(count, series int) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, count)
+	for i := 0; i < count; i++ {
+		m, _ := New("disk",
+			map[string]string{
+				"host":   "web" + strconv.Itoa(i%series) + ".example.org",
+				"device": "sda" + strconv.Itoa(i%4),
+				"fstype": "ext4",
+				"mode":   "rw",
+				"path":   "/var/lib/docker/" + strconv.Itoa(i%series),
+			},
+			map[string]interface{}{
+				"free":  int64(i),
+				"total": int64(2 * i),
+				"used":  int64(i),
+			},
+			time.Now(),
+		)
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newSeries(1000, 100)
+	}
+}
+
+// BenchmarkRetained reports the memory kept by the metrics of a buffer.
+func BenchmarkRetained(b *testing.B) {
+	var before, after runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		metrics := newSeries(10000, 500)
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.Logf("%d bytes per metric", (after.HeapAlloc-before.HeapAlloc)/uint64(len(metrics)))
+		runtime.KeepAlive(metrics)
+	}
+}
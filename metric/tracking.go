@@ -0,0 +1,93 @@
+package metric
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NotifyFunc is called once with the delivery report of a group of tracking
+// metrics.
+type NotifyFunc func(telegraf.DeliveryInfo)
+
+var lastTrackingID uint64
+
+func newTrackingID() telegraf.TrackingID {
+	return telegraf.TrackingID(atomic.AddUint64(&lastTrackingID, 1))
+}
+
+// trackingData counts the metrics of a group and their copies that are not
+// accepted, rejected or dropped yet.
+type trackingData struct {
+	id       telegraf.TrackingID
+	rc       int32
+	rejected int32
+	notify   NotifyFunc
+}
+
+func (d *trackingData) done() {
+	if atomic.AddInt32(&d.rc, -1) == 0 {
+		d.notify(&deliveryInfo{
+			id:        d.id,
+			delivered: atomic.LoadInt32(&d.rejected) == 0,
+		})
+	}
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (r *deliveryInfo) ID() telegraf.TrackingID {
+	return r.id
+}
+
+func (r *deliveryInfo) Delivered() bool {
+	return r.delivered
+}
+
+// trackingMetric is a metric of a group, its copies belong to the group too.
+type trackingMetric struct {
+	telegraf.Metric
+	d *trackingData
+}
+
+// WithGroupTracking returns the metrics tracked as one group, notify is called
+// once each of them and of their copies is accepted, rejected or dropped.
+// An empty group is reported as delivered at once.
+func WithGroupTracking(metrics []telegraf.Metric, notify NotifyFunc) ([]telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{
+		id:     newTrackingID(),
+		rc:     int32(len(metrics)),
+		notify: notify,
+	}
+	if len(metrics) == 0 {
+		notify(&deliveryInfo{id: d.id, delivered: true})
+		return metrics, d.id
+	}
+
+	tracked := make([]telegraf.Metric, len(metrics))
+	for i, m := range metrics {
+		tracked[i] = &trackingMetric{Metric: m, d: d}
+	}
+	return tracked, d.id
+}
+
+func (m *trackingMetric) Copy() telegraf.Metric {
+	atomic.AddInt32(&m.d.rc, 1)
+	return &trackingMetric{Metric: m.Metric.Copy(), d: m.d}
+}
+
+func (m *trackingMetric) Accept() {
+	m.d.done()
+}
+
+func (m *trackingMetric) Reject() {
+	atomic.StoreInt32(&m.d.rejected, 1)
+	m.d.done()
+}
+
+func (m *trackingMetric) Drop() {
+	m.d.done()
+}
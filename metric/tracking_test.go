@@ -0,0 +1,92 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deliveries struct {
+	infos []telegraf.DeliveryInfo
+}
+
+func (d *deliveries) notify(info telegraf.DeliveryInfo) {
+	d.infos = append(d.infos, info)
+}
+
+func newTrackingGroup(t *testing.T, d *deliveries, n int) ([]telegraf.Metric, telegraf.TrackingID) {
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		m, err := New("cpu", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(0, 0))
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	return WithGroupTracking(metrics, d.notify)
+}
+
+func TestTrackingAccept(t *testing.T) {
+	d := &deliveries{}
+	metrics, id := newTrackingGroup(t, d, 2)
+
+	metrics[0].Accept()
+	assert.Len(t, d.infos, 0)
+	metrics[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingReject(t *testing.T) {
+	d := &deliveries{}
+	metrics, _ := newTrackingGroup(t, d, 2)
+
+	metrics[0].Reject()
+	metrics[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.False(t, d.infos[0].Delivered())
+}
+
+func TestTrackingDrop(t *testing.T) {
+	d := &deliveries{}
+	metrics, _ := newTrackingGroup(t, d, 1)
+
+	metrics[0].Drop()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingCopy(t *testing.T) {
+	d := &deliveries{}
+	metrics, _ := newTrackingGroup(t, d, 1)
+
+	// the group is delivered once the copies are delivered too
+	m := metrics[0].Copy()
+	m.AddTag("host", "localhost")
+	assert.False(t, metrics[0].HasTag("host"))
+
+	metrics[0].Accept()
+	assert.Len(t, d.infos, 0)
+	m.Accept()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingEmptyGroup(t *testing.T) {
+	d := &deliveries{}
+	metrics, id := newTrackingGroup(t, d, 0)
+
+	assert.Len(t, metrics, 0)
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingIDs(t *testing.T) {
+	d := &deliveries{}
+	_, id1 := newTrackingGroup(t, d, 1)
+	_, id2 := newTrackingGroup(t, d, 1)
+	assert.NotEqual(t, id1, id2)
+}
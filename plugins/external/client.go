@@ -0,0 +1,198 @@
+package external
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/process"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotRunning is returned while the plugin is not running or not
+// configured.
+var ErrNotRunning = errors.New("the plugin is not running")
+
+// handshakeTimeout is how long the client waits for the handshake of the
+// plugin and for its configuration.
+const handshakeTimeout = 10 * time.Second
+
+// Client runs a plugin program and connects to it, the program is started
+// again after the RestartDelay when it exits and configured again.
+type Client struct {
+	Command      []string
+	Environment  []string
+	RestartDelay time.Duration
+	// Kind is the kind of the plugin and Config its TOML configuration.
+	Kind   string
+	Config string
+	Log    telegraf.Logger
+
+	process *process.Process
+	started chan error
+	once    sync.Once
+
+	mu     sync.Mutex
+	plugin PluginClient
+}
+
+// Start starts the program and waits for it to be configured.
+func (c *Client) Start() error {
+	c.started = make(chan error, 1)
+	c.process = &process.Process{
+		Command:      c.Command,
+		Environment:  append([]string{MagicCookieKey + "=" + MagicCookieValue}, c.Environment...),
+		RestartDelay: c.RestartDelay,
+		ReadStdout:   c.readStdout,
+		ReadStderr:   c.readStderr,
+		Log:          c.Log,
+	}
+	if err := c.process.Start(); err != nil {
+		return err
+	}
+
+	var err error
+	select {
+	case err = <-c.started:
+	case <-time.After(handshakeTimeout):
+		err = fmt.Errorf("plugin %s did not complete the handshake within %s", c.Command[0], handshakeTimeout)
+	}
+	if err != nil {
+		c.process.Stop()
+	}
+	return err
+}
+
+// Stop closes the stdin of the program and waits for it to exit.
+func (c *Client) Stop() {
+	c.process.Stop()
+}
+
+// Plugin returns the client of the running plugin.
+func (c *Client) Plugin() (PluginClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.plugin == nil {
+		return nil, ErrNotRunning
+	}
+	return c.plugin, nil
+}
+
+// report reports the result of the first run to Start, the errors of the
+// later runs are logged.
+func (c *Client) report(err error) {
+	reported := false
+	c.once.Do(func() {
+		c.started <- err
+		reported = true
+	})
+	if !reported && err != nil {
+		c.Log.Errorf("%s", err)
+	}
+}
+
+// readStdout connects to the address of the handshake of each run of the
+// program, the lines after the handshake are logged.
+func (c *Client) readStdout(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		c.report(fmt.Errorf("plugin %s exited before the handshake", c.Command[0]))
+		return
+	}
+	conn, err := c.connect(scanner.Text())
+	if err != nil {
+		// the program is started again until it can be configured
+		c.process.Signal(os.Kill)
+		c.report(err)
+		return
+	}
+
+	c.mu.Lock()
+	c.plugin = NewPluginClient(conn)
+	c.mu.Unlock()
+	c.report(nil)
+
+	for scanner.Scan() {
+		c.Log.Debugf("stdout: %s", scanner.Text())
+	}
+
+	c.mu.Lock()
+	c.plugin = nil
+	c.mu.Unlock()
+	conn.Close()
+}
+
+// connect connects to the address of the handshake and configures the
+// plugin.
+func (c *Client) connect(handshake string) (*grpc.ClientConn, error) {
+	parts := strings.Split(handshake, "|")
+	if len(parts) != 4 || parts[0] != handshakePrefix {
+		return nil, fmt.Errorf("invalid handshake of plugin %s: %q", c.Command[0], handshake)
+	}
+	if parts[1] != strconv.Itoa(ProtocolVersion) {
+		return nil, fmt.Errorf("plugin %s uses protocol version %s, telegraf version %d",
+			c.Command[0], parts[1], ProtocolVersion)
+	}
+	network := parts[2]
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("plugin %s listens on the unsupported network %q", c.Command[0], network)
+	}
+
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout(network, addr, timeout)
+	}
+	conn, err := grpc.Dial(parts[3], grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithTimeout(handshakeTimeout), grpc.WithDialer(dialer))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to plugin %s: %s", c.Command[0], err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	_, err = NewPluginClient(conn).Configure(ctx, &ConfigureRequest{Kind: c.Kind, Config: c.Config})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("configuring plugin %s: %s", c.Command[0], ErrorMessage(err))
+	}
+	return conn, nil
+}
+
+// readStderr logs the lines of stderr at the level of their prefix, as
+// errors without a prefix.
+func (c *Client) readStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "D! "):
+			c.Log.Debugf("%s", line[3:])
+		case strings.HasPrefix(line, "I! "):
+			c.Log.Infof("%s", line[3:])
+		case strings.HasPrefix(line, "W! "):
+			c.Log.Warnf("%s", line[3:])
+		case strings.HasPrefix(line, "E! "):
+			c.Log.Errorf("%s", line[3:])
+		default:
+			c.Log.Errorf("%s", line)
+		}
+	}
+}
+
+// ErrorMessage returns the message of the error returned by the plugin
+// without its gRPC code.
+func ErrorMessage(err error) string {
+	if s, ok := status.FromError(err); ok {
+		return s.Message()
+	}
+	return err.Error()
+}
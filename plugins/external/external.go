@@ -0,0 +1,145 @@
+// Package external runs input, processor and output plugins as separate
+// programs that telegraf talks to over gRPC, so that plugins which cannot
+// be compiled into telegraf run isolated from it.
+//
+// The plugin programs call Serve with their plugin. Telegraf starts them
+// with the MagicCookieKey environment variable set to MagicCookieValue, the
+// program listens on a local address and writes the handshake line
+//
+//	telegraf-plugin|<ProtocolVersion>|<network>|<address>
+//
+// to its stdout. Telegraf connects to the address and calls Configure with
+// the TOML configuration of the plugin, then the methods of the Plugin
+// service of plugin.proto for the kind of the plugin. The program must exit
+// when its stdin is closed. The lines it writes to stderr are logged by
+// telegraf, at the level of their "D! ", "I! ", "W! " or "E! " prefix.
+package external
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	// ProtocolVersion is the version of the handshake and of the Plugin
+	// service, telegraf refuses plugins with another version.
+	ProtocolVersion = 1
+
+	MagicCookieKey   = "TELEGRAF_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "b4c1e3a8-telegraf-external-plugin"
+
+	handshakePrefix = "telegraf-plugin"
+)
+
+// The kinds of plugins.
+const (
+	KindInput     = "input"
+	KindProcessor = "processor"
+	KindOutput    = "output"
+)
+
+// FromMetric converts the metric to its message.
+func FromMetric(m telegraf.Metric) *Metric {
+	out := &Metric{
+		Name: m.Name(),
+		Time: m.Time().UnixNano(),
+	}
+	switch m.Type() {
+	case telegraf.Counter:
+		out.Type = ValueType_COUNTER
+	case telegraf.Gauge:
+		out.Type = ValueType_GAUGE
+	case telegraf.Summary:
+		out.Type = ValueType_SUMMARY
+	case telegraf.Histogram:
+		out.Type = ValueType_HISTOGRAM
+	}
+	for _, tag := range m.TagList() {
+		out.Tags = append(out.Tags, &Tag{Key: tag.Key, Value: tag.Value})
+	}
+	for _, field := range m.FieldList() {
+		f := &Field{Key: field.Key}
+		switch v := field.Value.(type) {
+		case float64:
+			f.FloatValue = v
+		case int64:
+			f.Type, f.IntValue = FieldType_INTEGER, v
+		case uint64:
+			f.Type, f.UintValue = FieldType_UNSIGNED, v
+		case string:
+			f.Type, f.StringValue = FieldType_STRING, v
+		case bool:
+			f.Type, f.BoolValue = FieldType_BOOLEAN, v
+		default:
+			continue
+		}
+		out.Fields = append(out.Fields, f)
+	}
+	return out
+}
+
+// ToMetric converts the message to a metric.
+func ToMetric(m *Metric) (telegraf.Metric, error) {
+	tags := make(map[string]string, len(m.Tags))
+	for _, tag := range m.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	fields := make(map[string]interface{}, len(m.Fields))
+	for _, f := range m.Fields {
+		switch f.Type {
+		case FieldType_FLOAT:
+			fields[f.Key] = f.FloatValue
+		case FieldType_INTEGER:
+			fields[f.Key] = f.IntValue
+		case FieldType_UNSIGNED:
+			fields[f.Key] = f.UintValue
+		case FieldType_STRING:
+			fields[f.Key] = f.StringValue
+		case FieldType_BOOLEAN:
+			fields[f.Key] = f.BoolValue
+		default:
+			return nil, fmt.Errorf("field %s of metric %s has the unknown type %d", f.Key, m.Name, f.Type)
+		}
+	}
+	var tp telegraf.ValueType
+	switch m.Type {
+	case ValueType_UNTYPED:
+		tp = telegraf.Untyped
+	case ValueType_COUNTER:
+		tp = telegraf.Counter
+	case ValueType_GAUGE:
+		tp = telegraf.Gauge
+	case ValueType_SUMMARY:
+		tp = telegraf.Summary
+	case ValueType_HISTOGRAM:
+		tp = telegraf.Histogram
+	default:
+		return nil, fmt.Errorf("metric %s has the unknown type %d", m.Name, m.Type)
+	}
+	return metric.New(m.Name, tags, fields, time.Unix(0, m.Time), tp)
+}
+
+// FromMetrics converts the metrics to their messages.
+func FromMetrics(metrics []telegraf.Metric) []*Metric {
+	out := make([]*Metric, 0, len(metrics))
+	for _, m := range metrics {
+		out = append(out, FromMetric(m))
+	}
+	return out
+}
+
+// ToMetrics converts the messages to metrics.
+func ToMetrics(metrics []*Metric) ([]telegraf.Metric, error) {
+	out := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		converted, err := ToMetric(m)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, converted)
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package external
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// The test binary serves the test plugins when it is run by a Client.
+func TestMain(m *testing.M) {
+	if os.Getenv(MagicCookieKey) == MagicCookieValue {
+		Serve(&testPlugin{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testPlugin is an input, a processor and an output.
+type testPlugin struct {
+	Value  int64
+	Prefix string
+}
+
+func (p *testPlugin) SampleConfig() string { return "" }
+func (p *testPlugin) Description() string  { return "" }
+
+func (p *testPlugin) Gather(acc telegraf.Accumulator) error {
+	acc.AddCounter("test", map[string]interface{}{"value": p.Value}, map[string]string{"tag": "a"},
+		time.Unix(0, 42))
+	return errors.New("gather failed")
+}
+
+func (p *testPlugin) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.SetName(p.Prefix + m.Name())
+	}
+	return in
+}
+
+func (p *testPlugin) Connect() error { return nil }
+func (p *testPlugin) Close() error   { return nil }
+
+func (p *testPlugin) Write(metrics []telegraf.Metric) error {
+	return errors.New("write failed")
+}
+
+func newClient(kind, config string) *Client {
+	return &Client{
+		Command:      []string{os.Args[0]},
+		RestartDelay: time.Second,
+		Kind:         kind,
+		Config:       config,
+		Log:          testutil.Logger{Name: "external"},
+	}
+}
+
+func TestClient(t *testing.T) {
+	c := newClient(KindInput, "value = 7\nprefix = \"p_\"")
+	require.NoError(t, c.Start())
+	defer c.Stop()
+	plugin, err := c.Plugin()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	resp, err := plugin.Gather(ctx, &Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gather failed"}, resp.Errors)
+	metrics, err := ToMetrics(resp.Metrics)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "test", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{"value": int64(7)}, metrics[0].Fields())
+	assert.Equal(t, map[string]string{"tag": "a"}, metrics[0].Tags())
+	assert.Equal(t, time.Unix(0, 42), metrics[0].Time())
+	assert.Equal(t, telegraf.Counter, metrics[0].Type())
+
+	applied, err := plugin.Apply(ctx, &Metrics{Metrics: resp.Metrics})
+	require.NoError(t, err)
+	require.Len(t, applied.Metrics, 1)
+	assert.Equal(t, "p_test", applied.Metrics[0].Name)
+
+	_, err = plugin.Write(ctx, &Metrics{Metrics: resp.Metrics})
+	assert.Equal(t, "write failed", ErrorMessage(err))
+}
+
+func TestClientConfigureError(t *testing.T) {
+	c := newClient(KindInput, "value = \"seven\"")
+	err := c.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "configuring plugin "+os.Args[0]+": parsing the configuration:")
+
+	c = newClient("aggregator", "")
+	assert.EqualError(t, c.Start(), "configuring plugin "+os.Args[0]+": the plugin is not an aggregator")
+}
+
+func TestConvertMetric(t *testing.T) {
+	m, err := metric.New("cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{
+			"float":    1.5,
+			"int":      int64(-2),
+			"uint":     uint64(3),
+			"string":   "four",
+			"bool":     true,
+			"zero":     0.0,
+			"zero_int": int64(0),
+		},
+		time.Unix(1, 2),
+		telegraf.Gauge,
+	)
+	require.NoError(t, err)
+
+	converted, err := ToMetric(FromMetric(m))
+	require.NoError(t, err)
+	assert.Equal(t, m.Name(), converted.Name())
+	assert.Equal(t, m.Tags(), converted.Tags())
+	assert.Equal(t, m.Fields(), converted.Fields())
+	assert.Equal(t, m.Time(), converted.Time())
+	assert.Equal(t, m.Type(), converted.Type())
+
+	_, err = ToMetric(&Metric{Name: "cpu", Fields: []*Field{{Key: "x", Type: 9}}})
+	assert.EqualError(t, err, "field x of metric cpu has the unknown type 9")
+}
@@ -0,0 +1,263 @@
+package external
+
+import (
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// The message types and the gRPC client and server of the Plugin service,
+// they are wire compatible with plugin.proto.
+
+type FieldType int32
+
+const (
+	FieldType_FLOAT    FieldType = 0
+	FieldType_INTEGER  FieldType = 1
+	FieldType_UNSIGNED FieldType = 2
+	FieldType_STRING   FieldType = 3
+	FieldType_BOOLEAN  FieldType = 4
+)
+
+type ValueType int32
+
+const (
+	ValueType_UNTYPED   ValueType = 0
+	ValueType_COUNTER   ValueType = 1
+	ValueType_GAUGE     ValueType = 2
+	ValueType_SUMMARY   ValueType = 3
+	ValueType_HISTOGRAM ValueType = 4
+)
+
+type Tag struct {
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *Tag) Reset()         { *m = Tag{} }
+func (m *Tag) String() string { return proto.CompactTextString(m) }
+func (*Tag) ProtoMessage()    {}
+
+// Field has the value of its type set.
+type Field struct {
+	Key         string    `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Type        FieldType `protobuf:"varint,2,opt,name=type,proto3,enum=telegraf.external.FieldType" json:"type,omitempty"`
+	FloatValue  float64   `protobuf:"fixed64,3,opt,name=float_value,json=floatValue,proto3" json:"float_value,omitempty"`
+	IntValue    int64     `protobuf:"varint,4,opt,name=int_value,json=intValue,proto3" json:"int_value,omitempty"`
+	UintValue   uint64    `protobuf:"varint,5,opt,name=uint_value,json=uintValue,proto3" json:"uint_value,omitempty"`
+	StringValue string    `protobuf:"bytes,6,opt,name=string_value,json=stringValue,proto3" json:"string_value,omitempty"`
+	BoolValue   bool      `protobuf:"varint,7,opt,name=bool_value,json=boolValue,proto3" json:"bool_value,omitempty"`
+}
+
+func (m *Field) Reset()         { *m = Field{} }
+func (m *Field) String() string { return proto.CompactTextString(m) }
+func (*Field) ProtoMessage()    {}
+
+type Metric struct {
+	Name   string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Tags   []*Tag   `protobuf:"bytes,2,rep,name=tags" json:"tags,omitempty"`
+	Fields []*Field `protobuf:"bytes,3,rep,name=fields" json:"fields,omitempty"`
+	// nanoseconds since the epoch
+	Time int64     `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
+	Type ValueType `protobuf:"varint,5,opt,name=type,proto3,enum=telegraf.external.ValueType" json:"type,omitempty"`
+}
+
+func (m *Metric) Reset()         { *m = Metric{} }
+func (m *Metric) String() string { return proto.CompactTextString(m) }
+func (*Metric) ProtoMessage()    {}
+
+type Empty struct{}
+
+func (m *Empty) Reset()         { *m = Empty{} }
+func (m *Empty) String() string { return proto.CompactTextString(m) }
+func (*Empty) ProtoMessage()    {}
+
+// ConfigureRequest has the kind of the plugin, "input", "processor" or
+// "output", and its TOML configuration.
+type ConfigureRequest struct {
+	Kind   string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Config string `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+}
+
+func (m *ConfigureRequest) Reset()         { *m = ConfigureRequest{} }
+func (m *ConfigureRequest) String() string { return proto.CompactTextString(m) }
+func (*ConfigureRequest) ProtoMessage()    {}
+
+type GatherResponse struct {
+	Metrics []*Metric `protobuf:"bytes,1,rep,name=metrics" json:"metrics,omitempty"`
+	Errors  []string  `protobuf:"bytes,2,rep,name=errors" json:"errors,omitempty"`
+}
+
+func (m *GatherResponse) Reset()         { *m = GatherResponse{} }
+func (m *GatherResponse) String() string { return proto.CompactTextString(m) }
+func (*GatherResponse) ProtoMessage()    {}
+
+type Metrics struct {
+	Metrics []*Metric `protobuf:"bytes,1,rep,name=metrics" json:"metrics,omitempty"`
+}
+
+func (m *Metrics) Reset()         { *m = Metrics{} }
+func (m *Metrics) String() string { return proto.CompactTextString(m) }
+func (*Metrics) ProtoMessage()    {}
+
+// PluginClient is the client API for the Plugin service.
+type PluginClient interface {
+	Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*Empty, error)
+	Gather(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GatherResponse, error)
+	Apply(ctx context.Context, in *Metrics, opts ...grpc.CallOption) (*Metrics, error)
+	Connect(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	Write(ctx context.Context, in *Metrics, opts ...grpc.CallOption) (*Empty, error)
+	Close(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type pluginClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewPluginClient(cc *grpc.ClientConn) PluginClient {
+	return &pluginClient{cc}
+}
+
+func (c *pluginClient) Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/telegraf.external.Plugin/Configure", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Gather(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GatherResponse, error) {
+	out := new(GatherResponse)
+	err := grpc.Invoke(ctx, "/telegraf.external.Plugin/Gather", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Apply(ctx context.Context, in *Metrics, opts ...grpc.CallOption) (*Metrics, error) {
+	out := new(Metrics)
+	err := grpc.Invoke(ctx, "/telegraf.external.Plugin/Apply", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Connect(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/telegraf.external.Plugin/Connect", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Write(ctx context.Context, in *Metrics, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/telegraf.external.Plugin/Write", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Close(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/telegraf.external.Plugin/Close", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PluginServer is the server API for the Plugin service.
+type PluginServer interface {
+	Configure(context.Context, *ConfigureRequest) (*Empty, error)
+	Gather(context.Context, *Empty) (*GatherResponse, error)
+	Apply(context.Context, *Metrics) (*Metrics, error)
+	Connect(context.Context, *Empty) (*Empty, error)
+	Write(context.Context, *Metrics) (*Empty, error)
+	Close(context.Context, *Empty) (*Empty, error)
+}
+
+func RegisterPluginServer(s *grpc.Server, srv PluginServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+// unaryHandler returns the handler of a method, call calls the method of the
+// server with the decoded request.
+func unaryHandler(method string, newRequest func() interface{}, call func(PluginServer, context.Context, interface{}) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := newRequest()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(PluginServer), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/telegraf.external.Plugin/" + method,
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(PluginServer), ctx, req)
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+func newEmpty() interface{}   { return new(Empty) }
+func newMetrics() interface{} { return new(Metrics) }
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "telegraf.external.Plugin",
+	HandlerType: (*PluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Configure",
+			Handler: unaryHandler("Configure", func() interface{} { return new(ConfigureRequest) },
+				func(s PluginServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return s.Configure(ctx, in.(*ConfigureRequest))
+				}),
+		},
+		{
+			MethodName: "Gather",
+			Handler: unaryHandler("Gather", newEmpty,
+				func(s PluginServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return s.Gather(ctx, in.(*Empty))
+				}),
+		},
+		{
+			MethodName: "Apply",
+			Handler: unaryHandler("Apply", newMetrics,
+				func(s PluginServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return s.Apply(ctx, in.(*Metrics))
+				}),
+		},
+		{
+			MethodName: "Connect",
+			Handler: unaryHandler("Connect", newEmpty,
+				func(s PluginServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return s.Connect(ctx, in.(*Empty))
+				}),
+		},
+		{
+			MethodName: "Write",
+			Handler: unaryHandler("Write", newMetrics,
+				func(s PluginServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return s.Write(ctx, in.(*Metrics))
+				}),
+		},
+		{
+			MethodName: "Close",
+			Handler: unaryHandler("Close", newEmpty,
+				func(s PluginServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return s.Close(ctx, in.(*Empty))
+				}),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugin.proto",
+}
@@ -0,0 +1,301 @@
+package external
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/toml"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Serve serves the plugin, a telegraf.Input, telegraf.ServiceInput,
+// telegraf.Processor or telegraf.Output, until the stdin of the program is
+// closed. It is called by the main function of the plugin programs, the
+// program exits if it is not run by telegraf.
+func Serve(plugin interface{}) {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		fmt.Fprintln(os.Stderr, "This program is a telegraf plugin, it is run by the external plugins of telegraf.")
+		os.Exit(1)
+	}
+	if err := serve(plugin, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "E! %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func serve(plugin interface{}, stdin io.Reader, stdout io.Writer) error {
+	if p, ok := plugin.(telegraf.LoggingPlugin); ok {
+		p.SetLogger(stderrLogger{})
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer()
+	srv := &server{plugin: plugin, acc: &accumulator{}}
+	RegisterPluginServer(s, srv)
+	if _, err := fmt.Fprintf(stdout, "%s|%d|tcp|%s\n", handshakePrefix, ProtocolVersion, l.Addr()); err != nil {
+		l.Close()
+		return err
+	}
+
+	go func() {
+		io.Copy(ioutil.Discard, stdin)
+		s.GracefulStop()
+	}()
+	err = s.Serve(l)
+	srv.stop()
+	return err
+}
+
+// server serves the Plugin service for the plugin.
+type server struct {
+	plugin interface{}
+	// acc accumulates the metrics of an input until the next Gather
+	acc *accumulator
+
+	mu      sync.Mutex
+	service telegraf.ServiceInput
+}
+
+func (s *server) Configure(ctx context.Context, req *ConfigureRequest) (*Empty, error) {
+	var ok bool
+	switch req.Kind {
+	case KindInput:
+		_, ok = s.plugin.(telegraf.Input)
+	case KindProcessor:
+		_, ok = s.plugin.(telegraf.Processor)
+	case KindOutput:
+		_, ok = s.plugin.(telegraf.Output)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "the plugin is not an %s", req.Kind)
+	}
+	if err := toml.Unmarshal([]byte(req.Config), s.plugin); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "parsing the configuration: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.plugin.(telegraf.ServiceInput); ok && req.Kind == KindInput && s.service == nil {
+		if err := p.Start(s.acc); err != nil {
+			return nil, err
+		}
+		s.service = p
+	}
+	return &Empty{}, nil
+}
+
+func (s *server) Gather(ctx context.Context, req *Empty) (*GatherResponse, error) {
+	input, ok := s.plugin.(telegraf.Input)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the plugin is not an input")
+	}
+	if err := input.Gather(s.acc); err != nil {
+		s.acc.AddError(err)
+	}
+	metrics, errs := s.acc.take()
+	return &GatherResponse{Metrics: FromMetrics(metrics), Errors: errs}, nil
+}
+
+func (s *server) Apply(ctx context.Context, req *Metrics) (*Metrics, error) {
+	processor, ok := s.plugin.(telegraf.Processor)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the plugin is not a processor")
+	}
+	in, err := ToMetrics(req.Metrics)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &Metrics{Metrics: FromMetrics(processor.Apply(in...))}, nil
+}
+
+func (s *server) Connect(ctx context.Context, req *Empty) (*Empty, error) {
+	output, ok := s.plugin.(telegraf.Output)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the plugin is not an output")
+	}
+	if err := output.Connect(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *server) Write(ctx context.Context, req *Metrics) (*Empty, error) {
+	output, ok := s.plugin.(telegraf.Output)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the plugin is not an output")
+	}
+	metrics, err := ToMetrics(req.Metrics)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := output.Write(metrics); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *server) Close(ctx context.Context, req *Empty) (*Empty, error) {
+	output, ok := s.plugin.(telegraf.Output)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the plugin is not an output")
+	}
+	if err := output.Close(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+// stop stops the service of a service input.
+func (s *server) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.service != nil {
+		s.service.Stop()
+		s.service = nil
+	}
+}
+
+// accumulator keeps the metrics and errors of an input until they are
+// taken by Gather, the precision is set by telegraf.
+type accumulator struct {
+	mu      sync.Mutex
+	metrics []telegraf.Metric
+	errors  []string
+}
+
+func (a *accumulator) add(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	tp telegraf.ValueType,
+	t []time.Time,
+) {
+	tm := time.Now()
+	if len(t) > 0 {
+		tm = t[0]
+	}
+	m, err := metric.New(measurement, tags, fields, tm, tp)
+	if err != nil {
+		a.AddError(err)
+		return
+	}
+	a.AddMetrics([]telegraf.Metric{m})
+}
+
+func (a *accumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Untyped, t)
+}
+
+func (a *accumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Gauge, t)
+}
+
+func (a *accumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Counter, t)
+}
+
+func (a *accumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Summary, t)
+}
+
+func (a *accumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Histogram, t)
+}
+
+func (a *accumulator) AddMetrics(metrics []telegraf.Metric) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.metrics = append(a.metrics, metrics...)
+}
+
+func (a *accumulator) SetPrecision(precision, interval time.Duration) {
+}
+
+func (a *accumulator) AddError(err error) {
+	if err == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.errors = append(a.errors, err.Error())
+}
+
+// WithTracking returns an accumulator reporting the metrics as delivered
+// once they are added, their delivery is not tracked by telegraf.
+func (a *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+func (a *accumulator) take() ([]telegraf.Metric, []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	metrics, errs := a.metrics, a.errors
+	a.metrics, a.errors = nil, nil
+	return metrics, errs
+}
+
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+	id        uint64
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	a.AddMetrics(group)
+	id := telegraf.TrackingID(atomic.AddUint64(&a.id, 1))
+	go func() {
+		a.delivered <- deliveryInfo(id)
+	}()
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+type deliveryInfo telegraf.TrackingID
+
+func (d deliveryInfo) ID() telegraf.TrackingID {
+	return telegraf.TrackingID(d)
+}
+
+func (d deliveryInfo) Delivered() bool {
+	return true
+}
+
+// stderrLogger writes the messages to stderr with the prefix of their level,
+// telegraf logs them at that level.
+type stderrLogger struct{}
+
+func (stderrLogger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "E! %s\n", fmt.Sprintf(format, args...))
+}
+
+func (stderrLogger) Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "W! %s\n", fmt.Sprintf(format, args...))
+}
+
+func (stderrLogger) Infof(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "I! %s\n", fmt.Sprintf(format, args...))
+}
+
+func (stderrLogger) Debugf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "D! %s\n", fmt.Sprintf(format, args...))
+}
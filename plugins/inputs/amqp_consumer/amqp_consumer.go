@@ -29,6 +29,10 @@ type AMQPConsumer struct {
 	// for consumers before receiving delivery acks.
 	PrefetchCount int
 
+	// Maximum number of messages whose metrics are not written yet, they are
+	// acknowledged once the outputs wrote the metrics.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// AMQP Auth method
 	AuthMethod string
 	// Path to CA file
@@ -57,6 +61,8 @@ func (a *externalAuth) Response() string {
 const (
 	DefaultAuthMethod    = "PLAIN"
 	DefaultPrefetchCount = 50
+
+	DefaultMaxUndeliveredMessages = 1000
 )
 
 func (a *AMQPConsumer) SampleConfig() string {
@@ -73,6 +79,11 @@ func (a *AMQPConsumer) SampleConfig() string {
   ## Maximum number of messages server should give to the worker.
   prefetch_count = 50
 
+  ## Maximum number of messages read before their metrics are written by the
+  ## outputs, a message is acknowledged once its metrics are written. The
+  ## server doesn't send more than prefetch_count unacknowledged messages.
+  # max_undelivered_messages = 1000
+
   ## Auth method. PLAIN and EXTERNAL are supported
   ## Using EXTERNAL requires enabling the rabbitmq_auth_mechanism_ssl plugin as
   ## described here: https://www.rabbitmq.com/plugins.html
@@ -244,22 +255,49 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 	return msgs, err
 }
 
-// Read messages from queue and add them to the Accumulator
-func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.Accumulator) {
+// Read messages from queue and add them to the Accumulator, a message is
+// acknowledged once its metrics are delivered and rejected if they are not.
+// The server delivers the unacknowledged messages again if the connection is
+// closed.
+func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, ac telegraf.Accumulator) {
 	defer a.wg.Done()
-	for d := range msgs {
-		metrics, err := a.parser.Parse(d.Body)
-		if err != nil {
-			log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
-		} else {
-			for _, m := range metrics {
-				acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
-			}
+	acc := ac.WithTracking(a.MaxUndeliveredMessages)
+	undelivered := make(map[telegraf.TrackingID]amqp.Delivery)
+	for {
+		in := msgs
+		if len(undelivered) >= a.MaxUndeliveredMessages {
+			in = nil
 		}
 
-		d.Ack(false)
+		select {
+		case info := <-acc.Delivered():
+			d, ok := undelivered[info.ID()]
+			if !ok {
+				continue
+			}
+			delete(undelivered, info.ID())
+			if info.Delivered() {
+				d.Ack(false)
+			} else {
+				d.Reject(false)
+			}
+		case d, ok := <-in:
+			if !ok {
+				log.Printf("I! AMQP consumer queue closed")
+				return
+			}
+
+			metrics, err := a.parser.Parse(d.Body)
+			if err != nil {
+				log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
+			}
+			if len(metrics) == 0 {
+				d.Ack(false)
+				continue
+			}
+			undelivered[acc.AddTrackingMetricGroup(metrics)] = d
+		}
 	}
-	log.Printf("I! AMQP consumer queue closed")
 }
 
 func (a *AMQPConsumer) Stop() {
@@ -275,8 +313,9 @@ func (a *AMQPConsumer) Stop() {
 func init() {
 	inputs.Add("amqp_consumer", func() telegraf.Input {
 		return &AMQPConsumer{
-			AuthMethod:    DefaultAuthMethod,
-			PrefetchCount: DefaultPrefetchCount,
+			AuthMethod:             DefaultAuthMethod,
+			PrefetchCount:          DefaultPrefetchCount,
+			MaxUndeliveredMessages: DefaultMaxUndeliveredMessages,
 		}
 	})
 }
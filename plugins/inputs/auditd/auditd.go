@@ -0,0 +1,296 @@
+// +build linux
+
+package auditd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// source reads the audit records.
+type source interface {
+	// ReadRecord returns the type and the message of the next record.
+	ReadRecord() (string, string, error)
+	Close() error
+}
+
+var (
+	// errTimeout is returned by ReadRecord when no record was received
+	// within the read timeout, it allows the reader to check if the plugin
+	// is stopping.
+	errTimeout = fmt.Errorf("read timeout")
+
+	// errLost is returned by ReadRecord when records were dropped because
+	// the socket buffer was full.
+	errLost = fmt.Errorf("records lost")
+)
+
+// retryDelay is the delay before the source is opened again after an error.
+var retryDelay = 5 * time.Second
+
+// unsetID is the audit uid of the processes not started by a login.
+const unsetID = "4294967295"
+
+type syscallKey struct {
+	syscall string
+	key     string
+	auid    string
+	success string
+}
+
+type Auditd struct {
+	Source string   `toml:"source"`
+	Events []string `toml:"events"`
+
+	open   func(address string) (source, error)
+	events filter.Filter
+
+	acc  telegraf.Accumulator
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	sync.Mutex
+	records  map[string]int64
+	syscalls map[syscallKey]int64
+	lost     int64
+}
+
+var sampleConfig = `
+  ## Source of the audit records, either "netlink" to receive a copy of the
+  ## records from the kernel, this requires the CAP_AUDIT_READ capability
+  ## and doesn't interfere with auditd, or the address of the af_unix plugin
+  ## of audisp, like "unix:///var/run/audispd_events".
+  # source = "netlink"
+
+  ## Keys of the audit rules whose syscalls are reported as auditd_event
+  ## metrics, globs are supported. By default the syscalls are only counted.
+  # events = ["passwd_changes", "exec_*"]
+`
+
+func (a *Auditd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Auditd) Description() string {
+	return "Count and report the records of the Linux audit subsystem"
+}
+
+// Gather reports the records counted since the plugin started.
+func (a *Auditd) Gather(acc telegraf.Accumulator) error {
+	a.Lock()
+	defer a.Unlock()
+
+	var total int64
+	for typ, count := range a.records {
+		total += count
+		acc.AddFields("auditd_records",
+			map[string]interface{}{"count": count},
+			map[string]string{"type": typ})
+	}
+	for k, count := range a.syscalls {
+		tags := map[string]string{
+			"syscall": k.syscall,
+			"auid":    k.auid,
+			"success": k.success,
+		}
+		if k.key != "" {
+			tags["key"] = k.key
+		}
+		acc.AddFields("auditd_syscalls", map[string]interface{}{"count": count}, tags)
+	}
+	acc.AddFields("auditd",
+		map[string]interface{}{
+			"records": total,
+			"lost":    a.lost,
+		},
+		map[string]string{})
+	return nil
+}
+
+func (a *Auditd) Start(acc telegraf.Accumulator) error {
+	var err error
+	a.events, err = filter.Compile(a.Events)
+	if err != nil {
+		return fmt.Errorf("compiling events: %s", err)
+	}
+	if a.Source == "" {
+		a.Source = "netlink"
+	}
+	if a.open == nil {
+		a.open = openSource
+	}
+
+	// open the source now to report invalid addresses and missing
+	// capabilities when telegraf starts
+	src, err := a.open(a.Source)
+	if err != nil {
+		return fmt.Errorf("opening %s: %s", a.Source, err)
+	}
+
+	a.acc = acc
+	a.records = make(map[string]int64)
+	a.syscalls = make(map[syscallKey]int64)
+	a.done = make(chan struct{})
+	a.wg.Add(1)
+	go a.run(src)
+
+	log.Printf("I! Started the auditd service on %s", a.Source)
+	return nil
+}
+
+func openSource(address string) (source, error) {
+	if address == "netlink" {
+		return openNetlink()
+	}
+	if strings.HasPrefix(address, "unix://") {
+		return openUnix(strings.TrimPrefix(address, "unix://"))
+	}
+	return nil, fmt.Errorf("unsupported source, expected \"netlink\" or a unix:// address")
+}
+
+func (a *Auditd) Stop() {
+	close(a.done)
+	// the reader returns within the read timeout of the source
+	a.wg.Wait()
+}
+
+// run reads the records, the source is opened again after an error.
+func (a *Auditd) run(src source) {
+	defer a.wg.Done()
+	for {
+		err := a.read(src)
+		src.Close()
+		if err == nil {
+			return
+		}
+		a.acc.AddError(fmt.Errorf("reading %s: %s", a.Source, err))
+
+		for err != nil {
+			select {
+			case <-a.done:
+				return
+			case <-time.After(retryDelay):
+			}
+			src, err = a.open(a.Source)
+			if err != nil {
+				a.acc.AddError(fmt.Errorf("opening %s: %s", a.Source, err))
+			}
+		}
+	}
+}
+
+// read returns nil when the plugin is stopped.
+func (a *Auditd) read(src source) error {
+	for {
+		select {
+		case <-a.done:
+			return nil
+		default:
+		}
+
+		typ, msg, err := src.ReadRecord()
+		switch err {
+		case nil:
+			a.handle(typ, msg)
+		case errTimeout:
+		case errLost:
+			a.Lock()
+			a.lost++
+			a.Unlock()
+		default:
+			return err
+		}
+	}
+}
+
+func (a *Auditd) handle(typ, msg string) {
+	// the end of the records of an event
+	if typ == "EOE" {
+		return
+	}
+
+	a.Lock()
+	a.records[typ]++
+	a.Unlock()
+	if typ != "SYSCALL" {
+		return
+	}
+
+	r, err := parseRecord(typ, msg)
+	if err != nil {
+		a.acc.AddError(fmt.Errorf("E! Malformed audit record [%s], Error: %s", msg, err))
+		return
+	}
+
+	// with the enriched format of auditd the syscalls have their name
+	syscall := r.fields["SYSCALL"]
+	if syscall == "" {
+		syscall = r.fields["syscall"]
+	}
+	auid := r.fields["auid"]
+	if auid == unsetID {
+		auid = "unset"
+	}
+	ruleKeys := keys(r)
+	k := syscallKey{
+		syscall: syscall,
+		key:     strings.Join(ruleKeys, ","),
+		auid:    auid,
+		success: r.fields["success"],
+	}
+	a.Lock()
+	a.syscalls[k]++
+	a.Unlock()
+
+	if a.events == nil {
+		return
+	}
+	for _, key := range ruleKeys {
+		if a.events.Match(key) {
+			a.addEvent(r, k)
+			return
+		}
+	}
+}
+
+// addEvent reports the syscall record of an event.
+func (a *Auditd) addEvent(r *record, k syscallKey) {
+	tags := map[string]string{
+		"syscall": k.syscall,
+		"key":     k.key,
+		"auid":    k.auid,
+		"success": k.success,
+	}
+	if uid, ok := r.fields["uid"]; ok {
+		tags["uid"] = uid
+	}
+
+	fields := map[string]interface{}{
+		"serial": int64(r.serial),
+	}
+	for _, name := range []string{"pid", "ppid", "exit"} {
+		if v, err := strconv.ParseInt(r.fields[name], 10, 64); err == nil {
+			fields[name] = v
+		}
+	}
+	for _, name := range []string{"comm", "exe", "tty"} {
+		if v, ok := r.fields[name]; ok {
+			fields[name] = v
+		}
+	}
+	a.acc.AddFields("auditd_event", fields, tags, r.time)
+}
+
+func init() {
+	inputs.Add("auditd", func() telegraf.Input {
+		return &Auditd{Source: "netlink"}
+	})
+}
@@ -0,0 +1,246 @@
+// +build linux
+
+package auditd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var auditLog = []string{
+	`type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 a0=7fffd19c5592 a1=0 a2=7fffd19c4b50 a3=a items=1 ppid=2686 pid=3538 auid=1000 uid=1000 gid=1000 euid=1000 suid=1000 fsuid=1000 egid=1000 sgid=1000 fsgid=1000 tty=pts0 ses=1 comm="cat" exe="/bin/cat" key="sshd_config"`,
+	`type=CWD msg=audit(1364481363.243:24287):  cwd="/home/shadowman"`,
+	`type=PATH msg=audit(1364481363.243:24287): item=0 name="/etc/ssh/sshd_config" inode=409248 dev=fd:00 mode=0100600 ouid=0 ogid=0 rdev=00:00 nametype=NORMAL`,
+	`type=EOE msg=audit(1364481363.243:24287): `,
+	`node=server type=SYSCALL msg=audit(1364481364.5:24288): arch=c000003e syscall=59 success=yes exit=0 ppid=1 pid=3540 auid=4294967295 uid=0 tty=(none) comm=2F746D702F612062 exe="/usr/bin/ls" key=(null)` + "\x1d" + `ARCH=x86_64 SYSCALL=execve AUID="unset" UID="root"`,
+	`type=USER_LOGIN msg=audit(1364481365.000:24289): pid=3541 uid=0 auid=1000 ses=2 msg='op=login id=1000 exe="/usr/sbin/sshd" hostname=? addr=10.0.0.1 terminal=/dev/pts/1 res=success'`,
+}
+
+func TestParseRecord(t *testing.T) {
+	typ, msg, err := parseLine(auditLog[0])
+	require.NoError(t, err)
+	assert.Equal(t, "SYSCALL", typ)
+
+	r, err := parseRecord(typ, msg)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1364481363, 243000000), r.time)
+	assert.Equal(t, uint64(24287), r.serial)
+	assert.Equal(t, "2", r.fields["syscall"])
+	assert.Equal(t, "-13", r.fields["exit"])
+	assert.Equal(t, "/bin/cat", r.fields["exe"])
+	assert.Equal(t, []string{"sshd_config"}, keys(r))
+
+	// node name, hex encoded value and enriched fields
+	typ, msg, err = parseLine(auditLog[4])
+	require.NoError(t, err)
+	r, err = parseRecord(typ, msg)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1364481364, 5000000), r.time)
+	assert.Equal(t, "/tmp/a b", r.fields["comm"])
+	assert.Equal(t, "execve", r.fields["SYSCALL"])
+	assert.Equal(t, "root", r.fields["UID"])
+	assert.Nil(t, keys(r))
+
+	// fields of user space messages
+	typ, msg, err = parseLine(auditLog[5])
+	require.NoError(t, err)
+	assert.Equal(t, "USER_LOGIN", typ)
+	r, err = parseRecord(typ, msg)
+	require.NoError(t, err)
+	assert.Equal(t, "login", r.fields["op"])
+	assert.Equal(t, "/usr/sbin/sshd", r.fields["exe"])
+	assert.Equal(t, "success", r.fields["res"])
+	assert.Equal(t, "3541", r.fields["pid"])
+
+	// multiple keys
+	r, err = parseRecord("SYSCALL", `audit(1364481363.243:1): syscall=2 key=6130017762`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a0", "wb"}, keys(r))
+
+	_, err = parseRecord("SYSCALL", "arch=c000003e syscall=2")
+	assert.Error(t, err)
+	_, err = parseRecord("SYSCALL", "audit(1364481363.243): syscall=2")
+	assert.Error(t, err)
+	_, _, err = parseLine("msg=audit(1364481363.243:1):")
+	assert.Error(t, err)
+}
+
+func TestRecordType(t *testing.T) {
+	assert.Equal(t, "SYSCALL", recordType(1300))
+	assert.Equal(t, "UNKNOWN[1999]", recordType(1999))
+}
+
+// fakeSource returns the records of the audit log and then times out.
+type fakeSource struct {
+	lines []string
+	err   error
+}
+
+func (s *fakeSource) ReadRecord() (string, string, error) {
+	if len(s.lines) == 0 {
+		if s.err != nil {
+			err := s.err
+			s.err = nil
+			return "", "", err
+		}
+		time.Sleep(time.Millisecond)
+		return "", "", errTimeout
+	}
+	line := s.lines[0]
+	s.lines = s.lines[1:]
+	if line == "" {
+		return "", "", errLost
+	}
+	return parseLine(line)
+}
+
+func (s *fakeSource) Close() error {
+	return nil
+}
+
+func TestAuditd(t *testing.T) {
+	src := &fakeSource{lines: append(append([]string{}, auditLog...), "")}
+	a := &Auditd{
+		Source: "netlink",
+		Events: []string{"sshd_*"},
+		open: func(address string) (source, error) {
+			return src, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Start(&acc))
+	acc.Wait(1)
+	a.Stop()
+
+	event := acc.Metrics[0]
+	assert.Equal(t, "auditd_event", event.Measurement)
+	assert.Equal(t, time.Unix(1364481363, 243000000), event.Time)
+	assert.Equal(t, map[string]string{
+		"syscall": "2",
+		"key":     "sshd_config",
+		"auid":    "1000",
+		"uid":     "1000",
+		"success": "no",
+	}, event.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"serial": int64(24287),
+		"pid":    int64(3538),
+		"ppid":   int64(2686),
+		"exit":   int64(-13),
+		"comm":   "cat",
+		"exe":    "/bin/cat",
+		"tty":    "pts0",
+	}, event.Fields)
+
+	acc.ClearMetrics()
+	require.NoError(t, a.Gather(&acc))
+	acc.AssertContainsFields(t, "auditd", map[string]interface{}{
+		"records": int64(5),
+		"lost":    int64(1),
+	})
+	for typ, count := range map[string]int64{"SYSCALL": 2, "CWD": 1, "PATH": 1, "USER_LOGIN": 1} {
+		acc.AssertContainsTaggedFields(t, "auditd_records",
+			map[string]interface{}{"count": count},
+			map[string]string{"type": typ})
+	}
+	acc.AssertContainsTaggedFields(t, "auditd_syscalls",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"syscall": "2", "key": "sshd_config", "auid": "1000", "success": "no"})
+	acc.AssertContainsTaggedFields(t, "auditd_syscalls",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"syscall": "execve", "auid": "unset", "success": "yes"})
+	assert.False(t, acc.HasMeasurement("auditd_event"))
+}
+
+func TestAuditdReopen(t *testing.T) {
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = 5 * time.Second }()
+
+	sources := []*fakeSource{
+		{lines: auditLog[:1], err: io.EOF},
+		{lines: auditLog[4:5]},
+	}
+	opened := 0
+	a := &Auditd{
+		Source: "unix:///var/run/audispd_events",
+		open: func(address string) (source, error) {
+			opened++
+			if opened == 2 {
+				return nil, fmt.Errorf("connection refused")
+			}
+			return sources[opened/2], nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Start(&acc))
+	acc.WaitError(2)
+	for {
+		a.Lock()
+		n := a.records["SYSCALL"]
+		a.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	a.Stop()
+
+	assert.Contains(t, acc.Errors[0].Error(), "EOF")
+	assert.Contains(t, acc.Errors[1].Error(), "connection refused")
+}
+
+func TestUnixSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audispd_events")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintln(conn, auditLog[0])
+		// a record split between writes
+		fmt.Fprint(conn, auditLog[1][:20])
+		time.Sleep(readTimeout + 100*time.Millisecond)
+		fmt.Fprintln(conn, auditLog[1][20:])
+	}()
+
+	src, err := openSource("unix://" + path)
+	require.NoError(t, err)
+	defer src.Close()
+
+	typ, _, err := src.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, "SYSCALL", typ)
+
+	_, _, err = src.ReadRecord()
+	assert.Equal(t, errTimeout, err)
+	typ, msg, err := src.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, "CWD", typ)
+	assert.Equal(t, `audit(1364481363.243:24287):  cwd="/home/shadowman"`, msg)
+
+	_, _, err = src.ReadRecord()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = openSource("file:///var/log/audit/audit.log")
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+// +build linux
+
+package auditd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordTypes are the names of the audit record types, see linux/audit.h.
+var recordTypes = map[uint16]string{
+	1006: "LOGIN",
+	1100: "USER_AUTH",
+	1101: "USER_ACCT",
+	1102: "USER_MGMT",
+	1103: "CRED_ACQ",
+	1104: "CRED_DISP",
+	1105: "USER_START",
+	1106: "USER_END",
+	1107: "USER_AVC",
+	1108: "USER_CHAUTHTOK",
+	1109: "USER_ERR",
+	1110: "CRED_REFR",
+	1111: "USYS_CONFIG",
+	1112: "USER_LOGIN",
+	1113: "USER_LOGOUT",
+	1114: "ADD_USER",
+	1115: "DEL_USER",
+	1116: "ADD_GROUP",
+	1117: "DEL_GROUP",
+	1118: "DAC_CHECK",
+	1119: "CHGRP_ID",
+	1120: "TEST",
+	1121: "TRUSTED_APP",
+	1122: "USER_SELINUX_ERR",
+	1123: "USER_CMD",
+	1124: "USER_TTY",
+	1125: "CHUSER_ID",
+	1126: "GRP_AUTH",
+	1127: "SYSTEM_BOOT",
+	1128: "SYSTEM_SHUTDOWN",
+	1129: "SYSTEM_RUNLEVEL",
+	1130: "SERVICE_START",
+	1131: "SERVICE_STOP",
+	1300: "SYSCALL",
+	1302: "PATH",
+	1303: "IPC",
+	1304: "SOCKETCALL",
+	1305: "CONFIG_CHANGE",
+	1306: "SOCKADDR",
+	1307: "CWD",
+	1309: "EXECVE",
+	1311: "IPC_SET_PERM",
+	1312: "MQ_OPEN",
+	1313: "MQ_SENDRECV",
+	1314: "MQ_NOTIFY",
+	1315: "MQ_GETSETATTR",
+	1316: "KERNEL_OTHER",
+	1317: "FD_PAIR",
+	1318: "OBJ_PID",
+	1319: "TTY",
+	1320: "EOE",
+	1321: "BPRM_FCAPS",
+	1322: "CAPSET",
+	1323: "MMAP",
+	1324: "NETFILTER_PKT",
+	1325: "NETFILTER_CFG",
+	1326: "SECCOMP",
+	1327: "PROCTITLE",
+	1328: "FEATURE_CHANGE",
+	1329: "REPLACE",
+	1330: "KERN_MODULE",
+	1331: "FANOTIFY",
+	1334: "BPF",
+	1400: "AVC",
+	1401: "SELINUX_ERR",
+	1700: "ANOM_PROMISCUOUS",
+	1701: "ANOM_ABEND",
+	1702: "ANOM_LINK",
+	1703: "ANOM_CREAT",
+	2000: "KERNEL",
+}
+
+// recordType returns the name of the record type like ausearch.
+func recordType(typ uint16) string {
+	if name, ok := recordTypes[typ]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN[%d]", typ)
+}
+
+// record is an audit record, the records of an event share its serial.
+type record struct {
+	typ    string
+	time   time.Time
+	serial uint64
+	fields map[string]string
+}
+
+// parseRecord parses the message of a record like
+// "audit(1364481363.243:24287): arch=c000003e syscall=2 ...".
+func parseRecord(typ, msg string) (*record, error) {
+	msg = strings.TrimRight(msg, "\x00\n")
+	if !strings.HasPrefix(msg, "audit(") {
+		return nil, fmt.Errorf("missing audit header")
+	}
+	end := strings.Index(msg, "):")
+	if end < 0 {
+		return nil, fmt.Errorf("missing audit header")
+	}
+	header := msg[len("audit("):end]
+
+	i := strings.IndexByte(header, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid audit header %q", header)
+	}
+	serial, err := strconv.ParseUint(header[i+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid serial in audit header %q", header)
+	}
+	// the timestamp has milliseconds
+	sec, msec := header[:i], "0"
+	if j := strings.IndexByte(sec, '.'); j >= 0 {
+		sec, msec = sec[:j], sec[j+1:]
+	}
+	s, err1 := strconv.ParseInt(sec, 10, 64)
+	ms, err2 := strconv.ParseInt(msec, 10, 64)
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("invalid time in audit header %q", header)
+	}
+
+	r := &record{
+		typ:    typ,
+		time:   time.Unix(s, ms*int64(time.Millisecond)),
+		serial: serial,
+		fields: make(map[string]string),
+	}
+	parseFields(msg[end+2:], r.fields)
+	return r, nil
+}
+
+// encodedFields are the fields hex encoded by the kernel when they are not
+// quoted.
+var encodedFields = map[string]bool{
+	"comm":      true,
+	"cmd":       true,
+	"cwd":       true,
+	"exe":       true,
+	"key":       true,
+	"name":      true,
+	"proctitle": true,
+}
+
+// parseFields parses the key=value fields of a record. The values are
+// either unquoted, double quoted or, for the messages of user space
+// programs, a single quoted list of fields. The fields interpreted by
+// auditd follow a group separator and have upper case keys.
+func parseFields(s string, fields map[string]string) {
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " \x1d")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return
+		}
+		key := s[:eq]
+		if i := strings.IndexAny(key, " \x1d"); i >= 0 {
+			// a word without value
+			s = s[i:]
+			continue
+		}
+		s = s[eq+1:]
+
+		var value string
+		if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+			quote := s[0]
+			end := strings.IndexByte(s[1:], quote)
+			if end < 0 {
+				end = len(s) - 1
+			}
+			value = s[1 : end+1]
+			s = s[min(end+2, len(s)):]
+			if quote == '\'' {
+				parseFields(value, fields)
+				continue
+			}
+		} else {
+			end := strings.IndexAny(s, " \x1d")
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			s = s[end:]
+			// untrusted strings with special characters are hex encoded
+			if encodedFields[key] {
+				if b, err := hex.DecodeString(value); err == nil {
+					value = string(b)
+				}
+			}
+		}
+		fields[key] = value
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// keys returns the keys of the audit rules matched by a syscall, multiple
+// keys are separated by \x01.
+func keys(r *record) []string {
+	key, ok := r.fields["key"]
+	if !ok || key == "(null)" || key == "" {
+		return nil
+	}
+	return strings.Split(key, "\x01")
+}
@@ -0,0 +1,134 @@
+// +build linux
+
+package auditd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// auditNlgrpReadlog is the multicast group of the audit netlink socket
+// receiving a copy of the records, see linux/audit.h.
+const auditNlgrpReadlog = 1
+
+// readTimeout bounds the time a read blocks so the reader notices when the
+// plugin is stopped
+const readTimeout = time.Second
+
+// netlinkSource receives the records from the kernel. Joining the readlog
+// group doesn't interfere with auditd, it only requires CAP_AUDIT_READ.
+type netlinkSource struct {
+	fd      int
+	buf     []byte
+	pending []syscall.NetlinkMessage
+}
+
+func openNetlink() (source, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_AUDIT)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	tv := unix.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	addr := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1 << (auditNlgrpReadlog - 1)}
+	if err := unix.Bind(fd, addr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("joining the audit multicast group: %s, the CAP_AUDIT_READ capability is required", err)
+	}
+	return &netlinkSource{fd: fd, buf: make([]byte, 1<<16)}, nil
+}
+
+func (s *netlinkSource) ReadRecord() (string, string, error) {
+	for len(s.pending) == 0 {
+		n, _, err := unix.Recvfrom(s.fd, s.buf, 0)
+		switch err {
+		case nil:
+		case unix.EAGAIN, unix.EINTR:
+			return "", "", errTimeout
+		case unix.ENOBUFS:
+			return "", "", errLost
+		default:
+			return "", "", os.NewSyscallError("recvfrom", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(s.buf[:n])
+		if err != nil {
+			return "", "", err
+		}
+		s.pending = msgs
+	}
+
+	m := s.pending[0]
+	s.pending = s.pending[1:]
+	return recordType(m.Header.Type), string(m.Data), nil
+}
+
+func (s *netlinkSource) Close() error {
+	return unix.Close(s.fd)
+}
+
+// unixSource reads the records from the af_unix plugin of audisp, in the
+// format of the audit log like "type=SYSCALL msg=audit(...): ...".
+type unixSource struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	partial string
+}
+
+func openUnix(path string) (source, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &unixSource{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (s *unixSource) ReadRecord() (string, string, error) {
+	if err := s.conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+		return "", "", err
+	}
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		// the line is continued by the next read
+		s.partial += line
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return "", "", errTimeout
+		}
+		return "", "", err
+	}
+	line, s.partial = s.partial+line, ""
+	return parseLine(strings.TrimRight(line, "\n"))
+}
+
+func (s *unixSource) Close() error {
+	return s.conn.Close()
+}
+
+// parseLine splits a line of the audit log into the record type and the
+// message, the line starts with the node name if auditd is configured so.
+func parseLine(line string) (string, string, error) {
+	if strings.HasPrefix(line, "node=") {
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			line = line[i+1:]
+		}
+	}
+	if !strings.HasPrefix(line, "type=") {
+		return "", "", fmt.Errorf("malformed audit record %q", line)
+	}
+	line = line[len("type="):]
+	i := strings.Index(line, " msg=")
+	if i < 0 {
+		return "", "", fmt.Errorf("malformed audit record %q", line)
+	}
+	return line[:i], line[i+len(" msg="):], nil
+}
@@ -0,0 +1,200 @@
+// +build linux
+
+package canbus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	// flags and masks of the can_id of a frame, see linux/can.h
+	canEFFFlag = 0x80000000
+	canRTRFlag = 0x40000000
+	canERRFlag = 0x20000000
+	canSFFMask = 0x000007FF
+	canEFFMask = 0x1FFFFFFF
+)
+
+// frame is a CAN frame as received from the bus.
+type frame struct {
+	id   uint32
+	data []byte
+}
+
+// bus reads frames from a CAN interface.
+type bus interface {
+	ReadFrame() (frame, error)
+	Close() error
+}
+
+// errTimeout is returned by ReadFrame when no frame was received within the
+// read timeout, it allows the reader to check if the plugin is stopping.
+var errTimeout = fmt.Errorf("read timeout")
+
+type CANBus struct {
+	Interfaces    []string `toml:"interfaces"`
+	DBCFiles      []string `toml:"dbc_files"`
+	UnknownFrames bool     `toml:"unknown_frames"`
+
+	open     func(iface string) (bus, error)
+	messages map[uint32]*message
+	buses    []bus
+	done     chan struct{}
+	wg       sync.WaitGroup
+	acc      telegraf.Accumulator
+}
+
+var sampleConfig = `
+  ## SocketCAN interfaces to read frames from
+  interfaces = ["can0"]
+
+  ## DBC files describing the messages and signals to decode, signals are
+  ## stored as fields of a metric named after their message
+  dbc_files = ["/etc/telegraf/vehicle.dbc"]
+
+  ## Report frames not described in the DBC files as raw data in the
+  ## canbus_frame measurement
+  # unknown_frames = false
+`
+
+func (c *CANBus) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CANBus) Description() string {
+	return "Read CAN bus frames from SocketCAN interfaces and decode them using DBC files"
+}
+
+func (c *CANBus) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (c *CANBus) Start(acc telegraf.Accumulator) error {
+	c.acc = acc
+
+	if len(c.Interfaces) == 0 {
+		return fmt.Errorf("no interfaces configured")
+	}
+
+	c.messages = make(map[uint32]*message)
+	for _, filename := range c.DBCFiles {
+		messages, err := loadDBC(filename)
+		if err != nil {
+			return err
+		}
+		for id, m := range messages {
+			c.messages[id] = m
+		}
+	}
+
+	if c.open == nil {
+		c.open = openSocketCAN
+	}
+
+	c.done = make(chan struct{})
+	for _, iface := range c.Interfaces {
+		b, err := c.open(iface)
+		if err != nil {
+			c.Stop()
+			return fmt.Errorf("opening %s failed: %s", iface, err)
+		}
+		c.buses = append(c.buses, b)
+
+		c.wg.Add(1)
+		go c.read(iface, b)
+	}
+
+	log.Printf("I! Started the canbus service on %v", c.Interfaces)
+	return nil
+}
+
+func (c *CANBus) read(iface string, b bus) {
+	defer c.wg.Done()
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		f, err := b.ReadFrame()
+		if err == errTimeout {
+			continue
+		}
+		if err != nil {
+			select {
+			case <-c.done:
+			default:
+				c.acc.AddError(fmt.Errorf("reading from %s failed: %s", iface, err))
+			}
+			return
+		}
+		c.handle(iface, f, time.Now())
+	}
+}
+
+// handle decodes the frame using the loaded DBC messages.
+func (c *CANBus) handle(iface string, f frame, now time.Time) {
+	// remote transmission requests and error frames carry no signals
+	if f.id&(canRTRFlag|canERRFlag) != 0 {
+		return
+	}
+
+	// DBC files flag extended ids with the same bit as SocketCAN
+	id := f.id & canSFFMask
+	if f.id&canEFFFlag != 0 {
+		id = f.id & (canEFFFlag | canEFFMask)
+	}
+
+	m, ok := c.messages[id]
+	if !ok {
+		if c.UnknownFrames {
+			c.acc.AddFields("canbus_frame",
+				map[string]interface{}{
+					"data":   hex.EncodeToString(f.data),
+					"length": len(f.data),
+				},
+				map[string]string{
+					"interface": iface,
+					"id":        strconv.FormatUint(uint64(id&canEFFMask), 16),
+				},
+				now)
+		}
+		return
+	}
+
+	fields := m.decode(f.data)
+	if len(fields) == 0 {
+		return
+	}
+	c.acc.AddFields(m.name, fields,
+		map[string]string{"interface": iface},
+		now)
+}
+
+func (c *CANBus) Stop() {
+	if c.done != nil {
+		close(c.done)
+	}
+	// readers return within the read timeout of the bus
+	c.wg.Wait()
+	for _, b := range c.buses {
+		b.Close()
+	}
+	c.buses = nil
+	c.done = nil
+}
+
+func init() {
+	inputs.Add("canbus", func() telegraf.Input {
+		return &CANBus{}
+	})
+}
@@ -0,0 +1,97 @@
+// +build linux
+
+package canbus
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBus replays a list of frames and then blocks until closed.
+type fakeBus struct {
+	frames chan frame
+	closed chan struct{}
+}
+
+func (b *fakeBus) ReadFrame() (frame, error) {
+	select {
+	case f := <-b.frames:
+		return f, nil
+	case <-b.closed:
+		return frame{}, errTimeout
+	}
+}
+
+func (b *fakeBus) Close() error {
+	return nil
+}
+
+func TestCANBus(t *testing.T) {
+	dbc, err := ioutil.TempFile("", "canbus")
+	require.NoError(t, err)
+	defer os.Remove(dbc.Name())
+	_, err = dbc.WriteString(testDBC)
+	require.NoError(t, err)
+	dbc.Close()
+
+	b := &fakeBus{
+		frames: make(chan frame, 4),
+		closed: make(chan struct{}),
+	}
+	b.frames <- frame{id: 256, data: []byte{0x40, 0x1F, 0x78, 0x38, 0x0F, 0, 0, 0}}
+	// remote transmission request
+	b.frames <- frame{id: 256 | canRTRFlag}
+	// extended frame
+	b.frames <- frame{id: 0x18FEF1FE | canEFFFlag, data: []byte{0x27, 0x10, 0xF6, 0, 0, 0, 0, 0}}
+	// unknown frame
+	b.frames <- frame{id: 0x123, data: []byte{0xde, 0xad}}
+
+	c := &CANBus{
+		Interfaces:    []string{"vcan0"},
+		DBCFiles:      []string{dbc.Name()},
+		UnknownFrames: true,
+		open: func(iface string) (bus, error) {
+			return b, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Start(&acc))
+	acc.Wait(3)
+	close(b.closed)
+	c.Stop()
+
+	acc.AssertContainsTaggedFields(t, "EngineData",
+		map[string]interface{}{
+			"EngineSpeed": float64(2000),
+			"CoolantTemp": float64(80),
+			"Torque":      float64(-100),
+		},
+		map[string]string{"interface": "vcan0"})
+	acc.AssertContainsTaggedFields(t, "VehicleSpeed",
+		map[string]interface{}{
+			"Speed":        float64(100),
+			"Acceleration": float64(-1),
+		},
+		map[string]string{"interface": "vcan0"})
+	acc.AssertContainsTaggedFields(t, "canbus_frame",
+		map[string]interface{}{
+			"data":   "dead",
+			"length": 2,
+		},
+		map[string]string{"interface": "vcan0", "id": "123"})
+	require.Equal(t, uint64(3), acc.NMetrics())
+}
+
+func TestMissingDBC(t *testing.T) {
+	c := &CANBus{
+		Interfaces: []string{"vcan0"},
+		DBCFiles:   []string{"/nonexistent.dbc"},
+	}
+	var acc testutil.Accumulator
+	require.Error(t, c.Start(&acc))
+}
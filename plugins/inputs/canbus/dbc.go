@@ -0,0 +1,224 @@
+package canbus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// message is a CAN message described by a BO_ entry of a DBC file.
+type message struct {
+	id      uint32
+	name    string
+	length  int
+	signals []*signal
+}
+
+// signal is a value encoded in a message described by a SG_ entry.
+type signal struct {
+	name      string
+	start     uint
+	length    uint
+	bigEndian bool
+	signed    bool
+	factor    float64
+	offset    float64
+
+	// multiplexer is set on the signal selecting the multiplexed signals,
+	// multiplexed signals are only decoded when the value of the
+	// multiplexer equals multiplexID
+	multiplexer bool
+	multiplexed bool
+	multiplexID uint64
+}
+
+var (
+	messageRe = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)`)
+	signalRe  = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(([^,]+),([^)]+)\)\s*\[[^\]]*\]\s*"[^"]*"`)
+)
+
+// loadDBC reads the message definitions of a DBC file.
+func loadDBC(filename string) (map[uint32]*message, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	messages, err := parseDBC(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return messages, nil
+}
+
+// parseDBC parses the messages and signals of a DBC database, all other
+// sections are ignored.
+func parseDBC(r io.Reader) (map[uint32]*message, error) {
+	messages := make(map[uint32]*message)
+
+	var current *message
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "BO_ "):
+			m := messageRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: invalid message definition", lineno)
+			}
+			id, err := strconv.ParseUint(m[1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid message id: %s", lineno, err)
+			}
+			length, _ := strconv.Atoi(m[3])
+			current = &message{
+				id:     uint32(id),
+				name:   m[2],
+				length: length,
+			}
+			messages[current.id] = current
+		case strings.HasPrefix(line, "SG_ "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: signal outside of a message", lineno)
+			}
+			s, err := parseSignal(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineno, err)
+			}
+			current.signals = append(current.signals, s)
+		case line == "":
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func parseSignal(line string) (*signal, error) {
+	m := signalRe.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("invalid signal definition")
+	}
+
+	start, _ := strconv.ParseUint(m[3], 10, 32)
+	length, _ := strconv.ParseUint(m[4], 10, 32)
+	if length == 0 || length > 64 || start > 63 {
+		return nil, fmt.Errorf("invalid position %s|%s of signal %s", m[3], m[4], m[1])
+	}
+	factor, err := strconv.ParseFloat(strings.TrimSpace(m[7]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid factor of signal %s: %s", m[1], err)
+	}
+	offset, err := strconv.ParseFloat(strings.TrimSpace(m[8]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid offset of signal %s: %s", m[1], err)
+	}
+
+	s := &signal{
+		name:      m[1],
+		start:     uint(start),
+		length:    uint(length),
+		bigEndian: m[5] == "0",
+		signed:    m[6] == "-",
+		factor:    factor,
+		offset:    offset,
+	}
+
+	switch {
+	case m[2] == "M":
+		s.multiplexer = true
+	case m[2] != "":
+		id, err := strconv.ParseUint(m[2][1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multiplexer id of signal %s: %s", m[1], err)
+		}
+		s.multiplexed = true
+		s.multiplexID = id
+	}
+	return s, nil
+}
+
+// decode returns the physical values of the signals of the message found
+// in data.
+func (m *message) decode(data []byte) map[string]interface{} {
+	var frame [8]byte
+	copy(frame[:], data)
+	size := uint(len(data)) * 8
+
+	var mux uint64
+	hasMux := false
+	for _, s := range m.signals {
+		if s.multiplexer {
+			if raw, ok := s.raw(frame, size); ok {
+				mux, hasMux = raw, true
+			}
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for _, s := range m.signals {
+		if s.multiplexed && (!hasMux || s.multiplexID != mux) {
+			continue
+		}
+		raw, ok := s.raw(frame, size)
+		if !ok {
+			continue
+		}
+		var value float64
+		if s.signed {
+			value = float64(signExtend(raw, s.length))
+		} else {
+			value = float64(raw)
+		}
+		fields[s.name] = value*s.factor + s.offset
+	}
+	return fields
+}
+
+// raw extracts the raw bits of the signal from the frame, size is the
+// number of bits received.
+func (s *signal) raw(frame [8]byte, size uint) (uint64, bool) {
+	var mask uint64 = 1<<s.length - 1
+	if s.length == 64 {
+		mask = ^uint64(0)
+	}
+
+	if !s.bigEndian {
+		if s.start+s.length > size {
+			return 0, false
+		}
+		var v uint64
+		for i := 7; i >= 0; i-- {
+			v = v<<8 | uint64(frame[i])
+		}
+		return (v >> s.start) & mask, true
+	}
+
+	// The start bit of big endian (motorola) signals is the most
+	// significant bit, numbered within its byte from the least significant
+	// bit.
+	msb := 8*(s.start/8) + (7 - s.start%8)
+	if msb+s.length > size {
+		return 0, false
+	}
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v = v<<8 | uint64(frame[i])
+	}
+	return (v >> (64 - msb - s.length)) & mask, true
+}
+
+func signExtend(v uint64, length uint) int64 {
+	shift := 64 - length
+	return int64(v<<shift) >> shift
+}
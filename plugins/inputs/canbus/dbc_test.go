@@ -0,0 +1,106 @@
+package canbus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDBC = `VERSION ""
+
+NS_ :
+
+BS_:
+
+BU_: ECU Dashboard
+
+BO_ 256 EngineData: 8 ECU
+ SG_ EngineSpeed : 0|16@1+ (0.25,0) [0|16383.75] "rpm" Dashboard
+ SG_ CoolantTemp : 16|8@1+ (1,-40) [-40|215] "degC" Dashboard
+ SG_ Torque : 24|12@1- (0.5,0) [-1024|1023.5] "Nm" Dashboard
+
+BO_ 2566844926 VehicleSpeed: 8 ECU
+ SG_ Speed : 7|16@0+ (0.01,0) [0|655.35] "km/h" Dashboard
+ SG_ Acceleration : 23|8@0- (0.1,0) [-12.8|12.7] "m/s2" Dashboard
+
+BO_ 512 Battery: 4 ECU
+ SG_ Mode M : 0|8@1+ (1,0) [0|255] "" Dashboard
+ SG_ Voltage m0 : 8|16@1+ (0.001,0) [0|65.535] "V" Dashboard
+ SG_ Current m1 : 8|16@1- (0.01,0) [-327.68|327.67] "A" Dashboard
+
+CM_ SG_ 256 EngineSpeed "Engine speed";
+`
+
+func TestParseDBC(t *testing.T) {
+	messages, err := parseDBC(strings.NewReader(testDBC))
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+
+	engine := messages[256]
+	require.NotNil(t, engine)
+	assert.Equal(t, "EngineData", engine.name)
+	assert.Equal(t, 8, engine.length)
+	require.Len(t, engine.signals, 3)
+	assert.Equal(t, &signal{
+		name:   "Torque",
+		start:  24,
+		length: 12,
+		signed: true,
+		factor: 0.5,
+	}, engine.signals[2])
+
+	// extended ids are flagged with the most significant bit
+	speed := messages[0x80000000|0x18FEF1FE]
+	require.NotNil(t, speed)
+	assert.True(t, speed.signals[0].bigEndian)
+
+	battery := messages[512]
+	require.NotNil(t, battery)
+	assert.True(t, battery.signals[0].multiplexer)
+	assert.True(t, battery.signals[2].multiplexed)
+	assert.Equal(t, uint64(1), battery.signals[2].multiplexID)
+}
+
+func TestParseDBCErrors(t *testing.T) {
+	_, err := parseDBC(strings.NewReader(" SG_ Speed : 0|8@1+ (1,0) [0|0] \"\" ECU\n"))
+	assert.Error(t, err)
+
+	_, err = parseDBC(strings.NewReader("BO_ 1 A: 8 ECU\n SG_ Speed : 0|65@1+ (1,0) [0|0] \"\" ECU\n"))
+	assert.Error(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	messages, err := parseDBC(strings.NewReader(testDBC))
+	require.NoError(t, err)
+
+	// little endian signals, torque is -100 (0xF38 as 12 bit two's complement)
+	fields := messages[256].decode([]byte{0x40, 0x1F, 0x78, 0x38, 0x0F, 0, 0, 0})
+	assert.Equal(t, map[string]interface{}{
+		"EngineSpeed": float64(2000),
+		"CoolantTemp": float64(80),
+		"Torque":      float64(-100),
+	}, fields)
+
+	// big endian signals
+	fields = messages[0x80000000|0x18FEF1FE].decode([]byte{0x27, 0x10, 0xF6, 0, 0, 0, 0, 0})
+	assert.Equal(t, float64(100), fields["Speed"])
+	assert.InDelta(t, -1.0, fields["Acceleration"], 1e-9)
+
+	// multiplexed signals
+	fields = messages[512].decode([]byte{0, 0xE8, 0x30, 0})
+	assert.Equal(t, map[string]interface{}{
+		"Mode":    float64(0),
+		"Voltage": float64(12.52),
+	}, fields)
+	fields = messages[512].decode([]byte{1, 0x0C, 0xFE, 0})
+	assert.Equal(t, map[string]interface{}{
+		"Mode":    float64(1),
+		"Current": float64(-5),
+	}, fields)
+
+	// signals beyond the received data are skipped
+	fields = messages[256].decode([]byte{0x40, 0x1F})
+	assert.Equal(t, map[string]interface{}{"EngineSpeed": float64(2000)}, fields)
+}
@@ -0,0 +1,75 @@
+// +build linux
+
+package canbus
+
+import (
+	"fmt"
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// frameSize is the size of struct can_frame
+const frameSize = 16
+
+// readTimeout bounds the time a read blocks so the readers notice when the
+// plugin is stopped
+const readTimeout = time.Second
+
+type socketCAN struct {
+	fd int
+}
+
+// openSocketCAN opens a raw CAN socket bound to the interface.
+func openSocketCAN(iface string) (bus, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
+	if err != nil {
+		return nil, err
+	}
+
+	tv := unix.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return &socketCAN{fd: fd}, nil
+}
+
+func (s *socketCAN) ReadFrame() (frame, error) {
+	buf := make([]byte, frameSize)
+	n, err := unix.Read(s.fd, buf)
+	if err == unix.EAGAIN || err == unix.EINTR {
+		return frame{}, errTimeout
+	}
+	if err != nil {
+		return frame{}, err
+	}
+	if n != frameSize {
+		return frame{}, fmt.Errorf("short frame of %d bytes", n)
+	}
+
+	length := int(buf[4])
+	if length > 8 {
+		length = 8
+	}
+	return frame{
+		// can_id is in host byte order
+		id:   *(*uint32)(unsafe.Pointer(&buf[0])),
+		data: buf[8 : 8+length],
+	}, nil
+}
+
+func (s *socketCAN) Close() error {
+	return unix.Close(s.fd)
+}
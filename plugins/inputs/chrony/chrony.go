@@ -1,9 +1,9 @@
 package chrony
 
 import (
-	"errors"
 	"fmt"
-	"os/exec"
+	"net"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -13,119 +13,221 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
-var (
-	execCommand = exec.Command // execCommand is used to mock commands in tests.
-)
+const defaultServer = "udp://127.0.0.1:323"
 
 type Chrony struct {
-	DNSLookup bool `toml:"dns_lookup"`
-	path      string
+	Server    string            `toml:"server"`
+	Timeout   internal.Duration `toml:"timeout"`
+	DNSLookup bool              `toml:"dns_lookup"`
+	Metrics   []string          `toml:"metrics"`
 }
 
 func (*Chrony) Description() string {
-	return "Get standard chrony metrics, requires chronyc executable."
+	return "Get standard chrony metrics from chronyd with its monitoring protocol."
 }
 
 func (*Chrony) SampleConfig() string {
 	return `
-  ## If true, chronyc tries to perform a DNS lookup for the time server.
+  ## Address of the command port of chronyd, chronyd answers monitoring
+  ## requests from the hosts allowed by its cmdallow directives, by default
+  ## from localhost only.
+  # server = "udp://127.0.0.1:323"
+
+  ## Timeout of each request, the requests are sent up to three times.
+  # timeout = "5s"
+
+  ## If true, try to perform a DNS lookup for the time servers.
   # dns_lookup = false
+
+  ## Metrics to gather, "tracking" for the synchronization of the system
+  ## clock and "sources" for the state and statistics of each time source.
+  # metrics = ["tracking"]
   `
 }
 
 func (c *Chrony) Gather(acc telegraf.Accumulator) error {
-	if len(c.path) == 0 {
-		return errors.New("chronyc not found: verify that chrony is installed and that chronyc is in your PATH")
+	var tracking, sources bool
+	for _, m := range c.Metrics {
+		switch m {
+		case "tracking":
+			tracking = true
+		case "sources":
+			sources = true
+		default:
+			return fmt.Errorf("unknown metric %q", m)
+		}
 	}
 
-	flags := []string{}
-	if !c.DNSLookup {
-		flags = append(flags, "-n")
+	server := c.Server
+	if server == "" {
+		server = defaultServer
 	}
-	flags = append(flags, "tracking")
-
-	cmd := execCommand(c.path, flags...)
-	out, err := internal.CombinedOutputTimeout(cmd, time.Second*5)
+	u, err := url.Parse(server)
 	if err != nil {
-		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+		return fmt.Errorf("parsing server address %s: %s", server, err)
 	}
-	fields, tags, err := processChronycOutput(string(out))
+	if u.Scheme != "udp" {
+		return fmt.Errorf("unsupported scheme %q in server address %s", u.Scheme, server)
+	}
+
+	cmd, err := dialCmdmon(u.Host, c.Timeout.Duration)
 	if err != nil {
 		return err
 	}
+	defer cmd.Close()
+
+	if tracking {
+		if err := c.gatherTracking(acc, cmd); err != nil {
+			acc.AddError(err)
+		}
+	}
+	if sources {
+		if err := c.gatherSources(acc, cmd); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+var leapStatus = map[uint16]string{
+	0: "normal",
+	1: "insert second",
+	2: "delete second",
+	3: "not synchronised",
+}
+
+// gatherTracking reports the state of the system clock like chronyc
+// tracking, the system time is positive when the clock is fast and the
+// frequency is negative when the clock is slow.
+func (c *Chrony) gatherTracking(acc telegraf.Accumulator, cmd *cmdmon) error {
+	t, err := cmd.tracking()
+	if err != nil {
+		return fmt.Errorf("tracking: %s", err)
+	}
+
+	tags := map[string]string{
+		"stratum": strconv.Itoa(int(t.stratum)),
+	}
+	// unsynchronised clocks have no reference
+	if ref := c.sourceName(t.ipAddr, t.refID); ref != "" {
+		tags["reference_id"] = ref
+	}
+	if status, ok := leapStatus[t.leapStatus]; ok {
+		tags["leap_status"] = status
+	}
+
+	fields := map[string]interface{}{
+		"system_time":     -t.currentCorrection,
+		"last_offset":     t.lastOffset,
+		"rms_offset":      t.rmsOffset,
+		"frequency":       t.freqPPM,
+		"residual_freq":   t.residFreqPPM,
+		"skew":            t.skewPPM,
+		"root_delay":      t.rootDelay,
+		"root_dispersion": t.rootDispersion,
+		"update_interval": t.updateInterval,
+	}
 	acc.AddFields("chrony", fields, tags)
 	return nil
 }
 
-// processChronycOutput takes in a string output from the chronyc command, like:
-//
-//     Reference ID    : 192.168.1.22 (ntp.example.com)
-//     Stratum         : 3
-//     Ref time (UTC)  : Thu May 12 14:27:07 2016
-//     System time     : 0.000020390 seconds fast of NTP time
-//     Last offset     : +0.000012651 seconds
-//     RMS offset      : 0.000025577 seconds
-//     Frequency       : 16.001 ppm slow
-//     Residual freq   : -0.000 ppm
-//     Skew            : 0.006 ppm
-//     Root delay      : 0.001655 seconds
-//     Root dispersion : 0.003307 seconds
-//     Update interval : 507.2 seconds
-//     Leap status     : Normal
-//
-// The value on the left side of the colon is used as field name, if the first field on
-// the right side is a float. If it cannot be parsed as float, it is a tag name.
-//
-// Ref time is ignored and all names are converted to snake case.
-//
-// It returns (<fields>, <tags>)
-func processChronycOutput(out string) (map[string]interface{}, map[string]string, error) {
-	tags := map[string]string{}
-	fields := map[string]interface{}{}
-	lines := strings.Split(strings.TrimSpace(out), "\n")
-	for _, line := range lines {
-		stats := strings.Split(line, ":")
-		if len(stats) < 2 {
-			return nil, nil, fmt.Errorf("unexpected output from chronyc, expected ':' in %s", out)
+var sourceModes = map[uint16]string{
+	0: "server",
+	1: "peer",
+	2: "refclock",
+}
+
+var sourceStates = map[uint16]string{
+	0: "selected",
+	1: "nonselectable",
+	2: "falseticker",
+	3: "jittery",
+	4: "unselected",
+	5: "selectable",
+}
+
+// gatherSources reports each source like chronyc sources and sourcestats.
+func (c *Chrony) gatherSources(acc telegraf.Accumulator, cmd *cmdmon) error {
+	n, err := cmd.nSources()
+	if err != nil {
+		return fmt.Errorf("sources: %s", err)
+	}
+
+	for i := 0; i < n; i++ {
+		src, err := cmd.sourceData(i)
+		if err != nil {
+			// the sources changed since they were counted
+			if se, ok := err.(*statusError); ok && se.status == statusNoSuchSource {
+				break
+			}
+			return fmt.Errorf("source %d: %s", i, err)
 		}
-		name := strings.ToLower(strings.Replace(strings.TrimSpace(stats[0]), " ", "_", -1))
-		// ignore reference time
-		if strings.Contains(name, "ref_time") {
-			continue
+
+		tags := map[string]string{}
+		if mode, ok := sourceModes[src.mode]; ok {
+			tags["mode"] = mode
 		}
-		valueFields := strings.Fields(stats[1])
-		if len(valueFields) == 0 {
-			return nil, nil, fmt.Errorf("unexpected output from chronyc: %s", out)
+		if state, ok := sourceStates[src.state]; ok {
+			tags["state"] = state
 		}
-		if strings.Contains(strings.ToLower(name), "stratum") {
-			tags["stratum"] = valueFields[0]
-			continue
+		if src.mode == 2 {
+			tags["source"] = refIDString(src.refID)
+		} else {
+			tags["source"] = c.sourceName(src.ipAddr, 0)
 		}
-		if strings.Contains(strings.ToLower(name), "reference_id") {
-			tags["reference_id"] = valueFields[0]
-			continue
+
+		fields := map[string]interface{}{
+			"stratum":         int(src.stratum),
+			"poll":            int(src.poll),
+			"reachability":    int(src.reachability),
+			"last_rx":         int64(src.sinceSample),
+			"offset":          src.latestMeas,
+			"measured_offset": src.origLatestMeas,
+			"offset_error":    src.latestMeasErr,
 		}
-		value, err := strconv.ParseFloat(valueFields[0], 64)
+
+		stats, err := cmd.sourcestats(i)
 		if err != nil {
-			tags[name] = strings.ToLower(strings.Join(valueFields, " "))
-			continue
-		}
-		if strings.Contains(stats[1], "slow") {
-			value = -value
+			if se, ok := err.(*statusError); ok && se.status == statusNoSuchSource {
+				break
+			}
+			return fmt.Errorf("sourcestats %d: %s", i, err)
 		}
-		fields[name] = value
+		fields["samples"] = int64(stats.nSamples)
+		fields["runs"] = int64(stats.nRuns)
+		fields["span"] = int64(stats.spanSeconds)
+		fields["std_dev"] = stats.stdDev
+		fields["residual_freq"] = stats.residFreqPPM
+		fields["skew"] = stats.skewPPM
+		fields["estimated_offset"] = stats.estOffset
+		fields["estimated_offset_error"] = stats.estOffsetErr
+
+		acc.AddFields("chrony_sources", fields, tags)
 	}
+	return nil
+}
 
-	return fields, tags, nil
+// sourceName returns the address or, with dns_lookup, the host name of the
+// source. Reference clocks have no address, they are named by their
+// reference id.
+func (c *Chrony) sourceName(ip net.IP, refID uint32) string {
+	if ip == nil {
+		return refIDString(refID)
+	}
+	if c.DNSLookup {
+		if names, err := net.LookupAddr(ip.String()); err == nil && len(names) > 0 {
+			return strings.TrimSuffix(names[0], ".")
+		}
+	}
+	return ip.String()
 }
 
 func init() {
-	c := Chrony{}
-	path, _ := exec.LookPath("chronyc")
-	if len(path) > 0 {
-		c.path = path
-	}
 	inputs.Add("chrony", func() telegraf.Input {
-		return &c
+		return &Chrony{
+			Server:  defaultServer,
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			Metrics: []string{"tracking"},
+		}
 	})
 }
@@ -1,34 +1,187 @@
 package chrony
 
 import (
-	"fmt"
-	"os"
-	"os/exec"
+	"encoding/binary"
+	"math"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestGather(t *testing.T) {
-	c := Chrony{
-		path: "chronyc",
+// encodeFloat encodes x in the floating point format of chrony.
+func encodeFloat(x float64) []byte {
+	b := make([]byte, 4)
+	if x == 0 {
+		return b
 	}
-	// overwriting exec commands with mock commands
-	execCommand = fakeExecCommand
-	defer func() { execCommand = exec.Command }()
-	var acc testutil.Accumulator
+	frac, exp := math.Frexp(x)
+	coef := int32(math.Floor(frac*(1<<24) + 0.5))
+	binary.BigEndian.PutUint32(b, uint32(exp+1)<<25|uint32(coef)&(1<<25-1))
+	return b
+}
+
+func ipAddr4(ip string) []byte {
+	b := make([]byte, 20)
+	copy(b, net.ParseIP(ip).To4())
+	binary.BigEndian.PutUint16(b[16:18], 1)
+	return b
+}
+
+func refID(id string) []byte {
+	b := make([]byte, 20)
+	copy(b, id)
+	return b
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func join(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func trackingReply() []byte {
+	return join(
+		be32(0xC0A80116), ipAddr4("192.168.1.22"),
+		be16(3), be16(0),
+		make([]byte, 12),
+		encodeFloat(-0.00002039),
+		encodeFloat(0.000012651),
+		encodeFloat(0.000025577),
+		encodeFloat(-16.001),
+		encodeFloat(0),
+		encodeFloat(0.006),
+		encodeFloat(0.001655),
+		encodeFloat(0.003307),
+		encodeFloat(507.2),
+		make([]byte, 4))
+}
+
+func sourceDataReply(index uint32) []byte {
+	if index == 1 {
+		// reference clock
+		return join(refID("PPS"),
+			be16(uint16(4)), be16(0), be16(4), be16(2), be16(0), be16(0377),
+			be32(5),
+			encodeFloat(0.0000001), encodeFloat(0.0000001), encodeFloat(0.000000125),
+			make([]byte, 4))
+	}
+	return join(ipAddr4("192.168.1.22"),
+		be16(uint16(10)), be16(2), be16(0), be16(0), be16(0), be16(0377),
+		be32(37),
+		encodeFloat(-0.000015), encodeFloat(-0.000014), encodeFloat(0.0005),
+		make([]byte, 4))
+}
+
+func sourcestatsReply(index uint32) []byte {
+	return join(be32(index), ipAddr4("192.168.1.22"),
+		be32(12), be32(7), be32(3600),
+		encodeFloat(0.000028), encodeFloat(-0.001), encodeFloat(0.0125),
+		encodeFloat(0.000002), encodeFloat(0.00003),
+		make([]byte, 4))
+}
+
+// fakeChronyd answers the requests like chronyd, the first request is not
+// answered to test the retries.
+func fakeChronyd(t *testing.T, conn net.PacketConn) {
+	buf := make([]byte, 1024)
+	dropped := false
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req := buf[:n]
+		if !dropped {
+			dropped = true
+			continue
+		}
 
-	err := c.Gather(&acc)
-	if err != nil {
-		t.Fatal(err)
+		command := binary.BigEndian.Uint16(req[4:6])
+		reply := make([]byte, replyHeaderLen)
+		reply[0] = protocolVersion
+		reply[1] = pktTypeReply
+		copy(reply[4:6], req[4:6])
+		copy(reply[16:20], req[8:12])
+
+		rpy := replies[command]
+		var data []byte
+		switch command {
+		case reqTracking:
+			data = trackingReply()
+		case reqNSources:
+			data = join(be32(3), make([]byte, 4))
+		case reqSourceData:
+			index := binary.BigEndian.Uint32(req[20:24])
+			if index > 1 {
+				binary.BigEndian.PutUint16(reply[8:10], statusNoSuchSource)
+				break
+			}
+			data = sourceDataReply(index)
+		case reqSourcestats:
+			data = sourcestatsReply(binary.BigEndian.Uint32(req[20:24]))
+		}
+		if len(req) < replyHeaderLen+rpy.dataLen {
+			binary.BigEndian.PutUint16(reply[8:10], 19)
+			data = nil
+		}
+		binary.BigEndian.PutUint16(reply[6:8], rpy.code)
+		conn.WriteTo(append(reply, data...), addr)
+	}
+}
+
+func startChronyd(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go fakeChronyd(t, conn)
+	return conn
+}
+
+func TestParseFloat(t *testing.T) {
+	for _, x := range []float64{0, 1, -1, 0.5, 507.2, -16.001, 0.000025577, 1e-9} {
+		assert.InDelta(t, x, parseFloat(encodeFloat(x)), math.Abs(x)*1e-7)
+	}
+}
+
+func TestGather(t *testing.T) {
+	conn := startChronyd(t)
+	defer conn.Close()
+
+	c := &Chrony{
+		Server:  "udp://" + conn.LocalAddr().String(),
+		Timeout: internal.Duration{Duration: 100 * time.Millisecond},
+		Metrics: []string{"tracking", "sources"},
 	}
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(c.Gather))
 
 	tags := map[string]string{
 		"reference_id": "192.168.1.22",
-		"leap_status":  "not synchronized",
+		"leap_status":  "normal",
 		"stratum":      "3",
 	}
-	fields := map[string]interface{}{
+	require.True(t, acc.HasMeasurement("chrony"))
+	m, _ := acc.Get("chrony")
+	assert.Equal(t, tags, m.Tags)
+	fields := map[string]float64{
 		"system_time":     0.000020390,
 		"last_offset":     0.000012651,
 		"rms_offset":      0.000025577,
@@ -39,70 +192,59 @@ func TestGather(t *testing.T) {
 		"root_dispersion": 0.003307,
 		"update_interval": 507.2,
 	}
+	for k, v := range fields {
+		assert.InDelta(t, v, m.Fields[k], math.Abs(v)*1e-6, k)
+	}
 
-	acc.AssertContainsTaggedFields(t, "chrony", fields, tags)
-
-	// test with dns lookup
-	c.DNSLookup = true
-	err = c.Gather(&acc)
-	if err != nil {
-		t.Fatal(err)
+	var sources []*testutil.Metric
+	for _, m := range acc.Metrics {
+		if m.Measurement == "chrony_sources" {
+			sources = append(sources, m)
+		}
 	}
-	acc.AssertContainsTaggedFields(t, "chrony", fields, tags)
+	require.Len(t, sources, 2)
 
-}
+	assert.Equal(t, map[string]string{
+		"source": "192.168.1.22",
+		"mode":   "server",
+		"state":  "selected",
+	}, sources[0].Tags)
+	assert.Equal(t, 2, sources[0].Fields["stratum"])
+	assert.Equal(t, 10, sources[0].Fields["poll"])
+	assert.Equal(t, 0377, sources[0].Fields["reachability"])
+	assert.Equal(t, int64(37), sources[0].Fields["last_rx"])
+	assert.InDelta(t, -0.000014, sources[0].Fields["offset"], 1e-12)
+	assert.InDelta(t, -0.000015, sources[0].Fields["measured_offset"], 1e-12)
+	assert.Equal(t, int64(12), sources[0].Fields["samples"])
+	assert.Equal(t, int64(3600), sources[0].Fields["span"])
+	assert.InDelta(t, 0.000028, sources[0].Fields["std_dev"], 1e-12)
 
-// fackeExecCommand is a helper function that mock
-// the exec.Command call (and call the test binary)
-func fakeExecCommand(command string, args ...string) *exec.Cmd {
-	cs := []string{"-test.run=TestHelperProcess", "--", command}
-	cs = append(cs, args...)
-	cmd := exec.Command(os.Args[0], cs...)
-	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
-	return cmd
+	assert.Equal(t, map[string]string{
+		"source": "PPS",
+		"mode":   "refclock",
+		"state":  "unselected",
+	}, sources[1].Tags)
 }
 
-// TestHelperProcess isn't a real test. It's used to mock exec.Command
-// For example, if you run:
-// GO_WANT_HELPER_PROCESS=1 go test -test.run=TestHelperProcess -- chrony tracking
-// it returns below mockData.
-func TestHelperProcess(t *testing.T) {
-	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
-		return
+func TestGatherNoReply(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := &Chrony{
+		Server:  "udp://" + conn.LocalAddr().String(),
+		Timeout: internal.Duration{Duration: 10 * time.Millisecond},
+		Metrics: []string{"tracking"},
 	}
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(c.Gather))
+}
 
-	lookup := "Reference ID    : 192.168.1.22 (ntp.example.com)\n"
-	noLookup := "Reference ID    : 192.168.1.22 (192.168.1.22)\n"
-	mockData := `Stratum         : 3
-Ref time (UTC)  : Thu May 12 14:27:07 2016
-System time     : 0.000020390 seconds fast of NTP time
-Last offset     : +0.000012651 seconds
-RMS offset      : 0.000025577 seconds
-Frequency       : 16.001 ppm slow
-Residual freq   : -0.000 ppm
-Skew            : 0.006 ppm
-Root delay      : 0.001655 seconds
-Root dispersion : 0.003307 seconds
-Update interval : 507.2 seconds
-Leap status     : Not synchronized
-`
-
-	args := os.Args
-
-	// Previous arguments are tests stuff, that looks like :
-	// /tmp/go-build970079519/…/_test/integration.test -test.run=TestHelperProcess --
-	cmd, args := args[3], args[4:]
-
-	if cmd == "chronyc" {
-		if args[0] == "tracking" {
-			fmt.Fprint(os.Stdout, lookup+mockData)
-		} else {
-			fmt.Fprint(os.Stdout, noLookup+mockData)
-		}
-	} else {
-		fmt.Fprint(os.Stdout, "command not found")
-		os.Exit(1)
+func TestGatherInvalidConfig(t *testing.T) {
+	var acc testutil.Accumulator
+	c := &Chrony{Server: "unix:///var/run/chrony/chronyd.sock", Metrics: []string{"tracking"}}
+	require.Error(t, c.Gather(&acc))
 
-	}
-	os.Exit(0)
+	c = &Chrony{Metrics: []string{"clients"}}
+	require.Error(t, c.Gather(&acc))
 }
@@ -0,0 +1,325 @@
+package chrony
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+)
+
+// chronyd command and monitoring protocol, see candm.h of chrony. All the
+// values are in network byte order.
+const (
+	protocolVersion = 6
+	pktTypeRequest  = 1
+	pktTypeReply    = 2
+
+	requestHeaderLen = 20
+	replyHeaderLen   = 28
+
+	reqNSources    = 14
+	reqSourceData  = 15
+	reqTracking    = 33
+	reqSourcestats = 34
+
+	rpyNSources    = 2
+	rpySourceData  = 3
+	rpyTracking    = 5
+	rpySourcestats = 6
+
+	statusSuccess       = 0
+	statusUnauth        = 2
+	statusNoSuchSource  = 4
+	statusBadPktVersion = 18
+)
+
+type replyType struct {
+	code    uint16
+	dataLen int
+}
+
+// replies are the reply codes of the commands and the length of their data,
+// including the end of record marker. The requests are padded to the length
+// of their reply as chronyd ignores shorter requests.
+var replies = map[uint16]replyType{
+	reqNSources:    {rpyNSources, 8},
+	reqSourceData:  {rpySourceData, 52},
+	reqTracking:    {rpyTracking, 80},
+	reqSourcestats: {rpySourcestats, 60},
+}
+
+var statusText = map[uint16]string{
+	1:                   "failed",
+	statusUnauth:        "unauthorised",
+	3:                   "invalid",
+	statusNoSuchSource:  "no such source",
+	6:                   "not enabled",
+	9:                   "access denied",
+	10:                  "no host access, check the cmdallow directives",
+	15:                  "inactive",
+	statusBadPktVersion: "bad packet version",
+	19:                  "bad packet length",
+}
+
+type cmdmon struct {
+	conn     net.Conn
+	timeout  time.Duration
+	attempts int
+	sequence uint32
+}
+
+func dialCmdmon(address string, timeout time.Duration) (*cmdmon, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &cmdmon{
+		conn:     conn,
+		timeout:  timeout,
+		attempts: 3,
+		sequence: uint32(time.Now().UnixNano()),
+	}, nil
+}
+
+func (c *cmdmon) Close() error {
+	return c.conn.Close()
+}
+
+// request sends the command and returns the data of the reply, the
+// request is sent again if the reply is lost.
+func (c *cmdmon) request(command uint16, data []byte) ([]byte, error) {
+	c.sequence++
+	rpy, ok := replies[command]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %d", command)
+	}
+
+	length := requestHeaderLen + len(data)
+	if l := replyHeaderLen + rpy.dataLen; l > length {
+		length = l
+	}
+	req := make([]byte, length)
+	req[0] = protocolVersion
+	req[1] = pktTypeRequest
+	binary.BigEndian.PutUint16(req[4:6], command)
+	binary.BigEndian.PutUint32(req[8:12], c.sequence)
+	copy(req[requestHeaderLen:], data)
+
+	buf := make([]byte, 1024)
+	var err error
+	for attempt := 0; attempt < c.attempts; attempt++ {
+		binary.BigEndian.PutUint16(req[6:8], uint16(attempt))
+		if _, err = c.conn.Write(req); err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(c.timeout)
+		for {
+			if err = c.conn.SetReadDeadline(deadline); err != nil {
+				return nil, err
+			}
+			var n int
+			n, err = c.conn.Read(buf)
+			if err != nil {
+				break
+			}
+			reply := buf[:n]
+			// replies to earlier attempts or requests
+			if n < replyHeaderLen || reply[1] != pktTypeReply ||
+				binary.BigEndian.Uint32(reply[16:20]) != c.sequence {
+				continue
+			}
+			return parseReply(reply, rpy)
+		}
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("no reply from chronyd after %d attempts: %s", c.attempts, err)
+}
+
+func parseReply(reply []byte, rpy replyType) ([]byte, error) {
+	status := binary.BigEndian.Uint16(reply[8:10])
+	if reply[0] != protocolVersion && status != statusBadPktVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d", reply[0])
+	}
+	if status != statusSuccess {
+		text, ok := statusText[status]
+		if !ok {
+			text = fmt.Sprintf("status %d", status)
+		}
+		return nil, &statusError{status: status, text: text}
+	}
+	if code := binary.BigEndian.Uint16(reply[6:8]); code != rpy.code {
+		return nil, fmt.Errorf("unexpected reply %d instead of %d", code, rpy.code)
+	}
+	if len(reply) < replyHeaderLen+rpy.dataLen {
+		return nil, fmt.Errorf("short reply of %d bytes", len(reply))
+	}
+	return reply[replyHeaderLen:], nil
+}
+
+type statusError struct {
+	status uint16
+	text   string
+}
+
+func (e *statusError) Error() string {
+	return "chronyd: " + e.text
+}
+
+type tracking struct {
+	refID             uint32
+	ipAddr            net.IP
+	stratum           uint16
+	leapStatus        uint16
+	currentCorrection float64
+	lastOffset        float64
+	rmsOffset         float64
+	freqPPM           float64
+	residFreqPPM      float64
+	skewPPM           float64
+	rootDelay         float64
+	rootDispersion    float64
+	updateInterval    float64
+}
+
+func (c *cmdmon) tracking() (*tracking, error) {
+	b, err := c.request(reqTracking, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tracking{
+		refID:             binary.BigEndian.Uint32(b[0:4]),
+		ipAddr:            parseIPAddr(b[4:24]),
+		stratum:           binary.BigEndian.Uint16(b[24:26]),
+		leapStatus:        binary.BigEndian.Uint16(b[26:28]),
+		currentCorrection: parseFloat(b[40:44]),
+		lastOffset:        parseFloat(b[44:48]),
+		rmsOffset:         parseFloat(b[48:52]),
+		freqPPM:           parseFloat(b[52:56]),
+		residFreqPPM:      parseFloat(b[56:60]),
+		skewPPM:           parseFloat(b[60:64]),
+		rootDelay:         parseFloat(b[64:68]),
+		rootDispersion:    parseFloat(b[68:72]),
+		updateInterval:    parseFloat(b[72:76]),
+	}, nil
+}
+
+func (c *cmdmon) nSources() (int, error) {
+	b, err := c.request(reqNSources, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(b[0:4])), nil
+}
+
+type sourceData struct {
+	ipAddr         net.IP
+	refID          uint32
+	poll           int16
+	stratum        uint16
+	state          uint16
+	mode           uint16
+	reachability   uint16
+	sinceSample    uint32
+	origLatestMeas float64
+	latestMeas     float64
+	latestMeasErr  float64
+}
+
+func (c *cmdmon) sourceData(index int) (*sourceData, error) {
+	b, err := c.request(reqSourceData, indexData(index))
+	if err != nil {
+		return nil, err
+	}
+	return &sourceData{
+		ipAddr: parseIPAddr(b[0:20]),
+		// reference clocks have their reference id instead of an address
+		refID:          binary.BigEndian.Uint32(b[0:4]),
+		poll:           int16(binary.BigEndian.Uint16(b[20:22])),
+		stratum:        binary.BigEndian.Uint16(b[22:24]),
+		state:          binary.BigEndian.Uint16(b[24:26]),
+		mode:           binary.BigEndian.Uint16(b[26:28]),
+		reachability:   binary.BigEndian.Uint16(b[30:32]),
+		sinceSample:    binary.BigEndian.Uint32(b[32:36]),
+		origLatestMeas: parseFloat(b[36:40]),
+		latestMeas:     parseFloat(b[40:44]),
+		latestMeasErr:  parseFloat(b[44:48]),
+	}, nil
+}
+
+type sourcestats struct {
+	nSamples     uint32
+	nRuns        uint32
+	spanSeconds  uint32
+	stdDev       float64
+	residFreqPPM float64
+	skewPPM      float64
+	estOffset    float64
+	estOffsetErr float64
+}
+
+func (c *cmdmon) sourcestats(index int) (*sourcestats, error) {
+	b, err := c.request(reqSourcestats, indexData(index))
+	if err != nil {
+		return nil, err
+	}
+	return &sourcestats{
+		nSamples:     binary.BigEndian.Uint32(b[24:28]),
+		nRuns:        binary.BigEndian.Uint32(b[28:32]),
+		spanSeconds:  binary.BigEndian.Uint32(b[32:36]),
+		stdDev:       parseFloat(b[36:40]),
+		residFreqPPM: parseFloat(b[40:44]),
+		skewPPM:      parseFloat(b[44:48]),
+		estOffset:    parseFloat(b[48:52]),
+		estOffsetErr: parseFloat(b[52:56]),
+	}, nil
+}
+
+func indexData(index int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b[0:4], uint32(index))
+	return b
+}
+
+// parseIPAddr parses an IPAddr, the address is followed by the family and
+// padding.
+func parseIPAddr(b []byte) net.IP {
+	switch binary.BigEndian.Uint16(b[16:18]) {
+	case 1:
+		return net.IP(append([]byte(nil), b[0:4]...))
+	case 2:
+		return net.IP(append([]byte(nil), b[0:16]...))
+	}
+	return nil
+}
+
+// parseFloat decodes the floating point format of chrony, a 7 bit signed
+// exponent followed by a 25 bit signed coefficient.
+func parseFloat(b []byte) float64 {
+	const expBits, coefBits = 7, 25
+
+	x := binary.BigEndian.Uint32(b)
+	exp := int32(x >> coefBits)
+	if exp >= 1<<(expBits-1) {
+		exp -= 1 << expBits
+	}
+	exp -= coefBits
+
+	coef := int32(x % (1 << coefBits))
+	if coef >= 1<<(coefBits-1) {
+		coef -= 1 << coefBits
+	}
+	return float64(coef) * math.Pow(2, float64(exp))
+}
+
+// refIDString formats the reference id of reference clocks like chronyc,
+// e.g. GPS or PPS.
+func refIDString(id uint32) string {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, id)
+	return strings.TrimRight(string(b), "\x00 ")
+}
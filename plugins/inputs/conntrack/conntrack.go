@@ -3,6 +3,7 @@
 package conntrack
 
 import (
+	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -15,9 +16,11 @@ import (
 )
 
 type Conntrack struct {
-	Path  string
-	Dirs  []string
-	Files []string
+	Path        string
+	Dirs        []string
+	Files       []string
+	CountStates bool     `toml:"count_states"`
+	Tables      []string `toml:"tables"`
 }
 
 const (
@@ -36,6 +39,11 @@ var dfltFiles = []string{
 	"nf_conntrack_max",
 }
 
+var dfltTables = []string{
+	"/proc/net/nf_conntrack",
+	"/proc/net/ip_conntrack",
+}
+
 func (c *Conntrack) setDefaults() {
 	if len(c.Dirs) == 0 {
 		c.Dirs = dfltDirs
@@ -44,6 +52,10 @@ func (c *Conntrack) setDefaults() {
 	if len(c.Files) == 0 {
 		c.Files = dfltFiles
 	}
+
+	if len(c.Tables) == 0 {
+		c.Tables = dfltTables
+	}
 }
 
 func (c *Conntrack) Description() string {
@@ -63,6 +75,14 @@ var sampleConfig = `
    ## Directories to search within for the conntrack files above.
    ## Missing directrories will be ignored.
    dirs = ["/proc/sys/net/ipv4/netfilter","/proc/sys/net/netfilter"]
+
+   ## Count the entries of the conntrack table by protocol and state. The
+   ## whole table is read on each interval, which is expensive for tables
+   ## with millions of entries.
+   # count_states = false
+
+   ## Superset of the conntrack table files, the first one found is read.
+   # tables = ["/proc/net/nf_conntrack","/proc/net/ip_conntrack"]
 `
 
 func (c *Conntrack) SampleConfig() string {
@@ -111,9 +131,70 @@ func (c *Conntrack) Gather(acc telegraf.Accumulator) error {
 	}
 
 	acc.AddFields(inputName, fields, nil)
+
+	if c.CountStates {
+		if err := c.gatherStates(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
 	return nil
 }
 
+type stateKey struct {
+	protocol string
+	state    string
+}
+
+// gatherStates counts the entries of the first conntrack table found by
+// protocol and state, the entries look like
+//   ipv4     2 tcp      6 431999 ESTABLISHED src=10.0.0.1 dst=10.0.0.2 ...
+// in nf_conntrack and, without the layer 3 protocol, like
+//   tcp      6 431999 ESTABLISHED src=10.0.0.1 dst=10.0.0.2 ...
+// in ip_conntrack. Only connection oriented protocols have a state.
+func (c *Conntrack) gatherStates(acc telegraf.Accumulator) error {
+	for _, table := range c.Tables {
+		f, err := os.Open(table)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("E! failed to open file '%s': %v", table, err)
+		}
+		defer f.Close()
+
+		counts := make(map[stateKey]int64)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			cols := strings.Fields(scanner.Text())
+			if len(cols) > 1 && (cols[0] == "ipv4" || cols[0] == "ipv6") {
+				cols = cols[2:]
+			}
+			if len(cols) < 3 {
+				continue
+			}
+
+			key := stateKey{protocol: cols[0]}
+			if len(cols) > 3 && !strings.Contains(cols[3], "=") && !strings.HasPrefix(cols[3], "[") {
+				key.state = cols[3]
+			}
+			counts[key]++
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("E! failed to read file '%s': %v", table, err)
+		}
+
+		for key, count := range counts {
+			tags := map[string]string{"protocol": key.protocol}
+			if key.state != "" {
+				tags["state"] = key.state
+			}
+			acc.AddFields(inputName+"_entries", map[string]interface{}{"count": count}, tags)
+		}
+		return nil
+	}
+	return fmt.Errorf("Conntrack input failed to find a conntrack table in %v", c.Tables)
+}
+
 func init() {
 	inputs.Add(inputName, func() telegraf.Input { return &Conntrack{} })
 }
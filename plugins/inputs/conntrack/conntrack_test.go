@@ -18,6 +18,49 @@ func restoreDflts(savedFiles, savedDirs []string) {
 	dfltDirs = savedDirs
 }
 
+const nfConntrack = `ipv4     2 tcp      6 431999 ESTABLISHED src=10.0.0.2 dst=10.0.0.1 sport=52058 dport=22 src=10.0.0.1 dst=10.0.0.2 sport=22 dport=52058 [ASSURED] mark=0 zone=0 use=2
+ipv4     2 tcp      6 86 TIME_WAIT src=10.0.0.2 dst=93.184.216.34 sport=40348 dport=80 src=93.184.216.34 dst=10.0.0.2 sport=80 dport=40348 [ASSURED] mark=0 zone=0 use=2
+ipv6     10 tcp      6 431955 ESTABLISHED src=fd00::2 dst=fd00::1 sport=40438 dport=443 src=fd00::1 dst=fd00::2 sport=443 dport=40438 [ASSURED] mark=0 zone=0 use=2
+ipv4     2 udp      17 22 src=10.0.0.2 dst=10.0.0.53 sport=35588 dport=53 src=10.0.0.53 dst=10.0.0.2 sport=53 dport=35588 mark=0 zone=0 use=2
+ipv4     2 udp      17 171 src=10.0.0.2 dst=10.0.0.123 sport=123 dport=123 [UNREPLIED] src=10.0.0.123 dst=10.0.0.2 sport=123 dport=123 mark=0 zone=0 use=2
+ipv4     2 icmp     1 29 src=10.0.0.2 dst=10.0.0.1 type=8 code=0 id=1718 src=10.0.0.1 dst=10.0.0.2 type=0 code=0 id=1718 mark=0 zone=0 use=2
+`
+
+func TestCountStates(t *testing.T) {
+	defer restoreDflts(dfltFiles, dfltDirs)
+	tmpdir, err := ioutil.TempDir("", "tmp1")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	cntFile := path.Join(tmpdir, "nf_conntrack_count")
+	table := path.Join(tmpdir, "nf_conntrack")
+	ioutil.WriteFile(cntFile, []byte("6"), 0660)
+	ioutil.WriteFile(table, []byte(nfConntrack), 0660)
+
+	dfltDirs = []string{tmpdir}
+	dfltFiles = []string{"nf_conntrack_count"}
+	c := &Conntrack{
+		CountStates: true,
+		Tables:      []string{path.Join(tmpdir, "missing"), table},
+	}
+	acc := &testutil.Accumulator{}
+
+	assert.NoError(t, acc.GatherError(c.Gather))
+	acc.AssertContainsTaggedFields(t, inputName+"_entries",
+		map[string]interface{}{"count": int64(2)},
+		map[string]string{"protocol": "tcp", "state": "ESTABLISHED"})
+	acc.AssertContainsTaggedFields(t, inputName+"_entries",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"protocol": "tcp", "state": "TIME_WAIT"})
+	acc.AssertContainsTaggedFields(t, inputName+"_entries",
+		map[string]interface{}{"count": int64(2)},
+		map[string]string{"protocol": "udp"})
+	acc.AssertContainsTaggedFields(t, inputName+"_entries",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"protocol": "icmp"})
+	assert.Equal(t, uint64(5), acc.NMetrics())
+}
+
 func TestNoFilesFound(t *testing.T) {
 	defer restoreDflts(dfltFiles, dfltDirs)
 
@@ -116,9 +116,11 @@ func NewClusterClient(
 	timeout time.Duration,
 	maxConns int,
 	tlsConfig *tls.Config,
+	proxy func(*http.Request) (*url.URL, error),
 ) *ClusterClient {
 	httpClient := &http.Client{
 		Transport: &http.Transport{
+			Proxy:           proxy,
 			MaxIdleConns:    maxConns,
 			TLSClientConfig: tlsConfig,
 		},
@@ -80,7 +80,7 @@ func TestLogin(t *testing.T) {
 				AccountID:  "telegraf",
 				PrivateKey: key,
 			}
-			client := NewClusterClient(u, defaultResponseTimeout, 1, nil)
+			client := NewClusterClient(u, defaultResponseTimeout, 1, nil, nil)
 			auth, err := client.Login(ctx, sa)
 
 			require.Equal(t, tt.expectedError, err)
@@ -150,7 +150,7 @@ func TestGetSummary(t *testing.T) {
 			require.NoError(t, err)
 
 			ctx := context.Background()
-			client := NewClusterClient(u, defaultResponseTimeout, 1, nil)
+			client := NewClusterClient(u, defaultResponseTimeout, 1, nil, nil)
 			summary, err := client.GetSummary(ctx)
 
 			require.Equal(t, tt.expectedError, err)
@@ -192,7 +192,7 @@ func TestGetNodeMetrics(t *testing.T) {
 			require.NoError(t, err)
 
 			ctx := context.Background()
-			client := NewClusterClient(u, defaultResponseTimeout, 1, nil)
+			client := NewClusterClient(u, defaultResponseTimeout, 1, nil, nil)
 			m, err := client.GetNodeMetrics(ctx, "foo")
 
 			require.Equal(t, tt.expectedError, err)
@@ -234,7 +234,7 @@ func TestGetContainerMetrics(t *testing.T) {
 			require.NoError(t, err)
 
 			ctx := context.Background()
-			client := NewClusterClient(u, defaultResponseTimeout, 1, nil)
+			client := NewClusterClient(u, defaultResponseTimeout, 1, nil, nil)
 			m, err := client.GetContainerMetrics(ctx, "foo", "bar")
 
 			require.Equal(t, tt.expectedError, err)
@@ -0,0 +1,232 @@
+// +build linux
+
+package ethtool
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/safchain/ethtool"
+	"github.com/vishvananda/netns"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// handle is the part of the ethtool API used by the plugin, bound to the
+// network namespace it was opened in.
+type handle interface {
+	Interfaces() []net.Interface
+	DriverName(intf string) (string, error)
+	Stats(intf string) (map[string]uint64, error)
+	CmdGet(ecmd *ethtool.EthtoolCmd, intf string) (uint32, error)
+	LinkState(intf string) (uint32, error)
+	Close()
+}
+
+// nsHandle is an ethtool socket with the interfaces of its namespace.
+type nsHandle struct {
+	*ethtool.Ethtool
+	interfaces []net.Interface
+}
+
+func (h *nsHandle) Interfaces() []net.Interface {
+	return h.interfaces
+}
+
+type Ethtool struct {
+	InterfaceInclude []string `toml:"interface_include"`
+	InterfaceExclude []string `toml:"interface_exclude"`
+	Namespaces       []string `toml:"namespaces"`
+
+	filter filter.Filter
+	open   func(namespace string) (handle, error)
+}
+
+var sampleConfig = `
+  ## List of interfaces to pull metrics for, globs are supported
+  # interface_include = ["eth0"]
+
+  ## List of interfaces to ignore when pulling metrics.
+  # interface_exclude = ["eth1"]
+
+  ## Named network namespaces, as created by "ip netns", whose interfaces
+  ## are gathered in addition to the ones of the host.
+  # namespaces = []
+`
+
+func (e *Ethtool) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Ethtool) Description() string {
+	return "Returns ethtool statistics for given interfaces"
+}
+
+func (e *Ethtool) Gather(acc telegraf.Accumulator) error {
+	if e.filter == nil {
+		f, err := filter.NewIncludeExcludeFilter(e.InterfaceInclude, e.InterfaceExclude)
+		if err != nil {
+			return err
+		}
+		e.filter = f
+	}
+
+	namespaces := append([]string{""}, e.Namespaces...)
+	for _, namespace := range namespaces {
+		h, err := e.open(namespace)
+		if err != nil {
+			if namespace == "" {
+				return err
+			}
+			acc.AddError(fmt.Errorf("namespace %s: %s", namespace, err))
+			continue
+		}
+		e.gatherNamespace(acc, h, namespace)
+		h.Close()
+	}
+	return nil
+}
+
+func (e *Ethtool) gatherNamespace(acc telegraf.Accumulator, h handle, namespace string) {
+	for _, iface := range h.Interfaces() {
+		if iface.Flags&net.FlagLoopback != 0 || !e.filter.Match(iface.Name) {
+			continue
+		}
+		if err := gatherInterface(acc, h, iface, namespace); err != nil {
+			acc.AddError(fmt.Errorf("interface %s: %s", iface.Name, err))
+		}
+	}
+}
+
+func gatherInterface(acc telegraf.Accumulator, h handle, iface net.Interface, namespace string) error {
+	driver, err := h.DriverName(iface.Name)
+	if err != nil {
+		// virtual interfaces without driver information
+		if err == syscall.EOPNOTSUPP {
+			return nil
+		}
+		return err
+	}
+
+	tags := map[string]string{
+		"interface": iface.Name,
+		"driver":    driver,
+	}
+	if namespace != "" {
+		tags["namespace"] = namespace
+	}
+
+	fields := map[string]interface{}{
+		"interface_up": iface.Flags&net.FlagUp != 0,
+	}
+
+	// drivers without statistics, like the bridge driver, don't support
+	// the string set of the statistics
+	stats, err := h.Stats(iface.Name)
+	if err != nil && err != syscall.EOPNOTSUPP {
+		return err
+	}
+	for k, v := range stats {
+		fields[strings.TrimSpace(k)] = v
+	}
+
+	if link, err := h.LinkState(iface.Name); err == nil {
+		fields["link"] = int(link)
+	}
+
+	var ecmd ethtool.EthtoolCmd
+	if speed, err := h.CmdGet(&ecmd, iface.Name); err == nil {
+		// the speed and duplex are unknown without link
+		if speed != 0 && speed != math.MaxUint32 {
+			fields["speed"] = int64(speed)
+		}
+		if ecmd.Duplex <= 1 {
+			fields["duplex"] = int(ecmd.Duplex)
+		}
+		fields["autoneg"] = int(ecmd.Autoneg)
+	}
+
+	acc.AddFields("ethtool", fields, tags)
+	return nil
+}
+
+func newHandle() (handle, error) {
+	e, err := ethtool.NewEthtool()
+	if err != nil {
+		return nil, err
+	}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		e.Close()
+		return nil, err
+	}
+	return &nsHandle{Ethtool: e, interfaces: interfaces}, nil
+}
+
+func open(namespace string) (handle, error) {
+	if namespace == "" {
+		return newHandle()
+	}
+
+	type result struct {
+		h   handle
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		h, err := openNamespace(namespace)
+		done <- result{h, err}
+	}()
+	r := <-done
+	return r.h, r.err
+}
+
+// openNamespace opens a handle in the given namespace, the socket and the
+// interface list belong to the namespace of the thread creating them. The
+// thread stays locked if it can't return to its namespace so it isn't
+// reused by other goroutines.
+func openNamespace(namespace string) (handle, error) {
+	runtime.LockOSThread()
+
+	orig, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	defer orig.Close()
+
+	ns, err := netns.GetFromName(namespace)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	defer ns.Close()
+
+	if err := netns.Set(ns); err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	h, err := newHandle()
+	if err := netns.Set(orig); err != nil {
+		if h != nil {
+			h.Close()
+		}
+		return nil, fmt.Errorf("restoring network namespace: %s", err)
+	}
+	runtime.UnlockOSThread()
+	return h, err
+}
+
+func init() {
+	inputs.Add("ethtool", func() telegraf.Input {
+		return &Ethtool{
+			open: open,
+		}
+	})
+}
@@ -0,0 +1,155 @@
+// +build linux
+
+package ethtool
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/safchain/ethtool"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHandle struct {
+	interfaces []net.Interface
+	closed     bool
+}
+
+func (f *fakeHandle) Interfaces() []net.Interface {
+	return f.interfaces
+}
+
+func (f *fakeHandle) DriverName(intf string) (string, error) {
+	switch intf {
+	case "eth0", "eth1":
+		return "ixgbe", nil
+	case "br0":
+		return "bridge", nil
+	}
+	return "", syscall.EOPNOTSUPP
+}
+
+func (f *fakeHandle) Stats(intf string) (map[string]uint64, error) {
+	if intf == "br0" {
+		return nil, syscall.EOPNOTSUPP
+	}
+	return map[string]uint64{
+		"rx_missed_errors":      12,
+		"rx_fifo_errors":        3,
+		"tx_queue_0_restart":    1,
+		"     rx_queue_0_drops": 7,
+	}, nil
+}
+
+func (f *fakeHandle) CmdGet(ecmd *ethtool.EthtoolCmd, intf string) (uint32, error) {
+	if intf == "eth1" {
+		// no link
+		ecmd.Duplex = 0xff
+		ecmd.Autoneg = 1
+		return 0xffffffff, nil
+	}
+	ecmd.Duplex = 1
+	ecmd.Autoneg = 1
+	return 10000, nil
+}
+
+func (f *fakeHandle) LinkState(intf string) (uint32, error) {
+	if intf == "eth1" {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func (f *fakeHandle) Close() {
+	f.closed = true
+}
+
+func testInterfaces() []net.Interface {
+	return []net.Interface{
+		{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+		{Name: "eth0", Flags: net.FlagUp},
+		{Name: "eth1"},
+		{Name: "br0", Flags: net.FlagUp},
+		{Name: "tun0", Flags: net.FlagUp},
+	}
+}
+
+func TestGather(t *testing.T) {
+	handles := map[string]*fakeHandle{}
+	e := &Ethtool{
+		InterfaceExclude: []string{"eth1"},
+		Namespaces:       []string{"blue", "missing"},
+		open: func(namespace string) (handle, error) {
+			if namespace == "missing" {
+				return nil, fmt.Errorf("no such file or directory")
+			}
+			h := &fakeHandle{interfaces: testInterfaces()}
+			handles[namespace] = h
+			return h, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+
+	fields := map[string]interface{}{
+		"interface_up":       true,
+		"rx_missed_errors":   uint64(12),
+		"rx_fifo_errors":     uint64(3),
+		"tx_queue_0_restart": uint64(1),
+		"rx_queue_0_drops":   uint64(7),
+		"link":               1,
+		"speed":              int64(10000),
+		"duplex":             1,
+		"autoneg":            1,
+	}
+	acc.AssertContainsTaggedFields(t, "ethtool", fields,
+		map[string]string{"interface": "eth0", "driver": "ixgbe"})
+	acc.AssertContainsTaggedFields(t, "ethtool", fields,
+		map[string]string{"interface": "eth0", "driver": "ixgbe", "namespace": "blue"})
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{
+			"interface_up": true,
+			"link":         1,
+			"speed":        int64(10000),
+			"duplex":       1,
+			"autoneg":      1,
+		},
+		map[string]string{"interface": "br0", "driver": "bridge"})
+
+	assert.Equal(t, uint64(4), acc.NMetrics())
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "missing")
+	assert.True(t, handles[""].closed)
+	assert.True(t, handles["blue"].closed)
+}
+
+func TestGatherNoLink(t *testing.T) {
+	e := &Ethtool{
+		InterfaceInclude: []string{"eth*"},
+		open: func(namespace string) (handle, error) {
+			return &fakeHandle{interfaces: testInterfaces()}, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(e.Gather))
+
+	assert.Equal(t, uint64(2), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{
+			"interface_up":       false,
+			"rx_missed_errors":   uint64(12),
+			"rx_fifo_errors":     uint64(3),
+			"tx_queue_0_restart": uint64(1),
+			"rx_queue_0_drops":   uint64(7),
+			"link":               0,
+			"autoneg":            1,
+		},
+		map[string]string{"interface": "eth1", "driver": "ixgbe"})
+}
@@ -34,6 +34,28 @@ const sampleConfig = `
   ## measurement name suffix (for separating different commands)
   name_suffix = "_mycollector"
 
+  ## Run the commands as this user and group, telegraf must run as root.
+  # user = "nobody"
+  # group = "nogroup"
+
+  ## Only pass these environment variables to the commands, by default they
+  ## get the environment of telegraf.
+  # environment = ["PATH=/usr/bin:/bin", "LANG=C"]
+
+  ## Working directory of the commands, by default that of telegraf.
+  # working_directory = "/var/lib/telegraf"
+
+  ## Resource limits of each command, only on linux.
+  # limit_cpu_seconds = 10
+  # limit_memory_bytes = 268435456
+  # limit_open_files = 64
+  ## limit_processes limits the processes of the user of the command.
+  # limit_processes = 16
+
+  ## Run the commands in new linux namespaces, telegraf must run as root.
+  ## Supported namespaces are "ipc", "mount", "net", "pid" and "uts".
+  # namespaces = ["ipc", "net"]
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -47,6 +69,7 @@ type Exec struct {
 	Commands []string
 	Command  string
 	Timeout  internal.Duration
+	Sandbox
 
 	parser parsers.Parser
 
@@ -105,7 +128,11 @@ func (c CommandRunner) Run(
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr
 
-	if err := internal.RunTimeout(cmd, e.Timeout.Duration); err != nil {
+	err = e.Sandbox.start(cmd)
+	if err == nil {
+		err = internal.WaitTimeout(cmd, e.Timeout.Duration)
+	}
+	if err != nil {
 		switch e.parser.(type) {
 		case *nagios.NagiosParser:
 			AddNagiosState(err, acc)
@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"os/exec"
+)
+
+// Sandbox are the options restricting the commands, they are named after
+// their TOML keys as the fields of an embedded struct.
+type Sandbox struct {
+	User             string
+	Group            string
+	Environment      []string
+	WorkingDirectory string
+
+	LimitCpuSeconds  uint64
+	LimitMemoryBytes uint64
+	LimitOpenFiles   uint64
+	LimitProcesses   uint64
+
+	Namespaces []string
+}
+
+// prepare applies the options to the command before it is started.
+func (s *Sandbox) prepare(cmd *exec.Cmd) error {
+	if s.Environment != nil {
+		cmd.Env = s.Environment
+	}
+	cmd.Dir = s.WorkingDirectory
+	if err := s.setCredential(cmd); err != nil {
+		return err
+	}
+	return s.setNamespaces(cmd)
+}
+
+// hasLimits reports whether a resource limit is set.
+func (s *Sandbox) hasLimits() bool {
+	return s.LimitCpuSeconds != 0 || s.LimitMemoryBytes != 0 ||
+		s.LimitOpenFiles != 0 || s.LimitProcesses != 0
+}
+
+// start starts the command and sets its resource limits, the command is
+// killed if they can't be set.
+func (s *Sandbox) start(cmd *exec.Cmd) error {
+	if err := s.prepare(cmd); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := s.setLimits(cmd.Process.Pid); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package exec
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+var namespaces = map[string]uintptr{
+	"ipc":   syscall.CLONE_NEWIPC,
+	"mount": syscall.CLONE_NEWNS,
+	"net":   syscall.CLONE_NEWNET,
+	"pid":   syscall.CLONE_NEWPID,
+	"uts":   syscall.CLONE_NEWUTS,
+}
+
+// setNamespaces runs the command in new namespaces.
+func (s *Sandbox) setNamespaces(cmd *exec.Cmd) error {
+	if len(s.Namespaces) == 0 {
+		return nil
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	for _, name := range s.Namespaces {
+		flag, ok := namespaces[name]
+		if !ok {
+			return fmt.Errorf("exec: unsupported namespace %q, expected ipc, mount, net, pid or uts", name)
+		}
+		cmd.SysProcAttr.Cloneflags |= flag
+	}
+	return nil
+}
+
+// setLimits sets the resource limits of the started process, the soft and
+// hard limits are the same so the process can't raise them.
+func (s *Sandbox) setLimits(pid int) error {
+	for _, l := range []struct {
+		name     string
+		resource int
+		value    uint64
+	}{
+		{"limit_cpu_seconds", unix.RLIMIT_CPU, s.LimitCpuSeconds},
+		{"limit_memory_bytes", unix.RLIMIT_AS, s.LimitMemoryBytes},
+		{"limit_open_files", unix.RLIMIT_NOFILE, s.LimitOpenFiles},
+		{"limit_processes", unix.RLIMIT_NPROC, s.LimitProcesses},
+	} {
+		if l.value == 0 {
+			continue
+		}
+		rlimit := unix.Rlimit{Cur: l.value, Max: l.value}
+		_, _, errno := unix.Syscall6(unix.SYS_PRLIMIT64, uintptr(pid),
+			uintptr(l.resource), uintptr(unsafe.Pointer(&rlimit)), 0, 0, 0)
+		if errno != 0 {
+			return fmt.Errorf("exec: unable to set %s: %s", l.name, errno)
+		}
+	}
+	return nil
+}
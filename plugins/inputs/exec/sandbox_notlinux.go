@@ -0,0 +1,22 @@
+// +build !linux
+
+package exec
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func (s *Sandbox) setNamespaces(cmd *exec.Cmd) error {
+	if len(s.Namespaces) > 0 {
+		return fmt.Errorf("exec: namespaces are only supported on linux")
+	}
+	return nil
+}
+
+func (s *Sandbox) setLimits(pid int) error {
+	if s.hasLimits() {
+		return fmt.Errorf("exec: resource limits are only supported on linux")
+	}
+	return nil
+}
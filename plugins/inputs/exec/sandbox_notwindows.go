@@ -0,0 +1,52 @@
+// +build !windows
+
+package exec
+
+import (
+	"fmt"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// setCredential runs the command as the user and group, the group defaults
+// to the primary group of the user and the user to that of telegraf.
+func (s *Sandbox) setCredential(cmd *exec.Cmd) error {
+	if s.User == "" && s.Group == "" {
+		return nil
+	}
+
+	uid, gid := uint64(syscall.Getuid()), uint64(syscall.Getgid())
+	if s.User != "" {
+		u, err := user.Lookup(s.User)
+		if err != nil {
+			return fmt.Errorf("exec: unable to find user %q: %s", s.User, err)
+		}
+		if uid, err = strconv.ParseUint(u.Uid, 10, 32); err != nil {
+			return fmt.Errorf("exec: invalid uid of user %q: %s", s.User, err)
+		}
+		if gid, err = strconv.ParseUint(u.Gid, 10, 32); err != nil {
+			return fmt.Errorf("exec: invalid gid of user %q: %s", s.User, err)
+		}
+	}
+	if s.Group != "" {
+		g, err := user.LookupGroup(s.Group)
+		if err != nil {
+			return fmt.Errorf("exec: unable to find group %q: %s", s.Group, err)
+		}
+		if gid, err = strconv.ParseUint(g.Gid, 10, 32); err != nil {
+			return fmt.Errorf("exec: invalid gid of group %q: %s", s.Group, err)
+		}
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid: uint32(uid),
+		Gid: uint32(gid),
+		// drop the supplementary groups of telegraf
+		Groups: []uint32{},
+	}
+	return nil
+}
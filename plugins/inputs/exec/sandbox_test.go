@@ -0,0 +1,53 @@
+// +build linux
+
+package exec
+
+import (
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runSandboxed(t *testing.T, sandbox Sandbox, command string) ([]byte, error) {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	e := NewExec()
+	e.Sandbox = sandbox
+	e.SetParser(parser)
+	return e.runner.Run(e, command, &testutil.Accumulator{})
+}
+
+func TestSandboxEnvironmentAndDirectory(t *testing.T) {
+	require.NoError(t, os.Setenv("TELEGRAF_SANDBOX_TEST", "telegraf"))
+	defer os.Unsetenv("TELEGRAF_SANDBOX_TEST")
+
+	out, err := runSandboxed(t, Sandbox{
+		Environment:      []string{"FOO=bar"},
+		WorkingDirectory: "/",
+	}, `/bin/sh -c 'echo $FOO $TELEGRAF_SANDBOX_TEST; pwd'`)
+	require.NoError(t, err)
+	assert.Equal(t, "bar\n/\n", string(out))
+}
+
+func TestSandboxLimits(t *testing.T) {
+	out, err := runSandboxed(t, Sandbox{
+		LimitOpenFiles: 16,
+	}, `/bin/sh -c 'sleep 0.1; ulimit -n'`)
+	require.NoError(t, err)
+	assert.Equal(t, "16\n", string(out))
+}
+
+func TestSandboxErrors(t *testing.T) {
+	for _, s := range []Sandbox{
+		{User: "telegraf-missing-user"},
+		{Group: "telegraf-missing-group"},
+		{Namespaces: []string{"time"}},
+	} {
+		_, err := runSandboxed(t, s, "/bin/true")
+		assert.Error(t, err, "%+v", s)
+	}
+}
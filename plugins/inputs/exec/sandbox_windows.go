@@ -0,0 +1,13 @@
+package exec
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func (s *Sandbox) setCredential(cmd *exec.Cmd) error {
+	if s.User != "" || s.Group != "" {
+		return fmt.Errorf("exec: user and group are not supported on windows")
+	}
+	return nil
+}
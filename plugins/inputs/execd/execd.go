@@ -0,0 +1,148 @@
+package execd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const sampleConfig = `
+  ## Program to run as a daemon, with its arguments.
+  command = ["/usr/local/bin/telegraf-collector", "--flag", "value"]
+
+  ## Environment variables added to those of telegraf.
+  # environment = ["LANG=C"]
+
+  ## Signal sent to the program on each gather, it writes its metrics to
+  ## stdout when it gets it:
+  ##   "none"    : the program writes metrics on its own schedule
+  ##   "STDIN"   : a newline is written to its stdin
+  ##   "SIGHUP"  : the program gets a SIGHUP
+  ##   "SIGUSR1" : the program gets a SIGUSR1
+  ##   "SIGUSR2" : the program gets a SIGUSR2
+  signal = "none"
+
+  ## Delay before the program is started again when it exits.
+  restart_delay = "10s"
+
+  ## Data format of the lines written by the program, one metric per line.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// maxLineBytes is the longest line read from the program.
+const maxLineBytes = 1 << 20
+
+type Execd struct {
+	Command      []string
+	Environment  []string
+	Signal       string
+	RestartDelay internal.Duration
+
+	process *process.Process
+	acc     telegraf.Accumulator
+	parser  parsers.Parser
+	log     telegraf.Logger
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run a program as a daemon and read the metrics it writes to stdout"
+}
+
+func (e *Execd) SetParser(parser parsers.Parser) {
+	e.parser = parser
+}
+
+func (e *Execd) SetLogger(logger telegraf.Logger) {
+	e.log = logger
+}
+
+func (e *Execd) Start(acc telegraf.Accumulator) error {
+	if _, err := e.signal(); err != nil {
+		return err
+	}
+	e.acc = acc
+	e.process = &process.Process{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		ReadStdout:   e.read,
+		Log:          e.log,
+	}
+	return e.process.Start()
+}
+
+func (e *Execd) Stop() {
+	e.process.Stop()
+}
+
+// signal returns the signal of the Signal option, nil for "none" and
+// "STDIN".
+func (e *Execd) signal() (os.Signal, error) {
+	switch e.Signal {
+	case "", "none", "STDIN":
+		return nil, nil
+	}
+	if sig, ok := signals[e.Signal]; ok {
+		return sig, nil
+	}
+	return nil, fmt.Errorf("unsupported signal %q", e.Signal)
+}
+
+// Gather asks the program for its metrics.
+func (e *Execd) Gather(acc telegraf.Accumulator) error {
+	if e.Signal == "STDIN" {
+		_, err := e.process.Write([]byte("\n"))
+		return err
+	}
+	sig, _ := e.signal()
+	if sig == nil {
+		return nil
+	}
+	return e.process.Signal(sig)
+}
+
+// read parses the lines written by the program.
+func (e *Execd) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		metrics, err := e.parser.Parse(scanner.Bytes())
+		if err != nil {
+			e.acc.AddError(fmt.Errorf("parsing the output of %s: %s", e.Command[0], err))
+			continue
+		}
+		if len(metrics) > 0 {
+			e.acc.AddMetrics(metrics)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		e.acc.AddError(fmt.Errorf("reading the output of %s: %s", e.Command[0], err))
+	}
+}
+
+func init() {
+	inputs.Add("execd", func() telegraf.Input {
+		return &Execd{
+			Signal:       "none",
+			RestartDelay: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
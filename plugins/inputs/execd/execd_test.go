@@ -0,0 +1,75 @@
+// +build !windows
+
+package execd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExecd(t *testing.T, signal string, command ...string) *Execd {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	e := &Execd{
+		Command:      command,
+		Signal:       signal,
+		RestartDelay: internal.Duration{Duration: 10 * time.Millisecond},
+		log:          testutil.Logger{Name: "inputs.execd"},
+	}
+	e.SetParser(parser)
+	return e
+}
+
+func TestExecdStdinSignal(t *testing.T) {
+	e := newExecd(t, "STDIN", "sh", "-c",
+		`n=0; while read line; do n=$((n+1)); echo "counter value=${n}i"; done`)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	require.NoError(t, e.Gather(acc))
+	require.NoError(t, e.Gather(acc))
+	acc.Wait(2)
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Equal(t, int64(1), acc.Metrics[0].Fields["value"])
+	assert.Equal(t, int64(2), acc.Metrics[1].Fields["value"])
+}
+
+func TestExecdSigHup(t *testing.T) {
+	e := newExecd(t, "SIGHUP", "sh", "-c",
+		`trap 'echo "hup value=1i"' HUP; echo "ready value=1i"; while :; do read line; [ $? -eq 1 ] && exit; done`)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	acc.Wait(1)
+	require.NoError(t, e.Gather(acc))
+	acc.Wait(2)
+	assert.True(t, acc.HasMeasurement("hup"))
+}
+
+func TestExecdRestart(t *testing.T) {
+	e := newExecd(t, "none", "sh", "-c", `echo "run value=1i"; echo "invalid"`)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	// the program exits after writing its metric, it is started again
+	acc.Wait(3)
+	acc.WaitError(1)
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Contains(t, acc.Errors[0].Error(), "parsing the output of sh")
+}
+
+func TestExecdInvalidSignal(t *testing.T) {
+	e := newExecd(t, "SIGFOO", "true")
+	assert.EqualError(t, e.Start(&testutil.Accumulator{}), `unsupported signal "SIGFOO"`)
+}
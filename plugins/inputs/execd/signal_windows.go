@@ -0,0 +1,6 @@
+package execd
+
+import "os"
+
+// the programs can only be signaled through their stdin on windows
+var signals = map[string]os.Signal{}
@@ -0,0 +1,102 @@
+package external
+
+import (
+	"errors"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	ext "github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"golang.org/x/net/context"
+)
+
+const sampleConfig = `
+  ## Plugin program to run, with its arguments.
+  command = ["/usr/local/bin/telegraf-acme-input"]
+
+  ## Environment variables added to those of telegraf.
+  # environment = ["LANG=C"]
+
+  ## TOML configuration of the plugin.
+  config = '''
+    servers = ["localhost:8080"]
+  '''
+
+  ## Delay before the program is started again when it exits.
+  # restart_delay = "10s"
+
+  ## Timeout of each gather of the plugin.
+  # timeout = "5s"
+`
+
+type External struct {
+	Command      []string
+	Environment  []string
+	Config       string
+	RestartDelay internal.Duration
+	Timeout      internal.Duration
+
+	client *ext.Client
+	log    telegraf.Logger
+}
+
+func (e *External) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *External) Description() string {
+	return "Run an input plugin as a separate program over gRPC"
+}
+
+func (e *External) SetLogger(logger telegraf.Logger) {
+	e.log = logger
+}
+
+func (e *External) Start(acc telegraf.Accumulator) error {
+	e.client = &ext.Client{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		Kind:         ext.KindInput,
+		Config:       e.Config,
+		Log:          e.log,
+	}
+	return e.client.Start()
+}
+
+func (e *External) Stop() {
+	e.client.Stop()
+}
+
+func (e *External) Gather(acc telegraf.Accumulator) error {
+	plugin, err := e.client.Plugin()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout.Duration)
+	defer cancel()
+	resp, err := plugin.Gather(ctx, &ext.Empty{})
+	if err != nil {
+		return errors.New(ext.ErrorMessage(err))
+	}
+
+	for _, msg := range resp.Errors {
+		acc.AddError(errors.New(msg))
+	}
+	metrics, err := ext.ToMetrics(resp.Metrics)
+	if err != nil {
+		return err
+	}
+	acc.AddMetrics(metrics)
+	return nil
+}
+
+func init() {
+	inputs.Add("external", func() telegraf.Input {
+		return &External{
+			RestartDelay: internal.Duration{Duration: 10 * time.Second},
+			Timeout:      internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package external
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	ext "github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary serves the test input when it is run by the plugin.
+func TestMain(m *testing.M) {
+	if os.Getenv(ext.MagicCookieKey) == ext.MagicCookieValue {
+		ext.Serve(&testInput{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type testInput struct {
+	Host string
+}
+
+func (i *testInput) SampleConfig() string { return "" }
+func (i *testInput) Description() string  { return "" }
+
+func (i *testInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddGauge("acme", map[string]interface{}{"up": true}, map[string]string{"host": i.Host})
+	return nil
+}
+
+func TestExternalGather(t *testing.T) {
+	e := &External{
+		Command:      []string{os.Args[0]},
+		Config:       `host = "server01"`,
+		RestartDelay: internal.Duration{Duration: time.Second},
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		log:          testutil.Logger{Name: "inputs.external"},
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	require.NoError(t, e.Gather(acc))
+	acc.AssertContainsTaggedFields(t, "acme",
+		map[string]interface{}{"up": true}, map[string]string{"host": "server01"})
+}
@@ -29,9 +29,13 @@ func (s statServer) serverSocket(l net.Listener) {
 			n, _ := c.Read(buf)
 
 			data := buf[:n]
-			if string(data) == "show stat\n" {
+			switch string(data) {
+			case "show stat\n":
 				c.Write([]byte(csvOutputSample))
 				c.Close()
+			case "show stat json\n":
+				c.Write([]byte(jsonOutputSample))
+				c.Close()
 			}
 		}(conn)
 	}
@@ -162,6 +166,122 @@ func TestHaproxyGeneratesMetricsUsingSocket(t *testing.T) {
 	require.NotEmpty(t, acc.Errors)
 }
 
+func TestHaproxyGeneratesMetricsUsingJSONSocket(t *testing.T) {
+	var randomNumber int64
+	binary.Read(rand.Reader, binary.LittleEndian, &randomNumber)
+	sockname := fmt.Sprintf("/tmp/test-haproxy-json%d.sock", randomNumber)
+
+	sock, err := net.Listen("unix", sockname)
+	require.NoError(t, err)
+	defer sock.Close()
+
+	s := statServer{}
+	go s.serverSocket(sock)
+
+	r := &haproxy{
+		Servers: []string{"socket:" + sockname},
+		Format:  "json",
+	}
+
+	var acc testutil.Accumulator
+
+	err = r.Gather(&acc)
+	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
+
+	tags := map[string]string{
+		"server": sockname,
+		"proxy":  "git",
+		"sv":     "www",
+		"type":   "server",
+	}
+
+	fields := HaproxyGetFieldValues()
+	acc.AssertContainsTaggedFields(t, "haproxy", fields, tags)
+	assert.Equal(t, uint64(3), acc.NMetrics())
+}
+
+func TestHaproxyGeneratesMetricsUsingJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "stats/;json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, jsonOutputSample)
+	}))
+	defer ts.Close()
+
+	r := &haproxy{
+		Servers: []string{ts.URL + "/haproxy?stats"},
+		Format:  "json",
+	}
+
+	var acc testutil.Accumulator
+
+	err := r.Gather(&acc)
+	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
+
+	tags := map[string]string{
+		"server": ts.Listener.Addr().String(),
+		"proxy":  "git",
+		"sv":     "BACKEND",
+		"type":   "backend",
+	}
+	acc.AssertContainsTaggedFields(t, "haproxy", map[string]interface{}{
+		"qcur":                uint64(0),
+		"qmax":                uint64(6),
+		"scur":                uint64(0),
+		"smax":                uint64(8),
+		"slim":                uint64(2),
+		"stot":                uint64(14541),
+		"bin":                 uint64(8082393),
+		"bout":                uint64(303747668),
+		"dreq":                uint64(0),
+		"dresp":               uint64(0),
+		"econ":                uint64(2),
+		"eresp":               uint64(21),
+		"wretr":               uint64(0),
+		"wredis":              uint64(0),
+		"status":              "UP",
+		"weight":              uint64(1),
+		"active_servers":      uint64(1),
+		"backup_servers":      uint64(1),
+		"chkdown":             uint64(0),
+		"lastchg":             uint64(5218087),
+		"downtime":            uint64(0),
+		"pid":                 uint64(1),
+		"iid":                 uint64(4),
+		"sid":                 uint64(0),
+		"lbtot":               uint64(9481),
+		"rate":                uint64(0),
+		"rate_max":            uint64(7),
+		"http_response.1xx":   uint64(0),
+		"http_response.2xx":   uint64(5668),
+		"http_response.3xx":   uint64(8710),
+		"http_response.4xx":   uint64(140),
+		"http_response.5xx":   uint64(23),
+		"http_response.other": uint64(0),
+		"req_tot":             uint64(14541),
+		"cli_abort":           uint64(690),
+		"srv_abort":           uint64(0),
+		"comp_in":             uint64(133458298),
+		"comp_out":            uint64(38104818),
+		"comp_byp":            uint64(0),
+		"comp_rsp":            uint64(4379),
+		"lastsess":            int64(1342),
+		"qtime":               uint64(1268),
+		"ctime":               uint64(1),
+		"rtime":               uint64(2908),
+		"ttime":               uint64(4500),
+		"mode":                "http",
+	}, tags)
+
+	r.Format = "xml"
+	require.NoError(t, r.Gather(&acc))
+	require.NotEmpty(t, acc.Errors)
+}
+
 //When not passing server config, we default to localhost
 //We just want to make sure we did request stat from localhost
 func TestHaproxyDefaultGetFromLocalhost(t *testing.T) {
@@ -293,3 +413,168 @@ git,bck,0,0,0,0,2,0,0,0,,0,,0,0,0,0,UP,1,0,1,2,0,5218087,0,,1,4,2,,0,,2,0,,0,L7O
 git,BACKEND,0,6,0,8,2,14541,8082393,303747668,0,0,,2,21,0,0,UP,1,1,1,,0,5218087,0,,1,4,0,,9481,,1,0,,7,,,,0,5668,8710,140,23,0,,,,14541,690,0,133458298,38104818,0,4379,1342,,,1268,1,2908,4500,,,,,,,,,,,,,,http,,,,,,,,
 demo,BACKEND,0,0,1,5,20,24063,7876647,659864417,48,0,,1,0,0,0,UP,0,0,0,,0,5218087,,,1,17,0,,0,,1,1,,26,,,,0,23983,21,0,1,57,,,,24062,111,0,567843278,146884392,0,1083,0,,,2706,0,0,887,,,,,,,,,,,,,,http,,,,,,,,
 `
+
+// A part of the output of "show stat json" of the servers of the git backend
+const jsonOutputSample = `
+[
+[
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"git"}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"www"}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":2,"name":"qcur"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":3,"name":"qmax"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":4,"name":"scur"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":5,"name":"smax"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":6,"name":"slim"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":7,"name":"stot"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":14539}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":8,"name":"bin"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u64","value":5228218}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":9,"name":"bout"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u64","value":303747244}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":11,"name":"dresp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":13,"name":"econ"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":14,"name":"eresp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":21}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":15,"name":"wretr"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":16,"name":"wredis"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"UP"}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":18,"name":"weight"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":19,"name":"act"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":20,"name":"bck"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":21,"name":"chkfail"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":559}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":22,"name":"chkdown"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":84}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":23,"name":"lastchg"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1036557}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":24,"name":"downtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":3352}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":26,"name":"pid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":27,"name":"iid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":28,"name":"sid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":30,"name":"lbtot"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":9481}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":32,"name":"type"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":33,"name":"rate"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":35,"name":"rate_max"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":36,"name":"check_status"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"L7OK"}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":37,"name":"check_code"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":200}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":38,"name":"check_duration"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":3}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":39,"name":"hrsp_1xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":40,"name":"hrsp_2xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":5668}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":41,"name":"hrsp_3xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":8710}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":42,"name":"hrsp_4xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":140}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":43,"name":"hrsp_5xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":44,"name":"hrsp_other"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":49,"name":"cli_abrt"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":690}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":50,"name":"srv_abrt"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":55,"name":"lastsess"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"s32","value":1342}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":56,"name":"last_chk"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"OK"}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":58,"name":"qtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1268}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":59,"name":"ctime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":60,"name":"rtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2908}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":61,"name":"ttime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4500}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":65,"name":"check_desc"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"Layer7 check passed"}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":67,"name":"check_rise"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":68,"name":"check_fall"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":3}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":69,"name":"check_health"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4}},
+{"objType":"Server","proxyId":4,"id":1,"field":{"pos":75,"name":"mode"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"http"}}
+],
+[
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"git"}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"bck"}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":2,"name":"qcur"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":3,"name":"qmax"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":4,"name":"scur"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":5,"name":"smax"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":6,"name":"slim"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":7,"name":"stot"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":8,"name":"bin"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u64","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":9,"name":"bout"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u64","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":11,"name":"dresp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":13,"name":"econ"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":14,"name":"eresp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":15,"name":"wretr"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":16,"name":"wredis"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"UP"}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":18,"name":"weight"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":19,"name":"act"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":20,"name":"bck"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":21,"name":"chkfail"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":22,"name":"chkdown"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":23,"name":"lastchg"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":5218087}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":24,"name":"downtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":26,"name":"pid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":27,"name":"iid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":28,"name":"sid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":30,"name":"lbtot"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":32,"name":"type"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":33,"name":"rate"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":35,"name":"rate_max"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":36,"name":"check_status"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"L7OK"}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":37,"name":"check_code"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":200}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":38,"name":"check_duration"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":39,"name":"hrsp_1xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":40,"name":"hrsp_2xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":41,"name":"hrsp_3xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":42,"name":"hrsp_4xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":43,"name":"hrsp_5xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":44,"name":"hrsp_other"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":49,"name":"cli_abrt"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":50,"name":"srv_abrt"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":55,"name":"lastsess"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"s32","value":-1}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":56,"name":"last_chk"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"OK"}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":58,"name":"qtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":59,"name":"ctime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":60,"name":"rtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":61,"name":"ttime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":65,"name":"check_desc"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"Layer7 check passed"}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":67,"name":"check_rise"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":68,"name":"check_fall"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":3}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":69,"name":"check_health"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4}},
+{"objType":"Server","proxyId":4,"id":2,"field":{"pos":75,"name":"mode"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"http"}}
+],
+[
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"git"}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"BACKEND"}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":2,"name":"qcur"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":3,"name":"qmax"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":6}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":4,"name":"scur"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":5,"name":"smax"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":8}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":6,"name":"slim"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":7,"name":"stot"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":14541}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":8,"name":"bin"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u64","value":8082393}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":9,"name":"bout"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u64","value":303747668}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":10,"name":"dreq"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":11,"name":"dresp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":13,"name":"econ"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":14,"name":"eresp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":21}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":15,"name":"wretr"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":16,"name":"wredis"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"UP"}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":18,"name":"weight"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":19,"name":"act"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":20,"name":"bck"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":22,"name":"chkdown"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":23,"name":"lastchg"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":5218087}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":24,"name":"downtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":26,"name":"pid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":27,"name":"iid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":28,"name":"sid"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":30,"name":"lbtot"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":9481}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":32,"name":"type"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":33,"name":"rate"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":35,"name":"rate_max"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":7}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":39,"name":"hrsp_1xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":40,"name":"hrsp_2xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":5668}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":41,"name":"hrsp_3xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":8710}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":42,"name":"hrsp_4xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":140}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":43,"name":"hrsp_5xx"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":23}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":44,"name":"hrsp_other"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":48,"name":"req_tot"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":14541}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":49,"name":"cli_abrt"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":690}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":50,"name":"srv_abrt"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":51,"name":"comp_in"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":133458298}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":52,"name":"comp_out"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":38104818}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":53,"name":"comp_byp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":0}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":54,"name":"comp_rsp"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4379}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":55,"name":"lastsess"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"s32","value":1342}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":58,"name":"qtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1268}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":59,"name":"ctime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":1}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":60,"name":"rtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":2908}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":61,"name":"ttime"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"u32","value":4500}},
+{"objType":"Backend","proxyId":4,"id":0,"field":{"pos":75,"name":"mode"},"processNum":1,"tags":{"origin":"Metric","nature":"Counter","scope":"Process"},"value":{"type":"str","value":"http"}}
+]
+]
+`
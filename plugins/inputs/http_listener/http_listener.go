@@ -2,12 +2,9 @@ package http_listener
 
 import (
 	"bytes"
-	"compress/gzip"
 	"crypto/subtle"
 	"crypto/tls"
-	"crypto/x509"
 	"io"
-	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
@@ -16,6 +13,8 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/encoding"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/selfstat"
@@ -35,15 +34,14 @@ const (
 
 type HTTPListener struct {
 	ServiceAddress string
+	SocketMode     string
 	ReadTimeout    internal.Duration
 	WriteTimeout   internal.Duration
 	MaxBodySize    int64
 	MaxLineSize    int
 	Port           int
 
-	TlsAllowedCacerts []string
-	TlsCert           string
-	TlsKey            string
+	tlsint.ServerConfig
 
 	BasicUsername string
 	BasicPassword string
@@ -73,9 +71,13 @@ type HTTPListener struct {
 }
 
 const sampleConfig = `
-  ## Address and port to host HTTP listener on
+  ## Address and port to host HTTP listener on, or the path of a unix socket
+  ## like "unix:///var/run/telegraf/http_listener.sock"
   service_address = ":8186"
 
+  ## Permissions of the unix socket, in octal
+  # socket_mode = "0660"
+
   ## maximum duration before timing out read of the request
   read_timeout = "10s"
   ## maximum duration before timing out write of the response
@@ -89,13 +91,16 @@ const sampleConfig = `
   ## 0 means to use the default of 65536 bytes (64 kibibytes)
   max_line_size = 0
 
-  ## Set one or more allowed client CA certificate file names to 
+  ## Set one or more allowed client CA certificate file names to
   ## enable mutually authenticated TLS connections
-  tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
 
   ## Add service certificate and key
-  tls_cert = "/etc/telegraf/cert.pem"
-  tls_key = "/etc/telegraf/key.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Minimum and maximum TLS version, "TLS10", "TLS11", "TLS12" or "TLS13"
+  # tls_min_version = "TLS12"
+  # tls_max_version = "TLS12"
 
   ## Optional username and password to accept for HTTP basic authentication.
   ## You probably want to make sure you have TLS configured above for this.
@@ -154,7 +159,10 @@ func (h *HTTPListener) Start(acc telegraf.Accumulator) error {
 	h.acc = acc
 	h.pool = NewPool(200, h.MaxLineSize)
 
-	tlsConf := h.getTLSConfig()
+	tlsConf, err := h.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
 
 	server := &http.Server{
 		Addr:         h.ServiceAddress,
@@ -164,18 +172,17 @@ func (h *HTTPListener) Start(acc telegraf.Accumulator) error {
 		TLSConfig:    tlsConf,
 	}
 
-	var err error
-	var listener net.Listener
-	if tlsConf != nil {
-		listener, err = tls.Listen("tcp", h.ServiceAddress, tlsConf)
-	} else {
-		listener, err = net.Listen("tcp", h.ServiceAddress)
-	}
+	listener, err := internal.Listen(h.ServiceAddress, h.SocketMode)
 	if err != nil {
 		return err
 	}
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		h.Port = addr.Port
+	}
+	if tlsConf != nil {
+		listener = tls.NewListener(listener, tlsConf)
+	}
 	h.listener = listener
-	h.Port = listener.Addr().(*net.TCPAddr).Port
 
 	h.handler = influx.NewMetricHandler()
 	h.parser = influx.NewParser(h.handler)
@@ -245,18 +252,23 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 
 	precision := req.URL.Query().Get("precision")
 
-	// Handle gzip request bodies
-	body := req.Body
-	if req.Header.Get("Content-Encoding") == "gzip" {
-		var err error
-		body, err = gzip.NewReader(req.Body)
-		defer body.Close()
-		if err != nil {
-			log.Println("E! " + err.Error())
-			badRequest(res)
-			return
+	// Handle gzip, snappy and zstd request bodies
+	body, err := encoding.NewDecoder(req.Header.Get("Content-Encoding"), req.Body, h.MaxBodySize)
+	if err != nil {
+		log.Println("E! " + err.Error())
+		switch err.(type) {
+		case *encoding.UnsupportedError:
+			unsupportedEncoding(res)
+		default:
+			if err == encoding.ErrTooLarge {
+				tooLarge(res)
+			} else {
+				badRequest(res)
+			}
 		}
+		return
 	}
+	defer body.Close()
 	body = http.MaxBytesReader(res, body, h.MaxBodySize)
 
 	var return400 bool
@@ -360,6 +372,13 @@ func tooLarge(res http.ResponseWriter) {
 	res.Write([]byte(`{"error":"http: request body too large"}`))
 }
 
+func unsupportedEncoding(res http.ResponseWriter) {
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("X-Influxdb-Version", "1.0")
+	res.WriteHeader(http.StatusUnsupportedMediaType)
+	res.Write([]byte(`{"error":"http: unsupported content encoding"}`))
+}
+
 func badRequest(res http.ResponseWriter) {
 	res.Header().Set("Content-Type", "application/json")
 	res.Header().Set("X-Influxdb-Version", "1.0")
@@ -367,38 +386,6 @@ func badRequest(res http.ResponseWriter) {
 	res.Write([]byte(`{"error":"http: bad request"}`))
 }
 
-func (h *HTTPListener) getTLSConfig() *tls.Config {
-	tlsConf := &tls.Config{
-		InsecureSkipVerify: false,
-		Renegotiation:      tls.RenegotiateNever,
-	}
-
-	if len(h.TlsCert) == 0 || len(h.TlsKey) == 0 {
-		return nil
-	}
-
-	cert, err := tls.LoadX509KeyPair(h.TlsCert, h.TlsKey)
-	if err != nil {
-		return nil
-	}
-	tlsConf.Certificates = []tls.Certificate{cert}
-
-	if h.TlsAllowedCacerts != nil {
-		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
-		clientPool := x509.NewCertPool()
-		for _, ca := range h.TlsAllowedCacerts {
-			c, err := ioutil.ReadFile(ca)
-			if err != nil {
-				continue
-			}
-			clientPool.AppendCertsFromPEM(c)
-		}
-		tlsConf.ClientCAs = clientPool
-	}
-
-	return tlsConf
-}
-
 func (h *HTTPListener) AuthenticateIfSet(handler http.HandlerFunc, res http.ResponseWriter, req *http.Request) {
 	if h.BasicUsername != "" && h.BasicPassword != "" {
 		reqUsername, reqPassword, ok := req.BasicAuth()
@@ -6,16 +6,21 @@ import (
 	"crypto/x509"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	tlsint "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/testutil"
 
+	"github.com/golang/snappy"
 	"github.com/stretchr/testify/require"
 )
 
@@ -166,10 +171,12 @@ func newTestHTTPSListener() *HTTPListener {
 	})
 
 	listener := &HTTPListener{
-		ServiceAddress:    "localhost:0",
-		TlsAllowedCacerts: allowedCAFiles,
-		TlsCert:           serviceCertFile,
-		TlsKey:            serviceKeyFile,
+		ServiceAddress: "localhost:0",
+		ServerConfig: tlsint.ServerConfig{
+			TlsAllowedCacerts: allowedCAFiles,
+			TlsCert:           serviceCertFile,
+			TlsKey:            serviceKeyFile,
+		},
 	}
 
 	return listener
@@ -452,6 +459,75 @@ func TestWriteHTTPGzippedData(t *testing.T) {
 	}
 }
 
+// test that writing snappy compressed data works and that other
+// encodings are rejected
+func TestWriteHTTPSnappyData(t *testing.T) {
+	listener := newTestHTTPListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	data := snappy.Encode(nil, []byte(testMsg))
+	req, err := http.NewRequest("POST", createURL(listener, "http", "/write", ""), bytes.NewBuffer(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "snappy")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(12)},
+		map[string]string{"host": "server01"},
+	)
+
+	req, err = http.NewRequest("POST", createURL(listener, "http", "/write", ""), bytes.NewBufferString(testMsg))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "br")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 415, resp.StatusCode)
+}
+
+func TestWriteUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping unix sockets on windows")
+	}
+	dir, err := ioutil.TempDir("", "http_listener")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "telegraf.sock")
+
+	listener := newTestHTTPListener()
+	listener.ServiceAddress = "unix://" + sock
+	listener.SocketMode = "0600"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Dial: func(_, _ string) (net.Conn, error) {
+				return net.Dial("unix", sock)
+			},
+		},
+	}
+	resp, err := client.Post("http://localhost/write?db=mydb", "", bytes.NewBufferString(testMsg))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(12)},
+		map[string]string{"host": "server01"},
+	)
+}
+
 // writes 25,000 metrics to the listener with 10 different writers
 func TestWriteHTTPHighTraffic(t *testing.T) {
 	if runtime.GOOS != "darwin" {
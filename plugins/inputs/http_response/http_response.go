@@ -16,6 +16,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/proxy"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -52,7 +53,8 @@ var sampleConfig = `
   ## Server address (default http://localhost)
   # address = "http://localhost"
 
-  ## Set http_proxy (telegraf uses the system wide proxy settings if it's is not set)
+  ## Set http_proxy (telegraf uses the system wide proxy settings if it's is not set),
+  ## the hosts listed in the NO_PROXY environment variable are reached directly
   # http_proxy = "http://localhost:8888"
 
   ## Set response_timeout (default 5 seconds)
@@ -94,22 +96,6 @@ func (h *HTTPResponse) SampleConfig() string {
 // ErrRedirectAttempted indicates that a redirect occurred
 var ErrRedirectAttempted = errors.New("redirect")
 
-// Set the proxy. A configured proxy overwrites the system wide proxy.
-func getProxyFunc(http_proxy string) func(*http.Request) (*url.URL, error) {
-	if http_proxy == "" {
-		return http.ProxyFromEnvironment
-	}
-	proxyURL, err := url.Parse(http_proxy)
-	if err != nil {
-		return func(_ *http.Request) (*url.URL, error) {
-			return nil, errors.New("bad proxy: " + err.Error())
-		}
-	}
-	return func(r *http.Request) (*url.URL, error) {
-		return proxyURL, nil
-	}
-}
-
 // CreateHttpClient creates an http client which will timeout at the specified
 // timeout period and can follow redirects if specified
 func (h *HTTPResponse) createHttpClient() (*http.Client, error) {
@@ -120,7 +106,7 @@ func (h *HTTPResponse) createHttpClient() (*http.Client, error) {
 	}
 	client := &http.Client{
 		Transport: &http.Transport{
-			Proxy:             getProxyFunc(h.HTTPProxy),
+			Proxy:             proxy.ProxyURL(h.HTTPProxy),
 			DisableKeepAlives: true,
 			TLSClientConfig:   tlsCfg,
 		},
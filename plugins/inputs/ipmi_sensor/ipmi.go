@@ -21,6 +21,10 @@ type Ipmi struct {
 	Privilege string
 	Servers   []string
 	Timeout   internal.Duration
+	Native    bool
+
+	// SDR repositories of the servers queried with the native client
+	sdrCache map[string]*sdrRepository
 }
 
 var sampleConfig = `
@@ -45,6 +49,12 @@ var sampleConfig = `
 
   ## Timeout for the ipmitool command to complete
   timeout = "20s"
+
+  ## Query the servers with the built-in IPMI v2.0 (RMCP+) client instead
+  ## of ipmitool, the servers are queried as with the ipmitool lanplus
+  ## interface. The sensor data records are cached and only read again when
+  ## the SDR repository of the server changes.
+  # native = false
 `
 
 func (m *Ipmi) SampleConfig() string {
@@ -56,6 +66,18 @@ func (m *Ipmi) Description() string {
 }
 
 func (m *Ipmi) Gather(acc telegraf.Accumulator) error {
+	if m.Native {
+		if len(m.Servers) == 0 {
+			return fmt.Errorf("the native client requires at least one server")
+		}
+		for _, server := range m.Servers {
+			if err := m.gatherNative(acc, server); err != nil {
+				acc.AddError(err)
+			}
+		}
+		return nil
+	}
+
 	if len(m.Path) == 0 {
 		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH")
 	}
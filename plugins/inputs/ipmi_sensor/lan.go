@@ -0,0 +1,476 @@
+package ipmi_sensor
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// This file implements an IPMI v2.0 RMCP+ client, the "lanplus" interface
+// of ipmitool, using the RAKP-HMAC-SHA1 authentication, HMAC-SHA1-96
+// integrity and AES-CBC-128 confidentiality algorithms.
+
+const (
+	rmcpVersion   = 0x06
+	rmcpClassIPMI = 0x07
+
+	authTypeNone     = 0x00
+	authTypeRMCPPlus = 0x06
+
+	payloadIPMI                = 0x00
+	payloadOpenSessionRequest  = 0x10
+	payloadOpenSessionResponse = 0x11
+	payloadRAKP1               = 0x12
+	payloadRAKP2               = 0x13
+	payloadRAKP3               = 0x14
+	payloadRAKP4               = 0x15
+
+	payloadEncrypted     = 0x80
+	payloadAuthenticated = 0x40
+
+	algRAKPHMACSHA1    = 0x01
+	algHMACSHA196      = 0x01
+	algAESCBC128       = 0x01
+	integrityCodeSize  = 12
+	bmcAddress         = 0x20
+	remoteSWID         = 0x81
+	nameOnlyLookup     = 0x10
+	defaultIPMIPort    = 623
+	retransmitInterval = time.Second
+
+	netFnApp     = 0x06
+	netFnSensor  = 0x04
+	netFnStorage = 0x0a
+
+	cmdGetChannelAuthCapabilities = 0x38
+	cmdSetSessionPrivilegeLevel   = 0x3b
+	cmdCloseSession               = 0x3c
+)
+
+var privilegeLevels = map[string]byte{
+	"CALLBACK":      0x01,
+	"USER":          0x02,
+	"OPERATOR":      0x03,
+	"ADMINISTRATOR": 0x04,
+}
+
+// completionError is returned when a command does not complete normally.
+type completionError struct {
+	netFn, cmd, code byte
+}
+
+func (e *completionError) Error() string {
+	return fmt.Sprintf("command 0x%02x/0x%02x failed with completion code 0x%02x", e.netFn, e.cmd, e.code)
+}
+
+// lanClient is an authenticated RMCP+ session with a BMC.
+type lanClient struct {
+	conn     net.Conn
+	deadline time.Time
+
+	username  []byte
+	password  []byte
+	privilege byte
+
+	consoleID uint32 // remote console session id
+	managedID uint32 // managed system session id
+	sequence  uint32
+	rqSeq     byte
+
+	k1  []byte
+	aes []byte
+}
+
+// newLanClient opens and activates a session with the BMC of the
+// connection; all exchanges must complete before the given deadline.
+func newLanClient(c *Connection, deadline time.Time) (*lanClient, error) {
+	privilege := byte(0x02)
+	if c.Privilege != "" {
+		p, ok := privilegeLevels[strings.ToUpper(c.Privilege)]
+		if !ok {
+			return nil, fmt.Errorf("unknown privilege level %q", c.Privilege)
+		}
+		privilege = p
+	}
+	if len(c.Username) > 16 {
+		return nil, fmt.Errorf("username longer than 16 bytes")
+	}
+	if len(c.Password) > 20 {
+		return nil, fmt.Errorf("password longer than 20 bytes")
+	}
+
+	port := c.Port
+	if port == 0 {
+		port = defaultIPMIPort
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(c.Hostname, fmt.Sprint(port)))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &lanClient{
+		conn:      conn,
+		deadline:  deadline,
+		username:  []byte(c.Username),
+		password:  []byte(c.Password),
+		privilege: privilege,
+	}
+	if err := l.activate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// activate runs the RMCP+ session establishment: open session request
+// and the RAKP messages 1 to 4.
+func (l *lanClient) activate() error {
+	// Some BMCs only answer to RMCP+ after being asked for the capabilities
+	// of the channel, as ipmitool does.
+	if _, err := l.exchangeV15([]byte{0x8e, l.privilege}); err != nil {
+		return fmt.Errorf("get channel authentication capabilities: %s", err)
+	}
+
+	id := make([]byte, 4)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return err
+	}
+	l.consoleID = binary.LittleEndian.Uint32(id) | 1
+
+	req := make([]byte, 32)
+	req[0] = 0 // message tag
+	req[1] = l.privilege
+	binary.LittleEndian.PutUint32(req[4:], l.consoleID)
+	copy(req[8:], []byte{0x00, 0, 0, 0x08, algRAKPHMACSHA1, 0, 0, 0})
+	copy(req[16:], []byte{0x01, 0, 0, 0x08, algHMACSHA196, 0, 0, 0})
+	copy(req[24:], []byte{0x02, 0, 0, 0x08, algAESCBC128, 0, 0, 0})
+	resp, err := l.exchangeSession(payloadOpenSessionRequest, payloadOpenSessionResponse, req, 36)
+	if err != nil {
+		return fmt.Errorf("open session: %s", err)
+	}
+	if binary.LittleEndian.Uint32(resp[4:]) != l.consoleID {
+		return errors.New("open session: session id mismatch")
+	}
+	l.managedID = binary.LittleEndian.Uint32(resp[8:])
+
+	// RAKP 1
+	rm := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, rm); err != nil {
+		return err
+	}
+	role := l.privilege | nameOnlyLookup
+	rakp1 := make([]byte, 28, 28+len(l.username))
+	binary.LittleEndian.PutUint32(rakp1[4:], l.managedID)
+	copy(rakp1[8:], rm)
+	rakp1[24] = role
+	rakp1[27] = byte(len(l.username))
+	rakp1 = append(rakp1, l.username...)
+	resp, err = l.exchangeSession(payloadRAKP1, payloadRAKP2, rakp1, 60)
+	if err != nil {
+		return fmt.Errorf("rakp 2: %s", err)
+	}
+	rc := resp[8:24]
+	guid := resp[24:40]
+
+	// validate the key exchange authentication code of the BMC
+	ids := make([]byte, 8)
+	binary.LittleEndian.PutUint32(ids[0:], l.consoleID)
+	binary.LittleEndian.PutUint32(ids[4:], l.managedID)
+	expected := l.hmac(l.password, ids, rm, rc, guid,
+		[]byte{role, byte(len(l.username))}, l.username)
+	if !hmac.Equal(expected, resp[40:60]) {
+		return errors.New("rakp 2: invalid authentication code, check the username and password")
+	}
+
+	// RAKP 3
+	consoleID := make([]byte, 4)
+	binary.LittleEndian.PutUint32(consoleID, l.consoleID)
+	rakp3 := make([]byte, 8, 28)
+	binary.LittleEndian.PutUint32(rakp3[4:], l.managedID)
+	rakp3 = append(rakp3, l.hmac(l.password, rc, consoleID,
+		[]byte{role, byte(len(l.username))}, l.username)...)
+	resp, err = l.exchangeSession(payloadRAKP3, payloadRAKP4, rakp3, 8+integrityCodeSize)
+	if err != nil {
+		return fmt.Errorf("rakp 4: %s", err)
+	}
+
+	sik := l.hmac(l.password, rm, rc, []byte{role, byte(len(l.username))}, l.username)
+	managedID := make([]byte, 4)
+	binary.LittleEndian.PutUint32(managedID, l.managedID)
+	expected = l.hmac(sik, rm, managedID, guid)[:integrityCodeSize]
+	if !hmac.Equal(expected, resp[8:8+integrityCodeSize]) {
+		return errors.New("rakp 4: invalid integrity check value")
+	}
+
+	l.k1 = l.hmac(sik, bytes.Repeat([]byte{0x01}, 20))
+	l.aes = l.hmac(sik, bytes.Repeat([]byte{0x02}, 20))[:16]
+
+	if _, err := l.command(netFnApp, 0, cmdSetSessionPrivilegeLevel, []byte{l.privilege}); err != nil {
+		return fmt.Errorf("set session privilege level: %s", err)
+	}
+	return nil
+}
+
+func (l *lanClient) hmac(key []byte, data ...[]byte) []byte {
+	h := hmac.New(sha1.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// Close closes the session with the BMC.
+func (l *lanClient) Close() error {
+	id := make([]byte, 4)
+	binary.LittleEndian.PutUint32(id, l.managedID)
+	l.command(netFnApp, 0, cmdCloseSession, id)
+	return l.conn.Close()
+}
+
+// exchange sends the packet and returns the first response accepted by
+// the match function, retransmitting the packet until the deadline.
+func (l *lanClient) exchange(packet []byte, match func([]byte) ([]byte, bool)) ([]byte, error) {
+	buf := make([]byte, 1024)
+	for {
+		if time.Now().After(l.deadline) {
+			return nil, errors.New("timeout")
+		}
+		if _, err := l.conn.Write(packet); err != nil {
+			return nil, err
+		}
+
+		wait := time.Now().Add(retransmitInterval)
+		if wait.After(l.deadline) {
+			wait = l.deadline
+		}
+		l.conn.SetReadDeadline(wait)
+		for {
+			n, err := l.conn.Read(buf)
+			if err != nil {
+				if e, ok := err.(net.Error); ok && e.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			if resp, ok := match(buf[:n]); ok {
+				return resp, nil
+			}
+		}
+	}
+}
+
+// exchangeV15 sends a Get Channel Authentication Capabilities request
+// outside of a session.
+func (l *lanClient) exchangeV15(data []byte) ([]byte, error) {
+	msg := l.message(netFnApp, 0, cmdGetChannelAuthCapabilities, data)
+	packet := []byte{rmcpVersion, 0, 0xff, rmcpClassIPMI, authTypeNone, 0, 0, 0, 0, 0, 0, 0, 0, byte(len(msg))}
+	packet = append(packet, msg...)
+
+	seq := l.rqSeq
+	resp, err := l.exchange(packet, func(b []byte) ([]byte, bool) {
+		if len(b) < 14 || b[3] != rmcpClassIPMI || b[4] != authTypeNone {
+			return nil, false
+		}
+		return parseResponse(b[14:], netFnApp, cmdGetChannelAuthCapabilities, seq)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checkCompletion(resp, netFnApp, cmdGetChannelAuthCapabilities)
+}
+
+// exchangeSession sends one of the session establishment payloads, the
+// status code of the response is checked and the response must be at
+// least size bytes long.
+func (l *lanClient) exchangeSession(reqType, respType byte, payload []byte, size int) ([]byte, error) {
+	packet := l.packet(reqType, 0, 0, payload)
+	resp, err := l.exchange(packet, func(b []byte) ([]byte, bool) {
+		payloadType, p, ok := l.parsePacket(b)
+		if !ok || payloadType != respType || len(p) < 2 {
+			return nil, false
+		}
+		return p, true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp[1] != 0 {
+		return nil, fmt.Errorf("status code 0x%02x", resp[1])
+	}
+	if len(resp) < size {
+		return nil, fmt.Errorf("short response of %d bytes", len(resp))
+	}
+	return resp, nil
+}
+
+// command sends an IPMI request within the session and returns the data
+// of the response, without the completion code.
+func (l *lanClient) command(netFn, lun, cmd byte, data []byte) ([]byte, error) {
+	l.sequence++
+	msg := l.message(netFn, lun, cmd, data)
+	payload, err := l.encrypt(msg)
+	if err != nil {
+		return nil, err
+	}
+	packet := l.packet(payloadIPMI|payloadEncrypted|payloadAuthenticated, l.managedID, l.sequence, payload)
+	packet = l.sign(packet)
+
+	seq := l.rqSeq
+	resp, err := l.exchange(packet, func(b []byte) ([]byte, bool) {
+		payloadType, p, ok := l.parsePacket(b)
+		if !ok || payloadType != payloadIPMI {
+			return nil, false
+		}
+		return parseResponse(p, netFn, cmd, seq)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checkCompletion(resp, netFn, cmd)
+}
+
+// message encodes an IPMI LAN request message.
+func (l *lanClient) message(netFn, lun, cmd byte, data []byte) []byte {
+	l.rqSeq = (l.rqSeq + 1) & 0x3f
+	msg := []byte{bmcAddress, netFn<<2 | lun&0x03, 0, remoteSWID, l.rqSeq << 2, cmd}
+	msg[2] = checksum(msg[0:2])
+	msg = append(msg, data...)
+	return append(msg, checksum(msg[3:]))
+}
+
+// parseResponse checks that b is the response to the request and returns
+// the completion code and data of the response.
+func parseResponse(b []byte, netFn, cmd, seq byte) ([]byte, bool) {
+	if len(b) < 8 ||
+		b[1]>>2 != netFn|1 ||
+		b[4]>>2 != seq ||
+		b[5] != cmd ||
+		checksum(b[0:2]) != b[2] ||
+		checksum(b[3:len(b)-1]) != b[len(b)-1] {
+		return nil, false
+	}
+	return b[6 : len(b)-1], true
+}
+
+func checkCompletion(resp []byte, netFn, cmd byte) ([]byte, error) {
+	if resp[0] != 0 {
+		return nil, &completionError{netFn, cmd, resp[0]}
+	}
+	return resp[1:], nil
+}
+
+func checksum(b []byte) byte {
+	var c byte
+	for _, v := range b {
+		c += v
+	}
+	return -c
+}
+
+// packet encodes an RMCP+ packet, the integrity trailer is added by sign.
+func (l *lanClient) packet(payloadType byte, sessionID, sequence uint32, payload []byte) []byte {
+	packet := make([]byte, 16, 16+len(payload)+32)
+	packet[0] = rmcpVersion
+	packet[2] = 0xff
+	packet[3] = rmcpClassIPMI
+	packet[4] = authTypeRMCPPlus
+	packet[5] = payloadType
+	binary.LittleEndian.PutUint32(packet[6:], sessionID)
+	binary.LittleEndian.PutUint32(packet[10:], sequence)
+	binary.LittleEndian.PutUint16(packet[14:], uint16(len(payload)))
+	return append(packet, payload...)
+}
+
+// sign pads the session part of the packet to a multiple of four bytes
+// and appends the HMAC-SHA1-96 authentication code.
+func (l *lanClient) sign(packet []byte) []byte {
+	pad := (4 - (len(packet)-4+2)%4) % 4
+	for i := 0; i < pad; i++ {
+		packet = append(packet, 0xff)
+	}
+	packet = append(packet, byte(pad), rmcpClassIPMI)
+	return append(packet, l.hmac(l.k1, packet[4:])[:integrityCodeSize]...)
+}
+
+// parsePacket returns the payload type and the decrypted payload of an
+// RMCP+ packet, authenticated packets are verified.
+func (l *lanClient) parsePacket(b []byte) (byte, []byte, bool) {
+	if len(b) < 16 || b[0] != rmcpVersion || b[3] != rmcpClassIPMI || b[4] != authTypeRMCPPlus {
+		return 0, nil, false
+	}
+	payloadType := b[5]
+	length := int(binary.LittleEndian.Uint16(b[14:]))
+	if len(b) < 16+length {
+		return 0, nil, false
+	}
+	payload := b[16 : 16+length]
+
+	if payloadType&payloadAuthenticated != 0 {
+		if l.k1 == nil || len(b) < 16+length+2+integrityCodeSize {
+			return 0, nil, false
+		}
+		end := len(b) - integrityCodeSize
+		if !hmac.Equal(l.hmac(l.k1, b[4:end])[:integrityCodeSize], b[end:]) {
+			return 0, nil, false
+		}
+		if binary.LittleEndian.Uint32(b[6:]) != l.consoleID {
+			return 0, nil, false
+		}
+	}
+	if payloadType&payloadEncrypted != 0 {
+		var err error
+		if payload, err = l.decrypt(payload); err != nil {
+			return 0, nil, false
+		}
+	}
+	return payloadType &^ (payloadEncrypted | payloadAuthenticated), payload, true
+}
+
+// encrypt encrypts the payload using AES-CBC-128 with a random IV.
+func (l *lanClient) encrypt(payload []byte) ([]byte, error) {
+	pad := (aes.BlockSize - (len(payload)+1)%aes.BlockSize) % aes.BlockSize
+	plain := make([]byte, 0, len(payload)+pad+1)
+	plain = append(plain, payload...)
+	for i := 1; i <= pad; i++ {
+		plain = append(plain, byte(i))
+	}
+	plain = append(plain, byte(pad))
+
+	block, err := aes.NewCipher(l.aes)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, aes.BlockSize+len(plain))
+	if _, err := io.ReadFull(rand.Reader, out[:aes.BlockSize]); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], plain)
+	return out, nil
+}
+
+func (l *lanClient) decrypt(payload []byte) ([]byte, error) {
+	if l.aes == nil || len(payload) < 2*aes.BlockSize || len(payload)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted payload")
+	}
+	block, err := aes.NewCipher(l.aes)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(payload)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, payload[:aes.BlockSize]).CryptBlocks(plain, payload[aes.BlockSize:])
+	pad := int(plain[len(plain)-1])
+	if pad >= len(plain) {
+		return nil, errors.New("invalid confidentiality pad")
+	}
+	return plain[:len(plain)-pad-1], nil
+}
@@ -0,0 +1,117 @@
+package ipmi_sensor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// gatherNative reads the sensors of the server with the built-in RMCP+
+// client, the fields and tags match those parsed from ipmitool.
+func (m *Ipmi) gatherNative(acc telegraf.Accumulator, server string) error {
+	conn := NewConnection(server, m.Privilege)
+	if conn.Hostname == "" {
+		return fmt.Errorf("no address found in server %q", server)
+	}
+
+	timeout := m.Timeout.Duration
+	if timeout == 0 {
+		timeout = 20 * time.Second
+	}
+	client, err := newLanClient(conn, time.Now().Add(timeout))
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %s", conn.Hostname, err)
+	}
+	defer client.Close()
+
+	return m.gatherSensors(acc, client, server, conn.Hostname)
+}
+
+func (m *Ipmi) gatherSensors(acc telegraf.Accumulator, client sdrClient, server, hostname string) error {
+	repo, err := m.repository(client, server)
+	if err != nil {
+		return fmt.Errorf("reading the SDR repository of %s: %s", hostname, err)
+	}
+
+	now := time.Now()
+	for _, r := range repo.records {
+		// sensors of other controllers would require bridged requests
+		if r.owner != bmcAddress {
+			continue
+		}
+
+		reading, err := readSensor(client, r)
+		if err != nil {
+			if _, ok := err.(*completionError); ok {
+				log.Printf("D! Reading sensor %q of %s failed: %s", r.name, hostname, err)
+				continue
+			}
+			return fmt.Errorf("reading sensor %q of %s: %s", r.name, hostname, err)
+		}
+
+		tags := map[string]string{
+			"name":   transform(r.name),
+			"server": hostname,
+		}
+		fields := map[string]interface{}{
+			"status": 0,
+			"value":  0.0,
+		}
+
+		if !reading.unavailable {
+			fields["status"] = 1
+		}
+		if r.analog {
+			tags["unit"] = transform(r.unit)
+			if !reading.unavailable {
+				fields["value"] = r.convert(reading.raw)
+			}
+		}
+		if r.readingType == readingTypeThreshold && !reading.unavailable {
+			state := reading.thresholdState()
+			if state != "ok" {
+				fields["status"] = 0
+			}
+			fields["threshold_status"] = state
+			if r.analog {
+				for name, raw := range r.thresholds {
+					fields[name] = r.convert(raw)
+				}
+			}
+		}
+
+		acc.AddFields("ipmi_sensor", fields, tags, now)
+	}
+
+	return nil
+}
+
+// repository returns the cached SDR repository of the server, it is read
+// again when it changed since the last read.
+func (m *Ipmi) repository(c sdrClient, server string) (*sdrRepository, error) {
+	addition, erase, err := repositoryTimestamps(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if repo, ok := m.sdrCache[server]; ok && repo.addition == addition && repo.erase == erase {
+		return repo, nil
+	}
+
+	records, err := readRepository(c)
+	if err != nil {
+		return nil, err
+	}
+	repo := &sdrRepository{
+		addition: addition,
+		erase:    erase,
+		records:  records,
+	}
+	if m.sdrCache == nil {
+		m.sdrCache = make(map[string]*sdrRepository)
+	}
+	m.sdrCache[server] = repo
+	return repo, nil
+}
@@ -0,0 +1,371 @@
+package ipmi_sensor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	cmdGetSDRRepositoryInfo = 0x20
+	cmdReserveSDRRepository = 0x22
+	cmdGetSDR               = 0x23
+	cmdGetSensorReading     = 0x2d
+
+	sdrFullSensor    = 0x01
+	sdrCompactSensor = 0x02
+
+	sdrHeaderSize     = 5
+	sdrChunkSize      = 16
+	sdrLastRecord     = 0xffff
+	ccReservationLost = 0xc5
+
+	// event/reading type code of threshold based sensors
+	readingTypeThreshold = 0x01
+)
+
+// sdrClient issues the IPMI commands needed to read the sensors.
+type sdrClient interface {
+	command(netFn, lun, cmd byte, data []byte) ([]byte, error)
+}
+
+// sensorRecord is the part of a full or compact sensor data record needed
+// to read and convert the sensor.
+type sensorRecord struct {
+	name        string
+	owner       byte
+	lun         byte
+	number      byte
+	readingType byte
+	unit        string
+
+	// analog reading conversion, only set for full sensor records
+	analog     bool
+	format     byte // 0 unsigned, 1 one's complement, 2 two's complement
+	linear     byte
+	m, b       int
+	rExp, bExp int
+
+	// raw threshold values readable according to the threshold mask
+	thresholds map[string]byte
+}
+
+// thresholdNames are the names of the thresholds in the order of the bits
+// of the readable threshold mask and of the threshold comparison status.
+var thresholdNames = []string{
+	"lower_non_critical",
+	"lower_critical",
+	"lower_non_recoverable",
+	"upper_non_critical",
+	"upper_critical",
+	"upper_non_recoverable",
+}
+
+// thresholdStatus are the short names of the threshold states as printed
+// by ipmitool, in the order of the threshold comparison status bits.
+var thresholdStatus = []string{"lnc", "lcr", "lnr", "unc", "ucr", "unr"}
+
+// sdrRepository is a cached copy of the SDR repository of a BMC, it is
+// read again when the addition or erase timestamps of the repository
+// change.
+type sdrRepository struct {
+	addition uint32
+	erase    uint32
+	records  []*sensorRecord
+}
+
+// repositoryTimestamps returns the most recent addition and erase
+// timestamps of the SDR repository.
+func repositoryTimestamps(c sdrClient) (uint32, uint32, error) {
+	info, err := c.command(netFnStorage, 0, cmdGetSDRRepositoryInfo, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(info) < 13 {
+		return 0, 0, fmt.Errorf("short SDR repository info")
+	}
+	return binary.LittleEndian.Uint32(info[5:]), binary.LittleEndian.Uint32(info[9:]), nil
+}
+
+// readRepository reads the sensor records of the SDR repository.
+func readRepository(c sdrClient) ([]*sensorRecord, error) {
+	reservation, err := reserveRepository(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*sensorRecord
+	id := uint16(0)
+	for id != sdrLastRecord {
+		next, data, err := readRecord(c, &reservation, id)
+		if err != nil {
+			return nil, fmt.Errorf("reading SDR record %d: %s", id, err)
+		}
+		if r := parseSensorRecord(data); r != nil {
+			records = append(records, r)
+		}
+		if next == id {
+			break
+		}
+		id = next
+	}
+	return records, nil
+}
+
+func reserveRepository(c sdrClient) (uint16, error) {
+	resp, err := c.command(netFnStorage, 0, cmdReserveSDRRepository, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp) < 2 {
+		return 0, fmt.Errorf("short SDR reservation")
+	}
+	return binary.LittleEndian.Uint16(resp), nil
+}
+
+// readRecord reads a whole record in chunks, the reservation is renewed
+// when it is cancelled by the BMC.
+func readRecord(c sdrClient, reservation *uint16, id uint16) (uint16, []byte, error) {
+	next, header, err := readChunk(c, reservation, id, 0, sdrHeaderSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	length := int(header[4])
+
+	data := header
+	for offset := sdrHeaderSize; offset < sdrHeaderSize+length; offset += sdrChunkSize {
+		size := sdrHeaderSize + length - offset
+		if size > sdrChunkSize {
+			size = sdrChunkSize
+		}
+		_, chunk, err := readChunk(c, reservation, id, byte(offset), byte(size))
+		if err != nil {
+			return 0, nil, err
+		}
+		data = append(data, chunk...)
+	}
+	return next, data, nil
+}
+
+func readChunk(c sdrClient, reservation *uint16, id uint16, offset, size byte) (uint16, []byte, error) {
+	for retry := 0; ; retry++ {
+		req := make([]byte, 6)
+		binary.LittleEndian.PutUint16(req[0:], *reservation)
+		binary.LittleEndian.PutUint16(req[2:], id)
+		req[4] = offset
+		req[5] = size
+
+		resp, err := c.command(netFnStorage, 0, cmdGetSDR, req)
+		if e, ok := err.(*completionError); ok && e.code == ccReservationLost && retry < 3 {
+			if *reservation, err = reserveRepository(c); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(resp) < 2+int(size) {
+			return 0, nil, fmt.Errorf("short SDR response")
+		}
+		return binary.LittleEndian.Uint16(resp), resp[2 : 2+int(size)], nil
+	}
+}
+
+// parseSensorRecord parses full and compact sensor records, other record
+// types are ignored.
+func parseSensorRecord(data []byte) *sensorRecord {
+	if len(data) < sdrHeaderSize {
+		return nil
+	}
+
+	var nameOffset int
+	switch data[3] {
+	case sdrFullSensor:
+		nameOffset = 47
+	case sdrCompactSensor:
+		nameOffset = 31
+	default:
+		return nil
+	}
+	if len(data) <= nameOffset {
+		return nil
+	}
+
+	r := &sensorRecord{
+		owner:       data[5],
+		lun:         data[6] & 0x03,
+		number:      data[7],
+		readingType: data[13],
+		unit:        unitName(data[20], data[21]),
+	}
+	length := int(data[nameOffset] & 0x1f)
+	if nameOffset+1+length > len(data) {
+		length = len(data) - nameOffset - 1
+	}
+	r.name = strings.TrimRight(string(data[nameOffset+1:nameOffset+1+length]), "\x00 ")
+
+	if data[3] == sdrFullSensor {
+		r.format = data[20] >> 6
+		r.analog = r.format != 3
+		r.linear = data[23] & 0x7f
+		r.m = tenBits(data[24], data[25])
+		r.b = tenBits(data[26], data[27])
+		r.rExp = fourBits(data[29] >> 4)
+		r.bExp = fourBits(data[29] & 0x0f)
+
+		if r.readingType == readingTypeThreshold {
+			mask := data[18]
+			values := data[36:42]
+			r.thresholds = make(map[string]byte)
+			// the threshold bytes are stored from upper non-recoverable
+			// down to lower non-critical
+			for i, name := range thresholdNames {
+				if mask&(1<<uint(i)) != 0 {
+					r.thresholds[name] = values[len(values)-1-i]
+				}
+			}
+		}
+	}
+	return r
+}
+
+// tenBits decodes a signed 10 bit value from its low byte and the byte
+// holding the two most significant bits in bits 7:6.
+func tenBits(ls, ms byte) int {
+	v := int(ls) | int(ms>>6)<<8
+	if v&0x200 != 0 {
+		v -= 0x400
+	}
+	return v
+}
+
+func fourBits(v byte) int {
+	if v&0x08 != 0 {
+		return int(v) - 0x10
+	}
+	return int(v)
+}
+
+// convert converts a raw reading into its value in the unit of the sensor.
+func (r *sensorRecord) convert(raw byte) float64 {
+	var x float64
+	switch r.format {
+	case 1:
+		if raw&0x80 != 0 {
+			x = -float64(^raw)
+		} else {
+			x = float64(raw)
+		}
+	case 2:
+		x = float64(int8(raw))
+	default:
+		x = float64(raw)
+	}
+
+	y := scale(float64(r.m)*x+scale(float64(r.b), r.bExp), r.rExp)
+
+	switch r.linear {
+	case 1:
+		y = math.Log(y)
+	case 2:
+		y = math.Log10(y)
+	case 3:
+		y = math.Log2(y)
+	case 4:
+		y = math.Exp(y)
+	case 5:
+		y = math.Pow(10, y)
+	case 6:
+		y = math.Exp2(y)
+	case 7:
+		y = 1 / y
+	case 8:
+		y = y * y
+	case 9:
+		y = y * y * y
+	case 10:
+		y = math.Sqrt(y)
+	case 11:
+		y = math.Cbrt(y)
+	}
+	return y
+}
+
+// scale returns v * 10^exp, negative exponents divide to avoid the
+// rounding error of the inexact negative powers of ten.
+func scale(v float64, exp int) float64 {
+	if exp < 0 {
+		return v / math.Pow10(-exp)
+	}
+	return v * math.Pow10(exp)
+}
+
+// sensorReading is the result of a Get Sensor Reading command.
+type sensorReading struct {
+	raw         byte
+	unavailable bool
+	status      byte
+}
+
+func readSensor(c sdrClient, r *sensorRecord) (*sensorReading, error) {
+	resp, err := c.command(netFnSensor, r.lun, cmdGetSensorReading, []byte{r.number})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 2 {
+		return nil, fmt.Errorf("short sensor reading")
+	}
+	s := &sensorReading{
+		raw: resp[0],
+		// reading unavailable or sensor scanning disabled
+		unavailable: resp[1]&0x20 != 0 || resp[1]&0x40 == 0,
+	}
+	if len(resp) > 2 {
+		s.status = resp[2] & 0x3f
+	}
+	return s, nil
+}
+
+// thresholdState returns the most severe threshold crossed by the reading
+// formatted as ipmitool does, or "ok".
+func (s *sensorReading) thresholdState() string {
+	for _, i := range []int{5, 2, 4, 1, 3, 0} {
+		if s.status&(1<<uint(i)) != 0 {
+			return thresholdStatus[i]
+		}
+	}
+	return "ok"
+}
+
+// unitName returns the name of the base unit of the sensor, with the
+// percentage modifier applied, as printed by ipmitool.
+func unitName(units1, base byte) string {
+	name := "unspecified"
+	if int(base) < len(unitNames) {
+		name = unitNames[base]
+	}
+	if units1&0x01 != 0 {
+		name = "percent"
+	}
+	return name
+}
+
+var unitNames = []string{
+	"unspecified", "degrees C", "degrees F", "degrees K", "Volts", "Amps",
+	"Watts", "Joules", "Coulombs", "VA", "Nits", "lumen", "lux", "Candela",
+	"kPa", "PSI", "Newton", "CFM", "RPM", "Hz", "microsecond",
+	"millisecond", "second", "minute", "hour", "day", "week", "mil",
+	"inches", "feet", "cu in", "cu feet", "mm", "cm", "m", "cu cm", "cu m",
+	"liters", "fluid ounce", "radians", "steradians", "revolutions",
+	"cycles", "gravities", "ounce", "pound", "ft-lb", "oz-in", "gauss",
+	"gilberts", "henry", "millihenry", "farad", "microfarad", "ohms",
+	"siemens", "mole", "becquerel", "PPM", "reserved", "Decibels", "DbA",
+	"DbC", "gray", "sievert", "color temp deg K", "bit", "kilobit",
+	"megabit", "gigabit", "byte", "kilobyte", "megabyte", "gigabyte",
+	"word", "dword", "qword", "line", "hit", "miss", "retry", "reset",
+	"overflow", "underrun", "collision", "packets", "messages",
+	"characters", "error", "correctable error", "uncorrectable error",
+	"fatal error", "grams",
+}
@@ -0,0 +1,255 @@
+package ipmi_sensor
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBMC answers the storage and sensor commands from a list of records.
+type fakeBMC struct {
+	records  [][]byte
+	readings map[byte][]byte
+	addition uint32
+
+	reservation uint16
+	lost        bool // cancel the reservation of the next Get SDR
+	sdrReads    int
+}
+
+func (b *fakeBMC) command(netFn, lun, cmd byte, data []byte) ([]byte, error) {
+	switch {
+	case netFn == netFnStorage && cmd == cmdGetSDRRepositoryInfo:
+		info := make([]byte, 14)
+		info[0] = 0x51
+		binary.LittleEndian.PutUint16(info[1:], uint16(len(b.records)))
+		binary.LittleEndian.PutUint32(info[5:], b.addition)
+		binary.LittleEndian.PutUint32(info[9:], 1)
+		return info, nil
+	case netFn == netFnStorage && cmd == cmdReserveSDRRepository:
+		b.reservation++
+		return []byte{byte(b.reservation), byte(b.reservation >> 8)}, nil
+	case netFn == netFnStorage && cmd == cmdGetSDR:
+		b.sdrReads++
+		if b.lost {
+			b.lost = false
+			b.reservation++
+		}
+		if binary.LittleEndian.Uint16(data) != b.reservation {
+			return nil, &completionError{netFn, cmd, ccReservationLost}
+		}
+		id := int(binary.LittleEndian.Uint16(data[2:]))
+		next := uint16(id + 1)
+		if id+1 == len(b.records) {
+			next = sdrLastRecord
+		}
+		record := b.records[id]
+		offset, size := int(data[4]), int(data[5])
+		resp := []byte{byte(next), byte(next >> 8)}
+		return append(resp, record[offset:offset+size]...), nil
+	case netFn == netFnSensor && cmd == cmdGetSensorReading:
+		if r, ok := b.readings[data[0]]; ok {
+			return r, nil
+		}
+		return nil, &completionError{netFn, cmd, 0xcb}
+	}
+	return nil, &completionError{netFn, cmd, 0xc1}
+}
+
+// fullRecord builds a full sensor record of a threshold based sensor.
+func fullRecord(number byte, name string, base byte, m, b int, exp byte, mask byte, thresholds [6]byte) []byte {
+	r := make([]byte, 48)
+	r[2] = 0x51
+	r[3] = sdrFullSensor
+	r[5] = bmcAddress
+	r[7] = number
+	r[13] = readingTypeThreshold
+	r[18] = mask
+	r[21] = base
+	r[24] = byte(m)
+	r[25] = byte(m>>8) << 6
+	r[26] = byte(b)
+	r[27] = byte(b>>8) << 6
+	r[29] = exp
+	copy(r[36:42], thresholds[:])
+	r[47] = 0xc0 | byte(len(name))
+	r = append(r, name...)
+	r[4] = byte(len(r) - sdrHeaderSize)
+	return r
+}
+
+// compactRecord builds a compact sensor record of a discrete sensor.
+func compactRecord(owner, number byte, name string) []byte {
+	r := make([]byte, 32)
+	r[2] = 0x51
+	r[3] = sdrCompactSensor
+	r[5] = owner
+	r[7] = number
+	r[13] = 0x6f
+	r[31] = 0xc0 | byte(len(name))
+	r = append(r, name...)
+	r[4] = byte(len(r) - sdrHeaderSize)
+	return r
+}
+
+func newFakeBMC() *fakeBMC {
+	return &fakeBMC{
+		records: [][]byte{
+			// thresholds ordered from upper non-recoverable to lower
+			// non-critical, upper and lower critical are readable
+			fullRecord(1, "Ambient Temp", 1, 1, 0, 0x00, 0x12, [6]byte{0, 90, 0, 0, 5, 0}),
+			// M = 2, R exp = -2, upper non-critical is readable
+			fullRecord(2, "12V", 4, 2, 0, 0xe0, 0x08, [6]byte{0, 0, 130, 0, 0, 0}),
+			fullRecord(3, "Fan 1", 18, 100, 0, 0x00, 0x00, [6]byte{}),
+			compactRecord(bmcAddress, 4, "PS Status"),
+			compactRecord(0x2c, 5, "ME Sensor"),
+			// OEM record
+			{0x07, 0x00, 0x51, 0xc0, 0x03, 0x57, 0x01, 0x00},
+		},
+		readings: map[byte][]byte{
+			1: {30, 0xc0, 0x00},
+			2: {140, 0xc0, 0x08},
+			3: {0, 0x60, 0x00},
+			4: {0, 0xc0, 0x01, 0x80},
+		},
+		addition: 1,
+	}
+}
+
+func TestParseSensorRecord(t *testing.T) {
+	r := parseSensorRecord(fullRecord(2, "12V", 4, 2, -3, 0xef, 0x08, [6]byte{0, 0, 130, 0, 0, 0}))
+	require.NotNil(t, r)
+	assert.Equal(t, "12V", r.name)
+	assert.Equal(t, byte(bmcAddress), r.owner)
+	assert.Equal(t, byte(2), r.number)
+	assert.Equal(t, "Volts", r.unit)
+	assert.True(t, r.analog)
+	assert.Equal(t, 2, r.m)
+	assert.Equal(t, -3, r.b)
+	assert.Equal(t, -2, r.rExp)
+	assert.Equal(t, -1, r.bExp)
+	assert.Equal(t, map[string]byte{"upper_non_critical": 130}, r.thresholds)
+
+	r = parseSensorRecord(compactRecord(bmcAddress, 4, "PS Status"))
+	require.NotNil(t, r)
+	assert.Equal(t, "PS Status", r.name)
+	assert.False(t, r.analog)
+	assert.Nil(t, r.thresholds)
+
+	assert.Nil(t, parseSensorRecord([]byte{0x07, 0x00, 0x51, 0xc0, 0x03, 0x57, 0x01, 0x00}))
+	assert.Nil(t, parseSensorRecord([]byte{0x01, 0x00, 0x51, 0x01}))
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		record sensorRecord
+		raw    byte
+		value  float64
+	}{
+		{sensorRecord{m: 1}, 30, 30},
+		{sensorRecord{m: 2, rExp: -2}, 120, 2.4},
+		{sensorRecord{m: 1, b: -3, bExp: 1}, 50, 20},
+		{sensorRecord{m: 1, format: 1}, 0xfe, -1},
+		{sensorRecord{m: 1, format: 2}, 0xfe, -2},
+		{sensorRecord{m: 1, linear: 7}, 4, 0.25},
+		{sensorRecord{m: 1, linear: 8}, 3, 9},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.value, tt.record.convert(tt.raw), 1e-9)
+	}
+}
+
+func TestThresholdState(t *testing.T) {
+	assert.Equal(t, "ok", (&sensorReading{}).thresholdState())
+	assert.Equal(t, "unc", (&sensorReading{status: 0x08}).thresholdState())
+	assert.Equal(t, "ucr", (&sensorReading{status: 0x18}).thresholdState())
+	assert.Equal(t, "lnr", (&sensorReading{status: 0x07}).thresholdState())
+}
+
+func TestReadRepository(t *testing.T) {
+	bmc := newFakeBMC()
+	bmc.lost = true
+	records, err := readRepository(bmc)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, "Ambient Temp", records[0].name)
+	assert.Equal(t, "ME Sensor", records[4].name)
+}
+
+func TestGatherNative(t *testing.T) {
+	bmc := newFakeBMC()
+	i := &Ipmi{Native: true}
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.gatherSensors(&acc, bmc, "lan(192.168.1.1)", "192.168.1.1"))
+
+	acc.AssertContainsTaggedFields(t, "ipmi_sensor",
+		map[string]interface{}{
+			"value":            float64(30),
+			"status":           1,
+			"threshold_status": "ok",
+			"upper_critical":   float64(90),
+			"lower_critical":   float64(5),
+		},
+		map[string]string{
+			"name":   "ambient_temp",
+			"server": "192.168.1.1",
+			"unit":   "degrees_c",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sensor",
+		map[string]interface{}{
+			"value":              2.8,
+			"status":             0,
+			"threshold_status":   "unc",
+			"upper_non_critical": 2.6,
+		},
+		map[string]string{
+			"name":   "12v",
+			"server": "192.168.1.1",
+			"unit":   "volts",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sensor",
+		map[string]interface{}{
+			"value":  0.0,
+			"status": 0,
+		},
+		map[string]string{
+			"name":   "fan_1",
+			"server": "192.168.1.1",
+			"unit":   "rpm",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sensor",
+		map[string]interface{}{
+			"value":  0.0,
+			"status": 1,
+		},
+		map[string]string{
+			"name":   "ps_status",
+			"server": "192.168.1.1",
+		})
+	// sensors owned by other controllers are skipped
+	assert.Equal(t, uint64(4), acc.NMetrics())
+
+	// the records are cached while the repository is unchanged
+	reads := bmc.sdrReads
+	acc.ClearMetrics()
+	require.NoError(t, i.gatherSensors(&acc, bmc, "lan(192.168.1.1)", "192.168.1.1"))
+	assert.Equal(t, reads, bmc.sdrReads)
+	assert.Equal(t, uint64(4), acc.NMetrics())
+
+	bmc.records = bmc.records[:1]
+	bmc.addition++
+	acc.ClearMetrics()
+	require.NoError(t, i.gatherSensors(&acc, bmc, "lan(192.168.1.1)", "192.168.1.1"))
+	assert.NotEqual(t, reads, bmc.sdrReads)
+	assert.Equal(t, uint64(1), acc.NMetrics())
+}
+
+func TestGatherNativeNoServers(t *testing.T) {
+	i := &Ipmi{Native: true}
+	var acc testutil.Accumulator
+	require.Error(t, i.Gather(&acc))
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/proxy"
 )
 
 type Client struct {
@@ -27,6 +28,7 @@ type ClientConfig struct {
 	SSLCert            string
 	SSLKey             string
 	InsecureSkipVerify bool
+	HTTPProxyURL       string
 
 	ProxyConfig *ProxyConfig
 }
@@ -107,6 +109,7 @@ func NewClient(url string, config *ClientConfig) (*Client, error) {
 	}
 
 	transport := &http.Transport{
+		Proxy:                 proxy.ProxyURL(config.HTTPProxyURL),
 		ResponseHeaderTimeout: config.ResponseTimeout,
 		TLSClientConfig:       tlsConfig,
 	}
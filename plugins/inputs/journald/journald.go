@@ -0,0 +1,353 @@
+package journald
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+var execCommand = exec.Command // execCommand is used to mock commands in tests.
+
+// restartDelay is the delay before journalctl is started again after it
+// exited.
+var restartDelay = 5 * time.Second
+
+var priorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+type Journald struct {
+	Journalctl string   `toml:"journalctl"`
+	Units      []string `toml:"units"`
+	Priority   string   `toml:"priority"`
+	Matches    []string `toml:"matches"`
+	CursorFile string   `toml:"cursor_file"`
+
+	parser parsers.Parser
+	acc    telegraf.Accumulator
+
+	cmd    *exec.Cmd
+	done   chan struct{}
+	wg     sync.WaitGroup
+	cursor string
+	saved  string
+
+	sync.Mutex
+}
+
+const sampleConfig = `
+  ## Path of journalctl, by default journalctl is looked up in the PATH.
+  # journalctl = "/bin/journalctl"
+
+  ## Only read the entries of these systemd units.
+  # units = ["nginx.service"]
+
+  ## Only read the entries of this priority and higher, either a name like
+  ## "emerg", "alert", "crit", "err", "warning", "notice", "info" and
+  ## "debug", or a number between 0 and 7.
+  # priority = "info"
+
+  ## Additional journal field matches, like "_TRANSPORT=kernel".
+  # matches = []
+
+  ## File to persist the cursor of the last entry read, with a cursor file
+  ## the reading continues after this entry on restarts, otherwise it
+  ## starts with new entries.
+  # cursor_file = "/var/lib/telegraf/journald.cursor"
+
+  ## Data format of the messages of the entries.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+func (j *Journald) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Journald) Description() string {
+	return "Read metrics from the messages of the systemd journal"
+}
+
+func (j *Journald) SetParser(parser parsers.Parser) {
+	j.parser = parser
+}
+
+// Gather saves the cursor of the last entry read.
+func (j *Journald) Gather(acc telegraf.Accumulator) error {
+	j.Lock()
+	defer j.Unlock()
+	return j.saveCursor()
+}
+
+func (j *Journald) Start(acc telegraf.Accumulator) error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.Journalctl == "" {
+		return fmt.Errorf("journalctl not found: verify that systemd is installed and that journalctl is in your PATH")
+	}
+	if j.Priority != "" {
+		if err := checkPriority(j.Priority); err != nil {
+			return err
+		}
+	}
+
+	j.acc = acc
+	j.done = make(chan struct{})
+	j.cursor = ""
+	if j.CursorFile != "" {
+		b, err := ioutil.ReadFile(j.CursorFile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("reading cursor file %s: %s", j.CursorFile, err)
+		}
+		j.cursor = strings.TrimSpace(string(b))
+		j.saved = j.cursor
+	}
+
+	j.wg.Add(1)
+	go j.run()
+	return nil
+}
+
+func (j *Journald) Stop() {
+	j.Lock()
+	close(j.done)
+	if j.cmd != nil && j.cmd.Process != nil {
+		j.cmd.Process.Kill()
+	}
+	j.Unlock()
+
+	j.wg.Wait()
+
+	j.Lock()
+	defer j.Unlock()
+	if err := j.saveCursor(); err != nil {
+		log.Printf("E! [inputs.journald] %s", err)
+	}
+}
+
+// run follows the journal with journalctl, journalctl is started again
+// after the last entry read if it exits.
+func (j *Journald) run() {
+	defer j.wg.Done()
+	for {
+		if err := j.follow(); err != nil {
+			j.acc.AddError(err)
+		}
+
+		select {
+		case <-j.done:
+			return
+		case <-time.After(restartDelay):
+		}
+	}
+}
+
+func (j *Journald) follow() error {
+	j.Lock()
+	select {
+	case <-j.done:
+		j.Unlock()
+		return nil
+	default:
+	}
+
+	cmd := execCommand(j.Journalctl, j.args()...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		j.Unlock()
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		j.Unlock()
+		return fmt.Errorf("starting journalctl: %s", err)
+	}
+	j.cmd = cmd
+	j.Unlock()
+
+	err = j.read(stdout)
+	if werr := cmd.Wait(); err == nil {
+		err = werr
+	}
+
+	select {
+	case <-j.done:
+		return nil
+	default:
+	}
+	if err == nil {
+		err = fmt.Errorf("journalctl exited")
+	}
+	return fmt.Errorf("following the journal: %s", err)
+}
+
+func (j *Journald) args() []string {
+	args := []string{"--follow", "--output=json", "--no-pager"}
+	if j.cursor != "" {
+		args = append(args, "--after-cursor="+j.cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+	for _, unit := range j.Units {
+		args = append(args, "--unit="+unit)
+	}
+	if j.Priority != "" {
+		args = append(args, "--priority="+j.Priority)
+	}
+	return append(args, j.Matches...)
+}
+
+// entry is a journal entry as exported by journalctl, the timestamps are in
+// microseconds.
+type entry struct {
+	Cursor     string          `json:"__CURSOR"`
+	Timestamp  string          `json:"__REALTIME_TIMESTAMP"`
+	Message    json.RawMessage `json:"MESSAGE"`
+	Priority   string          `json:"PRIORITY"`
+	Unit       string          `json:"_SYSTEMD_UNIT"`
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+}
+
+func (j *Journald) read(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			j.handle(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (j *Journald) handle(line []byte) {
+	var e entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		j.acc.AddError(fmt.Errorf("E! Malformed journal entry [%s], Error: %s", line, err))
+		return
+	}
+	defer func() {
+		j.Lock()
+		j.cursor = e.Cursor
+		j.Unlock()
+	}()
+
+	message, ok := decodeMessage(e.Message)
+	if !ok || strings.TrimSpace(message) == "" {
+		return
+	}
+	metrics, err := j.parser.Parse([]byte(message))
+	if err != nil {
+		j.acc.AddError(fmt.Errorf("E! Malformed message of %s: [%s], Error: %s", e.Unit, message, err))
+		return
+	}
+
+	var timestamp time.Time
+	if usec, err := strconv.ParseInt(e.Timestamp, 10, 64); err == nil {
+		timestamp = time.Unix(0, usec*int64(time.Microsecond))
+	}
+	for _, m := range metrics {
+		tags := m.Tags()
+		setTag(tags, "unit", e.Unit)
+		setTag(tags, "identifier", e.Identifier)
+		if p, err := strconv.Atoi(e.Priority); err == nil && p >= 0 && p < len(priorities) {
+			tags["priority"] = priorities[p]
+		}
+		t := m.Time()
+		if !timestamp.IsZero() {
+			t = timestamp
+		}
+		j.acc.AddFields(m.Name(), m.Fields(), tags, t)
+	}
+}
+
+// decodeMessage returns the message of an entry, messages that are not
+// valid UTF-8 are exported as array of bytes.
+func decodeMessage(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+	var b []byte
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err != nil {
+		return "", false
+	}
+	for _, i := range ints {
+		b = append(b, byte(i))
+	}
+	return string(b), true
+}
+
+func setTag(tags map[string]string, key, value string) {
+	if value != "" {
+		tags[key] = value
+	}
+}
+
+// saveCursor writes the cursor of the last entry read to the cursor file,
+// the file is replaced so that it is never partially written.
+func (j *Journald) saveCursor() error {
+	if j.CursorFile == "" || j.cursor == "" || j.cursor == j.saved {
+		return nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(j.CursorFile), filepath.Base(j.CursorFile))
+	if err != nil {
+		return fmt.Errorf("saving cursor: %s", err)
+	}
+	_, err = tmp.WriteString(j.cursor + "\n")
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.CursorFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("saving cursor: %s", err)
+	}
+	j.saved = j.cursor
+	return nil
+}
+
+// checkPriority validates a priority like journalctl, a name or a number.
+func checkPriority(p string) error {
+	for _, name := range priorities {
+		if p == name {
+			return nil
+		}
+	}
+	if i, err := strconv.Atoi(p); err == nil && i >= 0 && i < len(priorities) {
+		return nil
+	}
+	return fmt.Errorf("invalid priority %q", p)
+}
+
+func init() {
+	inputs.Add("journald", func() telegraf.Input {
+		j := &Journald{}
+		if path, err := exec.LookPath("journalctl"); err == nil {
+			j.Journalctl = path
+		}
+		return j
+	})
+}
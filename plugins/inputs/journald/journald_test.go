@@ -0,0 +1,147 @@
+package journald
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var journalEntries = []string{
+	`{"__CURSOR":"s=1;i=1","__REALTIME_TIMESTAMP":"1500000000000000","PRIORITY":"6","_SYSTEMD_UNIT":"app.service","SYSLOG_IDENTIFIER":"app","MESSAGE":"requests,path=/ count=3i,duration=0.25"}`,
+	`{"__CURSOR":"s=1;i=2","__REALTIME_TIMESTAMP":"1500000001000000","PRIORITY":"6","_SYSTEMD_UNIT":"app.service","SYSLOG_IDENTIFIER":"app","MESSAGE":"not a metric"}`,
+	// messages that are not valid UTF-8 are exported as array of bytes
+	`{"__CURSOR":"s=1;i=3","__REALTIME_TIMESTAMP":"1500000002000000","PRIORITY":"3","_SYSTEMD_UNIT":"app.service","MESSAGE":[101,114,114,111,114,115,32,99,111,117,110,116,61,49,105]}`,
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command, it
+// prints the entries after the cursor and waits like journalctl --follow.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args[4:]
+	entries := journalEntries
+	for _, arg := range args {
+		switch {
+		case arg == "--lines=0":
+			entries = nil
+		case arg == "--after-cursor=s=1;i=0":
+		case arg == "--after-cursor=s=1;i=2":
+			entries = entries[2:]
+		case strings.HasPrefix(arg, "--after-cursor="):
+			fmt.Fprintf(os.Stderr, "unexpected cursor %s", arg)
+			os.Exit(1)
+		}
+	}
+	for _, e := range entries {
+		fmt.Println(e)
+	}
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+func newJournald(t *testing.T, cursorFile string) *Journald {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	j := &Journald{
+		Journalctl: "journalctl",
+		Units:      []string{"app.service"},
+		Priority:   "info",
+		CursorFile: cursorFile,
+	}
+	j.SetParser(parser)
+	return j
+}
+
+func TestJournald(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	dir, err := ioutil.TempDir("", "journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cursorFile := filepath.Join(dir, "cursor")
+
+	// without cursor only new entries are read
+	j := newJournald(t, cursorFile)
+	assert.Equal(t, []string{"--follow", "--output=json", "--no-pager", "--lines=0", "--unit=app.service", "--priority=info"}, j.args())
+
+	require.NoError(t, ioutil.WriteFile(cursorFile, []byte("s=1;i=0\n"), 0644))
+	j = newJournald(t, cursorFile)
+
+	var acc testutil.Accumulator
+	require.NoError(t, j.Start(&acc))
+	acc.Wait(2)
+	acc.WaitError(1)
+	j.Stop()
+
+	acc.AssertContainsTaggedFields(t, "requests",
+		map[string]interface{}{"count": int64(3), "duration": 0.25},
+		map[string]string{"path": "/", "unit": "app.service", "identifier": "app", "priority": "info"})
+	assert.True(t, acc.HasTimestamp("requests", time.Unix(1500000000, 0)))
+	acc.AssertContainsTaggedFields(t, "errors",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"unit": "app.service", "priority": "err"})
+	assert.Contains(t, acc.Errors[0].Error(), "not a metric")
+
+	b, err := ioutil.ReadFile(cursorFile)
+	require.NoError(t, err)
+	assert.Equal(t, "s=1;i=3\n", string(b))
+}
+
+func TestJournaldResume(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	dir, err := ioutil.TempDir("", "journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cursorFile := filepath.Join(dir, "cursor")
+	require.NoError(t, ioutil.WriteFile(cursorFile, []byte("s=1;i=2\n"), 0644))
+
+	j := newJournald(t, cursorFile)
+	var acc testutil.Accumulator
+	require.NoError(t, j.Start(&acc))
+	acc.Wait(1)
+
+	// the cursor is saved on each interval
+	require.NoError(t, j.Gather(&acc))
+	b, err := ioutil.ReadFile(cursorFile)
+	require.NoError(t, err)
+	assert.Equal(t, "s=1;i=3\n", string(b))
+
+	j.Stop()
+	assert.True(t, acc.HasMeasurement("errors"))
+	assert.False(t, acc.HasMeasurement("requests"))
+	assert.Empty(t, acc.Errors)
+}
+
+func TestJournaldInvalidConfig(t *testing.T) {
+	var acc testutil.Accumulator
+	j := newJournald(t, "")
+	j.Priority = "verbose"
+	require.Error(t, j.Start(&acc))
+
+	j = newJournald(t, "")
+	j.Journalctl = ""
+	require.Error(t, j.Start(&acc))
+}
@@ -0,0 +1,437 @@
+package jti_openconfig_telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/jti_openconfig_telemetry/auth"
+	"github.com/influxdata/telegraf/plugins/inputs/jti_openconfig_telemetry/oc"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type OpenConfigTelemetry struct {
+	Servers         []string
+	Sensors         []string
+	Username        string
+	Password        string
+	ClientID        string            `toml:"client_id"`
+	SampleFrequency internal.Duration `toml:"sample_frequency"`
+	StrAsTags       bool              `toml:"str_as_tags"`
+	RetryDelay      internal.Duration `toml:"retry_delay"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	sensors    []*sensor
+	grpcConns  []*grpc.ClientConn
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	acc        telegraf.Accumulator
+	decoder    *decoder
+	dialOption grpc.DialOption
+}
+
+var sampleConfig = `
+  ## List of device addresses to collect telemetry from
+  servers = ["localhost:1883"]
+
+  ## Authentication details. Username and password are must if device expects
+  ## authentication. Client ID must be unique when connecting from multiple instances
+  ## of telegraf to the same device
+  username = "user"
+  password = "pass"
+  client_id = "telegraf"
+
+  ## Frequency to get data
+  sample_frequency = "1000ms"
+
+  ## Sensors to subscribe for
+  ## A identifier for each sensor can be provided in path by separating with space
+  ## Else sensor path will be used as identifier
+  ## When identifier is used, we can provide a list of space separated sensors.
+  ## A single subscription will be created with all these sensors and data will
+  ## be saved to measurement with this identifier name
+  sensors = [
+   "/interfaces/",
+   "collection /components/ /lldp",
+  ]
+
+  ## We allow specifying sensor group level reporting rate. To do this, specify the
+  ## reporting rate in Duration at the beginning of sensor paths / collection
+  ## name. For entries without reporting rate, we use configured sample frequency
+  # sensors = [
+  #  "1000ms customReporting /interfaces /lldp",
+  #  "2000ms collection /components",
+  #  "/interfaces",
+  # ]
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Delay between retry attempts of failed RPC calls or streams. Defaults to 1000ms.
+  ## Failed streams/calls will not be retried if 0 is provided
+  retry_delay = "1000ms"
+
+  ## To treat all string values as tags, set this to true
+  str_as_tags = false
+`
+
+// sensor is a group of sensor paths subscribed to with a single request.
+type sensor struct {
+	measurementName string
+	reportingRate   uint32
+	pathList        []*telemetry.Path
+}
+
+func (m *OpenConfigTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *OpenConfigTelemetry) Description() string {
+	return "Read JTI OpenConfig Telemetry from listed sensors"
+}
+
+func (m *OpenConfigTelemetry) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+// parseSensors splits the configured sensor strings into sensor groups.
+// Each entry has the form "[reporting-rate] [name] path [path...]".
+func parseSensors(entries []string, defaultRate time.Duration) ([]*sensor, error) {
+	var sensors []*sensor
+	for _, entry := range entries {
+		spl := strings.Fields(entry)
+		if len(spl) == 0 {
+			continue
+		}
+
+		rate := defaultRate
+		if d, err := time.ParseDuration(spl[0]); err == nil {
+			rate = d
+			spl = spl[1:]
+		}
+		if len(spl) == 0 {
+			return nil, fmt.Errorf("sensor %q has no paths", entry)
+		}
+
+		s := &sensor{
+			reportingRate: uint32(rate / time.Millisecond),
+		}
+
+		// If the first element does not look like a path, it is the name of
+		// the measurement the sensor group is stored in.
+		if !strings.HasPrefix(spl[0], "/") {
+			s.measurementName = spl[0]
+			spl = spl[1:]
+			if len(spl) == 0 {
+				return nil, fmt.Errorf("sensor %q has no paths", entry)
+			}
+		} else {
+			s.measurementName = spl[0]
+		}
+
+		for _, path := range spl {
+			s.pathList = append(s.pathList, &telemetry.Path{
+				Path:            path,
+				SampleFrequency: s.reportingRate,
+			})
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors, nil
+}
+
+func (m *OpenConfigTelemetry) Start(acc telegraf.Accumulator) error {
+	m.acc = acc
+	m.decoder = &decoder{strAsTags: m.StrAsTags}
+
+	var err error
+	m.sensors, err = parseSensors(m.Sensors, m.SampleFrequency.Duration)
+	if err != nil {
+		return err
+	}
+	if len(m.sensors) == 0 {
+		return fmt.Errorf("no valid sensors configured")
+	}
+
+	tlscfg, err := internal.GetTLSConfig(m.SSLCert, m.SSLKey, m.SSLCA, m.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	if tlscfg != nil {
+		m.dialOption = grpc.WithTransportCredentials(credentials.NewTLS(tlscfg))
+	} else {
+		m.dialOption = grpc.WithInsecure()
+	}
+
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+
+	for _, server := range m.Servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			log.Printf("E! Invalid server address %q: %s", server, err)
+			continue
+		}
+
+		conn, err := grpc.Dial(server, m.dialOption)
+		if err != nil {
+			log.Printf("E! Failed to connect to %s: %s", server, err)
+			continue
+		}
+		m.grpcConns = append(m.grpcConns, conn)
+
+		if m.Username != "" && m.Password != "" && m.ClientID != "" {
+			lc := authentication.NewLoginClient(conn)
+			reply, err := lc.LoginCheck(ctx, &authentication.LoginRequest{
+				UserName: m.Username,
+				Password: m.Password,
+				ClientId: m.ClientID,
+			})
+			if err != nil {
+				log.Printf("E! Could not initiate login check for %s: %s", server, err)
+				continue
+			}
+			if !reply.Result {
+				log.Printf("E! Failed to authenticate the user for %s", server)
+				continue
+			}
+		}
+
+		client := telemetry.NewOpenConfigTelemetryClient(conn)
+		for _, s := range m.sensors {
+			m.wg.Add(1)
+			go m.collect(ctx, server, client, s)
+		}
+	}
+
+	log.Printf("I! Started the JTI OpenConfig telemetry service, servers: %v", m.Servers)
+	return nil
+}
+
+// collect subscribes to the sensor group and adds all received data to the
+// accumulator. The subscription is recreated after retry_delay when the
+// stream fails, unless the plugin is stopping.
+func (m *OpenConfigTelemetry) collect(
+	ctx context.Context,
+	server string,
+	client telemetry.OpenConfigTelemetryClient,
+	s *sensor,
+) {
+	defer m.wg.Done()
+
+	host, _, _ := net.SplitHostPort(server)
+	for {
+		stream, err := client.TelemetrySubscribe(ctx,
+			&telemetry.SubscriptionRequest{PathList: s.pathList})
+		if err == nil {
+			err = m.receive(stream, host, s)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		m.acc.AddError(fmt.Errorf("subscription to %s on %s failed: %s",
+			s.measurementName, server, err))
+		if m.RetryDelay.Duration == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.RetryDelay.Duration):
+		}
+	}
+}
+
+func (m *OpenConfigTelemetry) receive(
+	stream telemetry.OpenConfigTelemetry_TelemetrySubscribeClient,
+	host string,
+	s *sensor,
+) error {
+	for {
+		data, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		for _, g := range m.decoder.decode(data) {
+			g.tags["device"] = host
+			m.acc.AddFields(s.measurementName, g.fields, g.tags, g.timestamp)
+		}
+	}
+}
+
+func (m *OpenConfigTelemetry) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+	for _, conn := range m.grpcConns {
+		conn.Close()
+	}
+	m.grpcConns = nil
+}
+
+// group is a set of fields sharing the same tags in a single
+// OpenConfigData record.
+type group struct {
+	tags      map[string]string
+	fields    map[string]interface{}
+	timestamp time.Time
+}
+
+// predicateRe matches the predicates of an xpath element,
+// eg. [name='xe-0/0/0'] or [name=xe-0/0/0]
+var predicateRe = regexp.MustCompile(`\[([\w-]+)=('[^']*'|"[^"]*"|[^\]]*)\]`)
+
+type decoder struct {
+	strAsTags bool
+}
+
+// decode converts an OpenConfigData record into groups of fields. Predicates
+// in the keys are converted into tags named after the element path they
+// belong to, eg. /interfaces/interface[name='xe-0/0/0']/state/mtu becomes
+// the field /interfaces/interface/state/mtu with the tag
+// /interfaces/interface/@name=xe-0/0/0.
+func (d *decoder) decode(data *telemetry.OpenConfigData) []*group {
+	timestamp := time.Unix(0, int64(data.Timestamp)*int64(time.Millisecond))
+
+	var prefix string
+	var groups []*group
+	index := make(map[string]*group)
+
+	for _, kv := range data.Kv {
+		// __prefix__ is prepended to each of the following keys.
+		if kv.Key == "__prefix__" {
+			if kv.StrValue != nil {
+				prefix = *kv.StrValue
+			}
+			continue
+		}
+		// Skip other internal keys such as __timestamp__
+		if strings.HasPrefix(kv.Key, "__") {
+			continue
+		}
+
+		value := kv.Value()
+		if value == nil {
+			continue
+		}
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+
+		key := kv.Key
+		if !strings.HasPrefix(key, "/") {
+			key = prefix + key
+		}
+		name, tags := parseXPath(key)
+		tags["path"] = data.Path
+		if data.SystemId != "" {
+			tags["system_id"] = data.SystemId
+		}
+
+		id := tagsID(tags)
+		g, ok := index[id]
+		if !ok {
+			g = &group{
+				tags:      tags,
+				fields:    make(map[string]interface{}),
+				timestamp: timestamp,
+			}
+			index[id] = g
+			groups = append(groups, g)
+		}
+
+		// string values can be stored as tags instead of fields.
+		if str, ok := value.(string); ok && d.strAsTags {
+			g.tags[name] = str
+		} else {
+			g.fields[name] = value
+		}
+	}
+
+	// groups made of string tags only carry no fields
+	result := groups[:0]
+	for _, g := range groups {
+		if len(g.fields) > 0 {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// parseXPath extracts the predicates of the given xpath as tags and returns
+// the xpath without predicates.
+func parseXPath(xpath string) (string, map[string]string) {
+	tags := make(map[string]string)
+
+	var name string
+	rest := xpath
+	for {
+		loc := predicateRe.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			name += rest
+			break
+		}
+		name += rest[:loc[0]]
+		key := rest[loc[2]:loc[3]]
+		value := strings.Trim(rest[loc[4]:loc[5]], `'"`)
+		tags[name+"/@"+key] = value
+		rest = rest[loc[1]:]
+	}
+	return name, tags
+}
+
+func tagsID(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(tags[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+func init() {
+	inputs.Add("jti_openconfig_telemetry", func() telegraf.Input {
+		return &OpenConfigTelemetry{
+			RetryDelay:      internal.Duration{Duration: time.Second},
+			SampleFrequency: internal.Duration{Duration: time.Second},
+			StrAsTags:       false,
+		}
+	})
+}
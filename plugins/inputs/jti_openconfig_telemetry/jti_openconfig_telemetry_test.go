@@ -0,0 +1,144 @@
+package jti_openconfig_telemetry
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs/jti_openconfig_telemetry/oc"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func strPtr(s string) *string     { return &s }
+func uintPtr(u uint64) *uint64    { return &u }
+func floatPtr(f float64) *float64 { return &f }
+
+var data = &telemetry.OpenConfigData{
+	SystemId:  "router1",
+	Path:      "/interfaces/",
+	Timestamp: 1523000000000,
+	Kv: []*telemetry.KeyValue{
+		{Key: "__timestamp__", UintValue: uintPtr(1523000000000)},
+		{Key: "__prefix__", StrValue: strPtr("/interfaces/interface[name='xe-0/0/0']/")},
+		{Key: "state/counters/in-octets", UintValue: uintPtr(50)},
+		{Key: "state/counters/out-octets", UintValue: uintPtr(10)},
+		{Key: "state/oper-status", StrValue: strPtr("UP")},
+		{Key: "/interfaces/interface[name='xe-0/0/1']/state/counters/in-octets", UintValue: uintPtr(0)},
+		{Key: "/interfaces/interface[name='xe-0/0/1']/state/rate", DoubleValue: floatPtr(1.5)},
+	},
+}
+
+type testServer struct{}
+
+func (s *testServer) TelemetrySubscribe(
+	req *telemetry.SubscriptionRequest,
+	stream telemetry.OpenConfigTelemetry_TelemetrySubscribeServer,
+) error {
+	if err := stream.Send(data); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestParseXPath(t *testing.T) {
+	name, tags := parseXPath("/interfaces/interface[name='xe-0/0/0']/subinterfaces/subinterface[index=0]/state/mtu")
+	assert.Equal(t, "/interfaces/interface/subinterfaces/subinterface/state/mtu", name)
+	assert.Equal(t, map[string]string{
+		"/interfaces/interface/@name":                             "xe-0/0/0",
+		"/interfaces/interface/subinterfaces/subinterface/@index": "0",
+	}, tags)
+
+	name, tags = parseXPath("/system/state/hostname")
+	assert.Equal(t, "/system/state/hostname", name)
+	assert.Empty(t, tags)
+}
+
+func TestParseSensors(t *testing.T) {
+	sensors, err := parseSensors([]string{
+		"/interfaces/",
+		"collection /components/ /lldp",
+		"2000ms customReporting /interfaces /lldp",
+	}, time.Second)
+	require.NoError(t, err)
+	require.Len(t, sensors, 3)
+
+	assert.Equal(t, "/interfaces/", sensors[0].measurementName)
+	assert.Equal(t, uint32(1000), sensors[0].reportingRate)
+	assert.Len(t, sensors[0].pathList, 1)
+
+	assert.Equal(t, "collection", sensors[1].measurementName)
+	assert.Len(t, sensors[1].pathList, 2)
+	assert.Equal(t, "/lldp", sensors[1].pathList[1].Path)
+
+	assert.Equal(t, "customReporting", sensors[2].measurementName)
+	assert.Equal(t, uint32(2000), sensors[2].reportingRate)
+	assert.Equal(t, uint32(2000), sensors[2].pathList[0].SampleFrequency)
+
+	_, err = parseSensors([]string{"1000ms collection"}, time.Second)
+	assert.Error(t, err)
+}
+
+func TestDecodeStrAsTags(t *testing.T) {
+	d := &decoder{strAsTags: true}
+	groups := d.decode(data)
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "UP", groups[0].tags["/interfaces/interface/state/oper-status"])
+	assert.Equal(t, map[string]interface{}{
+		"/interfaces/interface/state/counters/in-octets":  uint64(50),
+		"/interfaces/interface/state/counters/out-octets": uint64(10),
+	}, groups[0].fields)
+}
+
+func TestOpenConfigTelemetry(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	telemetry.RegisterOpenConfigTelemetryServer(server, &testServer{})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	plugin := &OpenConfigTelemetry{
+		Servers:         []string{listener.Addr().String()},
+		Sensors:         []string{"/interfaces/"},
+		SampleFrequency: internal.Duration{Duration: time.Second},
+		RetryDelay:      internal.Duration{Duration: time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	acc.Wait(2)
+
+	device, _, _ := net.SplitHostPort(listener.Addr().String())
+	acc.AssertContainsTaggedFields(t, "/interfaces/",
+		map[string]interface{}{
+			"/interfaces/interface/state/counters/in-octets":  uint64(50),
+			"/interfaces/interface/state/counters/out-octets": uint64(10),
+			"/interfaces/interface/state/oper-status":         "UP",
+		},
+		map[string]string{
+			"device":                      device,
+			"path":                        "/interfaces/",
+			"system_id":                   "router1",
+			"/interfaces/interface/@name": "xe-0/0/0",
+		})
+	acc.AssertContainsTaggedFields(t, "/interfaces/",
+		map[string]interface{}{
+			"/interfaces/interface/state/counters/in-octets": uint64(0),
+			"/interfaces/interface/state/rate":               float64(1.5),
+		},
+		map[string]string{
+			"device":                      device,
+			"path":                        "/interfaces/",
+			"system_id":                   "router1",
+			"/interfaces/interface/@name": "xe-0/0/1",
+		})
+}
@@ -0,0 +1,233 @@
+// Package telemetry contains the message types and gRPC client of the Juniper
+// OpenConfig telemetry service. The types are wire compatible with Juniper's
+// oc.proto; members of the KeyValue value oneof are declared as optional
+// fields so that the decoder can tell which value was sent.
+package telemetry
+
+import (
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// SubscriptionRequest is sent by the collector to start a subscription.
+type SubscriptionRequest struct {
+	Input            *SubscriptionInput            `protobuf:"bytes,1,opt,name=input" json:"input,omitempty"`
+	PathList         []*Path                       `protobuf:"bytes,2,rep,name=path_list,json=pathList" json:"path_list,omitempty"`
+	AdditionalConfig *SubscriptionAdditionalConfig `protobuf:"bytes,3,opt,name=additional_config,json=additionalConfig" json:"additional_config,omitempty"`
+}
+
+func (m *SubscriptionRequest) Reset()         { *m = SubscriptionRequest{} }
+func (m *SubscriptionRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionRequest) ProtoMessage()    {}
+
+// SubscriptionInput lists the collectors the data should be streamed to.
+type SubscriptionInput struct {
+	CollectorList []*Collector `protobuf:"bytes,1,rep,name=collector_list,json=collectorList" json:"collector_list,omitempty"`
+}
+
+func (m *SubscriptionInput) Reset()         { *m = SubscriptionInput{} }
+func (m *SubscriptionInput) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionInput) ProtoMessage()    {}
+
+// Collector is the address of a data collector.
+type Collector struct {
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Port    uint32 `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+}
+
+func (m *Collector) Reset()         { *m = Collector{} }
+func (m *Collector) String() string { return proto.CompactTextString(m) }
+func (*Collector) ProtoMessage()    {}
+
+// Path is a sensor path to subscribe to.
+type Path struct {
+	Path              string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Filter            string `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+	SuppressUnchanged bool   `protobuf:"varint,3,opt,name=suppress_unchanged,json=suppressUnchanged,proto3" json:"suppress_unchanged,omitempty"`
+	MaxSilentInterval uint32 `protobuf:"varint,4,opt,name=max_silent_interval,json=maxSilentInterval,proto3" json:"max_silent_interval,omitempty"`
+	// Reporting rate in milliseconds
+	SampleFrequency uint32 `protobuf:"varint,5,opt,name=sample_frequency,json=sampleFrequency,proto3" json:"sample_frequency,omitempty"`
+	NeedEom         bool   `protobuf:"varint,6,opt,name=need_eom,json=needEom,proto3" json:"need_eom,omitempty"`
+}
+
+func (m *Path) Reset()         { *m = Path{} }
+func (m *Path) String() string { return proto.CompactTextString(m) }
+func (*Path) ProtoMessage()    {}
+
+// SubscriptionAdditionalConfig limits the lifetime of a subscription.
+type SubscriptionAdditionalConfig struct {
+	LimitRecords     int32 `protobuf:"varint,1,opt,name=limit_records,json=limitRecords,proto3" json:"limit_records,omitempty"`
+	LimitTimeSeconds int32 `protobuf:"varint,2,opt,name=limit_time_seconds,json=limitTimeSeconds,proto3" json:"limit_time_seconds,omitempty"`
+	NeedEos          bool  `protobuf:"varint,3,opt,name=need_eos,json=needEos,proto3" json:"need_eos,omitempty"`
+}
+
+func (m *SubscriptionAdditionalConfig) Reset()         { *m = SubscriptionAdditionalConfig{} }
+func (m *SubscriptionAdditionalConfig) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionAdditionalConfig) ProtoMessage()    {}
+
+// OpenConfigData is a single telemetry record streamed by the device.
+type OpenConfigData struct {
+	SystemId       string `protobuf:"bytes,1,opt,name=system_id,json=systemId,proto3" json:"system_id,omitempty"`
+	ComponentId    uint32 `protobuf:"varint,2,opt,name=component_id,json=componentId,proto3" json:"component_id,omitempty"`
+	SubComponentId uint32 `protobuf:"varint,3,opt,name=sub_component_id,json=subComponentId,proto3" json:"sub_component_id,omitempty"`
+	Path           string `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	SequenceNumber uint64 `protobuf:"varint,5,opt,name=sequence_number,json=sequenceNumber,proto3" json:"sequence_number,omitempty"`
+	// Timestamp in milliseconds since the epoch
+	Timestamp    uint64      `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Kv           []*KeyValue `protobuf:"bytes,7,rep,name=kv" json:"kv,omitempty"`
+	Delete       []*Delete   `protobuf:"bytes,8,rep,name=delete" json:"delete,omitempty"`
+	Eom          []*Eom      `protobuf:"bytes,9,rep,name=eom" json:"eom,omitempty"`
+	SyncResponse bool        `protobuf:"varint,10,opt,name=sync_response,json=syncResponse,proto3" json:"sync_response,omitempty"`
+}
+
+func (m *OpenConfigData) Reset()         { *m = OpenConfigData{} }
+func (m *OpenConfigData) String() string { return proto.CompactTextString(m) }
+func (*OpenConfigData) ProtoMessage()    {}
+
+// KeyValue is a single value of a telemetry record; at most one of the
+// value fields is set.
+type KeyValue struct {
+	Key         string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	DoubleValue *float64 `protobuf:"fixed64,5,opt,name=double_value,json=doubleValue" json:"double_value,omitempty"`
+	IntValue    *int64   `protobuf:"varint,6,opt,name=int_value,json=intValue" json:"int_value,omitempty"`
+	UintValue   *uint64  `protobuf:"varint,7,opt,name=uint_value,json=uintValue" json:"uint_value,omitempty"`
+	SintValue   *int64   `protobuf:"zigzag64,8,opt,name=sint_value,json=sintValue" json:"sint_value,omitempty"`
+	BoolValue   *bool    `protobuf:"varint,9,opt,name=bool_value,json=boolValue" json:"bool_value,omitempty"`
+	StrValue    *string  `protobuf:"bytes,10,opt,name=str_value,json=strValue" json:"str_value,omitempty"`
+	BytesValue  []byte   `protobuf:"bytes,11,opt,name=bytes_value,json=bytesValue" json:"bytes_value,omitempty"`
+}
+
+func (m *KeyValue) Reset()         { *m = KeyValue{} }
+func (m *KeyValue) String() string { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()    {}
+
+// Value returns the value carried by the KeyValue, or nil if none is set.
+func (m *KeyValue) Value() interface{} {
+	switch {
+	case m.DoubleValue != nil:
+		return *m.DoubleValue
+	case m.IntValue != nil:
+		return *m.IntValue
+	case m.UintValue != nil:
+		return *m.UintValue
+	case m.SintValue != nil:
+		return *m.SintValue
+	case m.BoolValue != nil:
+		return *m.BoolValue
+	case m.StrValue != nil:
+		return *m.StrValue
+	case m.BytesValue != nil:
+		return m.BytesValue
+	}
+	return nil
+}
+
+// Delete notifies the deletion of a path.
+type Delete struct {
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+}
+
+func (m *Delete) Reset()         { *m = Delete{} }
+func (m *Delete) String() string { return proto.CompactTextString(m) }
+func (*Delete) ProtoMessage()    {}
+
+// Eom marks the end of the data for a path.
+type Eom struct {
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+}
+
+func (m *Eom) Reset()         { *m = Eom{} }
+func (m *Eom) String() string { return proto.CompactTextString(m) }
+func (*Eom) ProtoMessage()    {}
+
+// OpenConfigTelemetryClient is the client API for the OpenConfigTelemetry
+// service.
+type OpenConfigTelemetryClient interface {
+	TelemetrySubscribe(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (OpenConfigTelemetry_TelemetrySubscribeClient, error)
+}
+
+type openConfigTelemetryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewOpenConfigTelemetryClient(cc *grpc.ClientConn) OpenConfigTelemetryClient {
+	return &openConfigTelemetryClient{cc}
+}
+
+func (c *openConfigTelemetryClient) TelemetrySubscribe(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (OpenConfigTelemetry_TelemetrySubscribeClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &serviceDesc.Streams[0], c.cc, "/telemetry.OpenConfigTelemetry/telemetrySubscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &openConfigTelemetryTelemetrySubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OpenConfigTelemetry_TelemetrySubscribeClient interface {
+	Recv() (*OpenConfigData, error)
+	grpc.ClientStream
+}
+
+type openConfigTelemetryTelemetrySubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *openConfigTelemetryTelemetrySubscribeClient) Recv() (*OpenConfigData, error) {
+	m := new(OpenConfigData)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OpenConfigTelemetryServer is the server API for the OpenConfigTelemetry
+// service, it is used to test the client.
+type OpenConfigTelemetryServer interface {
+	TelemetrySubscribe(*SubscriptionRequest, OpenConfigTelemetry_TelemetrySubscribeServer) error
+}
+
+func RegisterOpenConfigTelemetryServer(s *grpc.Server, srv OpenConfigTelemetryServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+func telemetrySubscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscriptionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OpenConfigTelemetryServer).TelemetrySubscribe(m, &openConfigTelemetryTelemetrySubscribeServer{stream})
+}
+
+type OpenConfigTelemetry_TelemetrySubscribeServer interface {
+	Send(*OpenConfigData) error
+	grpc.ServerStream
+}
+
+type openConfigTelemetryTelemetrySubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *openConfigTelemetryTelemetrySubscribeServer) Send(m *OpenConfigData) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "telemetry.OpenConfigTelemetry",
+	HandlerType: (*OpenConfigTelemetryServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "telemetrySubscribe",
+			Handler:       telemetrySubscribeHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "oc.proto",
+}
@@ -15,12 +15,16 @@ import (
 	cluster "github.com/bsm/sarama-cluster"
 )
 
+const defaultMaxUndeliveredMessages = 1000
+
 type Kafka struct {
 	ConsumerGroup string
 	Topics        []string
 	Brokers       []string
 	MaxMessageLen int
 
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	Cluster *cluster.Consumer
 
 	// Verify Kafka SSL Certificate
@@ -54,7 +58,11 @@ type Kafka struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+	// messages whose metrics are not delivered yet, by tracking id
+	undelivered map[telegraf.TrackingID]*sarama.ConsumerMessage
+	// no messages are read while paused by the agent
+	pause internal.Pause
 
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
@@ -92,6 +100,12 @@ var sampleConfig = `
   ## Maximum length of a message to consume, in bytes (default 0/unlimited);
   ## larger messages are dropped
   max_message_len = 65536
+
+  ## Maximum number of messages read before their metrics are written by the
+  ## outputs, the offset of a message is committed once its metrics are
+  ## written. Values larger than metric_buffer_limit of the agent may cause
+  ## the reading to stop until the outputs write the metrics.
+  # max_undelivered_messages = 1000
 `
 
 func (k *Kafka) SampleConfig() string {
@@ -111,7 +125,8 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	defer k.Unlock()
 	var clusterErr error
 
-	k.acc = acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
+	k.undelivered = make(map[telegraf.TrackingID]*sarama.ConsumerMessage)
 
 	config := cluster.NewConfig()
 	config.Consumer.Return.Errors = true
@@ -174,42 +189,76 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. The offset of a message is marked once its metrics
+// are delivered, at most max_undelivered_messages are read ahead. No messages
+// are read while it is paused.
 func (k *Kafka) receiver() {
 	for {
+		in := k.in
+		paused, pauseChanged := k.pause.State()
+		if paused || len(k.undelivered) >= k.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-k.done:
 			return
+		case <-pauseChanged:
 		case err := <-k.errs:
 			if err != nil {
 				k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
 			}
-		case msg := <-k.in:
+		case info := <-k.acc.Delivered():
+			msg, ok := k.undelivered[info.ID()]
+			if !ok {
+				continue
+			}
+			delete(k.undelivered, info.ID())
+			if info.Delivered() {
+				k.markOffset(msg)
+			}
+		case msg := <-in:
 			if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
 				k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
 					len(msg.Value), k.MaxMessageLen))
-			} else {
-				metrics, err := k.parser.Parse(msg.Value)
-				if err != nil {
-					k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
-						string(msg.Value), err.Error()))
-				}
-				for _, metric := range metrics {
-					k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-				}
+				k.markOffset(msg)
+				continue
 			}
 
-			if !k.doNotCommitMsgs {
-				// TODO(cam) this locking can be removed if this PR gets merged:
-				// https://github.com/wvanbergen/kafka/pull/84
-				k.Lock()
-				k.Cluster.MarkOffset(msg, "")
-				k.Unlock()
+			metrics, err := k.parser.Parse(msg.Value)
+			if err != nil {
+				k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
+					string(msg.Value), err.Error()))
+			}
+			if len(metrics) == 0 {
+				k.markOffset(msg)
+				continue
 			}
+			k.undelivered[k.acc.AddTrackingMetricGroup(metrics)] = msg
 		}
 	}
 }
 
+func (k *Kafka) markOffset(msg *sarama.ConsumerMessage) {
+	if !k.doNotCommitMsgs {
+		// TODO(cam) this locking can be removed if this PR gets merged:
+		// https://github.com/wvanbergen/kafka/pull/84
+		k.Lock()
+		k.Cluster.MarkOffset(msg, "")
+		k.Unlock()
+	}
+}
+
+// Pause stops reading the messages, they stay in the topics.
+func (k *Kafka) Pause() {
+	k.pause.Set(true)
+}
+
+// Resume reads the messages again.
+func (k *Kafka) Resume() {
+	k.pause.Set(false)
+}
+
 func (k *Kafka) Stop() {
 	k.Lock()
 	defer k.Unlock()
@@ -225,6 +274,8 @@ func (k *Kafka) Gather(acc telegraf.Accumulator) error {
 
 func init() {
 	inputs.Add("kafka_consumer", func() telegraf.Input {
-		return &Kafka{}
+		return &Kafka{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
@@ -3,7 +3,9 @@ package kafka_consumer
 import (
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -21,14 +23,16 @@ const (
 func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 	in := make(chan *sarama.ConsumerMessage, 1000)
 	k := Kafka{
-		ConsumerGroup:   "test",
-		Topics:          []string{"telegraf"},
-		Brokers:         []string{"localhost:9092"},
-		Offset:          "oldest",
-		in:              in,
-		doNotCommitMsgs: true,
-		errs:            make(chan error, 1000),
-		done:            make(chan struct{}),
+		ConsumerGroup:          "test",
+		Topics:                 []string{"telegraf"},
+		Brokers:                []string{"localhost:9092"},
+		Offset:                 "oldest",
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		in:                     in,
+		doNotCommitMsgs:        true,
+		errs:                   make(chan error, 1000),
+		done:                   make(chan struct{}),
+		undelivered:            make(map[telegraf.TrackingID]*sarama.ConsumerMessage),
 	}
 	return &k, in
 }
@@ -37,7 +41,7 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 func TestRunParser(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -48,11 +52,31 @@ func TestRunParser(t *testing.T) {
 	assert.Equal(t, acc.NFields(), 1)
 }
 
+// Test that no messages are read while paused
+func TestPause(t *testing.T) {
+	k, in := newTestKafka()
+	acc := testutil.Accumulator{}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
+	defer close(k.done)
+
+	k.parser, _ = parsers.NewInfluxParser()
+	k.Pause()
+	go k.receiver()
+	in <- saramaMsg(testMsg)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, acc.NFields())
+	assert.Len(t, in, 1)
+
+	k.Resume()
+	acc.Wait(1)
+	assert.Equal(t, 1, acc.NFields())
+}
+
 // Test that the parser ignores invalid messages
 func TestRunParserInvalidMsg(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -69,7 +93,7 @@ func TestDropOverlongMsg(t *testing.T) {
 	k, in := newTestKafka()
 	k.MaxMessageLen = maxMessageLen
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 	overlongMsg := strings.Repeat("v", maxMessageLen+1)
 
@@ -84,7 +108,7 @@ func TestDropOverlongMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -103,7 +127,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -122,7 +146,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewJSONParser("kafka_json_test", []string{}, nil)
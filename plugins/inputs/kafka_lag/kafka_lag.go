@@ -0,0 +1,335 @@
+package kafka_lag
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type KafkaLag struct {
+	Brokers        []string `toml:"brokers"`
+	ConsumerGroups []string `toml:"consumer_groups"`
+	Topics         []string `toml:"topics"`
+
+	tls.ClientConfig
+
+	// SASL Username
+	SASLUsername string `toml:"sasl_username"`
+	// SASL Password
+	SASLPassword string `toml:"sasl_password"`
+
+	client    sarama.Client
+	config    *sarama.Config
+	groups    filter.Filter
+	topics    filter.Filter
+	newClient func(brokers []string, config *sarama.Config) (sarama.Client, error)
+}
+
+var sampleConfig = `
+  ## kafka servers
+  brokers = ["localhost:9092"]
+
+  ## Consumer groups and topics to report the lag of, globs are supported.
+  ## By default the lag of all the consumer groups is reported for all the
+  ## topics they consume.
+  # consumer_groups = ["telegraf_metrics_consumers"]
+  # topics = ["telegraf"]
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+  ## Minimum and maximum TLS version, "TLS10", "TLS11", "TLS12" or "TLS13"
+  # tls_min_version = "TLS12"
+  # tls_max_version = "TLS12"
+  ## Accepted cipher suites, by default those of Go
+  # tls_cipher_suites = ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
+
+  ## Optional SASL Config
+  # sasl_username = "kafka"
+  # sasl_password = "secret"
+`
+
+func (k *KafkaLag) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *KafkaLag) Description() string {
+	return "Report the lag of the Kafka consumer groups"
+}
+
+func (k *KafkaLag) init() error {
+	var err error
+	k.groups, err = filter.Compile(k.ConsumerGroups)
+	if err != nil {
+		return fmt.Errorf("compiling consumer_groups: %s", err)
+	}
+	k.topics, err = filter.Compile(k.Topics)
+	if err != nil {
+		return fmt.Errorf("compiling topics: %s", err)
+	}
+
+	config := sarama.NewConfig()
+	// listing the consumer groups requires Kafka 0.9
+	config.Version = sarama.V0_9_0_0
+	config.ClientID = "telegraf"
+
+	tlsConfig, err := k.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	if k.ClientConfig.Enabled() {
+		log.Printf("D! TLS Enabled")
+		config.Net.TLS.Config = tlsConfig
+		config.Net.TLS.Enable = true
+	}
+	if k.SASLUsername != "" && k.SASLPassword != "" {
+		log.Printf("D! Using SASL auth with username '%s',",
+			k.SASLUsername)
+		config.Net.SASL.User = k.SASLUsername
+		config.Net.SASL.Password = k.SASLPassword
+		config.Net.SASL.Enable = true
+	}
+	k.config = config
+
+	if k.newClient == nil {
+		k.newClient = sarama.NewClient
+	}
+	return nil
+}
+
+// Gather reports the lag of the committed offsets of the consumer groups
+// behind the log end offsets of the partitions.
+func (k *KafkaLag) Gather(acc telegraf.Accumulator) error {
+	if k.config == nil {
+		if err := k.init(); err != nil {
+			return err
+		}
+	}
+	if k.client == nil {
+		client, err := k.newClient(k.Brokers, k.config)
+		if err != nil {
+			return fmt.Errorf("connecting to %v: %s", k.Brokers, err)
+		}
+		k.client = client
+	} else if err := k.client.RefreshMetadata(); err != nil {
+		// connect again on the next interval
+		k.client.Close()
+		k.client = nil
+		return fmt.Errorf("refreshing metadata: %s", err)
+	}
+
+	partitions, err := k.partitions()
+	if err != nil {
+		return err
+	}
+	ends, err := k.logEndOffsets(partitions)
+	if err != nil {
+		return err
+	}
+
+	groups, err := k.consumerGroups()
+	if err != nil {
+		acc.AddError(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, group := range groups {
+		wg.Add(1)
+		go func(group string) {
+			defer wg.Done()
+			acc.AddError(k.gatherGroup(acc, group, partitions, ends))
+		}(group)
+	}
+	wg.Wait()
+	return nil
+}
+
+// partitions returns the partitions of the topics.
+func (k *KafkaLag) partitions() (map[string][]int32, error) {
+	topics, err := k.client.Topics()
+	if err != nil {
+		return nil, fmt.Errorf("listing topics: %s", err)
+	}
+
+	partitions := make(map[string][]int32)
+	for _, topic := range topics {
+		// the offsets of the consumer groups are stored in a topic
+		if topic == "__consumer_offsets" {
+			continue
+		}
+		if k.topics != nil && !k.topics.Match(topic) {
+			continue
+		}
+		ids, err := k.client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("listing partitions of %s: %s", topic, err)
+		}
+		partitions[topic] = ids
+	}
+	return partitions, nil
+}
+
+// logEndOffsets returns the offsets of the next messages of the
+// partitions, the offsets are requested from their leaders.
+func (k *KafkaLag) logEndOffsets(partitions map[string][]int32) (map[string]map[int32]int64, error) {
+	requests := make(map[*sarama.Broker]*sarama.OffsetRequest)
+	for topic, ids := range partitions {
+		for _, id := range ids {
+			leader, err := k.client.Leader(topic, id)
+			if err != nil {
+				return nil, fmt.Errorf("leader of %s/%d: %s", topic, id, err)
+			}
+			request, ok := requests[leader]
+			if !ok {
+				request = &sarama.OffsetRequest{}
+				requests[leader] = request
+			}
+			request.AddBlock(topic, id, sarama.OffsetNewest, 1)
+		}
+	}
+
+	ends := make(map[string]map[int32]int64)
+	for broker, request := range requests {
+		response, err := broker.GetAvailableOffsets(request)
+		if err != nil {
+			return nil, fmt.Errorf("fetching offsets from %s: %s", broker.Addr(), err)
+		}
+		for topic, blocks := range response.Blocks {
+			for id, block := range blocks {
+				if block.Err != sarama.ErrNoError || len(block.Offsets) == 0 {
+					continue
+				}
+				if ends[topic] == nil {
+					ends[topic] = make(map[int32]int64)
+				}
+				ends[topic][id] = block.Offsets[0]
+			}
+		}
+	}
+	return ends, nil
+}
+
+// consumerGroups lists the consumer groups, each broker lists the groups it
+// coordinates.
+func (k *KafkaLag) consumerGroups() ([]string, error) {
+	var groups []string
+	var lastErr error
+	for _, broker := range k.client.Brokers() {
+		if err := broker.Open(k.config); err != nil && err != sarama.ErrAlreadyConnected {
+			lastErr = fmt.Errorf("connecting to %s: %s", broker.Addr(), err)
+			continue
+		}
+		response, err := broker.ListGroups(&sarama.ListGroupsRequest{})
+		if err == nil && response.Err != sarama.ErrNoError {
+			err = response.Err
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("listing groups of %s: %s", broker.Addr(), err)
+			continue
+		}
+		for group, protocolType := range response.Groups {
+			// connect and other clients also use group membership
+			if protocolType != "consumer" {
+				continue
+			}
+			if k.groups != nil && !k.groups.Match(group) {
+				continue
+			}
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups, lastErr
+}
+
+// gatherGroup reports the lag of a consumer group for the partitions it
+// committed offsets for.
+func (k *KafkaLag) gatherGroup(
+	acc telegraf.Accumulator,
+	group string,
+	partitions map[string][]int32,
+	ends map[string]map[int32]int64,
+) error {
+	coordinator, err := k.client.Coordinator(group)
+	if err != nil {
+		return fmt.Errorf("coordinator of %s: %s", group, err)
+	}
+
+	// version 1 fetches the offsets stored in Kafka instead of zookeeper
+	request := &sarama.OffsetFetchRequest{ConsumerGroup: group, Version: 1}
+	for topic, ids := range partitions {
+		for _, id := range ids {
+			request.AddPartition(topic, id)
+		}
+	}
+	response, err := coordinator.FetchOffset(request)
+	if err != nil {
+		return fmt.Errorf("fetching offsets of %s: %s", group, err)
+	}
+
+	for topic, blocks := range response.Blocks {
+		var total, max int64
+		var consumed bool
+		for id, block := range blocks {
+			// the group doesn't consume the partition
+			if block.Err != sarama.ErrNoError || block.Offset < 0 {
+				continue
+			}
+			end, ok := ends[topic][id]
+			if !ok {
+				continue
+			}
+			lag := end - block.Offset
+			if lag < 0 {
+				lag = 0
+			}
+			consumed = true
+			total += lag
+			if lag > max {
+				max = lag
+			}
+
+			acc.AddFields("kafka_lag",
+				map[string]interface{}{
+					"offset":         block.Offset,
+					"log_end_offset": end,
+					"lag":            lag,
+				},
+				map[string]string{
+					"group":     group,
+					"topic":     topic,
+					"partition": strconv.Itoa(int(id)),
+				})
+		}
+		if consumed {
+			acc.AddFields("kafka_lag_topic",
+				map[string]interface{}{
+					"lag":     total,
+					"max_lag": max,
+				},
+				map[string]string{
+					"group": group,
+					"topic": topic,
+				})
+		}
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("kafka_lag", func() telegraf.Input {
+		return &KafkaLag{}
+	})
+}
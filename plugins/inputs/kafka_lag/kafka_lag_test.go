@@ -0,0 +1,103 @@
+package kafka_lag
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	seed := sarama.NewMockBroker(t, 1)
+	defer seed.Close()
+	other := sarama.NewMockBroker(t, 2)
+	defer other.Close()
+
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetBroker(seed.Addr(), seed.BrokerID()).
+		SetBroker(other.Addr(), other.BrokerID()).
+		SetLeader("telegraf", 0, seed.BrokerID()).
+		SetLeader("telegraf", 1, other.BrokerID()).
+		SetLeader("logs", 0, seed.BrokerID()).
+		SetLeader("__consumer_offsets", 0, seed.BrokerID())
+	coordinators := sarama.NewMockConsumerMetadataResponse(t).
+		SetCoordinator("telegraf_metrics_consumers", seed).
+		SetCoordinator("logstash", other)
+
+	seed.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest":         metadata,
+		"ConsumerMetadataRequest": coordinators,
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("telegraf", 0, sarama.OffsetNewest, 1000).
+			SetOffset("logs", 0, sarama.OffsetNewest, 20),
+		"ListGroupsRequest": sarama.NewMockWrapper(&sarama.ListGroupsResponse{
+			Groups: map[string]string{
+				"telegraf_metrics_consumers": "consumer",
+				"connect-cluster":            "connect",
+			},
+		}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("telegraf_metrics_consumers", "telegraf", 0, 990, "", sarama.ErrNoError).
+			SetOffset("telegraf_metrics_consumers", "telegraf", 1, 500, "", sarama.ErrNoError).
+			SetOffset("telegraf_metrics_consumers", "logs", 0, -1, "", sarama.ErrNoError),
+	})
+	other.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest":         metadata,
+		"ConsumerMetadataRequest": coordinators,
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("telegraf", 1, sarama.OffsetNewest, 520),
+		"ListGroupsRequest": sarama.NewMockWrapper(&sarama.ListGroupsResponse{
+			Groups: map[string]string{"logstash": "consumer"},
+		}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("logstash", "logs", 0, 25, "", sarama.ErrNoError),
+	})
+
+	k := &KafkaLag{
+		Brokers: []string{seed.Addr()},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(k.Gather))
+	defer k.client.Close()
+
+	acc.AssertContainsTaggedFields(t, "kafka_lag",
+		map[string]interface{}{"offset": int64(990), "log_end_offset": int64(1000), "lag": int64(10)},
+		map[string]string{"group": "telegraf_metrics_consumers", "topic": "telegraf", "partition": "0"})
+	acc.AssertContainsTaggedFields(t, "kafka_lag",
+		map[string]interface{}{"offset": int64(500), "log_end_offset": int64(520), "lag": int64(20)},
+		map[string]string{"group": "telegraf_metrics_consumers", "topic": "telegraf", "partition": "1"})
+	acc.AssertContainsTaggedFields(t, "kafka_lag_topic",
+		map[string]interface{}{"lag": int64(30), "max_lag": int64(20)},
+		map[string]string{"group": "telegraf_metrics_consumers", "topic": "telegraf"})
+
+	// offsets committed after the log end offset was fetched
+	acc.AssertContainsTaggedFields(t, "kafka_lag",
+		map[string]interface{}{"offset": int64(25), "log_end_offset": int64(20), "lag": int64(0)},
+		map[string]string{"group": "logstash", "topic": "logs", "partition": "0"})
+
+	// no offsets committed and the topic of the offsets
+	assert.False(t, acc.HasPoint("kafka_lag_topic",
+		map[string]string{"group": "telegraf_metrics_consumers", "topic": "logs"}, "lag", int64(0)))
+	for _, m := range acc.Metrics {
+		assert.NotEqual(t, "connect-cluster", m.Tags["group"])
+		assert.NotEqual(t, "__consumer_offsets", m.Tags["topic"])
+	}
+	assert.Len(t, acc.Metrics, 5)
+
+	// only the selected groups and topics
+	k = &KafkaLag{
+		Brokers:        []string{seed.Addr()},
+		ConsumerGroups: []string{"telegraf_*"},
+		Topics:         []string{"tele*"},
+	}
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(k.Gather))
+	defer k.client.Close()
+	for _, m := range acc.Metrics {
+		assert.Equal(t, "telegraf_metrics_consumers", m.Tags["group"])
+		assert.Equal(t, "telegraf", m.Tags["topic"])
+	}
+	assert.Len(t, acc.Metrics, 3)
+}
@@ -0,0 +1,319 @@
+package libvirt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultAddress = "unix:///var/run/libvirt/libvirt-sock"
+
+// virt is the part of the libvirt RPC API used by the plugin.
+type virt interface {
+	Domains() ([]golibvirt.Domain, error)
+	DomainGetInfo(dom golibvirt.Domain) (uint8, uint64, uint64, uint16, uint64, error)
+	DomainGetXMLDesc(dom golibvirt.Domain, flags golibvirt.DomainXMLFlags) (string, error)
+	DomainMemoryStats(dom golibvirt.Domain, maxStats uint32, flags uint32) ([]golibvirt.DomainMemoryStat, error)
+	DomainBlockStats(dom golibvirt.Domain, path string) (int64, int64, int64, int64, int64, error)
+	DomainInterfaceStats(dom golibvirt.Domain, device string) (int64, int64, int64, int64, int64, int64, int64, int64, error)
+	Close() error
+}
+
+// client is a libvirt RPC connection.
+type client struct {
+	*golibvirt.Libvirt
+	conn net.Conn
+}
+
+// Close closes the connection without waiting for a reply of the daemon,
+// pending calls are abandoned.
+func (c *client) Close() error {
+	return c.conn.Close()
+}
+
+type Libvirt struct {
+	Address string
+	Domains []string
+	Timeout internal.Duration
+
+	virt    virt
+	connect func(address string, timeout time.Duration) (virt, error)
+}
+
+var sampleConfig = `
+  ## Address of the libvirt daemon, either the unix socket or the TCP
+  ## address of an unencrypted and unauthenticated listener.
+  ##   unix:///var/run/libvirt/libvirt-sock
+  ##   tcp://127.0.0.1:16509
+  # address = "unix:///var/run/libvirt/libvirt-sock"
+
+  ## Names of the domains to gather, all domains by default.
+  # domains = []
+
+  ## Timeout for connecting and querying the daemon.
+  # timeout = "5s"
+`
+
+func (l *Libvirt) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Libvirt) Description() string {
+	return "Read CPU, memory, block device and network interface stats of libvirt domains"
+}
+
+func (l *Libvirt) Gather(acc telegraf.Accumulator) error {
+	if l.virt == nil {
+		address := l.Address
+		if address == "" {
+			address = defaultAddress
+		}
+		v, err := l.connect(address, l.Timeout.Duration)
+		if err != nil {
+			return fmt.Errorf("connecting to libvirt at %s: %s", address, err)
+		}
+		l.virt = v
+	}
+
+	v := l.virt
+	err := withTimeout(l.Timeout.Duration, func() error {
+		return l.gather(acc, v)
+	})
+	if err != nil {
+		// reconnect on the next gather
+		v.Close()
+		l.virt = nil
+		return err
+	}
+	return nil
+}
+
+// withTimeout runs f and returns its error, or an error when f does not
+// return in time. The calls of the libvirt client block until the daemon
+// replies, they are abandoned by closing the connection.
+func withTimeout(timeout time.Duration, f func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timeout after %s", timeout)
+	}
+}
+
+func (l *Libvirt) gather(acc telegraf.Accumulator, v virt) error {
+	domains, err := v.Domains()
+	if err != nil {
+		return fmt.Errorf("listing domains: %s", err)
+	}
+
+	for _, dom := range domains {
+		if !l.selected(dom.Name) {
+			continue
+		}
+		if err := gatherDomain(acc, v, dom); err != nil {
+			acc.AddError(fmt.Errorf("domain %s: %s", dom.Name, err))
+		}
+	}
+	return nil
+}
+
+func (l *Libvirt) selected(name string) bool {
+	if len(l.Domains) == 0 {
+		return true
+	}
+	for _, d := range l.Domains {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+// domainXML is the part of the domain description needed to find the
+// block devices and network interfaces of the domain.
+type domainXML struct {
+	Title string `xml:"title"`
+	Disks []struct {
+		Target struct {
+			Dev string `xml:"dev,attr"`
+		} `xml:"target"`
+	} `xml:"devices>disk"`
+	Interfaces []struct {
+		Target struct {
+			Dev string `xml:"dev,attr"`
+		} `xml:"target"`
+	} `xml:"devices>interface"`
+}
+
+var memoryStats = map[golibvirt.DomainMemoryStatTags]string{
+	golibvirt.DomainMemoryStatSwapIn:        "memory_swap_in",
+	golibvirt.DomainMemoryStatSwapOut:       "memory_swap_out",
+	golibvirt.DomainMemoryStatMajorFault:    "memory_major_fault",
+	golibvirt.DomainMemoryStatMinorFault:    "memory_minor_fault",
+	golibvirt.DomainMemoryStatUnused:        "memory_unused",
+	golibvirt.DomainMemoryStatAvailable:     "memory_available",
+	golibvirt.DomainMemoryStatActualBalloon: "memory_actual_balloon",
+	golibvirt.DomainMemoryStatRss:           "memory_rss",
+	golibvirt.DomainMemoryStatUsable:        "memory_usable",
+}
+
+func gatherDomain(acc telegraf.Accumulator, v virt, dom golibvirt.Domain) error {
+	state, maxMem, memory, vcpus, cpuTime, err := v.DomainGetInfo(dom)
+	if err != nil {
+		return err
+	}
+	desc, err := v.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return err
+	}
+	var x domainXML
+	if err := xml.Unmarshal([]byte(desc), &x); err != nil {
+		return fmt.Errorf("parsing domain description: %s", err)
+	}
+
+	tags := map[string]string{
+		"domain": dom.Name,
+		"uuid":   formatUUID(dom.UUID),
+	}
+	if x.Title != "" {
+		tags["title"] = x.Title
+	}
+
+	fields := map[string]interface{}{
+		"state":      int(state),
+		"cpu_time":   int64(cpuTime),
+		"vcpus":      int(vcpus),
+		"memory_max": int64(maxMem) * 1024,
+		"memory":     int64(memory) * 1024,
+	}
+
+	// inactive domains have no id and no stats
+	if dom.ID == -1 {
+		acc.AddFields("libvirt_domain", fields, tags)
+		return nil
+	}
+
+	stats, err := v.DomainMemoryStats(dom, uint32(golibvirt.DomainMemoryStatNr), 0)
+	if err != nil {
+		return err
+	}
+	for _, s := range stats {
+		name, ok := memoryStats[golibvirt.DomainMemoryStatTags(s.Tag)]
+		if !ok {
+			continue
+		}
+		val := int64(s.Val)
+		// the sizes are reported in KiB
+		if s.Tag != int32(golibvirt.DomainMemoryStatMajorFault) && s.Tag != int32(golibvirt.DomainMemoryStatMinorFault) {
+			val *= 1024
+		}
+		fields[name] = val
+	}
+	acc.AddFields("libvirt_domain", fields, tags)
+
+	for _, disk := range x.Disks {
+		dev := disk.Target.Dev
+		if dev == "" {
+			continue
+		}
+		rdReq, rdBytes, wrReq, wrBytes, errs, err := v.DomainBlockStats(dom, dev)
+		if err != nil {
+			return fmt.Errorf("block device %s: %s", dev, err)
+		}
+		acc.AddFields("libvirt_block",
+			map[string]interface{}{
+				"rd_req":   rdReq,
+				"rd_bytes": rdBytes,
+				"wr_req":   wrReq,
+				"wr_bytes": wrBytes,
+				"errors":   errs,
+			},
+			deviceTags(tags, "device", dev))
+	}
+
+	for _, iface := range x.Interfaces {
+		dev := iface.Target.Dev
+		if dev == "" {
+			continue
+		}
+		rxBytes, rxPackets, rxErrs, rxDrop, txBytes, txPackets, txErrs, txDrop, err := v.DomainInterfaceStats(dom, dev)
+		if err != nil {
+			return fmt.Errorf("interface %s: %s", dev, err)
+		}
+		acc.AddFields("libvirt_interface",
+			map[string]interface{}{
+				"rx_bytes":   rxBytes,
+				"rx_packets": rxPackets,
+				"rx_errs":    rxErrs,
+				"rx_drop":    rxDrop,
+				"tx_bytes":   txBytes,
+				"tx_packets": txPackets,
+				"tx_errs":    txErrs,
+				"tx_drop":    txDrop,
+			},
+			deviceTags(tags, "interface", dev))
+	}
+	return nil
+}
+
+func deviceTags(domain map[string]string, key, dev string) map[string]string {
+	tags := map[string]string{key: dev}
+	for k, v := range domain {
+		tags[k] = v
+	}
+	return tags
+}
+
+func formatUUID(u golibvirt.UUID) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+func connect(address string, timeout time.Duration) (virt, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "unix":
+		conn, err = net.DialTimeout("unix", u.Path, timeout)
+	case "tcp":
+		conn, err = net.DialTimeout("tcp", u.Host, timeout)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{Libvirt: golibvirt.New(conn), conn: conn}
+	if err := withTimeout(timeout, c.Connect); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func init() {
+	inputs.Add("libvirt", func() telegraf.Input {
+		return &Libvirt{
+			Address: defaultAddress,
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			connect: connect,
+		}
+	})
+}
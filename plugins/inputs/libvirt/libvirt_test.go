@@ -0,0 +1,217 @@
+package libvirt
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDomainXML = `<domain type='kvm' id='1'>
+  <name>web01</name>
+  <uuid>6695eb01-f6a4-8304-79aa-97f2502e193f</uuid>
+  <title>Web server</title>
+  <memory unit='KiB'>2097152</memory>
+  <devices>
+    <disk type='file' device='disk'>
+      <source file='/var/lib/libvirt/images/web01.qcow2'/>
+      <target dev='vda' bus='virtio'/>
+    </disk>
+    <disk type='file' device='cdrom'>
+      <target dev='hda' bus='ide'/>
+    </disk>
+    <interface type='network'>
+      <mac address='52:54:00:6b:3c:58'/>
+      <target dev='vnet0'/>
+    </interface>
+  </devices>
+</domain>
+`
+
+var testUUID = golibvirt.UUID{0x66, 0x95, 0xeb, 0x01, 0xf6, 0xa4, 0x83, 0x04, 0x79, 0xaa, 0x97, 0xf2, 0x50, 0x2e, 0x19, 0x3f}
+
+type fakeVirt struct {
+	closed bool
+	block  chan struct{}
+}
+
+func (f *fakeVirt) Domains() ([]golibvirt.Domain, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	return []golibvirt.Domain{
+		{Name: "web01", UUID: testUUID, ID: 1},
+		{Name: "db01", ID: -1},
+		{Name: "broken", ID: 3},
+	}, nil
+}
+
+func (f *fakeVirt) DomainGetInfo(dom golibvirt.Domain) (uint8, uint64, uint64, uint16, uint64, error) {
+	if dom.Name == "db01" {
+		return uint8(golibvirt.DomainShutoff), 1048576, 0, 1, 0, nil
+	}
+	return uint8(golibvirt.DomainRunning), 2097152, 2097152, 2, 58460000000, nil
+}
+
+func (f *fakeVirt) DomainGetXMLDesc(dom golibvirt.Domain, flags golibvirt.DomainXMLFlags) (string, error) {
+	if dom.Name == "broken" {
+		return "", fmt.Errorf("domain not found")
+	}
+	if dom.Name == "db01" {
+		return "<domain><name>db01</name></domain>", nil
+	}
+	return testDomainXML, nil
+}
+
+func (f *fakeVirt) DomainMemoryStats(dom golibvirt.Domain, maxStats uint32, flags uint32) ([]golibvirt.DomainMemoryStat, error) {
+	return []golibvirt.DomainMemoryStat{
+		{Tag: int32(golibvirt.DomainMemoryStatMajorFault), Val: 230},
+		{Tag: int32(golibvirt.DomainMemoryStatActualBalloon), Val: 2097152},
+		{Tag: int32(golibvirt.DomainMemoryStatRss), Val: 524288},
+		{Tag: int32(golibvirt.DomainMemoryStatLastUpdate), Val: 1500000000},
+	}, nil
+}
+
+func (f *fakeVirt) DomainBlockStats(dom golibvirt.Domain, path string) (int64, int64, int64, int64, int64, error) {
+	if path == "hda" {
+		return 0, 0, 0, 0, -1, nil
+	}
+	return 8166, 226147328, 2751, 43331584, -1, nil
+}
+
+func (f *fakeVirt) DomainInterfaceStats(dom golibvirt.Domain, device string) (int64, int64, int64, int64, int64, int64, int64, int64, error) {
+	return 91025, 1108, 0, 0, 6760, 81, 0, 0, nil
+}
+
+func (f *fakeVirt) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestGather(t *testing.T) {
+	fake := &fakeVirt{}
+	l := &Libvirt{
+		Timeout: internal.Duration{Duration: time.Second},
+		connect: func(address string, timeout time.Duration) (virt, error) {
+			assert.Equal(t, defaultAddress, address)
+			return fake, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, l.Gather(&acc))
+
+	tags := map[string]string{
+		"domain": "web01",
+		"uuid":   "6695eb01-f6a4-8304-79aa-97f2502e193f",
+		"title":  "Web server",
+	}
+	acc.AssertContainsTaggedFields(t, "libvirt_domain",
+		map[string]interface{}{
+			"state":                 1,
+			"cpu_time":              int64(58460000000),
+			"vcpus":                 2,
+			"memory_max":            int64(2147483648),
+			"memory":                int64(2147483648),
+			"memory_major_fault":    int64(230),
+			"memory_actual_balloon": int64(2147483648),
+			"memory_rss":            int64(536870912),
+		},
+		tags)
+	acc.AssertContainsTaggedFields(t, "libvirt_block",
+		map[string]interface{}{
+			"rd_req":   int64(8166),
+			"rd_bytes": int64(226147328),
+			"wr_req":   int64(2751),
+			"wr_bytes": int64(43331584),
+			"errors":   int64(-1),
+		},
+		deviceTags(tags, "device", "vda"))
+	acc.AssertContainsTaggedFields(t, "libvirt_interface",
+		map[string]interface{}{
+			"rx_bytes":   int64(91025),
+			"rx_packets": int64(1108),
+			"rx_errs":    int64(0),
+			"rx_drop":    int64(0),
+			"tx_bytes":   int64(6760),
+			"tx_packets": int64(81),
+			"tx_errs":    int64(0),
+			"tx_drop":    int64(0),
+		},
+		deviceTags(tags, "interface", "vnet0"))
+
+	// inactive domains only report their configuration
+	acc.AssertContainsTaggedFields(t, "libvirt_domain",
+		map[string]interface{}{
+			"state":      5,
+			"cpu_time":   int64(0),
+			"vcpus":      1,
+			"memory_max": int64(1073741824),
+			"memory":     int64(0),
+		},
+		map[string]string{
+			"domain": "db01",
+			"uuid":   "00000000-0000-0000-0000-000000000000",
+		})
+
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "broken")
+	assert.Equal(t, uint64(5), acc.NMetrics())
+	assert.False(t, fake.closed)
+}
+
+func TestGatherDomains(t *testing.T) {
+	l := &Libvirt{
+		Domains: []string{"db01"},
+		Timeout: internal.Duration{Duration: time.Second},
+		connect: func(address string, timeout time.Duration) (virt, error) {
+			return &fakeVirt{}, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(l.Gather))
+	assert.Equal(t, uint64(1), acc.NMetrics())
+	assert.True(t, acc.HasTag("libvirt_domain", "domain"))
+	assert.Empty(t, acc.Errors)
+}
+
+func TestGatherTimeout(t *testing.T) {
+	fake := &fakeVirt{block: make(chan struct{})}
+	defer close(fake.block)
+	connects := 0
+	l := &Libvirt{
+		Timeout: internal.Duration{Duration: 10 * time.Millisecond},
+		connect: func(address string, timeout time.Duration) (virt, error) {
+			connects++
+			return fake, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(l.Gather))
+	assert.True(t, fake.closed)
+
+	// the plugin reconnects after a timeout
+	require.Error(t, acc.GatherError(l.Gather))
+	assert.Equal(t, 2, connects)
+}
+
+func TestConnectError(t *testing.T) {
+	l := &Libvirt{
+		Address: "unix:///nonexistent/libvirt-sock",
+		Timeout: internal.Duration{Duration: time.Second},
+		connect: connect,
+	}
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(l.Gather))
+
+	l.Address = "http://localhost:16509"
+	require.Error(t, acc.GatherError(l.Gather))
+}
@@ -9,7 +9,7 @@ import (
 
 	"github.com/influxdata/telegraf/testutil"
 
-	"github.com/influxdata/telegraf/plugins/inputs/logparser/grok"
+	"github.com/influxdata/telegraf/plugins/parsers/grok"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -17,7 +17,7 @@ import (
 func TestStartNoParsers(t *testing.T) {
 	logparser := &LogParserPlugin{
 		FromBeginning: true,
-		Files:         []string{"grok/testdata/*.log"},
+		Files:         []string{"testdata/*.log"},
 	}
 
 	acc := testutil.Accumulator{}
@@ -28,12 +28,12 @@ func TestGrokParseLogFilesNonExistPattern(t *testing.T) {
 	thisdir := getCurrentDir()
 	p := &grok.Parser{
 		Patterns:           []string{"%{FOOBAR}"},
-		CustomPatternFiles: []string{thisdir + "grok/testdata/test-patterns"},
+		CustomPatternFiles: []string{thisdir + "testdata/test-patterns"},
 	}
 
 	logparser := &LogParserPlugin{
 		FromBeginning: true,
-		Files:         []string{thisdir + "grok/testdata/*.log"},
+		Files:         []string{thisdir + "testdata/*.log"},
 		GrokParser:    p,
 	}
 
@@ -46,12 +46,12 @@ func TestGrokParseLogFiles(t *testing.T) {
 	thisdir := getCurrentDir()
 	p := &grok.Parser{
 		Patterns:           []string{"%{TEST_LOG_A}", "%{TEST_LOG_B}"},
-		CustomPatternFiles: []string{thisdir + "grok/testdata/test-patterns"},
+		CustomPatternFiles: []string{thisdir + "testdata/test-patterns"},
 	}
 
 	logparser := &LogParserPlugin{
 		FromBeginning: true,
-		Files:         []string{thisdir + "grok/testdata/*.log"},
+		Files:         []string{thisdir + "testdata/*.log"},
 		GrokParser:    p,
 	}
 
@@ -71,7 +71,7 @@ func TestGrokParseLogFiles(t *testing.T) {
 		},
 		map[string]string{
 			"response_code": "200",
-			"path":          thisdir + "grok/testdata/test_a.log",
+			"path":          thisdir + "testdata/test_a.log",
 		})
 
 	acc.AssertContainsTaggedFields(t, "logparser_grok",
@@ -81,7 +81,7 @@ func TestGrokParseLogFiles(t *testing.T) {
 			"nomodifier": "nomodifier",
 		},
 		map[string]string{
-			"path": thisdir + "grok/testdata/test_b.log",
+			"path": thisdir + "testdata/test_b.log",
 		})
 }
 
@@ -93,7 +93,7 @@ func TestGrokParseLogFilesAppearLater(t *testing.T) {
 	thisdir := getCurrentDir()
 	p := &grok.Parser{
 		Patterns:           []string{"%{TEST_LOG_A}", "%{TEST_LOG_B}"},
-		CustomPatternFiles: []string{thisdir + "grok/testdata/test-patterns"},
+		CustomPatternFiles: []string{thisdir + "testdata/test-patterns"},
 	}
 
 	logparser := &LogParserPlugin{
@@ -107,7 +107,7 @@ func TestGrokParseLogFilesAppearLater(t *testing.T) {
 
 	assert.Equal(t, acc.NFields(), 0)
 
-	_ = os.Symlink(thisdir+"grok/testdata/test_a.log", emptydir+"/test_a.log")
+	_ = os.Symlink(thisdir+"testdata/test_a.log", emptydir+"/test_a.log")
 	assert.NoError(t, acc.GatherError(logparser.Gather))
 	acc.Wait(1)
 
@@ -132,13 +132,13 @@ func TestGrokParseLogFilesOneBad(t *testing.T) {
 	thisdir := getCurrentDir()
 	p := &grok.Parser{
 		Patterns:           []string{"%{TEST_LOG_A}", "%{TEST_LOG_BAD}"},
-		CustomPatternFiles: []string{thisdir + "grok/testdata/test-patterns"},
+		CustomPatternFiles: []string{thisdir + "testdata/test-patterns"},
 	}
 	assert.NoError(t, p.Compile())
 
 	logparser := &LogParserPlugin{
 		FromBeginning: true,
-		Files:         []string{thisdir + "grok/testdata/test_a.log"},
+		Files:         []string{thisdir + "testdata/test_a.log"},
 		GrokParser:    p,
 	}
 
@@ -158,7 +158,7 @@ func TestGrokParseLogFilesOneBad(t *testing.T) {
 		},
 		map[string]string{
 			"response_code": "200",
-			"path":          thisdir + "grok/testdata/test_a.log",
+			"path":          thisdir + "testdata/test_a.log",
 		})
 }
 
@@ -0,0 +1,470 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	mb "github.com/goburrow/modbus"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Modbus holds all data relevant to the plugin
+type Modbus struct {
+	Name             string            `toml:"name"`
+	Controller       string            `toml:"controller"`
+	TransmissionMode string            `toml:"transmission_mode"`
+	BaudRate         int               `toml:"baud_rate"`
+	DataBits         int               `toml:"data_bits"`
+	Parity           string            `toml:"parity"`
+	StopBits         int               `toml:"stop_bits"`
+	SlaveID          int               `toml:"slave_id"`
+	Timeout          internal.Duration `toml:"timeout"`
+	DiscreteInputs   []fieldDefinition `toml:"discrete_inputs"`
+	Coils            []fieldDefinition `toml:"coils"`
+	HoldingRegisters []fieldDefinition `toml:"holding_registers"`
+	InputRegisters   []fieldDefinition `toml:"input_registers"`
+
+	registers []register
+	handler   handler
+	client    mb.Client
+	connected bool
+}
+
+// fieldDefinition describes a single field read from the device.
+type fieldDefinition struct {
+	Name      string  `toml:"name"`
+	Address   uint16  `toml:"address"`
+	DataType  string  `toml:"data_type"`
+	ByteOrder string  `toml:"byte_order"`
+	Scale     float64 `toml:"scale"`
+}
+
+// handler is implemented by the TCP, RTU and ASCII client handlers of the
+// modbus library.
+type handler interface {
+	mb.ClientHandler
+	Connect() error
+	Close() error
+}
+
+// register is a set of fields of the same register type read by a list of
+// requests.
+type register struct {
+	Type     string
+	requests []request
+}
+
+// request is a single read of contiguous addresses.
+type request struct {
+	address uint16
+	length  uint16
+	fields  []field
+}
+
+type field struct {
+	fieldDefinition
+	length uint16
+}
+
+const (
+	cDiscreteInputs   = "discrete_input"
+	cCoils            = "coil"
+	cHoldingRegisters = "holding_register"
+	cInputRegisters   = "input_register"
+
+	// maximum number of bits or registers allowed in a single read request
+	maxBitsPerRequest      = 2000
+	maxRegistersPerRequest = 125
+)
+
+const sampleConfig = `
+  ## Connection Configuration
+  ##
+  ## The plugin supports connections to PLCs via MODBUS/TCP or
+  ## via serial line communication in binary (RTU) or readable (ASCII) encoding
+  ##
+  ## Device name
+  name = "Device"
+
+  ## Slave ID - addresses a MODBUS device on the bus
+  ## Range: 0 - 255 [0 = broadcast; 248 - 255 = reserved]
+  slave_id = 1
+
+  ## Timeout for each request
+  timeout = "1s"
+
+  # TCP - connect via Modbus/TCP
+  controller = "tcp://localhost:502"
+
+  # Serial (RS485; RS232)
+  # controller = "file:///dev/ttyUSB0"
+  # baud_rate = 9600
+  # data_bits = 8
+  # parity = "N"
+  # stop_bits = 1
+  # transmission_mode = "RTU"
+
+  ## Measurements
+  ##
+
+  ## Digital Variables, Discrete Inputs and Coils
+  ## name    - the variable name
+  ## address - variable address
+  discrete_inputs = [
+    { name = "start", address = 0 },
+    { name = "stop",  address = 1 },
+  ]
+  coils = [
+    { name = "motor_on", address = 0 },
+  ]
+
+  ## Analog Variables, Input Registers and Holding Registers
+  ## name       - the variable name
+  ## address    - address of the first register of the variable, requests
+  ##              are grouped into as few reads of contiguous registers as
+  ##              possible
+  ## data_type  - INT16, UINT16, INT32, UINT32, INT64, UINT64, FLOAT32 or
+  ##              FLOAT64 (IEEE 754), defaults to INT16
+  ## byte_order - the order of the bytes as sent by the device, A is the most
+  ##              significant byte:
+  ##              16 bit: AB, BA
+  ##              32 bit: ABCD, CDAB, BADC, DCBA
+  ##              64 bit: ABCDEFGH, GHEFCDAB, BADCFEHG, HGFEDCBA
+  ##              defaults to big endian (AB, ABCD, ABCDEFGH)
+  ## scale      - the final numeric variable representation, the value is
+  ##              multiplied by scale and reported as float when set
+  holding_registers = [
+    { name = "power_factor", address = 8, data_type = "INT16", scale = 0.01 },
+    { name = "voltage",      address = 0, data_type = "FLOAT32", byte_order = "CDAB" },
+    { name = "energy",       address = 2, data_type = "UINT32", scale = 0.001 },
+  ]
+  input_registers = [
+    { name = "tank_level", address = 0, data_type = "UINT16" },
+  ]
+`
+
+// SampleConfig returns a basic configuration for the plugin
+func (m *Modbus) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a short description of what the plugin does
+func (m *Modbus) Description() string {
+	return "Retrieve data from MODBUS slave devices"
+}
+
+func (m *Modbus) init() error {
+	if m.Name == "" {
+		return fmt.Errorf("device name is empty")
+	}
+	if m.SlaveID < 0 || m.SlaveID > 255 {
+		return fmt.Errorf("invalid slave id %d", m.SlaveID)
+	}
+
+	registers := []struct {
+		Type   string
+		fields []fieldDefinition
+	}{
+		{cDiscreteInputs, m.DiscreteInputs},
+		{cCoils, m.Coils},
+		{cHoldingRegisters, m.HoldingRegisters},
+		{cInputRegisters, m.InputRegisters},
+	}
+
+	m.registers = nil
+	for _, r := range registers {
+		if len(r.fields) == 0 {
+			continue
+		}
+		requests, err := newRequests(r.Type, r.fields)
+		if err != nil {
+			return err
+		}
+		m.registers = append(m.registers, register{r.Type, requests})
+	}
+
+	return m.initHandler()
+}
+
+func (m *Modbus) initHandler() error {
+	u, err := url.Parse(m.Controller)
+	if err != nil {
+		return err
+	}
+
+	timeout := m.Timeout.Duration
+	if timeout == 0 {
+		timeout = time.Second
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		h := mb.NewTCPClientHandler(u.Host)
+		h.Timeout = timeout
+		h.SlaveId = byte(m.SlaveID)
+		m.handler = h
+	case "file":
+		switch m.TransmissionMode {
+		case "", "RTU":
+			h := mb.NewRTUClientHandler(u.Path)
+			h.Timeout = timeout
+			h.SlaveId = byte(m.SlaveID)
+			h.BaudRate = m.BaudRate
+			h.DataBits = m.DataBits
+			h.Parity = m.Parity
+			h.StopBits = m.StopBits
+			m.handler = h
+		case "ASCII":
+			h := mb.NewASCIIClientHandler(u.Path)
+			h.Timeout = timeout
+			h.SlaveId = byte(m.SlaveID)
+			h.BaudRate = m.BaudRate
+			h.DataBits = m.DataBits
+			h.Parity = m.Parity
+			h.StopBits = m.StopBits
+			m.handler = h
+		default:
+			return fmt.Errorf("invalid transmission mode %q", m.TransmissionMode)
+		}
+	default:
+		return fmt.Errorf("invalid controller %q", m.Controller)
+	}
+
+	m.client = mb.NewClient(m.handler)
+	return nil
+}
+
+// newRequests validates the field definitions and groups them into as few
+// reads of contiguous addresses as possible.
+func newRequests(registerType string, definitions []fieldDefinition) ([]request, error) {
+	maxLength := uint16(maxRegistersPerRequest)
+	if registerType == cDiscreteInputs || registerType == cCoils {
+		maxLength = maxBitsPerRequest
+	}
+
+	names := make(map[string]bool)
+	fields := make([]field, 0, len(definitions))
+	for _, def := range definitions {
+		if def.Name == "" {
+			return nil, fmt.Errorf("empty field name in %s", registerType)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("duplicate field name %q in %s", def.Name, registerType)
+		}
+		names[def.Name] = true
+
+		f := field{fieldDefinition: def, length: 1}
+		if registerType == cHoldingRegisters || registerType == cInputRegisters {
+			if f.DataType == "" {
+				f.DataType = "INT16"
+			}
+			length, ok := registerLength[f.DataType]
+			if !ok {
+				return nil, fmt.Errorf("invalid data type %q for field %q", f.DataType, f.Name)
+			}
+			f.length = length
+			if f.ByteOrder == "" {
+				f.ByteOrder = "ABCDEFGH"[:2*length]
+			}
+			if err := validateByteOrder(f.ByteOrder, length); err != nil {
+				return nil, fmt.Errorf("field %q: %s", f.Name, err)
+			}
+		}
+		if int(f.Address)+int(f.length) > math.MaxUint16+1 {
+			return nil, fmt.Errorf("field %q exceeds the address space", f.Name)
+		}
+		fields = append(fields, f)
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Address < fields[j].Address
+	})
+
+	var requests []request
+	for _, f := range fields {
+		n := len(requests)
+		if n > 0 {
+			r := &requests[n-1]
+			end := int(r.address) + int(r.length)
+			fieldEnd := int(f.Address) + int(f.length)
+			if int(f.Address) <= end && fieldEnd-int(r.address) <= int(maxLength) {
+				if fieldEnd > end {
+					r.length = uint16(fieldEnd - int(r.address))
+				}
+				r.fields = append(r.fields, f)
+				continue
+			}
+		}
+		requests = append(requests, request{
+			address: f.Address,
+			length:  f.length,
+			fields:  []field{f},
+		})
+	}
+	return requests, nil
+}
+
+// registerLength is the number of registers used by each data type
+var registerLength = map[string]uint16{
+	"INT16":   1,
+	"UINT16":  1,
+	"INT32":   2,
+	"UINT32":  2,
+	"FLOAT32": 2,
+	"INT64":   4,
+	"UINT64":  4,
+	"FLOAT64": 4,
+}
+
+// validateByteOrder checks that the byte order is a permutation of the
+// letters used to name the bytes of the value.
+func validateByteOrder(order string, length uint16) error {
+	if len(order) != int(2*length) {
+		return fmt.Errorf("invalid byte order %q", order)
+	}
+	seen := make(map[byte]bool)
+	for i := 0; i < len(order); i++ {
+		c := order[i]
+		if c < 'A' || int(c-'A') >= len(order) || seen[c] {
+			return fmt.Errorf("invalid byte order %q", order)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+// Gather implements the telegraf plugin interface method for data accumulation
+func (m *Modbus) Gather(acc telegraf.Accumulator) error {
+	if m.client == nil {
+		if err := m.init(); err != nil {
+			return err
+		}
+	}
+
+	if !m.connected {
+		if err := m.handler.Connect(); err != nil {
+			return err
+		}
+		m.connected = true
+	}
+
+	now := time.Now()
+	for _, r := range m.registers {
+		fields := make(map[string]interface{})
+		for _, req := range r.requests {
+			data, err := m.read(r.Type, req)
+			if err != nil {
+				// drop the connection so that it is reestablished on the
+				// next interval
+				m.handler.Close()
+				m.connected = false
+				return fmt.Errorf("reading %s %d-%d from %s failed: %s",
+					r.Type, req.address, int(req.address)+int(req.length)-1, m.Controller, err)
+			}
+			if err := req.decode(r.Type, data, fields); err != nil {
+				acc.AddError(err)
+			}
+		}
+
+		tags := map[string]string{
+			"name":     m.Name,
+			"type":     r.Type,
+			"slave_id": strconv.Itoa(m.SlaveID),
+		}
+		acc.AddFields("modbus", fields, tags, now)
+	}
+
+	return nil
+}
+
+func (m *Modbus) read(registerType string, r request) ([]byte, error) {
+	switch registerType {
+	case cDiscreteInputs:
+		return m.client.ReadDiscreteInputs(r.address, r.length)
+	case cCoils:
+		return m.client.ReadCoils(r.address, r.length)
+	case cHoldingRegisters:
+		return m.client.ReadHoldingRegisters(r.address, r.length)
+	case cInputRegisters:
+		return m.client.ReadInputRegisters(r.address, r.length)
+	}
+	return nil, fmt.Errorf("unknown register type %q", registerType)
+}
+
+// decode converts the response of the request into fields.
+func (r request) decode(registerType string, data []byte, fields map[string]interface{}) error {
+	if registerType == cDiscreteInputs || registerType == cCoils {
+		if len(data)*8 < int(r.length) {
+			return fmt.Errorf("short response reading %s at %d", registerType, r.address)
+		}
+		for _, f := range r.fields {
+			bit := f.Address - r.address
+			fields[f.Name] = uint16((data[bit/8] >> (bit % 8)) & 1)
+		}
+		return nil
+	}
+
+	if len(data) < 2*int(r.length) {
+		return fmt.Errorf("short response reading %s at %d", registerType, r.address)
+	}
+	for _, f := range r.fields {
+		offset := 2 * int(f.Address-r.address)
+		fields[f.Name] = f.convert(data[offset : offset+2*int(f.length)])
+	}
+	return nil
+}
+
+// convert reorders the bytes of the registers into big endian order and
+// converts them into the configured data type.
+func (f field) convert(raw []byte) interface{} {
+	buf := make([]byte, len(raw))
+	for i := 0; i < len(raw); i++ {
+		buf[f.ByteOrder[i]-'A'] = raw[i]
+	}
+
+	var value interface{}
+	var number float64
+	switch f.DataType {
+	case "INT16":
+		v := int16(binary.BigEndian.Uint16(buf))
+		value, number = int64(v), float64(v)
+	case "UINT16":
+		v := binary.BigEndian.Uint16(buf)
+		value, number = uint64(v), float64(v)
+	case "INT32":
+		v := int32(binary.BigEndian.Uint32(buf))
+		value, number = int64(v), float64(v)
+	case "UINT32":
+		v := binary.BigEndian.Uint32(buf)
+		value, number = uint64(v), float64(v)
+	case "INT64":
+		v := int64(binary.BigEndian.Uint64(buf))
+		value, number = v, float64(v)
+	case "UINT64":
+		v := binary.BigEndian.Uint64(buf)
+		value, number = v, float64(v)
+	case "FLOAT32":
+		v := float64(math.Float32frombits(binary.BigEndian.Uint32(buf)))
+		value, number = v, v
+	case "FLOAT64":
+		v := math.Float64frombits(binary.BigEndian.Uint64(buf))
+		value, number = v, v
+	}
+
+	if f.Scale != 0 && f.Scale != 1 {
+		return number * f.Scale
+	}
+	return value
+}
+
+// Add this plugin to telegraf
+func init() {
+	inputs.Add("modbus", func() telegraf.Input { return &Modbus{} })
+}
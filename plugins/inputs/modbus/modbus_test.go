@@ -0,0 +1,228 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is a minimal MODBUS/TCP slave serving the read functions from
+// fixed bit and register tables.
+type fakeServer struct {
+	listener  net.Listener
+	bits      []byte
+	registers []uint16
+	requests  int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &fakeServer{
+		listener:  l,
+		bits:      make([]byte, 16),
+		registers: make([]uint16, 16),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		pdu := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+		s.requests++
+
+		function := pdu[0]
+		address := binary.BigEndian.Uint16(pdu[1:])
+		quantity := binary.BigEndian.Uint16(pdu[3:])
+
+		var data []byte
+		switch function {
+		case 1, 2:
+			data = make([]byte, (quantity+7)/8)
+			for i := uint16(0); i < quantity; i++ {
+				if s.bits[address+i] != 0 {
+					data[i/8] |= 1 << (i % 8)
+				}
+			}
+		case 3, 4:
+			data = make([]byte, 2*quantity)
+			for i := uint16(0); i < quantity; i++ {
+				binary.BigEndian.PutUint16(data[2*i:], s.registers[address+i])
+			}
+		}
+
+		resp := make([]byte, 9, 9+len(data))
+		copy(resp, header)
+		binary.BigEndian.PutUint16(resp[4:], uint16(3+len(data)))
+		resp[7] = function
+		resp[8] = byte(len(data))
+		resp = append(resp, data...)
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func TestNewRequests(t *testing.T) {
+	requests, err := newRequests(cHoldingRegisters, []fieldDefinition{
+		{Name: "c", Address: 10},
+		{Name: "a", Address: 0, DataType: "FLOAT32"},
+		{Name: "b", Address: 2, DataType: "UINT64"},
+	})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	assert.Equal(t, uint16(0), requests[0].address)
+	assert.Equal(t, uint16(6), requests[0].length)
+	require.Len(t, requests[0].fields, 2)
+	assert.Equal(t, "ABCD", requests[0].fields[0].ByteOrder)
+	assert.Equal(t, "ABCDEFGH", requests[0].fields[1].ByteOrder)
+
+	assert.Equal(t, uint16(10), requests[1].address)
+	assert.Equal(t, uint16(1), requests[1].length)
+	assert.Equal(t, "INT16", requests[1].fields[0].DataType)
+
+	// requests are split at the protocol limit
+	var defs []fieldDefinition
+	for i := 0; i < 130; i++ {
+		defs = append(defs, fieldDefinition{Name: string(rune('a'+i%26)) + string(rune('a'+i/26)), Address: uint16(i)})
+	}
+	requests, err = newRequests(cInputRegisters, defs)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, uint16(125), requests[0].length)
+	assert.Equal(t, uint16(5), requests[1].length)
+}
+
+func TestNewRequestsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		defs []fieldDefinition
+	}{
+		{"empty name", []fieldDefinition{{Address: 0}}},
+		{"duplicate name", []fieldDefinition{{Name: "a"}, {Name: "a", Address: 1}}},
+		{"invalid data type", []fieldDefinition{{Name: "a", DataType: "INT8"}}},
+		{"invalid byte order", []fieldDefinition{{Name: "a", DataType: "INT32", ByteOrder: "AB"}}},
+		{"repeated byte", []fieldDefinition{{Name: "a", ByteOrder: "AA"}}},
+		{"address overflow", []fieldDefinition{{Name: "a", Address: 65535, DataType: "INT32"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRequests(cHoldingRegisters, tt.defs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    fieldDefinition
+		raw      []byte
+		expected interface{}
+	}{
+		{"int16", fieldDefinition{DataType: "INT16", ByteOrder: "AB"}, []byte{0xff, 0xfe}, int64(-2)},
+		{"uint16 swapped", fieldDefinition{DataType: "UINT16", ByteOrder: "BA"}, []byte{0x01, 0x02}, uint64(0x0201)},
+		{"int32 word swapped", fieldDefinition{DataType: "INT32", ByteOrder: "CDAB"}, []byte{0x00, 0x02, 0x00, 0x01}, int64(0x00010002)},
+		{"uint32 little endian", fieldDefinition{DataType: "UINT32", ByteOrder: "DCBA"}, []byte{0x04, 0x03, 0x02, 0x01}, uint64(0x01020304)},
+		{"float32", fieldDefinition{DataType: "FLOAT32", ByteOrder: "ABCD"}, []byte{0x40, 0x49, 0x0f, 0xdb}, float64(float32(3.1415927))},
+		{"uint64 byte swapped", fieldDefinition{DataType: "UINT64", ByteOrder: "BADCFEHG"}, []byte{2, 1, 4, 3, 6, 5, 8, 7}, uint64(0x0102030405060708)},
+		{"float64", fieldDefinition{DataType: "FLOAT64", ByteOrder: "ABCDEFGH"}, []byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, float64(1.5)},
+		{"scaled", fieldDefinition{DataType: "INT16", ByteOrder: "AB", Scale: 0.1}, []byte{0x00, 0x0f}, float64(1.5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := field{fieldDefinition: tt.field}
+			assert.InDelta(t, tt.expected, f.convert(tt.raw), 1e-9)
+			assert.IsType(t, tt.expected, f.convert(tt.raw))
+		})
+	}
+}
+
+func TestGather(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.listener.Close()
+
+	s.bits[0] = 1
+	s.bits[9] = 1
+	// FLOAT32 3.1415927 in CDAB order
+	s.registers[0] = 0x0fdb
+	s.registers[1] = 0x4049
+	s.registers[2] = 1234
+	s.registers[3] = 0xfffe
+
+	m := &Modbus{
+		Name:       "plc",
+		Controller: "tcp://" + s.listener.Addr().String(),
+		SlaveID:    1,
+		Coils: []fieldDefinition{
+			{Name: "motor_on", Address: 0},
+			{Name: "pump_on", Address: 9},
+			{Name: "valve_open", Address: 1},
+		},
+		HoldingRegisters: []fieldDefinition{
+			{Name: "pi", Address: 0, DataType: "FLOAT32", ByteOrder: "CDAB"},
+			{Name: "level", Address: 2, DataType: "UINT16", Scale: 0.1},
+			{Name: "offset", Address: 3},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	defer m.handler.Close()
+
+	acc.AssertContainsTaggedFields(t, "modbus",
+		map[string]interface{}{
+			"motor_on":   uint16(1),
+			"pump_on":    uint16(1),
+			"valve_open": uint16(0),
+		},
+		map[string]string{"name": "plc", "type": "coil", "slave_id": "1"})
+
+	require.True(t, acc.HasPoint("modbus",
+		map[string]string{"name": "plc", "type": "holding_register", "slave_id": "1"},
+		"offset", int64(-2)))
+	pi, ok := acc.FloatField("modbus", "pi")
+	require.True(t, ok)
+	assert.InDelta(t, 3.1415927, pi, 1e-6)
+	level, ok := acc.FloatField("modbus", "level")
+	require.True(t, ok)
+	assert.InDelta(t, 123.4, level, 1e-9)
+
+	// contiguous addresses are read with a single request
+	assert.Equal(t, 3, s.requests)
+}
+
+func TestInvalidController(t *testing.T) {
+	m := &Modbus{
+		Name:       "plc",
+		Controller: "udp://localhost:502",
+	}
+	var acc testutil.Accumulator
+	assert.Error(t, m.Gather(&acc))
+}
@@ -18,6 +18,8 @@ import (
 // 30 Seconds is the default used by paho.mqtt.golang
 var defaultConnectionTimeout = internal.Duration{Duration: 30 * time.Second}
 
+const defaultMaxUndeliveredMessages = 1000
+
 type MQTTConsumer struct {
 	Servers           []string
 	Topics            []string
@@ -26,6 +28,8 @@ type MQTTConsumer struct {
 	QoS               int               `toml:"qos"`
 	ConnectionTimeout internal.Duration `toml:"connection_timeout"`
 
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	parser parsers.Parser
 
 	// Legacy metric buffer support
@@ -50,7 +54,11 @@ type MQTTConsumer struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+	// number of messages whose metrics are not delivered yet
+	undelivered int
+	// no messages are read while paused by the agent
+	pause internal.Pause
 
 	connected bool
 }
@@ -65,6 +73,11 @@ var sampleConfig = `
   ## Connection timeout for initial connection in seconds
   connection_timeout = "30s"
 
+  ## Maximum number of messages read before their metrics are written by the
+  ## outputs, the next messages are not read from the connection. The
+  ## messages are acknowledged to the broker when they are read.
+  # max_undelivered_messages = 1000
+
   ## Topics to subscribe to
   topics = [
     "telegraf/host01/cpu",
@@ -119,7 +132,8 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 			" = true, you MUST also set client_id")
 	}
 
-	m.acc = acc
+	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
+	m.undelivered = 0
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("MQTT Consumer, invalid QoS value: %d", m.QoS)
 	}
@@ -179,25 +193,38 @@ func (m *MQTTConsumer) onConnectionLost(c mqtt.Client, err error) {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. At most max_undelivered_messages are read before
+// their metrics are written, none while it is paused.
 func (m *MQTTConsumer) receiver() {
 	for {
+		in := m.in
+		paused, pauseChanged := m.pause.State()
+		if paused || m.undelivered >= m.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-m.done:
 			return
-		case msg := <-m.in:
+		case <-pauseChanged:
+		case <-m.acc.Delivered():
+			m.undelivered--
+		case msg := <-in:
 			topic := msg.Topic()
 			metrics, err := m.parser.Parse(msg.Payload())
 			if err != nil {
 				m.acc.AddError(fmt.Errorf("E! MQTT Parse Error\nmessage: %s\nerror: %s",
 					string(msg.Payload()), err.Error()))
 			}
+			if len(metrics) == 0 {
+				continue
+			}
 
 			for _, metric := range metrics {
-				tags := metric.Tags()
-				tags["topic"] = topic
-				m.acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+				metric.AddTag("topic", topic)
 			}
+			m.acc.AddTrackingMetricGroup(metrics)
+			m.undelivered++
 		}
 	}
 }
@@ -206,6 +233,17 @@ func (m *MQTTConsumer) recvMessage(_ mqtt.Client, msg mqtt.Message) {
 	m.in <- msg
 }
 
+// Pause stops reading the messages, the client stops reading from the broker
+// once the channel of the incoming messages is full.
+func (m *MQTTConsumer) Pause() {
+	m.pause.Set(true)
+}
+
+// Resume reads the messages again.
+func (m *MQTTConsumer) Resume() {
+	m.pause.Set(false)
+}
+
 func (m *MQTTConsumer) Stop() {
 	m.Lock()
 	defer m.Unlock()
@@ -284,7 +322,8 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 func init() {
 	inputs.Add("mqtt_consumer", func() telegraf.Input {
 		return &MQTTConsumer{
-			ConnectionTimeout: defaultConnectionTimeout,
+			ConnectionTimeout:      defaultConnectionTimeout,
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
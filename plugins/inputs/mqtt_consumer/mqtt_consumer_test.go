@@ -22,11 +22,12 @@ const (
 func newTestMQTTConsumer() (*MQTTConsumer, chan mqtt.Message) {
 	in := make(chan mqtt.Message, 100)
 	n := &MQTTConsumer{
-		Topics:    []string{"telegraf"},
-		Servers:   []string{"localhost:1883"},
-		in:        in,
-		done:      make(chan struct{}),
-		connected: true,
+		Topics:                 []string{"telegraf"},
+		Servers:                []string{"localhost:1883"},
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		in:                     in,
+		done:                   make(chan struct{}),
+		connected:              true,
 	}
 
 	return n, in
@@ -81,7 +82,7 @@ func TestPersistentClientIDFail(t *testing.T) {
 func TestRunParser(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -97,7 +98,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserNegativeNumber(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -114,7 +115,7 @@ func TestRunParserNegativeNumber(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -132,7 +133,7 @@ func TestRunParserInvalidMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 
 	defer close(n.done)
 
@@ -151,7 +152,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -169,7 +170,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewJSONParser("nats_json_test", []string{}, nil)
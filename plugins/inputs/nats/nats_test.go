@@ -66,6 +66,129 @@ var sampleVarz = `
 }
 `
 
+var sampleConnz = `
+{
+  "now": "2011-10-05T15:24:23.722084098-07:00",
+  "num_connections": 1,
+  "total": 1,
+  "offset": 0,
+  "limit": 1024,
+  "connections": [
+    {
+      "cid": 49,
+      "ip": "127.0.0.1",
+      "port": 55086,
+      "start": "2011-10-05T15:20:23.722084098-07:00",
+      "last_activity": "2011-10-05T15:24:23.722084098-07:00",
+      "uptime": "4m0s",
+      "idle": "0s",
+      "pending_bytes": 12,
+      "in_msgs": 33,
+      "out_msgs": 31,
+      "in_bytes": 4380,
+      "out_bytes": 4116,
+      "subscriptions": 2,
+      "name": "telegraf",
+      "lang": "go",
+      "version": "1.2.2"
+    }
+  ]
+}
+`
+
+var sampleSubsz = `
+{
+  "num_subscriptions": 4,
+  "num_cache": 3,
+  "num_inserts": 8,
+  "num_removes": 4,
+  "num_matches": 250,
+  "cache_hit_rate": 0.5,
+  "max_fanout": 2,
+  "avg_fanout": 1.25
+}
+`
+
+var sampleRoutez = `
+{
+  "now": "2018-01-27T01:05:07.412345678Z",
+  "num_routes": 1,
+  "routes": [
+    {
+      "rid": 1,
+      "remote_id": "NCV5OXPJPRA2ZN5DO3SAHQFAJBYOBNNJ7QZOYLWVHEQWH3VTR6K4N6YM",
+      "did_solicit": true,
+      "is_configured": true,
+      "ip": "10.0.0.2",
+      "port": 6222,
+      "pending_size": 0,
+      "in_msgs": 120,
+      "out_msgs": 95,
+      "in_bytes": 10240,
+      "out_bytes": 8192,
+      "subscriptions": 7
+    }
+  ]
+}
+`
+
+var sampleJsz = `
+{
+  "server_id": "n2afhLHLl64Gcaj7S7jaNa",
+  "now": "2011-10-05T15:24:23.722084098-07:00",
+  "memory": 0,
+  "storage": 26845,
+  "reserved_memory": 0,
+  "reserved_storage": 1073741824,
+  "accounts": 1,
+  "ha_assets": 0,
+  "api": {
+    "total": 12,
+    "errors": 1
+  },
+  "streams": 1,
+  "consumers": 1,
+  "messages": 100,
+  "bytes": 26845,
+  "account_details": [
+    {
+      "name": "$G",
+      "id": "$G",
+      "stream_detail": [
+        {
+          "name": "telegraf",
+          "state": {
+            "messages": 100,
+            "bytes": 26845,
+            "first_seq": 1,
+            "last_seq": 100,
+            "consumer_count": 1
+          },
+          "consumer_detail": [
+            {
+              "stream_name": "telegraf",
+              "name": "telegraf_consumer",
+              "delivered": {
+                "consumer_seq": 90,
+                "stream_seq": 90
+              },
+              "ack_floor": {
+                "consumer_seq": 85,
+                "stream_seq": 85
+              },
+              "num_ack_pending": 5,
+              "num_redelivered": 2,
+              "num_waiting": 1,
+              "num_pending": 10
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
 func TestMetricsCorrect(t *testing.T) {
 	var acc testutil.Accumulator
 
@@ -98,6 +221,180 @@ func TestMetricsCorrect(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, "nats", fields, tags)
 }
 
+func TestConnzSubszMetricsCorrect(t *testing.T) {
+	var acc testutil.Accumulator
+
+	srv := newTestNatsServer()
+	defer srv.Close()
+
+	n := &Nats{Server: srv.URL, Endpoints: []string{"connz", "subsz"}}
+	err := n.Gather(&acc)
+	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "nats_connz",
+		map[string]interface{}{
+			"num_connections": 1,
+			"total":           1,
+		},
+		map[string]string{"server": srv.URL})
+
+	acc.AssertContainsTaggedFields(t, "nats_connection",
+		map[string]interface{}{
+			"pending_bytes": 12,
+			"in_msgs":       int64(33),
+			"out_msgs":      int64(31),
+			"in_bytes":      int64(4380),
+			"out_bytes":     int64(4116),
+			"subscriptions": uint32(2),
+		},
+		map[string]string{
+			"server":  srv.URL,
+			"cid":     "49",
+			"ip":      "127.0.0.1",
+			"name":    "telegraf",
+			"lang":    "go",
+			"version": "1.2.2",
+		})
+
+	acc.AssertContainsTaggedFields(t, "nats_subsz",
+		map[string]interface{}{
+			"num_subscriptions": uint32(4),
+			"num_cache":         uint32(3),
+			"num_inserts":       uint64(8),
+			"num_removes":       uint64(4),
+			"num_matches":       uint64(250),
+			"cache_hit_rate":    float64(0.5),
+			"max_fanout":        uint32(2),
+			"avg_fanout":        float64(1.25),
+		},
+		map[string]string{"server": srv.URL})
+}
+
+func TestJetStreamMetricsCorrect(t *testing.T) {
+	var acc testutil.Accumulator
+
+	srv := newTestNatsServer()
+	defer srv.Close()
+
+	n := &Nats{Server: srv.URL, Endpoints: []string{"jsz"}}
+	err := n.Gather(&acc)
+	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "nats_jetstream",
+		map[string]interface{}{
+			"memory":           uint64(0),
+			"storage":          uint64(26845),
+			"reserved_memory":  uint64(0),
+			"reserved_storage": uint64(1073741824),
+			"accounts":         1,
+			"ha_assets":        0,
+			"streams":          1,
+			"consumers":        1,
+			"messages":         uint64(100),
+			"bytes":            uint64(26845),
+			"api_total":        uint64(12),
+			"api_errors":       uint64(1),
+		},
+		map[string]string{"server": srv.URL})
+
+	acc.AssertContainsTaggedFields(t, "nats_jetstream_stream",
+		map[string]interface{}{
+			"messages":       uint64(100),
+			"bytes":          uint64(26845),
+			"first_seq":      uint64(1),
+			"last_seq":       uint64(100),
+			"consumer_count": 1,
+		},
+		map[string]string{
+			"server":  srv.URL,
+			"account": "$G",
+			"stream":  "telegraf",
+		})
+
+	acc.AssertContainsTaggedFields(t, "nats_jetstream_consumer",
+		map[string]interface{}{
+			"delivered_consumer_seq": uint64(90),
+			"delivered_stream_seq":   uint64(90),
+			"ack_floor_consumer_seq": uint64(85),
+			"ack_floor_stream_seq":   uint64(85),
+			"num_ack_pending":        5,
+			"num_redelivered":        2,
+			"num_waiting":            1,
+			"num_pending":            uint64(10),
+		},
+		map[string]string{
+			"server":   srv.URL,
+			"account":  "$G",
+			"stream":   "telegraf",
+			"consumer": "telegraf_consumer",
+		})
+}
+
+func TestRoutezMetricsCorrect(t *testing.T) {
+	var acc testutil.Accumulator
+
+	srv := newTestNatsServer()
+	defer srv.Close()
+
+	n := &Nats{Server: srv.URL, Endpoints: []string{"routez"}}
+	err := n.Gather(&acc)
+	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "nats_routez",
+		map[string]interface{}{"num_routes": 1},
+		map[string]string{"server": srv.URL})
+
+	acc.AssertContainsTaggedFields(t, "nats_route",
+		map[string]interface{}{
+			"pending_bytes": 0,
+			"in_msgs":       int64(120),
+			"out_msgs":      int64(95),
+			"in_bytes":      int64(10240),
+			"out_bytes":     int64(8192),
+			"subscriptions": uint32(7),
+		},
+		map[string]string{
+			"server":    srv.URL,
+			"rid":       "1",
+			"remote_id": "NCV5OXPJPRA2ZN5DO3SAHQFAJBYOBNNJ7QZOYLWVHEQWH3VTR6K4N6YM",
+			"ip":        "10.0.0.2",
+			"port":      "6222",
+		})
+}
+
+func TestUnknownEndpoint(t *testing.T) {
+	var acc testutil.Accumulator
+
+	srv := newTestNatsServer()
+	defer srv.Close()
+
+	n := &Nats{Server: srv.URL, Endpoints: []string{"varz", "bogusz"}}
+	err := n.Gather(&acc)
+	require.NoError(t, err)
+	require.Len(t, acc.Errors, 1)
+
+	// the gather fails if all the endpoints fail
+	acc = testutil.Accumulator{}
+	n = &Nats{Server: srv.URL, Endpoints: []string{"bogusz"}}
+	err = n.Gather(&acc)
+	require.EqualError(t, err, `unknown monitoring endpoint "bogusz"`)
+	require.Empty(t, acc.Errors)
+}
+
+func TestServerDown(t *testing.T) {
+	var acc testutil.Accumulator
+
+	srv := newTestNatsServer()
+	srv.Close()
+
+	n := &Nats{Server: srv.URL, Endpoints: []string{"varz", "connz"}}
+	require.Error(t, n.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+}
+
 func newTestNatsServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var rsp string
@@ -105,6 +402,14 @@ func newTestNatsServer() *httptest.Server {
 		switch r.URL.Path {
 		case "/varz":
 			rsp = sampleVarz
+		case "/connz":
+			rsp = sampleConnz
+		case "/subsz":
+			rsp = sampleSubsz
+		case "/routez":
+			rsp = sampleRoutez
+		case "/jsz":
+			rsp = sampleJsz
 		default:
 			panic("Cannot handle request")
 		}
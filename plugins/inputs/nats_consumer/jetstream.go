@@ -0,0 +1,410 @@
+package natsconsumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	jsAPIPrefix = "$JS.API."
+	jsAckPrefix = "$JS.ACK."
+
+	// jsPullInterval is how often the messages of the consumer are pulled,
+	// each pull request expires before the next one.
+	jsPullInterval = time.Second
+	jsTimeout      = 5 * time.Second
+)
+
+// jsConsumerConfig is the configuration of the durable pull consumer, see
+// https://docs.nats.io/reference/reference-protocols/nats_api_reference
+type jsConsumerConfig struct {
+	DurableName   string `json:"durable_name"`
+	AckPolicy     string `json:"ack_policy"`
+	AckWait       int64  `json:"ack_wait,omitempty"`
+	MaxAckPending int    `json:"max_ack_pending,omitempty"`
+	FilterSubject string `json:"filter_subject,omitempty"`
+	DeliverPolicy string `json:"deliver_policy,omitempty"`
+	OptStartSeq   uint64 `json:"opt_start_seq,omitempty"`
+}
+
+type jsCreateConsumerRequest struct {
+	Stream string           `json:"stream_name"`
+	Config jsConsumerConfig `json:"config"`
+}
+
+type jsPullRequest struct {
+	Batch   int   `json:"batch"`
+	Expires int64 `json:"expires"`
+}
+
+type jsResponse struct {
+	Error *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// createConsumerRequest returns the request creating the durable consumer of
+// the stream, or updating it if it exists. A new consumer delivers the
+// messages from startSeq if it is not 0.
+func (n *natsConsumer) createConsumerRequest(startSeq uint64) (string, []byte, error) {
+	ackPolicy := "explicit"
+	if n.ackBatching() {
+		ackPolicy = "all"
+	}
+	config := jsConsumerConfig{
+		DurableName:   n.JetStreamConsumer,
+		AckPolicy:     ackPolicy,
+		AckWait:       int64(n.AckWait.Duration),
+		MaxAckPending: n.MaxAckPending,
+	}
+	// a consumer can only filter one subject of the stream
+	if subjects := n.subjects(); len(subjects) == 1 {
+		config.FilterSubject = subjects[0]
+	}
+	if startSeq > 0 {
+		config.DeliverPolicy = "by_start_sequence"
+		config.OptStartSeq = startSeq
+	}
+	data, err := json.Marshal(jsCreateConsumerRequest{
+		Stream: n.JetStreamStream,
+		Config: config,
+	})
+	subject := jsAPIPrefix + "CONSUMER.DURABLE.CREATE." + n.JetStreamStream + "." + n.JetStreamConsumer
+	return subject, data, err
+}
+
+// pullRequest returns the request pulling at most batch messages of the
+// consumer.
+func (n *natsConsumer) pullRequest(batch int) (string, []byte, error) {
+	data, err := json.Marshal(jsPullRequest{
+		Batch:   batch,
+		Expires: int64(jsPullInterval),
+	})
+	subject := jsAPIPrefix + "CONSUMER.MSG.NEXT." + n.JetStreamStream + "." + n.JetStreamConsumer
+	return subject, data, err
+}
+
+// subscribeJetStream creates the durable consumer of the stream and
+// subscribes to the inbox its pulled messages are sent to.
+func (n *natsConsumer) subscribeJetStream() error {
+	if n.JetStreamConsumer == "" {
+		return fmt.Errorf("jetstream_consumer must be set with jetstream_stream")
+	}
+
+	// a consumer removed while telegraf was stopped is created again from
+	// the message after the last one acknowledged
+	var startSeq uint64
+	if seq := n.storedSeq(); seq > 0 {
+		exists, err := n.consumerExists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			startSeq = seq + 1
+			n.log.Infof("Creating JetStream consumer %s of stream %s from sequence %d",
+				n.JetStreamConsumer, n.JetStreamStream, startSeq)
+		}
+	}
+
+	subject, data, err := n.createConsumerRequest(startSeq)
+	if err != nil {
+		return err
+	}
+	msg, err := n.Conn.Request(subject, data, jsTimeout)
+	if err != nil {
+		return fmt.Errorf("creating JetStream consumer %s of stream %s: %s",
+			n.JetStreamConsumer, n.JetStreamStream, err)
+	}
+	var resp jsResponse
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return fmt.Errorf("creating JetStream consumer %s of stream %s: %s",
+			n.JetStreamConsumer, n.JetStreamStream, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("creating JetStream consumer %s of stream %s: %s (%d)",
+			n.JetStreamConsumer, n.JetStreamStream, resp.Error.Description, resp.Error.Code)
+	}
+
+	// the pending limits cannot be set on a channel subscription, the
+	// capacity of the channel is its limit
+	n.inbox = nats.NewInbox()
+	sub, err := n.Conn.ChanSubscribe(n.inbox, n.in)
+	if err != nil {
+		return err
+	}
+	n.Subs = append(n.Subs, sub)
+	return n.Conn.Flush()
+}
+
+// consumerExists reports whether the durable consumer of the stream exists.
+func (n *natsConsumer) consumerExists() (bool, error) {
+	subject := jsAPIPrefix + "CONSUMER.INFO." + n.JetStreamStream + "." + n.JetStreamConsumer
+	msg, err := n.Conn.Request(subject, nil, jsTimeout)
+	if err != nil {
+		return false, fmt.Errorf("getting JetStream consumer %s of stream %s: %s",
+			n.JetStreamConsumer, n.JetStreamStream, err)
+	}
+	var resp jsResponse
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return false, fmt.Errorf("getting JetStream consumer %s of stream %s: %s",
+			n.JetStreamConsumer, n.JetStreamStream, err)
+	}
+	if resp.Error == nil {
+		return true, nil
+	}
+	if resp.Error.Code == 404 {
+		return false, nil
+	}
+	return false, fmt.Errorf("getting JetStream consumer %s of stream %s: %s (%d)",
+		n.JetStreamConsumer, n.JetStreamStream, resp.Error.Description, resp.Error.Code)
+}
+
+// seqKey is the key of the stream sequence of the last message acknowledged
+// in the store.
+func (n *natsConsumer) seqKey() string {
+	return "jetstream:" + n.JetStreamStream + "." + n.JetStreamConsumer
+}
+
+// storedSeq returns the stream sequence of the last message acknowledged
+// when the plugin last stopped, or 0.
+func (n *natsConsumer) storedSeq() uint64 {
+	if n.store == nil {
+		return 0
+	}
+	value, err := n.store.Get(n.seqKey())
+	if err != nil || value == nil {
+		return 0
+	}
+	seq, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
+// saveSeq stores the stream sequence of the last message acknowledged.
+func (n *natsConsumer) saveSeq() error {
+	if n.store == nil || n.lastSeq == 0 {
+		return nil
+	}
+	return n.store.Set(n.seqKey(), []byte(strconv.FormatUint(n.lastSeq, 10)))
+}
+
+// pull requests the messages read before max_undelivered_messages is
+// reached.
+func (n *natsConsumer) pull(undelivered int) error {
+	batch := n.MaxUndeliveredMessages - undelivered - len(n.in)
+	if batch <= 0 {
+		return nil
+	}
+	subject, data, err := n.pullRequest(batch)
+	if err != nil {
+		return err
+	}
+	return n.Conn.PublishRequest(subject, n.inbox, data)
+}
+
+// ackBatching reports whether the messages are acknowledged in batches.
+func (n *natsConsumer) ackBatching() bool {
+	return n.AckBatchSize > 1 || n.AckBatchInterval.Duration > 0
+}
+
+// streamSeq returns the stream sequence of a JetStream message from its ack
+// subject, "$JS.ACK.<stream>.<consumer>.<delivered>.<stream seq>...", the
+// servers with domains add the domain and account after the prefix.
+func streamSeq(reply string) (uint64, bool) {
+	if !strings.HasPrefix(reply, jsAckPrefix) {
+		return 0, false
+	}
+	tokens := strings.Split(reply, ".")
+	i := 5
+	if len(tokens) >= 12 {
+		i = 7
+	}
+	if len(tokens) <= i {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(tokens[i], 10, 64)
+	return seq, err == nil
+}
+
+// jsDedup finds the messages JetStream delivers again although they are
+// read: those whose ack_wait expires while their metrics are not written yet
+// and those whose acknowledgement is lost.
+type jsDedup struct {
+	window time.Duration
+	// stream sequences of the messages read and not acknowledged yet
+	pending map[uint64]struct{}
+	// stream sequences of the acknowledged messages, with the time of their
+	// acknowledgement
+	acked map[uint64]time.Time
+}
+
+func newJSDedup(window time.Duration) *jsDedup {
+	return &jsDedup{
+		window:  window,
+		pending: make(map[uint64]struct{}),
+		acked:   make(map[uint64]time.Time),
+	}
+}
+
+// read reports whether the message is a duplicate of a message already read,
+// and whether that one is acknowledged already.
+func (d *jsDedup) read(msg *nats.Msg) (duplicate bool, acked bool) {
+	seq, ok := streamSeq(msg.Reply)
+	if !ok {
+		return false, false
+	}
+	if _, ok := d.pending[seq]; ok {
+		return true, false
+	}
+	if t, ok := d.acked[seq]; ok && time.Since(t) < d.window {
+		return true, true
+	}
+	d.pending[seq] = struct{}{}
+	return false, false
+}
+
+// done records the acknowledgement of a message read.
+func (d *jsDedup) done(msg *nats.Msg, delivered bool) {
+	seq, ok := streamSeq(msg.Reply)
+	if !ok {
+		return
+	}
+	delete(d.pending, seq)
+	if delivered {
+		d.acked[seq] = time.Now()
+	}
+}
+
+// expire forgets the acknowledgements older than the window.
+func (d *jsDedup) expire() {
+	for seq, t := range d.acked {
+		if time.Since(t) >= d.window {
+			delete(d.acked, seq)
+		}
+	}
+}
+
+// jsBatch acknowledges the messages of a consumer with the "all" ack policy
+// in batches. The acknowledgement of a message acknowledges those of the
+// lower stream sequences, so it is only sent once they are all delivered: a
+// message whose metrics are not written holds the acknowledgement of the next
+// messages until it is delivered again.
+type jsBatch struct {
+	size int
+	// the messages read and not acknowledged yet by their stream sequence,
+	// and whether their metrics are written
+	msgs      map[uint64]*nats.Msg
+	delivered map[uint64]bool
+	// the messages delivered since the last acknowledgement
+	count int
+}
+
+func newJSBatch(size int) *jsBatch {
+	return &jsBatch{
+		size:      size,
+		msgs:      make(map[uint64]*nats.Msg),
+		delivered: make(map[uint64]bool),
+	}
+}
+
+// read records a message read, a message delivered again replaces the
+// previous delivery.
+func (b *jsBatch) read(msg *nats.Msg) {
+	if seq, ok := streamSeq(msg.Reply); ok {
+		b.msgs[seq] = msg
+		b.delivered[seq] = false
+	}
+}
+
+// done records whether the metrics of a message are written, it reports
+// whether the batch is complete.
+func (b *jsBatch) done(msg *nats.Msg, delivered bool) bool {
+	seq, ok := streamSeq(msg.Reply)
+	if !ok || !delivered {
+		return false
+	}
+	if _, ok := b.msgs[seq]; !ok {
+		return false
+	}
+	b.delivered[seq] = true
+	b.count++
+	return b.size > 0 && b.count >= b.size
+}
+
+// next returns the message whose acknowledgement acknowledges the delivered
+// messages of the lowest stream sequences, or nil if there is none, and
+// forgets them.
+func (b *jsBatch) next() *nats.Msg {
+	seqs := make([]uint64, 0, len(b.msgs))
+	for seq := range b.msgs {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	var last *nats.Msg
+	for _, seq := range seqs {
+		if !b.delivered[seq] {
+			break
+		}
+		last = b.msgs[seq]
+		delete(b.msgs, seq)
+		delete(b.delivered, seq)
+	}
+	b.count = 0
+	return last
+}
+
+// flushAcks sends the acknowledgement of the batch of delivered messages.
+func (n *natsConsumer) flushAcks() error {
+	if n.batch == nil {
+		return nil
+	}
+	msg := n.batch.next()
+	if msg == nil {
+		return nil
+	}
+	n.acked(msg)
+	return n.Conn.Publish(msg.Reply, []byte("+ACK"))
+}
+
+// acked records the stream sequence of the message acknowledged, the
+// sequence is stored at stop.
+func (n *natsConsumer) acked(msg *nats.Msg) {
+	if seq, ok := streamSeq(msg.Reply); ok && seq > n.lastSeq {
+		n.lastSeq = seq
+	}
+}
+
+// ack acknowledges a JetStream message once its metrics are delivered, it is
+// delivered again at once if they are not. Core NATS messages are not
+// acknowledged.
+func (n *natsConsumer) ack(msg *nats.Msg, delivered bool) error {
+	if !strings.HasPrefix(msg.Reply, jsAckPrefix) {
+		return nil
+	}
+	if n.dedup != nil {
+		n.dedup.done(msg, delivered)
+	}
+	if n.batch != nil && delivered {
+		if n.batch.done(msg, delivered) {
+			return n.flushAcks()
+		}
+		return nil
+	}
+	body := "+ACK"
+	if !delivered {
+		body = "-NAK"
+	} else {
+		n.acked(msg)
+	}
+	return n.Conn.Publish(msg.Reply, []byte(body))
+}
@@ -1,19 +1,33 @@
 package natsconsumer
 
 import (
-	"crypto/tls"
-	"crypto/x509"
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/internal/proxy"
+	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
-	"github.com/nats-io/nats"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/nats-io/nats.go"
 )
 
+const defaultMaxUndeliveredMessages = 1000
+
+// maxDecodedSize limits the size of the decompressed messages.
+const maxDecodedSize = 64 * 1024 * 1024
+
 type natsError struct {
 	conn *nats.Conn
 	sub  *nats.Subscription
@@ -25,29 +39,97 @@ func (e natsError) Error() string {
 		e.err.Error(), e.conn.ConnectedUrl(), e.conn.ConnectedServerId(), e.sub.Subject, e.sub.Queue)
 }
 
+// subjectParser creates the parsers of the messages of the subjects matching
+// subject.
+type subjectParser struct {
+	subject    string
+	parserFunc parsers.ParserFunc
+}
+
+// route renames the measurements of the messages of the subjects matching
+// Subject.
+type route struct {
+	Subject     string
+	Measurement string
+}
+
+// msgParser parses the messages with the parser of their subject, each
+// worker has its own as the parsers are not safe for concurrent use.
+type msgParser struct {
+	parser   parsers.Parser
+	subjects []string
+	parsers  []parsers.Parser
+}
+
+// parsed is a message parsed by a worker.
+type parsed struct {
+	msg     *nats.Msg
+	metrics []telegraf.Metric
+	err     error
+}
+
 type natsConsumer struct {
 	QueueGroup string
 	Subjects   []string
+	SubjectTag string
+	// headers of the messages added to their metrics
+	HeaderTags   []string
+	HeaderFields []string
+	Routes       []route `toml:"route"`
 	Servers    []string
-	Secure     bool
-
-	// Path to CA file
-	SSLCA string `toml:"ssl_ca"`
-	// Path to host cert file
-	SSLCert string `toml:"ssl_cert"`
-	// Path to cert key file
-	SSLKey string `toml:"ssl_key"`
-	// Use SSL but skip chain & host verification
+	Username   string
+	Password   string
+	Token      string
+	// Credentials is the file of the user JWT and NKey seed of the
+	// decentralized authentication, NkeySeed the seed file of an NKey user.
+	Credentials string
+	NkeySeed    string `toml:"nkey_seed"`
+	tls.ClientConfig
+	// proxy of the websocket servers
+	proxy.HTTPProxy
+
+	// Deprecated, TLS is enabled by the tls_ options
+	Secure bool
+	// Deprecated, use insecure_skip_verify
 	VerifyHost bool `toml:"verify_host"`
 
 	// Client pending limits:
 	PendingMessageLimit int
 	PendingBytesLimit   int
 
+	MaxReconnects   int
+	ReconnectWait   internal.Duration
+	ReconnectJitter internal.Duration
+
+	ClientName          string
+	PingInterval        internal.Duration
+	MaxPingsOut         int
+	CustomDialerTimeout internal.Duration
+
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+	MaxMessagesPerSecond   int `toml:"max_messages_per_second"`
+	ParserWorkers          int
+	DeadLetterSubject      string
+	ContentEncoding        string
+
+	// JetStream durable pull consumer, the subjects filter its stream
+	JetStreamStream   string `toml:"jetstream_stream"`
+	JetStreamConsumer string `toml:"jetstream_consumer"`
+	AckWait           internal.Duration
+	MaxAckPending     int
+	DedupWindow       internal.Duration `toml:"jetstream_dedup_window"`
+	AckBatchSize      int
+	AckBatchInterval  internal.Duration
+
 	// Legacy metric buffer support
 	MetricBuffer int
 
-	parser parsers.Parser
+	parserFunc parsers.ParserFunc
+	// parsers of the subject tables, in their order
+	subjectParsers []subjectParser
+	log            telegraf.Logger
+	// alias of the instance, tagging its stats
+	alias string
 
 	sync.Mutex
 	wg   sync.WaitGroup
@@ -59,36 +141,162 @@ type natsConsumer struct {
 	// channel for all NATS read errors
 	errs chan error
 	done chan struct{}
-	acc  telegraf.Accumulator
+	acc  telegraf.TrackingAccumulator
+	// inbox of the messages pulled from JetStream
+	inbox string
+	// duplicates of the messages pulled from JetStream, for the receiver
+	dedup *jsDedup
+	// acknowledgements of the messages pulled from JetStream, for the
+	// receiver
+	batch *jsBatch
+	// no messages are read or pulled while paused by the agent
+	pause internal.Pause
+	// store keeps the stream sequence of the last message acknowledged,
+	// lastSeq, across restarts
+	store   telegraf.Store
+	lastSeq uint64
+
+	MessagesRecv    selfstat.Stat
+	BytesRecv       selfstat.Stat
+	ParseErrors     selfstat.Stat
+	PendingMessages selfstat.Stat
+	MessagesDropped selfstat.Stat
 }
 
 var sampleConfig = `
-  ## urls of NATS servers
+  ## urls of NATS servers, ws:// and wss:// urls connect through a
+  ## websocket endpoint, like "wss://nats.example.com:443/nats"
   # servers = ["nats://localhost:4222"]
-  ## Use Transport Layer Security
-  # secure = false
   ## subject(s) to consume
   # subjects = ["telegraf"]
+  ## Tag set to the subject of the messages, for wildcard subjects, no tag
+  ## is added if empty
+  # subject_tag = ""
+  ## Headers of the messages added to their metrics as tags or as fields,
+  ## named after the header. The names are case-sensitive and the first
+  ## value of a header is used, the fields are integers, floats or booleans
+  ## if their value is one. Headers need NATS 2.2 servers.
+  # header_tags = []
+  # header_fields = []
   ## name a queue group
   # queue_group = "telegraf_consumers"
 
-  ## Optional SSL Config
-  # ssl_ca = "/etc/telegraf/ca.pem"
-  # ssl_cert = "/etc/telegraf/cert.pem"
-  # ssl_key = "/etc/telegraf/key.pem"
-  ## Use SSL but skip chain & host verification
-  # verify_host = false
+  ## Optional credentials, instead of those of the server urls
+  # username = ""
+  # password = ""
+  ## Optional authentication token
+  # token = ""
+  ## Optional NATS 2.0 authentication, with the user JWT and NKey seed of a
+  ## credentials file, like those of NGS, or with an NKey seed file. The
+  ## credentials file is read again on each connection.
+  # credentials = "/etc/telegraf/nats.creds"
+  # nkey_seed = "/etc/telegraf/seed.txt"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+  ## Minimum and maximum TLS version, "TLS10", "TLS11", "TLS12" or "TLS13"
+  # tls_min_version = "TLS12"
+  # tls_max_version = "TLS12"
+  ## Accepted cipher suites, by default those of Go
+  # tls_cipher_suites = ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
+
+  ## HTTP proxy of the websocket servers, by default the proxy of the
+  ## HTTP_PROXY and HTTPS_PROXY environment variables is used.
+  # http_proxy_url = "http://proxy.example.com:3128"
 
   ## Sets the limits for pending msgs and bytes for each subscription
   ## These shouldn't need to be adjusted except in very high throughput scenarios
   # pending_message_limit = 65536
   # pending_bytes_limit = 67108864
 
+  ## Maximum number of reconnection attempts to the servers, -1 retries
+  ## forever. The plugin stops consuming once they are exhausted.
+  # max_reconnects = -1
+  ## Time waited between the reconnection attempts to a server, a random
+  ## duration up to reconnect_jitter is added once per connection so that
+  ## the instances of telegraf do not reconnect at the same time.
+  # reconnect_wait = "2s"
+  # reconnect_jitter = "0s"
+
+  ## Name of the connection, shown by the connz endpoint of the servers.
+  # client_name = "telegraf"
+  ## Interval of the pings sent to the servers, the connection is lost once
+  ## max_pings_out pings are not answered. Longer intervals suit links with
+  ## a high latency.
+  # ping_interval = "2m"
+  # max_pings_out = 2
+  ## Timeout of the connections to the servers, and of the websocket
+  ## handshakes.
+  # custom_dialer_timeout = "2s"
+
+  ## Maximum number of messages read before their metrics are written by the
+  ## outputs, the next messages wait in the pending messages of the
+  ## subscriptions.
+  # max_undelivered_messages = 1000
+
+  ## Maximum number of messages read per second, 0 for no limit. The next
+  ## messages wait in the pending messages of the subscriptions.
+  # max_messages_per_second = 0
+
+  ## Number of goroutines parsing the messages, the metrics of the messages
+  ## are not added in order with more than one.
+  # parser_workers = 1
+
+  ## Subject the messages that cannot be parsed are published to, unchanged,
+  ## so that they can be replayed. They are dropped if empty.
+  # dead_letter_subject = "telegraf.dlq"
+
+  ## Content encoding of the messages, "identity", "gzip", "snappy" (the
+  ## block format) or "zstd", they are decompressed before being parsed.
+  # content_encoding = "identity"
+
+  ## Consume the durable JetStream consumer of a stream instead of
+  ## subscribing to the subjects, a single subject filters the stream. The
+  ## consumer is created if needed, messages are acknowledged once their
+  ## metrics are written and are delivered again if they are not acknowledged
+  ## within ack_wait, for example if telegraf restarts.
+  # jetstream_stream = ""
+  # jetstream_consumer = "telegraf"
+  ## Redelivery delay and maximum number of unacknowledged messages of the
+  ## consumer.
+  # ack_wait = "30s"
+  # max_ack_pending = 1000
+  ## Messages delivered again although they are read already, because
+  ## their acknowledgement is lost or late, are dropped. Those acknowledged
+  ## within the window are acknowledged again, 0 disables the deduplication.
+  # jetstream_dedup_window = "2m"
+  ## Acknowledge the messages in batches, once ack_batch_size messages are
+  ## delivered or every ack_batch_interval, instead of one by one. The
+  ## consumer uses the "all" ack policy, the ack policy of an existing
+  ## consumer cannot be changed. A message whose metrics are not written
+  ## holds the acknowledgement of the next messages until it is delivered
+  ## again. The delivered messages are acknowledged when telegraf stops.
+  # ack_batch_size = 0
+  # ack_batch_interval = "0s"
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
   data_format = "influx"
+
+  ## Subjects with their own data format, they are consumed in addition to
+  ## the subjects above. The first subject matching a message sets its data
+  ## format, the others use data_format.
+  # [[inputs.nats_consumer.subject]]
+  #   subject = "sensors.>"
+  #   data_format = "json"
+  #   tag_keys = ["device"]
+
+  ## Measurement name of the metrics of the messages of the subjects
+  ## matching subject, the first matching route applies.
+  # [[inputs.nats_consumer.route]]
+  #   subject = "sensors.*.temp"
+  #   measurement = "temperature"
 `
 
 func (n *natsConsumer) SampleConfig() string {
@@ -99,11 +307,102 @@ func (n *natsConsumer) Description() string {
 	return "Read metrics from NATS subject(s)"
 }
 
-func (n *natsConsumer) SetParser(parser parsers.Parser) {
-	n.parser = parser
+func (n *natsConsumer) SetParserFunc(fn parsers.ParserFunc) {
+	n.parserFunc = fn
+}
+
+func (n *natsConsumer) SetLogger(logger telegraf.Logger) {
+	n.log = logger
+}
+
+func (n *natsConsumer) SetAlias(alias string) {
+	n.alias = alias
+}
+
+func (n *natsConsumer) SetStore(store telegraf.Store) {
+	n.store = store
+}
+
+func (n *natsConsumer) SetSubjectParserFunc(subject string, fn parsers.ParserFunc) {
+	n.subjectParsers = append(n.subjectParsers, subjectParser{subject: subject, parserFunc: fn})
+}
+
+// subjects returns the subjects consumed, those of the subject tables follow
+// the subjects option, "telegraf" is consumed if there are none.
+func (n *natsConsumer) subjects() []string {
+	subjects := append([]string{}, n.Subjects...)
+	for _, sp := range n.subjectParsers {
+		subjects = append(subjects, sp.subject)
+	}
+	if len(subjects) == 0 {
+		subjects = []string{"telegraf"}
+	}
+	return subjects
+}
+
+// newMsgParser creates the parsers of a worker.
+func (n *natsConsumer) newMsgParser() (*msgParser, error) {
+	parser, err := n.parserFunc()
+	if err != nil {
+		return nil, err
+	}
+	p := &msgParser{parser: parser}
+	for _, sp := range n.subjectParsers {
+		parser, err := sp.parserFunc()
+		if err != nil {
+			return nil, err
+		}
+		p.subjects = append(p.subjects, sp.subject)
+		p.parsers = append(p.parsers, parser)
+	}
+	return p, nil
+}
+
+// parse parses the data of a message with the parser of its subject.
+func (p *msgParser) parse(subject string, data []byte) ([]telegraf.Metric, error) {
+	for i, pattern := range p.subjects {
+		if matchSubject(pattern, subject) {
+			return p.parsers[i].Parse(data)
+		}
+	}
+	return p.parser.Parse(data)
+}
+
+// measurement returns the measurement name of the route of the subject, or
+// an empty string if no route matches.
+func (n *natsConsumer) measurement(subject string) string {
+	for _, r := range n.Routes {
+		if matchSubject(r.Subject, subject) {
+			return r.Measurement
+		}
+	}
+	return ""
+}
+
+// matchSubject reports whether the subject matches the pattern, "*" matches
+// a token and a final ">" the remaining tokens.
+func matchSubject(pattern, subject string) bool {
+	patterns := strings.Split(pattern, ".")
+	tokens := strings.Split(subject, ".")
+	for i, p := range patterns {
+		if p == ">" && i == len(patterns)-1 {
+			return len(tokens) > i
+		}
+		if i >= len(tokens) || (p != "*" && p != tokens[i]) {
+			return false
+		}
+	}
+	return len(tokens) == len(patterns)
 }
 
 func (n *natsConsumer) natsErrHandler(c *nats.Conn, s *nats.Subscription, e error) {
+	// the client reports a subscription once until it is not slow anymore
+	if e == nats.ErrSlowConsumer && s != nil {
+		tags := n.instanceTags()
+		tags["subject"] = s.Subject
+		tags["queue"] = s.Queue
+		selfstat.Register("nats_consumer", "slow_consumer_events", tags).Incr(1)
+	}
 	select {
 	case n.errs <- natsError{conn: c, sub: s, err: e}:
 	default:
@@ -111,50 +410,142 @@ func (n *natsConsumer) natsErrHandler(c *nats.Conn, s *nats.Subscription, e erro
 	}
 }
 
-// Start the nats consumer. Caller must call *natsConsumer.Stop() to clean up.
-func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.acc = acc
-
-	var connectErr error
-
+// options returns the options of the connection to the servers.
+func (n *natsConsumer) options() (nats.Options, error) {
 	// set default NATS connection options
 	opts := nats.DefaultOptions
 
-	// override max reconnection tries
-	opts.MaxReconnect = -1
+	opts.MaxReconnect = n.MaxReconnects
+	opts.ReconnectWait = n.ReconnectWait.Duration
+	if n.ReconnectJitter.Duration > 0 {
+		opts.ReconnectWait += time.Duration(rand.Int63n(int64(n.ReconnectJitter.Duration)))
+	}
+	opts.Name = n.ClientName
+	if n.PingInterval.Duration > 0 {
+		opts.PingInterval = n.PingInterval.Duration
+	}
+	if n.MaxPingsOut > 0 {
+		opts.MaxPingsOut = n.MaxPingsOut
+	}
+	if n.CustomDialerTimeout.Duration > 0 {
+		opts.Timeout = n.CustomDialerTimeout.Duration
+	}
+	opts.DisconnectedCB = func(c *nats.Conn) {
+		n.log.Warnf("Disconnected from NATS server %s: %v", c.ConnectedUrl(), c.LastError())
+	}
+	opts.ReconnectedCB = func(c *nats.Conn) {
+		n.log.Infof("Reconnected to NATS server %s, id: %s", c.ConnectedUrl(), c.ConnectedServerId())
+	}
+	opts.ClosedCB = func(c *nats.Conn) {
+		if err := c.LastError(); err != nil {
+			n.log.Errorf("Connection to NATS closed, no more messages are consumed: %v", err)
+		}
+	}
 
 	// override servers if any were specified
 	opts.Servers = n.Servers
 
-	// override secure switch
-	opts.Secure = n.Secure
+	if n.Username != "" {
+		opts.User = n.Username
+		opts.Password = n.Password
+	}
+	opts.Token = n.Token
 
-	// setup client certificate
-	if n.Secure {
-		cert, err := tls.LoadX509KeyPair(n.SSLCert, n.SSLKey)
+	if n.Credentials != "" && n.NkeySeed != "" {
+		return opts, fmt.Errorf("credentials and nkey_seed cannot both be set")
+	}
+	if n.Credentials != "" {
+		if _, err := os.Stat(n.Credentials); err != nil {
+			return opts, err
+		}
+		if err := nats.UserCredentials(n.Credentials)(&opts); err != nil {
+			return opts, err
+		}
+	}
+	if n.NkeySeed != "" {
+		opt, err := nats.NkeyOptionFromSeed(n.NkeySeed)
 		if err != nil {
-			log.Fatalf("error parsing X509 certificate/key pair: %v", err)
+			return opts, err
 		}
-
-		pool := x509.NewCertPool()
-		rootPEM, err := ioutil.ReadFile(n.SSLCA)
-		if err != nil || rootPEM == nil {
-			log.Fatalf("error parsing CA certificate: %v", err)
+		if err := opt(&opts); err != nil {
+			return opts, err
 		}
-		ok := pool.AppendCertsFromPEM(rootPEM)
-		if !ok {
-			log.Fatalf("error processing CA certificate")
+	}
+
+	// the deprecated secure option didn't verify the server unless
+	// verify_host was set
+	if n.Secure && !n.VerifyHost {
+		n.InsecureSkipVerify = true
+	}
+	if n.Secure && n.TlsMinVersion == "" {
+		n.TlsMinVersion = "TLS12"
+	}
+
+	tlsConfig, err := n.ClientConfig.TLSConfig()
+	if err != nil {
+		return opts, err
+	}
+	if hasWebsocket(n.Servers) {
+		// the dialer secures the websocket connections
+		d, servers, err := newWSDialer(n.Servers, tlsConfig, proxy.ProxyURL(n.HttpProxyUrl), opts.Timeout)
+		if err != nil {
+			return opts, err
 		}
+		opts.CustomDialer = d
+		opts.Servers = servers
+		return opts, nil
+	}
+	// the config of FIPS mode is only used by the servers requiring TLS
+	opts.Secure = n.ClientConfig.Enabled()
+	opts.TLSConfig = tlsConfig
+	return opts, nil
+}
 
-		opts.TLSConfig = &tls.Config{
-			InsecureSkipVerify: !n.VerifyHost,
-			Certificates:       []tls.Certificate{cert},
-			RootCAs:            pool,
-			MinVersion:         tls.VersionTLS12,
+// Init checks the servers, the TLS files and max_undelivered_messages.
+func (n *natsConsumer) Init() error {
+	if n.MaxUndeliveredMessages < 1 {
+		return fmt.Errorf("max_undelivered_messages must be at least 1, found %d", n.MaxUndeliveredMessages)
+	}
+	for _, server := range n.Servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			return fmt.Errorf("invalid server %q: %s", server, err)
+		}
+		switch u.Scheme {
+		case "nats", "tls", "ws", "wss":
+			if u.Host != "" {
+				continue
+			}
 		}
+		return fmt.Errorf("invalid server %q, expected a nats, tls, ws or wss url", server)
+	}
+	_, err := n.options()
+	return err
+}
+
+// Start the nats consumer. Caller must call *natsConsumer.Stop() to clean up.
+func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
+	n.Lock()
+	defer n.Unlock()
+
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	n.registerStats()
+	// done of a previous run is closed already
+	n.done = nil
+
+	// report the errors of the parsers before connecting
+	if _, err := n.newMsgParser(); err != nil {
+		return err
+	}
+	if err := encoding.Check(n.ContentEncoding); err != nil {
+		return err
+	}
+
+	var connectErr error
+
+	opts, err := n.options()
+	if err != nil {
+		return err
 	}
 
 	if n.Conn == nil || n.Conn.IsClosed() {
@@ -168,22 +559,17 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 		n.Conn.SetErrorHandler(n.natsErrHandler)
 
 		n.in = make(chan *nats.Msg, 1000)
-		for _, subj := range n.Subjects {
-			sub, err := n.Conn.QueueSubscribe(subj, n.QueueGroup, func(m *nats.Msg) {
-				n.in <- m
-			})
-			if err != nil {
-				return err
-			}
-			// ensure that the subscription has been processed by the server
-			if err = n.Conn.Flush(); err != nil {
-				return err
-			}
-			// set the subscription pending limits
-			if err = sub.SetPendingLimits(n.PendingMessageLimit, n.PendingBytesLimit); err != nil {
-				return err
-			}
-			n.Subs = append(n.Subs, sub)
+		if n.JetStreamStream != "" {
+			err = n.subscribeJetStream()
+		} else {
+			err = n.subscribe()
+		}
+		if err != nil {
+			// a retried Start connects and subscribes again
+			n.unsubscribe()
+			n.Conn.Close()
+			n.Conn = nil
+			return err
 		}
 	}
 
@@ -192,57 +578,360 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 	// Start the message reader
 	n.wg.Add(1)
 	go n.receiver()
-	log.Printf("I! Started the NATS consumer service, nats: %v, subjects: %v, queue: %v\n",
-		n.Conn.ConnectedUrl(), n.Subjects, n.QueueGroup)
+	n.log.Infof("Started the NATS consumer service, nats: %v, subjects: %v, queue: %v",
+		n.Conn.ConnectedUrl(), n.subjects(), n.QueueGroup)
+
+	return nil
+}
+
+// instanceTags returns the tags telling the stats of the instances apart:
+// their alias, servers and queue group or JetStream consumer.
+func (n *natsConsumer) instanceTags() map[string]string {
+	tags := map[string]string{
+		"servers": strings.Join(n.Servers, ","),
+	}
+	if n.alias != "" {
+		tags["alias"] = n.alias
+	}
+	if n.JetStreamStream != "" {
+		tags["jetstream_consumer"] = n.JetStreamStream + "." + n.JetStreamConsumer
+	} else {
+		tags["queue_group"] = n.QueueGroup
+	}
+	return tags
+}
+
+func (n *natsConsumer) registerStats() {
+	tags := n.instanceTags()
+	tags["subjects"] = strings.Join(n.subjects(), ",")
+	n.MessagesRecv = selfstat.Register("nats_consumer", "messages_received", tags)
+	n.BytesRecv = selfstat.Register("nats_consumer", "bytes_received", tags)
+	n.ParseErrors = selfstat.Register("nats_consumer", "parse_errors", tags)
+	n.PendingMessages = selfstat.Register("nats_consumer", "pending_messages", tags)
+	n.MessagesDropped = selfstat.Register("nats_consumer", "messages_dropped", tags)
+}
 
+// subscribe subscribes to the subjects in the queue group.
+func (n *natsConsumer) subscribe() error {
+	for _, subj := range n.subjects() {
+		sub, err := n.Conn.QueueSubscribe(subj, n.QueueGroup, func(m *nats.Msg) {
+			n.in <- m
+		})
+		if err != nil {
+			return err
+		}
+		// ensure that the subscription has been processed by the server
+		if err = n.Conn.Flush(); err != nil {
+			return err
+		}
+		// set the subscription pending limits
+		if err = sub.SetPendingLimits(n.PendingMessageLimit, n.PendingBytesLimit); err != nil {
+			return err
+		}
+		n.Subs = append(n.Subs, sub)
+	}
 	return nil
 }
 
 // receiver() reads all incoming messages from NATS, and parses them into
-// telegraf metrics.
+// telegraf metrics with parser_workers goroutines. At most
+// max_undelivered_messages are read before their metrics are written, the
+// JetStream messages are pulled and acknowledged once their metrics are
+// delivered. Once done is closed the messages already read are parsed before
+// it returns, up to max_undelivered_messages.
 func (n *natsConsumer) receiver() {
 	defer n.wg.Done()
+	undelivered := make(map[telegraf.TrackingID]*nats.Msg)
+
+	workers := n.ParserWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	// a worker is idle if fewer than workers messages are parsed, so that
+	// the jobs and their results never block
+	jobs := make(chan *nats.Msg, workers)
+	results := make(chan parsed, workers)
+	defer close(jobs)
+	for i := 0; i < workers; i++ {
+		p, err := n.newMsgParser()
+		if err != nil {
+			n.acc.AddError(fmt.Errorf("E! error creating parser: %s", err))
+			return
+		}
+		n.wg.Add(1)
+		go n.worker(p, jobs, results)
+	}
+	parsing := 0
+
+	var pull, ackBatch <-chan time.Time
+	if n.JetStreamStream != "" {
+		ticker := time.NewTicker(jsPullInterval)
+		defer ticker.Stop()
+		pull = ticker.C
+		if n.DedupWindow.Duration > 0 {
+			n.dedup = newJSDedup(n.DedupWindow.Duration)
+		}
+		if n.ackBatching() {
+			n.batch = newJSBatch(n.AckBatchSize)
+			defer func() {
+				if err := n.flushAcks(); err != nil {
+					n.acc.AddError(fmt.Errorf("E! error acknowledging messages of stream %s: %s", n.JetStreamStream, err))
+				}
+			}()
+			if n.AckBatchInterval.Duration > 0 {
+				ticker := time.NewTicker(n.AckBatchInterval.Duration)
+				defer ticker.Stop()
+				ackBatch = ticker.C
+			}
+		}
+		if err := n.pull(0); err != nil {
+			n.acc.AddError(fmt.Errorf("E! error pulling from stream %s: %s", n.JetStreamStream, err))
+		}
+	}
+
+	// the messages read in the current second, reset every second
+	var limit <-chan time.Time
+	read := 0
+	if n.MaxMessagesPerSecond > 0 {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		limit = ticker.C
+	}
+
+	done := n.done
+	draining := false
 	for {
-		select {
-		case <-n.done:
+		full := len(undelivered)+parsing >= n.MaxUndeliveredMessages
+		if draining && parsing == 0 && (len(n.in) == 0 || full) {
 			return
+		}
+		in := n.in
+		// the messages already read are parsed once stopped even if paused
+		paused, pauseChanged := n.pause.State()
+		if full || parsing >= workers || (paused && !draining) {
+			in = nil
+		}
+		if n.MaxMessagesPerSecond > 0 && read >= n.MaxMessagesPerSecond {
+			in = nil
+		}
+
+		select {
+		case <-pauseChanged:
+		case <-limit:
+			read = 0
+		case <-done:
+			done = nil
+			pull = nil
+			draining = true
 		case err := <-n.errs:
 			n.acc.AddError(fmt.Errorf("E! error reading from %s\n", err.Error()))
-		case msg := <-n.in:
-			metrics, err := n.parser.Parse(msg.Data)
-			if err != nil {
-				n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, err.Error()))
+		case <-ackBatch:
+			if err := n.flushAcks(); err != nil {
+				n.acc.AddError(fmt.Errorf("E! error acknowledging messages of stream %s: %s", n.JetStreamStream, err))
+			}
+		case <-pull:
+			if n.dedup != nil {
+				n.dedup.expire()
+			}
+			if paused {
+				continue
+			}
+			if err := n.pull(len(undelivered) + parsing); err != nil {
+				n.acc.AddError(fmt.Errorf("E! error pulling from stream %s: %s", n.JetStreamStream, err))
+			}
+		case info := <-n.acc.Delivered():
+			msg, ok := undelivered[info.ID()]
+			if !ok {
+				continue
+			}
+			delete(undelivered, info.ID())
+			if err := n.ack(msg, info.Delivered()); err != nil {
+				n.acc.AddError(fmt.Errorf("E! error acknowledging message of %s: %s", msg.Subject, err))
+			}
+		case msg := <-in:
+			read++
+			if n.dedup != nil {
+				if duplicate, acked := n.dedup.read(msg); duplicate {
+					if acked {
+						err := n.Conn.Publish(msg.Reply, []byte("+ACK"))
+						if err != nil {
+							n.acc.AddError(fmt.Errorf("E! error acknowledging message of %s: %s", msg.Subject, err))
+						}
+					}
+					continue
+				}
+			}
+			if n.batch != nil {
+				n.batch.read(msg)
 			}
+			n.MessagesRecv.Incr(1)
+			n.BytesRecv.Incr(int64(len(msg.Data)))
+			parsing++
+			jobs <- msg
+		case r := <-results:
+			parsing--
+			msg, metrics := r.msg, r.metrics
+			if r.err != nil {
+				n.ParseErrors.Incr(1)
+				n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, r.err.Error()))
+				if err := n.deadLetter(msg); err != nil {
+					n.acc.AddError(fmt.Errorf("E! error publishing message of %s to %s: %s",
+						msg.Subject, n.DeadLetterSubject, err))
+				}
+			}
+			if n.SubjectTag != "" {
+				for _, m := range metrics {
+					m.AddTag(n.SubjectTag, msg.Subject)
+				}
+			}
+			n.addHeaders(msg, metrics)
+			if name := n.measurement(msg.Subject); name != "" {
+				for _, m := range metrics {
+					m.SetName(name)
+				}
+			}
+			if len(metrics) == 0 {
+				if err := n.ack(msg, true); err != nil {
+					n.acc.AddError(fmt.Errorf("E! error acknowledging message of %s: %s", msg.Subject, err))
+				}
+				continue
+			}
+
+			undelivered[n.acc.AddTrackingMetricGroup(metrics)] = msg
+		}
+	}
+}
 
-			for _, metric := range metrics {
-				n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+// addHeaders adds the headers of header_tags and header_fields of the
+// message to its metrics.
+func (n *natsConsumer) addHeaders(msg *nats.Msg, metrics []telegraf.Metric) {
+	if len(msg.Header) == 0 {
+		return
+	}
+	for _, key := range n.HeaderTags {
+		if value := msg.Header.Get(key); value != "" {
+			for _, m := range metrics {
+				m.AddTag(key, value)
+			}
+		}
+	}
+	for _, key := range n.HeaderFields {
+		if value := msg.Header.Get(key); value != "" {
+			for _, m := range metrics {
+				m.AddField(key, internal.ParseValue(value))
 			}
 		}
 	}
 }
 
-func (n *natsConsumer) clean() {
+// deadLetter publishes the message to the dead letter subject if it is set,
+// with its headers.
+func (n *natsConsumer) deadLetter(msg *nats.Msg) error {
+	if n.DeadLetterSubject == "" {
+		return nil
+	}
+	return n.Conn.PublishMsg(&nats.Msg{
+		Subject: n.DeadLetterSubject,
+		Header:  msg.Header,
+		Data:    msg.Data,
+	})
+}
+
+// worker parses the messages of jobs until it is closed.
+func (n *natsConsumer) worker(p *msgParser, jobs <-chan *nats.Msg, results chan<- parsed) {
+	defer n.wg.Done()
+	for msg := range jobs {
+		var metrics []telegraf.Metric
+		data, err := n.decode(msg.Data)
+		if err == nil {
+			metrics, err = p.parse(msg.Subject, data)
+		}
+		results <- parsed{msg: msg, metrics: metrics, err: err}
+	}
+}
+
+// decode decompresses the data of a message with the content encoding.
+func (n *natsConsumer) decode(data []byte) ([]byte, error) {
+	if n.ContentEncoding == "" || n.ContentEncoding == encoding.Identity {
+		return data, nil
+	}
+	r, err := encoding.NewDecoder(n.ContentEncoding, bytes.NewReader(data), maxDecodedSize)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	decoded, err := ioutil.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > maxDecodedSize {
+		return nil, encoding.ErrTooLarge
+	}
+	return decoded, nil
+}
+
+func (n *natsConsumer) unsubscribe() {
 	for _, sub := range n.Subs {
 		if err := sub.Unsubscribe(); err != nil {
 			n.acc.AddError(fmt.Errorf("E! Error unsubscribing from subject %s in queue %s: %s\n",
 				sub.Subject, sub.Queue, err.Error()))
 		}
 	}
+	n.Subs = nil
+}
 
-	if n.Conn != nil && !n.Conn.IsClosed() {
-		n.Conn.Close()
-	}
+// Pause stops reading the messages and pulling from JetStream, the messages
+// of the subscriptions pile up in the client until they are dropped as those
+// of a slow consumer.
+func (n *natsConsumer) Pause() {
+	n.pause.Set(true)
+}
+
+// Resume reads the messages again.
+func (n *natsConsumer) Resume() {
+	n.pause.Set(false)
 }
 
+// Stop unsubscribes, parses the messages already read and closes the
+// connection, like the drain of a NATS connection.
 func (n *natsConsumer) Stop() {
 	n.Lock()
-	close(n.done)
+	n.unsubscribe()
+	// done is not made if Start failed
+	if n.done != nil {
+		close(n.done)
+	}
 	n.wg.Wait()
-	n.clean()
+	if err := n.saveSeq(); err != nil {
+		n.acc.AddError(fmt.Errorf("E! error storing the sequence of stream %s: %s", n.JetStreamStream, err))
+	}
+	if n.Conn != nil && !n.Conn.IsClosed() {
+		n.Conn.Close()
+	}
 	n.Unlock()
 }
 
+// Gather updates the pending messages and the messages dropped by the
+// subscriptions, as slow consumers, it gets no metrics.
 func (n *natsConsumer) Gather(acc telegraf.Accumulator) error {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.PendingMessages == nil {
+		return nil
+	}
+	pending := len(n.in)
+	var dropped int
+	for _, sub := range n.Subs {
+		if p, _, err := sub.Pending(); err == nil {
+			pending += p
+		}
+		if d, err := sub.Dropped(); err == nil {
+			dropped += d
+		}
+	}
+	n.PendingMessages.Set(int64(pending))
+	n.MessagesDropped.Set(int64(dropped))
 	return nil
 }
 
@@ -250,11 +939,22 @@ func init() {
 	inputs.Add("nats_consumer", func() telegraf.Input {
 		return &natsConsumer{
 			Servers:             []string{"nats://localhost:4222"},
-			Secure:              true,
-			Subjects:            []string{"telegraf"},
 			QueueGroup:          "telegraf_consumers",
 			PendingBytesLimit:   nats.DefaultSubPendingBytesLimit,
 			PendingMessageLimit: nats.DefaultSubPendingMsgsLimit,
+			MaxReconnects:       -1,
+			ReconnectWait:       internal.Duration{Duration: nats.DefaultReconnectWait},
+			PingInterval:        internal.Duration{Duration: nats.DefaultPingInterval},
+			MaxPingsOut:         nats.DefaultMaxPingOut,
+			CustomDialerTimeout: internal.Duration{Duration: nats.DefaultTimeout},
+
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+			ParserWorkers:          1,
+
+			JetStreamConsumer: "telegraf",
+			AckWait:           internal.Duration{Duration: 30 * time.Second},
+			MaxAckPending:     defaultMaxUndeliveredMessages,
+			DedupWindow:       internal.Duration{Duration: 2 * time.Minute},
 		}
 	})
 }
@@ -1,12 +1,28 @@
 package natsconsumer
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/store"
+	internaltls "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
-	"github.com/nats-io/nats"
+	"github.com/nats-io/gnatsd/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -20,14 +36,17 @@ const (
 func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 	in := make(chan *nats.Msg, metricBuffer)
 	n := &natsConsumer{
-		QueueGroup: "test",
-		Subjects:   []string{"telegraf"},
-		Servers:    []string{"nats://localhost:4222"},
-		Secure:     false,
-		in:         in,
-		errs:       make(chan error, metricBuffer),
-		done:       make(chan struct{}),
+		QueueGroup:             "test",
+		Subjects:               []string{"telegraf"},
+		Servers:                []string{"nats://localhost:4222"},
+		Secure:                 false,
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		in:                     in,
+		errs:                   make(chan error, metricBuffer),
+		done:                   make(chan struct{}),
+		log:                    testutil.Logger{Name: "inputs.nats_consumer"},
 	}
+	n.registerStats()
 	return n, in
 }
 
@@ -35,10 +54,10 @@ func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 func TestRunParser(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
-	n.parser, _ = parsers.NewInfluxParser()
+	n.SetParserFunc(parsers.NewInfluxParser)
 	n.wg.Add(1)
 	go n.receiver()
 	in <- natsMsg(testMsg)
@@ -46,14 +65,39 @@ func TestRunParser(t *testing.T) {
 	acc.Wait(1)
 }
 
+// Test that no messages are read while paused, those already read are parsed
+// once stopped
+func TestPause(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.Pause()
+	n.wg.Add(1)
+	go n.receiver()
+	in <- natsMsg(testMsg)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, acc.NFields())
+
+	n.Resume()
+	acc.Wait(1)
+
+	n.Pause()
+	in <- natsMsg(testMsg)
+	close(n.done)
+	n.wg.Wait()
+	assert.Equal(t, 2, acc.NFields())
+}
+
 // Test that the parser ignores invalid messages
 func TestRunParserInvalidMsg(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
-	n.parser, _ = parsers.NewInfluxParser()
+	n.SetParserFunc(parsers.NewInfluxParser)
 	n.wg.Add(1)
 	go n.receiver()
 	in <- natsMsg(invalidMsg)
@@ -67,10 +111,10 @@ func TestRunParserInvalidMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
-	n.parser, _ = parsers.NewInfluxParser()
+	n.SetParserFunc(parsers.NewInfluxParser)
 	n.wg.Add(1)
 	go n.receiver()
 	in <- natsMsg(testMsg)
@@ -86,10 +130,12 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
-	n.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
+	n.SetParserFunc(func() (parsers.Parser, error) {
+		return parsers.NewGraphiteParser("_", []string{}, nil)
+	})
 	n.wg.Add(1)
 	go n.receiver()
 	in <- natsMsg(testMsgGraphite)
@@ -105,10 +151,12 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
-	n.parser, _ = parsers.NewJSONParser("nats_json_test", []string{}, nil)
+	n.SetParserFunc(func() (parsers.Parser, error) {
+		return parsers.NewJSONParser("nats_json_test", []string{}, nil)
+	})
 	n.wg.Add(1)
 	go n.receiver()
 	in <- natsMsg(testMsgJSON)
@@ -123,6 +171,784 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that the subject of the messages is tagged
+func TestRunParserSubjectTag(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.SubjectTag = "subject"
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+	msg := natsMsg(testMsg)
+	msg.Subject = "sensors.device01"
+	in <- msg
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(23422)},
+		map[string]string{"host": "server01", "subject": "sensors.device01"})
+}
+
+// Test that the routes rename the measurements of their subjects
+func TestRunParserRoutes(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.Routes = []route{
+		{Subject: "sensors.*.temp", Measurement: "temperature"},
+		{Subject: "sensors.>", Measurement: "sensors"},
+	}
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+	for _, subject := range []string{"sensors.device01.temp", "sensors.device01.humidity", "telegraf"} {
+		msg := natsMsg(testMsg)
+		msg.Subject = subject
+		in <- msg
+	}
+
+	acc.Wait(3)
+	assert.True(t, acc.HasMeasurement("temperature"))
+	assert.True(t, acc.HasMeasurement("sensors"))
+	assert.True(t, acc.HasMeasurement("cpu_load_short"))
+}
+
+// Test that the messages of the subject tables use their parser
+func TestRunParserSubjectParsers(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.SetSubjectParserFunc("sensors.>", func() (parsers.Parser, error) {
+		return parsers.NewJSONParser("nats_json_test", []string{}, nil)
+	})
+	assert.Equal(t, []string{"telegraf", "sensors.>"}, n.subjects())
+
+	n.wg.Add(1)
+	go n.receiver()
+	msg := natsMsg(testMsgJSON)
+	msg.Subject = "sensors.device01"
+	in <- msg
+	in <- natsMsg(testMsg)
+
+	acc.Wait(2)
+	acc.AssertContainsFields(t, "nats_json_test",
+		map[string]interface{}{
+			"a":   float64(5),
+			"b_c": float64(6),
+		})
+	acc.AssertContainsFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(23422)})
+}
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		match   bool
+	}{
+		{"telegraf", "telegraf", true},
+		{"telegraf", "telegraf.cpu", false},
+		{"sensors.*", "sensors.a", true},
+		{"sensors.*", "sensors.a.b", false},
+		{"sensors.*.temp", "sensors.a.temp", true},
+		{"sensors.>", "sensors.a.b", true},
+		{"sensors.>", "sensors", false},
+		{">", "sensors", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, matchSubject(tt.pattern, tt.subject), "%s %s", tt.pattern, tt.subject)
+	}
+}
+
+// Test that the messages are parsed by several workers
+func TestRunParserWorkers(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.ParserWorkers = 4
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+	for i := 0; i < metricBuffer; i++ {
+		in <- natsMsg(testMsg)
+	}
+
+	acc.Wait(metricBuffer)
+	assert.EqualValues(t, metricBuffer, acc.NMetrics())
+}
+
+// Test that max_messages_per_second limits the messages read per second
+func TestRunParserMaxMessagesPerSecond(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.MaxMessagesPerSecond = 2
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+	for i := 0; i < metricBuffer; i++ {
+		in <- natsMsg(testMsg)
+	}
+
+	acc.Wait(2)
+	time.Sleep(100 * time.Millisecond)
+	assert.EqualValues(t, 2, acc.NMetrics())
+	assert.Len(t, in, metricBuffer-2)
+
+	// the next messages are read in the following second
+	acc.Wait(4)
+	assert.Len(t, in, metricBuffer-4)
+}
+
+// Test that the messages already read are parsed once done is closed
+func TestRunParserDrain(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	for i := 0; i < metricBuffer; i++ {
+		in <- natsMsg(testMsg)
+	}
+	close(n.done)
+	n.wg.Add(1)
+	n.receiver()
+
+	assert.EqualValues(t, metricBuffer, acc.NMetrics())
+	assert.Len(t, in, 0)
+}
+
+// runServer starts a NATS server listening on a random port.
+func runServer(t *testing.T) *server.Server {
+	s := server.New(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	go s.Start()
+	require.True(t, s.ReadyForConnections(10*time.Second))
+	return s
+}
+
+// Test that the messages that cannot be parsed are published to the dead
+// letter subject
+func TestRunParserDeadLetter(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+
+	n, in := newTestNatsConsumer()
+	n.DeadLetterSubject = "telegraf.dlq"
+	conn, err := nats.Connect("nats://" + s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	n.Conn = conn
+	sub, err := conn.SubscribeSync("telegraf.dlq")
+	require.NoError(t, err)
+
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+	in <- natsMsg(invalidMsg)
+
+	msg, err := sub.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, invalidMsg, string(msg.Data))
+	acc.WaitError(1)
+}
+
+// Test the statistics of the messages
+func TestStats(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.Subjects = []string{"stats"}
+	n.registerStats()
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+	in <- natsMsg(testMsg)
+	in <- natsMsg(invalidMsg)
+
+	acc.Wait(1)
+	acc.WaitError(1)
+	assert.EqualValues(t, 2, n.MessagesRecv.Get())
+	assert.EqualValues(t, len(testMsg)+len(invalidMsg), n.BytesRecv.Get())
+	assert.EqualValues(t, 1, n.ParseErrors.Get())
+
+	require.NoError(t, n.Gather(&acc))
+	assert.EqualValues(t, 0, n.PendingMessages.Get())
+	assert.EqualValues(t, 0, n.MessagesDropped.Get())
+}
+
+// Test that the slow consumer events are counted per subscription
+func TestSlowConsumerEvents(t *testing.T) {
+	n, _ := newTestNatsConsumer()
+	n.SetAlias("slow")
+	sub := &nats.Subscription{Subject: "slow", Queue: "test"}
+	n.natsErrHandler(nil, sub, nats.ErrSlowConsumer)
+	n.natsErrHandler(nil, sub, nats.ErrSlowConsumer)
+	n.natsErrHandler(nil, sub, nats.ErrBadSubscription)
+
+	stat := selfstat.Register("nats_consumer", "slow_consumer_events", map[string]string{
+		"alias":       "slow",
+		"servers":     "nats://localhost:4222",
+		"queue_group": "test",
+		"subject":     "slow",
+		"queue":       "test",
+	})
+	assert.EqualValues(t, 2, stat.Get())
+}
+
+// Test that the instances consuming the same subjects have their own stats
+func TestStatsPerInstance(t *testing.T) {
+	east, _ := newTestNatsConsumer()
+	east.SetAlias("east")
+	east.registerStats()
+	west, _ := newTestNatsConsumer()
+	west.SetAlias("west")
+	west.JetStreamStream = "metrics"
+	west.JetStreamConsumer = "telegraf"
+	west.registerStats()
+
+	east.MessagesRecv.Incr(1)
+	assert.EqualValues(t, 0, west.MessagesRecv.Get())
+	assert.Equal(t, map[string]string{
+		"alias":              "west",
+		"servers":            "nats://localhost:4222",
+		"jetstream_consumer": "metrics.telegraf",
+		"subjects":           "telegraf",
+	}, west.MessagesRecv.Tags())
+}
+
+// Test that the messages are decompressed
+func TestRunParserContentEncoding(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.ContentEncoding = "gzip"
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(testMsg))
+	w.Close()
+	in <- natsMsg(buf.String())
+	in <- natsMsg(testMsg)
+
+	acc.Wait(1)
+	acc.WaitError(1)
+	acc.AssertContainsFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(23422)})
+	assert.Contains(t, acc.Errors[0].Error(), "gzip")
+}
+
+// Test that the headers of the messages are added to their metrics
+func TestRunParserHeaders(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.HeaderTags = []string{"Source", "Missing"}
+	n.HeaderFields = []string{"Sequence"}
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+
+	msg := natsMsg(testMsg)
+	msg.Header = nats.Header{"Source": {"plant1", "plant2"}, "Sequence": {"42"}, "Other": {"a"}}
+	in <- msg
+	in <- natsMsg(testMsg)
+
+	acc.Wait(2)
+	acc.Lock()
+	defer acc.Unlock()
+	assert.Equal(t, map[string]string{"host": "server01", "Source": "plant1"}, acc.Metrics[0].Tags)
+	assert.Equal(t, map[string]interface{}{"value": float64(23422), "Sequence": int64(42)}, acc.Metrics[0].Fields)
+	assert.Equal(t, map[string]string{"host": "server01"}, acc.Metrics[1].Tags)
+	assert.Equal(t, map[string]interface{}{"value": float64(23422)}, acc.Metrics[1].Fields)
+}
+
+// Test that the credentials are set in the connection options
+func TestOptions(t *testing.T) {
+	n, _ := newTestNatsConsumer()
+	opts, err := n.options()
+	require.NoError(t, err)
+	assert.Equal(t, "", opts.User)
+	assert.Equal(t, "", opts.Token)
+
+	n.Username = "telegraf"
+	n.Password = "secret"
+	n.Token = "token"
+	opts, err = n.options()
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf", opts.User)
+	assert.Equal(t, "secret", opts.Password)
+	assert.Equal(t, "token", opts.Token)
+	assert.Equal(t, []string{"nats://localhost:4222"}, opts.Servers)
+
+	n.MaxReconnects = 10
+	n.ReconnectWait = internal.Duration{Duration: time.Second}
+	n.ReconnectJitter = internal.Duration{Duration: time.Second}
+	opts, err = n.options()
+	require.NoError(t, err)
+	assert.Equal(t, 10, opts.MaxReconnect)
+	assert.True(t, opts.ReconnectWait >= time.Second && opts.ReconnectWait < 2*time.Second,
+		"reconnect wait %s", opts.ReconnectWait)
+	assert.NotNil(t, opts.DisconnectedCB)
+	assert.NotNil(t, opts.ReconnectedCB)
+	assert.Equal(t, "", opts.Name)
+	assert.Equal(t, nats.DefaultPingInterval, opts.PingInterval)
+	assert.Equal(t, nats.DefaultMaxPingOut, opts.MaxPingsOut)
+	assert.Equal(t, nats.DefaultTimeout, opts.Timeout)
+
+	n.ClientName = "telegraf"
+	n.PingInterval = internal.Duration{Duration: 5 * time.Minute}
+	n.MaxPingsOut = 5
+	n.CustomDialerTimeout = internal.Duration{Duration: 10 * time.Second}
+	opts, err = n.options()
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf", opts.Name)
+	assert.Equal(t, 5*time.Minute, opts.PingInterval)
+	assert.Equal(t, 5, opts.MaxPingsOut)
+	assert.Equal(t, 10*time.Second, opts.Timeout)
+}
+
+// Test that the TLS versions and cipher suites configure the connection
+// Test the NATS 2.0 authentication with an NKey seed or a credentials file
+func TestOptionsNkey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nats")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	user, err := nkeys.CreateUser()
+	require.NoError(t, err)
+	seed, err := user.Seed()
+	require.NoError(t, err)
+	public, err := user.PublicKey()
+	require.NoError(t, err)
+
+	n, _ := newTestNatsConsumer()
+	n.NkeySeed = filepath.Join(dir, "seed.txt")
+	require.NoError(t, ioutil.WriteFile(n.NkeySeed, seed, 0600))
+	opts, err := n.options()
+	require.NoError(t, err)
+	assert.Equal(t, public, opts.Nkey)
+	sig, err := opts.SignatureCB([]byte("nonce"))
+	require.NoError(t, err)
+	assert.NoError(t, user.Verify([]byte("nonce"), sig))
+
+	n, _ = newTestNatsConsumer()
+	n.Credentials = filepath.Join(dir, "user.creds")
+	creds := fmt.Sprintf("-----BEGIN NATS USER JWT-----\n%s\n------END NATS USER JWT------\n\n"+
+		"-----BEGIN USER NKEY SEED-----\n%s\n------END USER NKEY SEED------\n",
+		"eyJ0eXAiOiJqd3QiLCJhbGciOiJlZDI1NTE5In0.e30.c2ln", seed)
+	require.NoError(t, ioutil.WriteFile(n.Credentials, []byte(creds), 0600))
+	opts, err = n.options()
+	require.NoError(t, err)
+	jwt, err := opts.UserJWT()
+	require.NoError(t, err)
+	assert.Equal(t, "eyJ0eXAiOiJqd3QiLCJhbGciOiJlZDI1NTE5In0.e30.c2ln", jwt)
+	sig, err = opts.SignatureCB([]byte("nonce"))
+	require.NoError(t, err)
+	assert.NoError(t, user.Verify([]byte("nonce"), sig))
+
+	n.NkeySeed = filepath.Join(dir, "seed.txt")
+	_, err = n.options()
+	assert.EqualError(t, err, "credentials and nkey_seed cannot both be set")
+
+	n, _ = newTestNatsConsumer()
+	n.Credentials = filepath.Join(dir, "missing.creds")
+	assert.Error(t, n.Init())
+	n, _ = newTestNatsConsumer()
+	n.NkeySeed = filepath.Join(dir, "missing.txt")
+	assert.Error(t, n.Init())
+}
+
+func TestOptionsTLSVersions(t *testing.T) {
+	n, _ := newTestNatsConsumer()
+	n.Secure = true
+	opts, err := n.options()
+	require.NoError(t, err)
+	assert.True(t, opts.Secure)
+	assert.Equal(t, uint16(tls.VersionTLS12), opts.TLSConfig.MinVersion)
+
+	n, _ = newTestNatsConsumer()
+	n.TlsMinVersion = "TLS11"
+	n.TlsMaxVersion = "TLS12"
+	n.TlsCipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}
+	opts, err = n.options()
+	require.NoError(t, err)
+	assert.True(t, opts.Secure)
+	assert.Equal(t, uint16(tls.VersionTLS11), opts.TLSConfig.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS12), opts.TLSConfig.MaxVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, opts.TLSConfig.CipherSuites)
+
+	n.TlsMaxVersion = "TLS10"
+	_, err = n.options()
+	assert.Error(t, err)
+}
+
+// Test that FIPS mode restricts the TLS of the servers requiring it without
+// securing the other connections
+func TestOptionsFIPSMode(t *testing.T) {
+	internaltls.FIPSMode = true
+	defer func() { internaltls.FIPSMode = false }()
+
+	n, _ := newTestNatsConsumer()
+	opts, err := n.options()
+	require.NoError(t, err)
+	assert.False(t, opts.Secure)
+	require.NotNil(t, opts.TLSConfig)
+	assert.Equal(t, uint16(tls.VersionTLS12), opts.TLSConfig.MinVersion)
+}
+
+// Test that an invalid TLS configuration is returned as an error
+func TestOptionsTLSError(t *testing.T) {
+	n, _ := newTestNatsConsumer()
+	n.TlsCert = "/nonexistent/cert.pem"
+	n.TlsKey = "/nonexistent/key.pem"
+	_, err := n.options()
+	assert.Error(t, err)
+
+	acc := testutil.Accumulator{}
+	n.SetParserFunc(parsers.NewInfluxParser)
+	assert.Error(t, n.Start(&acc))
+}
+
+// Test that Init checks the servers, the TLS files and
+// max_undelivered_messages
+func TestInit(t *testing.T) {
+	n, _ := newTestNatsConsumer()
+	n.Servers = []string{"nats://localhost:4222", "tls://nats.example.com:4443"}
+	assert.NoError(t, n.Init())
+	n.Servers = []string{"wss://nats.example.com/ws"}
+	assert.NoError(t, n.Init())
+
+	n.Servers = []string{"localhost:4222"}
+	assert.EqualError(t, n.Init(), `invalid server "localhost:4222", expected a nats, tls, ws or wss url`)
+	n.Servers = []string{"http://localhost:4222"}
+	assert.EqualError(t, n.Init(), `invalid server "http://localhost:4222", expected a nats, tls, ws or wss url`)
+
+	n.Servers = []string{"nats://localhost:4222"}
+	n.TlsCert = "/nonexistent/cert.pem"
+	n.TlsKey = "/nonexistent/key.pem"
+	assert.Error(t, n.Init())
+
+	n, _ = newTestNatsConsumer()
+	n.MaxUndeliveredMessages = 0
+	assert.EqualError(t, n.Init(), "max_undelivered_messages must be at least 1, found 0")
+}
+
+// Test the requests creating and pulling the JetStream consumer
+func TestJetStreamRequests(t *testing.T) {
+	n, _ := newTestNatsConsumer()
+	n.JetStreamStream = "metrics"
+	n.JetStreamConsumer = "telegraf"
+	n.AckWait = internal.Duration{Duration: 30 * time.Second}
+	n.MaxAckPending = 100
+
+	subject, data, err := n.createConsumerRequest(0)
+	require.NoError(t, err)
+	assert.Equal(t, "$JS.API.CONSUMER.DURABLE.CREATE.metrics.telegraf", subject)
+	assert.JSONEq(t, `{"stream_name": "metrics", "config": {"durable_name": "telegraf",
+		"ack_policy": "explicit", "ack_wait": 30000000000, "max_ack_pending": 100,
+		"filter_subject": "telegraf"}}`, string(data))
+
+	n.AckBatchSize = 100
+	_, data, err = n.createConsumerRequest(0)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"ack_policy":"all"`)
+	n.AckBatchSize = 0
+
+	n.Subjects = []string{"cpu", "mem"}
+	_, data, err = n.createConsumerRequest(0)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "filter_subject")
+	assert.NotContains(t, string(data), "deliver_policy")
+
+	_, data, err = n.createConsumerRequest(43)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"deliver_policy":"by_start_sequence","opt_start_seq":43`)
+
+	subject, data, err = n.pullRequest(10)
+	require.NoError(t, err)
+	assert.Equal(t, "$JS.API.CONSUMER.MSG.NEXT.metrics.telegraf", subject)
+	assert.JSONEq(t, `{"batch": 10, "expires": 1000000000}`, string(data))
+}
+
+func TestStreamSeq(t *testing.T) {
+	for reply, expected := range map[string]uint64{
+		"$JS.ACK.metrics.telegraf.1.7.3.1600000000000000000.0":                   7,
+		"$JS.ACK.hub.ACCOUNT.metrics.telegraf.2.8.4.1600000000000000000.0.token": 8,
+	} {
+		seq, ok := streamSeq(reply)
+		assert.True(t, ok, reply)
+		assert.Equal(t, expected, seq, reply)
+	}
+	for _, reply := range []string{"", "_INBOX.abc", "$JS.ACK.metrics", "$JS.ACK.metrics.telegraf.1.x.3.0.0"} {
+		_, ok := streamSeq(reply)
+		assert.False(t, ok, reply)
+	}
+}
+
+func TestJSDedup(t *testing.T) {
+	d := newJSDedup(time.Minute)
+	msg := &nats.Msg{Reply: "$JS.ACK.metrics.telegraf.1.7.3.0.0"}
+	again := &nats.Msg{Reply: "$JS.ACK.metrics.telegraf.2.7.4.0.0"}
+	other := &nats.Msg{Reply: "$JS.ACK.metrics.telegraf.1.8.5.0.0"}
+
+	duplicate, _ := d.read(msg)
+	assert.False(t, duplicate)
+	duplicate, acked := d.read(again)
+	assert.True(t, duplicate)
+	assert.False(t, acked)
+
+	// a message whose metrics are not written is read again
+	d.done(msg, false)
+	duplicate, _ = d.read(again)
+	assert.False(t, duplicate)
+
+	d.done(again, true)
+	duplicate, acked = d.read(msg)
+	assert.True(t, duplicate)
+	assert.True(t, acked)
+	duplicate, _ = d.read(other)
+	assert.False(t, duplicate)
+
+	d.window = 0
+	d.expire()
+	assert.Empty(t, d.acked)
+}
+
+func TestJSBatch(t *testing.T) {
+	b := newJSBatch(2)
+	msgs := []*nats.Msg{
+		{Reply: "$JS.ACK.metrics.telegraf.1.7.1.0.0"},
+		{Reply: "$JS.ACK.metrics.telegraf.1.8.2.0.0"},
+		{Reply: "$JS.ACK.metrics.telegraf.1.9.3.0.0"},
+	}
+	for _, msg := range msgs {
+		b.read(msg)
+	}
+	assert.Nil(t, b.next())
+
+	// the delivered messages after an undelivered one wait for it
+	assert.False(t, b.done(msgs[1], true))
+	assert.Nil(t, b.next())
+	assert.False(t, b.done(msgs[0], false))
+	assert.Nil(t, b.next())
+
+	again := &nats.Msg{Reply: "$JS.ACK.metrics.telegraf.2.7.4.0.0"}
+	b.read(again)
+	assert.False(t, b.done(again, true))
+	assert.True(t, b.done(msgs[2], true))
+	assert.Equal(t, msgs[2], b.next())
+	assert.Empty(t, b.msgs)
+}
+
+// Test that the JetStream messages are acknowledged in batches, and the
+// last messages once the receiver stops
+func TestRunParserJetStreamAckBatch(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+
+	n, in := newTestNatsConsumer()
+	n.JetStreamStream = "metrics"
+	n.JetStreamConsumer = "telegraf"
+	n.AckBatchSize = 2
+	conn, err := nats.Connect("nats://" + s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	n.Conn = conn
+	n.inbox = nats.NewInbox()
+	acks, err := conn.SubscribeSync("$JS.ACK.>")
+	require.NoError(t, err)
+
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+	for _, reply := range []string{
+		"$JS.ACK.metrics.telegraf.1.1.1.0.0",
+		"$JS.ACK.metrics.telegraf.1.2.2.0.0",
+		"$JS.ACK.metrics.telegraf.1.3.3.0.0",
+	} {
+		msg := natsMsg(testMsg)
+		msg.Reply = reply
+		in <- msg
+	}
+
+	ack, err := acks.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "$JS.ACK.metrics.telegraf.1.2.2.0.0", ack.Subject)
+	assert.Equal(t, "+ACK", string(ack.Data))
+	acc.Wait(3)
+
+	close(n.done)
+	n.wg.Wait()
+	ack, err = acks.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "$JS.ACK.metrics.telegraf.1.3.3.0.0", ack.Subject)
+	assert.Equal(t, uint64(3), n.lastSeq)
+}
+
+// Test that a durable consumer removed while stopped is created again from
+// the message after the last one acknowledged
+func TestJetStreamResumesFromStoredSeq(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+
+	n, _ := newTestNatsConsumer()
+	n.JetStreamStream = "metrics"
+	n.JetStreamConsumer = "telegraf"
+	conn, err := nats.Connect("nats://" + s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	n.Conn = conn
+
+	db, err := store.Open("")
+	require.NoError(t, err)
+	n.SetStore(db.Namespace("inputs.nats_consumer"))
+	n.lastSeq = 42
+	require.NoError(t, n.saveSeq())
+
+	var exists int32
+	_, err = conn.Subscribe("$JS.API.CONSUMER.INFO.metrics.telegraf", func(msg *nats.Msg) {
+		if atomic.LoadInt32(&exists) == 1 {
+			conn.Publish(msg.Reply, []byte(`{"name": "telegraf"}`))
+		} else {
+			conn.Publish(msg.Reply, []byte(`{"error": {"code": 404, "description": "consumer not found"}}`))
+		}
+	})
+	require.NoError(t, err)
+	created := make(chan string, 2)
+	_, err = conn.Subscribe("$JS.API.CONSUMER.DURABLE.CREATE.metrics.telegraf", func(msg *nats.Msg) {
+		created <- string(msg.Data)
+		conn.Publish(msg.Reply, []byte(`{}`))
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.subscribeJetStream())
+	assert.Contains(t, <-created, `"deliver_policy":"by_start_sequence","opt_start_seq":43`)
+
+	// an existing consumer keeps its position
+	atomic.StoreInt32(&exists, 1)
+	require.NoError(t, n.subscribeJetStream())
+	assert.NotContains(t, <-created, "deliver_policy")
+}
+
+// Test that a Start failing once connected, because the stream does not
+// exist yet, closes the connection so that a retried Start subscribes
+func TestStartRetryAfterJetStreamError(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+
+	conn, err := nats.Connect("nats://" + s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	var created int32
+	_, err = conn.Subscribe("$JS.API.CONSUMER.DURABLE.CREATE.metrics.telegraf", func(msg *nats.Msg) {
+		if atomic.AddInt32(&created, 1) == 1 {
+			conn.Publish(msg.Reply, []byte(`{"error": {"code": 404, "description": "stream not found"}}`))
+		} else {
+			conn.Publish(msg.Reply, []byte(`{}`))
+		}
+	})
+	require.NoError(t, err)
+	require.NoError(t, conn.Flush())
+
+	n := &natsConsumer{
+		Servers:                []string{"nats://" + s.Addr().String()},
+		Subjects:               []string{"metrics.>"},
+		JetStreamStream:        "metrics",
+		JetStreamConsumer:      "telegraf",
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		log:                    testutil.Logger{Name: "inputs.nats_consumer"},
+	}
+	n.SetParserFunc(parsers.NewInfluxParser)
+	acc := testutil.Accumulator{}
+
+	err = n.Start(&acc)
+	assert.EqualError(t, err, "creating JetStream consumer telegraf of stream metrics: stream not found (404)")
+	assert.Nil(t, n.Conn)
+	assert.Empty(t, n.Subs)
+	// Stop is safe after a failed Start
+	n.Stop()
+
+	require.NoError(t, n.Start(&acc))
+	defer n.Stop()
+	assert.EqualValues(t, 2, atomic.LoadInt32(&created))
+	assert.Len(t, n.Subs, 1)
+	assert.True(t, n.Conn.IsConnected())
+}
+
+// Test that a JetStream message delivered again once it is acknowledged is
+// acknowledged without adding its metrics twice
+func TestRunParserJetStreamDuplicate(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+
+	n, in := newTestNatsConsumer()
+	n.JetStreamStream = "metrics"
+	n.JetStreamConsumer = "telegraf"
+	n.DedupWindow = internal.Duration{Duration: time.Minute}
+	conn, err := nats.Connect("nats://" + s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	n.Conn = conn
+	n.inbox = nats.NewInbox()
+	acks, err := conn.SubscribeSync("$JS.ACK.>")
+	require.NoError(t, err)
+
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	defer close(n.done)
+
+	n.SetParserFunc(parsers.NewInfluxParser)
+	n.wg.Add(1)
+	go n.receiver()
+
+	for _, reply := range []string{
+		"$JS.ACK.metrics.telegraf.1.7.3.0.0",
+		"$JS.ACK.metrics.telegraf.2.7.4.0.0",
+	} {
+		msg := natsMsg(testMsg)
+		msg.Reply = reply
+		in <- msg
+
+		ack, err := acks.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, reply, ack.Subject)
+		assert.Equal(t, "+ACK", string(ack.Data))
+	}
+	assert.EqualValues(t, 1, acc.NMetrics())
+}
+
 func natsMsg(val string) *nats.Msg {
 	return &nats.Msg{
 		Subject: "telegraf",
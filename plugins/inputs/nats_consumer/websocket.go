@@ -0,0 +1,183 @@
+package natsconsumer
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/proxy"
+	netproxy "golang.org/x/net/proxy"
+	"golang.org/x/net/websocket"
+)
+
+// wsDialer connects the NATS client to servers behind a websocket endpoint,
+// the client dials the host of the servers and wsDialer opens the websocket
+// of the same host.
+type wsDialer struct {
+	urls      map[string]*url.URL
+	tlsConfig *tls.Config
+	proxy     func(*http.Request) (*url.URL, error)
+	timeout   time.Duration
+}
+
+// hasWebsocket reports whether one of the server urls is a websocket url.
+func hasWebsocket(servers []string) bool {
+	for _, server := range servers {
+		u, err := url.Parse(server)
+		if err == nil && (u.Scheme == "ws" || u.Scheme == "wss") {
+			return true
+		}
+	}
+	return false
+}
+
+// newWSDialer returns the dialer of the websocket servers and the servers
+// the client connects to, the servers cannot mix websocket and NATS urls
+// as TLS is done by the dialer.
+func newWSDialer(servers []string, tlsConfig *tls.Config, proxyFunc func(*http.Request) (*url.URL, error), timeout time.Duration) (*wsDialer, []string, error) {
+	d := &wsDialer{
+		urls:      make(map[string]*url.URL),
+		tlsConfig: tlsConfig,
+		proxy:     proxyFunc,
+		timeout:   timeout,
+	}
+	var natsServers []string
+	for _, server := range servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			return nil, nil, err
+		}
+		if u.Scheme != "ws" && u.Scheme != "wss" {
+			return nil, nil, fmt.Errorf("cannot mix websocket and NATS servers: %s", server)
+		}
+		d.urls[u.Host] = u
+
+		// the credentials of the url authenticate to NATS
+		natsServers = append(natsServers, (&url.URL{Scheme: "nats", User: u.User, Host: u.Host}).String())
+	}
+	return d, natsServers, nil
+}
+
+// Dial opens the websocket of the server address, through the proxy of its
+// url if there is one.
+func (d *wsDialer) Dial(network, address string) (net.Conn, error) {
+	u, ok := d.urls[address]
+	if !ok {
+		return nil, fmt.Errorf("no websocket url for NATS server %s", address)
+	}
+
+	scheme, port := "http", "80"
+	if u.Scheme == "wss" {
+		scheme, port = "https", "443"
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var dialer proxy.Dialer = &net.Dialer{Timeout: d.timeout}
+	proxyURL, err := d.proxy(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL != nil {
+		if dialer, err = d.proxyDialer(proxyURL, dialer); err != nil {
+			return nil, err
+		}
+	}
+
+	var conn net.Conn
+	if u.Scheme == "wss" {
+		config := d.tlsConfig
+		if config == nil {
+			config = &tls.Config{}
+		}
+		conn, err = proxy.DialTLS(dialer, network, addr, config, d.timeout)
+	} else {
+		conn, err = dialer.Dial(network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	location := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path, RawQuery: u.RawQuery}
+	config, err := websocket.NewConfig(location.String(), scheme+"://"+u.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if d.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.timeout))
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake with %s failed: %s", location, err)
+	}
+	conn.SetDeadline(time.Time{})
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
+
+// proxyDialer returns a dialer connecting through the proxy.
+func (d *wsDialer) proxyDialer(proxyURL *url.URL, direct proxy.Dialer) (proxy.Dialer, error) {
+	switch proxyURL.Scheme {
+	case "socks5":
+		return netproxy.FromURL(proxyURL, direct)
+	case "http":
+		return &connectDialer{proxy: proxyURL, dialer: direct, timeout: d.timeout}, nil
+	}
+	return nil, fmt.Errorf("unsupported scheme of the websocket proxy %s", proxyURL.Scheme)
+}
+
+// connectDialer opens tunnels through an HTTP proxy with CONNECT requests.
+type connectDialer struct {
+	proxy   *url.URL
+	dialer  proxy.Dialer
+	timeout time.Duration
+}
+
+func (d *connectDialer) Dial(network, address string) (net.Conn, error) {
+	conn, err := d.dialer.Dial(network, d.proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if d.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.timeout))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the server speaks once the tunnel is used, nothing is buffered past
+	// the response
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused the connection to %s: %s", d.proxy.Host, address, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,137 @@
+package nats_request
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/nats-io/nats.go"
+)
+
+// NatsRequest sends a request to a subject and measures the time of the
+// reply, like net_response for the NATS responders.
+type NatsRequest struct {
+	Servers  []string
+	Username string
+	Password string
+	Token    string
+	tls.ClientConfig
+
+	Subject string
+	Payload string
+	Expect  string
+	Timeout internal.Duration
+
+	sync.Mutex
+	conn *nats.Conn
+}
+
+var sampleConfig = `
+  ## urls of NATS servers
+  # servers = ["nats://localhost:4222"]
+  ## Optional credentials, instead of those of the server urls
+  # username = ""
+  # password = ""
+  ## Optional authentication token
+  # token = ""
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Subject the request is sent to, and its payload
+  subject = "health"
+  # payload = "ping"
+  ## Optional string expected in the reply
+  # expect = "pong"
+
+  ## Maximum time waited for the reply
+  # timeout = "1s"
+`
+
+func (n *NatsRequest) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NatsRequest) Description() string {
+	return "Send a NATS request and measure the time of the reply"
+}
+
+// connect returns the connection to the servers, it is opened again once it
+// is closed.
+func (n *NatsRequest) connect() (*nats.Conn, error) {
+	if n.conn != nil && !n.conn.IsClosed() {
+		return n.conn, nil
+	}
+
+	opts := nats.DefaultOptions
+	opts.Servers = n.Servers
+	opts.Timeout = n.Timeout.Duration
+	if n.Username != "" {
+		opts.User = n.Username
+		opts.Password = n.Password
+	}
+	opts.Token = n.Token
+
+	tlsConfig, err := n.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	// the config of FIPS mode is only used by the servers requiring TLS
+	opts.Secure = n.ClientConfig.Enabled()
+	opts.TLSConfig = tlsConfig
+
+	n.conn, err = opts.Connect()
+	return n.conn, err
+}
+
+func (n *NatsRequest) Gather(acc telegraf.Accumulator) error {
+	n.Lock()
+	defer n.Unlock()
+
+	tags := map[string]string{"subject": n.Subject}
+	fields := make(map[string]interface{})
+
+	conn, err := n.connect()
+	if err != nil {
+		acc.AddError(err)
+		fields["result_type"] = "connection_failed"
+		acc.AddFields("nats_request", fields, tags)
+		return nil
+	}
+
+	start := time.Now()
+	msg, err := conn.Request(n.Subject, []byte(n.Payload), n.Timeout.Duration)
+	responseTime := time.Since(start).Seconds()
+	switch {
+	case err == nats.ErrTimeout:
+		fields["result_type"] = "timeout"
+	case err != nil:
+		acc.AddError(err)
+		fields["result_type"] = "request_failed"
+	case n.Expect != "" && !strings.Contains(string(msg.Data), n.Expect):
+		fields["result_type"] = "string_mismatch"
+		fields["response_time"] = responseTime
+	default:
+		fields["result_type"] = "success"
+		fields["response_time"] = responseTime
+	}
+	acc.AddFields("nats_request", fields, tags)
+	return nil
+}
+
+func init() {
+	inputs.Add("nats_request", func() telegraf.Input {
+		return &NatsRequest{
+			Servers: []string{"nats://localhost:4222"},
+			Timeout: internal.Duration{Duration: time.Second},
+		}
+	})
+}
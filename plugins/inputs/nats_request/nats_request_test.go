@@ -0,0 +1,81 @@
+package nats_request
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/nats-io/gnatsd/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	s := server.New(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	go s.Start()
+	require.True(t, s.ReadyForConnections(10*time.Second))
+	defer s.Shutdown()
+
+	url := "nats://" + s.Addr().String()
+	responder, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer responder.Close()
+	_, err = responder.Subscribe("health", func(m *nats.Msg) {
+		responder.Publish(m.Reply, []byte("pong"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, responder.Flush())
+
+	n := &NatsRequest{
+		Servers: []string{url},
+		Subject: "health",
+		Payload: "ping",
+		Expect:  "pong",
+		Timeout: internal.Duration{Duration: time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	assert.True(t, acc.HasFloatField("nats_request", "response_time"))
+	acc.AssertContainsTaggedFields(t, "nats_request",
+		map[string]interface{}{
+			"result_type":   "success",
+			"response_time": acc.Metrics[0].Fields["response_time"],
+		},
+		map[string]string{"subject": "health"})
+
+	n.Expect = "ok"
+	acc.ClearMetrics()
+	require.NoError(t, n.Gather(&acc))
+	assert.Equal(t, "string_mismatch", acc.Metrics[0].Fields["result_type"])
+
+	n.Subject = "nobody"
+	n.Timeout = internal.Duration{Duration: 100 * time.Millisecond}
+	acc.ClearMetrics()
+	require.NoError(t, n.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "nats_request",
+		map[string]interface{}{"result_type": "timeout"},
+		map[string]string{"subject": "nobody"})
+}
+
+func TestGatherConnectionFailed(t *testing.T) {
+	n := &NatsRequest{
+		Servers: []string{"nats://127.0.0.1:1"},
+		Subject: "health",
+		Timeout: internal.Duration{Duration: 100 * time.Millisecond},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "nats_request",
+		map[string]interface{}{"result_type": "connection_failed"},
+		map[string]string{"subject": "health"})
+	assert.Len(t, acc.Errors, 1)
+}
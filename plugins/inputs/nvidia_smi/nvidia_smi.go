@@ -0,0 +1,290 @@
+package nvidia_smi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+var execCommand = exec.Command // execCommand is used to mock commands in tests.
+
+// NvidiaSMI gathers the metrics of the NVIDIA GPUs reported by nvidia-smi.
+type NvidiaSMI struct {
+	BinPath   string
+	Timeout   internal.Duration
+	Processes bool
+}
+
+var sampleConfig = `
+  ## Optional: path to nvidia-smi binary, defaults to $PATH via exec.LookPath
+  # bin_path = "/usr/bin/nvidia-smi"
+
+  ## Optional: timeout for GPU polling
+  # timeout = "5s"
+
+  ## Report the GPU memory used by each process running on the GPUs
+  # processes = true
+`
+
+func (smi *NvidiaSMI) SampleConfig() string {
+	return sampleConfig
+}
+
+func (smi *NvidiaSMI) Description() string {
+	return "Pulls statistics from nvidia GPUs attached to the host"
+}
+
+func (smi *NvidiaSMI) Gather(acc telegraf.Accumulator) error {
+	if len(smi.BinPath) == 0 {
+		return fmt.Errorf("nvidia-smi not found: verify that nvidia-smi is installed and that nvidia-smi is in your PATH")
+	}
+
+	cmd := execCommand(smi.BinPath, "-q", "-x")
+	out, err := internal.CombinedOutputTimeout(cmd, smi.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+
+	return smi.parse(acc, out)
+}
+
+// smiLog is the part of the XML output of nvidia-smi -q -x reported by
+// the plugin.
+type smiLog struct {
+	DriverVersion string   `xml:"driver_version"`
+	CUDAVersion   string   `xml:"cuda_version"`
+	GPUs          []smiGPU `xml:"gpu"`
+}
+
+type smiGPU struct {
+	ProductName string    `xml:"product_name"`
+	UUID        string    `xml:"uuid"`
+	PCIBusID    string    `xml:"pci>pci_bus_id"`
+	MIGMode     string    `xml:"mig_mode>current_mig"`
+	FanSpeed    string    `xml:"fan_speed"`
+	PState      string    `xml:"performance_state"`
+	ComputeMode string    `xml:"compute_mode"`
+	Memory      smiMemory `xml:"fb_memory_usage"`
+	Utilization struct {
+		GPU     string `xml:"gpu_util"`
+		Memory  string `xml:"memory_util"`
+		Encoder string `xml:"encoder_util"`
+		Decoder string `xml:"decoder_util"`
+	} `xml:"utilization"`
+	ECC         smiECC `xml:"ecc_errors>volatile"`
+	Temperature string `xml:"temperature>gpu_temp"`
+	// the power readings moved to gpu_power_readings with driver 530
+	Power    smiPower `xml:"power_readings"`
+	GPUPower smiPower `xml:"gpu_power_readings"`
+	Clocks   struct {
+		Graphics string `xml:"graphics_clock"`
+		SM       string `xml:"sm_clock"`
+		Memory   string `xml:"mem_clock"`
+		Video    string `xml:"video_clock"`
+	} `xml:"clocks"`
+	MIGDevices []smiMIGDevice `xml:"mig_devices>mig_device"`
+	Processes  []smiProcess   `xml:"processes>process_info"`
+}
+
+type smiMemory struct {
+	Total string `xml:"total"`
+	Used  string `xml:"used"`
+	Free  string `xml:"free"`
+}
+
+// smiECC holds the volatile ECC error counts, drivers before 450 report
+// single and double bit errors, later drivers the correctable and
+// uncorrectable errors of the SRAM and DRAM.
+type smiECC struct {
+	SingleBit         string `xml:"single_bit>total"`
+	DoubleBit         string `xml:"double_bit>total"`
+	SRAMCorrectable   string `xml:"sram_correctable"`
+	SRAMUncorrectable string `xml:"sram_uncorrectable"`
+	DRAMCorrectable   string `xml:"dram_correctable"`
+	DRAMUncorrectable string `xml:"dram_uncorrectable"`
+}
+
+type smiPower struct {
+	Draw  string `xml:"power_draw"`
+	Limit string `xml:"power_limit"`
+}
+
+type smiMIGDevice struct {
+	Index             string    `xml:"index"`
+	GPUInstanceID     string    `xml:"gpu_instance_id"`
+	ComputeInstanceID string    `xml:"compute_instance_id"`
+	Multiprocessors   string    `xml:"device_attributes>shared>multiprocessor_count"`
+	Memory            smiMemory `xml:"fb_memory_usage"`
+	SRAMUncorrectable string    `xml:"ecc_error_count>volatile_count>sram_uncorrectable"`
+}
+
+type smiProcess struct {
+	GPUInstanceID     string `xml:"gpu_instance_id"`
+	ComputeInstanceID string `xml:"compute_instance_id"`
+	PID               string `xml:"pid"`
+	Type              string `xml:"type"`
+	Name              string `xml:"process_name"`
+	UsedMemory        string `xml:"used_memory"`
+}
+
+func (smi *NvidiaSMI) parse(acc telegraf.Accumulator, out []byte) error {
+	var log smiLog
+	if err := xml.Unmarshal(out, &log); err != nil {
+		return fmt.Errorf("parsing output of nvidia-smi: %s", err)
+	}
+
+	now := time.Now()
+	for i, gpu := range log.GPUs {
+		tags := map[string]string{
+			"index": strconv.Itoa(i),
+		}
+		setTag(tags, "name", gpu.ProductName)
+		setTag(tags, "uuid", gpu.UUID)
+		setTag(tags, "pci_bus_id", gpu.PCIBusID)
+		setTag(tags, "pstate", gpu.PState)
+		setTag(tags, "compute_mode", gpu.ComputeMode)
+		setTag(tags, "mig_mode", gpu.MIGMode)
+		setTag(tags, "driver_version", log.DriverVersion)
+		setTag(tags, "cuda_version", log.CUDAVersion)
+
+		fields := map[string]interface{}{}
+		setInt(fields, "fan_speed", gpu.FanSpeed)
+		setInt(fields, "memory_total", gpu.Memory.Total)
+		setInt(fields, "memory_used", gpu.Memory.Used)
+		setInt(fields, "memory_free", gpu.Memory.Free)
+		setInt(fields, "utilization_gpu", gpu.Utilization.GPU)
+		setInt(fields, "utilization_memory", gpu.Utilization.Memory)
+		setInt(fields, "utilization_encoder", gpu.Utilization.Encoder)
+		setInt(fields, "utilization_decoder", gpu.Utilization.Decoder)
+		setInt(fields, "temperature_gpu", gpu.Temperature)
+		setInt(fields, "clocks_current_graphics", gpu.Clocks.Graphics)
+		setInt(fields, "clocks_current_sm", gpu.Clocks.SM)
+		setInt(fields, "clocks_current_memory", gpu.Clocks.Memory)
+		setInt(fields, "clocks_current_video", gpu.Clocks.Video)
+
+		power := gpu.Power
+		if power.Draw == "" {
+			power = gpu.GPUPower
+		}
+		setFloat(fields, "power_draw", power.Draw)
+		setFloat(fields, "power_limit", power.Limit)
+
+		setSum(fields, "ecc_errors_corrected", gpu.ECC.SingleBit, gpu.ECC.SRAMCorrectable, gpu.ECC.DRAMCorrectable)
+		setSum(fields, "ecc_errors_uncorrected", gpu.ECC.DoubleBit, gpu.ECC.SRAMUncorrectable, gpu.ECC.DRAMUncorrectable)
+
+		acc.AddFields("nvidia_smi", fields, tags, now)
+
+		for _, mig := range gpu.MIGDevices {
+			migTags := map[string]string{
+				"index":     tags["index"],
+				"mig_index": mig.Index,
+			}
+			setTag(migTags, "uuid", gpu.UUID)
+			setTag(migTags, "gpu_instance_id", mig.GPUInstanceID)
+			setTag(migTags, "compute_instance_id", mig.ComputeInstanceID)
+
+			migFields := map[string]interface{}{}
+			setInt(migFields, "multiprocessor_count", mig.Multiprocessors)
+			setInt(migFields, "memory_total", mig.Memory.Total)
+			setInt(migFields, "memory_used", mig.Memory.Used)
+			setInt(migFields, "memory_free", mig.Memory.Free)
+			setInt(migFields, "ecc_errors_uncorrected", mig.SRAMUncorrectable)
+
+			acc.AddFields("nvidia_smi_mig", migFields, migTags, now)
+		}
+
+		if !smi.Processes {
+			continue
+		}
+		for _, p := range gpu.Processes {
+			procTags := map[string]string{
+				"index": tags["index"],
+			}
+			setTag(procTags, "uuid", gpu.UUID)
+			setTag(procTags, "pid", p.PID)
+			setTag(procTags, "process_name", p.Name)
+			setTag(procTags, "type", p.Type)
+			setTag(procTags, "gpu_instance_id", p.GPUInstanceID)
+			setTag(procTags, "compute_instance_id", p.ComputeInstanceID)
+
+			procFields := map[string]interface{}{}
+			setInt(procFields, "used_memory", p.UsedMemory)
+
+			acc.AddFields("nvidia_smi_process", procFields, procTags, now)
+		}
+	}
+	return nil
+}
+
+// setTag sets the tag unless the value is empty or not available.
+func setTag(tags map[string]string, key, value string) {
+	value = strings.TrimSpace(value)
+	if value != "" && value != "N/A" {
+		tags[key] = value
+	}
+}
+
+// setInt parses values like "35 C", "1024 MiB" or "12 %" into an integer
+// field, values that are not available are skipped.
+func setInt(fields map[string]interface{}, key, value string) {
+	if v, ok := parseInt(value); ok {
+		fields[key] = v
+	}
+}
+
+func setFloat(fields map[string]interface{}, key, value string) {
+	f := strings.Fields(value)
+	if len(f) == 0 {
+		return
+	}
+	if v, err := strconv.ParseFloat(f[0], 64); err == nil {
+		fields[key] = v
+	}
+}
+
+// setSum sets the field to the sum of the available values.
+func setSum(fields map[string]interface{}, key string, values ...string) {
+	var sum int64
+	found := false
+	for _, value := range values {
+		if v, ok := parseInt(value); ok {
+			sum += v
+			found = true
+		}
+	}
+	if found {
+		fields[key] = sum
+	}
+}
+
+func parseInt(value string) (int64, bool) {
+	f := strings.Fields(value)
+	if len(f) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(f[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func init() {
+	inputs.Add("nvidia_smi", func() telegraf.Input {
+		smi := &NvidiaSMI{
+			Timeout:   internal.Duration{Duration: 5 * time.Second},
+			Processes: true,
+		}
+		if path, err := exec.LookPath("nvidia-smi"); err == nil {
+			smi.BinPath = path
+		}
+		return smi
+	})
+}
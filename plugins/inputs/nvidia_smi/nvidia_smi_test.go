@@ -0,0 +1,322 @@
+package nvidia_smi
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	mockTeslaData = `<?xml version="1.0" ?>
+<!DOCTYPE nvidia_smi_log SYSTEM "nvsmi_device_v9.dtd">
+<nvidia_smi_log>
+	<timestamp>Thu Jun 14 20:37:02 2018</timestamp>
+	<driver_version>396.26</driver_version>
+	<attached_gpus>1</attached_gpus>
+	<gpu id="00000000:00:1E.0">
+		<product_name>Tesla K80</product_name>
+		<uuid>GPU-f7c3471b-4a23-5c94-0c68-c18c748a6197</uuid>
+		<pci>
+			<pci_bus_id>00000000:00:1E.0</pci_bus_id>
+		</pci>
+		<fan_speed>N/A</fan_speed>
+		<performance_state>P0</performance_state>
+		<fb_memory_usage>
+			<total>11441 MiB</total>
+			<used>2139 MiB</used>
+			<free>9302 MiB</free>
+		</fb_memory_usage>
+		<compute_mode>Default</compute_mode>
+		<utilization>
+			<gpu_util>27 %</gpu_util>
+			<memory_util>7 %</memory_util>
+			<encoder_util>0 %</encoder_util>
+			<decoder_util>0 %</decoder_util>
+		</utilization>
+		<ecc_errors>
+			<volatile>
+				<single_bit>
+					<device_memory>3</device_memory>
+					<total>3</total>
+				</single_bit>
+				<double_bit>
+					<device_memory>0</device_memory>
+					<total>0</total>
+				</double_bit>
+			</volatile>
+		</ecc_errors>
+		<temperature>
+			<gpu_temp>42 C</gpu_temp>
+		</temperature>
+		<power_readings>
+			<power_state>P0</power_state>
+			<power_draw>62.85 W</power_draw>
+			<power_limit>149.00 W</power_limit>
+		</power_readings>
+		<clocks>
+			<graphics_clock>823 MHz</graphics_clock>
+			<sm_clock>823 MHz</sm_clock>
+			<mem_clock>2505 MHz</mem_clock>
+			<video_clock>780 MHz</video_clock>
+		</clocks>
+		<processes>
+			<process_info>
+				<pid>2996</pid>
+				<type>C</type>
+				<process_name>python</process_name>
+				<used_memory>2128 MiB</used_memory>
+			</process_info>
+		</processes>
+	</gpu>
+</nvidia_smi_log>
+`
+	mockA100Data = `<?xml version="1.0" ?>
+<!DOCTYPE nvidia_smi_log SYSTEM "nvsmi_device_v11.dtd">
+<nvidia_smi_log>
+	<driver_version>470.57.02</driver_version>
+	<cuda_version>11.4</cuda_version>
+	<attached_gpus>1</attached_gpus>
+	<gpu id="00000000:07:00.0">
+		<product_name>NVIDIA A100-SXM4-40GB</product_name>
+		<mig_mode>
+			<current_mig>Enabled</current_mig>
+			<pending_mig>Enabled</pending_mig>
+		</mig_mode>
+		<mig_devices>
+			<mig_device>
+				<index>0</index>
+				<gpu_instance_id>1</gpu_instance_id>
+				<compute_instance_id>0</compute_instance_id>
+				<device_attributes>
+					<shared>
+						<multiprocessor_count>42</multiprocessor_count>
+					</shared>
+				</device_attributes>
+				<ecc_error_count>
+					<volatile_count>
+						<sram_uncorrectable>0</sram_uncorrectable>
+					</volatile_count>
+				</ecc_error_count>
+				<fb_memory_usage>
+					<total>19968 MiB</total>
+					<used>1035 MiB</used>
+					<free>18933 MiB</free>
+				</fb_memory_usage>
+			</mig_device>
+		</mig_devices>
+		<uuid>GPU-3e8ad2bd-b2b5-7b1e-6451-2b6a8d4cbd63</uuid>
+		<pci>
+			<pci_bus_id>00000000:07:00.0</pci_bus_id>
+		</pci>
+		<fan_speed>N/A</fan_speed>
+		<performance_state>P0</performance_state>
+		<fb_memory_usage>
+			<total>40536 MiB</total>
+			<used>1048 MiB</used>
+			<free>39488 MiB</free>
+		</fb_memory_usage>
+		<compute_mode>Default</compute_mode>
+		<utilization>
+			<gpu_util>N/A</gpu_util>
+			<memory_util>N/A</memory_util>
+			<encoder_util>N/A</encoder_util>
+			<decoder_util>N/A</decoder_util>
+		</utilization>
+		<ecc_errors>
+			<volatile>
+				<sram_correctable>1</sram_correctable>
+				<sram_uncorrectable>0</sram_uncorrectable>
+				<dram_correctable>2</dram_correctable>
+				<dram_uncorrectable>0</dram_uncorrectable>
+			</volatile>
+		</ecc_errors>
+		<temperature>
+			<gpu_temp>33 C</gpu_temp>
+		</temperature>
+		<power_readings>
+			<power_draw>57.42 W</power_draw>
+			<power_limit>400.00 W</power_limit>
+		</power_readings>
+		<clocks>
+			<graphics_clock>1410 MHz</graphics_clock>
+			<sm_clock>1410 MHz</sm_clock>
+			<mem_clock>1215 MHz</mem_clock>
+			<video_clock>1275 MHz</video_clock>
+		</clocks>
+		<processes>
+			<process_info>
+				<gpu_instance_id>1</gpu_instance_id>
+				<compute_instance_id>0</compute_instance_id>
+				<pid>4711</pid>
+				<type>C</type>
+				<process_name>/usr/bin/python3</process_name>
+				<used_memory>1024 MiB</used_memory>
+			</process_info>
+		</processes>
+	</gpu>
+</nvidia_smi_log>
+`
+)
+
+func TestGather(t *testing.T) {
+	smi := &NvidiaSMI{
+		BinPath:   "nvidia-smi",
+		Timeout:   internal.Duration{Duration: 5 * time.Second},
+		Processes: true,
+	}
+	// overwriting exec commands with mock commands
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(smi.Gather))
+
+	acc.AssertContainsTaggedFields(t, "nvidia_smi",
+		map[string]interface{}{
+			"memory_total":            int64(11441),
+			"memory_used":             int64(2139),
+			"memory_free":             int64(9302),
+			"utilization_gpu":         int64(27),
+			"utilization_memory":      int64(7),
+			"utilization_encoder":     int64(0),
+			"utilization_decoder":     int64(0),
+			"temperature_gpu":         int64(42),
+			"clocks_current_graphics": int64(823),
+			"clocks_current_sm":       int64(823),
+			"clocks_current_memory":   int64(2505),
+			"clocks_current_video":    int64(780),
+			"power_draw":              62.85,
+			"power_limit":             149.0,
+			"ecc_errors_corrected":    int64(3),
+			"ecc_errors_uncorrected":  int64(0),
+		},
+		map[string]string{
+			"index":          "0",
+			"name":           "Tesla K80",
+			"uuid":           "GPU-f7c3471b-4a23-5c94-0c68-c18c748a6197",
+			"pci_bus_id":     "00000000:00:1E.0",
+			"pstate":         "P0",
+			"compute_mode":   "Default",
+			"driver_version": "396.26",
+		})
+	acc.AssertContainsTaggedFields(t, "nvidia_smi_process",
+		map[string]interface{}{
+			"used_memory": int64(2128),
+		},
+		map[string]string{
+			"index":        "0",
+			"uuid":         "GPU-f7c3471b-4a23-5c94-0c68-c18c748a6197",
+			"pid":          "2996",
+			"process_name": "python",
+			"type":         "C",
+		})
+	assert.Equal(t, uint64(2), acc.NMetrics())
+}
+
+func TestParseMIG(t *testing.T) {
+	smi := &NvidiaSMI{Processes: true}
+	var acc testutil.Accumulator
+	require.NoError(t, smi.parse(&acc, []byte(mockA100Data)))
+
+	acc.AssertContainsTaggedFields(t, "nvidia_smi",
+		map[string]interface{}{
+			"memory_total":            int64(40536),
+			"memory_used":             int64(1048),
+			"memory_free":             int64(39488),
+			"temperature_gpu":         int64(33),
+			"clocks_current_graphics": int64(1410),
+			"clocks_current_sm":       int64(1410),
+			"clocks_current_memory":   int64(1215),
+			"clocks_current_video":    int64(1275),
+			"power_draw":              57.42,
+			"power_limit":             400.0,
+			"ecc_errors_corrected":    int64(3),
+			"ecc_errors_uncorrected":  int64(0),
+		},
+		map[string]string{
+			"index":          "0",
+			"name":           "NVIDIA A100-SXM4-40GB",
+			"uuid":           "GPU-3e8ad2bd-b2b5-7b1e-6451-2b6a8d4cbd63",
+			"pci_bus_id":     "00000000:07:00.0",
+			"pstate":         "P0",
+			"compute_mode":   "Default",
+			"mig_mode":       "Enabled",
+			"driver_version": "470.57.02",
+			"cuda_version":   "11.4",
+		})
+	acc.AssertContainsTaggedFields(t, "nvidia_smi_mig",
+		map[string]interface{}{
+			"multiprocessor_count":   int64(42),
+			"memory_total":           int64(19968),
+			"memory_used":            int64(1035),
+			"memory_free":            int64(18933),
+			"ecc_errors_uncorrected": int64(0),
+		},
+		map[string]string{
+			"index":               "0",
+			"mig_index":           "0",
+			"uuid":                "GPU-3e8ad2bd-b2b5-7b1e-6451-2b6a8d4cbd63",
+			"gpu_instance_id":     "1",
+			"compute_instance_id": "0",
+		})
+	acc.AssertContainsTaggedFields(t, "nvidia_smi_process",
+		map[string]interface{}{
+			"used_memory": int64(1024),
+		},
+		map[string]string{
+			"index":               "0",
+			"uuid":                "GPU-3e8ad2bd-b2b5-7b1e-6451-2b6a8d4cbd63",
+			"pid":                 "4711",
+			"process_name":        "/usr/bin/python3",
+			"type":                "C",
+			"gpu_instance_id":     "1",
+			"compute_instance_id": "0",
+		})
+
+	// processes are not reported when disabled
+	smi.Processes = false
+	acc.ClearMetrics()
+	require.NoError(t, smi.parse(&acc, []byte(mockA100Data)))
+	assert.False(t, acc.HasMeasurement("nvidia_smi_process"))
+	assert.Equal(t, uint64(2), acc.NMetrics())
+}
+
+func TestParseInvalid(t *testing.T) {
+	smi := &NvidiaSMI{}
+	var acc testutil.Accumulator
+	require.Error(t, smi.parse(&acc, []byte("NVIDIA-SMI has failed")))
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command
+// and prints the output of nvidia-smi -q -x.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	cmd, args := args[3], args[4:]
+	if cmd == "nvidia-smi" && len(args) == 2 && args[0] == "-q" && args[1] == "-x" {
+		fmt.Fprint(os.Stdout, mockTeslaData)
+		os.Exit(0)
+	}
+	fmt.Fprint(os.Stdout, "command not found")
+	os.Exit(1)
+}
@@ -0,0 +1,482 @@
+package opcua
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// OpcUA reads or subscribes to the values of a list of nodes of an OPC UA
+// server.
+type OpcUA struct {
+	Name                 string            `toml:"name"`
+	Endpoint             string            `toml:"endpoint"`
+	SecurityPolicy       string            `toml:"security_policy"`
+	SecurityMode         string            `toml:"security_mode"`
+	Certificate          string            `toml:"certificate"`
+	PrivateKey           string            `toml:"private_key"`
+	AuthMethod           string            `toml:"auth_method"`
+	Username             string            `toml:"username"`
+	Password             string            `toml:"password"`
+	ConnectTimeout       internal.Duration `toml:"connect_timeout"`
+	RequestTimeout       internal.Duration `toml:"request_timeout"`
+	Mode                 string            `toml:"mode"`
+	SubscriptionInterval internal.Duration `toml:"subscription_interval"`
+	Nodes                []nodeSettings    `toml:"nodes"`
+
+	nodes  []node
+	client *opcua.Client
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	acc    telegraf.Accumulator
+}
+
+// nodeSettings describes a node as configured by the user.
+type nodeSettings struct {
+	Name   string            `toml:"name"`
+	NodeID string            `toml:"node_id"`
+	Tags   map[string]string `toml:"tags"`
+}
+
+type node struct {
+	nodeSettings
+	id *ua.NodeID
+}
+
+const (
+	modeRead      = "read"
+	modeSubscribe = "subscribe"
+)
+
+var sampleConfig = `
+  ## Metric name
+  # name = "opcua"
+
+  ## OPC UA endpoint URL
+  endpoint = "opc.tcp://localhost:4840"
+
+  ## Maximum time allowed to establish a connection to the endpoint
+  # connect_timeout = "10s"
+
+  ## Maximum time allowed for a request over the established connection
+  # request_timeout = "5s"
+
+  ## Security policy, one of "None", "Basic128Rsa15", "Basic256",
+  ## "Basic256Sha256", or "auto" to use the most secure policy offered by
+  ## the endpoint
+  # security_policy = "auto"
+
+  ## Security mode, one of "None", "Sign", "SignAndEncrypt", or "auto"
+  # security_mode = "auto"
+
+  ## Path to the client certificate and private key, both are required when
+  ## the security policy is not "None" or when authenticating with the
+  ## certificate
+  # certificate = "/etc/telegraf/cert.pem"
+  # private_key = "/etc/telegraf/key.pem"
+
+  ## Authentication method, one of "Anonymous", "UserName" or "Certificate"
+  # auth_method = "Anonymous"
+  # username = ""
+  # password = ""
+
+  ## Acquisition mode:
+  ##   read      - read all nodes on each interval
+  ##   subscribe - subscribe to value changes of the nodes, changes are
+  ##               reported as soon as they are published by the server
+  # mode = "read"
+
+  ## Publishing interval of the subscription in subscribe mode
+  # subscription_interval = "1s"
+
+  ## Node IDs to collect, node_id uses the OPC UA string notation
+  ## (eg. "ns=2;s=Temperature" or "ns=3;i=1001"). The value of each node is
+  ## stored in a field named after the node, the given tags are added to the
+  ## metric of the node.
+  [[inputs.opcua.nodes]]
+    name = "temperature"
+    node_id = "ns=2;s=Line1.Temperature"
+    [inputs.opcua.nodes.tags]
+      line = "1"
+`
+
+func (o *OpcUA) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpcUA) Description() string {
+	return "Retrieve data from OPC UA server nodes"
+}
+
+// parseNodes validates the configured nodes.
+func parseNodes(settings []nodeSettings) ([]node, error) {
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("no nodes configured")
+	}
+
+	names := make(map[string]bool)
+	nodes := make([]node, 0, len(settings))
+	for _, s := range settings {
+		if s.Name == "" {
+			return nil, fmt.Errorf("empty name for node %q", s.NodeID)
+		}
+		id, err := ua.ParseNodeID(s.NodeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node_id %q of node %q: %s", s.NodeID, s.Name, err)
+		}
+		key := s.Name + "\x00" + id.String()
+		if names[key] {
+			return nil, fmt.Errorf("duplicate node %q", s.Name)
+		}
+		names[key] = true
+		nodes = append(nodes, node{nodeSettings: s, id: id})
+	}
+	return nodes, nil
+}
+
+// Start connects to the endpoint and, in subscribe mode, creates the
+// subscription of the nodes.
+func (o *OpcUA) Start(acc telegraf.Accumulator) error {
+	o.acc = acc
+
+	switch o.Mode {
+	case "", modeRead, modeSubscribe:
+	default:
+		return fmt.Errorf("invalid mode %q", o.Mode)
+	}
+
+	var err error
+	o.nodes, err = parseNodes(o.Nodes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := url.Parse(o.Endpoint); err != nil {
+		return fmt.Errorf("invalid endpoint %q: %s", o.Endpoint, err)
+	}
+
+	var ctx context.Context
+	ctx, o.cancel = context.WithCancel(context.Background())
+
+	if err := o.connect(ctx); err != nil {
+		// the connection is retried on the next interval in read mode
+		if o.Mode != modeSubscribe {
+			log.Printf("E! [inputs.opcua] Connecting to %s failed: %s", o.Endpoint, err)
+			return nil
+		}
+		o.cancel()
+		return err
+	}
+
+	if o.Mode == modeSubscribe {
+		if err := o.subscribe(ctx); err != nil {
+			o.cancel()
+			o.client.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// options returns the client options for the configured security policy,
+// security mode and authentication method.
+func (o *OpcUA) options() ([]opcua.Option, error) {
+	endpoints, err := opcua.GetEndpoints(o.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := o.SecurityPolicy
+	if policy == "auto" {
+		policy = ""
+	}
+	mode := ua.MessageSecurityModeInvalid
+	switch o.SecurityMode {
+	case "", "auto":
+	case "None", "Sign", "SignAndEncrypt":
+		mode = ua.MessageSecurityModeFromString(o.SecurityMode)
+	default:
+		return nil, fmt.Errorf("invalid security_mode %q", o.SecurityMode)
+	}
+
+	// without a client certificate only unsecured endpoints are usable
+	if policy == "" && mode == ua.MessageSecurityModeInvalid && o.Certificate == "" {
+		policy = "None"
+	}
+
+	ep := opcua.SelectEndpoint(endpoints, policy, mode)
+	if ep == nil {
+		return nil, fmt.Errorf("no endpoint matches security policy %q and mode %q",
+			o.SecurityPolicy, o.SecurityMode)
+	}
+
+	timeout := o.RequestTimeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	var opts []opcua.Option
+	opts = append(opts, opcua.RequestTimeout(timeout))
+	if o.Certificate != "" || o.PrivateKey != "" {
+		opts = append(opts,
+			opcua.CertificateFile(o.Certificate),
+			opcua.PrivateKeyFile(o.PrivateKey))
+	} else if ep.SecurityPolicyURI != ua.SecurityPolicyURINone {
+		return nil, fmt.Errorf("certificate and private_key are required for security policy %s",
+			ep.SecurityPolicyURI)
+	}
+
+	var authType ua.UserTokenType
+	switch o.AuthMethod {
+	case "", "Anonymous":
+		authType = ua.UserTokenTypeAnonymous
+		opts = append(opts, opcua.AuthAnonymous())
+	case "UserName":
+		authType = ua.UserTokenTypeUserName
+		opts = append(opts, opcua.AuthUsername(o.Username, o.Password))
+	case "Certificate":
+		cert, err := loadCertificate(o.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		authType = ua.UserTokenTypeCertificate
+		opts = append(opts, opcua.AuthCertificate(cert))
+	default:
+		return nil, fmt.Errorf("invalid auth_method %q", o.AuthMethod)
+	}
+	opts = append(opts, opcua.SecurityFromEndpoint(ep, authType))
+
+	return opts, nil
+}
+
+func (o *OpcUA) connect(ctx context.Context) error {
+	opts, err := o.options()
+	if err != nil {
+		return err
+	}
+
+	timeout := o.ConnectTimeout.Duration
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := opcua.NewClient(o.Endpoint, opts...)
+	if err := client.Connect(connectCtx); err != nil {
+		return err
+	}
+	o.client = client
+	return nil
+}
+
+// subscribe monitors the value of all nodes, the client handle of each
+// monitored item is the index of the node.
+func (o *OpcUA) subscribe(ctx context.Context) error {
+	interval := o.SubscriptionInterval.Duration
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	notifications := make(chan *opcua.PublishNotificationData)
+	sub, err := o.client.Subscribe(&opcua.SubscriptionParameters{
+		Interval: interval,
+	}, notifications)
+	if err != nil {
+		return err
+	}
+
+	items := make([]*ua.MonitoredItemCreateRequest, 0, len(o.nodes))
+	for i, n := range o.nodes {
+		items = append(items,
+			opcua.NewMonitoredItemCreateRequestWithDefaults(n.id, ua.AttributeIDValue, uint32(i)))
+	}
+	res, err := sub.Monitor(ua.TimestampsToReturnBoth, items...)
+	if err != nil {
+		sub.Cancel()
+		return err
+	}
+	for i, result := range res.Results {
+		if result.StatusCode != ua.StatusOK {
+			log.Printf("W! [inputs.opcua] Monitoring node %q failed: %s",
+				o.nodes[i].Name, result.StatusCode)
+		}
+	}
+
+	o.wg.Add(2)
+	go func() {
+		defer o.wg.Done()
+		sub.Run(ctx)
+	}()
+	go func() {
+		defer o.wg.Done()
+		defer sub.Cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-notifications:
+				if n.Error != nil {
+					o.acc.AddError(n.Error)
+					continue
+				}
+				change, ok := n.Value.(*ua.DataChangeNotification)
+				if !ok {
+					continue
+				}
+				for _, item := range change.MonitoredItems {
+					if int(item.ClientHandle) >= len(o.nodes) {
+						continue
+					}
+					o.addValue(o.nodes[item.ClientHandle], item.Value, time.Now())
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Gather reads all nodes in read mode; value changes are added as they are
+// received in subscribe mode.
+func (o *OpcUA) Gather(acc telegraf.Accumulator) error {
+	if o.Mode == modeSubscribe {
+		return nil
+	}
+
+	if o.client == nil {
+		ctx := context.Background()
+		if err := o.connect(ctx); err != nil {
+			return fmt.Errorf("connecting to %s failed: %s", o.Endpoint, err)
+		}
+	}
+
+	req := &ua.ReadRequest{
+		MaxAge:             2000,
+		TimestampsToReturn: ua.TimestampsToReturnBoth,
+	}
+	for _, n := range o.nodes {
+		req.NodesToRead = append(req.NodesToRead, &ua.ReadValueID{NodeID: n.id})
+	}
+
+	res, err := o.client.Read(req)
+	if err != nil {
+		// reconnect on the next interval
+		o.client.Close()
+		o.client = nil
+		return fmt.Errorf("reading from %s failed: %s", o.Endpoint, err)
+	}
+
+	now := time.Now()
+	for i, value := range res.Results {
+		if i >= len(o.nodes) {
+			break
+		}
+		o.addValue(o.nodes[i], value, now)
+	}
+	return nil
+}
+
+// addValue adds the data value of the node to the accumulator, using the
+// source timestamp of the value when available.
+func (o *OpcUA) addValue(n node, value *ua.DataValue, now time.Time) {
+	name := o.Name
+	if name == "" {
+		name = "opcua"
+	}
+
+	tags := map[string]string{
+		"id": n.id.String(),
+	}
+	for k, v := range n.Tags {
+		tags[k] = v
+	}
+
+	fields := map[string]interface{}{
+		"quality": statusName(value.Status),
+	}
+	if value.Status == ua.StatusOK && value.Value != nil {
+		v, err := fieldValue(value.Value.Value())
+		if err != nil {
+			o.acc.AddError(fmt.Errorf("node %q: %s", n.Name, err))
+		} else {
+			fields[n.Name] = v
+		}
+	}
+
+	timestamp := now
+	if !value.SourceTimestamp.IsZero() {
+		timestamp = value.SourceTimestamp
+	} else if !value.ServerTimestamp.IsZero() {
+		timestamp = value.ServerTimestamp
+	}
+
+	o.acc.AddFields(name, fields, tags, timestamp)
+}
+
+// fieldValue converts the value of a variant into a field value.
+func fieldValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool, string, float32, float64,
+		int8, int16, int32, int64,
+		uint8, uint16, uint32, uint64:
+		return v, nil
+	case time.Time:
+		return v.UnixNano(), nil
+	case *ua.LocalizedText:
+		return v.Text, nil
+	case *ua.QualifiedName:
+		return v.Name, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+// loadCertificate returns the DER encoded certificate of a PEM or DER file.
+func loadCertificate(filename string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(b); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%s does not contain a certificate", filename)
+		}
+		return block.Bytes, nil
+	}
+	return b, nil
+}
+
+func statusName(status ua.StatusCode) string {
+	if desc, ok := ua.StatusCodes[status]; ok {
+		return strings.TrimPrefix(desc.Name, "Status")
+	}
+	return fmt.Sprintf("0x%08X", uint32(status))
+}
+
+func (o *OpcUA) Stop() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.wg.Wait()
+	if o.client != nil {
+		o.client.Close()
+		o.client = nil
+	}
+}
+
+func init() {
+	inputs.Add("opcua", func() telegraf.Input {
+		return &OpcUA{}
+	})
+}
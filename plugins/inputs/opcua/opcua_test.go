@@ -0,0 +1,106 @@
+package opcua
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNodes(t *testing.T) {
+	nodes, err := parseNodes([]nodeSettings{
+		{Name: "temperature", NodeID: "ns=2;s=Line1.Temperature", Tags: map[string]string{"line": "1"}},
+		{Name: "pressure", NodeID: "ns=3;i=1001"},
+	})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "ns=2;s=Line1.Temperature", nodes[0].id.String())
+	assert.Equal(t, "ns=3;i=1001", nodes[1].id.String())
+
+	tests := []struct {
+		name     string
+		settings []nodeSettings
+	}{
+		{"no nodes", nil},
+		{"empty name", []nodeSettings{{NodeID: "ns=2;s=a"}}},
+		{"invalid node id", []nodeSettings{{Name: "a", NodeID: "ns=x;s=a"}}},
+		{"duplicate", []nodeSettings{{Name: "a", NodeID: "ns=2;s=a"}, {Name: "a", NodeID: "ns=2;s=a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseNodes(tt.settings)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	ts := time.Unix(1500000000, 0)
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{int32(-5), int32(-5)},
+		{uint16(7), uint16(7)},
+		{float32(1.5), float32(1.5)},
+		{true, true},
+		{"running", "running"},
+		{ts, ts.UnixNano()},
+		{&ua.LocalizedText{Text: "hello"}, "hello"},
+	}
+	for _, tt := range tests {
+		v, err := fieldValue(tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, v)
+	}
+
+	_, err := fieldValue([]int32{1, 2})
+	assert.Error(t, err)
+}
+
+func TestAddValue(t *testing.T) {
+	var acc testutil.Accumulator
+	o := &OpcUA{acc: &acc}
+	nodes, err := parseNodes([]nodeSettings{
+		{Name: "temperature", NodeID: "ns=2;s=Line1.Temperature", Tags: map[string]string{"line": "1"}},
+		{Name: "pressure", NodeID: "ns=3;i=1001"},
+	})
+	require.NoError(t, err)
+
+	source := time.Unix(1500000000, 0)
+	o.addValue(nodes[0], &ua.DataValue{
+		Value:           ua.MustVariant(float64(21.5)),
+		Status:          ua.StatusOK,
+		SourceTimestamp: source,
+	}, time.Now())
+	o.addValue(nodes[1], &ua.DataValue{
+		Status: ua.StatusBadNodeIDUnknown,
+	}, time.Now())
+
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{
+			"temperature": float64(21.5),
+			"quality":     "OK",
+		},
+		map[string]string{"id": "ns=2;s=Line1.Temperature", "line": "1"})
+	assert.True(t, acc.HasTimestamp("opcua", source))
+
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{
+			"quality": "BadNodeIDUnknown",
+		},
+		map[string]string{"id": "ns=3;i=1001"})
+}
+
+func TestInvalidMode(t *testing.T) {
+	o := &OpcUA{
+		Endpoint: "opc.tcp://localhost:4840",
+		Mode:     "poll",
+		Nodes:    []nodeSettings{{Name: "a", NodeID: "ns=2;s=a"}},
+	}
+	var acc testutil.Accumulator
+	assert.Error(t, o.Start(&acc))
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"time"
 
 	// register in driver.
 	_ "github.com/jackc/pgx/stdlib"
@@ -19,14 +21,14 @@ type Postgresql struct {
 	postgresql.Service
 	Databases      []string
 	AdditionalTags []string
-	Query          []struct {
-		Sqlquery    string
-		Version     int
-		Withdbname  bool
-		Tagvalue    string
-		Measurement string
-	}
-	Debug bool
+	Query          query
+	Debug          bool
+
+	lastRun map[int]time.Time
+	// bookmarks are the bookmarks of the queries with a bookmark_column,
+	// they are persisted in the store.
+	bookmarks map[int]string
+	store     telegraf.Store
 }
 
 type query []struct {
@@ -35,6 +37,14 @@ type query []struct {
 	Withdbname  bool
 	Tagvalue    string
 	Measurement string
+	MinVersion  int               `toml:"min_version"`
+	MaxVersion  int               `toml:"max_version"`
+	Interval    internal.Duration `toml:"interval"`
+
+	// BookmarkColumn is the column whose value in the last row is passed as
+	// $1 to the next run of the query, BookmarkStart to the first run.
+	BookmarkColumn string `toml:"bookmark_column"`
+	BookmarkStart  string `toml:"bookmark_start"`
 }
 
 var ignoredColumns = map[string]bool{"stats_reset": true}
@@ -81,6 +91,21 @@ var sampleConfig = `
   ## field is used to define custom tags (separated by commas)
   ## The optional "measurement" value can be used to override the default
   ## output measurement name ("postgresql").
+  ##
+  ## The optional "min_version" and "max_version" values restrict the query
+  ## to the servers whose server_version_num is in this range, like 90600 for
+  ## 9.6 or 100000 for 10. The older "version" value is the minimum version
+  ## in the short format, like 901 for 9.1 or 1000 for 10.
+  ##
+  ## The optional "interval" value runs the query less often than the other
+  ## queries, at the first gather after the interval elapsed.
+  ##
+  ## The optional "bookmark_column" value reads the new rows of a table only:
+  ## the value of the column in the last row is passed as $1 to the next run
+  ## of the query, and "bookmark_start" to the first one. The bookmark is kept
+  ## across restarts in the state_file of the agent. The query must be ordered
+  ## by the column, like
+  ## "SELECT id, duration FROM jobs WHERE id > $1 ORDER BY id LIMIT 1000".
   #
   ## Structure :
   ## [[inputs.postgresql_extensible.query]]
@@ -89,6 +114,11 @@ var sampleConfig = `
   ##   withdbname boolean
   ##   tagvalue string (comma separated)
   ##   measurement string
+  ##   min_version int
+  ##   max_version int
+  ##   interval duration
+  ##   bookmark_column string
+  ##   bookmark_start string
   [[inputs.postgresql_extensible.query]]
     sqlquery="SELECT * FROM pg_stat_database"
     version=901
@@ -100,6 +130,13 @@ var sampleConfig = `
     version=901
     withdbname=false
     tagvalue="postgresql.stats"
+  [[inputs.postgresql_extensible.query]]
+    sqlquery="SELECT slot_name, active::int, pg_wal_lsn_diff(pg_current_wal_lsn(), restart_lsn) AS retained_bytes FROM pg_replication_slots"
+    min_version=100000
+    withdbname=false
+    tagvalue="slot_name"
+    measurement="postgresql_replication_slots"
+    interval="5m"
 `
 
 func (p *Postgresql) SampleConfig() string {
@@ -110,6 +147,19 @@ func (p *Postgresql) Description() string {
 	return "Read metrics from one or many postgresql servers"
 }
 
+func (p *Postgresql) SetStore(store telegraf.Store) {
+	p.store = store
+}
+
+func (p *Postgresql) Init() error {
+	for _, q := range p.Query {
+		if q.BookmarkColumn != "" && q.BookmarkStart == "" {
+			return fmt.Errorf("query %q: bookmark_start must be set with bookmark_column", q.Sqlquery)
+		}
+	}
+	return nil
+}
+
 func (p *Postgresql) IgnoredColumns() map[string]bool {
 	return ignoredColumns
 }
@@ -128,15 +178,25 @@ func (p *Postgresql) Gather(acc telegraf.Accumulator) error {
 
 	// Retreiving the database version
 
-	query = `select substring(setting from 1 for 3) as version from pg_settings where name='server_version_num'`
+	query = `select setting from pg_settings where name='server_version_num'`
 	if err = p.DB.QueryRow(query).Scan(&db_version); err != nil {
 		db_version = 0
 	}
 
+	if p.lastRun == nil {
+		p.lastRun = make(map[int]time.Time)
+	}
+	now := time.Now()
+
 	// We loop in order to process each query
 	// Query is not run if Database version does not match the query version.
 
 	for i := range p.Query {
+		if !p.shouldRun(i, db_version, now) {
+			continue
+		}
+		p.lastRun[i] = now
+
 		sql_query = p.Query[i].Sqlquery
 		tag_value = p.Query[i].Tagvalue
 		if p.Query[i].Measurement != "" {
@@ -157,46 +217,142 @@ func (p *Postgresql) Gather(acc telegraf.Accumulator) error {
 		}
 		sql_query += query_addon
 
-		if p.Query[i].Version <= db_version {
-			rows, err := p.DB.Query(sql_query)
-			if err != nil {
-				acc.AddError(err)
-				continue
-			}
+		var args []interface{}
+		if p.Query[i].BookmarkColumn != "" {
+			args = append(args, p.bookmark(i))
+		}
+		rows, err := p.DB.Query(sql_query, args...)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
 
-			defer rows.Close()
+		// grab the column information from the result
+		if columns, err = rows.Columns(); err != nil {
+			acc.AddError(err)
+			rows.Close()
+			continue
+		}
 
-			// grab the column information from the result
-			if columns, err = rows.Columns(); err != nil {
-				acc.AddError(err)
-				continue
+		p.AdditionalTags = nil
+		if tag_value != "" {
+			tag_list := strings.Split(tag_value, ",")
+			for t := range tag_list {
+				p.AdditionalTags = append(p.AdditionalTags, tag_list[t])
 			}
+		}
 
-			p.AdditionalTags = nil
-			if tag_value != "" {
-				tag_list := strings.Split(tag_value, ",")
-				for t := range tag_list {
-					p.AdditionalTags = append(p.AdditionalTags, tag_list[t])
-				}
+		moved := false
+		for rows.Next() {
+			bookmark, err := p.accRow(meas_name, rows, acc, columns, p.Query[i].BookmarkColumn)
+			if err != nil {
+				acc.AddError(err)
+				break
 			}
-
-			for rows.Next() {
-				err = p.accRow(meas_name, rows, acc, columns)
-				if err != nil {
-					acc.AddError(err)
-					break
-				}
+			if bookmark != "" {
+				p.bookmarks[i] = bookmark
+				moved = true
+			}
+		}
+		rows.Close()
+		if moved {
+			if err := p.saveBookmark(i); err != nil {
+				acc.AddError(err)
 			}
 		}
 	}
 	return nil
 }
 
+// bookmark returns the bookmark of the query, from the store before its
+// first run.
+func (p *Postgresql) bookmark(i int) string {
+	if p.bookmarks == nil {
+		p.bookmarks = make(map[int]string)
+	}
+	if bookmark, ok := p.bookmarks[i]; ok {
+		return bookmark
+	}
+	bookmark := p.Query[i].BookmarkStart
+	if p.store != nil {
+		if value, err := p.store.Get(bookmarkKey(p.Query[i].Sqlquery)); err == nil && value != nil {
+			bookmark = string(value)
+		}
+	}
+	p.bookmarks[i] = bookmark
+	return bookmark
+}
+
+// saveBookmark persists the bookmark of the query, the bookmarks are keyed
+// by the query so that reordering the queries keeps them.
+func (p *Postgresql) saveBookmark(i int) error {
+	if p.store == nil {
+		return nil
+	}
+	return p.store.Set(bookmarkKey(p.Query[i].Sqlquery), []byte(p.bookmarks[i]))
+}
+
+func bookmarkKey(sqlquery string) string {
+	return "bookmark:" + sqlquery
+}
+
+// bookmarkValue returns the value of the bookmark column as the text of a
+// query parameter.
+func bookmarkValue(column string, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case nil:
+		return "", fmt.Errorf("bookmark column %s is null", column)
+	}
+	return "", fmt.Errorf("bookmark column %s has the unsupported type %T", column, v)
+}
+
+// shouldRun returns true if the query supports the version of the server,
+// the server_version_num, and if its interval elapsed since its last run.
+func (p *Postgresql) shouldRun(i int, serverVersion int, now time.Time) bool {
+	q := p.Query[i]
+
+	// version is compared with the major and minor version like 901 for 9.1,
+	// since 10 the major version has two digits and no minor version
+	shortVersion := serverVersion / 100
+	if serverVersion >= 100000 {
+		shortVersion = serverVersion / 10000 * 100
+	}
+	if q.Version > shortVersion {
+		return false
+	}
+	if q.MinVersion != 0 && serverVersion < q.MinVersion {
+		return false
+	}
+	if q.MaxVersion != 0 && serverVersion > q.MaxVersion {
+		return false
+	}
+
+	if q.Interval.Duration == 0 {
+		return true
+	}
+	last, ok := p.lastRun[i]
+	// tolerate the jitter of the gathers
+	return !ok || now.Sub(last) >= q.Interval.Duration-time.Second
+}
+
 type scanner interface {
 	Scan(dest ...interface{}) error
 }
 
-func (p *Postgresql) accRow(meas_name string, row scanner, acc telegraf.Accumulator, columns []string) error {
+// accRow adds the metric of the row, it returns the value of the bookmark
+// column if it is set.
+func (p *Postgresql) accRow(meas_name string, row scanner, acc telegraf.Accumulator, columns []string,
+	bookmarkColumn string) (string, error) {
 	var (
 		err        error
 		columnVars []interface{}
@@ -218,7 +374,18 @@ func (p *Postgresql) accRow(meas_name string, row scanner, acc telegraf.Accumula
 
 	// deconstruct array of variables and send to Scan
 	if err = row.Scan(columnVars...); err != nil {
-		return err
+		return "", err
+	}
+
+	var bookmark string
+	if bookmarkColumn != "" {
+		val, ok := columnMap[bookmarkColumn]
+		if !ok {
+			return "", fmt.Errorf("bookmark column %s is not a column of the query", bookmarkColumn)
+		}
+		if bookmark, err = bookmarkValue(bookmarkColumn, *val); err != nil {
+			return "", err
+		}
 	}
 
 	if columnMap["datname"] != nil {
@@ -229,7 +396,7 @@ func (p *Postgresql) accRow(meas_name string, row scanner, acc telegraf.Accumula
 	}
 
 	if tagAddress, err = p.SanitizedAddress(); err != nil {
-		return err
+		return "", err
 	}
 
 	// Process the additional tags
@@ -271,7 +438,7 @@ COLUMN:
 		}
 	}
 	acc.AddFields(meas_name, fields, tags)
-	return nil
+	return bookmark, nil
 }
 
 func init() {
@@ -3,7 +3,10 @@ package postgresql_extensible
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/store"
 	"github.com/influxdata/telegraf/plugins/inputs/postgresql"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
@@ -222,3 +225,121 @@ func TestPostgresqlIgnoresUnwantedColumns(t *testing.T) {
 		assert.False(t, acc.HasMeasurement(col))
 	}
 }
+
+func TestPostgresqlQueryVersions(t *testing.T) {
+	p := &Postgresql{
+		Query: query{
+			{Sqlquery: "select 1", Version: 901},
+			{Sqlquery: "select 2", MinVersion: 90600},
+			{Sqlquery: "select 3", MaxVersion: 99999},
+			{Sqlquery: "select 4", Version: 1000, MaxVersion: 109999},
+		},
+	}
+	now := time.Now()
+
+	versions := map[int][]bool{
+		90105:  {true, false, true, false},
+		90605:  {true, true, true, false},
+		100003: {true, true, false, true},
+		110001: {true, true, false, false},
+	}
+	for version, expected := range versions {
+		for i := range p.Query {
+			assert.Equal(t, expected[i], p.shouldRun(i, version, now),
+				fmt.Sprintf("query %d on version %d", i, version))
+		}
+	}
+}
+
+func TestPostgresqlQueryInterval(t *testing.T) {
+	p := &Postgresql{
+		Query: query{
+			{Sqlquery: "select 1"},
+			{Sqlquery: "select 2", Interval: internal.Duration{Duration: time.Minute}},
+		},
+		lastRun: make(map[int]time.Time),
+	}
+	now := time.Now()
+
+	assert.True(t, p.shouldRun(1, 90605, now))
+	p.lastRun[0] = now
+	p.lastRun[1] = now
+
+	now = now.Add(10 * time.Second)
+	assert.True(t, p.shouldRun(0, 90605, now))
+	assert.False(t, p.shouldRun(1, 90605, now))
+
+	// the gathers are not exactly one interval apart
+	now = now.Add(50*time.Second - 10*time.Millisecond)
+	assert.True(t, p.shouldRun(1, 90605, now))
+}
+
+// row scans its values.
+type row []interface{}
+
+func (r row) Scan(dest ...interface{}) error {
+	for i, v := range r {
+		*dest[i].(*interface{}) = v
+	}
+	return nil
+}
+
+func TestPostgresqlBookmarks(t *testing.T) {
+	db, err := store.Open("")
+	require.NoError(t, err)
+	newPostgresql := func() *Postgresql {
+		p := &Postgresql{
+			Service: postgresql.Service{Address: "host=localhost user=postgres"},
+			Query: query{
+				{Sqlquery: "select 1"},
+				{Sqlquery: "select id from events where id > $1 order by id", BookmarkColumn: "id", BookmarkStart: "0"},
+			},
+		}
+		p.SetStore(db.Namespace("inputs.postgresql_extensible"))
+		return p
+	}
+
+	p := newPostgresql()
+	require.NoError(t, p.Init())
+	assert.Equal(t, "0", p.bookmark(1))
+
+	var acc testutil.Accumulator
+	bookmark, err := p.accRow("events", row{int64(42)}, &acc, []string{"id"}, "id")
+	require.NoError(t, err)
+	assert.Equal(t, "42", bookmark)
+	p.bookmarks[1] = bookmark
+	require.NoError(t, p.saveBookmark(1))
+
+	// the bookmark is kept across restarts, by query
+	p = newPostgresql()
+	p.Query[0], p.Query[1] = p.Query[1], p.Query[0]
+	assert.Equal(t, "42", p.bookmark(0))
+
+	_, err = p.accRow("events", row{int64(42)}, &acc, []string{"id"}, "ts")
+	assert.EqualError(t, err, "bookmark column ts is not a column of the query")
+
+	p.Query[0].BookmarkStart = ""
+	assert.Error(t, p.Init())
+}
+
+func TestPostgresqlBookmarkValue(t *testing.T) {
+	ts := time.Date(2018, 6, 13, 10, 17, 0, 500, time.UTC)
+	for _, tt := range []struct {
+		value    interface{}
+		bookmark string
+	}{
+		{int64(42), "42"},
+		{1.5, "1.5"},
+		{"a", "a"},
+		{[]byte("b"), "b"},
+		{ts, "2018-06-13T10:17:00.0000005Z"},
+	} {
+		bookmark, err := bookmarkValue("id", tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.bookmark, bookmark)
+	}
+	_, err := bookmarkValue("id", nil)
+	assert.EqualError(t, err, "bookmark column id is null")
+	_, err = bookmarkValue("id", true)
+	assert.EqualError(t, err, "bookmark column id has the unsupported type bool")
+}
@@ -0,0 +1,308 @@
+// +build linux
+
+package powerstat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// default host sys path
+const defaultHostSys = "/sys"
+
+// env host sys variable name
+const envSys = "HOST_SYS"
+
+const (
+	cpuFrequency        = "cpu_frequency"
+	cpuCStateResidency  = "cpu_c_state_residency"
+	microJoulesPerJoule = 1e6
+)
+
+type PowerStat struct {
+	HostSys    string   `toml:"host_sys"`
+	CPUMetrics []string `toml:"cpu_metrics"`
+
+	// previous samples of the energy counters and idle state residency,
+	// the rates are computed from the difference to the previous gather
+	energy    map[string]sample
+	residency map[string]sample
+
+	now func() time.Time
+}
+
+type sample struct {
+	value uint64
+	time  time.Time
+}
+
+var sampleConfig = `
+  ## Sets 'sys' directory path
+  ## If not specified, then default is /sys
+  # host_sys = "/sys"
+
+  ## The per package power draw is always reported, optionally report per
+  ## CPU metrics, can be "cpu_frequency" and "cpu_c_state_residency".
+  # cpu_metrics = ["cpu_frequency", "cpu_c_state_residency"]
+`
+
+func (p *PowerStat) Description() string {
+	return "Read the power draw of the CPU packages from the Intel RAPL energy counters"
+}
+
+func (p *PowerStat) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PowerStat) Gather(acc telegraf.Accumulator) error {
+	if p.HostSys == "" {
+		p.HostSys = sys(envSys, defaultHostSys)
+	}
+	if p.now == nil {
+		p.now = time.Now
+	}
+	if p.energy == nil {
+		p.energy = make(map[string]sample)
+		p.residency = make(map[string]sample)
+	}
+
+	var frequency, residency bool
+	for _, m := range p.CPUMetrics {
+		switch m {
+		case cpuFrequency:
+			frequency = true
+		case cpuCStateResidency:
+			residency = true
+		default:
+			return fmt.Errorf("unknown cpu metric %q", m)
+		}
+	}
+
+	if err := p.gatherPackages(acc); err != nil {
+		acc.AddError(err)
+	}
+	if frequency || residency {
+		if err := p.gatherCPUs(acc, frequency, residency); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// gatherPackages reports the power draw of the packages and of their DRAM
+// from the powercap RAPL zones, e.g.
+//   /sys/class/powercap/intel-rapl:0/name     package-0
+//   /sys/class/powercap/intel-rapl:0:0/name   dram
+func (p *PowerStat) gatherPackages(acc telegraf.Accumulator) error {
+	zones, err := filepath.Glob(filepath.Join(p.HostSys, "class/powercap/intel-rapl:*"))
+	if err != nil {
+		return err
+	}
+	if len(zones) == 0 {
+		return fmt.Errorf("no RAPL zones found in %s, verify that the intel_rapl module is loaded",
+			filepath.Join(p.HostSys, "class/powercap"))
+	}
+
+	packages := make(map[string]map[string]interface{})
+	for _, zone := range zones {
+		// the top level zones are the packages, their subzones are inside
+		// and are linked with the package number as prefix
+		ids := strings.Split(strings.TrimPrefix(filepath.Base(zone), "intel-rapl:"), ":")
+		if len(ids) > 2 {
+			continue
+		}
+		name, err := readString(filepath.Join(zone, "name"))
+		if err != nil {
+			return err
+		}
+
+		var field string
+		switch {
+		case len(ids) == 1 && strings.HasPrefix(name, "package-"):
+			field = "current_power_consumption_watts"
+		case name == "dram":
+			field = "current_dram_power_consumption_watts"
+		default:
+			continue
+		}
+
+		fields, ok := packages[ids[0]]
+		if !ok {
+			fields = make(map[string]interface{})
+			packages[ids[0]] = fields
+		}
+
+		if field == "current_power_consumption_watts" {
+			if tdp, err := readUint(filepath.Join(zone, "constraint_0_max_power_uw")); err == nil {
+				fields["thermal_design_power_watts"] = float64(tdp) / microJoulesPerJoule
+			}
+		}
+
+		power, ok, err := p.power(zone)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fields[field] = power
+		}
+	}
+
+	for id, fields := range packages {
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields("powerstat_package", fields, map[string]string{"package_id": id})
+	}
+	return nil
+}
+
+// power returns the average power draw of the zone in watts since the
+// previous gather, the energy counter wraps around at max_energy_range_uj.
+func (p *PowerStat) power(zone string) (float64, bool, error) {
+	energy, err := readUint(filepath.Join(zone, "energy_uj"))
+	if err != nil {
+		return 0, false, err
+	}
+	now := p.now()
+
+	prev, ok := p.energy[zone]
+	p.energy[zone] = sample{energy, now}
+	if !ok {
+		return 0, false, nil
+	}
+	elapsed := now.Sub(prev.time).Seconds()
+	if elapsed <= 0 {
+		return 0, false, nil
+	}
+
+	delta := energy - prev.value
+	if energy < prev.value {
+		max, err := readUint(filepath.Join(zone, "max_energy_range_uj"))
+		if err != nil {
+			return 0, false, err
+		}
+		delta = max - prev.value + energy
+	}
+	return float64(delta) / microJoulesPerJoule / elapsed, true, nil
+}
+
+// gatherCPUs reports the frequency and the residency of the idle states of
+// each CPU from the cpufreq and cpuidle subsystems.
+func (p *PowerStat) gatherCPUs(acc telegraf.Accumulator, frequency, residency bool) error {
+	cpus, err := filepath.Glob(filepath.Join(p.HostSys, "devices/system/cpu/cpu[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, cpu := range cpus {
+		id := strings.TrimPrefix(filepath.Base(cpu), "cpu")
+		tags := map[string]string{"cpu_id": id}
+		if pkg, err := readString(filepath.Join(cpu, "topology/physical_package_id")); err == nil {
+			tags["package_id"] = pkg
+		}
+		if core, err := readString(filepath.Join(cpu, "topology/core_id")); err == nil {
+			tags["core_id"] = core
+		}
+
+		fields := make(map[string]interface{})
+		if frequency {
+			// offline CPUs and CPUs without cpufreq driver have no frequency
+			if khz, err := readUint(filepath.Join(cpu, "cpufreq/scaling_cur_freq")); err == nil {
+				fields["cpu_frequency_mhz"] = float64(khz) / 1000
+			}
+		}
+		if residency {
+			if err := p.cStateResidency(cpu, fields); err != nil {
+				return err
+			}
+		}
+
+		if len(fields) > 0 {
+			acc.AddFields("powerstat_core", fields, tags)
+		}
+	}
+	return nil
+}
+
+// cStateResidency adds the share of the time since the previous gather the
+// CPU spent in each idle state, the polling state is skipped.
+func (p *PowerStat) cStateResidency(cpu string, fields map[string]interface{}) error {
+	states, err := filepath.Glob(filepath.Join(cpu, "cpuidle/state[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, state := range states {
+		name, err := readString(filepath.Join(state, "name"))
+		if err != nil {
+			return err
+		}
+		if name == "POLL" {
+			continue
+		}
+		usec, err := readUint(filepath.Join(state, "time"))
+		if err != nil {
+			return err
+		}
+		now := p.now()
+
+		prev, ok := p.residency[state]
+		p.residency[state] = sample{usec, now}
+		if !ok || usec < prev.value {
+			continue
+		}
+		elapsed := now.Sub(prev.time)
+		if elapsed <= 0 {
+			continue
+		}
+
+		name = strings.ToLower(strings.Replace(name, "-", "_", -1))
+		field := fmt.Sprintf("cpu_%s_state_residency_percent", name)
+		residency := float64(usec-prev.value) * 100 / float64(elapsed/time.Microsecond)
+		if residency > 100 {
+			residency = 100
+		}
+		fields[field] = residency
+	}
+	return nil
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// sys can be used to read file paths from env
+func sys(env, path string) string {
+	// try to read full file path
+	if p := os.Getenv(env); p != "" {
+		return p
+	}
+	// return default path
+	return path
+}
+
+func init() {
+	inputs.Add("powerstat", func() telegraf.Input {
+		return &PowerStat{}
+	})
+}
@@ -0,0 +1,120 @@
+// +build linux
+
+package powerstat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content+"\n"), 0644))
+	}
+}
+
+func TestGather(t *testing.T) {
+	root, err := ioutil.TempDir("", "powerstat")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"class/powercap/intel-rapl:0/name":                      "package-0",
+		"class/powercap/intel-rapl:0/energy_uj":                 "262143000000",
+		"class/powercap/intel-rapl:0/max_energy_range_uj":       "262143328850",
+		"class/powercap/intel-rapl:0/constraint_0_max_power_uw": "165000000",
+		"class/powercap/intel-rapl:0:0/name":                    "dram",
+		"class/powercap/intel-rapl:0:0/energy_uj":               "1000000",
+		"class/powercap/intel-rapl:0:1/name":                    "core",
+		"class/powercap/intel-rapl:0:1/energy_uj":               "1000000",
+
+		"devices/system/cpu/cpu0/topology/physical_package_id": "0",
+		"devices/system/cpu/cpu0/topology/core_id":             "0",
+		"devices/system/cpu/cpu0/cpufreq/scaling_cur_freq":     "2400000",
+		"devices/system/cpu/cpu0/cpuidle/state0/name":          "POLL",
+		"devices/system/cpu/cpu0/cpuidle/state0/time":          "100",
+		"devices/system/cpu/cpu0/cpuidle/state1/name":          "C1-SKX",
+		"devices/system/cpu/cpu0/cpuidle/state1/time":          "1000000",
+		"devices/system/cpu/cpu0/cpuidle/state2/name":          "C6",
+		"devices/system/cpu/cpu0/cpuidle/state2/time":          "5000000",
+		// offline CPU without cpufreq and cpuidle
+		"devices/system/cpu/cpu1/topology/physical_package_id": "0",
+		"devices/system/cpu/cpu1/topology/core_id":             "1",
+	})
+
+	now := time.Unix(1500000000, 0)
+	p := &PowerStat{
+		HostSys:    root,
+		CPUMetrics: []string{"cpu_frequency", "cpu_c_state_residency"},
+		now:        func() time.Time { return now },
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	// the power draw is only known after the second gather
+	acc.AssertContainsTaggedFields(t, "powerstat_package",
+		map[string]interface{}{
+			"thermal_design_power_watts": float64(165),
+		},
+		map[string]string{"package_id": "0"})
+	acc.AssertContainsTaggedFields(t, "powerstat_core",
+		map[string]interface{}{
+			"cpu_frequency_mhz": float64(2400),
+		},
+		map[string]string{"package_id": "0", "core_id": "0", "cpu_id": "0"})
+	assert.Equal(t, uint64(2), acc.NMetrics())
+
+	// the package energy counter wraps around
+	writeFiles(t, root, map[string]string{
+		"class/powercap/intel-rapl:0/energy_uj":            "19671150",
+		"class/powercap/intel-rapl:0:0/energy_uj":          "21000000",
+		"devices/system/cpu/cpu0/cpufreq/scaling_cur_freq": "1200000",
+		"devices/system/cpu/cpu0/cpuidle/state1/time":      "3000000",
+		"devices/system/cpu/cpu0/cpuidle/state2/time":      "11000000",
+	})
+	now = now.Add(10 * time.Second)
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	acc.AssertContainsTaggedFields(t, "powerstat_package",
+		map[string]interface{}{
+			"thermal_design_power_watts":           float64(165),
+			"current_power_consumption_watts":      float64(2),
+			"current_dram_power_consumption_watts": float64(2),
+		},
+		map[string]string{"package_id": "0"})
+	acc.AssertContainsTaggedFields(t, "powerstat_core",
+		map[string]interface{}{
+			"cpu_frequency_mhz":                  float64(1200),
+			"cpu_c1_skx_state_residency_percent": float64(20),
+			"cpu_c6_state_residency_percent":     float64(60),
+		},
+		map[string]string{"package_id": "0", "core_id": "0", "cpu_id": "0"})
+	assert.Equal(t, uint64(2), acc.NMetrics())
+}
+
+func TestGatherNoRAPL(t *testing.T) {
+	root, err := ioutil.TempDir("", "powerstat")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	p := &PowerStat{HostSys: root}
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(p.Gather))
+}
+
+func TestUnknownCPUMetric(t *testing.T) {
+	p := &PowerStat{CPUMetrics: []string{"cpu_temperature"}}
+	var acc testutil.Accumulator
+	require.Error(t, p.Gather(&acc))
+}
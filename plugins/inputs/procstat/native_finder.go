@@ -55,3 +55,27 @@ func (pg *NativeFinder) PidFile(path string) ([]PID, error) {
 	return pids, nil
 
 }
+
+//Children returns the direct children of the processes
+func (pg *NativeFinder) Children(pids []PID) ([]PID, error) {
+	var children []PID
+	parents := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		parents[int32(pid)] = true
+	}
+	procs, err := process.Processes()
+	if err != nil {
+		return children, err
+	}
+	for _, p := range procs {
+		ppid, err := p.Ppid()
+		if err != nil {
+			//skip, this can be caused by the pid no longer existing
+			continue
+		}
+		if parents[ppid] {
+			children = append(children, PID(p.Pid))
+		}
+	}
+	return children, nil
+}
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 // Implemention of PIDGatherer that execs pgrep to find processes
@@ -51,6 +52,25 @@ func (pg *Pgrep) FullPattern(pattern string) ([]PID, error) {
 	return find(pg.path, args)
 }
 
+// Children returns the direct children of the processes
+func (pg *Pgrep) Children(pids []PID) ([]PID, error) {
+	parents := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		parents = append(parents, strconv.Itoa(int(pid)))
+	}
+	out, err := exec.Command(pg.path, "-P", strings.Join(parents, ",")).Output()
+	if err != nil {
+		// pgrep exits with status 1 when no process matched
+		if exiterr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exiterr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() == 1 {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("Error running %s: %s", pg.path, err)
+	}
+	return parseOutput(string(out))
+}
+
 func find(path string, args []string) ([]PID, error) {
 	out, err := run(path, args)
 	if err != nil {
@@ -28,6 +28,7 @@ type PIDFinder interface {
 	Pattern(pattern string) ([]PID, error)
 	Uid(user string) ([]PID, error)
 	FullPattern(path string) ([]PID, error)
+	Children(pids []PID) ([]PID, error)
 }
 
 type Proc struct {
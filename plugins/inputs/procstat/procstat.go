@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -33,7 +34,11 @@ type Procstat struct {
 	CGroup      string `toml:"cgroup"`
 	PidTag      bool
 
+	IncludeChildren bool `toml:"include_children"`
+	Aggregate       bool `toml:"aggregate"`
+
 	finder PIDFinder
+	tags   map[string]string
 
 	createPIDFinder func() (PIDFinder, error)
 	procs           map[PID]Process
@@ -51,8 +56,17 @@ var sampleConfig = `
   # user = "nginx"
   ## Systemd unit name
   # systemd_unit = "nginx.service"
-  ## CGroup name or path
+  ## CGroup name or path, globs are supported
   # cgroup = "systemd/system.slice/nginx.service"
+  # cgroup = "cpu/docker/*"
+
+  ## Also monitor the descendants of the selected processes, like the
+  ## workers forked by a server
+  # include_children = false
+
+  ## Report the sum of the metrics of all the monitored processes as the
+  ## procstat_total measurement, in addition to the metrics of each process
+  # aggregate = false
 
   ## override for process_name
   ## This is optional; default is sourced from /proc/<pid>/status
@@ -104,15 +118,66 @@ func (p *Procstat) Gather(acc telegraf.Accumulator) error {
 	}
 	p.procs = procs
 
+	total := map[string]interface{}{}
 	for _, proc := range p.procs {
-		p.addMetrics(proc, acc)
+		fields := p.addMetrics(proc, acc)
+		if p.Aggregate {
+			p.addTotal(total, fields)
+		}
+	}
+
+	if p.Aggregate && err == nil {
+		var prefix string
+		if p.Prefix != "" {
+			prefix = p.Prefix + "_"
+		}
+		total[prefix+"num_processes"] = int64(len(p.procs))
+
+		tags := make(map[string]string, len(p.tags)+1)
+		for k, v := range p.tags {
+			tags[k] = v
+		}
+		if p.ProcessName != "" {
+			tags["process_name"] = p.ProcessName
+		}
+		acc.AddFields("procstat_total", total, tags)
 	}
 
 	return nil
 }
 
+// Add the fields of a process to the totals, the limits and the priorities
+// are not summed
+func (p *Procstat) addTotal(total, fields map[string]interface{}) {
+	var prefix string
+	if p.Prefix != "" {
+		prefix = p.Prefix + "_"
+	}
+
+	for k, v := range fields {
+		if k == "pid" || strings.HasPrefix(k, prefix+"rlimit_") ||
+			k == prefix+"nice_priority" || k == prefix+"realtime_priority" {
+			continue
+		}
+		switch v := v.(type) {
+		case int32:
+			n, _ := total[k].(int64)
+			total[k] = n + int64(v)
+		case int64:
+			n, _ := total[k].(int64)
+			total[k] = n + v
+		case uint64:
+			n, _ := total[k].(uint64)
+			total[k] = n + v
+		case float64:
+			n, _ := total[k].(float64)
+			total[k] = n + v
+		}
+	}
+}
+
 // Add metrics a single Process
-func (p *Procstat) addMetrics(proc Process, acc telegraf.Accumulator) {
+func (p *Procstat) addMetrics(proc Process, acc telegraf.Accumulator) map[string]interface{} {
 	var prefix string
 	if p.Prefix != "" {
 		prefix = p.Prefix + "_"
@@ -227,6 +292,7 @@ func (p *Procstat) addMetrics(proc Process, acc telegraf.Accumulator) {
 	}
 
 	acc.AddFields("procstat", fields, proc.Tags())
+	return fields
 }
 
 // Update monitored Processes
@@ -235,6 +301,7 @@ func (p *Procstat) updateProcesses(prevInfo map[PID]Process) (map[PID]Process, e
 	if err != nil {
 		return nil, err
 	}
+	p.tags = tags
 
 	procs := make(map[PID]Process, len(prevInfo))
 
@@ -313,9 +380,40 @@ func (p *Procstat) findPids() ([]PID, map[string]string, error) {
 		err = fmt.Errorf("Either exe, pid_file, user, pattern, systemd_unit, or cgroup must be specified")
 	}
 
+	if err == nil && p.IncludeChildren {
+		pids, err = descendants(f, pids)
+	}
+
 	return pids, tags, err
 }
 
+// Add the descendants of the processes, the children of each generation are
+// looked up together
+func descendants(f PIDFinder, pids []PID) ([]PID, error) {
+	seen := make(map[PID]bool, len(pids))
+	for _, pid := range pids {
+		seen[pid] = true
+	}
+
+	parents := pids
+	for len(parents) > 0 {
+		children, err := f.Children(parents)
+		if err != nil {
+			return nil, err
+		}
+		parents = nil
+		for _, pid := range children {
+			if seen[pid] {
+				continue
+			}
+			seen[pid] = true
+			pids = append(pids, pid)
+			parents = append(parents, pid)
+		}
+	}
+	return pids, nil
+}
+
 // execCommand is so tests can mock out exec.Command usage.
 var execCommand = exec.Command
 
@@ -347,14 +445,34 @@ func (p *Procstat) systemdUnitPIDs() ([]PID, error) {
 }
 
 func (p *Procstat) cgroupPIDs() ([]PID, error) {
-	var pids []PID
-
 	procsPath := p.CGroup
 	if procsPath[0] != '/' {
 		procsPath = "/sys/fs/cgroup/" + procsPath
 	}
-	procsPath = filepath.Join(procsPath, "cgroup.procs")
-	out, err := ioutil.ReadFile(procsPath)
+	items, err := filepath.Glob(procsPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cgroup '%s': %s", p.CGroup, err)
+	}
+	// a missing cgroup is reported, a glob may match no cgroup
+	if len(items) == 0 && !strings.ContainsAny(procsPath, "*?[") {
+		items = []string{procsPath}
+	}
+
+	var pids []PID
+	for _, item := range items {
+		cgroupPids, err := singleCgroupPIDs(item)
+		if err != nil {
+			return nil, err
+		}
+		pids = append(pids, cgroupPids...)
+	}
+	return pids, nil
+}
+
+func singleCgroupPIDs(path string) ([]PID, error) {
+	var pids []PID
+
+	out, err := ioutil.ReadFile(filepath.Join(path, "cgroup.procs"))
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +59,9 @@ ExecMainPID=11408
 }
 
 type testPgrep struct {
-	pids []PID
-	err  error
+	pids     []PID
+	children map[PID][]PID
+	err      error
 }
 
 func pidFinder(pids []PID, err error) func() (PIDFinder, error) {
@@ -88,6 +89,14 @@ func (pg *testPgrep) FullPattern(pattern string) ([]PID, error) {
 	return pg.pids, pg.err
 }
 
+func (pg *testPgrep) Children(pids []PID) ([]PID, error) {
+	var children []PID
+	for _, pid := range pids {
+		children = append(children, pg.children[pid]...)
+	}
+	return children, pg.err
+}
+
 type testProc struct {
 	pid  PID
 	tags map[string]string
@@ -129,7 +138,7 @@ func (p *testProc) NumFDs() (int32, error) {
 }
 
 func (p *testProc) NumThreads() (int32, error) {
-	return 0, nil
+	return 2, nil
 }
 
 func (p *testProc) Percent(interval time.Duration) (float64, error) {
@@ -369,3 +378,82 @@ func TestGather_cgroupPIDs(t *testing.T) {
 	assert.Equal(t, []PID{1234, 5678}, pids)
 	assert.Equal(t, td, tags["cgroup"])
 }
+
+func TestGather_cgroupGlobPIDs(t *testing.T) {
+	//no cgroups in windows
+	if runtime.GOOS == "windows" {
+		t.Skip("no cgroups in windows")
+	}
+	td, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(td)
+	for name, procs := range map[string]string{"a": "1234\n", "b": "5678\n"} {
+		require.NoError(t, os.Mkdir(filepath.Join(td, name), 0755))
+		err = ioutil.WriteFile(filepath.Join(td, name, "cgroup.procs"), []byte(procs), 0644)
+		require.NoError(t, err)
+	}
+
+	p := Procstat{
+		createPIDFinder: pidFinder([]PID{}, nil),
+		CGroup:          filepath.Join(td, "*"),
+	}
+	pids, tags, err := p.findPids()
+	require.NoError(t, err)
+	assert.Equal(t, []PID{1234, 5678}, pids)
+	assert.Equal(t, filepath.Join(td, "*"), tags["cgroup"])
+
+	// no cgroup matching the glob is not an error
+	p.CGroup = filepath.Join(td, "c*")
+	pids, _, err = p.findPids()
+	require.NoError(t, err)
+	assert.Empty(t, pids)
+
+	p.CGroup = filepath.Join(td, "c")
+	_, _, err = p.findPids()
+	require.Error(t, err)
+}
+
+func TestGather_IncludeChildren(t *testing.T) {
+	p := Procstat{
+		Exe:             exe,
+		IncludeChildren: true,
+		createPIDFinder: func() (PIDFinder, error) {
+			return &testPgrep{
+				pids: []PID{10, 20},
+				children: map[PID][]PID{
+					10: {11, 12},
+					12: {13},
+					20: {21},
+				},
+			}, nil
+		},
+	}
+	pids, tags, err := p.findPids()
+	require.NoError(t, err)
+	assert.Equal(t, []PID{10, 20, 11, 12, 21, 13}, pids)
+	assert.Equal(t, exe, tags["exe"])
+}
+
+func TestGather_Aggregate(t *testing.T) {
+	var acc testutil.Accumulator
+
+	p := Procstat{
+		Exe:             exe,
+		Aggregate:       true,
+		createPIDFinder: pidFinder([]PID{1, 2, 3}, nil),
+		createProcess:   newTestProc,
+	}
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	assert.Equal(t, exe, acc.TagValue("procstat_total", "exe"))
+	assert.False(t, acc.HasTag("procstat_total", "process_name"))
+	assert.True(t, acc.HasInt64Field("procstat_total", "num_processes"))
+	assert.True(t, acc.HasFloatField("procstat_total", "cpu_time_user"))
+	assert.True(t, acc.HasUIntField("procstat_total", "memory_rss"))
+	assert.False(t, acc.HasField("procstat_total", "pid"))
+
+	fields, ok := acc.Get("procstat_total")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), fields.Fields["num_processes"])
+	assert.Equal(t, int64(6), fields.Fields["num_threads"])
+}
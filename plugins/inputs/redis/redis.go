@@ -17,15 +17,31 @@ import (
 )
 
 type Redis struct {
-	Servers []string
+	Servers         []string
+	Cluster         bool     `toml:"cluster"`
+	SentinelMasters []string `toml:"sentinel_masters"`
+	Latency         bool     `toml:"latency"`
 
 	clients     []Client
+	seeds       []*seed
+	nodes       map[string]Client
+	newClient   func(network, address, password string, tags map[string]string) Client
 	initialized bool
 }
 
+// seed is a configured server, with cluster or sentinel_masters the nodes
+// are discovered from the seeds.
+type seed struct {
+	client   Client
+	host     string
+	password string
+}
+
 type Client interface {
 	Info() *redis.StringCmd
+	Do(args ...interface{}) *redis.Cmd
 	BaseTags() map[string]string
+	Close() error
 }
 
 type RedisClient struct {
@@ -33,8 +49,19 @@ type RedisClient struct {
 	tags   map[string]string
 }
 
+// Info returns all the sections of INFO, including the command statistics.
 func (r *RedisClient) Info() *redis.StringCmd {
-	return r.client.Info()
+	return r.client.Info("all")
+}
+
+func (r *RedisClient) Do(args ...interface{}) *redis.Cmd {
+	cmd := redis.NewCmd(args...)
+	r.client.Process(cmd)
+	return cmd
+}
+
+func (r *RedisClient) Close() error {
+	return r.client.Close()
 }
 
 func (r *RedisClient) BaseTags() map[string]string {
@@ -56,6 +83,19 @@ var sampleConfig = `
   ## If no servers are specified, then localhost is used as the host.
   ## If no port is specified, 6379 is used
   servers = ["tcp://localhost:6379"]
+
+  ## Gather all the nodes of a Redis Cluster, the nodes are discovered from
+  ## the servers with CLUSTER NODES and use the password of the server they
+  ## were discovered from.
+  # cluster = false
+
+  ## Gather the masters monitored by the Sentinels listed in servers, with
+  ## their replicas, instead of the Sentinels themselves.
+  # sentinel_masters = ["mymaster"]
+
+  ## Gather the latest latency spikes of the latency monitor with LATENCY
+  ## LATEST, the latency monitor is enabled by latency-monitor-threshold.
+  # latency = false
 `
 
 func (r *Redis) SampleConfig() string {
@@ -80,8 +120,12 @@ func (r *Redis) init(acc telegraf.Accumulator) error {
 	if len(r.Servers) == 0 {
 		r.Servers = []string{"tcp://localhost:6379"}
 	}
+	if r.newClient == nil {
+		r.newClient = newClient
+	}
 
 	r.clients = make([]Client, len(r.Servers))
+	r.seeds = make([]*seed, len(r.Servers))
 
 	for i, serv := range r.Servers {
 		if !strings.HasPrefix(serv, "tcp://") && !strings.HasPrefix(serv, "unix://") {
@@ -109,15 +153,6 @@ func (r *Redis) init(acc telegraf.Accumulator) error {
 			address = u.Host
 		}
 
-		client := redis.NewClient(
-			&redis.Options{
-				Addr:     address,
-				Password: password,
-				Network:  u.Scheme,
-				PoolSize: 1,
-			},
-		)
-
 		tags := map[string]string{}
 		if u.Scheme == "unix" {
 			tags["socket"] = u.Path
@@ -126,9 +161,11 @@ func (r *Redis) init(acc telegraf.Accumulator) error {
 			tags["port"] = u.Port()
 		}
 
-		r.clients[i] = &RedisClient{
-			client: client,
-			tags:   tags,
+		r.clients[i] = r.newClient(u.Scheme, address, password, tags)
+		r.seeds[i] = &seed{
+			client:   r.clients[i],
+			host:     u.Hostname(),
+			password: password,
 		}
 	}
 
@@ -136,6 +173,21 @@ func (r *Redis) init(acc telegraf.Accumulator) error {
 	return nil
 }
 
+func newClient(network, address, password string, tags map[string]string) Client {
+	client := redis.NewClient(
+		&redis.Options{
+			Addr:     address,
+			Password: password,
+			Network:  network,
+			PoolSize: 1,
+		},
+	)
+	return &RedisClient{
+		client: client,
+		tags:   tags,
+	}
+}
+
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (r *Redis) Gather(acc telegraf.Accumulator) error {
@@ -146,9 +198,14 @@ func (r *Redis) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 
+	clients := r.clients
+	if r.Cluster || len(r.SentinelMasters) > 0 {
+		clients = r.discover(acc)
+	}
+
 	var wg sync.WaitGroup
 
-	for _, client := range r.clients {
+	for _, client := range clients {
 		wg.Add(1)
 		go func(client Client) {
 			defer wg.Done()
@@ -167,7 +224,14 @@ func (r *Redis) gatherServer(client Client, acc telegraf.Accumulator) error {
 	}
 
 	rdr := strings.NewReader(info)
-	return gatherInfoOutput(rdr, acc, client.BaseTags())
+	if err := gatherInfoOutput(rdr, acc, client.BaseTags()); err != nil {
+		return err
+	}
+
+	if r.Latency {
+		return gatherLatency(client, acc)
+	}
+	return nil
 }
 
 // gatherInfoOutput gathers
@@ -195,6 +259,11 @@ func gatherInfoOutput(
 			continue
 		}
 
+		// the latency percentiles of the commands since redis 7
+		if section == "Latencystats" {
+			continue
+		}
+
 		parts := strings.SplitN(line, ":", 2)
 		if len(parts) < 2 {
 			continue
@@ -219,6 +288,11 @@ func gatherInfoOutput(
 			continue
 		}
 
+		if section == "Commandstats" {
+			gatherCommandstatLine(name, strings.TrimSpace(parts[1]), acc, tags)
+			continue
+		}
+
 		metric, ok := Tracking[name]
 		if !ok {
 			if section == "Keyspace" {
@@ -299,6 +373,70 @@ func gatherKeyspaceLine(
 	}
 }
 
+// Parse the command statistics of INFO, like:
+//     cmdstat_get:calls=53,usec=161,usec_per_call=3.04
+func gatherCommandstatLine(
+	name string,
+	line string,
+	acc telegraf.Accumulator,
+	global_tags map[string]string,
+) {
+	if !strings.HasPrefix(name, "cmdstat_") {
+		return
+	}
+
+	fields := make(map[string]interface{})
+	tags := make(map[string]string)
+	for k, v := range global_tags {
+		tags[k] = v
+	}
+	tags["command"] = strings.TrimPrefix(name, "cmdstat_")
+	for _, part := range strings.Split(line, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if ival, err := strconv.ParseInt(kv[1], 10, 64); err == nil {
+			fields[kv[0]] = ival
+		} else if fval, err := strconv.ParseFloat(kv[1], 64); err == nil {
+			fields[kv[0]] = fval
+		}
+	}
+	acc.AddFields("redis_cmdstat", fields, tags)
+}
+
+// gatherLatency reports the latest latency spike of each event, the reply
+// of LATENCY LATEST is an array of event name, time of the latest spike and
+// the latest and maximum latencies in milliseconds.
+func gatherLatency(client Client, acc telegraf.Accumulator) error {
+	reply, err := client.Do("latency", "latest").Result()
+	if err != nil {
+		return fmt.Errorf("latency latest: %s", err)
+	}
+	events, _ := reply.([]interface{})
+	for _, e := range events {
+		event, ok := e.([]interface{})
+		if !ok || len(event) < 4 {
+			continue
+		}
+		name, _ := event[0].(string)
+		timestamp, _ := event[1].(int64)
+		latest, _ := event[2].(int64)
+		max, _ := event[3].(int64)
+
+		tags := client.BaseTags()
+		tags["event"] = name
+		acc.AddFields("redis_latency",
+			map[string]interface{}{
+				"latest_time":       timestamp,
+				"latest_latency_ms": latest,
+				"max_latency_ms":    max,
+			},
+			tags)
+	}
+	return nil
+}
+
 func init() {
 	inputs.Add("redis", func() telegraf.Input {
 		return &Redis{}
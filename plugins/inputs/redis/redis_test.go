@@ -3,10 +3,12 @@ package redis
 import (
 	"bufio"
 	"fmt"
+	"net"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-redis/redis"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -116,6 +118,187 @@ func TestRedis_ParseMetrics(t *testing.T) {
 	}
 	acc.AssertContainsTaggedFields(t, "redis", fields, tags)
 	acc.AssertContainsTaggedFields(t, "redis_keyspace", keyspaceFields, keyspaceTags)
+
+	cmdstatTags := map[string]string{"host": "redis.net", "replication_role": "master", "command": "get"}
+	cmdstatFields := map[string]interface{}{
+		"calls":         int64(53),
+		"usec":          int64(161),
+		"usec_per_call": float64(3.04),
+	}
+	acc.AssertContainsTaggedFields(t, "redis_cmdstat", cmdstatFields, cmdstatTags)
+	for _, m := range acc.Metrics {
+		assert.NotContains(t, m.Fields, "latency_percentiles_usec_get")
+	}
+}
+
+// mockClient replies to the commands with the configured replies.
+type mockClient struct {
+	address string
+	tags    map[string]string
+	info    string
+	replies map[string]interface{}
+	closed  bool
+}
+
+func (c *mockClient) Info() *redis.StringCmd {
+	return redis.NewStringResult(c.info, nil)
+}
+
+func (c *mockClient) Do(args ...interface{}) *redis.Cmd {
+	var words []string
+	for _, arg := range args {
+		words = append(words, fmt.Sprint(arg))
+	}
+	reply, ok := c.replies[strings.Join(words, " ")]
+	if !ok {
+		return redis.NewCmdResult(nil, fmt.Errorf("ERR unknown command"))
+	}
+	return redis.NewCmdResult(reply, nil)
+}
+
+func (c *mockClient) BaseTags() map[string]string {
+	tags := make(map[string]string)
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+func (c *mockClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+// mockServers creates the mock clients of the servers with replies.
+func mockServers(r *Redis, replies map[string]map[string]interface{}) map[string]*mockClient {
+	clients := make(map[string]*mockClient)
+	r.newClient = func(network, address, password string, tags map[string]string) Client {
+		c := &mockClient{
+			address: address,
+			tags:    tags,
+			info:    "# Replication\nrole:master\n",
+			replies: replies[address],
+		}
+		clients[address] = c
+		return c
+	}
+	return clients
+}
+
+const clusterNodesOutput = `07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected
+67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002,node-2 master - 0 1426238316232 2 connected 5461-10922
+292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 127.0.0.1:30003@31003 master - 0 1426238318243 3 connected 10923-16383 [93-<-292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f]
+e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca :30001@31001 myself,master - 0 0 1 connected 0-5460
+6ec23923021cf3ffec47632106199cb7f496ce01 127.0.0.1:30005@31005 handshake - 0 1426238316232 5 connected
+`
+
+func TestRedis_Cluster(t *testing.T) {
+	r := &Redis{
+		Servers: []string{"tcp://:secret@127.0.0.1:30001"},
+		Cluster: true,
+	}
+	clients := mockServers(r, map[string]map[string]interface{}{
+		"127.0.0.1:30001": {"cluster nodes": clusterNodesOutput},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	expected := map[string]string{
+		"127.0.0.1:30001": "0-5460",
+		"127.0.0.1:30002": "5461-10922",
+		"127.0.0.1:30003": "10923-16383",
+		"127.0.0.1:30004": "",
+	}
+	for address, slots := range expected {
+		host, port, _ := net.SplitHostPort(address)
+		tags := map[string]string{"server": host, "port": port, "replication_role": "master"}
+		if slots != "" {
+			tags["slots"] = slots
+		}
+		acc.AssertContainsTaggedFields(t, "redis", map[string]interface{}{"keyspace_hitrate": float64(0)}, tags)
+	}
+	assert.Len(t, acc.Metrics, 4)
+	assert.NotContains(t, clients, "127.0.0.1:30005")
+
+	// the clients of the nodes are reused
+	node := clients["127.0.0.1:30002"]
+	require.NoError(t, acc.GatherError(r.Gather))
+	assert.True(t, node == clients["127.0.0.1:30002"])
+	assert.False(t, node.closed)
+
+	// removed nodes are closed
+	clients["127.0.0.1:30001"].replies["cluster nodes"] = strings.Join(strings.Split(clusterNodesOutput, "\n")[1:], "\n")
+	require.NoError(t, acc.GatherError(r.Gather))
+	assert.True(t, clients["127.0.0.1:30004"].closed)
+}
+
+func TestRedis_Sentinel(t *testing.T) {
+	r := &Redis{
+		Servers:         []string{"tcp://10.0.0.1:26379"},
+		SentinelMasters: []string{"mymaster"},
+	}
+	clients := mockServers(r, map[string]map[string]interface{}{
+		"10.0.0.1:26379": {
+			"sentinel master mymaster": []interface{}{
+				"name", "mymaster", "ip", "10.0.0.2", "port", "6379", "flags", "master",
+			},
+			"sentinel slaves mymaster": []interface{}{
+				[]interface{}{"name", "10.0.0.3:6379", "ip", "10.0.0.3", "port", "6379", "flags", "slave"},
+				[]interface{}{"name", "10.0.0.4:6379", "ip", "10.0.0.4", "port", "6379", "flags", "s_down,slave,disconnected"},
+			},
+		},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	for _, host := range []string{"10.0.0.2", "10.0.0.3"} {
+		assert.True(t, acc.HasPoint("redis",
+			map[string]string{"server": host, "port": "6379", "sentinel_master": "mymaster", "replication_role": "master"},
+			"keyspace_hitrate", float64(0)))
+	}
+	assert.Len(t, acc.Metrics, 2)
+	assert.NotContains(t, clients, "10.0.0.4:6379")
+
+	// the masters must be monitored by the sentinel
+	r.SentinelMasters = []string{"othermaster"}
+	acc.ClearMetrics()
+	assert.Error(t, acc.GatherError(r.Gather))
+	assert.True(t, clients["10.0.0.2:6379"].closed)
+}
+
+func TestRedis_Latency(t *testing.T) {
+	r := &Redis{
+		Servers: []string{"tcp://localhost:6379"},
+		Latency: true,
+	}
+	mockServers(r, map[string]map[string]interface{}{
+		"localhost:6379": {
+			"latency latest": []interface{}{
+				[]interface{}{"command", int64(1405067976), int64(251), int64(1001)},
+				[]interface{}{"fast-command", int64(1405067822), int64(14), int64(14)},
+			},
+		},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	acc.AssertContainsTaggedFields(t, "redis_latency",
+		map[string]interface{}{
+			"latest_time":       int64(1405067976),
+			"latest_latency_ms": int64(251),
+			"max_latency_ms":    int64(1001),
+		},
+		map[string]string{"server": "localhost", "port": "6379", "event": "command"})
+	acc.AssertContainsTaggedFields(t, "redis_latency",
+		map[string]interface{}{
+			"latest_time":       int64(1405067822),
+			"latest_latency_ms": int64(14),
+			"max_latency_ms":    int64(14),
+		},
+		map[string]string{"server": "localhost", "port": "6379", "event": "fast-command"})
 }
 
 const testOutput = `# Server
@@ -205,6 +388,12 @@ used_cpu_user:0.05
 used_cpu_sys_children:0.00
 used_cpu_user_children:0.00
 
+# Commandstats
+cmdstat_get:calls=53,usec=161,usec_per_call=3.04
+
+# Latencystats
+latency_percentiles_usec_get:p50=1.003,p99=4.015,p99.9=4.015
+
 # Keyspace
 db0:keys=2,expires=0,avg_ttl=0
 
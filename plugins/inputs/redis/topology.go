@@ -0,0 +1,229 @@
+package redis
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// node is a server discovered from a seed.
+type node struct {
+	address  string
+	password string
+	tags     map[string]string
+}
+
+// discover returns the clients of the nodes of the clusters, or of the
+// masters and replicas monitored by the sentinels. Nodes known by several
+// seeds are gathered once and the clients of the nodes that disappeared are
+// closed.
+func (r *Redis) discover(acc telegraf.Accumulator) []Client {
+	found := make(map[string]*node)
+	var order []string
+	for _, s := range r.seeds {
+		var nodes []*node
+		var err error
+		if r.Cluster {
+			nodes, err = clusterNodes(s)
+		} else {
+			nodes, err = sentinelNodes(s, r.SentinelMasters)
+		}
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		for _, n := range nodes {
+			if _, ok := found[n.address]; !ok {
+				order = append(order, n.address)
+			}
+			found[n.address] = n
+		}
+	}
+
+	if r.nodes == nil {
+		r.nodes = make(map[string]Client)
+	}
+	for address, client := range r.nodes {
+		if _, ok := found[address]; !ok {
+			client.Close()
+			delete(r.nodes, address)
+		}
+	}
+
+	clients := make([]Client, 0, len(order))
+	for _, address := range order {
+		n := found[address]
+		client, ok := r.nodes[address]
+		// the slots of the masters change when the cluster is resharded
+		if ok && !sameTags(client.BaseTags(), n.tags) {
+			client.Close()
+			ok = false
+		}
+		if !ok {
+			client = r.newClient("tcp", address, n.password, n.tags)
+			r.nodes[address] = client
+		}
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func sameTags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeTags(address string) map[string]string {
+	tags := map[string]string{}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	tags["server"] = host
+	tags["port"] = port
+	return tags
+}
+
+// clusterNodes discovers the nodes of a cluster with CLUSTER NODES.
+func clusterNodes(s *seed) ([]*node, error) {
+	reply, err := s.client.Do("cluster", "nodes").Result()
+	if err != nil {
+		return nil, fmt.Errorf("cluster nodes: %s", err)
+	}
+	nodes, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("cluster nodes: unexpected reply %v", reply)
+	}
+	return parseClusterNodes(nodes, s.host, s.password), nil
+}
+
+// parseClusterNodes parses the reply of CLUSTER NODES, one node per line:
+//     <id> <ip:port@cport[,hostname]> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> <slot> ...
+// The masters are tagged with the ranges of their slots.
+func parseClusterNodes(reply, seedHost, password string) []*node {
+	var nodes []*node
+	for _, line := range strings.Split(reply, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 8 {
+			continue
+		}
+
+		flags := strings.Split(parts[2], ",")
+		skip := false
+		for _, flag := range flags {
+			if flag == "noaddr" || flag == "handshake" {
+				skip = true
+			}
+		}
+		if skip {
+			continue
+		}
+
+		address := parts[1]
+		if i := strings.IndexAny(address, "@,"); i >= 0 {
+			address = address[:i]
+		}
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			continue
+		}
+		// the node doesn't know its own address before it joins the cluster
+		if host == "" {
+			host = seedHost
+		}
+		address = net.JoinHostPort(host, port)
+
+		tags := nodeTags(address)
+		var slots []string
+		for _, slot := range parts[8:] {
+			// slots being imported or migrated
+			if strings.HasPrefix(slot, "[") {
+				continue
+			}
+			slots = append(slots, slot)
+		}
+		if len(slots) > 0 {
+			tags["slots"] = strings.Join(slots, ",")
+		}
+
+		nodes = append(nodes, &node{
+			address:  address,
+			password: password,
+			tags:     tags,
+		})
+	}
+	return nodes
+}
+
+// sentinelNodes discovers the masters monitored by a sentinel and their
+// replicas.
+func sentinelNodes(s *seed, masters []string) ([]*node, error) {
+	var nodes []*node
+	for _, name := range masters {
+		reply, err := s.client.Do("sentinel", "master", name).Result()
+		if err != nil {
+			return nil, fmt.Errorf("sentinel master %s: %s", name, err)
+		}
+		master := parseSentinelInfo(reply)
+		if n := sentinelNode(master, name, s.password); n != nil {
+			nodes = append(nodes, n)
+		}
+
+		reply, err = s.client.Do("sentinel", "slaves", name).Result()
+		if err != nil {
+			return nil, fmt.Errorf("sentinel slaves %s: %s", name, err)
+		}
+		replicas, _ := reply.([]interface{})
+		for _, replica := range replicas {
+			if n := sentinelNode(parseSentinelInfo(replica), name, s.password); n != nil {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// parseSentinelInfo parses the description of a server returned by
+// SENTINEL MASTER and SENTINEL SLAVES, an array of field names and values.
+func parseSentinelInfo(reply interface{}) map[string]string {
+	info := make(map[string]string)
+	values, _ := reply.([]interface{})
+	for i := 0; i+1 < len(values); i += 2 {
+		k, _ := values[i].(string)
+		v, _ := values[i+1].(string)
+		info[k] = v
+	}
+	return info
+}
+
+// sentinelNode returns the node of a server described by a sentinel, the
+// servers that are down are not gathered.
+func sentinelNode(info map[string]string, master, password string) *node {
+	if info["ip"] == "" || info["port"] == "" {
+		return nil
+	}
+	flags := strings.Split(info["flags"], ",")
+	for _, flag := range flags {
+		if flag == "s_down" || flag == "o_down" || flag == "disconnected" {
+			return nil
+		}
+	}
+
+	address := net.JoinHostPort(info["ip"], info["port"])
+	tags := nodeTags(address)
+	tags["sentinel_master"] = master
+	return &node{
+		address:  address,
+		password: password,
+		tags:     tags,
+	}
+}
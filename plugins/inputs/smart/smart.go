@@ -20,13 +20,15 @@ var (
 	execCommand = exec.Command // execCommand is used to mock commands in tests.
 
 	// Device Model:     APPLE SSD SM256E
-	modelInInfo = regexp.MustCompile("^Device Model:\\s+(.*)$")
+	// Model Number:     Samsung SSD 960 EVO 250GB
+	modelInInfo = regexp.MustCompile("^(?:Device Model|Model Number):\\s+(.*)$")
 	// Serial Number:    S0X5NZBC422720
 	serialInInfo = regexp.MustCompile("^Serial Number:\\s+(.*)$")
 	// LU WWN Device Id: 5 002538 655584d30
 	wwnInInfo = regexp.MustCompile("^LU WWN Device Id:\\s+(.*)$")
 	// User Capacity:    251,000,193,024 bytes [251 GB]
-	usercapacityInInfo = regexp.MustCompile("^User Capacity:\\s+([0-9,]+)\\s+bytes.*$")
+	// Total NVM Capacity:                 250,059,350,016 [250 GB]
+	usercapacityInInfo = regexp.MustCompile("^(?:User|Total NVM) Capacity:\\s+([0-9,]+)\\s+(?:bytes)?.*$")
 	// SMART support is: Enabled
 	smartEnabledInInfo = regexp.MustCompile("^SMART support is:\\s+(\\w+)$")
 	// SMART overall-health self-assessment test result: PASSED
@@ -45,6 +47,23 @@ var (
 		"194": "temp_c",
 		"199": "udma_crc_errors",
 	}
+
+	// SMART/Health Information (NVMe Log 0x02)
+	nvmeHealthInfo = regexp.MustCompile("^SMART/Health Information \\(NVMe Log 0x02")
+	// Critical Warning:                   0x00
+	// Temperature:                        38 Celsius
+	// Percentage Used:                    0%
+	// Data Units Read:                    5,234,193 [2.67 TB]
+	nvmeAttribute = regexp.MustCompile("^([\\w][\\w\\. ]*\\w):\\s+(0x[0-9a-fA-F]+|[0-9,]+).*$")
+
+	// NVMe health log entries saved to a field of the device
+	nvmeDeviceFields = map[string]string{
+		"Critical Warning":                "critical_warning",
+		"Temperature":                     "temp_c",
+		"Available Spare":                 "available_spare",
+		"Percentage Used":                 "percentage_used",
+		"Media and Data Integrity Errors": "media_errors",
+	}
 )
 
 type Smart struct {
@@ -54,6 +73,8 @@ type Smart struct {
 	Excludes   []string
 	Devices    []string
 	UseSudo    bool
+
+	VendorAttributes []string
 }
 
 var sampleConfig = `
@@ -79,6 +100,11 @@ var sampleConfig = `
   ##
   # attributes = false
   #
+  ## Optionally specify how the raw values of vendor specific attributes
+  ## are decoded, passed to smartctl as --vendorattribute.
+  ## See --vendorattribute in the man pages for smartctl.
+  # vendor_attributes = [ "9,minutes", "194,tempminmax" ]
+  #
   ## Optionally specify devices to exclude from reporting.
   # excludes = [ "/dev/pass6" ]
   #
@@ -161,8 +187,14 @@ func (m *Smart) getAttributes(acc telegraf.Accumulator, devices []string) {
 	var wg sync.WaitGroup
 	wg.Add(len(devices))
 
+	// smartctl 5.41 & 5.42 have are broken regarding handling of --nocheck/-n
+	args := []string{"--info", "--health", "--attributes", "--tolerance=verypermissive", "-n", m.Nocheck, "--format=brief"}
+	for _, v := range m.VendorAttributes {
+		args = append(args, "--vendorattribute="+v)
+	}
+
 	for _, device := range devices {
-		go gatherDisk(acc, m.UseSudo, m.Attributes, m.Path, m.Nocheck, device, &wg)
+		go gatherDisk(acc, m.UseSudo, m.Attributes, m.Path, args, device, &wg)
 	}
 
 	wg.Wait()
@@ -179,11 +211,10 @@ func exitStatus(err error) (int, error) {
 	return 0, err
 }
 
-func gatherDisk(acc telegraf.Accumulator, usesudo, attributes bool, smartctl, nockeck, device string, wg *sync.WaitGroup) {
+func gatherDisk(acc telegraf.Accumulator, usesudo, attributes bool, smartctl string, options []string, device string, wg *sync.WaitGroup) {
 
 	defer wg.Done()
-	// smartctl 5.41 & 5.42 have are broken regarding handling of --nocheck/-n
-	args := []string{"--info", "--health", "--attributes", "--tolerance=verypermissive", "-n", nockeck, "--format=brief"}
+	args := append([]string{}, options...)
 	args = append(args, strings.Split(device, " ")...)
 	cmd := sudo(usesudo, smartctl, args...)
 	out, e := internal.CombinedOutputTimeout(cmd, time.Second*5)
@@ -202,6 +233,7 @@ func gatherDisk(acc telegraf.Accumulator, usesudo, attributes bool, smartctl, no
 	device_fields := make(map[string]interface{})
 	device_fields["exit_status"] = exitStatus
 
+	nvme := false
 	for _, line := range strings.Split(outStr, "\n") {
 
 		model := modelInInfo.FindStringSubmatch(line)
@@ -234,6 +266,42 @@ func gatherDisk(acc telegraf.Accumulator, usesudo, attributes bool, smartctl, no
 			device_fields["health_ok"] = (health[1] == "PASSED")
 		}
 
+		if nvmeHealthInfo.MatchString(line) {
+			nvme = true
+			continue
+		}
+		if nvme {
+			attr := nvmeAttribute.FindStringSubmatch(line)
+			if len(attr) < 3 {
+				continue
+			}
+			val, err := parseNvmeValue(attr[2])
+			if err != nil {
+				continue
+			}
+
+			if attributes {
+				tags := map[string]string{}
+				fields := make(map[string]interface{})
+
+				tags["device"] = path.Base(device_node)
+				if serial, ok := device_tags["serial_no"]; ok {
+					tags["serial_no"] = serial
+				}
+				tags["name"] = nvmeAttributeName(attr[1])
+
+				fields["exit_status"] = exitStatus
+				fields["raw_value"] = val
+
+				acc.AddFields("smart_attribute", fields, tags)
+			}
+
+			if field, ok := nvmeDeviceFields[attr[1]]; ok {
+				device_fields[field] = val
+			}
+			continue
+		}
+
 		attr := attribute.FindStringSubmatch(line)
 
 		if len(attr) > 1 {
@@ -321,6 +389,22 @@ func parseRawValue(rawVal string) (int64, error) {
 	return duration, nil
 }
 
+// parseNvmeValue parses the hexadecimal and thousands separated decimal
+// values of the NVMe health log.
+func parseNvmeValue(rawVal string) (int64, error) {
+	if strings.HasPrefix(rawVal, "0x") {
+		return strconv.ParseInt(rawVal[2:], 16, 64)
+	}
+	return strconv.ParseInt(strings.Replace(rawVal, ",", "", -1), 10, 64)
+}
+
+// nvmeAttributeName converts the description of a NVMe health log entry
+// to an attribute name like those of smartctl, e.g. "Power On Hours"
+// becomes "Power_On_Hours".
+func nvmeAttributeName(description string) string {
+	return strings.Join(strings.Fields(strings.Replace(description, ".", "", -1)), "_")
+}
+
 func parseInt(str string) int64 {
 	if i, err := strconv.ParseInt(str, 10, 64); err == nil {
 		return i
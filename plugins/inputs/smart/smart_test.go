@@ -59,6 +59,48 @@ ID# ATTRIBUTE_NAME          FLAGS    VALUE WORST THRESH FAIL RAW_VALUE
                             |||____ S speed/performance
                             ||_____ O updated online
                             |______ P prefailure warning
+`
+	mockNvmeInfoData = `smartctl 6.6 2017-11-05 r4594 [x86_64-linux-4.15.0-23-generic] (local build)
+Copyright (C) 2002-17, Bruce Allen, Christian Franke, www.smartmontools.org
+
+=== START OF INFORMATION SECTION ===
+Model Number:                       Samsung SSD 960 EVO 250GB
+Serial Number:                      S3ESNX0K308438J
+Firmware Version:                   2B7QCXE7
+PCI Vendor/Subsystem ID:            0x144d
+IEEE OUI Identifier:                0x002538
+Total NVM Capacity:                 250,059,350,016 [250 GB]
+Unallocated NVM Capacity:           0
+Controller ID:                      2
+Number of Namespaces:               1
+Namespace 1 Size/Capacity:          250,059,350,016 [250 GB]
+Namespace 1 Utilization:            26,500,821,504 [26.5 GB]
+Namespace 1 Formatted LBA Size:     512
+Local Time is:                      Thu Jun 14 20:14:23 2018 CEST
+
+=== START OF SMART DATA SECTION ===
+SMART overall-health self-assessment test result: PASSED
+
+SMART/Health Information (NVMe Log 0x02)
+Critical Warning:                   0x04
+Temperature:                        38 Celsius
+Available Spare:                    100%
+Available Spare Threshold:          10%
+Percentage Used:                    3%
+Data Units Read:                    5,234,193 [2.67 TB]
+Data Units Written:                 3,981,165 [2.03 TB]
+Host Read Commands:                 75,099,914
+Host Write Commands:                60,028,604
+Controller Busy Time:               263
+Power Cycles:                       733
+Power On Hours:                     1,465
+Unsafe Shutdowns:                   51
+Media and Data Integrity Errors:    2
+Error Information Log Entries:      712
+Warning  Comp. Temperature Time:    0
+Critical Comp. Temperature Time:    0
+Temperature Sensor 1:               38 Celsius
+Temperature Sensor 2:               45 Celsius
 `
 )
 
@@ -379,6 +421,64 @@ func TestGatherNoAttributes(t *testing.T) {
 
 }
 
+func TestGatherNvme(t *testing.T) {
+	s := &Smart{
+		Path:             "smartctl",
+		Attributes:       true,
+		Devices:          []string{"/dev/nvme0"},
+		VendorAttributes: []string{"9,minutes"},
+	}
+	// overwriting exec commands with mock commands
+	execCommand = fakeExecCommand
+	var acc testutil.Accumulator
+
+	err := s.Gather(&acc)
+
+	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "smart_device",
+		map[string]interface{}{
+			"exit_status":      int(0),
+			"health_ok":        true,
+			"critical_warning": int64(4),
+			"temp_c":           int64(38),
+			"available_spare":  int64(100),
+			"percentage_used":  int64(3),
+			"media_errors":     int64(2),
+		},
+		map[string]string{
+			"device":    "nvme0",
+			"model":     "Samsung SSD 960 EVO 250GB",
+			"serial_no": "S3ESNX0K308438J",
+			"capacity":  "250059350016",
+		})
+
+	var testsNvmeAttributes = []struct {
+		name  string
+		value int64
+	}{
+		{"Critical_Warning", 4},
+		{"Data_Units_Read", 5234193},
+		{"Power_On_Hours", 1465},
+		{"Warning_Comp_Temperature_Time", 0},
+		{"Temperature_Sensor_2", 45},
+	}
+	for _, test := range testsNvmeAttributes {
+		acc.AssertContainsTaggedFields(t, "smart_attribute",
+			map[string]interface{}{
+				"exit_status": int(0),
+				"raw_value":   test.value,
+			},
+			map[string]string{
+				"device":    "nvme0",
+				"serial_no": "S3ESNX0K308438J",
+				"name":      test.name,
+			})
+	}
+	assert.Equal(t, uint64(20), acc.NMetrics())
+}
+
 func TestExcludedDev(t *testing.T) {
 	assert.Equal(t, true, excludedDev([]string{"/dev/pass6"}, "/dev/pass6 -d atacam"), "Should be excluded.")
 	assert.Equal(t, false, excludedDev([]string{}, "/dev/pass6 -d atacam"), "Shouldn't be excluded.")
@@ -416,7 +516,17 @@ func TestHelperProcess(t *testing.T) {
 			fmt.Fprint(os.Stdout, mockScanData)
 		}
 		if arg1 == "--info" {
-			fmt.Fprint(os.Stdout, mockInfoAttributeData)
+			switch args[len(args)-1] {
+			case "/dev/nvme0":
+				// the vendor attributes precede the device
+				if args[len(args)-2] != "--vendorattribute=9,minutes" {
+					fmt.Fprint(os.Stdout, "invalid arguments")
+					os.Exit(1)
+				}
+				fmt.Fprint(os.Stdout, mockNvmeInfoData)
+			default:
+				fmt.Fprint(os.Stdout, mockInfoAttributeData)
+			}
 		}
 	} else {
 		fmt.Fprint(os.Stdout, "command not found")
@@ -3,6 +3,7 @@ package snmp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"math"
 	"net"
@@ -341,6 +342,12 @@ func (s *Snmp) Description() string {
 // Any error encountered does not halt the process. The errors are accumulated
 // and returned at the end.
 func (s *Snmp) Gather(acc telegraf.Accumulator) error {
+	return s.GatherContext(context.Background(), acc)
+}
+
+// GatherContext gathers like Gather, the agents stop being queried once ctx
+// is done.
+func (s *Snmp) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
 	if err := s.init(); err != nil {
 		return err
 	}
@@ -355,6 +362,7 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 				acc.AddError(Errorf(err, "agent %s", agent))
 				return
 			}
+			gs = contextConnection{snmpConnection: gs, ctx: ctx}
 
 			// First is the top-level fields. We treat the fields as table prefixes with an empty index.
 			t := Table{
@@ -368,6 +376,9 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 
 			// Now is the real tables.
 			for _, t := range s.Tables {
+				if ctx.Err() != nil {
+					return
+				}
 				if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
 					acc.AddError(Errorf(err, "agent %s: gathering table %s", agent, t.Name))
 				}
@@ -576,6 +587,32 @@ func (gsw gosnmpWrapper) Get(oids []string) (*gosnmp.SnmpPacket, error) {
 	return nil, err
 }
 
+// contextConnection is a snmpConnection whose queries fail once its context
+// is done, a walk stops at the next PDU.
+type contextConnection struct {
+	snmpConnection
+	ctx context.Context
+}
+
+func (c contextConnection) Walk(oid string, fn gosnmp.WalkFunc) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return c.snmpConnection.Walk(oid, func(ent gosnmp.SnmpPDU) error {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		return fn(ent)
+	})
+}
+
+func (c contextConnection) Get(oids []string) (*gosnmp.SnmpPacket, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.snmpConnection.Get(oids)
+}
+
 // getConnection creates a snmpConnection (*gosnmp.GoSNMP) object and caches the
 // result using `agentIndex` as the cache key.  This is done to allow multiple
 // connections to a single address.  It is an error to use a connection in
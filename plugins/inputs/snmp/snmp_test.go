@@ -2,6 +2,7 @@
 package snmp
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
@@ -609,6 +610,43 @@ func TestGather(t *testing.T) {
 	assert.Equal(t, 123456, m2.Fields["myOtherField"])
 }
 
+func TestGatherContext_canceled(t *testing.T) {
+	s := &Snmp{
+		Agents: []string{"TestGather"},
+		Name:   "mytable",
+		Fields: []Field{
+			{
+				Name: "myfield2",
+				Oid:  ".1.0.0.1.2",
+			},
+		},
+		Tables: []Table{
+			{
+				Name: "myOtherTable",
+				Fields: []Field{
+					{
+						Name: "myOtherField",
+						Oid:  ".1.0.0.0.1.4",
+					},
+				},
+			},
+		},
+
+		connectionCache: []snmpConnection{
+			tsc,
+		},
+		initialized: true,
+	}
+	acc := &testutil.Accumulator{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, s.GatherContext(ctx, acc))
+	assert.Empty(t, acc.Metrics)
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), context.Canceled.Error())
+}
+
 func TestGather_host(t *testing.T) {
 	s := &Snmp{
 		Agents: []string{"TestGather"},
@@ -116,9 +116,7 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 			//TODO rate limit
 			continue
 		}
-		for _, m := range metrics {
-			ssl.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
-		}
+		ssl.AddMetrics(metrics)
 	}
 
 	if err := scnr.Err(); err != nil {
@@ -152,9 +150,7 @@ func (psl *packetSocketListener) listen() {
 			//TODO rate limit
 			continue
 		}
-		for _, m := range metrics {
-			psl.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
-		}
+		psl.AddMetrics(metrics)
 	}
 }
 
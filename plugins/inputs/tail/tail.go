@@ -4,6 +4,8 @@ package tail
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -29,6 +31,8 @@ type Tail struct {
 	parser  parsers.Parser
 	wg      sync.WaitGroup
 	acc     telegraf.Accumulator
+	// store keeps the offset of each file at stop, by file name
+	store telegraf.Store
 
 	sync.Mutex
 }
@@ -50,7 +54,8 @@ const sampleConfig = `
   ## See https://github.com/gobwas/glob for more examples
   ##
   files = ["/var/mymetrics.out"]
-  ## Read file from beginning.
+  ## Read file from beginning. The files are read from the offsets they were
+  ## read to when telegraf stopped if the state_file of the agent is set.
   from_beginning = false
   ## Whether file is a named pipe
   pipe = false
@@ -73,6 +78,10 @@ func (t *Tail) Description() string {
 	return "Stream a log file, like the tail -f command"
 }
 
+func (t *Tail) SetStore(store telegraf.Store) {
+	t.store = store
+}
+
 func (t *Tail) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
@@ -103,11 +112,15 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 			t.acc.AddError(fmt.Errorf("E! Error Glob %s failed to compile, %s", filepath, err))
 		}
 		for file, _ := range g.Match() {
+			location := seek
+			if offset, ok := t.offset(file); ok {
+				location = &tail.SeekInfo{Whence: 0, Offset: offset}
+			}
 			tailer, err := tail.TailFile(file,
 				tail.Config{
 					ReOpen:    true,
 					Follow:    true,
-					Location:  seek,
+					Location:  location,
 					MustExist: true,
 					Poll:      poll,
 					Pipe:      t.Pipe,
@@ -127,6 +140,43 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// offset returns the offset the file was read to when the plugin stopped,
+// the file is read from its start or end as configured if it is shorter,
+// like once it is rotated.
+func (t *Tail) offset(file string) (int64, bool) {
+	if t.store == nil || t.Pipe {
+		return 0, false
+	}
+	value, err := t.store.Get(file)
+	if err != nil || value == nil {
+		return 0, false
+	}
+	offset, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	fi, err := os.Stat(file)
+	if err != nil || fi.Size() < offset {
+		return 0, false
+	}
+	return offset, true
+}
+
+// saveOffset stores the offset the file is read to.
+func (t *Tail) saveOffset(tailer *tail.Tail) {
+	if t.store == nil || t.Pipe {
+		return
+	}
+	offset, err := tailer.Tell()
+	if err != nil {
+		t.acc.AddError(fmt.Errorf("E! Error getting the offset of file %s: %s", tailer.Filename, err))
+		return
+	}
+	if err := t.store.Set(tailer.Filename, []byte(strconv.FormatInt(offset, 10))); err != nil {
+		t.acc.AddError(fmt.Errorf("E! Error storing the offset of file %s: %s", tailer.Filename, err))
+	}
+}
+
 // this is launched as a goroutine to continuously watch a tailed logfile
 // for changes, parse any incoming msgs, and add to the accumulator.
 func (t *Tail) receiver(tailer *tail.Tail) {
@@ -163,6 +213,7 @@ func (t *Tail) Stop() {
 	defer t.Unlock()
 
 	for _, tailer := range t.tailers {
+		t.saveOffset(tailer)
 		err := tailer.Stop()
 		if err != nil {
 			t.acc.AddError(fmt.Errorf("E! Error stopping tail on file %s\n", tailer.Filename))
@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/influxdata/telegraf/internal/store"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -141,3 +142,51 @@ func TestTailDosLineendings(t *testing.T) {
 			"usage_idle": float64(200),
 		})
 }
+
+func TestTailResumesFromOffset(t *testing.T) {
+	if os.Getenv("CIRCLE_PROJECT_REPONAME") != "" {
+		t.Skip("Skipping CI testing due to race conditions")
+	}
+
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	defer tmpfile.Close()
+	_, err = tmpfile.WriteString("cpu,mytag=foo usage_idle=100\n")
+	require.NoError(t, err)
+
+	db, err := store.Open("")
+	require.NoError(t, err)
+	newTail := func() *Tail {
+		tt := NewTail()
+		tt.FromBeginning = true
+		tt.Files = []string{tmpfile.Name()}
+		p, _ := parsers.NewInfluxParser()
+		tt.SetParser(p)
+		tt.SetStore(db.Namespace("inputs.tail"))
+		return tt
+	}
+
+	tt := newTail()
+	acc := testutil.Accumulator{}
+	require.NoError(t, tt.Start(&acc))
+	acc.Wait(1)
+	tt.Stop()
+
+	// the line written while stopped is read, the first is not read again
+	_, err = tmpfile.WriteString("cpu,mytag=bar usage_idle=50\n")
+	require.NoError(t, err)
+	tt = newTail()
+	acc = testutil.Accumulator{}
+	require.NoError(t, tt.Start(&acc))
+	defer tt.Stop()
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{
+			"usage_idle": float64(50),
+		},
+		map[string]string{
+			"mytag": "bar",
+		})
+	assert.Equal(t, uint64(1), acc.NMetrics())
+}
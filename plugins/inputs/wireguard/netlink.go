@@ -0,0 +1,180 @@
+// +build linux
+
+package wireguard
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// generic netlink constants from linux/genetlink.h
+const (
+	genlIDCtrl           = 0x10
+	genlHeaderLen        = 4
+	ctrlCmdGetFamily     = 3
+	ctrlAttrFamilyID     = 1
+	ctrlAttrFamilyName   = 2
+	nlaFNested           = 0x8000
+	nlaTypeMask          = ^uint16(0xc000)
+	nlmsgHeaderLen       = syscall.NLMSG_HDRLEN
+	netlinkReceiveBuffer = 1 << 16
+)
+
+// nativeEndian is the byte order of the netlink messages.
+var nativeEndian binary.ByteOrder
+
+func init() {
+	i := uint16(1)
+	if *(*byte)(unsafe.Pointer(&i)) == 1 {
+		nativeEndian = binary.LittleEndian
+	} else {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// attribute is a netlink attribute.
+type attribute struct {
+	typ  uint16
+	data []byte
+}
+
+// parseAttributes splits b into its netlink attributes, the nested flag is
+// removed from the types.
+func parseAttributes(b []byte) ([]attribute, error) {
+	var attrs []attribute
+	for len(b) >= syscall.SizeofRtAttr {
+		l := int(nativeEndian.Uint16(b[0:2]))
+		if l < syscall.SizeofRtAttr || l > len(b) {
+			return nil, fmt.Errorf("invalid attribute length %d", l)
+		}
+		attrs = append(attrs, attribute{
+			typ:  nativeEndian.Uint16(b[2:4]) & nlaTypeMask,
+			data: b[syscall.SizeofRtAttr:l],
+		})
+		l = align(l)
+		if l > len(b) {
+			break
+		}
+		b = b[l:]
+	}
+	return attrs, nil
+}
+
+// appendAttribute appends the attribute, padded to the netlink alignment.
+func appendAttribute(b []byte, typ uint16, data []byte) []byte {
+	hdr := make([]byte, syscall.SizeofRtAttr)
+	nativeEndian.PutUint16(hdr[0:2], uint16(syscall.SizeofRtAttr+len(data)))
+	nativeEndian.PutUint16(hdr[2:4], typ)
+	b = append(b, hdr...)
+	b = append(b, data...)
+	return append(b, make([]byte, align(len(data))-len(data))...)
+}
+
+func align(l int) int {
+	return (l + syscall.NLA_ALIGNTO - 1) & ^(syscall.NLA_ALIGNTO - 1)
+}
+
+// genl is a generic netlink socket.
+type genl struct {
+	fd  int
+	seq uint32
+}
+
+func dialGenl() (*genl, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_GENERIC)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	return &genl{fd: fd}, nil
+}
+
+func (g *genl) Close() error {
+	return syscall.Close(g.fd)
+}
+
+// family returns the id of the generic netlink family.
+func (g *genl) family(name string) (uint16, error) {
+	attrs := appendAttribute(nil, ctrlAttrFamilyName, append([]byte(name), 0))
+	msgs, err := g.execute(genlIDCtrl, ctrlCmdGetFamily, 0, attrs)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range msgs {
+		attrs, err := parseAttributes(msg)
+		if err != nil {
+			return 0, err
+		}
+		for _, a := range attrs {
+			if a.typ == ctrlAttrFamilyID && len(a.data) >= 2 {
+				return nativeEndian.Uint16(a.data), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("generic netlink family %s not found", name)
+}
+
+// execute sends the request and returns the attributes of the replies,
+// requests with the dump flag are answered with several messages.
+func (g *genl) execute(family uint16, cmd uint8, flags uint16, attrs []byte) ([][]byte, error) {
+	seq := atomic.AddUint32(&g.seq, 1)
+
+	req := make([]byte, nlmsgHeaderLen+genlHeaderLen, nlmsgHeaderLen+genlHeaderLen+len(attrs))
+	nativeEndian.PutUint32(req[0:4], uint32(cap(req)))
+	nativeEndian.PutUint16(req[4:6], family)
+	nativeEndian.PutUint16(req[6:8], flags|syscall.NLM_F_REQUEST|syscall.NLM_F_ACK)
+	nativeEndian.PutUint32(req[8:12], seq)
+	req[nlmsgHeaderLen] = cmd
+	req[nlmsgHeaderLen+1] = 1
+	req = append(req, attrs...)
+
+	if err := syscall.Sendto(g.fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	var replies [][]byte
+	buf := make([]byte, netlinkReceiveBuffer)
+	for {
+		n, _, err := syscall.Recvfrom(g.fd, buf, 0)
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range msgs {
+			if m.Header.Seq != seq {
+				continue
+			}
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return replies, nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return nil, fmt.Errorf("short netlink error message")
+				}
+				// the acknowledgement is an error message without error
+				if errno := int32(nativeEndian.Uint32(m.Data[0:4])); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+				return replies, nil
+			}
+			if len(m.Data) < genlHeaderLen {
+				continue
+			}
+			data := make([]byte, len(m.Data)-genlHeaderLen)
+			copy(data, m.Data[genlHeaderLen:])
+			replies = append(replies, data)
+		}
+	}
+}
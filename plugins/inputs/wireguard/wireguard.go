@@ -0,0 +1,330 @@
+// +build linux
+
+package wireguard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// wireguard generic netlink constants from linux/wireguard.h
+const (
+	wgGenlName      = "wireguard"
+	wgCmdGetDevice  = 0
+	wgDeviceIfindex = 1
+	wgDeviceIfname  = 2
+	wgDevicePubkey  = 4
+	wgDevicePort    = 6
+	wgDeviceFwmark  = 7
+	wgDevicePeers   = 8
+
+	wgPeerPubkey      = 1
+	wgPeerEndpoint    = 4
+	wgPeerKeepalive   = 5
+	wgPeerHandshake   = 6
+	wgPeerRxBytes     = 7
+	wgPeerTxBytes     = 8
+	wgPeerAllowedIPs  = 9
+	wgPeerProtocolVer = 10
+)
+
+type device struct {
+	name       string
+	publicKey  []byte
+	listenPort uint16
+	fwmark     uint32
+	peers      []*peer
+}
+
+type peer struct {
+	publicKey       []byte
+	endpoint        string
+	keepalive       uint16
+	lastHandshake   time.Time
+	rxBytes         uint64
+	txBytes         uint64
+	allowedIPs      int
+	protocolVersion uint32
+}
+
+// client lists the wireguard devices.
+type client interface {
+	Devices(names []string) ([]*device, error)
+	Close() error
+}
+
+type Wireguard struct {
+	Devices []string `toml:"devices"`
+
+	client client
+	dial   func() (client, error)
+	now    func() time.Time
+}
+
+var sampleConfig = `
+  ## Optional list of wireguard interfaces to gather, by default all the
+  ## wireguard interfaces are gathered.
+  # devices = ["wg0"]
+`
+
+func (wg *Wireguard) SampleConfig() string {
+	return sampleConfig
+}
+
+func (wg *Wireguard) Description() string {
+	return "Collect the state of the WireGuard interfaces and their peers"
+}
+
+func (wg *Wireguard) Gather(acc telegraf.Accumulator) error {
+	if wg.client == nil {
+		c, err := wg.dial()
+		if err != nil {
+			return err
+		}
+		wg.client = c
+	}
+
+	devices, err := wg.client.Devices(wg.Devices)
+	if err != nil {
+		// the socket is opened again on the next gather
+		wg.client.Close()
+		wg.client = nil
+		return err
+	}
+
+	now := wg.now()
+	for _, dev := range devices {
+		acc.AddFields("wireguard_device",
+			map[string]interface{}{
+				"listen_port":   int(dev.listenPort),
+				"firewall_mark": int64(dev.fwmark),
+				"peers":         len(dev.peers),
+			},
+			map[string]string{
+				"name":       dev.name,
+				"public_key": base64.StdEncoding.EncodeToString(dev.publicKey),
+			})
+
+		for _, p := range dev.peers {
+			fields := map[string]interface{}{
+				"persistent_keepalive_interval_s": int(p.keepalive),
+				"rx_bytes":                        int64(p.rxBytes),
+				"tx_bytes":                        int64(p.txBytes),
+				"allowed_ips":                     p.allowedIPs,
+				"protocol_version":                int(p.protocolVersion),
+			}
+			if p.endpoint != "" {
+				fields["endpoint"] = p.endpoint
+			}
+			// peers without handshake have a zero handshake time
+			if !p.lastHandshake.IsZero() {
+				fields["last_handshake_time_ns"] = p.lastHandshake.UnixNano()
+				fields["last_handshake_age_s"] = int64(now.Sub(p.lastHandshake) / time.Second)
+			}
+
+			acc.AddFields("wireguard_peer", fields, map[string]string{
+				"device":     dev.name,
+				"public_key": base64.StdEncoding.EncodeToString(p.publicKey),
+			})
+		}
+	}
+	return nil
+}
+
+// netlinkClient queries the wireguard devices with generic netlink.
+type netlinkClient struct {
+	genl   *genl
+	family uint16
+}
+
+func dial() (client, error) {
+	g, err := dialGenl()
+	if err != nil {
+		return nil, err
+	}
+	family, err := g.family(wgGenlName)
+	if err != nil {
+		g.Close()
+		return nil, fmt.Errorf("resolving the %s netlink family: %s, verify that the wireguard module is loaded", wgGenlName, err)
+	}
+	return &netlinkClient{genl: g, family: family}, nil
+}
+
+func (c *netlinkClient) Close() error {
+	return c.genl.Close()
+}
+
+func (c *netlinkClient) Devices(names []string) ([]*device, error) {
+	all := len(names) == 0
+	if all {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			return nil, err
+		}
+		for _, iface := range ifaces {
+			names = append(names, iface.Name)
+		}
+	}
+
+	var devices []*device
+	for _, name := range names {
+		attrs := appendAttribute(nil, wgDeviceIfname, append([]byte(name), 0))
+		msgs, err := c.genl.execute(c.family, wgCmdGetDevice, syscall.NLM_F_DUMP, attrs)
+		// the other interfaces are not supported by the wireguard family
+		if all && (err == syscall.EOPNOTSUPP || err == syscall.ENODEV) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting device %s: %s", name, err)
+		}
+		dev, err := parseDevice(msgs)
+		if err != nil {
+			return nil, fmt.Errorf("parsing device %s: %s", name, err)
+		}
+		devices = append(devices, dev)
+	}
+	return devices, nil
+}
+
+// parseDevice parses the replies of a device dump. Devices with many peers
+// span several messages, each repeating the device attributes, a peer with
+// many allowed IPs is continued in the next message.
+func parseDevice(msgs [][]byte) (*device, error) {
+	dev := &device{}
+	peers := make(map[string]*peer)
+	for _, msg := range msgs {
+		attrs, err := parseAttributes(msg)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attrs {
+			switch a.typ {
+			case wgDeviceIfname:
+				dev.name = cString(a.data)
+			case wgDevicePubkey:
+				dev.publicKey = a.data
+			case wgDevicePort:
+				if len(a.data) >= 2 {
+					dev.listenPort = nativeEndian.Uint16(a.data)
+				}
+			case wgDeviceFwmark:
+				if len(a.data) >= 4 {
+					dev.fwmark = nativeEndian.Uint32(a.data)
+				}
+			case wgDevicePeers:
+				entries, err := parseAttributes(a.data)
+				if err != nil {
+					return nil, err
+				}
+				for _, e := range entries {
+					p, err := parsePeer(e.data)
+					if err != nil {
+						return nil, err
+					}
+					key := string(p.publicKey)
+					if prev, ok := peers[key]; ok {
+						prev.allowedIPs += p.allowedIPs
+						continue
+					}
+					peers[key] = p
+					dev.peers = append(dev.peers, p)
+				}
+			}
+		}
+	}
+	return dev, nil
+}
+
+func parsePeer(b []byte) (*peer, error) {
+	attrs, err := parseAttributes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &peer{}
+	for _, a := range attrs {
+		switch a.typ {
+		case wgPeerPubkey:
+			p.publicKey = a.data
+		case wgPeerEndpoint:
+			p.endpoint = parseEndpoint(a.data)
+		case wgPeerKeepalive:
+			if len(a.data) >= 2 {
+				p.keepalive = nativeEndian.Uint16(a.data)
+			}
+		case wgPeerHandshake:
+			// struct __kernel_timespec
+			if len(a.data) >= 16 {
+				sec := int64(nativeEndian.Uint64(a.data[0:8]))
+				nsec := int64(nativeEndian.Uint64(a.data[8:16]))
+				if sec != 0 || nsec != 0 {
+					p.lastHandshake = time.Unix(sec, nsec)
+				}
+			}
+		case wgPeerRxBytes:
+			if len(a.data) >= 8 {
+				p.rxBytes = nativeEndian.Uint64(a.data)
+			}
+		case wgPeerTxBytes:
+			if len(a.data) >= 8 {
+				p.txBytes = nativeEndian.Uint64(a.data)
+			}
+		case wgPeerAllowedIPs:
+			ips, err := parseAttributes(a.data)
+			if err != nil {
+				return nil, err
+			}
+			p.allowedIPs = len(ips)
+		case wgPeerProtocolVer:
+			if len(a.data) >= 4 {
+				p.protocolVersion = nativeEndian.Uint32(a.data)
+			}
+		}
+	}
+	return p, nil
+}
+
+// parseEndpoint formats a struct sockaddr_in or sockaddr_in6, the port is
+// in network byte order.
+func parseEndpoint(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	port := strconv.Itoa(int(b[2])<<8 | int(b[3]))
+	switch nativeEndian.Uint16(b[0:2]) {
+	case syscall.AF_INET:
+		if len(b) >= 8 {
+			return net.JoinHostPort(net.IP(b[4:8]).String(), port)
+		}
+	case syscall.AF_INET6:
+		if len(b) >= 24 {
+			return net.JoinHostPort(net.IP(b[8:24]).String(), port)
+		}
+	}
+	return ""
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+func init() {
+	inputs.Add("wireguard", func() telegraf.Input {
+		return &Wireguard{
+			dial: dial,
+			now:  time.Now,
+		}
+	})
+}
@@ -0,0 +1,226 @@
+// +build linux
+
+package wireguard
+
+import (
+	"bytes"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	nativeEndian.PutUint16(b, v)
+	return b
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	nativeEndian.PutUint32(b, v)
+	return b
+}
+
+func u64(v uint64) []byte {
+	b := make([]byte, 8)
+	nativeEndian.PutUint64(b, v)
+	return b
+}
+
+func sockaddr4(ip []byte, port uint16) []byte {
+	b := append(u16(syscall.AF_INET), byte(port>>8), byte(port))
+	return append(append(b, ip...), make([]byte, 8)...)
+}
+
+func sockaddr6(ip []byte, port uint16) []byte {
+	b := append(u16(syscall.AF_INET6), byte(port>>8), byte(port))
+	b = append(b, make([]byte, 4)...)
+	return append(append(b, ip...), make([]byte, 4)...)
+}
+
+func allowedIPs(n int) []byte {
+	var ips []byte
+	for i := 0; i < n; i++ {
+		var ip []byte
+		ip = appendAttribute(ip, 1, u16(syscall.AF_INET))
+		ip = appendAttribute(ip, 2, []byte{10, 0, 0, byte(i)})
+		ip = appendAttribute(ip, 3, []byte{32})
+		ips = appendAttribute(ips, uint16(i)|nlaFNested, ip)
+	}
+	return ips
+}
+
+var (
+	devKey   = bytes.Repeat([]byte{1}, 32)
+	peerKey1 = bytes.Repeat([]byte{2}, 32)
+	peerKey2 = bytes.Repeat([]byte{3}, 32)
+)
+
+// deviceMessages returns a device dump split in two messages, the first
+// peer is continued in the second message.
+func deviceMessages() [][]byte {
+	header := func() []byte {
+		var b []byte
+		b = appendAttribute(b, wgDeviceIfindex, u32(5))
+		b = appendAttribute(b, wgDeviceIfname, []byte("wg0\x00"))
+		b = appendAttribute(b, wgDevicePubkey, devKey)
+		b = appendAttribute(b, wgDevicePort, u16(51820))
+		b = appendAttribute(b, wgDeviceFwmark, u32(0))
+		return b
+	}
+
+	var p1 []byte
+	p1 = appendAttribute(p1, wgPeerPubkey, peerKey1)
+	p1 = appendAttribute(p1, wgPeerEndpoint, sockaddr4([]byte{192, 0, 2, 1}, 51820))
+	p1 = appendAttribute(p1, wgPeerKeepalive, u16(25))
+	p1 = appendAttribute(p1, wgPeerHandshake, append(u64(1500000000), u64(0)...))
+	p1 = appendAttribute(p1, wgPeerRxBytes, u64(1024))
+	p1 = appendAttribute(p1, wgPeerTxBytes, u64(2048))
+	p1 = appendAttribute(p1, wgPeerAllowedIPs|nlaFNested, allowedIPs(2))
+	p1 = appendAttribute(p1, wgPeerProtocolVer, u32(1))
+	first := appendAttribute(header(), wgDevicePeers|nlaFNested, appendAttribute(nil, 0|nlaFNested, p1))
+
+	var p1cont []byte
+	p1cont = appendAttribute(p1cont, wgPeerPubkey, peerKey1)
+	p1cont = appendAttribute(p1cont, wgPeerAllowedIPs|nlaFNested, allowedIPs(1))
+
+	var p2 []byte
+	p2 = appendAttribute(p2, wgPeerPubkey, peerKey2)
+	p2 = appendAttribute(p2, wgPeerEndpoint, sockaddr6(net6(), 443))
+	p2 = appendAttribute(p2, wgPeerHandshake, append(u64(0), u64(0)...))
+	p2 = appendAttribute(p2, wgPeerProtocolVer, u32(1))
+
+	var peers []byte
+	peers = appendAttribute(peers, 0|nlaFNested, p1cont)
+	peers = appendAttribute(peers, 1|nlaFNested, p2)
+	second := appendAttribute(header(), wgDevicePeers|nlaFNested, peers)
+
+	return [][]byte{first, second}
+}
+
+func net6() []byte {
+	return []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+}
+
+func TestParseDevice(t *testing.T) {
+	dev, err := parseDevice(deviceMessages())
+	require.NoError(t, err)
+
+	assert.Equal(t, "wg0", dev.name)
+	assert.Equal(t, devKey, dev.publicKey)
+	assert.Equal(t, uint16(51820), dev.listenPort)
+	require.Len(t, dev.peers, 2)
+
+	p := dev.peers[0]
+	assert.Equal(t, peerKey1, p.publicKey)
+	assert.Equal(t, "192.0.2.1:51820", p.endpoint)
+	assert.Equal(t, uint16(25), p.keepalive)
+	assert.Equal(t, time.Unix(1500000000, 0), p.lastHandshake)
+	assert.Equal(t, uint64(1024), p.rxBytes)
+	assert.Equal(t, uint64(2048), p.txBytes)
+	assert.Equal(t, 3, p.allowedIPs)
+	assert.Equal(t, uint32(1), p.protocolVersion)
+
+	p = dev.peers[1]
+	assert.Equal(t, "[2001:db8::1]:443", p.endpoint)
+	assert.True(t, p.lastHandshake.IsZero())
+	assert.Equal(t, 0, p.allowedIPs)
+}
+
+func TestParseAttributesInvalid(t *testing.T) {
+	// the length exceeds the message
+	b := appendAttribute(nil, 1, u32(1))
+	nativeEndian.PutUint16(b[0:2], 64)
+	_, err := parseAttributes(b)
+	assert.Error(t, err)
+}
+
+type fakeClient struct {
+	err    error
+	closed bool
+}
+
+func (f *fakeClient) Devices(names []string) ([]*device, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	dev, err := parseDevice(deviceMessages())
+	return []*device{dev}, err
+}
+
+func (f *fakeClient) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestGather(t *testing.T) {
+	wg := &Wireguard{
+		dial: func() (client, error) { return &fakeClient{}, nil },
+		now:  func() time.Time { return time.Unix(1500000090, 0) },
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(wg.Gather))
+
+	acc.AssertContainsTaggedFields(t, "wireguard_device",
+		map[string]interface{}{
+			"listen_port":   51820,
+			"firewall_mark": int64(0),
+			"peers":         2,
+		},
+		map[string]string{
+			"name":       "wg0",
+			"public_key": "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=",
+		})
+	acc.AssertContainsTaggedFields(t, "wireguard_peer",
+		map[string]interface{}{
+			"endpoint":                        "192.0.2.1:51820",
+			"persistent_keepalive_interval_s": 25,
+			"last_handshake_time_ns":          int64(1500000000000000000),
+			"last_handshake_age_s":            int64(90),
+			"rx_bytes":                        int64(1024),
+			"tx_bytes":                        int64(2048),
+			"allowed_ips":                     3,
+			"protocol_version":                1,
+		},
+		map[string]string{
+			"device":     "wg0",
+			"public_key": "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI=",
+		})
+	acc.AssertContainsTaggedFields(t, "wireguard_peer",
+		map[string]interface{}{
+			"endpoint":                        "[2001:db8::1]:443",
+			"persistent_keepalive_interval_s": 0,
+			"rx_bytes":                        int64(0),
+			"tx_bytes":                        int64(0),
+			"allowed_ips":                     0,
+			"protocol_version":                1,
+		},
+		map[string]string{
+			"device":     "wg0",
+			"public_key": "AwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwM=",
+		})
+}
+
+func TestGatherReconnect(t *testing.T) {
+	fake := &fakeClient{err: fmt.Errorf("recvfrom: no buffer space available")}
+	dials := 0
+	wg := &Wireguard{
+		dial: func() (client, error) {
+			dials++
+			return fake, nil
+		},
+		now: time.Now,
+	}
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(wg.Gather))
+	assert.True(t, fake.closed)
+	require.Error(t, acc.GatherError(wg.Gather))
+	assert.Equal(t, 2, dials)
+}
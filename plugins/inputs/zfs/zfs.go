@@ -1,14 +1,27 @@
 package zfs
 
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
 type Sysctl func(metric string) ([]string, error)
 type Zpool func() ([]string, error)
+type Zdataset func() ([]string, error)
 
 type Zfs struct {
-	KstatPath    string
-	KstatMetrics []string
-	PoolMetrics  bool
-	sysctl       Sysctl
-	zpool        Zpool
+	KstatPath      string
+	KstatMetrics   []string
+	PoolMetrics    bool
+	DatasetMetrics bool
+	sysctl         Sysctl
+	zpool          Zpool
+	zdataset       Zdataset
 }
 
 var sampleConfig = `
@@ -24,6 +37,8 @@ var sampleConfig = `
   #   "dmu_tx", "fm", "vdev_mirror_stats", "zfetchstats", "zil"]
   ## By default, don't gather zpool stats
   # poolMetrics = false
+  ## By default, don't gather dataset stats
+  # datasetMetrics = false
 `
 
 func (z *Zfs) SampleConfig() string {
@@ -33,3 +48,54 @@ func (z *Zfs) SampleConfig() string {
 func (z *Zfs) Description() string {
 	return "Read metrics of ZFS from arcstats, zfetchstats, vdev_cache_stats, and pools"
 }
+
+// gatherDatasetStats reports the space used by each dataset as listed by
+// zfs list.
+func (z *Zfs) gatherDatasetStats(acc telegraf.Accumulator) error {
+	lines, err := z.zdataset()
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		col := strings.Split(line, "\t")
+		if len(col) != len(datasetProperties) {
+			continue
+		}
+
+		tags := map[string]string{"dataset": col[0]}
+		fields := map[string]interface{}{}
+		for i, key := range datasetProperties[1:] {
+			// volumes and snapshots report - for some properties
+			value, err := strconv.ParseInt(col[i+1], 10, 64)
+			if err != nil {
+				continue
+			}
+			fields[key] = value
+		}
+		acc.AddFields("zfs_dataset", fields, tags)
+	}
+	return nil
+}
+
+var datasetProperties = []string{"name", "avail", "used", "usedsnap", "usedds"}
+
+func run(command string, args ...string) ([]string, error) {
+	cmd := exec.Command(command, args...)
+	var outbuf, errbuf bytes.Buffer
+	cmd.Stdout = &outbuf
+	cmd.Stderr = &errbuf
+	err := cmd.Run()
+
+	stdout := strings.TrimSpace(outbuf.String())
+	stderr := strings.TrimSpace(errbuf.String())
+
+	if _, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%s error: %s", command, stderr)
+	}
+	return strings.Split(stdout, "\n"), nil
+}
+
+func zdataset() ([]string, error) {
+	return run("zfs", []string{"list", "-Hp", "-o", strings.Join(datasetProperties, ",")}...)
+}
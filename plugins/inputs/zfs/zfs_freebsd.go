@@ -3,9 +3,7 @@
 package zfs
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 
@@ -96,6 +94,12 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 	}
 	tags["pools"] = poolNames
 
+	if z.DatasetMetrics {
+		if err := z.gatherDatasetStats(acc); err != nil {
+			return err
+		}
+	}
+
 	fields := make(map[string]interface{})
 	for _, metric := range kstatMetrics {
 		stdout, err := z.sysctl(metric)
@@ -113,22 +117,6 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
-func run(command string, args ...string) ([]string, error) {
-	cmd := exec.Command(command, args...)
-	var outbuf, errbuf bytes.Buffer
-	cmd.Stdout = &outbuf
-	cmd.Stderr = &errbuf
-	err := cmd.Run()
-
-	stdout := strings.TrimSpace(outbuf.String())
-	stderr := strings.TrimSpace(errbuf.String())
-
-	if _, ok := err.(*exec.ExitError); ok {
-		return nil, fmt.Errorf("%s error: %s", command, stderr)
-	}
-	return strings.Split(stdout, "\n"), nil
-}
-
 func zpool() ([]string, error) {
 	return run("zpool", []string{"list", "-Hp"}...)
 }
@@ -140,8 +128,9 @@ func sysctl(metric string) ([]string, error) {
 func init() {
 	inputs.Add("zfs", func() telegraf.Input {
 		return &Zfs{
-			sysctl: sysctl,
-			zpool:  zpool,
+			sysctl:   sysctl,
+			zpool:    zpool,
+			zdataset: zdataset,
 		}
 	})
 }
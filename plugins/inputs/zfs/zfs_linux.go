@@ -44,7 +44,67 @@ func getTags(pools []poolInfo) map[string]string {
 	return map[string]string{"pools": poolNames}
 }
 
-func gatherPoolStats(pool poolInfo, acc telegraf.Accumulator) error {
+// poolProperties are the properties of the pools listed by zpool list.
+var poolProperties = []string{"name", "health", "size", "allocated", "free", "fragmentation", "capacity", "dedupratio"}
+
+type poolProps struct {
+	health string
+	fields map[string]interface{}
+}
+
+// getPoolProps returns the health, capacity and fragmentation of the
+// pools, in the order listed.
+func (z *Zfs) getPoolProps() ([]string, map[string]poolProps, error) {
+	lines, err := z.zpool()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	props := make(map[string]poolProps)
+	for _, line := range lines {
+		col := strings.Split(line, "\t")
+		if len(col) != len(poolProperties) {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		for i, key := range poolProperties[2:] {
+			// unavailable pools and read only pools report - for some
+			// properties
+			value := strings.TrimRight(col[i+2], "%x")
+			if key == "dedupratio" {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					fields[key] = v
+				}
+				continue
+			}
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[key] = v
+			}
+		}
+		names = append(names, col[0])
+		props[col[0]] = poolProps{health: col[1], fields: fields}
+	}
+	return names, props, nil
+}
+
+func gatherPoolStats(pool poolInfo, props *poolProps, acc telegraf.Accumulator) error {
+	tag := map[string]string{"pool": pool.name}
+	fields := make(map[string]interface{})
+	if props != nil {
+		tag["health"] = props.health
+		for k, v := range props.fields {
+			fields[k] = v
+		}
+	}
+
+	// the io kstat was removed with ZFS on Linux 2.0
+	if pool.ioFilename == "" {
+		acc.AddFields("zfs_pool", fields, tag)
+		return nil
+	}
+
 	lines, err := internal.ReadLines(pool.ioFilename)
 	if err != nil {
 		return err
@@ -63,8 +123,6 @@ func gatherPoolStats(pool poolInfo, acc telegraf.Accumulator) error {
 		return fmt.Errorf("Key and value count don't match Keys:%v Values:%v", keys, values)
 	}
 
-	tag := map[string]string{"pool": pool.name}
-	fields := make(map[string]interface{})
 	for i := 0; i < keyCount; i++ {
 		value, err := strconv.ParseInt(values[i], 10, 64)
 		if err != nil {
@@ -96,12 +154,39 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 	tags := getTags(pools)
 
 	if z.PoolMetrics {
+		var names []string
+		var props map[string]poolProps
+		if z.zpool != nil {
+			var err error
+			names, props, err = z.getPoolProps()
+			if err != nil {
+				acc.AddError(err)
+			}
+		}
+
 		for _, pool := range pools {
-			err := gatherPoolStats(pool, acc)
+			var p *poolProps
+			if pp, ok := props[pool.name]; ok {
+				p = &pp
+				delete(props, pool.name)
+			}
+			err := gatherPoolStats(pool, p, acc)
 			if err != nil {
 				return err
 			}
 		}
+		// pools without io kstat
+		for _, name := range names {
+			if pp, ok := props[name]; ok {
+				gatherPoolStats(poolInfo{name: name}, &pp, acc)
+			}
+		}
+	}
+
+	if z.DatasetMetrics && z.zdataset != nil {
+		if err := z.gatherDatasetStats(acc); err != nil {
+			acc.AddError(err)
+		}
 	}
 
 	fields := make(map[string]interface{})
@@ -131,8 +216,15 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+func zpool() ([]string, error) {
+	return run("zpool", []string{"list", "-Hp", "-o", strings.Join(poolProperties, ",")}...)
+}
+
 func init() {
 	inputs.Add("zfs", func() telegraf.Input {
-		return &Zfs{}
+		return &Zfs{
+			zpool:    zpool,
+			zdataset: zdataset,
+		}
 	})
 }
@@ -284,6 +284,81 @@ func TestZfsPoolMetrics(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// $ zpool list -Hp -o name,health,size,allocated,free,fragmentation,capacity,dedupratio
+var zpool_output = []string{
+	"HOME	ONLINE	3985729650688	1693170540544	2292559110144	11	42	1.00",
+	"tank	DEGRADED	8933531975680	1126164848640	7807367127040	8	12	1.83",
+	"temp	UNAVAIL	-	-	-	-	-	-",
+}
+
+func mock_zpool() ([]string, error) {
+	return zpool_output, nil
+}
+
+// $ zfs list -Hp -o name,avail,used,usedsnap,usedds
+var zdataset_output = []string{
+	"HOME	2161346437120	1693170540544	0	98304",
+	"HOME/data	2161346437120	1690812596224	24386142208	1666426454016",
+}
+
+func mock_zdataset() ([]string, error) {
+	return zdataset_output, nil
+}
+
+func TestZfsPoolProperties(t *testing.T) {
+	err := os.MkdirAll(testKstatPath+"/HOME", 0755)
+	require.NoError(t, err)
+	defer os.RemoveAll(os.TempDir() + "/telegraf")
+
+	err = ioutil.WriteFile(testKstatPath+"/HOME/io", []byte(pool_ioContents), 0644)
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+
+	z := &Zfs{
+		KstatPath:      testKstatPath,
+		KstatMetrics:   []string{"arcstats"},
+		PoolMetrics:    true,
+		DatasetMetrics: true,
+		zpool:          mock_zpool,
+		zdataset:       mock_zdataset,
+	}
+	err = z.Gather(&acc)
+	require.NoError(t, err)
+
+	// the kstat io stats are merged with the pool properties
+	poolMetrics := getPoolMetrics()
+	poolMetrics["size"] = int64(3985729650688)
+	poolMetrics["allocated"] = int64(1693170540544)
+	poolMetrics["free"] = int64(2292559110144)
+	poolMetrics["fragmentation"] = int64(11)
+	poolMetrics["capacity"] = int64(42)
+	poolMetrics["dedupratio"] = float64(1)
+	acc.AssertContainsTaggedFields(t, "zfs_pool", poolMetrics,
+		map[string]string{"pool": "HOME", "health": "ONLINE"})
+
+	// pools without io kstat only report their properties
+	acc.AssertContainsTaggedFields(t, "zfs_pool",
+		map[string]interface{}{
+			"size":          int64(8933531975680),
+			"allocated":     int64(1126164848640),
+			"free":          int64(7807367127040),
+			"fragmentation": int64(8),
+			"capacity":      int64(12),
+			"dedupratio":    float64(1.83),
+		},
+		map[string]string{"pool": "tank", "health": "DEGRADED"})
+
+	acc.AssertContainsTaggedFields(t, "zfs_dataset",
+		map[string]interface{}{
+			"avail":    int64(2161346437120),
+			"used":     int64(1690812596224),
+			"usedsnap": int64(24386142208),
+			"usedds":   int64(1666426454016),
+		},
+		map[string]string{"dataset": "HOME/data"})
+}
+
 func TestZfsGeneratesMetrics(t *testing.T) {
 	err := os.MkdirAll(testKstatPath, 0755)
 	require.NoError(t, err)
@@ -0,0 +1,104 @@
+package execd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const sampleConfig = `
+  ## Program to run as a daemon, with its arguments.
+  command = ["/usr/local/bin/telegraf-writer", "--flag", "value"]
+
+  ## Environment variables added to those of telegraf.
+  # environment = ["LANG=C"]
+
+  ## Delay before the program is started again when it exits.
+  restart_delay = "10s"
+
+  ## Data format of the metrics written to the stdin of the program.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+type Execd struct {
+	Command      []string
+	Environment  []string
+	RestartDelay internal.Duration
+
+	process    *process.Process
+	serializer serializers.Serializer
+	log        telegraf.Logger
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run a program as a daemon and write the metrics to its stdin"
+}
+
+func (e *Execd) SetSerializer(serializer serializers.Serializer) {
+	e.serializer = serializer
+}
+
+func (e *Execd) SetLogger(logger telegraf.Logger) {
+	e.log = logger
+}
+
+func (e *Execd) Connect() error {
+	e.process = &process.Process{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		ReadStdout:   e.logStdout,
+		Log:          e.log,
+	}
+	return e.process.Start()
+}
+
+func (e *Execd) Close() error {
+	e.process.Stop()
+	return nil
+}
+
+// Write writes the metrics to the stdin of the program, they are written
+// again on the next flush if the program is not running.
+func (e *Execd) Write(metrics []telegraf.Metric) error {
+	for _, metric := range metrics {
+		b, err := e.serializer.Serialize(metric)
+		if err != nil {
+			return fmt.Errorf("failed to serialize metric: %s", err)
+		}
+		if _, err := e.process.Write(b); err != nil {
+			return fmt.Errorf("writing to %s: %s", e.Command[0], err)
+		}
+	}
+	return nil
+}
+
+// logStdout logs the lines the program writes to stdout.
+func (e *Execd) logStdout(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.log.Debugf("stdout: %s", scanner.Text())
+	}
+}
+
+func init() {
+	outputs.Add("execd", func() telegraf.Output {
+		return &Execd{
+			RestartDelay: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
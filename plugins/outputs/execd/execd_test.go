@@ -0,0 +1,72 @@
+// +build !windows
+
+package execd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecdWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "execd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	e := &Execd{
+		Command:      []string{"sh", "-c", "cat > " + out},
+		RestartDelay: internal.Duration{Duration: time.Second},
+		log:          testutil.Logger{Name: "outputs.execd"},
+	}
+	e.SetSerializer(serializer)
+	require.NoError(t, e.Connect())
+
+	m := testutil.TestMetric(42.0, "cpu")
+	require.NoError(t, e.Write([]telegraf.Metric{m, m}))
+	// the program exits once its stdin is closed
+	require.NoError(t, e.Close())
+
+	b, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	line, err := serializer.Serialize(m)
+	require.NoError(t, err)
+	assert.Equal(t, string(line)+string(line), string(b))
+}
+
+func TestExecdWriteNotRunning(t *testing.T) {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	e := &Execd{
+		Command:      []string{"true"},
+		RestartDelay: internal.Duration{Duration: time.Hour},
+		log:          testutil.Logger{Name: "outputs.execd"},
+	}
+	e.SetSerializer(serializer)
+	require.NoError(t, e.Connect())
+	defer e.Close()
+
+	m := testutil.TestMetric(42.0, "cpu")
+	// writing may fail with a broken pipe until the exit is noticed
+	for i := 0; i < 100; i++ {
+		err = e.Write([]telegraf.Metric{m})
+		if err != nil && strings.HasSuffix(err.Error(), process.ErrNotRunning.Error()) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.EqualError(t, err, "writing to true: the process is not running")
+}
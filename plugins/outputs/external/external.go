@@ -0,0 +1,114 @@
+package external
+
+import (
+	"errors"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	ext "github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"golang.org/x/net/context"
+)
+
+const sampleConfig = `
+  ## Plugin program to run, with its arguments.
+  command = ["/usr/local/bin/telegraf-acme-output"]
+
+  ## Environment variables added to those of telegraf.
+  # environment = ["LANG=C"]
+
+  ## TOML configuration of the plugin.
+  config = '''
+    url = "https://metrics.example.com"
+  '''
+
+  ## Delay before the program is started again when it exits.
+  # restart_delay = "10s"
+
+  ## Timeout of each write of the plugin.
+  # timeout = "5s"
+`
+
+type External struct {
+	Command      []string
+	Environment  []string
+	Config       string
+	RestartDelay internal.Duration
+	Timeout      internal.Duration
+
+	client *ext.Client
+	log    telegraf.Logger
+}
+
+func (e *External) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *External) Description() string {
+	return "Run an output plugin as a separate program over gRPC"
+}
+
+func (e *External) SetLogger(logger telegraf.Logger) {
+	e.log = logger
+}
+
+// Connect starts the program and connects the plugin.
+func (e *External) Connect() error {
+	e.client = &ext.Client{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		Kind:         ext.KindOutput,
+		Config:       e.Config,
+		Log:          e.log,
+	}
+	if err := e.client.Start(); err != nil {
+		return err
+	}
+	return e.call(func(ctx context.Context, plugin ext.PluginClient) error {
+		_, err := plugin.Connect(ctx, &ext.Empty{})
+		return err
+	})
+}
+
+// Close closes the plugin and stops the program.
+func (e *External) Close() error {
+	err := e.call(func(ctx context.Context, plugin ext.PluginClient) error {
+		_, err := plugin.Close(ctx, &ext.Empty{})
+		return err
+	})
+	e.client.Stop()
+	return err
+}
+
+// Write writes the metrics of each flush with the plugin, they are written
+// again on the next flush if it fails.
+func (e *External) Write(metrics []telegraf.Metric) error {
+	return e.call(func(ctx context.Context, plugin ext.PluginClient) error {
+		_, err := plugin.Write(ctx, &ext.Metrics{Metrics: ext.FromMetrics(metrics)})
+		return err
+	})
+}
+
+func (e *External) call(f func(ctx context.Context, plugin ext.PluginClient) error) error {
+	plugin, err := e.client.Plugin()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout.Duration)
+	defer cancel()
+	if err := f(ctx, plugin); err != nil {
+		return errors.New(ext.ErrorMessage(err))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("external", func() telegraf.Output {
+		return &External{
+			RestartDelay: internal.Duration{Duration: 10 * time.Second},
+			Timeout:      internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package external
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	ext "github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary serves the test output when it is run by the plugin.
+func TestMain(m *testing.M) {
+	if os.Getenv(ext.MagicCookieKey) == ext.MagicCookieValue {
+		ext.Serve(&testOutput{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testOutput fails to write more than Max metrics.
+type testOutput struct {
+	Max int
+}
+
+func (o *testOutput) SampleConfig() string { return "" }
+func (o *testOutput) Description() string  { return "" }
+func (o *testOutput) Connect() error       { return nil }
+func (o *testOutput) Close() error         { return nil }
+
+func (o *testOutput) Write(metrics []telegraf.Metric) error {
+	if len(metrics) > o.Max {
+		return fmt.Errorf("cannot write %d metrics", len(metrics))
+	}
+	return nil
+}
+
+func TestExternalWrite(t *testing.T) {
+	e := &External{
+		Command:      []string{os.Args[0]},
+		Config:       `max = 1`,
+		RestartDelay: internal.Duration{Duration: time.Second},
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		log:          testutil.Logger{Name: "outputs.external"},
+	}
+	require.NoError(t, e.Connect())
+
+	m := testutil.TestMetric(42.0, "cpu")
+	assert.NoError(t, e.Write([]telegraf.Metric{m}))
+	assert.EqualError(t, e.Write([]telegraf.Metric{m, m}), "cannot write 2 metrics")
+	assert.NoError(t, e.Close())
+}
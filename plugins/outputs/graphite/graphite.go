@@ -7,10 +7,13 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/proxy"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
@@ -23,6 +26,12 @@ type Graphite struct {
 	Timeout  int
 	conns    []net.Conn
 
+	// DNSRefreshInterval is the interval to connect again to the servers,
+	// resolving their hosts and SRV records again.
+	DNSRefreshInterval internal.Duration `toml:"dns_refresh_interval"`
+	// connected is the time of the last connection to the servers.
+	connected time.Time
+
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
 	// Path to host cert file
@@ -32,6 +41,8 @@ type Graphite struct {
 	// Skip SSL verification
 	InsecureSkipVerify bool
 
+	proxy.Socks5
+
 	// tls config
 	tlsConfig *tls.Config
 }
@@ -40,7 +51,9 @@ var sampleConfig = `
   ## TCP endpoint for your graphite instance.
   ## If multiple endpoints are configured, output will be load balanced.
   ## Only one of the endpoints will be written to with each iteration.
+  ## The endpoints of a "srv://" server are the targets of its SRV records.
   servers = ["localhost:2003"]
+  # servers = ["srv://_carbon._tcp.example.com"]
   ## Prefix metrics name
   prefix = ""
   ## Graphite output template
@@ -49,12 +62,22 @@ var sampleConfig = `
   ## timeout in seconds for the write connection to graphite
   timeout = 2
 
+  ## Interval to connect again to the servers, resolving their hosts and SRV
+  ## records again for the DNS failover of the servers, 0 keeps the
+  ## connections.
+  # dns_refresh_interval = "0s"
+
   ## Optional SSL Config
   # ssl_ca = "/etc/telegraf/ca.pem"
   # ssl_cert = "/etc/telegraf/cert.pem"
   # ssl_key = "/etc/telegraf/key.pem"
   ## Use SSL but skip chain & host verification
   # insecure_skip_verify = false
+
+  ## Optional SOCKS5 proxy
+  # socks5_address = "socks.example.com:1080"
+  # socks5_username = "telegraf"
+  # socks5_password = "secret"
 `
 
 func (g *Graphite) Connect() error {
@@ -74,16 +97,20 @@ func (g *Graphite) Connect() error {
 		return err
 	}
 
+	// Dialer with timeout, through the SOCKS5 proxy if set
+	timeout := time.Duration(g.Timeout) * time.Second
+	d, err := g.Socks5.Dialer(timeout)
+	if err != nil {
+		return err
+	}
+
 	// Get Connections
 	var conns []net.Conn
-	for _, server := range g.Servers {
-		// Dialer with timeout
-		d := net.Dialer{Timeout: time.Duration(g.Timeout) * time.Second}
-
+	for _, server := range g.addresses() {
 		// Get secure connection if tls config is set
 		var conn net.Conn
 		if g.tlsConfig != nil {
-			conn, err = tls.DialWithDialer(&d, "tcp", server, g.tlsConfig)
+			conn, err = proxy.DialTLS(d, "tcp", server, g.tlsConfig, timeout)
 		} else {
 			conn, err = d.Dial("tcp", server)
 		}
@@ -93,9 +120,38 @@ func (g *Graphite) Connect() error {
 		}
 	}
 	g.conns = conns
+	g.connected = time.Now()
 	return nil
 }
 
+// lookupSRV looks up the SRV records of a name, it is replaced by the tests.
+var lookupSRV = func(name string) ([]*net.SRV, error) {
+	_, addrs, err := net.LookupSRV("", "", name)
+	return addrs, err
+}
+
+// addresses returns the addresses of the servers, the "srv://" servers are
+// replaced by the targets of their SRV records.
+func (g *Graphite) addresses() []string {
+	var addrs []string
+	for _, server := range g.Servers {
+		if !strings.HasPrefix(server, "srv://") {
+			addrs = append(addrs, server)
+			continue
+		}
+		records, err := lookupSRV(strings.TrimPrefix(server, "srv://"))
+		if err != nil {
+			log.Printf("E! Graphite: lookup of %s failed: %s", server, err)
+			continue
+		}
+		for _, srv := range records {
+			host := strings.TrimSuffix(srv.Target, ".")
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+		}
+	}
+	return addrs
+}
+
 func (g *Graphite) Close() error {
 	// Closing all connections
 	for _, conn := range g.conns {
@@ -155,6 +211,12 @@ func (g *Graphite) Write(metrics []telegraf.Metric) error {
 		batch = append(batch, buf...)
 	}
 
+	// connect again to follow the changes of the DNS records
+	if g.DNSRefreshInterval.Duration > 0 && time.Since(g.connected) >= g.DNSRefreshInterval.Duration {
+		g.Close()
+		g.Connect()
+	}
+
 	err = g.send(batch)
 
 	// try to reconnect and retry to send
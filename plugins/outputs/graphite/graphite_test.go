@@ -2,6 +2,7 @@ package graphite
 
 import (
 	"bufio"
+	"errors"
 	"net"
 	"net/textproto"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 
 	"github.com/stretchr/testify/assert"
@@ -98,6 +100,59 @@ func TestGraphiteOK(t *testing.T) {
 	g.Close()
 }
 
+func TestGraphiteAddresses(t *testing.T) {
+	defer func(f func(string) ([]*net.SRV, error)) { lookupSRV = f }(lookupSRV)
+	lookupSRV = func(name string) ([]*net.SRV, error) {
+		if name != "_carbon._tcp.example.com" {
+			return nil, errors.New("no such host")
+		}
+		return []*net.SRV{
+			{Target: "carbon1.example.com.", Port: 2003},
+			{Target: "carbon2.example.com.", Port: 2004},
+		}, nil
+	}
+
+	g := Graphite{
+		Servers: []string{"localhost:2003", "srv://_carbon._tcp.example.com", "srv://_carbon._tcp.example.org"},
+	}
+	assert.Equal(t, []string{"localhost:2003", "carbon1.example.com:2003", "carbon2.example.com:2004"},
+		g.addresses())
+}
+
+func TestGraphiteDNSRefresh(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	g := Graphite{
+		Servers:            []string{l.Addr().String()},
+		DNSRefreshInterval: internal.Duration{Duration: time.Nanosecond},
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+	<-accepted
+
+	m, _ := metric.New("mymeasurement", map[string]string{"host": "192.168.0.1"},
+		map[string]interface{}{"myfield": float64(3.14)}, time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC))
+	require.NoError(t, g.Write([]telegraf.Metric{m}))
+
+	// the write used a new connection
+	conn := <-accepted
+	data, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "192_168_0_1.mymeasurement.myfield 3.14 1289430000\n", data)
+}
+
 func TCPServer1(t *testing.T, wg *sync.WaitGroup) {
 	tcpServer, _ := net.Listen("tcp", "127.0.0.1:2003")
 	go func() {
@@ -1,7 +1,6 @@
 package influxdb
 
 import (
-	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -16,6 +15,8 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/encoding"
+	"github.com/influxdata/telegraf/internal/proxy"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
@@ -96,6 +97,7 @@ type HTTPConfig struct {
 	Password        string
 	TLSConfig       *tls.Config
 	Proxy           *url.URL
+	Dialer          proxy.Dialer
 	Headers         map[string]string
 	ContentEncoding string
 	Database        string
@@ -126,6 +128,10 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 		return nil, ErrMissingURL
 	}
 
+	if err := encoding.CheckEncode(config.ContentEncoding); err != nil {
+		return nil, err
+	}
+
 	database := config.Database
 	if database == "" {
 		database = defaultDatabase
@@ -147,11 +153,10 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 		headers[k] = v
 	}
 
-	var proxy func(*http.Request) (*url.URL, error)
+	// the hosts listed in NO_PROXY are reached directly with both proxies
+	var proxyURL string
 	if config.Proxy != nil {
-		proxy = http.ProxyURL(config.Proxy)
-	} else {
-		proxy = http.ProxyFromEnvironment
+		proxyURL = config.Proxy.String()
 	}
 
 	serializer := config.Serializer
@@ -170,9 +175,12 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 	switch config.URL.Scheme {
 	case "http", "https":
 		transport = &http.Transport{
-			Proxy:           proxy,
+			Proxy:           proxy.ProxyURL(proxyURL),
 			TLSClientConfig: config.TLSConfig,
 		}
+		if config.Dialer != nil {
+			transport.Dial = config.Dialer.Dial
+		}
 	case "unix":
 		transport = &http.Transport{
 			Dial: func(_, _ string) (net.Conn, error) {
@@ -206,6 +214,14 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 	return client, nil
 }
 
+// Reconnect closes the idle connections, the next write connects again to
+// the address of the host of the URL.
+func (c *httpClient) Reconnect() {
+	if t, ok := c.client.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+}
+
 // URL returns the origin URL that this client connects too.
 func (c *httpClient) URL() string {
 	return c.url.String()
@@ -352,12 +368,9 @@ func (c *httpClient) makeQueryRequest(query string) (*http.Request, error) {
 }
 
 func (c *httpClient) makeWriteRequest(body io.Reader) (*http.Request, error) {
-	var err error
-	if c.ContentEncoding == "gzip" {
-		body, err = compressWithGzip(body)
-		if err != nil {
-			return nil, err
-		}
+	body, err := encoding.Encode(c.ContentEncoding, body)
+	if err != nil {
+		return nil, err
 	}
 
 	req, err := http.NewRequest("POST", c.WriteURL, body)
@@ -368,27 +381,13 @@ func (c *httpClient) makeWriteRequest(body io.Reader) (*http.Request, error) {
 	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
 	c.addHeaders(req)
 
-	if c.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
+	if c.ContentEncoding == encoding.Gzip || c.ContentEncoding == encoding.Snappy {
+		req.Header.Set("Content-Encoding", c.ContentEncoding)
 	}
 
 	return req, nil
 }
 
-func compressWithGzip(data io.Reader) (io.Reader, error) {
-	pr, pw := io.Pipe()
-	gw := gzip.NewWriter(pw)
-	var err error
-
-	go func() {
-		_, err = io.Copy(gw, data)
-		gw.Close()
-		pw.Close()
-	}()
-
-	return pr, err
-}
-
 func (c *httpClient) addHeaders(req *http.Request) {
 	if c.Username != "" || c.Password != "" {
 		req.SetBasicAuth(c.Username, c.Password)
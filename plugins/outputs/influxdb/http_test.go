@@ -17,6 +17,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/outputs/influxdb"
@@ -560,6 +561,43 @@ func TestHTTP_WriteContentEncodingGzip(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestHTTP_WriteContentEncodingSnappy(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, r.Header.Get("Content-Encoding"), "snappy")
+
+			data, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			body, err := snappy.Decode(nil, data)
+			require.NoError(t, err)
+
+			require.Contains(t, string(body), "cpu value=42")
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s/", ts.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	m, err := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	config := &influxdb.HTTPConfig{
+		URL:             u,
+		Database:        "telegraf",
+		ContentEncoding: "snappy",
+	}
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+	err = client.Write(context.Background(), []telegraf.Metric{m})
+	require.NoError(t, err)
+
+	config.ContentEncoding = "zstd"
+	_, err = influxdb.NewHTTPClient(config)
+	require.Error(t, err)
+}
+
 func TestHTTP_UnixSocket(t *testing.T) {
 	tmpdir, err := ioutil.TempDir("", "telegraf-test")
 	if err != nil {
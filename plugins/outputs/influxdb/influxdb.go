@@ -11,6 +11,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/proxy"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
@@ -29,6 +30,12 @@ type Client interface {
 	Database() string
 }
 
+// reconnecter is a Client whose connections can be closed, so the host of
+// its URL is resolved again by the next write.
+type reconnecter interface {
+	Reconnect()
+}
+
 // InfluxDB struct is the primary data structure for the plugin
 type InfluxDB struct {
 	URL                  string   // url deprecated in 0.1.9; use urls
@@ -46,6 +53,7 @@ type InfluxDB struct {
 	ContentEncoding      string            `toml:"content_encoding"`
 	SkipDatabaseCreation bool              `toml:"skip_database_creation"`
 	InfluxUintSupport    bool              `toml:"influx_uint_support"`
+	DNSRefreshInterval   internal.Duration `toml:"dns_refresh_interval"`
 
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
@@ -56,9 +64,13 @@ type InfluxDB struct {
 	// Use SSL but skip chain & host verification
 	InsecureSkipVerify bool
 
+	proxy.Socks5
+
 	Precision string // precision deprecated in 1.0; value is ignored
 
 	clients []Client
+	// refreshed is the time the hosts of the clients were last resolved.
+	refreshed time.Time
 
 	CreateHTTPClientF func(config *HTTPConfig) (Client, error)
 	CreateUDPClientF  func(config *UDPConfig) (Client, error)
@@ -112,15 +124,25 @@ var sampleConfig = `
 
   ## HTTP Proxy override, if unset values the standard proxy environment
   ## variables are consulted to determine which proxy, if any, should be used.
+  ## The hosts listed in NO_PROXY are reached directly.
   # http_proxy = "http://corporate.proxy:3128"
 
+  ## SOCKS5 proxy of the HTTP connections, not supported with UDP.
+  # socks5_address = "socks.example.com:1080"
+  # socks5_username = "telegraf"
+  # socks5_password = "metricsmetricsmetricsmetrics"
+
   ## Additional HTTP headers
   # http_headers = {"X-Special-Header" = "Special-Value"}
 
-  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## HTTP Content-Encoding for write request body, can be set to "gzip" or
+  ## "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "identity"
 
+  ## Interval to close the connections so the hosts of the urls are resolved
+  ## again, for the DNS failover of the servers, 0 keeps the connections.
+  # dns_refresh_interval = "0s"
+
   ## When true, Telegraf will output unsigned integers as unsigned values,
   ## i.e.: "42u".  You will need a version of InfluxDB supporting unsigned
   ## integer values.  Enabling this option will result in field type errors if
@@ -162,6 +184,9 @@ func (i *InfluxDB) Connect() error {
 
 		switch u.Scheme {
 		case "udp", "udp4", "udp6":
+			if i.Socks5.Enabled() {
+				return fmt.Errorf("socks5 proxy does not support udp [%s]", u)
+			}
 			c, err := i.udpClient(u)
 			if err != nil {
 				return err
@@ -180,6 +205,7 @@ func (i *InfluxDB) Connect() error {
 		}
 	}
 
+	i.refreshed = time.Now()
 	return nil
 }
 
@@ -200,6 +226,15 @@ func (i *InfluxDB) SampleConfig() string {
 func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 	ctx := context.Background()
 
+	if i.DNSRefreshInterval.Duration > 0 && time.Since(i.refreshed) >= i.DNSRefreshInterval.Duration {
+		i.refreshed = time.Now()
+		for _, client := range i.clients {
+			if r, ok := client.(reconnecter); ok {
+				r.Reconnect()
+			}
+		}
+	}
+
 	var err error
 	p := rand.Perm(len(i.clients))
 	for _, n := range p {
@@ -243,13 +278,21 @@ func (i *InfluxDB) udpClient(url *url.URL) (Client, error) {
 	return c, nil
 }
 
-func (i *InfluxDB) httpClient(ctx context.Context, url *url.URL, proxy *url.URL) (Client, error) {
+func (i *InfluxDB) httpClient(ctx context.Context, url *url.URL, proxyURL *url.URL) (Client, error) {
 	tlsConfig, err := internal.GetTLSConfig(
 		i.SSLCert, i.SSLKey, i.SSLCA, i.InsecureSkipVerify)
 	if err != nil {
 		return nil, err
 	}
 
+	var dialer proxy.Dialer
+	if i.Socks5.Enabled() && url.Scheme != "unix" {
+		dialer, err = i.Socks5.Dialer(i.Timeout.Duration)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	config := &HTTPConfig{
 		URL:             url,
 		Timeout:         i.Timeout.Duration,
@@ -257,7 +300,8 @@ func (i *InfluxDB) httpClient(ctx context.Context, url *url.URL, proxy *url.URL)
 		UserAgent:       i.UserAgent,
 		Username:        i.Username,
 		Password:        i.Password,
-		Proxy:           proxy,
+		Proxy:           proxyURL,
+		Dialer:          dialer,
 		ContentEncoding: i.ContentEncoding,
 		Headers:         i.HTTPHeaders,
 		Database:        i.Database,
@@ -69,6 +69,15 @@ type udpClient struct {
 	url        *url.URL
 }
 
+// Reconnect closes the connection, the next write connects again to the
+// address of the host of the URL.
+func (c *udpClient) Reconnect() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
 func (c *udpClient) URL() string {
 	return c.url.String()
 }
@@ -121,6 +121,40 @@ func TestUDP_Simple(t *testing.T) {
 	require.Equal(t, metricString+metricString, buffer.String())
 }
 
+func TestUDP_Reconnect(t *testing.T) {
+	var dials, closes int
+	config := &influxdb.UDPConfig{
+		URL: getURL(),
+		Dialer: &MockDialer{
+			DialContextF: func(network, address string) (influxdb.Conn, error) {
+				dials++
+				conn := &MockConn{
+					WriteF: func(b []byte) (n int, err error) {
+						return len(b), nil
+					},
+					CloseF: func() error {
+						closes++
+						return nil
+					},
+				}
+				return conn, nil
+			},
+		},
+	}
+	client, err := influxdb.NewUDPClient(config)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, client.Write(ctx, []telegraf.Metric{getMetric()}))
+	require.NoError(t, client.Write(ctx, []telegraf.Metric{getMetric()}))
+	require.Equal(t, 1, dials)
+
+	client.Reconnect()
+	require.Equal(t, 1, closes)
+	require.NoError(t, client.Write(ctx, []telegraf.Metric{getMetric()}))
+	require.Equal(t, 2, dials)
+}
+
 func TestUDP_DialError(t *testing.T) {
 	u, err := url.Parse("invalid://127.0.0.1:9999")
 	require.NoError(t, err)
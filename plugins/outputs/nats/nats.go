@@ -1,12 +1,17 @@
 package nats
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
+	"time"
 
-	nats_client "github.com/nats-io/nats"
+	nats_client "github.com/nats-io/nats.go"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
@@ -20,14 +25,11 @@ type NATS struct {
 	// NATS subject to publish metrics to
 	Subject string
 
-	// Path to CA file
-	SSLCA string `toml:"ssl_ca"`
-	// Path to host cert file
-	SSLCert string `toml:"ssl_cert"`
-	// Path to cert key file
-	SSLKey string `toml:"ssl_key"`
-	// Use SSL but skip chain & host verification
-	InsecureSkipVerify bool
+	// JetStream publishes wait for the acknowledgement of the stream
+	JetStream        bool              `toml:"jetstream"`
+	JetStreamTimeout internal.Duration `toml:"jetstream_timeout"`
+
+	tls.ClientConfig
 
 	conn       *nats_client.Conn
 	serializer serializers.Serializer
@@ -42,12 +44,22 @@ var sampleConfig = `
   ## NATS subject for producer messages
   subject = "telegraf"
 
-  ## Optional SSL Config
-  # ssl_ca = "/etc/telegraf/ca.pem"
-  # ssl_cert = "/etc/telegraf/cert.pem"
-  # ssl_key = "/etc/telegraf/key.pem"
-  ## Use SSL but skip chain & host verification
+  ## Publish to a JetStream stream capturing the subject, the metrics are
+  ## written once the stream acknowledges them within jetstream_timeout.
+  # jetstream = false
+  # jetstream_timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+  ## Minimum and maximum TLS version, "TLS10", "TLS11", "TLS12" or "TLS13"
+  # tls_min_version = "TLS12"
+  # tls_max_version = "TLS12"
+  ## Accepted cipher suites, by default those of Go
+  # tls_cipher_suites = ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
 
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
@@ -60,6 +72,21 @@ func (n *NATS) SetSerializer(serializer serializers.Serializer) {
 	n.serializer = serializer
 }
 
+// Init checks the servers and the TLS files.
+func (n *NATS) Init() error {
+	for _, server := range n.Servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			return fmt.Errorf("invalid server %q: %s", server, err)
+		}
+		if (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+			return fmt.Errorf("invalid server %q, expected nats://host:port or tls://host:port", server)
+		}
+	}
+	_, err := n.ClientConfig.TLSConfig()
+	return err
+}
+
 func (n *NATS) Connect() error {
 	var err error
 
@@ -79,16 +106,14 @@ func (n *NATS) Connect() error {
 	}
 
 	// override TLS, if it was specified
-	tlsConfig, err := internal.GetTLSConfig(
-		n.SSLCert, n.SSLKey, n.SSLCA, n.InsecureSkipVerify)
+	tlsConfig, err := n.ClientConfig.TLSConfig()
 	if err != nil {
 		return err
 	}
-	if tlsConfig != nil {
-		// set NATS connection TLS options
-		opts.Secure = true
-		opts.TLSConfig = tlsConfig
-	}
+	// set NATS connection TLS options, the config of FIPS mode is only
+	// used by the servers requiring TLS
+	opts.Secure = n.ClientConfig.Enabled()
+	opts.TLSConfig = tlsConfig
 
 	// try and connect
 	n.conn, err = opts.Connect()
@@ -114,6 +139,10 @@ func (n *NATS) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	if n.JetStream {
+		return n.writeJetStream(metrics)
+	}
+
 	for _, metric := range metrics {
 		buf, err := n.serializer.Serialize(metric)
 		if err != nil {
@@ -128,8 +157,64 @@ func (n *NATS) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// pubAck is the acknowledgement of a message published to a JetStream
+// stream.
+type pubAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// writeJetStream publishes the metrics with a reply subject for the
+// acknowledgement of each of them and waits for them.
+func (n *NATS) writeJetStream(metrics []telegraf.Metric) error {
+	inbox := nats_client.NewInbox()
+	acks := make(chan *nats_client.Msg, len(metrics))
+	sub, err := n.conn.ChanSubscribe(inbox+".*", acks)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for i, metric := range metrics {
+		buf, err := n.serializer.Serialize(metric)
+		if err != nil {
+			return err
+		}
+
+		err = n.conn.PublishRequest(n.Subject, inbox+"."+strconv.Itoa(i), buf)
+		if err != nil {
+			return fmt.Errorf("FAILED to send NATS message: %s", err)
+		}
+	}
+
+	timeout := time.After(n.JetStreamTimeout.Duration)
+	for i := 0; i < len(metrics); i++ {
+		select {
+		case msg := <-acks:
+			var ack pubAck
+			if err := json.Unmarshal(msg.Data, &ack); err != nil {
+				return fmt.Errorf("invalid JetStream acknowledgement: %s", err)
+			}
+			if ack.Error != nil {
+				return fmt.Errorf("JetStream publish to %s failed: %s (%d)",
+					n.Subject, ack.Error.Description, ack.Error.Code)
+			}
+		case <-timeout:
+			return fmt.Errorf("JetStream acknowledged %d of %d messages published to %s in %s",
+				i, len(metrics), n.Subject, n.JetStreamTimeout.Duration)
+		}
+	}
+	return nil
+}
+
 func init() {
 	outputs.Add("nats", func() telegraf.Output {
-		return &NATS{}
+		return &NATS{
+			JetStreamTimeout: internal.Duration{Duration: 5 * time.Second},
+		}
 	})
 }
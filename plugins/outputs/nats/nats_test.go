@@ -2,9 +2,14 @@ package nats
 
 import (
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/nats-io/gnatsd/server"
+	nats_client "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +34,69 @@ func TestConnectAndWrite(t *testing.T) {
 	err = n.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+func TestInit(t *testing.T) {
+	n := &NATS{Servers: []string{"nats://localhost:4222", "tls://nats.example.com:4443"}}
+	assert.NoError(t, n.Init())
+
+	n.Servers = []string{"nats:4222"}
+	assert.EqualError(t, n.Init(), `invalid server "nats:4222", expected nats://host:port or tls://host:port`)
+
+	n.Servers = []string{"nats://localhost:4222"}
+	n.TlsCa = "/nonexistent/ca.pem"
+	assert.Error(t, n.Init())
+}
+
+func runServer(t *testing.T) *server.Server {
+	s := server.New(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	go s.Start()
+	require.True(t, s.ReadyForConnections(10*time.Second))
+	return s
+}
+
+func TestWriteJetStream(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+	addr := "nats://" + s.Addr().String()
+
+	// the stream acknowledges the messages of "telegraf" and rejects those
+	// of "rejected", "ignored" is not captured by a stream
+	stream, err := nats_client.Connect(addr)
+	require.NoError(t, err)
+	defer stream.Close()
+	stream.Subscribe("telegraf", func(msg *nats_client.Msg) {
+		stream.Publish(msg.Reply, []byte(`{"stream":"metrics","seq":1}`))
+	})
+	stream.Subscribe("rejected", func(msg *nats_client.Msg) {
+		stream.Publish(msg.Reply, []byte(`{"error":{"code":503,"description":"storage full"}}`))
+	})
+	require.NoError(t, stream.Flush())
+
+	serializer, _ := serializers.NewInfluxSerializer()
+	n := &NATS{
+		Servers:          []string{addr},
+		Subject:          "telegraf",
+		JetStream:        true,
+		JetStreamTimeout: internal.Duration{Duration: 100 * time.Millisecond},
+		serializer:       serializer,
+	}
+	require.NoError(t, n.Connect())
+	defer n.Close()
+
+	require.NoError(t, n.Write(testutil.MockMetrics()))
+
+	n.Subject = "rejected"
+	err = n.Write(testutil.MockMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "storage full")
+
+	n.Subject = "ignored"
+	err = n.Write(testutil.MockMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "acknowledged 0 of 1")
+}
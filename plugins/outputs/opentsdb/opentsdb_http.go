@@ -27,6 +27,7 @@ type openTSDBHttp struct {
 	User      *url.Userinfo
 	BatchSize int
 	Debug     bool
+	Client    *http.Client
 
 	metricCounter int
 	body          requestBody
@@ -147,7 +148,11 @@ func (o *openTSDBHttp) flush() error {
 		fmt.Printf("Body:\n%s\n\n", o.body.dbgB.String())
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("Error when sending metrics: %s", err.Error())
 	}
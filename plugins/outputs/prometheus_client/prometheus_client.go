@@ -54,6 +54,7 @@ type MetricFamily struct {
 
 type PrometheusClient struct {
 	Listen             string
+	SocketMode         string            `toml:"socket_mode"`
 	TLSCert            string            `toml:"tls_cert"`
 	TLSKey             string            `toml:"tls_key"`
 	BasicUsername      string            `toml:"basic_username"`
@@ -73,9 +74,13 @@ type PrometheusClient struct {
 }
 
 var sampleConfig = `
-  ## Address to listen on
+  ## Address to listen on, or the path of a unix socket like
+  ## "unix:///var/run/telegraf/prometheus.sock"
   # listen = ":9273"
 
+  ## Permissions of the unix socket, in octal
+  # socket_mode = "0660"
+
   ## Use TLS
   #tls_cert = "/etc/ssl/telegraf.crt"
   #tls_key = "/etc/ssl/telegraf.key"
@@ -154,12 +159,17 @@ func (p *PrometheusClient) Start() error {
 		Handler: mux,
 	}
 
+	listener, err := internal.Listen(p.Listen, p.SocketMode)
+	if err != nil {
+		return err
+	}
+
 	go func() {
 		var err error
 		if p.TLSCert != "" && p.TLSKey != "" {
-			err = p.server.ListenAndServeTLS(p.TLSCert, p.TLSKey)
+			err = p.server.ServeTLS(listener, p.TLSCert, p.TLSKey)
 		} else {
-			err = p.server.ListenAndServe()
+			err = p.server.Serve(listener)
 		}
 		if err != nil && err != http.ErrServerClosed {
 			log.Printf("E! Error creating prometheus metric endpoint, err: %s\n",
@@ -8,6 +8,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/proxy"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
@@ -16,6 +17,8 @@ type SocketWriter struct {
 	Address         string
 	KeepAlivePeriod *internal.Duration
 
+	proxy.Socks5
+
 	serializers.Serializer
 
 	net.Conn
@@ -45,6 +48,11 @@ func (sw *SocketWriter) SampleConfig() string {
   ## Defaults to the OS configuration.
   # keep_alive_period = "5m"
 
+  ## Optional SOCKS5 proxy of the TCP connections.
+  # socks5_address = "socks.example.com:1080"
+  # socks5_username = "telegraf"
+  # socks5_password = "secret"
+
   ## Data format to generate.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -63,7 +71,21 @@ func (sw *SocketWriter) Connect() error {
 		return fmt.Errorf("invalid address: %s", sw.Address)
 	}
 
-	c, err := net.Dial(spl[0], spl[1])
+	var dialer proxy.Dialer = &net.Dialer{}
+	if sw.Socks5.Enabled() {
+		switch spl[0] {
+		case "tcp", "tcp4", "tcp6":
+		default:
+			return fmt.Errorf("socks5 proxy does not support %s sockets", spl[0])
+		}
+		var err error
+		dialer, err = sw.Socks5.Dialer(0)
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := dialer.Dial(spl[0], spl[1])
 	if err != nil {
 		return err
 	}
@@ -187,3 +187,14 @@ func TestSocketWriter_Write_reconnect(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, string(mbsout), string(buf[:n]))
 }
+
+func TestSocketWriter_socks5(t *testing.T) {
+	sw := newSocketWriter()
+	sw.Socks5Address = "127.0.0.1:1080"
+
+	sw.Address = "udp://127.0.0.1:8094"
+	assert.Error(t, sw.Connect())
+
+	sw.Address = "unix:///tmp/telegraf_test.sock"
+	assert.Error(t, sw.Connect())
+}
@@ -0,0 +1,257 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Parser parses CSV documents, each record is a metric. The columns are
+// named by the header rows or by ColumnNames, they are fields unless they
+// are the tag, measurement or timestamp columns.
+type Parser struct {
+	MetricName string
+
+	// HeaderRowCount is the number of header rows, the names of a column in
+	// the rows are concatenated. ColumnNames overrides them.
+	HeaderRowCount int
+	// SkipRows is the number of lines skipped before the header rows, and
+	// SkipColumns the number of columns skipped at the start of the records.
+	SkipRows    int
+	SkipColumns int
+
+	// Delimiter separates the columns, "," by default. The lines starting
+	// with Comment are skipped if it is set.
+	Delimiter string
+	Comment   string
+	// TrimSpace removes the spaces around the values.
+	TrimSpace bool
+
+	ColumnNames []string
+	// ColumnTypes are the types of the fields of the columns in order: int,
+	// float, bool or string. The types are guessed if it is empty.
+	ColumnTypes []string
+
+	TagColumns []string
+	// MeasurementColumn names the metrics with the value of the column, they
+	// are named MetricName if the value is empty.
+	MeasurementColumn string
+	// TimestampColumn is the time of the metrics, parsed with
+	// TimestampFormat: unix, unix_ms, unix_us, unix_ns or a Go time layout.
+	TimestampColumn string
+	TimestampFormat string
+
+	DefaultTags map[string]string
+
+	TimeFunc func() time.Time
+}
+
+// NewParser returns a parser after checking its options.
+func NewParser(p *Parser) (*Parser, error) {
+	if p.HeaderRowCount == 0 && len(p.ColumnNames) == 0 {
+		return nil, fmt.Errorf("csv_column_names must be set when there is no header row")
+	}
+	if len(p.ColumnNames) > 0 && len(p.ColumnTypes) > 0 && len(p.ColumnTypes) != len(p.ColumnNames) {
+		return nil, fmt.Errorf("csv_column_types must have a type for each of the csv_column_names")
+	}
+	for _, columnType := range p.ColumnTypes {
+		switch columnType {
+		case "int", "float", "bool", "string":
+		default:
+			return nil, fmt.Errorf("unknown csv_column_types %q, expected int, float, bool or string", columnType)
+		}
+	}
+	if p.Delimiter != "" && utf8.RuneCountInString(p.Delimiter) != 1 {
+		return nil, fmt.Errorf("csv_delimiter %q must be a single character", p.Delimiter)
+	}
+	if p.Comment != "" && utf8.RuneCountInString(p.Comment) != 1 {
+		return nil, fmt.Errorf("csv_comment %q must be a single character", p.Comment)
+	}
+	if p.TimestampColumn != "" && p.TimestampFormat == "" {
+		return nil, fmt.Errorf("csv_timestamp_format must be set with csv_timestamp_column")
+	}
+	if p.TimeFunc == nil {
+		p.TimeFunc = time.Now
+	}
+	return p, nil
+}
+
+func (p *Parser) newReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	if p.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(p.Delimiter)
+	}
+	if p.Comment != "" {
+		reader.Comment, _ = utf8.DecodeRuneInString(p.Comment)
+	}
+	reader.TrimLeadingSpace = p.TrimSpace
+	// the number of columns is checked against the names
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// Parse parses the records of a CSV document after skipping its first rows
+// and reading its header.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	r := bufio.NewReader(bytes.NewReader(buf))
+	for i := 0; i < p.SkipRows; i++ {
+		if _, err := r.ReadString('\n'); err != nil {
+			if err == io.EOF {
+				return []telegraf.Metric{}, nil
+			}
+			return nil, err
+		}
+	}
+	reader := p.newReader(r)
+
+	names := p.ColumnNames
+	var header []string
+	for i := 0; i < p.HeaderRowCount; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return []telegraf.Metric{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record = p.skipColumns(record)
+		for j, name := range record {
+			if p.TrimSpace {
+				name = strings.TrimSpace(name)
+			}
+			if j < len(header) {
+				header[j] += name
+			} else {
+				header = append(header, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		names = header
+	}
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0)
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		m, err := p.parseRecord(names, p.skipColumns(record), now)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %s", n, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a record without header, with the names of ColumnNames.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	if len(p.ColumnNames) == 0 {
+		return nil, fmt.Errorf("csv_column_names must be set to parse a line")
+	}
+	record, err := p.newReader(strings.NewReader(line)).Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: csv", line)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.parseRecord(p.ColumnNames, p.skipColumns(record), p.TimeFunc())
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) skipColumns(record []string) []string {
+	if p.SkipColumns >= len(record) {
+		return nil
+	}
+	return record[p.SkipColumns:]
+}
+
+func (p *Parser) parseRecord(names []string, record []string, now time.Time) (telegraf.Metric, error) {
+	if len(record) > len(names) {
+		return nil, fmt.Errorf("%d columns for %d column names", len(record), len(names))
+	}
+
+	name := p.MetricName
+	tm := now
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+
+	for i, value := range record {
+		if p.TrimSpace {
+			value = strings.TrimSpace(value)
+		}
+		column := names[i]
+		switch {
+		case column == p.MeasurementColumn:
+			if value != "" {
+				name = value
+			}
+		case column == p.TimestampColumn:
+			t, err := internal.ParseTimestamp(value, p.TimestampFormat)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %s", column, err)
+			}
+			tm = t
+		case p.isTag(column):
+			if value != "" {
+				tags[column] = value
+			}
+		case value == "":
+		case i < len(p.ColumnTypes):
+			field, err := convert(value, p.ColumnTypes[i])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %s", column, err)
+			}
+			fields[column] = field
+		default:
+			fields[column] = internal.ParseValue(value)
+		}
+	}
+
+	return metric.New(name, tags, fields, tm)
+}
+
+func (p *Parser) isTag(column string) bool {
+	for _, tag := range p.TagColumns {
+		if tag == column {
+			return true
+		}
+	}
+	return false
+}
+
+// convert returns the value of the given type.
+func convert(value, columnType string) (interface{}, error) {
+	switch columnType {
+	case "int":
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "bool":
+		return strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+}
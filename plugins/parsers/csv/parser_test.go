@@ -0,0 +1,139 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1536843808, 0)
+
+func newParser(t *testing.T, p *Parser) *Parser {
+	p.MetricName = "csv"
+	p.TimeFunc = func() time.Time { return now }
+	parser, err := NewParser(p)
+	require.NoError(t, err)
+	return parser
+}
+
+func TestParseHeader(t *testing.T) {
+	p := newParser(t, &Parser{
+		HeaderRowCount: 1,
+		TagColumns:     []string{"machine"},
+		DefaultTags:    map[string]string{"site": "plant1"},
+	})
+	metrics, err := p.Parse([]byte("machine,temperature,count,running,state\n" +
+		"m1,21.5,3,true,ok\n" +
+		"m2,,4,false,\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "csv", metrics[0].Name())
+	assert.Equal(t, map[string]string{"machine": "m1", "site": "plant1"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"temperature": 21.5,
+		"count":       int64(3),
+		"running":     true,
+		"state":       "ok",
+	}, metrics[0].Fields())
+	assert.Equal(t, now, metrics[0].Time())
+
+	// the empty values are skipped
+	assert.Equal(t, map[string]interface{}{
+		"count":   int64(4),
+		"running": false,
+	}, metrics[1].Fields())
+}
+
+func TestParseColumnNamesAndTypes(t *testing.T) {
+	p := newParser(t, &Parser{
+		HeaderRowCount: 1,
+		Delimiter:      ";",
+		TrimSpace:      true,
+		ColumnNames:    []string{"code", "value"},
+		ColumnTypes:    []string{"string", "float"},
+	})
+	metrics, err := p.Parse([]byte("c;v\n 007 ; 2 \n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"code": "007", "value": 2.0}, metrics[0].Fields())
+
+	_, err = p.Parse([]byte("c;v\n007;two\n"))
+	assert.EqualError(t, err, `record 1: column value: strconv.ParseFloat: parsing "two": invalid syntax`)
+
+	_, err = p.Parse([]byte("c;v\n007;2;3\n"))
+	assert.EqualError(t, err, "record 1: 3 columns for 2 column names")
+}
+
+func TestParseSkipAndComment(t *testing.T) {
+	p := newParser(t, &Parser{
+		HeaderRowCount: 2,
+		SkipRows:       1,
+		SkipColumns:    1,
+		Comment:        "#",
+	})
+	metrics, err := p.Parse([]byte("exported by unit 4\n" +
+		"id,temp,temp\n" +
+		"x,_in,_out\n" +
+		"# stopped\n" +
+		"1,20,30\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"temp_in": int64(20), "temp_out": int64(30)}, metrics[0].Fields())
+}
+
+func TestParseMeasurementAndTimestamp(t *testing.T) {
+	p := newParser(t, &Parser{
+		ColumnNames:       []string{"name", "time", "value"},
+		MeasurementColumn: "name",
+		TimestampColumn:   "time",
+		TimestampFormat:   "2006-01-02T15:04:05",
+	})
+	metrics, err := p.Parse([]byte("pressure,2018-09-13T13:03:28,1.5\n,2018-09-13T13:03:29,2\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "pressure", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{"value": 1.5}, metrics[0].Fields())
+	assert.Equal(t, now.UTC(), metrics[0].Time())
+	assert.Equal(t, "csv", metrics[1].Name())
+
+	_, err = p.Parse([]byte("pressure,yesterday,1.5\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "record 1: column time:")
+}
+
+func TestParseLine(t *testing.T) {
+	p := newParser(t, &Parser{
+		ColumnNames: []string{"machine", "value"},
+		TagColumns:  []string{"machine"},
+	})
+	m, err := p.ParseLine("m1,42")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"machine": "m1"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"value": int64(42)}, m.Fields())
+
+	p = newParser(t, &Parser{HeaderRowCount: 1})
+	_, err = p.ParseLine("m1,42")
+	assert.EqualError(t, err, "csv_column_names must be set to parse a line")
+}
+
+func TestNewParserErrors(t *testing.T) {
+	for _, tt := range []struct {
+		parser *Parser
+		err    string
+	}{
+		{&Parser{}, "csv_column_names must be set when there is no header row"},
+		{&Parser{ColumnNames: []string{"a"}, ColumnTypes: []string{"int", "int"}},
+			"csv_column_types must have a type for each of the csv_column_names"},
+		{&Parser{HeaderRowCount: 1, ColumnTypes: []string{"integer"}},
+			`unknown csv_column_types "integer", expected int, float, bool or string`},
+		{&Parser{HeaderRowCount: 1, Delimiter: "||"}, `csv_delimiter "||" must be a single character`},
+		{&Parser{HeaderRowCount: 1, TimestampColumn: "time"},
+			"csv_timestamp_format must be set with csv_timestamp_column"},
+	} {
+		_, err := NewParser(tt.parser)
+		assert.EqualError(t, err, tt.err)
+	}
+}
@@ -958,3 +958,30 @@ func TestTimezoneLocalCompileFileAndParse(t *testing.T) {
 	assert.Equal(t, map[string]string{}, metricB.Tags())
 	assert.Equal(t, time.Date(2016, time.June, 4, 12, 41, 45, 0, time.Local).UnixNano(), metricB.Time().UnixNano())
 }
+
+func TestParse(t *testing.T) {
+	p := &Parser{
+		Measurement:        "grok",
+		Patterns:           []string{"%{TEST_LOG_A}"},
+		CustomPatternFiles: []string{"./testdata/test-patterns"},
+		DefaultTags:        map[string]string{"host": "plc1"},
+	}
+	require.NoError(t, p.Compile())
+
+	metrics, err := p.Parse([]byte("[04/Jun/2016:12:41:45 +0100] 1.25 200 192.168.1.1 5.432µs 101\r\n" +
+		"\n" +
+		"no match\n" +
+		"[04/Jun/2016:12:41:46 +0100] 2.5 404 192.168.1.2 1s 7\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "grok", metrics[0].Name())
+	assert.Equal(t, map[string]string{"host": "plc1", "response_code": "200"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"clientip":      "192.168.1.1",
+		"myint":         int64(101),
+		"myfloat":       float64(1.25),
+		"response_time": int64(5432),
+	}, metrics[0].Fields())
+	assert.Equal(t, time.Unix(1465040505, 0).UTC(), metrics[0].Time().UTC())
+	assert.Equal(t, map[string]string{"host": "plc1", "response_code": "404"}, metrics[1].Tags())
+}
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"unsafe"
+
+	"github.com/influxdata/telegraf/metric"
 )
 
 const (
@@ -33,30 +35,42 @@ var (
 	)
 )
 
+// unescape returns the tag key, tag value or field key of b, the string is
+// shared with the other metrics and does not reference b.
 func unescape(b []byte) string {
 	if bytes.ContainsAny(b, escapes) {
-		return unescaper.Replace(unsafeBytesToString(b))
+		return metric.Intern(replace(unescaper, b))
 	} else {
-		return string(b)
+		return metric.InternBytes(b)
 	}
 }
 
 func nameUnescape(b []byte) string {
 	if bytes.ContainsAny(b, nameEscapes) {
-		return nameUnescaper.Replace(unsafeBytesToString(b))
+		return metric.Intern(replace(nameUnescaper, b))
 	} else {
-		return string(b)
+		return metric.InternBytes(b)
 	}
 }
 
 func stringFieldUnescape(b []byte) string {
 	if bytes.ContainsAny(b, stringFieldEscapes) {
-		return stringFieldUnescaper.Replace(unsafeBytesToString(b))
+		return replace(stringFieldUnescaper, b)
 	} else {
 		return string(b)
 	}
 }
 
+// replace unescapes b with r without referencing b, the replacer returns its
+// input unchanged when nothing is replaced.
+func replace(r *strings.Replacer, b []byte) string {
+	s := r.Replace(unsafeBytesToString(b))
+	if len(s) == len(b) {
+		return string(b)
+	}
+	return s
+}
+
 // parseIntBytes is a zero-alloc wrapper around strconv.ParseInt.
 func parseIntBytes(b []byte, base int, bitSize int) (i int64, err error) {
 	s := unsafeBytesToString(b)
@@ -0,0 +1,52 @@
+// +build gofuzz
+
+package influx
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Fuzz is the entry point of go-fuzz, it checks that the parsers don't panic
+// and that the stream parser returns the metrics of the valid inputs:
+//
+//   go-fuzz-build github.com/influxdata/telegraf/plugins/parsers/influx
+//   go-fuzz -bin=influx-fuzz.zip -workdir=fuzz
+func Fuzz(data []byte) int {
+	parser := NewParser(NewMetricHandler())
+	metrics, err := parser.Parse(data)
+	if err != nil {
+		return 0
+	}
+
+	var stream []telegraf.Metric
+	streamParser := NewStreamParser(bytes.NewReader(data))
+	for {
+		m, err := streamParser.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			panic(err)
+		}
+		stream = append(stream, m)
+	}
+	if len(metrics) != len(stream) {
+		panic("stream parser returned a different number of metrics")
+	}
+	for i := range metrics {
+		if !reflect.DeepEqual(metrics[i].Fields(), stream[i].Fields()) ||
+			!reflect.DeepEqual(metrics[i].Tags(), stream[i].Tags()) ||
+			metrics[i].Name() != stream[i].Name() {
+			panic("stream parser returned a different metric")
+		}
+	}
+
+	if len(metrics) == 0 {
+		return 0
+	}
+	return 1
+}
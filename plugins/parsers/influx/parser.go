@@ -1,10 +1,14 @@
 package influx
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 )
 
 const (
@@ -16,9 +20,10 @@ var (
 )
 
 type ParseError struct {
-	Offset int
-	msg    string
-	buf    string
+	Offset     int
+	LineNumber int
+	msg        string
+	buf        string
 }
 
 func (e *ParseError) Error() string {
@@ -26,6 +31,9 @@ func (e *ParseError) Error() string {
 	if len(buffer) > maxErrorBufferSize {
 		buffer = buffer[:maxErrorBufferSize] + "..."
 	}
+	if e.LineNumber > 0 {
+		return fmt.Sprintf("metric parse error: %s at line %d offset %d: %q", e.msg, e.LineNumber, e.Offset, buffer)
+	}
 	return fmt.Sprintf("metric parse error: %s at offset %d: %q", e.msg, e.Offset, buffer)
 }
 
@@ -50,6 +58,7 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	for p.machine.ParseLine() {
 		err := p.machine.Err()
 		if err != nil {
+			p.handler.Reset()
 			return nil, &ParseError{
 				Offset: p.machine.Position(),
 				msg:    err.Error(),
@@ -62,10 +71,15 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 			return nil, err
 		}
 		p.handler.Reset()
+
+		// lines of whitespace and comments have no measurement
+		if metric.Name() == "" {
+			continue
+		}
 		metrics = append(metrics, metric)
 	}
 
-	p.applyDefaultTags(metrics)
+	applyDefaultTags(p.DefaultTags, metrics)
 	return metrics, nil
 }
 
@@ -86,16 +100,135 @@ func (p *Parser) SetDefaultTags(tags map[string]string) {
 	p.DefaultTags = tags
 }
 
-func (p *Parser) applyDefaultTags(metrics []telegraf.Metric) {
-	if len(p.DefaultTags) == 0 {
+func applyDefaultTags(tags map[string]string, metrics []telegraf.Metric) {
+	if len(tags) == 0 {
 		return
 	}
 
 	for _, m := range metrics {
-		for k, v := range p.DefaultTags {
+		for k, v := range tags {
 			if !m.HasTag(k) {
 				m.AddTag(k, v)
 			}
 		}
 	}
 }
+
+// StreamParser is an influx line protocol parser reading the metrics one at a
+// time from a reader, the input is not read in memory at once.
+type StreamParser struct {
+	DefaultTags map[string]string
+
+	reader     *bufio.Reader
+	buf        []byte
+	line       []byte
+	lineNumber int
+	machine    *machine
+	handler    *MetricHandler
+}
+
+func NewStreamParser(r io.Reader) *StreamParser {
+	handler := NewMetricHandler()
+	return &StreamParser{
+		reader:  bufio.NewReader(r),
+		machine: NewMachine(handler),
+		handler: handler,
+	}
+}
+
+func (p *StreamParser) SetTimeFunc(f metric.TimeFunc) {
+	p.handler.SetTimeFunc(f)
+}
+
+func (p *StreamParser) SetTimePrecision(precision time.Duration) {
+	p.handler.SetPrecision(precision)
+}
+
+// Next returns the next metric of the reader, or io.EOF after the last one.
+// An invalid line returns a *ParseError, the next call continues with the
+// following line.
+func (p *StreamParser) Next() (telegraf.Metric, error) {
+	for {
+		if !p.machine.ParseLine() {
+			if err := p.readLine(false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err := p.machine.Err()
+		if err != nil {
+			p.handler.Reset()
+
+			// string fields may contain newlines, the line is parsed again
+			// with the next one if the error is at its end
+			if p.machine.Position() >= len(p.line) {
+				if err := p.readLine(true); err == nil {
+					continue
+				} else if err != io.EOF {
+					return nil, err
+				}
+			}
+
+			perr := &ParseError{
+				Offset:     p.machine.Position(),
+				LineNumber: p.lineNumber,
+				msg:        err.Error(),
+				buf:        string(p.line),
+			}
+			// resume with the next line
+			p.machine.SetData(nil)
+			return nil, perr
+		}
+
+		m, err := p.handler.Metric()
+		if err != nil {
+			return nil, err
+		}
+		p.handler.Reset()
+
+		if m.Name() == "" {
+			continue
+		}
+		applyDefaultTags(p.DefaultTags, []telegraf.Metric{m})
+		return m, nil
+	}
+}
+
+// readLine sets the data of the machine to the next line, or to the current
+// line followed by the next one if join is set. The line references the
+// buffer of the reader unless it is joined or larger than the buffer.
+func (p *StreamParser) readLine(join bool) error {
+	p.buf = p.buf[:0]
+	if join {
+		// the reader reuses the buffer of the current line
+		p.buf = append(p.buf, p.line...)
+	}
+
+	line, err := p.reader.ReadSlice('\n')
+	n := len(line)
+	if join || err == bufio.ErrBufferFull {
+		p.buf = append(p.buf, line...)
+		for err == bufio.ErrBufferFull {
+			line, err = p.reader.ReadSlice('\n')
+			n += len(line)
+			p.buf = append(p.buf, line...)
+		}
+		line = p.buf
+	}
+
+	// the last line may not end with a newline
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	p.line = line
+	if !join {
+		p.lineNumber++
+	}
+	p.machine.SetData(line)
+	return nil
+}
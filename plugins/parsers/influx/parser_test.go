@@ -1,6 +1,10 @@
 package influx
 
 import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -449,6 +453,29 @@ var ptests = []struct {
 		},
 		err: nil,
 	},
+	{
+		name:    "comment only",
+		input:   []byte("# comment\n"),
+		metrics: nil,
+		err:     nil,
+	},
+	{
+		name:  "comment after line",
+		input: []byte("cpu value=42\n# comment\n"),
+		metrics: []telegraf.Metric{
+			Metric(
+				metric.New(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 42.0,
+					},
+					time.Unix(42, 0),
+				),
+			),
+		},
+		err: nil,
+	},
 	{
 		name:    "invalid measurement only",
 		input:   []byte("cpu"),
@@ -559,6 +586,7 @@ func BenchmarkParser(b *testing.B) {
 		b.Run(tt.name, func(b *testing.B) {
 			handler := NewMetricHandler()
 			parser := NewParser(handler)
+			b.ReportAllocs()
 			for n := 0; n < b.N; n++ {
 				metrics, err := parser.Parse(tt.input)
 				_ = err
@@ -567,3 +595,155 @@ func BenchmarkParser(b *testing.B) {
 		})
 	}
 }
+
+func TestParserDoesNotReferenceInput(t *testing.T) {
+	input := []byte(`reuse,host=reuse-host value="a\"b",count=1i 0`)
+	parser := NewParser(NewMetricHandler())
+	metrics, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	for i := range input {
+		input[i] = 'x'
+	}
+	m := metrics[0]
+	require.Equal(t, "reuse", m.Name())
+	require.Equal(t, map[string]string{"host": "reuse-host"}, m.Tags())
+	require.Equal(t, map[string]interface{}{"value": `a"b`, "count": int64(1)}, m.Fields())
+}
+
+func streamParse(t testing.TB, input []byte) ([]telegraf.Metric, error) {
+	parser := NewStreamParser(bytes.NewReader(input))
+	parser.SetTimeFunc(DefaultTime)
+	var metrics []telegraf.Metric
+	for {
+		m, err := parser.Next()
+		if err == io.EOF {
+			return metrics, nil
+		}
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+func TestStreamParser(t *testing.T) {
+	for _, tt := range ptests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := streamParse(t, tt.input)
+			if tt.err != nil {
+				require.IsType(t, tt.err, err)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, len(tt.metrics), len(metrics))
+			for i, expected := range tt.metrics {
+				require.Equal(t, expected.Name(), metrics[i].Name())
+				require.Equal(t, expected.Tags(), metrics[i].Tags())
+				require.Equal(t, expected.Fields(), metrics[i].Fields())
+				require.Equal(t, expected.Time(), metrics[i].Time())
+			}
+		})
+	}
+}
+
+func TestStreamParserErrorResumes(t *testing.T) {
+	parser := NewStreamParser(strings.NewReader("cpu value=1\ncpu\ncpu value=2\n"))
+
+	m, err := parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"value": 1.0}, m.Fields())
+
+	_, err = parser.Next()
+	require.Equal(t, &ParseError{
+		Offset:     3,
+		LineNumber: 2,
+		msg:        ErrFieldParse.Error(),
+		buf:        "cpu\n",
+	}, err)
+
+	m, err = parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"value": 2.0}, m.Fields())
+
+	_, err = parser.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestStreamParserLongLine(t *testing.T) {
+	value := strings.Repeat("x", 10000)
+	input := "cpu value=\"" + value + "\"\ncpu value=42\n"
+	metrics, err := streamParse(t, []byte(input))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{"value": value}, metrics[0].Fields())
+	require.Equal(t, map[string]interface{}{"value": 42.0}, metrics[1].Fields())
+}
+
+// TestParserMutations parses random mutations of the test inputs, the
+// parsers must not panic and must agree on the valid inputs; go-fuzz runs
+// the same checks with fuzz.go.
+func TestParserMutations(t *testing.T) {
+	iterations := 2000
+	if testing.Short() {
+		iterations = 200
+	}
+	special := []byte(" ,=\\\"\n#0123456789.eEiut-+")
+	r := rand.New(rand.NewSource(42))
+	parser := NewParser(NewMetricHandler())
+	parser.handler.SetTimeFunc(DefaultTime)
+
+	for _, tt := range ptests {
+		for n := 0; n < iterations; n++ {
+			input := append([]byte(nil), tt.input...)
+			for k := r.Intn(4); k >= 0; k-- {
+				i := r.Intn(len(input) + 1)
+				c := special[r.Intn(len(special))]
+				switch r.Intn(3) {
+				case 0:
+					if i < len(input) {
+						input[i] = c
+					}
+				case 1:
+					input = append(input[:i], append([]byte{c}, input[i:]...)...)
+				case 2:
+					if i < len(input) {
+						input = append(input[:i], input[i+1:]...)
+					}
+				}
+			}
+
+			metrics, err := parser.Parse(input)
+			if err != nil {
+				continue
+			}
+			stream, err := streamParse(t, input)
+			require.NoError(t, err, "%q", input)
+			require.Equal(t, len(metrics), len(stream), "%q", input)
+			for i := range metrics {
+				require.Equal(t, metrics[i], stream[i], "%q", input)
+			}
+		}
+	}
+}
+
+func BenchmarkStreamParser(b *testing.B) {
+	for _, tt := range ptests {
+		b.Run(tt.name, func(b *testing.B) {
+			reader := bytes.NewReader(tt.input)
+			parser := NewStreamParser(reader)
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				reader.Reset(tt.input)
+				for {
+					_, err := parser.Next()
+					if err == io.EOF {
+						break
+					}
+				}
+			}
+		})
+	}
+}
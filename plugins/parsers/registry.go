@@ -2,16 +2,21 @@ package parsers
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/influxdata/telegraf"
 
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
+	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
+	"github.com/influxdata/telegraf/plugins/parsers/grok"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
+	"github.com/influxdata/telegraf/plugins/parsers/wasm"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
 )
 
 // ParserInput is an interface for input plugins that are able to parse
@@ -21,6 +26,24 @@ type ParserInput interface {
 	SetParser(parser Parser)
 }
 
+// ParserFunc creates a parser, a Parser is not safe for concurrent use.
+type ParserFunc func() (Parser, error)
+
+// ParserFuncInput is an interface for input plugins parsing arbitrary data
+// formats with several parsers, for example in parallel.
+type ParserFuncInput interface {
+	// SetParserFunc sets the function creating the parsers of the input
+	SetParserFunc(fn ParserFunc)
+}
+
+// SubjectParserInput is a ParserFuncInput whose [[inputs.<name>.subject]]
+// tables set the data format of the messages of a subject.
+type SubjectParserInput interface {
+	// SetSubjectParserFunc sets the function creating the parsers of the
+	// messages of the subject
+	SetSubjectParserFunc(subject string, fn ParserFunc)
+}
+
 // Parser is an interface defining functions that a parser plugin must satisfy.
 type Parser interface {
 	// Parse takes a byte buffer separated by newlines
@@ -42,7 +65,8 @@ type Parser interface {
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
-	// Dataformat can be one of: json, influx, graphite, value, nagios
+	// Dataformat can be one of: json, influx, graphite, value, nagios, csv,
+	// grok, xml, wasm
 	DataFormat string
 
 	// Separator only applied to Graphite data.
@@ -83,6 +107,42 @@ type Config struct {
 	// an optional map containing tag names as keys and json paths to retrieve the tag values from as values
 	// used if TagsPath is empty or doesn't return any tags
 	DropwizardTagPathsMap map[string]string
+
+	// the options of the csv data format, see csv.Parser
+	CSVColumnNames       []string
+	CSVColumnTypes       []string
+	CSVComment           string
+	CSVDelimiter         string
+	CSVHeaderRowCount    int
+	CSVMeasurementColumn string
+	CSVSkipColumns       int
+	CSVSkipRows          int
+	CSVTagColumns        []string
+	CSVTimestampColumn   string
+	CSVTimestampFormat   string
+	CSVTrimSpace         bool
+
+	// the patterns of the grok data format, with the patterns of
+	// GrokCustomPatterns and of the GrokCustomPatternFiles
+	GrokPatterns           []string
+	GrokCustomPatterns     string
+	GrokCustomPatternFiles []string
+	// the time zone of the timestamps without offset, UTC by default
+	GrokTimezone string
+
+	// the XPath location paths of the xml data format, see xml.Parser
+	XMLMetricSelection string
+	XMLMetricName      string
+	XMLTimestamp       string
+	XMLTimestampFormat string
+	XMLTags            map[string]string
+	XMLFields          map[string]string
+
+	// the WASI module of the wasm data format and its arguments, see
+	// wasm.Parser, the timeout is a duration like "5s"
+	WASMModule  string
+	WASMArgs    []string
+	WASMTimeout string
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -110,6 +170,16 @@ func NewParser(config *Config) (Parser, error) {
 		parser, err = NewDropwizardParser(config.DropwizardMetricRegistryPath,
 			config.DropwizardTimePath, config.DropwizardTimeFormat, config.DropwizardTagsPath, config.DropwizardTagPathsMap, config.DefaultTags,
 			config.Separator, config.Templates)
+	case "csv":
+		parser, err = NewCSVParser(config)
+	case "grok":
+		parser, err = NewGrokParser(config.MetricName, config.GrokPatterns,
+			config.GrokCustomPatterns, config.GrokCustomPatternFiles,
+			config.GrokTimezone, config.DefaultTags)
+	case "xml":
+		parser, err = NewXMLParser(config)
+	case "wasm":
+		parser, err = NewWASMParser(config)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -191,3 +261,85 @@ func NewDropwizardParser(
 
 	return parser, err
 }
+
+func NewCSVParser(config *Config) (Parser, error) {
+	parser, err := csv.NewParser(&csv.Parser{
+		MetricName:        config.MetricName,
+		HeaderRowCount:    config.CSVHeaderRowCount,
+		SkipRows:          config.CSVSkipRows,
+		SkipColumns:       config.CSVSkipColumns,
+		Delimiter:         config.CSVDelimiter,
+		Comment:           config.CSVComment,
+		TrimSpace:         config.CSVTrimSpace,
+		ColumnNames:       config.CSVColumnNames,
+		ColumnTypes:       config.CSVColumnTypes,
+		TagColumns:        config.CSVTagColumns,
+		MeasurementColumn: config.CSVMeasurementColumn,
+		TimestampColumn:   config.CSVTimestampColumn,
+		TimestampFormat:   config.CSVTimestampFormat,
+		DefaultTags:       config.DefaultTags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+func NewGrokParser(
+	metricName string,
+	patterns []string,
+	customPatterns string,
+	customPatternFiles []string,
+	timezone string,
+	defaultTags map[string]string,
+) (Parser, error) {
+	parser := &grok.Parser{
+		Measurement:        metricName,
+		Patterns:           patterns,
+		CustomPatterns:     customPatterns,
+		CustomPatternFiles: customPatternFiles,
+		Timezone:           timezone,
+		DefaultTags:        defaultTags,
+	}
+	if err := parser.Compile(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+func NewXMLParser(config *Config) (Parser, error) {
+	parser, err := xml.NewParser(&xml.Parser{
+		MetricName:      config.MetricName,
+		MetricSelection: config.XMLMetricSelection,
+		MetricNamePath:  config.XMLMetricName,
+		TimestampPath:   config.XMLTimestamp,
+		TimestampFormat: config.XMLTimestampFormat,
+		Tags:            config.XMLTags,
+		Fields:          config.XMLFields,
+		DefaultTags:     config.DefaultTags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+func NewWASMParser(config *Config) (Parser, error) {
+	var timeout time.Duration
+	if config.WASMTimeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(config.WASMTimeout); err != nil {
+			return nil, fmt.Errorf("invalid wasm_timeout: %s", err)
+		}
+	}
+	parser, err := wasm.NewParser(&wasm.Parser{
+		Module:      config.WASMModule,
+		Args:        config.WASMArgs,
+		Timeout:     timeout,
+		DefaultTags: config.DefaultTags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
@@ -0,0 +1,73 @@
+package wasm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/wasm"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+// DefaultTimeout is the time the module has to parse the data when the
+// Timeout of the Parser is not set.
+const DefaultTimeout = 5 * time.Second
+
+// Parser parses the data with a WASI module: the data is written to its
+// stdin and it writes the metrics to its stdout in the influx line protocol.
+type Parser struct {
+	Module  string
+	Args    []string
+	Timeout time.Duration
+
+	DefaultTags map[string]string
+
+	module *wasm.Module
+	influx *influx.Parser
+}
+
+// NewParser returns a parser after compiling its module.
+func NewParser(p *Parser) (*Parser, error) {
+	if p.Module == "" {
+		return nil, fmt.Errorf("wasm_module must be set")
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultTimeout
+	}
+
+	var err error
+	if p.module, err = wasm.Load(p.Module, p.Args, p.Timeout, nil); err != nil {
+		return nil, err
+	}
+	p.influx = influx.NewParser(influx.NewMetricHandler())
+	p.influx.SetDefaultTags(p.DefaultTags)
+	return p, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	out, err := p.module.Run(buf)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := p.influx.Parse(out)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the output of %s: %s", p.Module, err)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line + "\n"))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("the module returned %d metrics for one line", len(metrics))
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+	p.influx.SetDefaultTags(tags)
+}
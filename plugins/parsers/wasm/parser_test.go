@@ -0,0 +1,68 @@
+package wasm
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildModule compiles the keyvalue program of the testdata directory to a
+// WASI module.
+func buildModule(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "keyvalue.wasm")
+	cmd := exec.Command("go", "build", "-o", path, "./testdata/keyvalue")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		t.Skipf("cannot build the test module: %s: %s", err, out)
+	}
+	return path
+}
+
+func TestParse(t *testing.T) {
+	path := buildModule(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	p, err := NewParser(&Parser{
+		Module:      path,
+		Args:        []string{"sensor"},
+		DefaultTags: map[string]string{"host": "server01"},
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse([]byte("temperature=21.5 humidity=40i\n\npressure=1013\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, "sensor", metrics[0].Name())
+	require.Equal(t, map[string]string{"host": "server01"}, metrics[0].Tags())
+	require.Equal(t, map[string]interface{}{"temperature": 21.5, "humidity": int64(40)},
+		metrics[0].Fields())
+	require.Equal(t, map[string]interface{}{"pressure": 1013.0}, metrics[1].Fields())
+
+	m, err := p.ParseLine("pressure=990")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"pressure": 990.0}, m.Fields())
+}
+
+func TestParseError(t *testing.T) {
+	path := buildModule(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	// the module panics without its argument
+	p, err := NewParser(&Parser{Module: path})
+	require.NoError(t, err)
+
+	_, err = p.Parse([]byte("temperature=21.5\n"))
+	require.Error(t, err)
+}
+
+func TestNewParserNoModule(t *testing.T) {
+	_, err := NewParser(&Parser{})
+	require.Error(t, err)
+}
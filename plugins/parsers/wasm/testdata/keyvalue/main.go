@@ -0,0 +1,21 @@
+// keyvalue is the module of the tests, it parses the key=value pairs of its
+// input lines to the fields of a metric named after its first argument.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		fmt.Printf("%s %s\n", os.Args[1], strings.Join(fields, ","))
+	}
+}
@@ -0,0 +1,189 @@
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Parser parses XML documents with XPath location paths: MetricSelection
+// selects the nodes of the metrics, the other paths are relative to them and
+// the value of the first node they select is used.
+type Parser struct {
+	MetricName string
+
+	// MetricSelection selects the nodes of the metrics, the document node
+	// if it is empty.
+	MetricSelection string
+	// MetricNamePath names the metrics, they are named MetricName if it
+	// selects no node.
+	MetricNamePath string
+	// TimestampPath is the time of the metrics, parsed with TimestampFormat:
+	// unix, unix_ms, unix_us, unix_ns or a Go time layout.
+	TimestampPath   string
+	TimestampFormat string
+	// Tags and Fields map the tag and field names to their paths, the types
+	// of the fields are guessed.
+	Tags   map[string]string
+	Fields map[string]string
+
+	DefaultTags map[string]string
+
+	TimeFunc func() time.Time
+
+	selection *path
+	name      *path
+	timestamp *path
+	tags      map[string]*path
+	fields    map[string]*path
+}
+
+// NewParser returns a parser after compiling its paths.
+func NewParser(p *Parser) (*Parser, error) {
+	if len(p.Fields) == 0 {
+		return nil, fmt.Errorf("xml_fields must be set")
+	}
+	if p.TimestampPath != "" && p.TimestampFormat == "" {
+		return nil, fmt.Errorf("xml_timestamp_format must be set with xml_timestamp")
+	}
+
+	var err error
+	selection := p.MetricSelection
+	if selection == "" {
+		selection = "/"
+	}
+	if p.selection, err = compilePath(selection); err != nil {
+		return nil, err
+	}
+	if p.MetricNamePath != "" {
+		if p.name, err = compilePath(p.MetricNamePath); err != nil {
+			return nil, err
+		}
+	}
+	if p.TimestampPath != "" {
+		if p.timestamp, err = compilePath(p.TimestampPath); err != nil {
+			return nil, err
+		}
+	}
+	if p.tags, err = compilePaths(p.Tags); err != nil {
+		return nil, err
+	}
+	if p.fields, err = compilePaths(p.Fields); err != nil {
+		return nil, err
+	}
+	if p.TimeFunc == nil {
+		p.TimeFunc = time.Now
+	}
+	return p, nil
+}
+
+func compilePaths(exprs map[string]string) (map[string]*path, error) {
+	paths := make(map[string]*path, len(exprs))
+	for name, expr := range exprs {
+		p, err := compilePath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		paths[name] = p
+	}
+	return paths, nil
+}
+
+// Parse returns a metric for each node of the metric selection, the nodes
+// without fields are skipped.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return metrics, nil
+	}
+	doc, err := parseDocument(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.TimeFunc()
+	for _, n := range p.selection.eval(doc) {
+		m, err := p.parseNode(n, now)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("Can not parse the line: %s, for data format: xml", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) parseNode(n *node, now time.Time) (telegraf.Metric, error) {
+	name := p.MetricName
+	if p.name != nil {
+		if value, ok := first(p.name, n); ok && value != "" {
+			name = value
+		}
+	}
+
+	tm := now
+	if p.timestamp != nil {
+		if value, ok := first(p.timestamp, n); ok {
+			t, err := internal.ParseTimestamp(value, p.TimestampFormat)
+			if err != nil {
+				return nil, fmt.Errorf("xml_timestamp: %s", err)
+			}
+			tm = t
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for tag, path := range p.tags {
+		if value, ok := first(path, n); ok && value != "" {
+			tags[tag] = value
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for field, path := range p.fields {
+		if value, ok := first(path, n); ok && value != "" {
+			fields[field] = internal.ParseValue(value)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return metric.New(name, tags, fields, tm)
+}
+
+// first returns the trimmed value of the first node selected by the path
+// from the node.
+func first(p *path, n *node) (string, bool) {
+	nodes := p.eval(n)
+	if len(nodes) == 0 {
+		return "", false
+	}
+	return strings.TrimSpace(nodes[0].value()), true
+}
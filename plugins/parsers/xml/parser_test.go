@@ -0,0 +1,153 @@
+package xml
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gateway = `<?xml version="1.0" encoding="UTF-8"?>
+<Gateway xmlns="urn:scada">
+  <Name>plant1</Name>
+  <Timestamp>2018-09-13T13:03:28Z</Timestamp>
+  <Bus id="1">
+    <Sensor name="pump 1" state="ok">
+      <Temperature unit="C">21.5</Temperature>
+      <Pressure>3</Pressure>
+    </Sensor>
+    <Sensor name="pump 2" state="failed">
+      <Temperature unit="C">19</Temperature>
+    </Sensor>
+  </Bus>
+  <Bus id="2">
+    <Sensor name="valve" state="ok">
+      <Open>true</Open>
+    </Sensor>
+  </Bus>
+</Gateway>
+`
+
+func TestPathEval(t *testing.T) {
+	doc, err := parseDocument(strings.NewReader(gateway))
+	require.NoError(t, err)
+
+	for expr, expected := range map[string][]string{
+		"/Gateway/Name":                                     {"plant1"},
+		"/Gateway/Bus/Sensor/@name":                         {"pump 1", "pump 2", "valve"},
+		"//Sensor[@state='ok']/@name":                       {"pump 1", "valve"},
+		"//Sensor[@state != 'ok']/@name":                    {"pump 2"},
+		"//Bus[@id=2]/Sensor/@name":                         {"valve"},
+		"//Sensor[Temperature > 20]/@name":                  {"pump 1"},
+		"//Sensor[Pressure]/@name":                          {"pump 1"},
+		"/Gateway/Bus[1]/Sensor[last()]/@name":              {"pump 2"},
+		"//Temperature/text()":                              {"21.5", "19"},
+		"//Open/../@name":                                   {"valve"},
+		"/Gateway/child::Bus/descendant::Temperature/@unit": {"C", "C"},
+		"/Gateway/Bus[@id='1']/*[2]/@name":                  {"pump 2"},
+		"//scada:Sensor[3]":                                 nil,
+		"//Missing":                                         nil,
+	} {
+		p, err := compilePath(expr)
+		require.NoError(t, err, expr)
+		var values []string
+		for _, n := range p.eval(doc) {
+			values = append(values, strings.TrimSpace(n.value()))
+		}
+		assert.Equal(t, expected, values, expr)
+	}
+
+	for expr, expected := range map[string]string{
+		"/Gateway/[":      `invalid XPath "/Gateway/[": expected a name at 9`,
+		"//Sensor[@state": `invalid XPath "//Sensor[@state": missing ] at 15`,
+		"count(//Sensor)": `invalid XPath "count(//Sensor)": unexpected "(//Sensor)" at 5`,
+		"//Sensor[@a=b]":  `invalid XPath "//Sensor[@a=b]": expected a string or a number at 12`,
+		"/Gateway/name()": `invalid XPath "/Gateway/name()": unsupported function name()`,
+		"//Sensor[@a='b]": `invalid XPath "//Sensor[@a='b]": unterminated string at 12`,
+	} {
+		_, err := compilePath(expr)
+		assert.EqualError(t, err, expected, expr)
+	}
+}
+
+func TestParse(t *testing.T) {
+	now := time.Unix(1536843900, 0)
+	p, err := NewParser(&Parser{
+		MetricName:      "xml",
+		MetricSelection: "//Sensor",
+		TimestampPath:   "/Gateway/Timestamp",
+		TimestampFormat: "2006-01-02T15:04:05Z07:00",
+		Tags: map[string]string{
+			"gateway": "/Gateway/Name",
+			"bus":     "../@id",
+			"name":    "@name",
+		},
+		Fields: map[string]string{
+			"temperature": "Temperature",
+			"pressure":    "Pressure",
+			"open":        "Open",
+			"state":       "@state",
+		},
+		DefaultTags: map[string]string{"site": "north"},
+		TimeFunc:    func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse([]byte(gateway))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, "xml", metrics[0].Name())
+	assert.Equal(t, map[string]string{
+		"site":    "north",
+		"gateway": "plant1",
+		"bus":     "1",
+		"name":    "pump 1",
+	}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"temperature": 21.5,
+		"pressure":    int64(3),
+		"state":       "ok",
+	}, metrics[0].Fields())
+	assert.Equal(t, time.Unix(1536843808, 0).UTC(), metrics[0].Time())
+	assert.Equal(t, map[string]interface{}{"temperature": int64(19), "state": "failed"}, metrics[1].Fields())
+	assert.Equal(t, map[string]interface{}{"open": true, "state": "ok"}, metrics[2].Fields())
+
+	_, err = p.Parse([]byte("<Gateway><Sensor>"))
+	assert.Error(t, err)
+}
+
+func TestParseMetricName(t *testing.T) {
+	p, err := NewParser(&Parser{
+		MetricName:      "xml",
+		MetricSelection: "/Gateway/Bus/Sensor",
+		MetricNamePath:  "@kind",
+		Fields:          map[string]string{"value": "."},
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse([]byte(`<Gateway><Bus>
+		<Sensor kind="temperature">21</Sensor>
+		<Sensor>3</Sensor>
+		<Sensor kind="pressure"/>
+	</Bus></Gateway>`))
+	require.NoError(t, err)
+	// the sensors without value are skipped
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "temperature", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{"value": int64(21)}, metrics[0].Fields())
+	assert.Equal(t, "xml", metrics[1].Name())
+}
+
+func TestNewParserErrors(t *testing.T) {
+	_, err := NewParser(&Parser{})
+	assert.EqualError(t, err, "xml_fields must be set")
+
+	_, err = NewParser(&Parser{Fields: map[string]string{"a": "a"}, TimestampPath: "t"})
+	assert.EqualError(t, err, "xml_timestamp_format must be set with xml_timestamp")
+
+	_, err = NewParser(&Parser{Fields: map[string]string{"a": "a["}})
+	assert.EqualError(t, err, `a: invalid XPath "a[": expected a name at 2`)
+}
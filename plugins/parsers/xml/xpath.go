@@ -0,0 +1,489 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	documentNode nodeKind = iota
+	elementNode
+	attributeNode
+	textNode
+)
+
+// node is a node of a parsed document, the whitespace between the elements
+// is not kept.
+type node struct {
+	kind     nodeKind
+	name     string
+	text     string
+	parent   *node
+	attrs    []*node
+	children []*node
+}
+
+// parseDocument returns the document node of the XML document.
+func parseDocument(r io.Reader) (*node, error) {
+	doc := &node{kind: documentNode}
+	current := doc
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &node{kind: elementNode, name: t.Name.Local, parent: current}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				n.attrs = append(n.attrs, &node{kind: attributeNode, name: attr.Name.Local, text: attr.Value, parent: n})
+			}
+			current.children = append(current.children, n)
+			current = n
+		case xml.EndElement:
+			current = current.parent
+		case xml.CharData:
+			if current != doc && strings.TrimSpace(string(t)) != "" {
+				current.children = append(current.children, &node{kind: textNode, text: string(t), parent: current})
+			}
+		}
+	}
+	if len(doc.children) == 0 {
+		return nil, fmt.Errorf("no root element")
+	}
+	return doc, nil
+}
+
+// value returns the string value of the node, the text of its descendants for
+// an element.
+func (n *node) value() string {
+	if n.kind == attributeNode || n.kind == textNode {
+		return n.text
+	}
+	var b bytes.Buffer
+	n.appendText(&b)
+	return b.String()
+}
+
+func (n *node) appendText(b *bytes.Buffer) {
+	for _, child := range n.children {
+		if child.kind == textNode {
+			b.WriteString(child.text)
+		} else {
+			child.appendText(b)
+		}
+	}
+}
+
+func (n *node) root() *node {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// descendants appends the descendants of the node in document order.
+func (n *node) descendants(out []*node) []*node {
+	for _, child := range n.children {
+		out = append(out, child)
+		out = child.descendants(out)
+	}
+	return out
+}
+
+type axis int
+
+const (
+	childAxis axis = iota
+	descendantAxis
+	descendantOrSelfAxis
+	parentAxis
+	selfAxis
+	attributeAxis
+)
+
+var axes = map[string]axis{
+	"child":              childAxis,
+	"descendant":         descendantAxis,
+	"descendant-or-self": descendantOrSelfAxis,
+	"parent":             parentAxis,
+	"self":               selfAxis,
+	"attribute":          attributeAxis,
+}
+
+// path is a location path of XPath, like /Gateway/Bus[@id='2']//Sensor.
+// The supported steps are the child, descendant, descendant-or-self, parent,
+// self and attribute axes and their abbreviations, with name, *, text() and
+// node() tests and predicates.
+type path struct {
+	absolute bool
+	steps    []step
+}
+
+type step struct {
+	axis       axis
+	test       string
+	predicates []predicate
+}
+
+// predicate is a position, last() or a path, compared to a literal if op is
+// set: the predicate is true if the value of one of the nodes of the path
+// compares.
+type predicate struct {
+	position int
+	last     bool
+	path     *path
+	op       string
+	literal  string
+	number   bool
+}
+
+// compilePath parses the expression as a location path.
+func compilePath(expr string) (*path, error) {
+	c := &compiler{expr: expr}
+	p, err := c.path()
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath %q: %s", expr, err)
+	}
+	c.spaces()
+	if c.pos < len(c.expr) {
+		return nil, fmt.Errorf("invalid XPath %q: unexpected %q at %d", expr, c.expr[c.pos:], c.pos)
+	}
+	return p, nil
+}
+
+type compiler struct {
+	expr string
+	pos  int
+}
+
+func (c *compiler) spaces() {
+	for c.pos < len(c.expr) && strings.ContainsRune(" \t\r\n", rune(c.expr[c.pos])) {
+		c.pos++
+	}
+}
+
+func (c *compiler) consume(s string) bool {
+	if strings.HasPrefix(c.expr[c.pos:], s) {
+		c.pos += len(s)
+		return true
+	}
+	return false
+}
+
+var descendantOrSelf = step{axis: descendantOrSelfAxis, test: "node()"}
+
+func (c *compiler) path() (*path, error) {
+	p := &path{}
+	c.spaces()
+	switch {
+	case c.consume("//"):
+		p.absolute = true
+		p.steps = append(p.steps, descendantOrSelf)
+	case c.consume("/"):
+		p.absolute = true
+		// the document node alone
+		c.spaces()
+		if c.pos == len(c.expr) || strings.ContainsRune("]=!<>", rune(c.expr[c.pos])) {
+			return p, nil
+		}
+	}
+	for {
+		s, err := c.step()
+		if err != nil {
+			return nil, err
+		}
+		p.steps = append(p.steps, s)
+		switch {
+		case c.consume("//"):
+			p.steps = append(p.steps, descendantOrSelf)
+		case c.consume("/"):
+		default:
+			return p, nil
+		}
+	}
+}
+
+func (c *compiler) step() (step, error) {
+	c.spaces()
+	s := step{axis: childAxis}
+	switch {
+	case c.consume(".."):
+		s.axis, s.test = parentAxis, "node()"
+	case c.consume("."):
+		s.axis, s.test = selfAxis, "node()"
+	default:
+		if c.consume("@") {
+			s.axis = attributeAxis
+		} else if i := strings.Index(c.expr[c.pos:], "::"); i > 0 {
+			if a, ok := axes[c.expr[c.pos:c.pos+i]]; ok {
+				s.axis = a
+				c.pos += i + 2
+			}
+		}
+		test, err := c.test()
+		if err != nil {
+			return s, err
+		}
+		s.test = test
+	}
+	for {
+		c.spaces()
+		if !c.consume("[") {
+			return s, nil
+		}
+		p, err := c.predicate()
+		if err != nil {
+			return s, err
+		}
+		c.spaces()
+		if !c.consume("]") {
+			return s, fmt.Errorf("missing ] at %d", c.pos)
+		}
+		s.predicates = append(s.predicates, p)
+	}
+}
+
+func (c *compiler) test() (string, error) {
+	if c.consume("*") {
+		return "*", nil
+	}
+	start := c.pos
+	for c.pos < len(c.expr) {
+		ch := c.expr[c.pos]
+		if ch == ':' && strings.HasPrefix(c.expr[c.pos:], "::") {
+			break
+		}
+		if !isNameChar(ch, c.pos == start) {
+			break
+		}
+		c.pos++
+	}
+	name := c.expr[start:c.pos]
+	if name == "" {
+		return "", fmt.Errorf("expected a name at %d", start)
+	}
+	if c.consume("()") {
+		if name != "text" && name != "node" {
+			return "", fmt.Errorf("unsupported function %s()", name)
+		}
+		return name + "()", nil
+	}
+	// the prefix of the namespace is not checked
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name, nil
+}
+
+func isNameChar(ch byte, first bool) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch == '_', ch >= 0x80:
+		return true
+	case ch >= '0' && ch <= '9', ch == '-', ch == '.', ch == ':':
+		return !first
+	}
+	return false
+}
+
+func (c *compiler) predicate() (predicate, error) {
+	c.spaces()
+	var p predicate
+	if c.consume("last()") {
+		p.last = true
+		return p, nil
+	}
+	start := c.pos
+	for c.pos < len(c.expr) && c.expr[c.pos] >= '0' && c.expr[c.pos] <= '9' {
+		c.pos++
+	}
+	if c.pos > start {
+		p.position, _ = strconv.Atoi(c.expr[start:c.pos])
+		return p, nil
+	}
+
+	var err error
+	if p.path, err = c.path(); err != nil {
+		return p, err
+	}
+	c.spaces()
+	for _, op := range []string{"!=", "<=", ">=", "=", "<", ">"} {
+		if c.consume(op) {
+			p.op = op
+			break
+		}
+	}
+	if p.op == "" {
+		return p, nil
+	}
+	c.spaces()
+	if c.pos < len(c.expr) && (c.expr[c.pos] == '\'' || c.expr[c.pos] == '"') {
+		quote := c.expr[c.pos]
+		end := strings.IndexByte(c.expr[c.pos+1:], quote)
+		if end < 0 {
+			return p, fmt.Errorf("unterminated string at %d", c.pos)
+		}
+		p.literal = c.expr[c.pos+1 : c.pos+1+end]
+		c.pos += end + 2
+		return p, nil
+	}
+	start = c.pos
+	for c.pos < len(c.expr) && strings.ContainsRune("0123456789.-", rune(c.expr[c.pos])) {
+		c.pos++
+	}
+	p.literal, p.number = c.expr[start:c.pos], true
+	if _, err := strconv.ParseFloat(p.literal, 64); err != nil {
+		return p, fmt.Errorf("expected a string or a number at %d", start)
+	}
+	return p, nil
+}
+
+// eval returns the nodes selected from the context node, in document order
+// for each step.
+func (p *path) eval(context *node) []*node {
+	nodes := []*node{context}
+	if p.absolute {
+		nodes = []*node{context.root()}
+	}
+	for i := range p.steps {
+		nodes = p.steps[i].eval(nodes)
+	}
+	return nodes
+}
+
+func (s *step) eval(contexts []*node) []*node {
+	var out []*node
+	seen := make(map[*node]bool)
+	for _, context := range contexts {
+		var candidates []*node
+		for _, n := range s.axisNodes(context) {
+			if s.matches(n) {
+				candidates = append(candidates, n)
+			}
+		}
+		for _, p := range s.predicates {
+			candidates = p.filter(candidates)
+		}
+		for _, n := range candidates {
+			if !seen[n] {
+				seen[n] = true
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+func (s *step) axisNodes(n *node) []*node {
+	switch s.axis {
+	case descendantAxis:
+		return n.descendants(nil)
+	case descendantOrSelfAxis:
+		return n.descendants([]*node{n})
+	case parentAxis:
+		if n.parent == nil {
+			return nil
+		}
+		return []*node{n.parent}
+	case selfAxis:
+		return []*node{n}
+	case attributeAxis:
+		return n.attrs
+	}
+	return n.children
+}
+
+func (s *step) matches(n *node) bool {
+	switch s.test {
+	case "node()":
+		return true
+	case "text()":
+		return n.kind == textNode
+	}
+	// the principal node type of the attribute axis is the attribute
+	kind := elementNode
+	if s.axis == attributeAxis {
+		kind = attributeNode
+	}
+	return n.kind == kind && (s.test == "*" || s.test == n.name)
+}
+
+func (p *predicate) filter(nodes []*node) []*node {
+	switch {
+	case p.last:
+		if len(nodes) == 0 {
+			return nil
+		}
+		return nodes[len(nodes)-1:]
+	case p.position > 0:
+		if p.position > len(nodes) {
+			return nil
+		}
+		return nodes[p.position-1 : p.position]
+	}
+	var out []*node
+	for _, n := range nodes {
+		if p.matches(n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (p *predicate) matches(n *node) bool {
+	nodes := p.path.eval(n)
+	if p.op == "" {
+		return len(nodes) > 0
+	}
+	for _, selected := range nodes {
+		if p.compare(strings.TrimSpace(selected.value())) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares the value to the literal, as numbers if the literal is a
+// number.
+func (p *predicate) compare(value string) bool {
+	if !p.number {
+		switch p.op {
+		case "=":
+			return value == p.literal
+		case "!=":
+			return value != p.literal
+		}
+		return false
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	literal, _ := strconv.ParseFloat(p.literal, 64)
+	switch p.op {
+	case "=":
+		return v == literal
+	case "!=":
+		return v != literal
+	case "<":
+		return v < literal
+	case "<=":
+		return v <= literal
+	case ">":
+		return v > literal
+	}
+	return v >= literal
+}
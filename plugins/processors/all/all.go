@@ -1,6 +1,12 @@
 package all
 
 import (
+	_ "github.com/influxdata/telegraf/plugins/processors/cardinality"
+	_ "github.com/influxdata/telegraf/plugins/processors/dedup"
+	_ "github.com/influxdata/telegraf/plugins/processors/execd"
+	_ "github.com/influxdata/telegraf/plugins/processors/external"
 	_ "github.com/influxdata/telegraf/plugins/processors/override"
 	_ "github.com/influxdata/telegraf/plugins/processors/printer"
+	_ "github.com/influxdata/telegraf/plugins/processors/schema"
+	_ "github.com/influxdata/telegraf/plugins/processors/wasm"
 )
@@ -0,0 +1,202 @@
+package cardinality
+
+import (
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Maximum number of active series, the metrics of new series are dropped
+  ## once it is reached. 0 disables the limit.
+  # max_series = 0
+
+  ## Maximum number of active values of each tag key, the metrics with new
+  ## values are dropped or aggregated once it is reached. 0 disables the
+  ## limit.
+  # max_tag_values = 0
+
+  ## Tag keys limited by max_tag_values, all the tag keys if empty.
+  # tag_keys = []
+
+  ## What to do with the metrics with a new tag value over max_tag_values:
+  ## "drop" drops them, "aggregate" replaces the tag value by overflow_value
+  ## so they share a single series.
+  # action = "drop"
+  # overflow_value = "other"
+
+  ## Series and tag values not seen for this long are no longer active.
+  # expiry = "1h"
+`
+
+const (
+	ActionDrop      = "drop"
+	ActionAggregate = "aggregate"
+)
+
+type Cardinality struct {
+	MaxSeries     int
+	MaxTagValues  int
+	TagKeys       []string
+	Action        string
+	OverflowValue string
+	Expiry        internal.Duration
+
+	// series and tagValues are the time the active series and the active
+	// values of each tag key were last seen.
+	series    map[uint64]time.Time
+	tagValues map[string]map[string]time.Time
+	// warned are the limits reached since they were last below, a warning
+	// is logged when they are reached.
+	warned     map[string]bool
+	lastExpiry time.Time
+}
+
+func NewCardinality() *Cardinality {
+	return &Cardinality{
+		Action:        ActionDrop,
+		OverflowValue: "other",
+		Expiry:        internal.Duration{Duration: time.Hour},
+		series:        make(map[uint64]time.Time),
+		tagValues:     make(map[string]map[string]time.Time),
+		warned:        make(map[string]bool),
+	}
+}
+
+func (p *Cardinality) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Cardinality) Description() string {
+	return "Limit the number of series and of values of the tags."
+}
+
+func (p *Cardinality) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := time.Now()
+	p.expire(now)
+
+	out := in[:0]
+	for _, m := range in {
+		if !p.limitTags(m, now) || !p.limitSeries(m, now) {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// limitTags records the values of the limited tags of the metric, it returns
+// false if the metric is dropped.
+func (p *Cardinality) limitTags(m telegraf.Metric, now time.Time) bool {
+	if p.MaxTagValues <= 0 {
+		return true
+	}
+	var overflow []string
+	for _, tag := range m.TagList() {
+		if !p.limited(tag.Key) {
+			continue
+		}
+		values, ok := p.tagValues[tag.Key]
+		if !ok {
+			values = make(map[string]time.Time)
+			p.tagValues[tag.Key] = values
+		}
+		if _, ok := values[tag.Value]; ok || len(values) < p.MaxTagValues {
+			values[tag.Value] = now
+			continue
+		}
+
+		if p.Action != ActionAggregate {
+			p.warn("tag:"+tag.Key, "Tag %s has more than %d values, dropping the metrics with new values",
+				tag.Key, p.MaxTagValues)
+			return false
+		}
+		p.warn("tag:"+tag.Key, "Tag %s has more than %d values, replacing the new values by %q",
+			tag.Key, p.MaxTagValues, p.OverflowValue)
+		overflow = append(overflow, tag.Key)
+	}
+
+	// the overflow value does not count against the limit
+	for _, key := range overflow {
+		m.AddTag(key, p.OverflowValue)
+	}
+	return true
+}
+
+// limitSeries records the series of the metric, it returns false if the
+// metric is dropped.
+func (p *Cardinality) limitSeries(m telegraf.Metric, now time.Time) bool {
+	if p.MaxSeries <= 0 {
+		return true
+	}
+	id := m.HashID()
+	if _, ok := p.series[id]; ok || len(p.series) < p.MaxSeries {
+		p.series[id] = now
+		return true
+	}
+	p.warn("series", "More than %d series, dropping the metrics of new series", p.MaxSeries)
+	return false
+}
+
+func (p *Cardinality) limited(key string) bool {
+	if len(p.TagKeys) == 0 {
+		return true
+	}
+	for _, k := range p.TagKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Cardinality) warn(limit string, format string, args ...interface{}) {
+	if p.warned[limit] {
+		return
+	}
+	p.warned[limit] = true
+	log.Printf("W! [processors.cardinality] "+format, args...)
+}
+
+// expire forgets the series and tag values not seen for the expiry, at most
+// once every tenth of it. The limits below their maximum again are warned
+// about when they are reached again.
+func (p *Cardinality) expire(now time.Time) {
+	if p.Expiry.Duration <= 0 || now.Sub(p.lastExpiry) < p.Expiry.Duration/10 {
+		return
+	}
+	p.lastExpiry = now
+	before := now.Add(-p.Expiry.Duration)
+
+	for id, seen := range p.series {
+		if seen.Before(before) {
+			delete(p.series, id)
+		}
+	}
+	if len(p.series) < p.MaxSeries {
+		delete(p.warned, "series")
+	}
+	for key, values := range p.tagValues {
+		for value, seen := range values {
+			if seen.Before(before) {
+				delete(values, value)
+			}
+		}
+		if len(values) < p.MaxTagValues {
+			delete(p.warned, "tag:"+key)
+		}
+		if len(values) == 0 {
+			delete(p.tagValues, key)
+		}
+	}
+}
+
+func init() {
+	processors.Add("cardinality", func() telegraf.Processor {
+		return NewCardinality()
+	})
+}
@@ -0,0 +1,81 @@
+package cardinality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetric(tags map[string]string) telegraf.Metric {
+	m, _ := metric.New("m1", tags, map[string]interface{}{"value": int64(1)}, time.Now())
+	return m
+}
+
+func TestMaxTagValuesDrop(t *testing.T) {
+	p := NewCardinality()
+	p.MaxTagValues = 2
+	p.TagKeys = []string{"id"}
+
+	out := p.Apply(
+		newMetric(map[string]string{"id": "a", "host": "h1"}),
+		newMetric(map[string]string{"id": "b", "host": "h2"}),
+		newMetric(map[string]string{"id": "c", "host": "h3"}),
+		newMetric(map[string]string{"id": "a", "host": "h4"}),
+	)
+	assert.Len(t, out, 3)
+	assert.Equal(t, "a", out[2].Tags()["id"])
+	// the host tag is not limited
+	assert.Equal(t, "h4", out[2].Tags()["host"])
+}
+
+func TestMaxTagValuesAggregate(t *testing.T) {
+	p := NewCardinality()
+	p.MaxTagValues = 1
+	p.Action = ActionAggregate
+
+	out := p.Apply(
+		newMetric(map[string]string{"id": "a"}),
+		newMetric(map[string]string{"id": "b"}),
+		newMetric(map[string]string{"id": "c"}),
+	)
+	assert.Len(t, out, 3)
+	assert.Equal(t, "a", out[0].Tags()["id"])
+	assert.Equal(t, "other", out[1].Tags()["id"])
+	assert.Equal(t, "other", out[2].Tags()["id"])
+	assert.Equal(t, out[1].HashID(), out[2].HashID())
+}
+
+func TestMaxSeries(t *testing.T) {
+	p := NewCardinality()
+	p.MaxSeries = 2
+
+	out := p.Apply(
+		newMetric(map[string]string{"id": "a"}),
+		newMetric(map[string]string{"id": "b"}),
+		newMetric(map[string]string{"id": "c"}),
+		newMetric(map[string]string{"id": "b"}),
+	)
+	assert.Len(t, out, 3)
+	assert.Equal(t, "b", out[2].Tags()["id"])
+	assert.True(t, p.warned["series"])
+}
+
+func TestExpiry(t *testing.T) {
+	p := NewCardinality()
+	p.MaxSeries = 1
+	p.MaxTagValues = 1
+
+	assert.Len(t, p.Apply(newMetric(map[string]string{"id": "a"})), 1)
+	assert.Len(t, p.Apply(newMetric(map[string]string{"id": "b"})), 0)
+
+	p.expire(time.Now().Add(2 * time.Hour))
+	assert.Len(t, p.series, 0)
+	assert.Len(t, p.tagValues, 0)
+	assert.Len(t, p.warned, 0)
+
+	p.lastExpiry = time.Time{}
+	assert.Len(t, p.Apply(newMetric(map[string]string{"id": "b"})), 1)
+}
@@ -0,0 +1,126 @@
+package dedup
+
+import (
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Maximum time to suppress the metrics of a series whose fields did not
+  ## change, a metric is passed at least once per interval.
+  dedup_interval = "600s"
+
+  ## Numeric fields changing by at most this much since the last passed
+  ## metric count as unchanged. 0 passes any change.
+  # deadband = 0.0
+`
+
+// last is the last metric of a series passed by the processor.
+type last struct {
+	fields map[string]interface{}
+	time   time.Time
+}
+
+type Dedup struct {
+	DedupInterval internal.Duration
+	Deadband      float64
+
+	series      map[uint64]last
+	lastCleanup time.Time
+}
+
+func NewDedup() *Dedup {
+	return &Dedup{
+		DedupInterval: internal.Duration{Duration: 10 * time.Minute},
+		series:        make(map[uint64]last),
+	}
+}
+
+func (d *Dedup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Dedup) Description() string {
+	return "Drop the metrics whose fields did not change since the last one of their series."
+}
+
+func (d *Dedup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	d.cleanup(time.Now())
+
+	out := in[:0]
+	for _, m := range in {
+		id := m.HashID()
+		fields := m.Fields()
+		if l, ok := d.series[id]; ok && m.Time().Sub(l.time) < d.DedupInterval.Duration &&
+			d.unchanged(l.fields, fields) {
+			m.Drop()
+			continue
+		}
+		d.series[id] = last{fields: fields, time: m.Time()}
+		out = append(out, m)
+	}
+	return out
+}
+
+// unchanged reports whether the fields have the same keys as those of the
+// last metric and values equal to them or within the deadband.
+func (d *Dedup) unchanged(last, fields map[string]interface{}) bool {
+	if len(last) != len(fields) {
+		return false
+	}
+	for key, value := range fields {
+		lastValue, ok := last[key]
+		if !ok {
+			return false
+		}
+		if value == lastValue {
+			continue
+		}
+		a, ok := toFloat(value)
+		if !ok {
+			return false
+		}
+		b, ok := toFloat(lastValue)
+		if !ok || math.Abs(a-b) > d.Deadband {
+			return false
+		}
+	}
+	return true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// cleanup forgets the series without a metric passed for the interval, at
+// most once per interval.
+func (d *Dedup) cleanup(now time.Time) {
+	if now.Sub(d.lastCleanup) < d.DedupInterval.Duration {
+		return
+	}
+	d.lastCleanup = now
+	before := now.Add(-d.DedupInterval.Duration)
+	for id, l := range d.series {
+		if l.time.Before(before) {
+			delete(d.series, id)
+		}
+	}
+}
+
+func init() {
+	processors.Add("dedup", func() telegraf.Processor {
+		return NewDedup()
+	})
+}
@@ -0,0 +1,82 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetric(host string, fields map[string]interface{}, tm time.Time) telegraf.Metric {
+	m, _ := metric.New("cpu", map[string]string{"host": host}, fields, tm)
+	return m
+}
+
+func TestDedup(t *testing.T) {
+	d := NewDedup()
+	now := time.Now()
+	value := func(v float64) map[string]interface{} {
+		return map[string]interface{}{"value": v}
+	}
+
+	out := d.Apply(
+		newMetric("a", value(1), now),
+		newMetric("b", value(1), now),
+		newMetric("a", value(1), now.Add(time.Minute)),
+		newMetric("a", value(2), now.Add(2*time.Minute)),
+		newMetric("a", map[string]interface{}{"value": 2.0, "status": "ok"}, now.Add(3*time.Minute)),
+		newMetric("b", value(1), now.Add(9*time.Minute)),
+		// the interval since the last passed metric of b is over
+		newMetric("b", value(1), now.Add(10*time.Minute)),
+	)
+	assert.Len(t, out, 5)
+	assert.Equal(t, now, out[0].Time())
+	assert.Equal(t, now, out[1].Time())
+	assert.Equal(t, 2.0, out[2].Fields()["value"])
+	assert.Equal(t, "ok", out[3].Fields()["status"])
+	assert.Equal(t, now.Add(10*time.Minute), out[4].Time())
+}
+
+func TestDedupDeadband(t *testing.T) {
+	d := NewDedup()
+	d.Deadband = 0.5
+	now := time.Now()
+	value := func(v interface{}) map[string]interface{} {
+		return map[string]interface{}{"value": v, "state": "up"}
+	}
+
+	out := d.Apply(
+		newMetric("a", value(10.0), now),
+		newMetric("a", value(10.3), now.Add(time.Second)),
+		newMetric("a", value(10.5), now.Add(2*time.Second)),
+		// the change is relative to the last passed metric
+		newMetric("a", value(10.6), now.Add(3*time.Second)),
+		newMetric("b", value(int64(3)), now),
+		newMetric("b", value(uint64(3)), now.Add(time.Second)),
+		newMetric("b", value(int64(4)), now.Add(2*time.Second)),
+	)
+	assert.Len(t, out, 4)
+	assert.Equal(t, 10.0, out[0].Fields()["value"])
+	assert.Equal(t, 10.6, out[1].Fields()["value"])
+	assert.Equal(t, int64(3), out[2].Fields()["value"])
+	assert.Equal(t, int64(4), out[3].Fields()["value"])
+}
+
+func TestDedupCleanup(t *testing.T) {
+	d := NewDedup()
+	now := time.Now()
+	d.Apply(
+		newMetric("a", map[string]interface{}{"value": 1.0}, now.Add(-time.Hour)),
+		newMetric("b", map[string]interface{}{"value": 1.0}, now),
+	)
+	assert.Len(t, d.series, 2)
+
+	// the series are cleaned up once per interval
+	d.Apply()
+	assert.Len(t, d.series, 2)
+	d.lastCleanup = now.Add(-d.DedupInterval.Duration)
+	d.Apply()
+	assert.Len(t, d.series, 1)
+}
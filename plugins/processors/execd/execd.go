@@ -0,0 +1,163 @@
+package execd
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+var sampleConfig = `
+  ## Program to run as a daemon, with its arguments.
+  command = ["/usr/local/bin/telegraf-processor", "--flag", "value"]
+
+  ## Environment variables added to those of telegraf.
+  # environment = ["LANG=C"]
+
+  ## Delay before the program is started again when it exits.
+  restart_delay = "10s"
+
+  ## Time to wait for the program to answer, the metrics are passed through
+  ## unchanged when it does not answer in time.
+  # timeout = "5s"
+`
+
+// maxLineBytes is the longest line read from the program.
+const maxLineBytes = 1 << 20
+
+// reply is the answer of the program to a batch of metrics.
+type reply struct {
+	metrics []telegraf.Metric
+	err     error
+}
+
+type Execd struct {
+	Command      []string
+	Environment  []string
+	RestartDelay internal.Duration
+	Timeout      internal.Duration
+
+	process    *process.Process
+	parser     parsers.Parser
+	serializer serializers.Serializer
+	replies    chan reply
+	log        telegraf.Logger
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run a program as a daemon to process the metrics."
+}
+
+func (e *Execd) SetLogger(logger telegraf.Logger) {
+	e.log = logger
+}
+
+func (e *Execd) Start() error {
+	var err error
+	if e.parser, err = parsers.NewInfluxParser(); err != nil {
+		return err
+	}
+	if e.serializer, err = serializers.NewInfluxSerializer(); err != nil {
+		return err
+	}
+	e.replies = make(chan reply, 1)
+	e.process = &process.Process{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		ReadStdout:   e.read,
+		Log:          e.log,
+	}
+	return e.process.Start()
+}
+
+func (e *Execd) Stop() {
+	e.process.Stop()
+}
+
+// Apply writes the metrics to the program followed by an empty line and
+// returns the metrics it answers with, until an empty line.
+func (e *Execd) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	var buf bytes.Buffer
+	for _, m := range in {
+		b, err := e.serializer.Serialize(m)
+		if err != nil {
+			e.log.Errorf("Failed to serialize metric: %s", err)
+			return in
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('\n')
+
+	// drop a late answer to a batch that timed out
+	select {
+	case <-e.replies:
+	default:
+	}
+
+	if _, err := e.process.Write(buf.Bytes()); err != nil {
+		e.log.Errorf("Writing to %s: %s", e.Command[0], err)
+		return in
+	}
+	select {
+	case r := <-e.replies:
+		if r.err != nil {
+			e.log.Errorf("Parsing the output of %s: %s", e.Command[0], r.err)
+			return in
+		}
+		return r.metrics
+	case <-time.After(e.Timeout.Duration):
+		e.log.Errorf("Program %s did not answer within %s", e.Command[0], e.Timeout.Duration)
+		return in
+	}
+}
+
+// read parses the batches of lines written by the program, each ends with
+// an empty line.
+func (e *Execd) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	var batch []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) > 0 {
+			batch = append(batch, line...)
+			batch = append(batch, '\n')
+			continue
+		}
+
+		var r reply
+		if len(batch) > 0 {
+			r.metrics, r.err = e.parser.Parse(batch)
+		}
+		batch = batch[:0]
+		select {
+		case e.replies <- r:
+		default:
+			e.log.Warnf("Dropped an answer of %s that came too late", e.Command[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		e.log.Errorf("Reading the output of %s: %s", e.Command[0], err)
+	}
+}
+
+func init() {
+	processors.Add("execd", func() telegraf.Processor {
+		return &Execd{
+			RestartDelay: internal.Duration{Duration: 10 * time.Second},
+			Timeout:      internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,68 @@
+// +build !windows
+
+package execd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExecd(command ...string) *Execd {
+	return &Execd{
+		Command:      command,
+		RestartDelay: internal.Duration{Duration: time.Hour},
+		Timeout:      internal.Duration{Duration: time.Second},
+		log:          testutil.Logger{Name: "processors.execd"},
+	}
+}
+
+func TestExecdApply(t *testing.T) {
+	e := newExecd("sed", "-u", "s/^cpu,/cpu_sed,/")
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	for i := 0; i < 2; i++ {
+		out := e.Apply(testutil.TestMetric(42.0, "cpu"), testutil.TestMetric(1.0, "mem"))
+		require.Len(t, out, 2)
+		assert.Equal(t, "cpu_sed", out[0].Name())
+		assert.Equal(t, map[string]interface{}{"value": 42.0}, out[0].Fields())
+		assert.Equal(t, "mem", out[1].Name())
+	}
+}
+
+func TestExecdApplyDrop(t *testing.T) {
+	e := newExecd("sed", "-u", "/^cpu,/d")
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	out := e.Apply(testutil.TestMetric(42.0, "cpu"))
+	assert.Len(t, out, 0)
+}
+
+func TestExecdApplyTimeout(t *testing.T) {
+	e := newExecd("sh", "-c", "while read line; do :; done")
+	e.Timeout.Duration = 10 * time.Millisecond
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	in := []telegraf.Metric{testutil.TestMetric(42.0, "cpu")}
+	assert.Equal(t, in, e.Apply(in...))
+}
+
+func TestExecdApplyNotRunning(t *testing.T) {
+	e := newExecd("true")
+	e.Timeout.Duration = 10 * time.Millisecond
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	in := []telegraf.Metric{testutil.TestMetric(42.0, "cpu")}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, in, e.Apply(in...))
+	}
+}
@@ -0,0 +1,102 @@
+package external
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	ext "github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"golang.org/x/net/context"
+)
+
+var sampleConfig = `
+  ## Plugin program to run, with its arguments.
+  command = ["/usr/local/bin/telegraf-acme-processor"]
+
+  ## Environment variables added to those of telegraf.
+  # environment = ["LANG=C"]
+
+  ## TOML configuration of the plugin.
+  config = '''
+    lookup_file = "/etc/acme/hosts.csv"
+  '''
+
+  ## Delay before the program is started again when it exits.
+  # restart_delay = "10s"
+
+  ## Time to wait for the plugin to apply a metric, the metrics are passed
+  ## through unchanged when it does not answer in time.
+  # timeout = "5s"
+`
+
+type External struct {
+	Command      []string
+	Environment  []string
+	Config       string
+	RestartDelay internal.Duration
+	Timeout      internal.Duration
+
+	client *ext.Client
+	log    telegraf.Logger
+}
+
+func (e *External) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *External) Description() string {
+	return "Run a processor plugin as a separate program over gRPC."
+}
+
+func (e *External) SetLogger(logger telegraf.Logger) {
+	e.log = logger
+}
+
+func (e *External) Start() error {
+	e.client = &ext.Client{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		Kind:         ext.KindProcessor,
+		Config:       e.Config,
+		Log:          e.log,
+	}
+	return e.client.Start()
+}
+
+func (e *External) Stop() {
+	e.client.Stop()
+}
+
+// Apply passes the metrics through the plugin, they are passed through
+// unchanged if it fails.
+func (e *External) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	plugin, err := e.client.Plugin()
+	if err != nil {
+		e.log.Errorf("Applying the metrics: %s", err)
+		return in
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout.Duration)
+	defer cancel()
+	resp, err := plugin.Apply(ctx, &ext.Metrics{Metrics: ext.FromMetrics(in)})
+	if err != nil {
+		e.log.Errorf("Applying the metrics: %s", ext.ErrorMessage(err))
+		return in
+	}
+	out, err := ext.ToMetrics(resp.Metrics)
+	if err != nil {
+		e.log.Errorf("Applying the metrics: %s", err)
+		return in
+	}
+	return out
+}
+
+func init() {
+	processors.Add("external", func() telegraf.Processor {
+		return &External{
+			RestartDelay: internal.Duration{Duration: 10 * time.Second},
+			Timeout:      internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package external
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	ext "github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary serves the test processor when it is run by the plugin.
+func TestMain(m *testing.M) {
+	if os.Getenv(ext.MagicCookieKey) == ext.MagicCookieValue {
+		ext.Serve(&testProcessor{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type testProcessor struct {
+	Tenant string
+}
+
+func (p *testProcessor) SampleConfig() string { return "" }
+func (p *testProcessor) Description() string  { return "" }
+
+func (p *testProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.AddTag("tenant", p.Tenant)
+	}
+	return in
+}
+
+func TestExternalApply(t *testing.T) {
+	e := &External{
+		Command:      []string{os.Args[0]},
+		Config:       `tenant = "acme"`,
+		RestartDelay: internal.Duration{Duration: time.Second},
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		log:          testutil.Logger{Name: "processors.external"},
+	}
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	out := e.Apply(testutil.TestMetric(42.0, "cpu"))
+	require.Len(t, out, 1)
+	assert.Equal(t, "cpu", out[0].Name())
+	assert.Equal(t, map[string]string{"tag1": "value1", "tenant": "acme"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": 42.0}, out[0].Fields())
+}
@@ -0,0 +1,379 @@
+package schema
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+var sampleConfig = `
+  ## What to do with the metrics violating their schema:
+  ##   "drop":        drop them
+  ##   "coerce":      convert the fields to their type, clamp them to their
+  ##                  range and remove the unknown fields of strict schemas,
+  ##                  the metrics missing a required tag or field are dropped
+  ##   "tag":         keep them with the invalid_tag tag set to "true"
+  ##   "dead_letter": write them to dead_letter_file instead
+  # action = "drop"
+  # invalid_tag = "schema_invalid"
+  # dead_letter_file = "/var/lib/telegraf/dead_letter.influx"
+
+  ## The schema of a measurement, the measurements without schema are not
+  ## validated.
+  # [[processors.schema.measurement]]
+  #   name = "cpu"
+  #   required_tags = ["host", "cpu"]
+  #   ## If true, the fields without schema are invalid.
+  #   strict = false
+  #
+  #   [[processors.schema.measurement.field]]
+  #     name = "usage_idle"
+  #     ## "float", "integer", "unsigned", "string" or "boolean", any type if
+  #     ## empty.
+  #     type = "float"
+  #     required = true
+  #     min = 0
+  #     max = 100
+`
+
+const (
+	ActionDrop       = "drop"
+	ActionCoerce     = "coerce"
+	ActionTag        = "tag"
+	ActionDeadLetter = "dead_letter"
+)
+
+type Schema struct {
+	Action         string
+	InvalidTag     string
+	DeadLetterFile string
+	Measurements   []Measurement `toml:"measurement"`
+
+	initialized bool
+	schemas     map[string]*Measurement
+	deadLetter  *os.File
+	serializer  *influx.Serializer
+}
+
+// Measurement is the schema of the metrics of a measurement.
+type Measurement struct {
+	Name         string
+	RequiredTags []string
+	Strict       bool
+	Fields       []Field `toml:"field"`
+
+	fields map[string]*Field
+}
+
+// Field is the schema of a field, the range is not checked for strings and
+// booleans.
+type Field struct {
+	Name     string
+	Type     string
+	Required bool
+	Min      Bound
+	Max      Bound
+}
+
+// Bound is a limit of the range of a field, it is not set by default.
+type Bound struct {
+	Set   bool
+	Value float64
+}
+
+// UnmarshalTOML parses the bound from an integer or float of the TOML config
+// file.
+func (b *Bound) UnmarshalTOML(data []byte) error {
+	v, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid bound %s: %s", data, err)
+	}
+	b.Set = true
+	b.Value = v
+	return nil
+}
+
+func NewSchema() *Schema {
+	return &Schema{
+		Action:     ActionDrop,
+		InvalidTag: "schema_invalid",
+	}
+}
+
+func (p *Schema) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Schema) Description() string {
+	return "Validate the metrics against the schema of their measurement."
+}
+
+func (p *Schema) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !p.initialized {
+		p.init()
+	}
+
+	out := in[:0]
+	for _, m := range in {
+		s, ok := p.schemas[m.Name()]
+		if !ok {
+			out = append(out, m)
+			continue
+		}
+
+		err := s.validate(m, p.Action == ActionCoerce)
+		if err == nil {
+			out = append(out, m)
+			continue
+		}
+		switch p.Action {
+		case ActionTag:
+			m.AddTag(p.InvalidTag, "true")
+			out = append(out, m)
+		case ActionDeadLetter:
+			p.writeDeadLetter(m)
+			m.Drop()
+		default:
+			m.Drop()
+		}
+		log.Printf("D! [processors.schema] Invalid metric %s: %s", m.Name(), err)
+	}
+	return out
+}
+
+func (p *Schema) init() {
+	p.initialized = true
+	switch p.Action {
+	case ActionDrop, ActionCoerce, ActionTag:
+	case ActionDeadLetter:
+		f, err := os.OpenFile(p.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+		if err != nil {
+			log.Printf("E! [processors.schema] Cannot open dead_letter_file, the invalid metrics are dropped: %s", err)
+			break
+		}
+		p.deadLetter = f
+		p.serializer = influx.NewSerializer()
+	default:
+		log.Printf("E! [processors.schema] Invalid action %q, the invalid metrics are dropped", p.Action)
+	}
+
+	p.schemas = make(map[string]*Measurement, len(p.Measurements))
+	for i := range p.Measurements {
+		s := &p.Measurements[i]
+		s.fields = make(map[string]*Field, len(s.Fields))
+		for j := range s.Fields {
+			f := &s.Fields[j]
+			switch f.Type {
+			case "", "float", "integer", "unsigned", "string", "boolean":
+			default:
+				log.Printf("E! [processors.schema] Invalid type %q of field %s of %s", f.Type, f.Name, s.Name)
+			}
+			s.fields[f.Name] = f
+		}
+		p.schemas[s.Name] = s
+	}
+}
+
+func (p *Schema) writeDeadLetter(m telegraf.Metric) {
+	if p.deadLetter == nil {
+		return
+	}
+	if _, err := p.serializer.Write(p.deadLetter, m); err != nil {
+		log.Printf("E! [processors.schema] Cannot write to dead_letter_file: %s", err)
+	}
+}
+
+// validate returns the first violation of the schema by the metric, if
+// coerce is true the violations that can be fixed are fixed.
+func (s *Measurement) validate(m telegraf.Metric, coerce bool) error {
+	for _, key := range s.RequiredTags {
+		if !m.HasTag(key) {
+			return fmt.Errorf("missing tag %s", key)
+		}
+	}
+	for _, f := range s.Fields {
+		if f.Required && !m.HasField(f.Name) {
+			return fmt.Errorf("missing field %s", f.Name)
+		}
+	}
+
+	var invalid error
+	var unknown []string
+	coerced := make(map[string]interface{})
+	for _, field := range m.FieldList() {
+		f, ok := s.fields[field.Key]
+		if !ok {
+			if !s.Strict {
+				continue
+			}
+			if !coerce {
+				return fmt.Errorf("unknown field %s", field.Key)
+			}
+			unknown = append(unknown, field.Key)
+			continue
+		}
+
+		v, err := f.check(field.Value, coerce)
+		if err != nil {
+			if !coerce {
+				return err
+			}
+			invalid = err
+			continue
+		}
+		if v != field.Value {
+			coerced[field.Key] = v
+		}
+	}
+
+	for _, key := range unknown {
+		m.RemoveField(key)
+	}
+	for key, v := range coerced {
+		m.AddField(key, v)
+	}
+	return invalid
+}
+
+// check returns an error if the value does not have the type of the field or
+// is out of its range, if coerce is true it returns the value converted to
+// the type and clamped to the range instead when possible.
+func (f *Field) check(value interface{}, coerce bool) (interface{}, error) {
+	v, ok := convert(value, f.Type, coerce)
+	if !ok {
+		return nil, fmt.Errorf("field %s is not of type %s", f.Name, f.Type)
+	}
+
+	var n float64
+	switch x := v.(type) {
+	case float64:
+		n = x
+	case int64:
+		n = float64(x)
+	case uint64:
+		n = float64(x)
+	default:
+		return v, nil
+	}
+	bound := n
+	if f.Min.Set && n < f.Min.Value {
+		bound = f.Min.Value
+	}
+	if f.Max.Set && n > f.Max.Value {
+		bound = f.Max.Value
+	}
+	if bound == n {
+		return v, nil
+	}
+	if !coerce {
+		return nil, fmt.Errorf("field %s is out of range", f.Name)
+	}
+	switch v.(type) {
+	case int64:
+		return int64(bound), nil
+	case uint64:
+		return uint64(bound), nil
+	}
+	return bound, nil
+}
+
+// convert returns the value as the type, it is only converted from another
+// type if coerce is true.
+func convert(value interface{}, typ string, coerce bool) (interface{}, bool) {
+	switch typ {
+	case "":
+		return value, true
+	case "float":
+		if v, ok := value.(float64); ok || !coerce {
+			return v, ok
+		}
+		switch v := value.(type) {
+		case int64:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		case bool:
+			return boolTo(v, float64(1), float64(0)), true
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			return f, err == nil
+		}
+	case "integer":
+		if v, ok := value.(int64); ok || !coerce {
+			return v, ok
+		}
+		switch v := value.(type) {
+		case float64:
+			if v < math.MinInt64 || v >= math.MaxInt64 || math.IsNaN(v) {
+				return nil, false
+			}
+			return int64(v), true
+		case uint64:
+			return int64(v), v <= math.MaxInt64
+		case bool:
+			return boolTo(v, int64(1), int64(0)), true
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			return i, err == nil
+		}
+	case "unsigned":
+		if v, ok := value.(uint64); ok || !coerce {
+			return v, ok
+		}
+		switch v := value.(type) {
+		case float64:
+			if v < 0 || v >= math.MaxUint64 || math.IsNaN(v) {
+				return nil, false
+			}
+			return uint64(v), true
+		case int64:
+			return uint64(v), v >= 0
+		case bool:
+			return boolTo(v, uint64(1), uint64(0)), true
+		case string:
+			u, err := strconv.ParseUint(v, 10, 64)
+			return u, err == nil
+		}
+	case "string":
+		if v, ok := value.(string); ok || !coerce {
+			return v, ok
+		}
+		return fmt.Sprint(value), true
+	case "boolean":
+		if v, ok := value.(bool); ok || !coerce {
+			return v, ok
+		}
+		switch v := value.(type) {
+		case float64:
+			return v != 0, true
+		case int64:
+			return v != 0, true
+		case uint64:
+			return v != 0, true
+		case string:
+			b, err := strconv.ParseBool(strings.ToLower(v))
+			return b, err == nil
+		}
+	}
+	return nil, false
+}
+
+func boolTo(b bool, t, f interface{}) interface{} {
+	if b {
+		return t
+	}
+	return f
+}
+
+func init() {
+	processors.Add("schema", func() telegraf.Processor {
+		return NewSchema()
+	})
+}
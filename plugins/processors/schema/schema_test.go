@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+[[measurement]]
+  name = "cpu"
+  required_tags = ["host"]
+  strict = true
+
+  [[measurement.field]]
+    name = "usage"
+    type = "float"
+    required = true
+    min = 0
+    max = 100.0
+
+  [[measurement.field]]
+    name = "count"
+    type = "integer"
+`
+
+func newSchema(t *testing.T, action string) *Schema {
+	p := NewSchema()
+	require.NoError(t, toml.Unmarshal([]byte(testConfig), p))
+	p.Action = action
+	return p
+}
+
+func newMetric(name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New(name, tags, fields, time.Unix(0, 0))
+	return m
+}
+
+func testMetrics() []telegraf.Metric {
+	host := map[string]string{"host": "a"}
+	return []telegraf.Metric{
+		newMetric("cpu", host, map[string]interface{}{"usage": 42.0, "count": int64(1)}),
+		newMetric("cpu", nil, map[string]interface{}{"usage": 42.0}),
+		newMetric("cpu", host, map[string]interface{}{"usage": int64(142)}),
+		newMetric("cpu", host, map[string]interface{}{"usage": 42.0, "other": 1.0}),
+		newMetric("mem", nil, map[string]interface{}{"used": "a lot"}),
+	}
+}
+
+func TestConfig(t *testing.T) {
+	p := newSchema(t, ActionDrop)
+	require.Len(t, p.Measurements, 1)
+	require.Len(t, p.Measurements[0].Fields, 2)
+	assert.Equal(t, Bound{Set: true, Value: 0}, p.Measurements[0].Fields[0].Min)
+	assert.Equal(t, Bound{Set: true, Value: 100}, p.Measurements[0].Fields[0].Max)
+	assert.Equal(t, Bound{}, p.Measurements[0].Fields[1].Min)
+}
+
+func TestDrop(t *testing.T) {
+	out := newSchema(t, ActionDrop).Apply(testMetrics()...)
+	require.Len(t, out, 2)
+	assert.Equal(t, map[string]interface{}{"usage": 42.0, "count": int64(1)}, out[0].Fields())
+	assert.Equal(t, "mem", out[1].Name())
+}
+
+func TestCoerce(t *testing.T) {
+	out := newSchema(t, ActionCoerce).Apply(testMetrics()...)
+	require.Len(t, out, 4)
+	assert.Equal(t, map[string]interface{}{"usage": 100.0}, out[1].Fields())
+	assert.Equal(t, map[string]interface{}{"usage": 42.0}, out[2].Fields())
+
+	m := newMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": "12.5", "count": 3.7})
+	out = newSchema(t, ActionCoerce).Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]interface{}{"usage": 12.5, "count": int64(3)}, out[0].Fields())
+
+	m = newMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": "high"})
+	assert.Len(t, newSchema(t, ActionCoerce).Apply(m), 0)
+}
+
+func TestTag(t *testing.T) {
+	out := newSchema(t, ActionTag).Apply(testMetrics()...)
+	require.Len(t, out, 5)
+	var invalid []bool
+	for _, m := range out {
+		invalid = append(invalid, m.HasTag("schema_invalid"))
+	}
+	assert.Equal(t, []bool{false, true, true, true, false}, invalid)
+}
+
+func TestDeadLetter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := newSchema(t, ActionDeadLetter)
+	p.DeadLetterFile = filepath.Join(dir, "dead_letter.influx")
+	out := p.Apply(testMetrics()...)
+	require.Len(t, out, 2)
+
+	data, err := ioutil.ReadFile(p.DeadLetterFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "cpu usage=42 0", lines[0])
+	assert.Equal(t, "cpu,host=a usage=142i 0", lines[1])
+}
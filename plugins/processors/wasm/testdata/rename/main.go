@@ -0,0 +1,24 @@
+// rename is the module of the tests, it renames the cpu measurement to the
+// name of its first argument and drops the mem measurement.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "cpu,"):
+			fmt.Println(os.Args[1] + strings.TrimPrefix(line, "cpu"))
+		case strings.HasPrefix(line, "mem,"):
+		default:
+			fmt.Println(line)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package wasm
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/wasm"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+var sampleConfig = `
+  ## WASI module run for each batch of metrics.
+  module = "/usr/local/lib/telegraf/processor.wasm"
+
+  ## Arguments of the module.
+  # args = ["--flag", "value"]
+
+  ## Time to wait for the module to exit, the metrics are passed through
+  ## unchanged when it does not exit in time.
+  # timeout = "5s"
+`
+
+type Wasm struct {
+	Module  string
+	Args    []string
+	Timeout internal.Duration
+
+	module     *wasm.Module
+	parser     parsers.Parser
+	serializer serializers.Serializer
+	log        telegraf.Logger
+}
+
+func (w *Wasm) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Wasm) Description() string {
+	return "Run a WebAssembly module to process the metrics."
+}
+
+func (w *Wasm) SetLogger(logger telegraf.Logger) {
+	w.log = logger
+}
+
+func (w *Wasm) Start() error {
+	if w.Module == "" {
+		return fmt.Errorf("no module")
+	}
+	var err error
+	if w.parser, err = parsers.NewInfluxParser(); err != nil {
+		return err
+	}
+	if w.serializer, err = serializers.NewInfluxSerializer(); err != nil {
+		return err
+	}
+	w.module, err = wasm.Load(w.Module, w.Args, w.Timeout.Duration, w.log)
+	return err
+}
+
+func (w *Wasm) Stop() {
+	w.module.Close()
+}
+
+// Apply runs the module with the metrics on its stdin and returns the
+// metrics it writes to its stdout.
+func (w *Wasm) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	var buf bytes.Buffer
+	for _, m := range in {
+		b, err := w.serializer.Serialize(m)
+		if err != nil {
+			w.log.Errorf("Failed to serialize metric: %s", err)
+			return in
+		}
+		buf.Write(b)
+	}
+
+	out, err := w.module.Run(buf.Bytes())
+	if err != nil {
+		w.log.Errorf("Processing the metrics: %s", err)
+		return in
+	}
+	metrics, err := w.parser.Parse(out)
+	if err != nil {
+		w.log.Errorf("Parsing the output of %s: %s", w.Module, err)
+		return in
+	}
+	return metrics
+}
+
+func init() {
+	processors.Add("wasm", func() telegraf.Processor {
+		return &Wasm{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package wasm
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildModule compiles the rename program of the testdata directory to a
+// WASI module.
+func buildModule(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "rename.wasm")
+	cmd := exec.Command("go", "build", "-o", path, "./testdata/rename")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		t.Skipf("cannot build the test module: %s: %s", err, out)
+	}
+	return path
+}
+
+func newWasm(module string, args ...string) *Wasm {
+	return &Wasm{
+		Module:  module,
+		Args:    args,
+		Timeout: internal.Duration{Duration: 10 * time.Second},
+		log:     testutil.Logger{Name: "processors.wasm"},
+	}
+}
+
+func TestWasmApply(t *testing.T) {
+	path := buildModule(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	w := newWasm(path, "cpu_wasm")
+	require.NoError(t, w.Start())
+	defer w.Stop()
+
+	for i := 0; i < 2; i++ {
+		out := w.Apply(testutil.TestMetric(42.0, "cpu"), testutil.TestMetric(1.0, "mem"),
+			testutil.TestMetric(2.0, "disk"))
+		require.Len(t, out, 2)
+		assert.Equal(t, "cpu_wasm", out[0].Name())
+		assert.Equal(t, map[string]interface{}{"value": 42.0}, out[0].Fields())
+		assert.Equal(t, "disk", out[1].Name())
+	}
+}
+
+func TestWasmApplyError(t *testing.T) {
+	path := buildModule(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	// the module panics without its argument
+	w := newWasm(path)
+	require.NoError(t, w.Start())
+	defer w.Stop()
+
+	in := []telegraf.Metric{testutil.TestMetric(42.0, "cpu")}
+	assert.Equal(t, in, w.Apply(in...))
+}
+
+func TestWasmStartNoModule(t *testing.T) {
+	w := newWasm("")
+	require.Error(t, w.Start())
+}
@@ -10,3 +10,22 @@ type Processor interface {
 	// Apply the filter to the given metric
 	Apply(in ...Metric) []Metric
 }
+
+// ServiceProcessor is a Processor that runs a service while the agent
+// runs, like an external program.
+type ServiceProcessor interface {
+	// SampleConfig returns the default configuration of the Processor
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the Processor
+	Description() string
+
+	// Apply the filter to the given metric
+	Apply(in ...Metric) []Metric
+
+	// Start starts the service before the first metric is applied
+	Start() error
+
+	// Stop stops the service after the last metric is applied
+	Stop()
+}
@@ -0,0 +1,128 @@
+package selfstat
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// histogramSize is the number of values a histogram keeps between two
+// collections, the values are sampled uniformly once it is reached.
+const histogramSize = 1028
+
+// percentiles are the fields of a histogram in addition to the mean and the
+// maximum.
+var percentiles = []struct {
+	suffix string
+	p      float64
+}{
+	{"_p50", 0.50},
+	{"_p90", 0.90},
+	{"_p99", 0.99},
+}
+
+type histogramStat struct {
+	measurement string
+	field       string
+	tags        map[string]string
+	key         uint64
+	values      []int64
+	count       int64
+	sum         int64
+	max         int64
+	prev        map[string]interface{}
+	rand        *rand.Rand
+	mu          sync.Mutex
+}
+
+func newHistogramStat(measurement, field string, tags map[string]string) *histogramStat {
+	return &histogramStat{
+		measurement: measurement,
+		field:       field,
+		tags:        tags,
+		values:      make([]int64, 0, histogramSize),
+		rand:        rand.New(rand.NewSource(1)),
+	}
+}
+
+func (s *histogramStat) Incr(v int64) {
+	s.mu.Lock()
+	s.count++
+	s.sum += v
+	if s.count == 1 || v > s.max {
+		s.max = v
+	}
+	if len(s.values) < histogramSize {
+		s.values = append(s.values, v)
+	} else if i := s.rand.Int63n(s.count); i < histogramSize {
+		s.values[i] = v
+	}
+	s.mu.Unlock()
+}
+
+func (s *histogramStat) Set(v int64) {
+	s.Incr(v)
+}
+
+// Get returns the mean of the values like a timing stat, and clears the
+// histogram.
+func (s *histogramStat) Get() int64 {
+	return s.Fields()[s.field].(int64)
+}
+
+// Fields returns the mean, percentiles and maximum of the values added
+// since the last call and clears the histogram. If no values were added, it
+// returns the previous fields.
+func (s *histogramStat) Fields() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		if s.prev == nil {
+			s.prev = map[string]interface{}{s.field: int64(0)}
+		}
+		return s.prev
+	}
+
+	sort.Slice(s.values, func(i, j int) bool { return s.values[i] < s.values[j] })
+	fields := map[string]interface{}{
+		s.field:          s.sum / s.count,
+		s.field + "_max": s.max,
+	}
+	for _, p := range percentiles {
+		// nearest rank
+		i := int(math.Ceil(p.p*float64(len(s.values)))) - 1
+		fields[s.field+p.suffix] = s.values[i]
+	}
+
+	s.values = s.values[:0]
+	s.count = 0
+	s.sum = 0
+	s.prev = fields
+	return fields
+}
+
+func (s *histogramStat) Name() string {
+	return s.measurement
+}
+
+func (s *histogramStat) FieldName() string {
+	return s.field
+}
+
+// Tags returns a copy of the histogramStat's tags.
+// NOTE this allocates a new map every time it is called.
+func (s *histogramStat) Tags() map[string]string {
+	m := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *histogramStat) Key() uint64 {
+	if s.key == 0 {
+		s.key = key(s.measurement, s.tags)
+	}
+	return s.key
+}
@@ -87,6 +87,27 @@ func RegisterTiming(measurement, field string, tags map[string]string) Stat {
 	})
 }
 
+// RegisterHistogram registers the given measurement, field, and tags in the
+// selfstat registry. If given an identical measurement, it will return the stat
+// that's already been registered.
+//
+// Histogram stats accumulate the values added to them like timing stats, and
+// are returned as several fields: the average with the name of the field, and
+// the 50th, 90th and 99th percentiles and the maximum with the _p50, _p90, _p99
+// and _max suffixes. The values are cleared after each collection, the
+// previous fields are returned if no values were added.
+//
+// The returned Stat can be incremented by the consumer of RegisterHistogram(),
+// its Get() returns the average of the values and clears them.
+func RegisterHistogram(measurement, field string, tags map[string]string) Stat {
+	return registry.register(newHistogramStat("internal_"+measurement, field, tags))
+}
+
+// fieldsStat is a stat with several fields, like a histogram.
+type fieldsStat interface {
+	Fields() map[string]interface{}
+}
+
 // Metrics returns all registered stats as telegraf metrics.
 func Metrics() []telegraf.Metric {
 	registry.mu.Lock()
@@ -104,7 +125,13 @@ func Metrics() []telegraf.Metric {
 					tags = stat.Tags()
 					name = stat.Name()
 				}
-				fields[fieldname] = stat.Get()
+				if fs, ok := stat.(fieldsStat); ok {
+					for k, v := range fs.Fields() {
+						fields[k] = v
+					}
+				} else {
+					fields[fieldname] = stat.Get()
+				}
 				j++
 			}
 			metric, err := metric.New(name, tags, fields, now)
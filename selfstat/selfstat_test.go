@@ -108,6 +108,45 @@ func TestRegisterTimingAndIncrAndSet(t *testing.T) {
 	assert.Equal(t, "internal_test", foo.Name())
 }
 
+func TestRegisterHistogramAndIncrAndSet(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+	s1 := RegisterHistogram("test", "test_field1_ns", map[string]string{"test": "foo"})
+	assert.Equal(t, int64(0), s1.Get())
+
+	s1.Incr(10)
+	s1.Set(5)
+	assert.Equal(t, int64(7), s1.Get())
+	// previous value is used on subsequent calls to Get()
+	assert.Equal(t, int64(7), s1.Get())
+
+	for i := int64(1); i <= 100; i++ {
+		s1.Incr(i)
+	}
+	acc := testutil.Accumulator{}
+	acc.AddMetrics(Metrics())
+	acc.AssertContainsTaggedFields(t, "internal_test",
+		map[string]interface{}{
+			"test_field1_ns":     int64(50),
+			"test_field1_ns_p50": int64(50),
+			"test_field1_ns_p90": int64(90),
+			"test_field1_ns_p99": int64(99),
+			"test_field1_ns_max": int64(100),
+		},
+		map[string]string{
+			"test": "foo",
+		},
+	)
+
+	// values beyond the size of the histogram are sampled
+	for i := int64(1); i <= 10*histogramSize; i++ {
+		s1.Incr(i)
+	}
+	fields := s1.(fieldsStat).Fields()
+	assert.Equal(t, int64(10*histogramSize), fields["test_field1_ns_max"])
+	assert.InDelta(t, 9*histogramSize, fields["test_field1_ns_p90"], histogramSize)
+}
+
 func TestStatKeyConsistency(t *testing.T) {
 	s := &stat{
 		measurement: "internal_stat",
@@ -0,0 +1,26 @@
+package telegraf
+
+// Store is a persistent key-value store for the small state of a plugin
+// instance, like the IDs already seen or an inventory of devices. Each
+// instance has its own keys, the state of all the instances is limited to
+// 16MB.
+type Store interface {
+	// Get returns the value of the key, or nil if the key is not set.
+	Get(key string) ([]byte, error)
+
+	// Set sets the value of the key, it is persisted within a second and
+	// when the agent stops.
+	Set(key string, value []byte) error
+
+	// Delete deletes the key.
+	Delete(key string) error
+
+	// Keys returns the keys that are set.
+	Keys() ([]string, error)
+}
+
+// StatefulPlugin is a plugin keeping state in a Store, the agent sets the
+// store before the plugin is started.
+type StatefulPlugin interface {
+	SetStore(store Store)
+}
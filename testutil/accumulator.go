@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -153,6 +154,18 @@ func (a *Accumulator) AddError(err error) {
 	a.Unlock()
 }
 
+// WithTracking returns a TrackingAccumulator adding the metrics to a, the
+// metric groups are delivered once they are added.
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	if maxTracked < 1 {
+		maxTracked = 1
+	}
+	return &TrackingAccumulator{
+		Accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
 func (a *Accumulator) SetPrecision(precision, interval time.Duration) {
 	return
 }
@@ -625,3 +638,26 @@ func (a *Accumulator) BoolField(measurement string, field string) (bool, bool) {
 
 	return false, false
 }
+
+// TrackingAccumulator is the TrackingAccumulator of an Accumulator.
+type TrackingAccumulator struct {
+	*Accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *TrackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	group, id := metric.WithGroupTracking(group, a.onDelivery)
+	for _, m := range group {
+		a.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+		m.Accept()
+	}
+	return id
+}
+
+func (a *TrackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+func (a *TrackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	a.delivered <- info
+}